@@ -214,6 +214,7 @@ var completeCmds = map[string]complete.Predictor{
 	"/find":      complete.PredictOr(s3Completer, fsCompleter),
 	"/mirror":    complete.PredictOr(s3Completer, fsCompleter),
 	"/pipe":      complete.PredictOr(s3Completer, fsCompleter),
+	"/reconcile": complete.PredictOr(s3Completer, fsCompleter),
 	"/stat":      complete.PredictOr(s3Completer, fsCompleter),
 	"/watch":     complete.PredictOr(s3Completer, fsCompleter),
 	"/policy":    complete.PredictOr(s3Completer, fsCompleter),
@@ -228,6 +229,12 @@ var completeCmds = map[string]complete.Predictor{
 	"/event/list":   aliasCompleter,
 	"/event/remove": aliasCompleter,
 
+	"/mpu/list": s3Completer,
+
+	"/cleanup-temps": s3Completer,
+
+	"/scan": s3Completer,
+
 	"/share/download": s3Completer,
 	"/share/list":     nil,
 	"/share/upload":   s3Completer,
@@ -0,0 +1,135 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/minio/mc/pkg/probe"
+)
+
+// Limiters of `--limit-upload` and `--limit-download`, shared by all
+// the transfers of a command. Transfers are not limited when nil.
+var (
+	globalUploadLimiter   *bandwidthLimiter
+	globalDownloadLimiter *bandwidthLimiter
+)
+
+// bandwidthLimiter - token bucket holding at most a second worth of
+// bytes, every reader wrapped by the same limiter draws from it.
+type bandwidthLimiter struct {
+	mutex sync.Mutex
+	rate  int64 // bytes per second
+	// Time at which the bytes reserved so far are all sent.
+	next time.Time
+}
+
+func newBandwidthLimiter(rate int64) *bandwidthLimiter {
+	return &bandwidthLimiter{rate: rate}
+}
+
+// reserve takes n bytes from the bucket and returns how long to wait
+// before they may be sent.
+func (l *bandwidthLimiter) reserve(now time.Time, n int) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// Unused bandwidth is kept for a second at most.
+	if burst := now.Add(-time.Second); l.next.Before(burst) {
+		l.next = burst
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.rate))
+	return l.next.Sub(now)
+}
+
+// wrap returns reader limited to the rate of l, reader itself when l
+// is nil.
+func (l *bandwidthLimiter) wrap(reader io.ReadCloser) io.ReadCloser {
+	if l == nil {
+		return reader
+	}
+	return &limitedReader{ReadCloser: reader, limiter: l}
+}
+
+// limitedReader - reads of a transfer limited by a bandwidthLimiter.
+type limitedReader struct {
+	io.ReadCloser
+	limiter *bandwidthLimiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	// Keep reads within a second worth of bytes, so that waits stay short.
+	if int64(len(p)) > r.limiter.rate {
+		p = p[:r.limiter.rate]
+	}
+	n, e := r.ReadCloser.Read(p)
+	if wait := r.limiter.reserve(time.Now(), n); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, e
+}
+
+// Seek implements io.Seeker for the filesystem targets resuming a
+// download, like hookreader it does nothing if the source can't seek.
+func (r *limitedReader) Seek(offset int64, whence int) (int64, error) {
+	if seeker, ok := r.ReadCloser.(io.Seeker); ok {
+		return seeker.Seek(offset, whence)
+	}
+	return 0, nil
+}
+
+// parseBandwidthLimit parses a rate such as "512KiB", "10MB/s" or
+// "1.5MiB/s" into bytes per second, an empty value means no limit.
+func parseBandwidthLimit(value string) (int64, *probe.Error) {
+	if value == "" {
+		return 0, nil
+	}
+	rate, e := humanize.ParseBytes(strings.TrimSuffix(value, "/s"))
+	if e != nil {
+		return 0, probe.NewError(e)
+	}
+	if rate == 0 {
+		return 0, probe.NewError(errors.New("bandwidth limit must be greater than zero"))
+	}
+	return int64(rate), nil
+}
+
+// setBandwidthLimits sets up the limiters shared by the transfers of
+// a command.
+func setBandwidthLimits(upload, download string) *probe.Error {
+	uploadRate, err := parseBandwidthLimit(upload)
+	if err != nil {
+		return err.Trace(upload)
+	}
+	downloadRate, err := parseBandwidthLimit(download)
+	if err != nil {
+		return err.Trace(download)
+	}
+	globalUploadLimiter, globalDownloadLimiter = nil, nil
+	if uploadRate > 0 {
+		globalUploadLimiter = newBandwidthLimiter(uploadRate)
+	}
+	if downloadRate > 0 {
+		globalDownloadLimiter = newBandwidthLimiter(downloadRate)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBandwidthLimit(t *testing.T) {
+	testCases := []struct {
+		value   string
+		rate    int64
+		success bool
+	}{
+		{"", 0, true},
+		{"512KiB", 512 << 10, true},
+		{"10MB/s", 10 * 1000 * 1000, true},
+		{"1.5MiB/s", 3 << 19, true},
+		{"0", 0, false},
+		{"fast", 0, false},
+	}
+	for i, testCase := range testCases {
+		rate, err := parseBandwidthLimit(testCase.value)
+		if testCase.success != (err == nil) {
+			t.Fatalf("Test %d: unexpected result for `%s`: %v", i+1, testCase.value, err)
+		}
+		if rate != testCase.rate {
+			t.Fatalf("Test %d: expected %d, found %d", i+1, testCase.rate, rate)
+		}
+	}
+}
+
+func TestBandwidthLimiterReserve(t *testing.T) {
+	limiter := newBandwidthLimiter(1000)
+	now := time.Now()
+
+	// A second worth of unused bandwidth is sent right away.
+	if wait := limiter.reserve(now, 1000); wait != 0 {
+		t.Fatalf("expected no wait, found %s", wait)
+	}
+	// Concurrent transfers share the same bucket.
+	if wait := limiter.reserve(now, 500); wait != 500*time.Millisecond {
+		t.Fatalf("expected 500ms wait, found %s", wait)
+	}
+	if wait := limiter.reserve(now, 500); wait != time.Second {
+		t.Fatalf("expected 1s wait, found %s", wait)
+	}
+	// Idle time beyond a second is not saved up.
+	later := now.Add(time.Minute)
+	if wait := limiter.reserve(later, 2000); wait != time.Second {
+		t.Fatalf("expected 1s wait, found %s", wait)
+	}
+}
@@ -18,9 +18,11 @@ package cmd
 
 import (
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 	"syscall"
@@ -32,10 +34,20 @@ import (
 )
 
 var (
-	// This is kept dummy for future purposes
-	// and also to add ioFlags and globalFlags
-	// in CLI registration.
-	catFlags = []cli.Flag{}
+	catFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "raw",
+			Usage: "output the stored bytes, do not decode 'Content-Encoding: gzip'",
+		},
+		cli.Int64Flag{
+			Name:  "offset",
+			Usage: "skip this many bytes at the start of each source",
+		},
+		cli.Int64Flag{
+			Name:  "length",
+			Usage: "output at most this many bytes of each source, all of them by default",
+		},
+	}
 )
 
 // Display contents of a file.
@@ -73,6 +85,15 @@ EXAMPLES:
   5. Display the content of encrypted object. In case the encryption key contains non-printable character like tab, pass the
      base64 encoded string as key.
      {{.Prompt}} {{.HelpName}} --encrypt-key "play/my-bucket/=MzJieXRlc2xvbmdzZWNyZXRrZQltdXN0YmVnaXZlbjE="  play/my-bucket/my-object
+
+  6. Save an object uploaded with 'Content-Encoding: gzip' as it is stored, without decompressing it.
+     {{.Prompt}} {{.HelpName}} --raw play/my-bucket/index.html > index.html.gz
+
+  7. Display the first kilobyte of a large log object.
+     {{.Prompt}} {{.HelpName}} --length 1024 play/my-bucket/server.log
+
+  8. Extract 512 bytes at offset 4096 of a disk image, only these bytes are downloaded.
+     {{.Prompt}} {{.HelpName}} --offset 4096 --length 512 play/my-bucket/disk.img > block.bin
 `,
 }
 
@@ -136,15 +157,61 @@ func checkCatSyntax(ctx *cli.Context) {
 			fatalIf(probe.NewError(errors.New("")), fmt.Sprintf("Unknown flag `%s` passed.", arg))
 		}
 	}
+	if offset := ctx.Int64("offset"); offset < 0 {
+		fatalIf(errInvalidArgument().Trace(ctx.String("offset")), "Invalid --offset `"+ctx.String("offset")+"`.")
+	}
+	if length := ctx.Int64("length"); ctx.IsSet("length") && length < 0 {
+		fatalIf(errInvalidArgument().Trace(ctx.String("length")), "Invalid --length `"+ctx.String("length")+"`.")
+	}
 }
 
-// catURL displays contents of a URL to stdout.
-func catURL(sourceURL string, encKeyDB map[string][]prefixSSEPair) *probe.Error {
-	var reader io.ReadCloser
+// decodeContentEncoding wraps r to undo the gzip encodings of a
+// Content-Encoding header. Encodings are listed in the order they
+// were applied, so they are undone from the last one. Decoding stops
+// at the first encoding which is not gzip, leaving it untouched.
+func decodeContentEncoding(r io.Reader, contentEncoding string) (reader io.Reader, isDecoded bool, e error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch strings.ToLower(strings.TrimSpace(encodings[i])) {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			if r, e = gzip.NewReader(r); e != nil {
+				return nil, false, e
+			}
+			isDecoded = true
+		default:
+			return r, isDecoded, nil
+		}
+	}
+	return r, isDecoded, nil
+}
+
+// isGzipDecodeErr returns true for errors of content which is not,
+// or not entirely, gzip compressed.
+func isGzipDecodeErr(e error) bool {
+	return e == gzip.ErrHeader || e == gzip.ErrChecksum || e == io.EOF || e == io.ErrUnexpectedEOF
+}
+
+// catURL displays contents of a URL to stdout, length bytes from offset
+// or all of them if length is negative. Ranges address the bytes as
+// stored, they are never decoded.
+func catURL(sourceURL string, encKeyDB map[string][]prefixSSEPair, isRaw bool, offset, length int64) *probe.Error {
+	var reader io.Reader
 	size := int64(-1)
+	contentEncoding := ""
+	isDecoded := false
+	isRange := offset > 0 || length >= 0
 	switch sourceURL {
 	case "-":
 		reader = os.Stdin
+		if offset > 0 {
+			if _, e := io.CopyN(ioutil.Discard, os.Stdin, offset); e != nil && e != io.EOF {
+				return probe.NewError(e)
+			}
+		}
+		if length >= 0 {
+			reader = io.LimitReader(os.Stdin, length)
+		}
 	default:
 		var err *probe.Error
 		// Try to stat the object, the purpose is to extract the
@@ -152,16 +219,58 @@ func catURL(sourceURL string, encKeyDB map[string][]prefixSSEPair) *probe.Error
 		// downloaded object is equal to the original one. FS files
 		// are ignored since some of them have zero size though they
 		// have contents like files under /proc.
-		client, content, err := url2Stat(sourceURL, false, false, encKeyDB)
+		client, content, err := url2Stat(sourceURL, !isRaw && !isRange, false, encKeyDB)
 		if err == nil && client.GetURL().Type == objectStorage {
 			size = content.Size
+			contentEncoding = content.Metadata["Content-Encoding"]
 		}
-		if reader, err = getSourceStreamFromURL(sourceURL, encKeyDB); err != nil {
+		var readCloser io.ReadCloser
+		if isRange {
+			if size >= 0 {
+				if offset > size {
+					return probe.NewError(fmt.Errorf("offset %d is beyond the %d bytes of `%s`", offset, size, sourceURL))
+				}
+				size -= offset
+				if length >= 0 && length < size {
+					size = length
+				}
+			}
+			contentEncoding = ""
+			if size == 0 {
+				// Nothing left past the offset, a range of it is not
+				// satisfiable.
+				readCloser = ioutil.NopCloser(strings.NewReader(""))
+			} else {
+				readCloser, err = getSourceRangeFromURL(sourceURL, offset, length, encKeyDB)
+			}
+		} else {
+			readCloser, err = getSourceStreamFromURL(sourceURL, encKeyDB)
+		}
+		if err != nil {
 			return err.Trace(sourceURL)
 		}
-		defer reader.Close()
+		defer readCloser.Close()
+		reader = readCloser
+	}
+	if !isRaw && contentEncoding != "" {
+		decoded, ok, e := decodeContentEncoding(reader, contentEncoding)
+		if e != nil {
+			if isGzipDecodeErr(e) {
+				return errInvalidContentEncoding(sourceURL, contentEncoding).Trace(sourceURL)
+			}
+			return probe.NewError(e).Trace(sourceURL)
+		}
+		if ok {
+			// The stored size is the compressed one, gzip
+			// verifies the decompressed length by itself.
+			reader, size, isDecoded = decoded, -1, true
+		}
 	}
-	return catOut(reader, size).Trace(sourceURL)
+	err := catOut(reader, size)
+	if err != nil && isDecoded && isGzipDecodeErr(err.ToGoError()) {
+		return errInvalidContentEncoding(sourceURL, contentEncoding).Trace(sourceURL)
+	}
+	return err.Trace(sourceURL)
 }
 
 // catOut reads from reader stream and writes to stdout. Also check the length of the
@@ -224,9 +333,15 @@ func mainCat(ctx *cli.Context) error {
 		stdinMode = true
 	}
 
+	isRaw := ctx.Bool("raw")
+	offset, length := ctx.Int64("offset"), int64(-1)
+	if ctx.IsSet("length") {
+		length = ctx.Int64("length")
+	}
+
 	// handle std input data.
 	if stdinMode {
-		fatalIf(catOut(os.Stdin, -1).Trace(), "Unable to read from standard input.")
+		fatalIf(catURL("-", encKeyDB, isRaw, offset, length).Trace(), "Unable to read from standard input.")
 		return nil
 	}
 
@@ -242,9 +357,22 @@ func mainCat(ctx *cli.Context) error {
 		}
 	}
 
+	// A missing source fails the command before the sources preceding
+	// it are displayed.
+	if len(args) > 1 {
+		for _, url := range args {
+			if url == "-" {
+				continue
+			}
+			if _, _, err := url2Stat(url, false, false, encKeyDB); err != nil && isErrSourceMissing(err) {
+				fatalIf(err.Trace(url), "Unable to read from `"+url+"`.")
+			}
+		}
+	}
+
 	// Convert arguments to URLs: expand alias, fix format.
 	for _, url := range args {
-		fatalIf(catURL(url, encKeyDB).Trace(url), "Unable to read from `"+url+"`.")
+		fatalIf(catURL(url, encKeyDB, isRaw, offset, length).Trace(url), "Unable to read from `"+url+"`.")
 	}
 
 	return nil
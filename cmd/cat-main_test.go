@@ -0,0 +1,101 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestCatURLOffset(t *testing.T) {
+	data := "hello world"
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", "\"etag\"")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		switch {
+		case r.URL.Path == "/bucket/":
+			fmt.Fprintf(w, "<ListBucketResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>bucket</Name><Prefix>object</Prefix><KeyCount>1</KeyCount><MaxKeys>1000</MaxKeys><Delimiter>/</Delimiter><IsTruncated>false</IsTruncated><Contents><Key>object</Key><LastModified>2006-01-02T15:04:05.000Z</LastModified><ETag>&quot;etag&quot;</ETag><Size>%d</Size><StorageClass>STANDARD</StorageClass></Contents></ListBucketResult>", len(data))
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		case r.Method == http.MethodGet:
+			var start int
+			if _, e := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); e != nil || start >= len(data) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				w.Write([]byte("<Error><Code>InvalidRange</Code><Message>The requested range is not satisfiable</Message></Error>"))
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(data)-1, len(data)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)-start))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(data[start:]))
+		}
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("cattest", server.URL)()
+
+	out, e := ioutil.TempFile("", "cat-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+	savedStdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = savedStdout }()
+
+	testCases := []struct {
+		offset  int64
+		output  string
+		success bool
+	}{
+		{6, "world", true},
+		// Nothing left to display at the end of the object.
+		{int64(len(data)), "", true},
+		{int64(len(data)) + 1, "", false},
+	}
+	for i, testCase := range testCases {
+		if _, e = out.Seek(0, 0); e != nil {
+			t.Fatal(e)
+		}
+		if e = out.Truncate(0); e != nil {
+			t.Fatal(e)
+		}
+		err := catURL("cattest/bucket/object", nil, false, testCase.offset, -1)
+		if testCase.success && err != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, err)
+		}
+		if !testCase.success {
+			if err == nil {
+				t.Fatalf("Test %d: expected an error", i+1)
+			}
+			continue
+		}
+		output, e := ioutil.ReadFile(out.Name())
+		if e != nil {
+			t.Fatal(e)
+		}
+		if string(output) != testCase.output {
+			t.Errorf("Test %d: expected %q, got %q", i+1, testCase.output, output)
+		}
+	}
+}
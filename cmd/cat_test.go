@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"bytes"
+	"compress/gzip"
 	"io"
 	"io/ioutil"
 	"testing"
@@ -62,3 +63,63 @@ func TestPrettyStdout(t *testing.T) {
 		}
 	}
 }
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeContentEncoding(t *testing.T) {
+	text := []byte("hello, world\n")
+	compressed := gzipBytes(t, text)
+
+	testCases := []struct {
+		content         []byte
+		contentEncoding string
+		output          []byte
+		isDecoded       bool
+		expectErr       bool
+	}{
+		{text, "identity", text, false, false},
+		{compressed, "gzip", text, true, false},
+		{compressed, "x-gzip", text, true, false},
+		{compressed, "GZIP", text, true, false},
+		// Compressed twice, both encodings are undone.
+		{gzipBytes(t, compressed), "gzip, gzip", text, true, false},
+		// Compressed twice but labeled once, only one is undone.
+		{gzipBytes(t, compressed), "gzip", compressed, true, false},
+		// Unknown encodings are left untouched.
+		{compressed, "br", compressed, false, false},
+		{compressed, "br, gzip", text, true, false},
+		// Mislabeled or truncated content.
+		{text, "gzip", nil, false, true},
+		{[]byte{}, "gzip", nil, false, true},
+		{compressed[:len(compressed)-4], "gzip", nil, true, true},
+	}
+
+	for i, testCase := range testCases {
+		reader, isDecoded, err := decodeContentEncoding(bytes.NewReader(testCase.content), testCase.contentEncoding)
+		if err == nil {
+			if isDecoded != testCase.isDecoded {
+				t.Fatalf("Test %d: expected decoded %t, found %t", i+1, testCase.isDecoded, isDecoded)
+			}
+			var output []byte
+			if output, err = ioutil.ReadAll(reader); err == nil && !bytes.Equal(output, testCase.output) {
+				t.Fatalf("Test %d: expected output `%s`, found `%s`", i+1, testCase.output, output)
+			}
+		}
+		if testCase.expectErr != (err != nil) {
+			t.Fatalf("Test %d: expected error %t, found %v", i+1, testCase.expectErr, err)
+		}
+		if err != nil && !isGzipDecodeErr(err) {
+			t.Fatalf("Test %d: expected a decode error, found %v", i+1, err)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/minio/mc/pkg/probe"
 )
@@ -133,3 +134,38 @@ func loadRootCAs() {
 		globalRootCAs.AppendCertsFromPEM(caCert)
 	}
 }
+
+var (
+	hostRootCAsMu sync.Mutex
+	hostRootCAs   = make(map[string]*x509.CertPool)
+)
+
+// getRootCAs returns the CAs trusted for a host whose config names the
+// PEM file caFile, which are added to the system and MinIO config CAs.
+// An empty caFile returns globalRootCAs.
+func getRootCAs(caFile string) (*x509.CertPool, *probe.Error) {
+	if caFile == "" {
+		return globalRootCAs, nil
+	}
+	hostRootCAsMu.Lock()
+	defer hostRootCAsMu.Unlock()
+	if pool, ok := hostRootCAs[caFile]; ok {
+		return pool, nil
+	}
+
+	caCert, e := ioutil.ReadFile(caFile)
+	if e != nil {
+		return nil, probe.NewError(CACertInvalid{Path: caFile, Reason: e.Error()})
+	}
+	pool := mustGetSystemCertPool()
+	for _, f := range mustGetCAFiles() {
+		if data, e := ioutil.ReadFile(f); e == nil {
+			pool.AppendCertsFromPEM(data)
+		}
+	}
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, probe.NewError(CACertInvalid{Path: caFile, Reason: "no PEM encoded certificate found"})
+	}
+	hostRootCAs[caFile] = pool
+	return pool, nil
+}
@@ -0,0 +1,138 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+)
+
+// checksumAlgorithms - algorithms a checksum can be stored with, in the
+// order they are looked up in the metadata of an object.
+var checksumAlgorithms = []string{"sha256", "md5"}
+
+// newChecksumHash returns a hash for algorithm, nil if it is unknown.
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case "sha256":
+		return sha256.New()
+	case "md5":
+		return md5.New()
+	}
+	return nil
+}
+
+// checksumMetadataKey - user metadata the checksum of an object made
+// with algorithm is stored in, e.g. X-Amz-Meta-Mc-Sha256.
+func checksumMetadataKey(algorithm string) string {
+	return "X-Amz-Meta-Mc-" + strings.Title(algorithm)
+}
+
+// storedChecksum returns the checksum stored in metadata and its
+// algorithm, both are empty for objects uploaded without one.
+func storedChecksum(metadata map[string]string) (algorithm, sum string) {
+	for _, algorithm := range checksumAlgorithms {
+		if sum := metadata[checksumMetadataKey(algorithm)]; sum != "" {
+			return algorithm, sum
+		}
+	}
+	return "", ""
+}
+
+// checksumReader returns the hex encoded checksum of what is read from
+// r and its length.
+func checksumReader(r io.Reader, algorithm string) (string, int64, error) {
+	hasher := newChecksumHash(algorithm)
+	n, e := io.Copy(hasher, r)
+	if e != nil {
+		return "", n, e
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), n, nil
+}
+
+// checksumFile returns the hex encoded checksum of a local file.
+func checksumFile(path, algorithm string) (string, *probe.Error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	defer f.Close()
+	sum, _, e := checksumReader(f, algorithm)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	return sum, nil
+}
+
+// sourceChecksum returns the checksum of the source of urls opened as
+// reader. Readers which can seek, such as files and objects, are hashed
+// then rewound for the upload to read them from their start. Others are
+// opened and read once more. Either way the returned hash must be fed
+// what is uploaded to confirm the source did not change in between.
+func sourceChecksum(urls URLs, reader io.Reader, algorithm string, sse encrypt.ServerSide) (string, hash.Hash, *probe.Error) {
+	seeker, ok := reader.(io.ReadSeeker)
+	if !ok || urls.SourceContent.Size < 0 {
+		sum, err := checksumSource(urls, algorithm, sse)
+		return sum, newChecksumHash(algorithm), err
+	}
+	var source io.ReadCloser = ioutil.NopCloser(seeker)
+	if urls.SourceContent.URL.Type == objectStorage {
+		source = globalDownloadLimiter.wrap(source)
+	}
+	sum, _, e := checksumReader(source, algorithm)
+	if e != nil {
+		return "", nil, probe.NewError(e).Trace(urls.SourceContent.URL.String())
+	}
+	offset, e := seeker.Seek(0, io.SeekStart)
+	if e == nil && offset != 0 {
+		e = fmt.Errorf("rewound to offset %d instead of the start", offset)
+	}
+	if e != nil {
+		return "", nil, probe.NewError(e).Trace(urls.SourceContent.URL.String())
+	}
+	return sum, newChecksumHash(algorithm), nil
+}
+
+// checksumSource reads the source of urls entirely and returns its hex
+// encoded checksum. Metadata is sent before the content of an upload,
+// the checksum has to be known before the upload starts.
+func checksumSource(urls URLs, algorithm string, sse encrypt.ServerSide) (string, *probe.Error) {
+	sourceURL := urls.SourceContent.URL.String()
+	reader, _, err := getSourceStream(urls.SourceAlias, sourceURL, false, sse)
+	if err != nil {
+		return "", err.Trace(sourceURL)
+	}
+	defer reader.Close()
+	if urls.SourceContent.URL.Type == objectStorage {
+		reader = globalDownloadLimiter.wrap(reader)
+	}
+	sum, _, e := checksumReader(reader, algorithm)
+	if e != nil {
+		return "", probe.NewError(e).Trace(sourceURL)
+	}
+	return sum, nil
+}
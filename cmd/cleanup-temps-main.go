@@ -0,0 +1,198 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	json "github.com/minio/mc/pkg/colorjson"
+	"github.com/minio/mc/pkg/ioutils"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+var (
+	cleanupTempsFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "older-than",
+			Usage: "remove staging folders not written to for longer than specified time",
+			Value: "1d",
+		},
+		cli.BoolFlag{
+			Name:  "fake",
+			Usage: "list orphaned staging folders without removing them",
+		},
+	}
+)
+
+var cleanupTempsCmd = cli.Command{
+	Name:   "cleanup-temps",
+	Usage:  "remove staging folders left behind by interrupted mirrors",
+	Action: mainCleanupTemps,
+	Before: setGlobalsFromContext,
+	Flags:  append(cleanupTempsFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  'mirror --staged' uploads below a '` + stagingFolder + `/<id>/' folder of its target and removes it
+  once published. A mirror which is killed leaves that folder behind. TARGET is the target
+  of the mirror, folders which are still written to by a running mirror are kept.
+
+EXAMPLES:
+  1. Remove staging folders of mirrors into a bucket which were interrupted a day ago or earlier.
+     {{.Prompt}} {{.HelpName}} s3/www
+
+  2. List staging folders below a prefix not written to for an hour, without removing them.
+     {{.Prompt}} {{.HelpName}} --fake --older-than 1h s3/backups/daily
+`,
+}
+
+// cleanupTempsMessage container for a single orphaned staging folder.
+type cleanupTempsMessage struct {
+	Status       string    `json:"status"`
+	Key          string    `json:"key"`
+	Objects      int       `json:"objects"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	Removed      bool      `json:"removed"`
+}
+
+func (m cleanupTempsMessage) String() string {
+	msg := console.Colorize("Time", fmt.Sprintf("[%s] ", m.LastModified.Format(printDate)))
+	msg += console.Colorize("Size", fmt.Sprintf("%7s ", humanizeSize(m.Size)))
+	msg += console.Colorize("Objects", fmt.Sprintf("%5d objects ", m.Objects))
+	if m.Removed {
+		msg += console.Colorize("Removed", "Removed ")
+	}
+	return msg + "`" + m.Key + "`."
+}
+
+func (m cleanupTempsMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkCleanupTempsSyntax - validate all the passed arguments
+func checkCleanupTempsSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "cleanup-temps", 1) // last argument is exit code
+	}
+	if _, e := ioutils.ParseDurationTime(ctx.String("older-than")); e != nil {
+		fatalIf(probe.NewError(e).Trace(ctx.String("older-than")), "Invalid --older-than value.")
+	}
+}
+
+// statStagingFolder sums up the objects of a staging folder, a killed
+// mirror can not be told apart from a running one other than by the
+// time its staging folder was last written to.
+func statStagingFolder(targetAlias, stagingURL string) (msg cleanupTempsMessage, err *probe.Error) {
+	clnt, err := newClientFromAlias(targetAlias, stagingURL)
+	if err != nil {
+		return msg, err.Trace(stagingURL)
+	}
+	for content := range clnt.List(true, false, false, DirNone) {
+		if content.Err != nil {
+			return msg, content.Err.Trace(stagingURL)
+		}
+		msg.Objects++
+		msg.Size += content.Size
+		if content.Time.After(msg.LastModified) {
+			msg.LastModified = content.Time
+		}
+	}
+	return msg, nil
+}
+
+// cleanupTemps removes the staging folders of the mirrors into
+// targetURL not written to for longer than olderThan, only listing them
+// when isFake is set.
+func cleanupTemps(targetURL, olderThan string, isFake bool) error {
+	separator := string(newClientURL(targetURL).Separator)
+	if !strings.HasSuffix(targetURL, separator) {
+		targetURL = targetURL + separator
+	}
+	targetAlias, expandedURL, _ := mustExpandAlias(targetURL)
+	foldersURL := urlJoinPath(expandedURL, stagingFolder) + separator
+	clnt, err := newClientFromAlias(targetAlias, foldersURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+
+	var cErr error
+	for content := range clnt.List(false, false, false, DirFirst) {
+		if content.Err != nil {
+			// No mirror left a staging folder.
+			if isErrSourceMissing(content.Err) {
+				continue
+			}
+			errorIf(content.Err.Trace(foldersURL), "Unable to list `%s`.", foldersURL)
+			cErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		if !content.Type.IsDir() {
+			continue
+		}
+		stagingURL := content.URL.String()
+		if !strings.HasSuffix(stagingURL, separator) {
+			stagingURL = stagingURL + separator
+		}
+		msg, err := statStagingFolder(targetAlias, stagingURL)
+		if err != nil {
+			errorIf(err, "Unable to list staging folder `%s`.", stagingURL)
+			cErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		// Still written to, most likely by a running mirror.
+		if msg.Objects > 0 && isOlder(msg.LastModified, olderThan) {
+			continue
+		}
+		msg.Key = filepath.ToSlash(filepath.Join(targetAlias, content.URL.Path)) + "/"
+		if !isFake {
+			if err = removeStagingFolder(targetAlias, stagingURL); err != nil {
+				errorIf(err, "Unable to remove staging folder `%s`.", stagingURL)
+				cErr = exitStatus(globalErrorExitStatus)
+				continue
+			}
+			msg.Removed = true
+		}
+		printMsg(msg)
+	}
+	return cErr
+}
+
+func mainCleanupTemps(ctx *cli.Context) error {
+	console.SetColor("Time", color.New(color.FgGreen))
+	console.SetColor("Size", color.New(color.FgYellow))
+	console.SetColor("Objects", color.New(color.FgCyan))
+	console.SetColor("Removed", color.New(color.FgRed, color.Bold))
+
+	checkCleanupTempsSyntax(ctx)
+
+	return cleanupTemps(ctx.Args().First(), ctx.String("older-than"), ctx.Bool("fake"))
+}
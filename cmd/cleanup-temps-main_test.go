@@ -0,0 +1,93 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanupTemps(t *testing.T) {
+	defer useEmptyMcConfig()()
+	savedQuiet := globalQuiet
+	globalQuiet = true
+	defer func() { globalQuiet = savedQuiet }()
+
+	targetDir, e := ioutil.TempDir("", "mc-cleanup-temps-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(targetDir)
+
+	// Nothing to clean up without staging folders.
+	if e = cleanupTemps(targetDir, "1d", false); e != nil {
+		t.Fatalf("unexpected error %s", e)
+	}
+
+	object := filepath.Join(targetDir, "object")
+	killed := filepath.Join(targetDir, stagingFolder, "killed", "dir", "object")
+	running := filepath.Join(targetDir, stagingFolder, "running", "object")
+	for _, name := range []string{object, killed, running} {
+		if e = os.MkdirAll(filepath.Dir(name), 0755); e != nil {
+			t.Fatal(e)
+		}
+		if e = ioutil.WriteFile(name, []byte("data"), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+	twoDaysAgo := time.Now().Add(-48 * time.Hour)
+	if e = os.Chtimes(killed, twoDaysAgo, twoDaysAgo); e != nil {
+		t.Fatal(e)
+	}
+	exists := func(name string) bool {
+		_, e := os.Stat(name)
+		return e == nil
+	}
+
+	// Listed only.
+	if e = cleanupTemps(targetDir, "1d", true); e != nil {
+		t.Fatalf("unexpected error %s", e)
+	}
+	if !exists(killed) || !exists(running) {
+		t.Fatal("expected no staging folder to be removed with fake")
+	}
+
+	// Folders written to within the age given are kept, as well as the
+	// objects of the target.
+	if e = cleanupTemps(targetDir, "1d", false); e != nil {
+		t.Fatalf("unexpected error %s", e)
+	}
+	if exists(killed) || !exists(running) || !exists(object) {
+		t.Fatalf("expected only the folder of the killed mirror to be removed, found %t %t %t",
+			exists(killed), exists(running), exists(object))
+	}
+
+	// Older than an hour, none is written to.
+	twoHoursAgo := time.Now().Add(-2 * time.Hour)
+	if e = os.Chtimes(running, twoHoursAgo, twoHoursAgo); e != nil {
+		t.Fatal(e)
+	}
+	if e = cleanupTemps(targetDir, "1h", false); e != nil {
+		t.Fatalf("unexpected error %s", e)
+	}
+	if exists(running) || !exists(object) {
+		t.Fatal("expected the staging folder older than an hour to be removed")
+	}
+}
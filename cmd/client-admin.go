@@ -52,9 +52,14 @@ func newAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 		// Save if target supports virtual host style.
 		hostName := targetURL.Host
 
+		rootCAs, err := getRootCAs(config.CACertFile)
+		if err != nil {
+			return nil, err.Trace(config.CACertFile)
+		}
+
 		// Generate a hash out of s3Conf.
 		confHash := fnv.New32a()
-		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey))
+		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + config.CACertFile))
 		confSum := confHash.Sum32()
 
 		// Lookup previous cache by hash.
@@ -71,7 +76,7 @@ func newAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 			}
 
 			// Keep TLS config.
-			tlsConfig := &tls.Config{RootCAs: globalRootCAs}
+			tlsConfig := &tls.Config{RootCAs: rootCAs}
 			if config.Insecure {
 				tlsConfig.InsecureSkipVerify = true
 			}
@@ -81,6 +86,7 @@ func newAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 				DialContext: (&net.Dialer{
 					Timeout:   30 * time.Second,
 					KeepAlive: 30 * time.Second,
+					LocalAddr: localTCPAddr(config.BindAddr),
 				}).DialContext,
 				MaxIdleConns:          100,
 				IdleConnTimeout:       90 * time.Second,
@@ -88,6 +94,9 @@ func newAdminFactory() func(config *Config) (*madmin.AdminClient, *probe.Error)
 				ExpectContinueTimeout: 1 * time.Second,
 				TLSClientConfig:       tlsConfig,
 			}
+			if config.ConnLimiter != nil {
+				transport = connLimitTransport{limiter: config.ConnLimiter, transport: transport}
+			}
 
 			if config.Debug {
 				transport = httptracer.GetNewTraceTransport(newTraceV4(), transport)
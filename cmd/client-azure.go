@@ -0,0 +1,1338 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+	"github.com/minio/minio/pkg/bucket/object/tagging"
+)
+
+const (
+	azureAPIType = "Azure Blob Storage"
+
+	// Version of the Blob service REST API requests are made with.
+	azureAPIVersion = "2019-12-12"
+
+	// Block size of uploads of unknown size, a blob has at most 50000
+	// blocks.
+	azureStreamBlockSize = 64 * 1024 * 1024
+
+	// Length of the upload ID prefixing block IDs, all block IDs of a
+	// blob must have the same length.
+	azureUploadIDLen = 16
+)
+
+var (
+	// Interval the status of a server side copy is polled at.
+	azureCopyPollInterval = time.Second
+
+	// Server side copies which copied nothing for this long are
+	// aborted instead of waited for.
+	azureCopyStallTimeout = 10 * time.Minute
+)
+
+// azureClient - Azure Blob Storage client, containers are listed as
+// buckets and blobs as objects. Requests are signed with the shared key
+// of the storage account.
+type azureClient struct {
+	mutex      *sync.Mutex
+	targetURL  *clientURL
+	config     *Config
+	account    string
+	key        []byte
+	httpClient *http.Client
+	userAgent  string
+}
+
+// isAzure tells if host is an Azure Blob Storage endpoint.
+func isAzure(host string) bool {
+	if h, _, e := net.SplitHostPort(host); e == nil {
+		host = h
+	}
+	return strings.HasSuffix(strings.ToLower(host), ".blob.core.windows.net")
+}
+
+// isAzureHost tells if the alias of hostCfg is an Azure Blob Storage
+// account, either by its API or by its endpoint.
+func isAzureHost(hostCfg *hostConfigV9) bool {
+	return strings.EqualFold(hostCfg.API, "azure") || isAzure(newClientURL(hostCfg.URL).Host)
+}
+
+// azureNew returns an initialized azureClient, the access key of config
+// is the name of the storage account and its secret key the base64
+// encoded account key.
+func azureNew(config *Config) (Client, *probe.Error) {
+	targetURL := newClientURL(config.HostURL)
+	key, e := base64.StdEncoding.DecodeString(config.SecretKey)
+	if e != nil {
+		return nil, probe.NewError(errors.New("the secret key of an Azure storage account is its base64 encoded account key"))
+	}
+	if globalOffline {
+		return offlineClient{url: *targetURL}, nil
+	}
+
+	rootCAs, err := getRootCAs(config.CACertFile)
+	if err != nil {
+		return nil, err.Trace(config.CACertFile)
+	}
+
+	var transport http.RoundTripper = newAzureTransport(config, targetURL.Scheme == "https", rootCAs)
+	if config.ReadTimeout > 0 {
+		transport = readTimeoutTransport{timeout: config.ReadTimeout, transport: transport}
+	}
+	if config.RequestTimeout > 0 {
+		transport = requestTimeoutTransport{timeout: config.RequestTimeout, transport: transport}
+	}
+	if config.ConnLimiter != nil {
+		transport = connLimitTransport{limiter: config.ConnLimiter, transport: transport}
+	}
+	statuses := config.RetryStatusCodes
+	if len(statuses) == 0 {
+		statuses = azureRetryStatusCodes
+	}
+	transport = retryStatusTransport{
+		statuses:  statuses,
+		maxRetry:  defaultRetryAttempts,
+		transport: transport,
+	}
+
+	return &azureClient{
+		mutex:      new(sync.Mutex),
+		targetURL:  targetURL,
+		config:     config,
+		account:    config.AccessKey,
+		key:        key,
+		httpClient: &http.Client{Transport: transport},
+		userAgent:  config.AppName + "/" + config.AppVersion,
+	}, nil
+}
+
+// HTTP statuses Azure requests are retried on by default.
+var azureRetryStatusCodes = map[int]struct{}{
+	http.StatusTooManyRequests:     {},
+	http.StatusInternalServerError: {},
+	http.StatusBadGateway:          {},
+	http.StatusServiceUnavailable:  {},
+	http.StatusGatewayTimeout:      {},
+}
+
+var (
+	azureTransportsMu sync.Mutex
+	azureTransports   = make(map[string]*http.Transport)
+)
+
+// newAzureTransport returns the transport of config, shared by all
+// clients created with the same connection settings so that
+// connections are kept alive across objects.
+func newAzureTransport(config *Config, useTLS bool, rootCAs *x509.CertPool) *http.Transport {
+	key := fmt.Sprintf("%v|%v|%v|%v|%v|%v|%v|%v|%v", useTLS, config.Insecure, config.CACertFile, config.ConnectTimeout,
+		config.ResponseHeaderTimeout, config.DualStack, config.BindAddr, config.maxIdleConns(), config.maxIdleConnsPerHost())
+	azureTransportsMu.Lock()
+	defer azureTransportsMu.Unlock()
+	if tr, ok := azureTransports[key]; ok {
+		return tr
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+		LocalAddr: localTCPAddr(config.BindAddr),
+	}
+	dialContext := dialer.DialContext
+	if config.DualStack {
+		dialContext = preferIPv6Dial(dialer)
+	}
+	tr := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialContext,
+		MaxIdleConns:          config.maxIdleConns(),
+		MaxIdleConnsPerHost:   config.maxIdleConnsPerHost(),
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		DisableCompression:    true,
+	}
+	if useTLS {
+		tr.TLSClientConfig = &tls.Config{
+			RootCAs:            rootCAs,
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: config.Insecure,
+		}
+	}
+	azureTransports[key] = tr
+	return tr
+}
+
+// azureError - error response of the Blob service.
+type azureError struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e azureError) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	// Messages carry the request ID and time on following lines.
+	return strings.SplitN(e.Message, "\n", 2)[0]
+}
+
+// azureErrorCode returns the error code of e, if answered by the Blob
+// service.
+func azureErrorCode(e error) string {
+	if ae, ok := e.(azureError); ok {
+		return ae.Code
+	}
+	return ""
+}
+
+// toProbeError converts an error of a request on blob of container to
+// the errors reported for S3.
+func (c *azureClient) toProbeError(e error, container, blob string) *probe.Error {
+	switch azureErrorCode(e) {
+	case "ContainerNotFound":
+		return probe.NewError(BucketDoesNotExist{Bucket: container})
+	case "ContainerAlreadyExists":
+		return probe.NewError(BucketExists{Bucket: container})
+	case "InvalidResourceName", "OutOfRangeInput":
+		return probe.NewError(BucketInvalid{Bucket: container})
+	case "BlobNotFound":
+		return probe.NewError(ObjectMissing{})
+	case "AuthorizationFailure", "AuthorizationPermissionMismatch":
+		return probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
+	}
+	if e == io.EOF || e == io.ErrUnexpectedEOF {
+		return probe.NewError(UnexpectedEOF{})
+	}
+	return probe.NewError(e)
+}
+
+// newAPINotImplemented - api is not supported by Azure Blob Storage.
+func newAzureAPINotImplemented(api string) *probe.Error {
+	return probe.NewError(APINotImplemented{API: api, APIType: azureAPIType})
+}
+
+// azureStringToSign returns the string a request is signed with for a
+// shared key of account.
+// Refer https://docs.microsoft.com/rest/api/storageservices/authorize-with-shared-key
+func azureStringToSign(req *http.Request, account string) string {
+	h := req.Header
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+	lines := []string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		length,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		h.Get("Date"),
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+	}
+
+	// Metadata names are set as they are, not in canonical form.
+	msHeaders := make(map[string]string)
+	var names []string
+	for name, values := range h {
+		if lname := strings.ToLower(name); strings.HasPrefix(lname, "x-ms-") && len(values) > 0 {
+			msHeaders[lname] = values[0]
+			names = append(names, lname)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, name+":"+strings.TrimSpace(msHeaders[name]))
+	}
+
+	resource := "/" + account + req.URL.EscapedPath()
+	query := make(map[string][]string)
+	for name, values := range req.URL.Query() {
+		name = strings.ToLower(name)
+		query[name] = append(query[name], values...)
+	}
+	names = names[:0]
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		resource += "\n" + name + ":" + strings.Join(values, ",")
+	}
+	return strings.Join(append(lines, resource), "\n")
+}
+
+// sign adds the shared key authorization to req.
+func (c *azureClient) sign(req *http.Request) {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(azureStringToSign(req, c.account)))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	req.Header.Set("Authorization", "SharedKey "+c.account+":"+signature)
+}
+
+// resourceURL returns the URL of blob in container, of container when
+// blob is empty and of the account when both are.
+func (c *azureClient) resourceURL(container, blob string) *url.URL {
+	p := "/"
+	if container != "" {
+		p += container
+		if blob != "" {
+			p += "/" + blob
+		}
+	}
+	return &url.URL{Scheme: c.targetURL.Scheme, Host: c.targetURL.Host, Path: p}
+}
+
+// do sends a signed request on blob of container and returns its
+// response, or the error answered by the Blob service.
+func (c *azureClient) do(ctx context.Context, method, container, blob string, query url.Values, header http.Header, body io.Reader, length int64) (*http.Response, error) {
+	u := c.resourceURL(container, blob)
+	u.RawQuery = query.Encode()
+	if body == nil {
+		body = http.NoBody
+	}
+	req, e := http.NewRequest(method, u.String(), body)
+	if e != nil {
+		return nil, e
+	}
+	req = req.WithContext(ctx)
+	req.ContentLength = length
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("x-ms-date", UTCNow().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	c.sign(req)
+
+	resp, e := c.httpClient.Do(req)
+	if e != nil {
+		return nil, e
+	}
+	if resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	errResp := azureError{StatusCode: resp.StatusCode}
+	if data, e := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024)); e == nil && len(data) > 0 {
+		xml.Unmarshal(data, &errResp)
+	}
+	if errResp.Code == "" {
+		// Answers to HEAD requests have no body.
+		errResp.Code = resp.Header.Get("x-ms-error-code")
+	}
+	if errResp.Code == "" {
+		errResp.Code = resp.Status
+	}
+	return nil, errResp
+}
+
+// discard closes resp after reading what is left of its body, for its
+// connection to be reused.
+func discard(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// url2BucketAndObject gives the container and the blob name of the URL.
+func (c *azureClient) url2BucketAndObject() (container, blob string) {
+	tokens := splitStr(c.targetURL.Path, string(c.targetURL.Separator), 3)
+	return tokens[1], tokens[2]
+}
+
+// splitPath splits path into container and blob name.
+func (c *azureClient) splitPath(path string) (container, blob string) {
+	path = strings.TrimPrefix(path, string(c.targetURL.Separator))
+	tokens := splitStr(path, string(c.targetURL.Separator), 2)
+	return tokens[0], tokens[1]
+}
+
+// joinPath joins container and blob names into the path of a URL.
+func (c *azureClient) joinPath(container string, blobs ...string) string {
+	p := string(c.targetURL.Separator) + container
+	for _, b := range blobs {
+		p += string(c.targetURL.Separator) + b
+	}
+	return p
+}
+
+// GetURL returns the URL of the client.
+func (c *azureClient) GetURL() clientURL {
+	return *c.targetURL
+}
+
+// AddUserAgent sets the user agent of requests.
+func (c *azureClient) AddUserAgent(app, version string) {
+	c.userAgent = app + "/" + version
+}
+
+/// Listing.
+
+type azureProperties struct {
+	LastModified  string `xml:"Last-Modified"`
+	Etag          string `xml:"Etag"`
+	ContentLength int64  `xml:"Content-Length"`
+	AccessTier    string `xml:"AccessTier"`
+}
+
+type azureMetadata struct {
+	Items []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+type azureBlob struct {
+	Name       string          `xml:"Name"`
+	Properties azureProperties `xml:"Properties"`
+	Metadata   azureMetadata   `xml:"Metadata"`
+}
+
+// azureListResult - answer of the List Containers and List Blobs calls.
+type azureListResult struct {
+	Containers []struct {
+		Name       string          `xml:"Name"`
+		Properties azureProperties `xml:"Properties"`
+	} `xml:"Containers>Container"`
+	Blobs    []azureBlob `xml:"Blobs>Blob"`
+	Prefixes []struct {
+		Name string `xml:"Name"`
+	} `xml:"Blobs>BlobPrefix"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// list returns a page of the blobs of container, or of the containers
+// of the account when container is empty.
+func (c *azureClient) list(container, prefix, delimiter, marker string, maxResults int) (*azureListResult, error) {
+	query := url.Values{"comp": {"list"}}
+	if container != "" {
+		query.Set("restype", "container")
+		query.Set("include", "metadata")
+		if delimiter != "" {
+			query.Set("delimiter", delimiter)
+		}
+	}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	if marker != "" {
+		query.Set("marker", marker)
+	}
+	if maxResults > 0 {
+		query.Set("maxresults", strconv.Itoa(maxResults))
+	}
+	resp, e := c.do(context.Background(), http.MethodGet, container, "", query, nil, nil, 0)
+	if e != nil {
+		return nil, e
+	}
+	defer discard(resp)
+	result := &azureListResult{}
+	if e = xml.NewDecoder(resp.Body).Decode(result); e != nil {
+		return nil, e
+	}
+	return result, nil
+}
+
+func parseAzureTime(value string) time.Time {
+	t, _ := time.Parse(http.TimeFormat, value)
+	return t
+}
+
+// blobContent returns the content of a listed blob of container.
+func (c *azureClient) blobContent(container string, blob azureBlob) *clientContent {
+	u := *c.targetURL
+	u.Path = c.joinPath(container, blob.Name)
+	content := &clientContent{
+		URL:          u,
+		Time:         parseAzureTime(blob.Properties.LastModified),
+		Size:         blob.Properties.ContentLength,
+		Type:         os.FileMode(0664),
+		StorageClass: blob.Properties.AccessTier,
+		ETag:         strings.Trim(blob.Properties.Etag, "\""),
+		Metadata:     map[string]string{},
+		UserMetadata: map[string]string{},
+	}
+	for _, item := range blob.Metadata.Items {
+		content.UserMetadata[decodeAzureMetaName(item.XMLName.Local)] = item.Value
+	}
+	return content
+}
+
+// prefixContent returns the content of a listed prefix of container.
+func (c *azureClient) prefixContent(container, prefix string) *clientContent {
+	u := *c.targetURL
+	u.Path = c.joinPath(container, prefix)
+	return &clientContent{URL: u, Time: time.Now(), Type: os.ModeDir, Metadata: map[string]string{}}
+}
+
+// listBlobs sends the blobs of container starting with prefix to
+// contentCh, the prefixes up to the next delimiter as directories when
+// delimiter is set. Returns false when listing failed.
+func (c *azureClient) listBlobs(contentCh chan *clientContent, container, prefix, delimiter string) bool {
+	marker := ""
+	for {
+		result, e := c.list(container, prefix, delimiter, marker, 0)
+		if e != nil {
+			contentCh <- &clientContent{Err: c.toProbeError(e, container, "")}
+			return false
+		}
+		contents := make([]*clientContent, 0, len(result.Blobs)+len(result.Prefixes))
+		for _, blob := range result.Blobs {
+			contents = append(contents, c.blobContent(container, blob))
+		}
+		for _, p := range result.Prefixes {
+			// Avoid sending the directory being listed.
+			if p.Name != prefix {
+				contents = append(contents, c.prefixContent(container, p.Name))
+			}
+		}
+		// Prefixes are listed apart from blobs.
+		sort.SliceStable(contents, func(i, j int) bool {
+			return contents[i].URL.Path < contents[j].URL.Path
+		})
+		for _, content := range contents {
+			contentCh <- content
+		}
+		if result.NextMarker == "" {
+			return true
+		}
+		marker = result.NextMarker
+	}
+}
+
+// listContainers returns all containers of the account.
+func (c *azureClient) listContainers() ([]*clientContent, *probe.Error) {
+	var contents []*clientContent
+	marker := ""
+	for {
+		result, e := c.list("", "", "", marker, 0)
+		if e != nil {
+			return nil, c.toProbeError(e, "", "")
+		}
+		for _, container := range result.Containers {
+			u := *c.targetURL
+			u.Path = c.joinPath(container.Name)
+			contents = append(contents, &clientContent{
+				URL:  u,
+				Time: parseAzureTime(container.Properties.LastModified),
+				Type: os.ModeDir,
+			})
+		}
+		if result.NextMarker == "" {
+			return contents, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// List - list containers, or the blobs of a container. Blobs are never
+// incomplete, uncommitted blocks are not listed.
+func (c *azureClient) List(isRecursive, isIncomplete, isFetchMeta bool, showDir DirOpt) <-chan *clientContent {
+	contentCh := make(chan *clientContent)
+	go func() {
+		defer close(contentCh)
+		if isIncomplete {
+			return
+		}
+		container, prefix := c.url2BucketAndObject()
+		delimiter := string(c.targetURL.Separator)
+		if isRecursive {
+			delimiter = ""
+		}
+		switch {
+		case container == "":
+			containers, err := c.listContainers()
+			if err != nil {
+				contentCh <- &clientContent{Err: err}
+				return
+			}
+			for _, content := range containers {
+				if !isRecursive {
+					contentCh <- content
+					continue
+				}
+				name, _ := c.splitPath(content.URL.Path)
+				if !c.listBlobs(contentCh, name, "", "") {
+					return
+				}
+			}
+		case prefix == "" && !isRecursive && !strings.HasSuffix(c.targetURL.Path, string(c.targetURL.Separator)):
+			content, err := c.containerStat(container)
+			if err != nil {
+				contentCh <- &clientContent{Err: err.Trace(container)}
+				return
+			}
+			contentCh <- content
+		default:
+			c.listBlobs(contentCh, container, prefix, delimiter)
+		}
+	}()
+	return contentCh
+}
+
+// ListPage - list up to maxKeys blobs following the page which returned
+// token.
+func (c *azureClient) ListPage(isRecursive bool, maxKeys int, token string) ([]*clientContent, string, *probe.Error) {
+	container, prefix := c.url2BucketAndObject()
+	if container == "" {
+		return nil, "", probe.NewError(BucketNameEmpty{})
+	}
+	delimiter := string(c.targetURL.Separator)
+	if isRecursive {
+		delimiter = ""
+	}
+	result, e := c.list(container, prefix, delimiter, token, maxKeys)
+	if e != nil {
+		if azureErrorCode(e) == "OutOfRangeInput" && token != "" {
+			return nil, "", probe.NewError(ContinuationTokenInvalid{Token: token})
+		}
+		return nil, "", c.toProbeError(e, container, "")
+	}
+	contents := make([]*clientContent, 0, len(result.Blobs)+len(result.Prefixes))
+	for _, blob := range result.Blobs {
+		contents = append(contents, c.blobContent(container, blob))
+	}
+	for _, p := range result.Prefixes {
+		contents = append(contents, c.prefixContent(container, p.Name))
+	}
+	sort.SliceStable(contents, func(i, j int) bool {
+		return contents[i].URL.Path < contents[j].URL.Path
+	})
+	return contents, result.NextMarker, nil
+}
+
+/// Stat.
+
+// containerStat returns the content of container.
+func (c *azureClient) containerStat(container string) (*clientContent, *probe.Error) {
+	query := url.Values{"restype": {"container"}}
+	resp, e := c.do(context.Background(), http.MethodHead, container, "", query, nil, nil, 0)
+	if e != nil {
+		return nil, c.toProbeError(e, container, "")
+	}
+	discard(resp)
+	u := *c.targetURL
+	u.Path = c.joinPath(container)
+	return &clientContent{URL: u, Time: parseAzureTime(resp.Header.Get("Last-Modified")), Type: os.ModeDir}, nil
+}
+
+// Content headers of blobs, set on uploads as x-ms-blob-<name>.
+var azureContentHeaders = []string{"Content-Type", "Cache-Control", "Content-Encoding", "Content-Disposition", "Content-Language"}
+
+// headerContent returns the content of the blob of the URL from the
+// headers of a response. Header names are in canonical form, the names
+// of user metadata are listed with their case by userMetadata.
+func (c *azureClient) headerContent(header http.Header) *clientContent {
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	content := &clientContent{
+		URL:          *c.targetURL,
+		Time:         parseAzureTime(header.Get("Last-Modified")),
+		Size:         size,
+		Type:         os.FileMode(0664),
+		StorageClass: header.Get("x-ms-access-tier"),
+		ETag:         strings.Trim(header.Get("ETag"), "\""),
+		Metadata:     map[string]string{},
+		UserMetadata: map[string]string{},
+	}
+	for _, name := range azureContentHeaders {
+		if value := header.Get(name); value != "" {
+			content.Metadata[name] = value
+		}
+	}
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ms-meta-") {
+			content.UserMetadata[decodeAzureMetaName(name[len("x-ms-meta-"):])] = header.Get(name)
+		}
+	}
+	return content
+}
+
+// userMetadata returns the user metadata of blob with the case of its
+// names, which only listings keep.
+func (c *azureClient) userMetadata(container, blob string) (map[string]string, bool) {
+	result, e := c.list(container, blob, "", "", 1)
+	if e != nil || len(result.Blobs) == 0 || result.Blobs[0].Name != blob {
+		return nil, false
+	}
+	return c.blobContent(container, result.Blobs[0]).UserMetadata, true
+}
+
+// isPrefix tells if prefix is followed by blobs in container.
+func (c *azureClient) isPrefix(container, prefix string) (bool, error) {
+	result, e := c.list(container, prefix, "", "", 1)
+	if e != nil {
+		return false, e
+	}
+	return len(result.Blobs) > 0, nil
+}
+
+// Stat - get the properties of a container, a blob, or of a prefix of
+// blobs which is reported as a directory.
+func (c *azureClient) Stat(isIncomplete, isFetchMeta, isPreserve bool, sse encrypt.ServerSide) (*clientContent, *probe.Error) {
+	container, blob := c.url2BucketAndObject()
+	if container == "" {
+		return nil, probe.NewError(BucketNameEmpty{})
+	}
+	if blob == "" {
+		return c.containerStat(container)
+	}
+	if isIncomplete {
+		return nil, probe.NewError(ObjectMissing{})
+	}
+
+	dir := &clientContent{URL: *c.targetURL, Type: os.ModeDir, Metadata: map[string]string{}}
+	separator := string(c.targetURL.Separator)
+	if !strings.HasSuffix(blob, separator) {
+		resp, e := c.do(context.Background(), http.MethodHead, container, blob, nil, nil, nil, 0)
+		if e == nil {
+			discard(resp)
+			content := c.headerContent(resp.Header)
+			if len(content.UserMetadata) > 0 {
+				if metadata, ok := c.userMetadata(container, blob); ok {
+					content.UserMetadata = metadata
+				}
+			}
+			return content, nil
+		}
+		if azureErrorCode(e) != "BlobNotFound" {
+			return nil, c.toProbeError(e, container, blob)
+		}
+		blob += separator
+	}
+	ok, e := c.isPrefix(container, blob)
+	if e != nil {
+		return nil, c.toProbeError(e, container, blob)
+	}
+	if !ok {
+		return nil, probe.NewError(ObjectMissing{})
+	}
+	return dir, nil
+}
+
+/// Downloads.
+
+// Get - get a reader of the blob.
+func (c *azureClient) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	return c.GetRange(0, -1, sse)
+}
+
+// GetRange - get a reader for length bytes of the blob from offset, up
+// to its end if length is negative.
+func (c *azureClient) GetRange(offset, length int64, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	container, blob := c.url2BucketAndObject()
+	method := http.MethodGet
+	header := make(http.Header)
+	switch {
+	case length == 0:
+		// No range of zero bytes, fail on missing blobs.
+		method = http.MethodHead
+	case length > 0:
+		header.Set("x-ms-range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		header.Set("x-ms-range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, e := c.do(context.Background(), method, container, blob, nil, header, nil, 0)
+	if e != nil {
+		return nil, c.toProbeError(e, container, blob)
+	}
+	if method == http.MethodHead {
+		discard(resp)
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	return resp.Body, nil
+}
+
+/// Uploads.
+
+// azureEncodedMetaPrefix starts the metadata names stored hex encoded,
+// see encodeAzureMetaName.
+const azureEncodedMetaPrefix = "mcx_"
+
+// isAzureIdentifier tells if name follows the rules of C# identifiers,
+// which Azure requires metadata names to follow.
+func isAzureIdentifier(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case i > 0 && '0' <= r && r <= '9':
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// encodeAzureMetaName returns the name metadata is stored with on Azure.
+// Hyphens, which S3 metadata names and those of mc use, are replaced by
+// underscores. Names which are not identifiers then, or which already
+// have underscores, are hex encoded after azureEncodedMetaPrefix.
+func encodeAzureMetaName(name string) string {
+	encoded := strings.Replace(name, "-", "_", -1)
+	if isAzureIdentifier(encoded) && !strings.Contains(name, "_") &&
+		!strings.HasPrefix(strings.ToLower(encoded), azureEncodedMetaPrefix) {
+		return encoded
+	}
+	return azureEncodedMetaPrefix + hex.EncodeToString([]byte(name))
+}
+
+// decodeAzureMetaName returns the name of metadata stored on Azure with
+// name by encodeAzureMetaName. Underscores of names set by other clients
+// are read as hyphens too.
+func decodeAzureMetaName(name string) string {
+	if strings.HasPrefix(strings.ToLower(name), azureEncodedMetaPrefix) {
+		if decoded, e := hex.DecodeString(name[len(azureEncodedMetaPrefix):]); e == nil {
+			return string(decoded)
+		}
+	}
+	return strings.Replace(name, "_", "-", -1)
+}
+
+// blobHeaders returns the headers of an upload with metadata, metadata
+// keys used by mc are dropped from it. User metadata names keep their
+// case, which Azure stores, and are encoded by encodeAzureMetaName.
+func blobHeaders(metadata map[string]string) http.Header {
+	header := make(http.Header)
+	for _, name := range azureContentHeaders {
+		if value, ok := metadata[name]; ok {
+			delete(metadata, name)
+			header.Set("x-ms-blob-"+strings.ToLower(name), value)
+		}
+	}
+	if value, ok := metadata[ifMatchMetaKey]; ok {
+		delete(metadata, ifMatchMetaKey)
+		header.Set("If-Match", value)
+	}
+	for name, value := range metadata {
+		lname := strings.ToLower(name)
+		switch {
+		case strings.HasPrefix(lname, "x-amz-meta-"):
+			header["x-ms-meta-"+encodeAzureMetaName(name[len("x-amz-meta-"):])] = []string{value}
+		case strings.HasPrefix(lname, "x-amz-"), strings.HasPrefix(lname, "x-mc-"):
+			// Storage classes, object locks and mc settings have no
+			// Azure counterpart.
+		default:
+			header["x-ms-meta-"+encodeAzureMetaName(name)] = []string{value}
+		}
+	}
+	return header
+}
+
+// Put - upload a blob. Blobs smaller than the multipart threshold are
+// uploaded at once, others as blocks committed once all are uploaded.
+func (c *azureClient) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide) (int64, *probe.Error) {
+	container, blob := c.url2BucketAndObject()
+	if container == "" {
+		return 0, probe.NewError(BucketNameEmpty{})
+	}
+	if sse != nil {
+		return 0, newAzureAPINotImplemented("Put with server side encryption")
+	}
+
+	threshold := int64(defaultMultipartThreshold)
+	if value, ok := metadata[multipartThresholdMetaKey]; ok {
+		if v, e := strconv.ParseInt(value, 10, 64); e == nil {
+			threshold = v
+		}
+	}
+	blockSize := int64(azureStreamBlockSize)
+	if value, ok := metadata[streamPartSizeMetaKey]; ok {
+		if v, e := strconv.ParseInt(value, 10, 64); e == nil && v >= minPartSize {
+			blockSize = v
+		}
+	}
+	ifMatch := metadata[ifMatchMetaKey]
+	header := blobHeaders(metadata)
+
+	var n int64
+	var e error
+	partSize := int64(putPartSize(size, threshold))
+	if size >= 0 && partSize > size {
+		n, e = c.putBlob(ctx, container, blob, reader, size, header, progress)
+	} else {
+		if size >= 0 {
+			blockSize = partSize
+		}
+		// Left uncommitted when cancelled, to continue on resume.
+		parts, _ := ctx.Value(uploadPartsContextKey{}).(*uploadParts)
+		n, e = c.putBlocks(ctx, container, blob, reader, size, blockSize, header, progress, parts)
+	}
+	if e != nil {
+		if e == io.EOF || e == io.ErrUnexpectedEOF {
+			return n, probe.NewError(UnexpectedEOF{
+				TotalSize:    size,
+				TotalWritten: n,
+			})
+		}
+		if azureErrorCode(e) == "ConditionNotMet" {
+			return n, probe.NewError(PreconditionFailed{
+				Object: blob,
+				ETag:   ifMatch,
+			})
+		}
+		return n, c.toProbeError(e, container, blob)
+	}
+	return n, nil
+}
+
+// putBlob uploads size bytes of reader as a blob in a single request.
+func (c *azureClient) putBlob(ctx context.Context, container, blob string, reader io.Reader, size int64, header http.Header, progress io.Reader) (int64, error) {
+	header.Set("x-ms-blob-type", "BlockBlob")
+	resp, e := c.do(ctx, http.MethodPut, container, blob, nil, header, io.LimitReader(reader, size), size)
+	if e != nil {
+		return 0, e
+	}
+	discard(resp)
+	if progress != nil {
+		io.CopyN(ioutil.Discard, progress, size)
+	}
+	return size, nil
+}
+
+// exactReader reads left bytes of reader, reporting when reader ends
+// before them.
+type exactReader struct {
+	reader io.Reader
+	left   int64
+	short  bool
+}
+
+func (r *exactReader) Read(p []byte) (int, error) {
+	if r.left <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.left {
+		p = p[:r.left]
+	}
+	n, e := r.reader.Read(p)
+	r.left -= int64(n)
+	if e == io.EOF && r.left > 0 {
+		r.short = true
+		e = io.ErrUnexpectedEOF
+	}
+	return n, e
+}
+
+// azureBlockID returns the ID of the block number of an upload.
+func azureBlockID(uploadID string, number int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%06d", uploadID, number)))
+}
+
+// azureBlockList - answer of the Get Block List call.
+type azureBlockList struct {
+	UncommittedBlocks []struct {
+		Name string `xml:"Name"`
+		Size int64  `xml:"Size"`
+	} `xml:"UncommittedBlocks>Block"`
+}
+
+// uncommittedBlocks returns the sizes of the uncommitted blocks of blob
+// by block ID.
+func (c *azureClient) uncommittedBlocks(ctx context.Context, container, blob string) (map[string]int64, error) {
+	query := url.Values{"comp": {"blocklist"}, "blocklisttype": {"uncommitted"}}
+	resp, e := c.do(ctx, http.MethodGet, container, blob, query, nil, nil, 0)
+	if e != nil {
+		if azureErrorCode(e) == "BlobNotFound" {
+			// Blobs with no blocks don't exist.
+			return map[string]int64{}, nil
+		}
+		return nil, e
+	}
+	defer discard(resp)
+	result := azureBlockList{}
+	if e = xml.NewDecoder(resp.Body).Decode(&result); e != nil {
+		return nil, e
+	}
+	blocks := make(map[string]int64, len(result.UncommittedBlocks))
+	for _, block := range result.UncommittedBlocks {
+		blocks[block.Name] = block.Size
+	}
+	return blocks, nil
+}
+
+// putBlocks uploads reader as blocks of blockSize bytes, committed into
+// the blob once all are uploaded, reading until EOF when size is
+// negative. Blocks of parts which the blob still has uncommitted with
+// the same size are skipped, the upload ID of parts prefixes block IDs.
+// Blocks of a known size are sent as they are read, only the blocks of
+// a stream are buffered to learn their length.
+func (c *azureClient) putBlocks(ctx context.Context, container, blob string, reader io.Reader, size, blockSize int64, header http.Header, progress io.Reader, parts *uploadParts) (int64, error) {
+	if parts == nil {
+		parts = &uploadParts{save: func(*uploadParts) {}}
+	}
+	blockLength := func(number int) int64 {
+		if rest := size - int64(number-1)*blockSize; rest < blockSize {
+			return rest
+		}
+		return blockSize
+	}
+
+	done := make(map[int]string)
+	if parts.UploadID != "" && parts.PartSize == blockSize && size >= 0 {
+		uploaded, e := c.uncommittedBlocks(ctx, container, blob)
+		if e != nil {
+			return 0, e
+		}
+		for _, part := range parts.Parts {
+			if length, ok := uploaded[part.ETag]; ok && length == blockLength(part.Number) {
+				done[part.Number] = part.ETag
+			}
+		}
+	} else {
+		parts.UploadID = newRandomID(azureUploadIDLen)
+		parts.PartSize = blockSize
+	}
+	parts.Parts = parts.Parts[:0]
+	for number, id := range done {
+		parts.Parts = append(parts.Parts, uploadPart{Number: number, ETag: id})
+	}
+	parts.save(parts)
+
+	var buf bytes.Buffer
+	var blockIDs []string
+	var total int64
+	for number, last := 1, false; !last && (size < 0 || total < size); number++ {
+		length := blockSize
+		if size >= 0 {
+			length = blockLength(number)
+		}
+		id, ok := done[number]
+		if ok {
+			if e := skipPart(reader, length); e != nil {
+				return total, e
+			}
+			skipProgress(progress, length)
+		} else {
+			var block io.Reader = &exactReader{reader: reader, left: length}
+			if size < 0 {
+				buf.Reset()
+				n, e := buf.ReadFrom(io.LimitReader(reader, length))
+				if e != nil {
+					return total, e
+				}
+				if n < length {
+					// The last block of a stream is short.
+					if n == 0 {
+						break
+					}
+					length, last = n, true
+				}
+				block = bytes.NewReader(buf.Bytes())
+			}
+			id = azureBlockID(parts.UploadID, number)
+			query := url.Values{"comp": {"block"}, "blockid": {id}}
+			resp, e := c.do(ctx, http.MethodPut, container, blob, query, nil, block, length)
+			if e != nil {
+				if r, ok := block.(*exactReader); ok && r.short {
+					e = io.EOF
+				}
+				return total, e
+			}
+			discard(resp)
+			parts.Parts = append(parts.Parts, uploadPart{Number: number, ETag: id})
+			parts.save(parts)
+			if progress != nil {
+				io.CopyN(ioutil.Discard, progress, length)
+			}
+		}
+		blockIDs = append(blockIDs, id)
+		total += length
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header + "<BlockList>")
+	for _, id := range blockIDs {
+		body.WriteString("<Latest>" + id + "</Latest>")
+	}
+	body.WriteString("</BlockList>")
+	query := url.Values{"comp": {"blocklist"}}
+	resp, e := c.do(ctx, http.MethodPut, container, blob, query, header, bytes.NewReader(body.Bytes()), int64(body.Len()))
+	if e != nil {
+		return total, e
+	}
+	discard(resp)
+	parts.save(nil)
+	return total, nil
+}
+
+// Copy - copy a blob of the same account on the server side, waiting
+// for the copy to complete. A copy which makes no progress for
+// azureCopyStallTimeout is aborted.
+func (c *azureClient) Copy(source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string) *probe.Error {
+	container, blob := c.url2BucketAndObject()
+	if container == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if srcSSE != nil || tgtSSE != nil {
+		return newAzureAPINotImplemented("Copy with server side encryption")
+	}
+	srcContainer, srcBlob := c.splitPath(source)
+	ifMatch := metadata[ifMatchMetaKey]
+	header := blobHeaders(metadata)
+	if ifMatch != "" {
+		// Conditions on the source of a copy.
+		header.Del("If-Match")
+		header.Set("x-ms-source-if-match", ifMatch)
+	}
+	for name := range header {
+		// Content headers are copied from the source.
+		if strings.HasPrefix(strings.ToLower(name), "x-ms-blob-") {
+			header.Del(name)
+		}
+	}
+	header.Set("x-ms-copy-source", c.resourceURL(srcContainer, srcBlob).String())
+
+	resp, e := c.do(context.Background(), http.MethodPut, container, blob, nil, header, nil, 0)
+	copyID, progressed, lastProgress := "", time.Now(), ""
+	for e == nil {
+		discard(resp)
+		status := resp.Header.Get("x-ms-copy-status")
+		if status != "pending" {
+			if status != "" && status != "success" {
+				e = errors.New("copy " + status + ": " + resp.Header.Get("x-ms-copy-status-description"))
+			}
+			break
+		}
+		if copyID == "" {
+			copyID = resp.Header.Get("x-ms-copy-id")
+		}
+		if p := resp.Header.Get("x-ms-copy-progress"); p != lastProgress {
+			progressed, lastProgress = time.Now(), p
+		} else if time.Since(progressed) > azureCopyStallTimeout {
+			query := url.Values{"comp": {"copy"}, "copyid": {copyID}}
+			abort := http.Header{"x-ms-copy-action": {"abort"}}
+			if resp, e := c.do(context.Background(), http.MethodPut, container, blob, query, abort, nil, 0); e == nil {
+				discard(resp)
+			}
+			return probe.NewError(fmt.Errorf("copy of `%s` made no progress for %s, aborted", source, azureCopyStallTimeout))
+		}
+		// The copy continues on the server, mc stops waiting for it
+		// when interrupted.
+		select {
+		case <-time.After(azureCopyPollInterval):
+		case <-globalContext.Done():
+			return probe.NewError(globalContext.Err())
+		}
+		resp, e = c.do(context.Background(), http.MethodHead, container, blob, nil, nil, nil, 0)
+	}
+	if e != nil {
+		if azureErrorCode(e) == "SourceConditionNotMet" {
+			return probe.NewError(PreconditionFailed{Object: srcBlob, ETag: ifMatch})
+		}
+		if azureErrorCode(e) == "CannotVerifyCopySource" {
+			return probe.NewError(ObjectMissing{})
+		}
+		return c.toProbeError(e, container, blob)
+	}
+	if progress != nil {
+		io.CopyN(ioutil.Discard, progress, size)
+	}
+	return nil
+}
+
+/// Containers.
+
+// MakeBucket - create a container. Blob names have no directories to
+// create.
+func (c *azureClient) MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error {
+	container, blob := c.url2BucketAndObject()
+	if container == "" {
+		return probe.NewError(BucketNameEmpty{})
+	}
+	if blob != "" {
+		return newAzureAPINotImplemented("MakeBucket of prefixes")
+	}
+	if withLock {
+		return newAzureAPINotImplemented("MakeBucket with object lock")
+	}
+	query := url.Values{"restype": {"container"}}
+	resp, e := c.do(context.Background(), http.MethodPut, container, "", query, nil, nil, 0)
+	if e != nil {
+		if ignoreExisting && azureErrorCode(e) == "ContainerAlreadyExists" {
+			return nil
+		}
+		return c.toProbeError(e, container, "")
+	}
+	discard(resp)
+	return nil
+}
+
+// Remove - remove blobs, and their containers when isRemoveBucket is
+// set. Blobs are never incomplete.
+func (c *azureClient) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *clientContent) <-chan *probe.Error {
+	errorCh := make(chan *probe.Error)
+	go func() {
+		defer close(errorCh)
+		if isRemoveBucket {
+			if _, blob := c.url2BucketAndObject(); blob != "" {
+				errorCh <- probe.NewError(errors.New("cannot delete prefixes with `mc rb` command - Use `mc rm` instead"))
+				return
+			}
+		}
+		removeContainer := func(container string) {
+			if container == "" || !isRemoveBucket || isIncomplete {
+				return
+			}
+			query := url.Values{"restype": {"container"}}
+			resp, e := c.do(context.Background(), http.MethodDelete, container, "", query, nil, nil, 0)
+			if e != nil {
+				errorCh <- c.toProbeError(e, container, "")
+				return
+			}
+			discard(resp)
+		}
+
+		prevContainer := ""
+		for content := range contentCh {
+			container, blob := c.splitPath(content.URL.Path)
+			if container == "" {
+				continue
+			}
+			if container != prevContainer {
+				removeContainer(prevContainer)
+				prevContainer = container
+			}
+			if blob == "" || isIncomplete {
+				continue
+			}
+			resp, e := c.do(context.Background(), http.MethodDelete, container, blob, nil, nil, nil, 0)
+			if e != nil {
+				if azureErrorCode(e) == "BlobNotFound" {
+					continue
+				}
+				errorCh <- probe.NewError(ObjectRemoveFailed{
+					Object: content.URL.Path,
+					Err:    c.toProbeError(e, container, blob).ToGoError(),
+				})
+				continue
+			}
+			discard(resp)
+		}
+		removeContainer(prevContainer)
+	}()
+	return errorCh
+}
+
+/// Not supported by Azure Blob Storage.
+
+// SetObjectLockConfig - not supported.
+func (c *azureClient) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
+	return newAzureAPINotImplemented("SetObjectLockConfig")
+}
+
+// GetObjectLockConfig - not supported.
+func (c *azureClient) GetObjectLockConfig() (*minio.RetentionMode, *uint, *minio.ValidityUnit, *probe.Error) {
+	return nil, nil, nil, newAzureAPINotImplemented("GetObjectLockConfig")
+}
+
+// GetAccess - not supported.
+func (c *azureClient) GetAccess() (string, string, *probe.Error) {
+	return "", "", newAzureAPINotImplemented("GetAccess")
+}
+
+// GetAccessRules - not supported.
+func (c *azureClient) GetAccessRules() (map[string]string, *probe.Error) {
+	return nil, newAzureAPINotImplemented("GetAccessRules")
+}
+
+// SetAccess - not supported.
+func (c *azureClient) SetAccess(access string, isJSON bool) *probe.Error {
+	return newAzureAPINotImplemented("SetAccess")
+}
+
+// Select - not supported.
+func (c *azureClient) Select(expression string, sse encrypt.ServerSide, opts SelectObjectOpts) (io.ReadCloser, *probe.Error) {
+	return nil, newAzureAPINotImplemented("Select")
+}
+
+// PutObjectRetention - not supported.
+func (c *azureClient) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error {
+	return newAzureAPINotImplemented("PutObjectRetention")
+}
+
+// PutObjectLegalHold - not supported.
+func (c *azureClient) PutObjectLegalHold(hold *minio.LegalHoldStatus) *probe.Error {
+	return newAzureAPINotImplemented("PutObjectLegalHold")
+}
+
+// ShareDownload - not supported.
+func (c *azureClient) ShareDownload(expires time.Duration) (string, *probe.Error) {
+	return "", newAzureAPINotImplemented("ShareDownload")
+}
+
+// ShareUpload - not supported.
+func (c *azureClient) ShareUpload(isRecursive bool, expires time.Duration, contentType string) (string, map[string]string, *probe.Error) {
+	return "", nil, newAzureAPINotImplemented("ShareUpload")
+}
+
+// SharePut - not supported.
+func (c *azureClient) SharePut(expires time.Duration) (string, *probe.Error) {
+	return "", newAzureAPINotImplemented("SharePut")
+}
+
+// Watch - not supported.
+func (c *azureClient) Watch(params watchParams) (*watchObject, *probe.Error) {
+	return nil, newAzureAPINotImplemented("Watch")
+}
+
+// GetObjectTagging - not supported.
+func (c *azureClient) GetObjectTagging() (tagging.Tagging, *probe.Error) {
+	return tagging.Tagging{}, newAzureAPINotImplemented("GetObjectTagging")
+}
+
+// SetObjectTagging - not supported.
+func (c *azureClient) SetObjectTagging(tagMap map[string]string) *probe.Error {
+	return newAzureAPINotImplemented("SetObjectTagging")
+}
+
+// DeleteObjectTagging - not supported.
+func (c *azureClient) DeleteObjectTagging() *probe.Error {
+	return newAzureAPINotImplemented("DeleteObjectTagging")
+}
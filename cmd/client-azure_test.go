@@ -0,0 +1,474 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+// azureIdentifierRgx - names of metadata Azure accepts.
+var azureIdentifierRgx = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// azureHandler is an in-memory Blob service of one account, verifying
+// the shared key signature of incoming requests.
+type azureHandler struct {
+	mu        sync.Mutex
+	account   string
+	key       []byte
+	container map[string]bool
+	blobs     map[string][]byte
+	headers   map[string]http.Header
+	blocks    map[string]map[string][]byte
+	blockPuts int
+}
+
+func newAzureHandler(account string, key []byte) *azureHandler {
+	return &azureHandler{
+		account:   account,
+		key:       key,
+		container: map[string]bool{},
+		blobs:     map[string][]byte{},
+		headers:   map[string]http.Header{},
+		blocks:    map[string]map[string][]byte{},
+	}
+}
+
+func (h *azureHandler) fail(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>%s</Code><Message>%s\nRequestId:1</Message></Error>", code, code)
+	}
+}
+
+func (h *azureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(azureStringToSign(r, h.account)))
+	if r.Header.Get("Authorization") != "SharedKey "+h.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+		h.fail(w, r, http.StatusForbidden, "AuthenticationFailed")
+		return
+	}
+
+	// Metadata names must be C# identifiers.
+	for k := range r.Header {
+		if strings.HasPrefix(strings.ToLower(k), "x-ms-meta-") && !azureIdentifierRgx.MatchString(k[len("x-ms-meta-"):]) {
+			h.fail(w, r, http.StatusBadRequest, "InvalidMetadata")
+			return
+		}
+	}
+
+	tokens := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	container, blob := tokens[0], ""
+	if len(tokens) > 1 {
+		blob = tokens[1]
+	}
+	name := container + "/" + blob
+	query := r.URL.Query()
+	body, e := ioutil.ReadAll(r.Body)
+	if e != nil {
+		// The client gave up on the request, nothing is stored.
+		return
+	}
+
+	if container != "" && !h.container[container] && !(r.Method == http.MethodPut && query.Get("restype") == "container") {
+		h.fail(w, r, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+	switch {
+	case query.Get("restype") == "container" && query.Get("comp") == "list":
+		h.list(w, container, query.Get("prefix"), query.Get("delimiter"))
+	case query.Get("restype") == "container" && r.Method == http.MethodPut:
+		if h.container[container] {
+			h.fail(w, r, http.StatusConflict, "ContainerAlreadyExists")
+			return
+		}
+		h.container[container] = true
+		w.WriteHeader(http.StatusCreated)
+	case query.Get("restype") == "container":
+		w.WriteHeader(http.StatusOK)
+	case query.Get("comp") == "block":
+		if h.blocks[name] == nil {
+			h.blocks[name] = map[string][]byte{}
+		}
+		h.blocks[name][query.Get("blockid")] = body
+		h.blockPuts++
+		w.WriteHeader(http.StatusCreated)
+	case query.Get("comp") == "blocklist" && r.Method == http.MethodGet:
+		if len(h.blocks[name]) == 0 {
+			h.fail(w, r, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		fmt.Fprint(w, "<BlockList><UncommittedBlocks>")
+		for id, data := range h.blocks[name] {
+			fmt.Fprintf(w, "<Block><Name>%s</Name><Size>%d</Size></Block>", id, len(data))
+		}
+		fmt.Fprint(w, "</UncommittedBlocks></BlockList>")
+	case query.Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		xml.Unmarshal(body, &list)
+		var data []byte
+		for _, id := range list.Latest {
+			block, ok := h.blocks[name][id]
+			if !ok {
+				h.fail(w, r, http.StatusBadRequest, "InvalidBlockList")
+				return
+			}
+			data = append(data, block...)
+		}
+		delete(h.blocks, name)
+		h.blobs[name] = data
+		h.headers[name] = r.Header.Clone()
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut:
+		h.blobs[name] = body
+		h.headers[name] = r.Header.Clone()
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		if _, ok := h.blobs[name]; !ok {
+			h.fail(w, r, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(h.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		data, ok := h.blobs[name]
+		if !ok {
+			h.fail(w, r, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		for k, v := range h.headers[name] {
+			if strings.HasPrefix(strings.ToLower(k), "x-ms-meta-") {
+				w.Header()[k] = v
+			}
+		}
+		w.Header().Set("Content-Type", h.headers[name].Get("x-ms-blob-content-type"))
+		w.Header().Set("ETag", "\"0x1\"")
+		w.Header().Set("Last-Modified", "Mon, 12 Oct 2026 10:00:00 GMT")
+		status := http.StatusOK
+		if rng := r.Header.Get("x-ms-range"); rng != "" {
+			var start, end int
+			if n, _ := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); n == 1 {
+				end = len(data) - 1
+			}
+			data = data[start : end+1]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	}
+}
+
+func (h *azureHandler) list(w http.ResponseWriter, container, prefix, delimiter string) {
+	var names []string
+	for name := range h.blobs {
+		if strings.HasPrefix(name, container+"/"+prefix) {
+			names = append(names, strings.TrimPrefix(name, container+"/"))
+		}
+	}
+	sort.Strings(names)
+	fmt.Fprint(w, "<EnumerationResults><Blobs>")
+	prefixes := map[string]bool{}
+	for _, name := range names {
+		if i := strings.Index(name[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			p := name[:len(prefix)+i+1]
+			if !prefixes[p] {
+				prefixes[p] = true
+				fmt.Fprintf(w, "<BlobPrefix><Name>%s</Name></BlobPrefix>", p)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "<Blob><Name>%s</Name><Properties><Content-Length>%d</Content-Length></Properties><Metadata><k>v</k>",
+			name, len(h.blobs[container+"/"+name]))
+		// Listings keep the case of metadata names.
+		for k, v := range h.headers[container+"/"+name] {
+			if strings.HasPrefix(strings.ToLower(k), "x-ms-meta-") {
+				fmt.Fprintf(w, "<%s>%s</%s>", k[len("x-ms-meta-"):], v[0], k[len("x-ms-meta-"):])
+			}
+		}
+		fmt.Fprint(w, "</Metadata></Blob>")
+	}
+	fmt.Fprint(w, "</Blobs><NextMarker /></EnumerationResults>")
+}
+
+func newTestAzureClient(c *C, serverURL, path string, key []byte) Client {
+	clnt, err := azureNew(&Config{
+		HostURL:   serverURL + path,
+		AccessKey: "account",
+		SecretKey: base64.StdEncoding.EncodeToString(key),
+	})
+	c.Assert(err, IsNil)
+	return clnt
+}
+
+func (s *TestSuite) TestAzureStringToSign(c *C) {
+	req, e := http.NewRequest(http.MethodPut, "https://account.blob.core.windows.net/data/a%20b?comp=block&blockid=MQ%3D%3D", strings.NewReader("abc"))
+	c.Assert(e, IsNil)
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("x-ms-date", "Mon, 12 Oct 2026 10:00:00 GMT")
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("User-Agent", "mc")
+	c.Assert(azureStringToSign(req, "account"), Equals, "PUT\n\n\n3\n\ntext/plain\n\n\n\n\n\n\n"+
+		"x-ms-date:Mon, 12 Oct 2026 10:00:00 GMT\nx-ms-version:"+azureAPIVersion+"\n"+
+		"/account/data/a%20b\nblockid:MQ==\ncomp:block")
+
+	// Metadata headers are signed whatever the case of their names.
+	req.Header = blobHeaders(map[string]string{"X-Amz-Meta-camelCase": "yes", "plainKey": "1", ifMatchMetaKey: "etag"})
+	c.Assert(req.Header["x-ms-meta-camelCase"], DeepEquals, []string{"yes"})
+	c.Assert(req.Header["x-ms-meta-plainKey"], DeepEquals, []string{"1"})
+	c.Assert(azureStringToSign(req, "account"), Equals, "PUT\n\n\n3\n\n\n\n\netag\n\n\n\n"+
+		"x-ms-meta-camelcase:yes\nx-ms-meta-plainkey:1\n"+
+		"/account/data/a%20b\nblockid:MQ==\ncomp:block")
+}
+
+func (s *TestSuite) TestAzureObjectOperations(c *C) {
+	key := []byte("secret")
+	handler := newAzureHandler("account", key)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	c.Assert(newTestAzureClient(c, server.URL, "/data", key).MakeBucket("", false, false), IsNil)
+	err := newTestAzureClient(c, server.URL, "/data", key).MakeBucket("", false, false)
+	c.Assert(err.ToGoError(), FitsTypeOf, BucketExists{})
+	c.Assert(newTestAzureClient(c, server.URL, "/data", key).MakeBucket("", true, false), IsNil)
+
+	for _, name := range []string{"dir/a", "dir/b", "top"} {
+		data := "data of " + name
+		metadata := map[string]string{"Content-Type": "text/plain", "X-Amz-Meta-Owner": "me"}
+		n, err := newTestAzureClient(c, server.URL, "/data/"+name, key).Put(context.Background(), strings.NewReader(data), int64(len(data)), metadata, nil, nil)
+		c.Assert(err, IsNil)
+		c.Assert(n, Equals, int64(len(data)))
+	}
+
+	content, err := newTestAzureClient(c, server.URL, "/data/dir/a", key).Stat(false, false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.Size, Equals, int64(len("data of dir/a")))
+	c.Assert(content.Metadata["Content-Type"], Equals, "text/plain")
+	c.Assert(content.UserMetadata["Owner"], Equals, "me")
+
+	// Names of metadata keep their case, which headers lose.
+	handler.headers["data/dir/a"]["x-ms-meta-camelCase"] = []string{"yes"}
+	content, err = newTestAzureClient(c, server.URL, "/data/dir/a", key).Stat(false, false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.UserMetadata["camelCase"], Equals, "yes")
+	c.Assert(content.UserMetadata["Owner"], Equals, "me")
+
+	content, err = newTestAzureClient(c, server.URL, "/data/dir", key).Stat(false, false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.Type.IsDir(), Equals, true)
+
+	_, err = newTestAzureClient(c, server.URL, "/data/missing", key).Stat(false, false, false, nil)
+	c.Assert(err.ToGoError(), FitsTypeOf, ObjectMissing{})
+	_, err = newTestAzureClient(c, server.URL, "/other/a", key).Stat(false, false, false, nil)
+	c.Assert(err.ToGoError(), FitsTypeOf, BucketDoesNotExist{})
+
+	reader, err := newTestAzureClient(c, server.URL, "/data/top", key).GetRange(5, 2, nil)
+	c.Assert(err, IsNil)
+	data, _ := ioutil.ReadAll(reader)
+	c.Assert(string(data), Equals, "of")
+
+	var paths []string
+	for content := range newTestAzureClient(c, server.URL, "/data/", key).List(false, false, false, DirNone) {
+		c.Assert(content.Err, IsNil)
+		paths = append(paths, content.URL.Path)
+	}
+	c.Assert(paths, DeepEquals, []string{"/data/dir/", "/data/top"})
+
+	paths = nil
+	for content := range newTestAzureClient(c, server.URL, "/data/", key).List(true, false, false, DirNone) {
+		c.Assert(content.Err, IsNil)
+		c.Assert(content.UserMetadata["k"], Equals, "v")
+		paths = append(paths, content.URL.Path)
+	}
+	c.Assert(paths, DeepEquals, []string{"/data/dir/a", "/data/dir/b", "/data/top"})
+
+	contentCh := make(chan *clientContent, 1)
+	contentCh <- &clientContent{URL: *newClientURL(server.URL + "/data/top")}
+	close(contentCh)
+	for err := range newTestAzureClient(c, server.URL, "/data", key).Remove(false, false, contentCh) {
+		c.Assert(err, IsNil)
+	}
+	c.Assert(handler.blobs["data/top"], IsNil)
+
+	_, err = newTestAzureClient(c, server.URL, "/data", []byte("wrong")).Stat(false, false, false, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestAzureMetadataNames(c *C) {
+	key := []byte("secret")
+	handler := newAzureHandler("account", key)
+	handler.container["data"] = true
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// Names mc sets, and names which are not identifiers either once
+	// their hyphens are replaced.
+	names := []string{"Mc-Mtime", "mc-attrs", "Mc-Sha256", "Snake_case", "1st", "Mcx-Name", "dotted.name"}
+	metadata := map[string]string{
+		mtimeMetaKey:                  "2020-01-01T00:00:00Z",
+		"mc-attrs":                    "mode:0644",
+		checksumMetadataKey("sha256"): "sum",
+		"X-Amz-Meta-Snake_case":       "snake",
+		"X-Amz-Meta-1st":              "first",
+		"X-Amz-Meta-Mcx-Name":         "prefixed",
+		"X-Amz-Meta-dotted.name":      "dotted",
+	}
+	values := map[string]string{}
+	for name, value := range metadata {
+		values[strings.TrimPrefix(name, "X-Amz-Meta-")] = value
+	}
+	_, err := newTestAzureClient(c, server.URL, "/data/a", key).Put(context.Background(), strings.NewReader("a"), 1, metadata, nil, nil)
+	c.Assert(err, IsNil)
+
+	// The handler stores names in canonical form, which Azure does not.
+	lookup := func(metadata map[string]string, name string) string {
+		for k, v := range metadata {
+			if strings.EqualFold(k, name) {
+				return v
+			}
+		}
+		return ""
+	}
+	content, err := newTestAzureClient(c, server.URL, "/data/a", key).Stat(false, false, false, nil)
+	c.Assert(err, IsNil)
+	for _, name := range names {
+		c.Assert(lookup(content.UserMetadata, name), Equals, values[name], Commentf("stat of %s", name))
+	}
+	for content := range newTestAzureClient(c, server.URL, "/data/", key).List(true, false, false, DirNone) {
+		c.Assert(content.Err, IsNil)
+		for _, name := range names {
+			c.Assert(lookup(content.UserMetadata, name), Equals, values[name], Commentf("listing of %s", name))
+		}
+	}
+
+	// Server side copies set them the same way.
+	err = newTestAzureClient(c, server.URL, "/data/b", key).Copy("/data/a", 1, nil, nil, nil,
+		map[string]string{mtimeMetaKey: "2020-01-01T00:00:00Z", "mc-attrs": "mode:0644"})
+	c.Assert(err, IsNil)
+	c.Assert(handler.headers["data/b"].Get("x-ms-meta-Mc_Mtime"), Equals, "2020-01-01T00:00:00Z")
+	c.Assert(handler.headers["data/b"].Get("x-ms-meta-mc_attrs"), Equals, "mode:0644")
+}
+
+// failingReader fails once its reader is read to the end.
+type failingReader struct {
+	io.Reader
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	n, e := r.Reader.Read(p)
+	if e == io.EOF {
+		e = errors.New("connection reset")
+	}
+	return n, e
+}
+
+func (s *TestSuite) TestAzureBlockUploadResume(c *C) {
+	key := []byte("secret")
+	handler := newAzureHandler("account", key)
+	handler.container["data"] = true
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 12*1024*1024/16)
+	size := int64(len(data))
+	metadata := func() map[string]string {
+		return map[string]string{multipartThresholdMetaKey: strconv.Itoa(10 * 1024 * 1024)}
+	}
+	parts := &uploadParts{save: func(*uploadParts) {}}
+	ctx := context.WithValue(context.Background(), uploadPartsContextKey{}, parts)
+
+	// Upload of the second block of 5MiB fails.
+	clnt := newTestAzureClient(c, server.URL, "/data/big", key)
+	_, err := clnt.Put(ctx, failingReader{bytes.NewReader(data[:7*1024*1024])}, size, metadata(), nil, nil)
+	c.Assert(err, NotNil)
+	c.Assert(handler.blockPuts, Equals, 1)
+	c.Assert(parts.Parts, HasLen, 1)
+
+	// Only the missing blocks are uploaded on resume.
+	n, err := clnt.Put(ctx, bytes.NewReader(data), size, metadata(), nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, size)
+	c.Assert(handler.blockPuts, Equals, 3)
+	c.Assert(bytes.Equal(handler.blobs["data/big"], data), Equals, true)
+
+	// Streams of unknown size are uploaded as blocks as well.
+	n, err = newTestAzureClient(c, server.URL, "/data/stream", key).Put(context.Background(), bytes.NewReader(data), -1, map[string]string{}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, size)
+	c.Assert(bytes.Equal(handler.blobs["data/stream"], data), Equals, true)
+}
+
+func (s *TestSuite) TestAzureCopyStalled(c *C) {
+	defer func(interval, timeout time.Duration) {
+		azureCopyPollInterval, azureCopyStallTimeout = interval, timeout
+	}(azureCopyPollInterval, azureCopyStallTimeout)
+	azureCopyPollInterval, azureCopyStallTimeout = time.Millisecond, 20*time.Millisecond
+
+	var mu sync.Mutex
+	progress, aborted := 0, false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Query().Get("comp") == "copy" && r.Header.Get("x-ms-copy-action") == "abort" {
+			aborted = true
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("x-ms-copy-id", "copy-1")
+		w.Header().Set("x-ms-copy-status", "pending")
+		w.Header().Set("x-ms-copy-progress", fmt.Sprintf("%d/100", progress))
+		if progress < 50 {
+			progress += 10
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	// Waited for while it copies, aborted once it stops.
+	err := newTestAzureClient(c, server.URL, "/data/copy", []byte("secret")).Copy("/data/source", 100, nil, nil, nil, map[string]string{})
+	c.Assert(err, NotNil)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(progress, Equals, 50)
+	c.Assert(aborted, Equals, true)
+}
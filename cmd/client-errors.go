@@ -16,7 +16,11 @@
 
 package cmd
 
-import "fmt"
+import (
+	"fmt"
+
+	humanize "github.com/dustin/go-humanize"
+)
 
 /// Collection of standard errors
 
@@ -42,11 +46,45 @@ func (e BucketDoesNotExist) Error() string {
 	return "Bucket `" + e.Bucket + "` does not exist."
 }
 
-// BucketExists - bucket exists.
+// EncryptionRequired - the policy of the bucket denies uploads without
+// server side encryption.
+type EncryptionRequired GenericBucketError
+
+func (e EncryptionRequired) Error() string {
+	return "Bucket `" + e.Bucket + "` only accepts uploads with server side encryption, use --encrypt or --auto-sse."
+}
+
+// BucketExists - bucket exists and is owned by the account.
 type BucketExists GenericBucketError
 
 func (e BucketExists) Error() string {
-	return "Bucket `" + e.Bucket + "` exists."
+	return "Bucket `" + e.Bucket + "` already exists and is owned by you."
+}
+
+// BucketNotAccessible - bucket exists and access to it is denied.
+type BucketNotAccessible GenericBucketError
+
+func (e BucketNotAccessible) Error() string {
+	return "Bucket `" + e.Bucket + "` already exists and cannot be accessed, either another account owns it or your policy denies listing it."
+}
+
+// DualStackNotSupported - dual-stack endpoint cannot be used for host.
+type DualStackNotSupported struct {
+	Host   string
+	Reason string
+}
+
+func (e DualStackNotSupported) Error() string {
+	return "Dual-stack endpoint is not available for `" + e.Host + "`, " + e.Reason + "."
+}
+
+// BindAddrNotLocal - address to bind connections to is not a local one.
+type BindAddrNotLocal struct {
+	Addr string
+}
+
+func (e BindAddrNotLocal) Error() string {
+	return "`" + e.Addr + "` is neither an address of this host nor a network interface."
 }
 
 // BucketNameEmpty - bucket name empty (http://goo.gl/wJlzDz)
@@ -90,6 +128,46 @@ func (e ObjectAlreadyExistsAsDirectory) Error() string {
 	return "Object `" + e.Object + "` already exists as directory."
 }
 
+// KeyTooLong - object name or path is longer than the target allows.
+type KeyTooLong struct {
+	Key    string
+	Length int
+	Max    int
+}
+
+func (e KeyTooLong) Error() string {
+	return fmt.Sprintf("`%s` is %d bytes long, the target allows at most %d bytes", e.Key, e.Length, e.Max)
+}
+
+// PreconditionFailed - target object changed since its ETag was read.
+type PreconditionFailed struct {
+	Object string
+	ETag   string
+}
+
+func (e PreconditionFailed) Error() string {
+	return "Object `" + e.Object + "` was modified, its ETag no longer matches `" + e.ETag + "`."
+}
+
+// UploadNotFound - multipart upload is no longer known to the target,
+// typically because the server restarted while the upload was running.
+type UploadNotFound struct {
+	Object string
+}
+
+func (e UploadNotFound) Error() string {
+	return "Multipart upload of `" + e.Object + "` no longer exists on the target."
+}
+
+// ContinuationTokenInvalid - a listing cannot continue from this token.
+type ContinuationTokenInvalid struct {
+	Token string
+}
+
+func (e ContinuationTokenInvalid) Error() string {
+	return "Continuation token `" + e.Token + "` does not belong to this listing."
+}
+
 // ObjectOnGlacier - object is of storage class glacier.
 type ObjectOnGlacier struct {
 	Object string
@@ -160,6 +238,26 @@ func (e ObjectMissing) Error() string {
 	return "Object does not exist"
 }
 
+// ObjectKeyRequired - object encrypted with a customer provided key read
+// without the key.
+type ObjectKeyRequired struct {
+	Path string
+}
+
+func (e ObjectKeyRequired) Error() string {
+	return "Object `" + e.Path + "` is encrypted with a customer provided key, pass the key with --encrypt-key or MC_ENCRYPT_KEY"
+}
+
+// ObjectRemoveFailed - an object of a batch remove was not removed.
+type ObjectRemoveFailed struct {
+	Object string
+	Err    error
+}
+
+func (e ObjectRemoveFailed) Error() string {
+	return "Unable to remove `" + e.Object + "`: " + e.Err.Error()
+}
+
 // UnexpectedShortWrite - write wrote less bytes than expected.
 type UnexpectedShortWrite struct {
 	InputSize int
@@ -182,6 +280,48 @@ func (e UnexpectedEOF) Error() string {
 	return msg
 }
 
+// SourceTruncated - an appended source is now smaller than its target,
+// it was most likely truncated or rotated.
+type SourceTruncated struct {
+	Path       string
+	Size       int64
+	TargetSize int64
+}
+
+func (e SourceTruncated) Error() string {
+	return fmt.Sprintf("Source has %d bytes but `%s` already has %d, it was truncated or rotated.", e.Size, e.Path, e.TargetSize)
+}
+
+// SourceRotated - an appended source is another file than the one the
+// target was appended from, it was rotated.
+type SourceRotated struct {
+	Path string
+}
+
+func (e SourceRotated) Error() string {
+	return fmt.Sprintf("`%s` was appended from another file, the source was rotated.", e.Path)
+}
+
+// TarMemberOutside - a member of a tar archive named to be extracted
+// outside of the target.
+type TarMemberOutside struct {
+	Name string
+}
+
+func (e TarMemberOutside) Error() string {
+	return "Member `" + e.Name + "` would be extracted outside of the target."
+}
+
+// TarMemberChanged - a member of a tar archive differs from the one
+// uploaded before the session was interrupted.
+type TarMemberChanged struct {
+	Name string
+}
+
+func (e TarMemberChanged) Error() string {
+	return "Member `" + e.Name + "` changed since it was uploaded."
+}
+
 // UnexpectedExcessRead - reader wrote more data than requested.
 type UnexpectedExcessRead UnexpectedEOF
 
@@ -198,3 +338,42 @@ type SameFile struct {
 func (e SameFile) Error() string {
 	return fmt.Sprintf("'%s' and '%s' are the same file", e.Source, e.Destination)
 }
+
+// ObjectIsFolder - object key is also the folder of other objects.
+type ObjectIsFolder struct {
+	Object string
+}
+
+func (e ObjectIsFolder) Error() string {
+	return "Object `" + e.Object + "` is also a folder of other objects."
+}
+
+// Offline - request to a host in offline mode.
+type Offline struct {
+	URL string
+}
+
+func (e Offline) Error() string {
+	return "Offline mode, `" + e.URL + "` is not reachable."
+}
+
+// CACertInvalid - CA certificate file of a host cannot be used.
+type CACertInvalid struct {
+	Path   string
+	Reason string
+}
+
+func (e CACertInvalid) Error() string {
+	return "CA certificate file `" + e.Path + "` cannot be used: " + e.Reason + "."
+}
+
+// StreamTooLarge - stream of unknown size does not fit in the parts
+// of a multipart upload.
+type StreamTooLarge struct {
+	PartSize int64
+}
+
+func (e StreamTooLarge) Error() string {
+	return "Stream is larger than " + humanize.IBytes(uint64(e.PartSize*maxPartsCount)) +
+		", the maximum of 10000 parts of " + humanize.IBytes(uint64(e.PartSize)) + ", use a larger part size."
+}
@@ -288,9 +288,11 @@ func (f *fsClient) put(reader io.Reader, size int64, metadata map[string][]strin
 
 	objectPath := f.PathURL.Path
 	avoidResumeUpload := isStreamFile(objectPath)
+	// Appending resumes from the current size of the file itself.
+	isAppend := len(metadata[appendMetaKey]) != 0
 	// Write to a temporary file "object.part.minio" before commit.
 	objectPartPath := objectPath + partSuffix
-	if avoidResumeUpload {
+	if avoidResumeUpload || isAppend {
 		objectPartPath = objectPath
 	}
 
@@ -322,17 +324,34 @@ func (f *fsClient) put(reader io.Reader, size int64, metadata map[string][]strin
 	var totalWritten int64
 	// Current file offset.
 	var currentOffset = partSt.Size()
+	if isAppend && size >= 0 && currentOffset > size {
+		partFile.Close()
+		return 0, probe.NewError(SourceTruncated{
+			Path:       objectPath,
+			Size:       size,
+			TargetSize: currentOffset,
+		})
+	}
+	sourceID := ""
+	if isAppend && metadata[appendMetaKey][0] != "true" {
+		sourceID = metadata[appendMetaKey][0]
+		// Targets which can't store it are checked by size only.
+		if id, e := xattr.Get(objectPath, appendSourceXattr); e == nil && currentOffset > 0 && string(id) != sourceID {
+			partFile.Close()
+			return 0, probe.NewError(SourceRotated{Path: objectPath})
+		}
+	}
 
 	if !isStdIO(reader) && size > 0 {
 		reader = hookreader.NewHook(reader, progress)
-		if seeker, ok := reader.(io.Seeker); ok {
+		// Readers are handed over at their start, object readers fetch
+		// their content again when seeked.
+		if seeker, ok := reader.(io.Seeker); ok && currentOffset > 0 {
 			if _, e = seeker.Seek(currentOffset, 0); e != nil {
 				return 0, probe.NewError(e)
 			}
 			// Discard bytes until currentOffset.
-			if _, e = io.CopyN(ioutil.Discard, progress, currentOffset); e != nil {
-				return 0, probe.NewError(e)
-			}
+			skipProgress(progress, currentOffset)
 		}
 	} else {
 		reader = hookreader.NewHook(reader, progress)
@@ -362,6 +381,9 @@ func (f *fsClient) put(reader io.Reader, size int64, metadata map[string][]strin
 	if e = partFile.Close(); e != nil {
 		return totalWritten, probe.NewError(e)
 	}
+	if sourceID != "" {
+		xattr.Set(objectPath, appendSourceXattr, []byte(sourceID)) // ignore error.
+	}
 
 	// Following verification is needed only for input size greater than '0'.
 	if size > 0 {
@@ -380,7 +402,7 @@ func (f *fsClient) put(reader io.Reader, size int64, metadata map[string][]strin
 			})
 		}
 	}
-	if !avoidResumeUpload {
+	if objectPartPath != objectPath {
 		// Safely completed put. Now commit by renaming to actual filename.
 		if e = os.Rename(objectPartPath, objectPath); e != nil {
 			err := f.toClientError(e, objectPath)
@@ -403,18 +425,39 @@ func (f *fsClient) put(reader io.Reader, size int64, metadata map[string][]strin
 				return totalWritten, probe.NewError(e)
 			}
 		}
+
+		if len(metadata[mtimeMetaKey]) != 0 {
+			mtime, e := time.Parse(time.RFC3339Nano, metadata[mtimeMetaKey][0])
+			if e != nil {
+				return totalWritten, probe.NewError(e)
+			}
+			// Modification time preserved from source overrides any attributes.
+			if e := os.Chtimes(objectPath, mtime, mtime); e != nil {
+				return totalWritten, probe.NewError(e)
+			}
+		}
 	}
 	return totalWritten, nil
 }
 
 // Put - create a new file with metadata.
 func (f *fsClient) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide) (int64, *probe.Error) {
-	if metadata["mc-attrs"] != "" {
-		meta := make(map[string][]string)
-		meta["mc-attrs"] = append(meta["mc-attrs"], metadata["mc-attrs"])
-		return f.put(reader, size, meta, progress)
+	return f.put(reader, size, fsPutMetadata(metadata), progress)
+}
+
+// appendSourceXattr records on an appended file the fileID of its
+// source, see appendMetaKey.
+const appendSourceXattr = "user.mc.append-source"
+
+// fsPutMetadata picks the metadata entries a filesystem target can apply.
+func fsPutMetadata(metadata map[string]string) map[string][]string {
+	meta := make(map[string][]string)
+	for _, k := range []string{"mc-attrs", mtimeMetaKey, appendMetaKey} {
+		if metadata[k] != "" {
+			meta[k] = append(meta[k], metadata[k])
+		}
 	}
-	return f.put(reader, size, nil, progress)
+	return meta
 }
 
 // ShareDownload - share download not implemented for filesystem.
@@ -433,6 +476,14 @@ func (f *fsClient) ShareUpload(startsWith bool, expires time.Duration, contentTy
 	})
 }
 
+// SharePut - share put not implemented for filesystem.
+func (f *fsClient) SharePut(expires time.Duration) (string, *probe.Error) {
+	return "", probe.NewError(APINotImplemented{
+		API:     "SharePut",
+		APIType: "filesystem",
+	})
+}
+
 // Copy - copy data from source to destination
 func (f *fsClient) Copy(source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string) *probe.Error {
 	rc, e := os.Open(source)
@@ -443,7 +494,7 @@ func (f *fsClient) Copy(source string, size int64, progress io.Reader, srcSSE, t
 	defer rc.Close()
 
 	destination := f.PathURL.Path
-	if _, err := f.put(rc, size, map[string][]string{}, progress); err != nil {
+	if _, err := f.put(rc, size, fsPutMetadata(metadata), progress); err != nil {
 		return err.Trace(destination, source)
 	}
 	return nil
@@ -459,6 +510,33 @@ func (f *fsClient) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
 	return fileData, nil
 }
 
+// limitedFile reads at most a number of bytes of a file, it does not
+// embed *os.File whose WriteTo would bypass the limit in io.Copy.
+type limitedFile struct {
+	io.Reader
+	io.Closer
+}
+
+// GetRange - get a reader for length bytes of the file from offset,
+// up to its end if length is negative.
+func (f *fsClient) GetRange(offset, length int64, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	fileData, e := os.Open(f.PathURL.Path)
+	if e != nil {
+		err := f.toClientError(e, f.PathURL.Path)
+		return nil, err.Trace(f.PathURL.Path)
+	}
+	if offset > 0 {
+		if _, e = fileData.Seek(offset, io.SeekStart); e != nil {
+			fileData.Close()
+			return nil, probe.NewError(e).Trace(f.PathURL.Path)
+		}
+	}
+	if length < 0 {
+		return fileData, nil
+	}
+	return limitedFile{io.LimitReader(fileData, length), fileData}, nil
+}
+
 // Check if the given error corresponds to ENOTEMPTY for unix
 // and ERROR_DIR_NOT_EMPTY for windows (directory not empty).
 func isSysErrNotEmpty(err error) bool {
@@ -479,6 +557,21 @@ func isSysErrNotEmpty(err error) bool {
 	return false
 }
 
+// Check if the given error corresponds to EXDEV for unix and
+// ERROR_NOT_SAME_DEVICE for windows (rename across devices).
+func isSysErrCrossDevice(err error) bool {
+	if linkErr, ok := err.(*os.LinkError); ok {
+		if runtime.GOOS == "windows" {
+			if errno, _ok := linkErr.Err.(syscall.Errno); _ok && errno == 0x11 {
+				// ERROR_NOT_SAME_DEVICE
+				return true
+			}
+		}
+		return linkErr.Err == syscall.EXDEV
+	}
+	return false
+}
+
 // deleteFile deletes a file path if its empty. If it's successfully deleted,
 // it will recursively delete empty parent directories
 // until it finds one with files in it. Returns nil for a non-empty directory.
@@ -538,21 +631,22 @@ func (f *fsClient) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *c
 // List - list files and folders.
 func (f *fsClient) List(isRecursive, isIncomplete, isMetadata bool, showDir DirOpt) <-chan *clientContent {
 	contentCh := make(chan *clientContent)
-	filteredCh := make(chan *clientContent)
-
 	if isRecursive {
 		if showDir == DirNone {
-			go f.listRecursiveInRoutine(contentCh, isMetadata)
+			go f.listRecursiveInRoutine(contentCh, isMetadata, "")
 		} else {
 			go f.listDirOpt(contentCh, isIncomplete, isMetadata, showDir)
 		}
 	} else {
 		go f.listInRoutine(contentCh, isMetadata)
 	}
+	return filterPartFiles(contentCh, isIncomplete)
+}
 
-	// This function filters entries from any  listing go routine
-	// created previously. If isIncomplete is activated, we will
-	// only show partly uploaded files,
+// filterPartFiles - filters entries of any listing go routine, if
+// isIncomplete is activated only partly uploaded files are shown.
+func filterPartFiles(contentCh <-chan *clientContent, isIncomplete bool) <-chan *clientContent {
+	filteredCh := make(chan *clientContent)
 	go func() {
 		for c := range contentCh {
 			if isIncomplete {
@@ -575,6 +669,60 @@ func (f *fsClient) List(isRecursive, isIncomplete, isMetadata bool, showDir DirO
 	return filteredCh
 }
 
+// ListPage - lists up to maxKeys entries, token is the path of the
+// last entry of the previous page. Entries which failed to be listed
+// are returned with their error like List sends them.
+func (f *fsClient) ListPage(isRecursive bool, maxKeys int, token string) ([]*clientContent, string, *probe.Error) {
+	if token != "" {
+		if _, e := os.Lstat(token); e != nil || !strings.HasPrefix(token, f.PathURL.Path) {
+			return nil, "", probe.NewError(ContinuationTokenInvalid{Token: token})
+		}
+	}
+
+	var contentCh <-chan *clientContent
+	if isRecursive {
+		// Do not walk again the tree listed by previous pages.
+		rawCh := make(chan *clientContent)
+		go f.listRecursiveInRoutine(rawCh, false, token)
+		contentCh = filterPartFiles(rawCh, false)
+	} else {
+		contentCh = f.List(false, false, false, DirNone)
+	}
+	defer func() {
+		// Let the listing routines end.
+		go func() {
+			for range contentCh {
+			}
+		}()
+	}()
+
+	if token != "" && !isRecursive {
+		found := false
+		for content := range contentCh {
+			if content.Err == nil && content.URL.Path == token {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, "", probe.NewError(ContinuationTokenInvalid{Token: token})
+		}
+	}
+
+	var contents []*clientContent
+	var last string
+	for content := range contentCh {
+		if len(contents) == maxKeys && last != "" {
+			return contents, last, nil
+		}
+		contents = append(contents, content)
+		if content.Err == nil {
+			last = content.URL.Path
+		}
+	}
+	return contents, "", nil
+}
+
 // byDirName implements sort.Interface.
 type byDirName []os.FileInfo
 
@@ -805,7 +953,9 @@ func (f *fsClient) listDirOpt(contentCh chan *clientContent, isIncomplete bool,
 	}
 }
 
-func (f *fsClient) listRecursiveInRoutine(contentCh chan *clientContent, isMetadata bool) {
+// listRecursiveInRoutine - walks the tree, only the files whose path
+// sorts after the path given by after are listed.
+func (f *fsClient) listRecursiveInRoutine(contentCh chan *clientContent, isMetadata bool, after string) {
 	// close channels upon return.
 	defer close(contentCh)
 	var dirName string
@@ -850,6 +1000,17 @@ func (f *fsClient) listRecursiveInRoutine(contentCh chan *clientContent, isMetad
 				}
 			}
 		}
+		// The walk lists paths in lexical order, skip what sorts
+		// before after without descending in directories.
+		if after != "" {
+			if !fi.IsDir() {
+				if fp <= after {
+					return nil
+				}
+			} else if dir := fp + string(pathURL.Separator); dir < after && !strings.HasPrefix(after, dir) {
+				return ioutils.ErrSkipDir
+			}
+		}
 		if e != nil {
 			// If operation is not permitted, we throw quickly back.
 			if strings.Contains(e.Error(), "operation not permitted") {
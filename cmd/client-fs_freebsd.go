@@ -32,6 +32,12 @@ var (
 	EventTypeGet = []notify.Event{} // On macOS, FreeBSD, Solaris this is not available.
 )
 
+// Longest path and file name accepted by the OS, PATH_MAX on FreeBSD.
+const (
+	fsMaxPathLength = 1024
+	fsMaxNameLength = 255
+)
+
 // IsGetEvent checks if the event return is a get event.
 func IsGetEvent(event notify.Event) bool {
 	return false
@@ -37,6 +37,12 @@ var (
 	EventTypeGet = []notify.Event{notify.InAccess | notify.InOpen}
 )
 
+// Longest path and file name accepted by the OS, PATH_MAX and NAME_MAX.
+const (
+	fsMaxPathLength = 4096
+	fsMaxNameLength = 255
+)
+
 // IsGetEvent checks if the event return is a get event.
 func IsGetEvent(event notify.Event) bool {
 	for _, ev := range EventTypeGet {
@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"runtime"
 
+	"github.com/pkg/xattr"
 	. "gopkg.in/check.v1"
 )
 
@@ -185,6 +186,55 @@ func (s *TestSuite) TestList(c *C) {
 	}
 }
 
+// Test listing a tree page by page.
+func (s *TestSuite) TestListPageRecursive(c *C) {
+	root, e := ioutil.TempDir(os.TempDir(), "fs-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	// dir-x and dir.d sort between dir and its entries.
+	for _, name := range []string{"a", "dir-x", "dir.d/f", "dir/b", "dir/sub/c", "dir/sub/d", "e"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0700), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(name), 0600), IsNil)
+	}
+
+	fsClient, err := fsNew(root + string(filepath.Separator))
+	c.Assert(err, IsNil)
+
+	// Pages follow each other without gaps or repeats.
+	var names []string
+	var token string
+	for pages := 0; ; pages++ {
+		c.Assert(pages < 5, Equals, true)
+		contents, nextToken, err := fsClient.ListPage(true, 2, token)
+		c.Assert(err, IsNil)
+		c.Assert(len(contents) <= 2, Equals, true)
+		for _, content := range contents {
+			c.Assert(content.Err, IsNil)
+			name, e := filepath.Rel(root, content.URL.Path)
+			c.Assert(e, IsNil)
+			names = append(names, filepath.ToSlash(name))
+		}
+		if nextToken == "" {
+			break
+		}
+		token = nextToken
+	}
+	c.Assert(names, DeepEquals, []string{"a", "dir-x", "dir.d/f", "dir/b", "dir/sub/c", "dir/sub/d", "e"})
+
+	// Pages restart from the token inside of the tree.
+	contents, nextToken, err := fsClient.ListPage(true, 2, filepath.Join(root, "dir", "b"))
+	c.Assert(err, IsNil)
+	c.Assert(len(contents), Equals, 2)
+	c.Assert(contents[0].URL.Path, Equals, filepath.Join(root, "dir", "sub", "c"))
+	c.Assert(nextToken, Equals, filepath.Join(root, "dir", "sub", "d"))
+
+	// Tokens of entries which do not exist are refused.
+	_, _, err = fsClient.ListPage(true, 2, filepath.Join(root, "missing"))
+	c.Assert(err, NotNil)
+}
+
 // Test put bucket aka 'mkdir()' operation.
 func (s *TestSuite) TestPutBucket(c *C) {
 	root, e := ioutil.TempDir(os.TempDir(), "fs-")
@@ -256,6 +306,57 @@ func (s *TestSuite) TestPut(c *C) {
 	c.Assert(n, Equals, int64(len(data)))
 }
 
+// Test put appending to an existing file.
+func (s *TestSuite) TestPutAppend(c *C) {
+	root, e := ioutil.TempDir(os.TempDir(), "fs-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(root)
+
+	objectPath := filepath.Join(root, "object")
+	c.Assert(ioutil.WriteFile(objectPath, []byte("hello "), 0644), IsNil)
+	fsClient, err := fsNew(objectPath)
+	c.Assert(err, IsNil)
+
+	metadata := map[string]string{appendMetaKey: "true"}
+	data := "hello world"
+	// Bytes already in the file are skipped through progress.
+	n, err := fsClient.Put(context.Background(), bytes.NewReader([]byte(data)), int64(len(data)), metadata, newAccounter(0), nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	content, e := ioutil.ReadFile(objectPath)
+	c.Assert(e, IsNil)
+	c.Assert(string(content), Equals, data)
+
+	// The source was rotated, the file is left as it is.
+	_, err = fsClient.Put(context.Background(), bytes.NewReader([]byte("new")), 3, metadata, newAccounter(0), nil)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(SourceTruncated)
+	c.Assert(ok, Equals, true)
+	content, e = ioutil.ReadFile(objectPath)
+	c.Assert(e, IsNil)
+	c.Assert(string(content), Equals, data)
+
+	// The target remembers the file it was appended from.
+	metadata[appendMetaKey] = "1:1"
+	data += " again"
+	_, err = fsClient.Put(context.Background(), bytes.NewReader([]byte(data)), int64(len(data)), metadata, newAccounter(0), nil)
+	c.Assert(err, IsNil)
+	if _, e = xattr.Get(objectPath, appendSourceXattr); e != nil {
+		c.Skip("extended attributes are not supported")
+	}
+
+	// A larger file replacing the source is also found out.
+	metadata[appendMetaKey] = "1:2"
+	rotated := data + " and again"
+	_, err = fsClient.Put(context.Background(), bytes.NewReader([]byte(rotated)), int64(len(rotated)), metadata, newAccounter(0), nil)
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(SourceRotated)
+	c.Assert(ok, Equals, true)
+	content, e = ioutil.ReadFile(objectPath)
+	c.Assert(e, IsNil)
+	c.Assert(string(content), Equals, data)
+}
+
 // Test read a file.
 func (s *TestSuite) TestGet(c *C) {
 	root, e := ioutil.TempDir(os.TempDir(), "fs-")
@@ -313,6 +414,26 @@ func (s *TestSuite) TestGetRange(c *C) {
 	_, e = results.Write(buf)
 	c.Assert(e, IsNil)
 	c.Assert([]byte("hello"), DeepEquals, results.Bytes())
+
+	testCases := []struct {
+		offset, length int64
+		expected       string
+	}{
+		{0, -1, "hello world"},
+		{6, -1, "world"},
+		{2, 3, "llo"},
+		{6, 100, "world"},
+		{4, 0, ""},
+	}
+	for _, testCase := range testCases {
+		rc, err := fsClient.GetRange(testCase.offset, testCase.length, nil)
+		c.Assert(err, IsNil)
+		results.Reset()
+		_, e = io.Copy(&results, rc)
+		c.Assert(e, IsNil)
+		c.Assert(rc.Close(), IsNil)
+		c.Assert(results.String(), Equals, testCase.expected)
+	}
 }
 
 // Test stat file.
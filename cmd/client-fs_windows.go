@@ -29,6 +29,13 @@ var (
 	EventTypeGet = []notify.Event{notify.FileNotifyChangeLastAccess}
 )
 
+// Longest path and file name accepted by the OS, Go prefixes long paths
+// with `\\?\` so MAX_PATH does not apply.
+const (
+	fsMaxPathLength = 32767
+	fsMaxNameLength = 255
+)
+
 // IsGetEvent checks if the event return is a get event.
 func IsGetEvent(event notify.Event) bool {
 	return event&notify.FileNotifyChangeLastAccess != 0
@@ -0,0 +1,364 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+	"github.com/minio/minio/pkg/bucket/object/tagging"
+)
+
+const httpAPIType = "HTTP(S) URLs without an alias"
+
+// httpClient - read-only client of a plain HTTP(S) URL, such as a file
+// published on a web server, to copy from it.
+type httpClient struct {
+	targetURL *clientURL
+	client    *http.Client
+	userAgent string
+}
+
+// httpNew returns an initialized httpClient of urlStr.
+func httpNew(urlStr string) (Client, *probe.Error) {
+	targetURL := newClientURL(urlStr)
+	if globalOffline {
+		return offlineClient{url: *targetURL}, nil
+	}
+	transport, err := getHTTPTransport()
+	if err != nil {
+		return nil, err.Trace(urlStr)
+	}
+	return &httpClient{
+		targetURL: targetURL,
+		client:    &http.Client{Transport: transport},
+		userAgent: filepath.Base(os.Args[0]) + "/" + Version,
+	}, nil
+}
+
+// httpTransport - transport shared by all clients of plain URLs, built
+// once with the timeouts, CA certificates and limits of aliases.
+var httpTransport struct {
+	once      sync.Once
+	transport http.RoundTripper
+	err       *probe.Error
+}
+
+func getHTTPTransport() (http.RoundTripper, *probe.Error) {
+	httpTransport.once.Do(func() {
+		config := newS3Config("", &hostConfigV9{})
+		rootCAs, err := getRootCAs(config.CACertFile)
+		if err != nil {
+			httpTransport.err = err.Trace(config.CACertFile)
+			return
+		}
+		transport := limitTransport(config, newHostTransport(config, rootCAs, true))
+		if len(config.RetryStatusCodes) > 0 {
+			transport = retryStatusTransport{
+				statuses:  config.RetryStatusCodes,
+				maxRetry:  defaultRetryAttempts,
+				transport: transport,
+			}
+		}
+		httpTransport.transport = transport
+	})
+	return httpTransport.transport, httpTransport.err
+}
+
+// newHTTPAPINotImplemented - api can't be done on a plain URL.
+func newHTTPAPINotImplemented(api string) *probe.Error {
+	return probe.NewError(APINotImplemented{API: api, APIType: httpAPIType})
+}
+
+// do sends a request to the URL, answers other than 2xx are returned as
+// errors.
+func (c *httpClient) do(method string, header http.Header) (*http.Response, *probe.Error) {
+	req, e := http.NewRequest(method, c.targetURL.String(), nil)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	resp, e := c.client.Do(req)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	if resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return nil, probe.NewError(ObjectMissing{})
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
+	}
+	// Kept as an S3 error response for server errors to be retried.
+	return nil, probe.NewError(minio.ErrorResponse{
+		StatusCode: resp.StatusCode,
+		Code:       http.StatusText(resp.StatusCode),
+		Message:    resp.Status,
+	})
+}
+
+// GetURL returns the URL of the client.
+func (c *httpClient) GetURL() clientURL {
+	return *c.targetURL
+}
+
+// AddUserAgent sets the user agent of requests.
+func (c *httpClient) AddUserAgent(app, version string) {
+	c.userAgent = app + "/" + version
+}
+
+// Stat - get the size and content headers of the URL with a HEAD
+// request, the URL is always a file.
+func (c *httpClient) Stat(isIncomplete, isFetchMeta, isPreserve bool, sse encrypt.ServerSide) (*clientContent, *probe.Error) {
+	if isIncomplete {
+		return nil, probe.NewError(ObjectMissing{})
+	}
+	resp, err := c.do(http.MethodHead, nil)
+	if err != nil && isErrHeadRejected(err) {
+		// The first byte tells the size instead.
+		resp, err = c.do(http.MethodGet, http.Header{"Range": []string{"bytes=0-0"}})
+	}
+	if err != nil {
+		return nil, err.Trace(c.targetURL.String())
+	}
+	resp.Body.Close()
+
+	size := resp.ContentLength
+	if resp.StatusCode == http.StatusPartialContent {
+		size = contentRangeSize(resp.Header.Get("Content-Range"))
+	}
+	content := &clientContent{
+		URL:          *c.targetURL,
+		Size:         size,
+		Type:         os.FileMode(0664),
+		ETag:         strings.Trim(resp.Header.Get("ETag"), "\""),
+		Metadata:     map[string]string{},
+		UserMetadata: map[string]string{},
+	}
+	if t, e := http.ParseTime(resp.Header.Get("Last-Modified")); e == nil {
+		content.Time = t
+	} else {
+		content.Time = time.Now()
+	}
+	for _, name := range []string{"Content-Type", "Cache-Control", "Content-Encoding", "Content-Disposition", "Content-Language"} {
+		if value := resp.Header.Get(name); value != "" {
+			content.Metadata[name] = value
+		}
+	}
+	return content, nil
+}
+
+// isErrHeadRejected tells if a server does not answer HEAD requests.
+func isErrHeadRejected(err *probe.Error) bool {
+	switch minio.ToErrorResponse(err.ToGoError()).StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// contentRangeSize returns the complete length of a Content-Range
+// header, -1 if unknown.
+func contentRangeSize(contentRange string) int64 {
+	i := strings.LastIndex(contentRange, "/")
+	if i < 0 {
+		return -1
+	}
+	size, e := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if e != nil {
+		return -1
+	}
+	return size
+}
+
+// Get - get a reader of the URL.
+func (c *httpClient) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	return c.GetRange(0, -1, sse)
+}
+
+// GetRange - get a reader for length bytes of the URL from offset, up
+// to its end if length is negative. Ranges are skipped to when the
+// server answers them with the whole content.
+func (c *httpClient) GetRange(offset, length int64, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	if length == 0 {
+		// No range of zero bytes, fail on missing URLs.
+		if _, err := c.Stat(false, false, false, sse); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	header := make(http.Header)
+	switch {
+	case length > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := c.do(http.MethodGet, header)
+	if err != nil {
+		return nil, err.Trace(c.targetURL.String())
+	}
+	if header.Get("Range") == "" || resp.StatusCode == http.StatusPartialContent {
+		return resp.Body, nil
+	}
+	if _, e := io.CopyN(ioutil.Discard, resp.Body, offset); e != nil {
+		resp.Body.Close()
+		return nil, probe.NewError(e)
+	}
+	if length < 0 {
+		return resp.Body, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, length), resp.Body}, nil
+}
+
+/// Not supported by plain URLs.
+
+// List - not supported, URLs can't be listed.
+func (c *httpClient) List(isRecursive, isIncomplete, isFetchMeta bool, showDir DirOpt) <-chan *clientContent {
+	contentCh := make(chan *clientContent, 1)
+	contentCh <- &clientContent{URL: *c.targetURL, Err: newHTTPAPINotImplemented("List")}
+	close(contentCh)
+	return contentCh
+}
+
+// ListPage - not supported.
+func (c *httpClient) ListPage(isRecursive bool, maxKeys int, token string) ([]*clientContent, string, *probe.Error) {
+	return nil, "", newHTTPAPINotImplemented("List")
+}
+
+// MakeBucket - not supported.
+func (c *httpClient) MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error {
+	return newHTTPAPINotImplemented("MakeBucket")
+}
+
+// SetObjectLockConfig - not supported.
+func (c *httpClient) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
+	return newHTTPAPINotImplemented("SetObjectLockConfig")
+}
+
+// GetObjectLockConfig - not supported.
+func (c *httpClient) GetObjectLockConfig() (*minio.RetentionMode, *uint, *minio.ValidityUnit, *probe.Error) {
+	return nil, nil, nil, newHTTPAPINotImplemented("GetObjectLockConfig")
+}
+
+// GetAccess - not supported.
+func (c *httpClient) GetAccess() (string, string, *probe.Error) {
+	return "", "", newHTTPAPINotImplemented("GetAccess")
+}
+
+// GetAccessRules - not supported.
+func (c *httpClient) GetAccessRules() (map[string]string, *probe.Error) {
+	return nil, newHTTPAPINotImplemented("GetAccessRules")
+}
+
+// SetAccess - not supported.
+func (c *httpClient) SetAccess(access string, isJSON bool) *probe.Error {
+	return newHTTPAPINotImplemented("SetAccess")
+}
+
+// Copy - not supported.
+func (c *httpClient) Copy(source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string) *probe.Error {
+	return newHTTPAPINotImplemented("Copy")
+}
+
+// Select - not supported.
+func (c *httpClient) Select(expression string, sse encrypt.ServerSide, opts SelectObjectOpts) (io.ReadCloser, *probe.Error) {
+	return nil, newHTTPAPINotImplemented("Select")
+}
+
+// Put - not supported, URLs are read-only.
+func (c *httpClient) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide) (int64, *probe.Error) {
+	return 0, newHTTPAPINotImplemented("Put")
+}
+
+// PutObjectRetention - not supported.
+func (c *httpClient) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error {
+	return newHTTPAPINotImplemented("PutObjectRetention")
+}
+
+// PutObjectLegalHold - not supported.
+func (c *httpClient) PutObjectLegalHold(hold *minio.LegalHoldStatus) *probe.Error {
+	return newHTTPAPINotImplemented("PutObjectLegalHold")
+}
+
+// ShareDownload - not supported.
+func (c *httpClient) ShareDownload(expires time.Duration) (string, *probe.Error) {
+	return "", newHTTPAPINotImplemented("ShareDownload")
+}
+
+// ShareUpload - not supported.
+func (c *httpClient) ShareUpload(isRecursive bool, expires time.Duration, contentType string) (string, map[string]string, *probe.Error) {
+	return "", nil, newHTTPAPINotImplemented("ShareUpload")
+}
+
+// SharePut - not supported.
+func (c *httpClient) SharePut(expires time.Duration) (string, *probe.Error) {
+	return "", newHTTPAPINotImplemented("SharePut")
+}
+
+// Watch - not supported.
+func (c *httpClient) Watch(params watchParams) (*watchObject, *probe.Error) {
+	return nil, newHTTPAPINotImplemented("Watch")
+}
+
+// Remove - drains contentCh and fails once, URLs are read-only.
+func (c *httpClient) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *clientContent) <-chan *probe.Error {
+	errorCh := make(chan *probe.Error, 1)
+	go func() {
+		defer close(errorCh)
+		for range contentCh {
+		}
+		errorCh <- newHTTPAPINotImplemented("Remove")
+	}()
+	return errorCh
+}
+
+// GetObjectTagging - not supported.
+func (c *httpClient) GetObjectTagging() (tagging.Tagging, *probe.Error) {
+	return tagging.Tagging{}, newHTTPAPINotImplemented("GetObjectTagging")
+}
+
+// SetObjectTagging - not supported.
+func (c *httpClient) SetObjectTagging(tagMap map[string]string) *probe.Error {
+	return newHTTPAPINotImplemented("SetObjectTagging")
+}
+
+// DeleteObjectTagging - not supported.
+func (c *httpClient) DeleteObjectTagging() *probe.Error {
+	return newHTTPAPINotImplemented("DeleteObjectTagging")
+}
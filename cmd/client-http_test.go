@@ -0,0 +1,98 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+// Test that plain URLs are read from and nothing else.
+func (s *TestSuite) TestHTTPClient(c *C) {
+	const data = "contents of file.iso"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/nohead.iso" {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			http.ServeContent(w, r, "nohead.iso", UTCNow(), strings.NewReader(data))
+			return
+		}
+		if r.URL.Path != "/file.iso" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-iso9660-image")
+		w.Header().Set("Last-Modified", "Mon, 12 Oct 2026 10:00:00 GMT")
+		if r.URL.Query().Get("range") == "ranges" {
+			// Ranges are served by http.ServeContent.
+			http.ServeContent(w, r, "file.iso", UTCNow(), strings.NewReader(data))
+			return
+		}
+		w.Write([]byte(data))
+	}))
+	defer server.Close()
+
+	clnt, err := httpNew(server.URL + "/file.iso")
+	c.Assert(err, IsNil)
+	content, err := clnt.Stat(false, false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.Size, Equals, int64(len(data)))
+	c.Assert(content.Type.IsRegular(), Equals, true)
+	c.Assert(content.Metadata["Content-Type"], Equals, "application/x-iso9660-image")
+
+	for _, urlStr := range []string{server.URL + "/file.iso", server.URL + "/file.iso?range=ranges"} {
+		clnt, err := httpNew(urlStr)
+		c.Assert(err, IsNil)
+		reader, err := clnt.GetRange(12, 4, nil)
+		c.Assert(err, IsNil)
+		got, _ := ioutil.ReadAll(reader)
+		reader.Close()
+		c.Assert(string(got), Equals, "file")
+	}
+
+	// Servers rejecting HEAD requests tell the size of the first byte.
+	noHead, err := httpNew(server.URL + "/nohead.iso")
+	c.Assert(err, IsNil)
+	content, err = noHead.Stat(false, false, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(content.Size, Equals, int64(len(data)))
+
+	// Connections are kept across clients.
+	c.Assert(noHead.(*httpClient).client.Transport, Equals, clnt.(*httpClient).client.Transport)
+
+	// Only sources may be plain URLs.
+	defer useEmptyMcConfig()()
+	_, err = newClient(server.URL + "/file.iso")
+	c.Assert(err, NotNil)
+	_, err = newSourceClient(server.URL + "/file.iso")
+	c.Assert(err, IsNil)
+
+	missing, err := httpNew(server.URL + "/missing")
+	c.Assert(err, IsNil)
+	_, err = missing.Stat(false, false, false, nil)
+	c.Assert(err.ToGoError(), FitsTypeOf, ObjectMissing{})
+
+	_, err = clnt.Put(context.Background(), strings.NewReader("hello"), 5, nil, nil, nil)
+	c.Assert(err.ToGoError(), FitsTypeOf, APINotImplemented{})
+}
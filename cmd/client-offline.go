@@ -0,0 +1,176 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+	"github.com/minio/minio/pkg/bucket/object/tagging"
+)
+
+// offlineClient stands in for object storage clients in offline mode,
+// every request fails before reaching the network.
+type offlineClient struct {
+	url clientURL
+}
+
+func (c offlineClient) offlineError() *probe.Error {
+	return probe.NewError(Offline{URL: c.url.String()})
+}
+
+// GetURL returns the URL of the client.
+func (c offlineClient) GetURL() clientURL {
+	return c.url
+}
+
+// AddUserAgent - nothing to do offline.
+func (c offlineClient) AddUserAgent(app, version string) {}
+
+// Stat - fails offline.
+func (c offlineClient) Stat(isIncomplete, isFetchMeta, isPreserve bool, sse encrypt.ServerSide) (*clientContent, *probe.Error) {
+	return nil, c.offlineError()
+}
+
+// List - lists nothing but the offline error.
+func (c offlineClient) List(isRecursive, isIncomplete, isFetchMeta bool, showDir DirOpt) <-chan *clientContent {
+	contentCh := make(chan *clientContent, 1)
+	contentCh <- &clientContent{URL: c.url, Err: c.offlineError()}
+	close(contentCh)
+	return contentCh
+}
+
+// ListPage - fails offline.
+func (c offlineClient) ListPage(isRecursive bool, maxKeys int, token string) ([]*clientContent, string, *probe.Error) {
+	return nil, "", c.offlineError()
+}
+
+// MakeBucket - fails offline.
+func (c offlineClient) MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error {
+	return c.offlineError()
+}
+
+// SetObjectLockConfig - fails offline.
+func (c offlineClient) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
+	return c.offlineError()
+}
+
+// GetObjectLockConfig - fails offline.
+func (c offlineClient) GetObjectLockConfig() (*minio.RetentionMode, *uint, *minio.ValidityUnit, *probe.Error) {
+	return nil, nil, nil, c.offlineError()
+}
+
+// GetAccess - fails offline.
+func (c offlineClient) GetAccess() (string, string, *probe.Error) {
+	return "", "", c.offlineError()
+}
+
+// GetAccessRules - fails offline.
+func (c offlineClient) GetAccessRules() (map[string]string, *probe.Error) {
+	return nil, c.offlineError()
+}
+
+// SetAccess - fails offline.
+func (c offlineClient) SetAccess(access string, isJSON bool) *probe.Error {
+	return c.offlineError()
+}
+
+// Copy - fails offline.
+func (c offlineClient) Copy(source string, size int64, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide, metadata map[string]string) *probe.Error {
+	return c.offlineError()
+}
+
+// Select - fails offline.
+func (c offlineClient) Select(expression string, sse encrypt.ServerSide, opts SelectObjectOpts) (io.ReadCloser, *probe.Error) {
+	return nil, c.offlineError()
+}
+
+// Get - fails offline.
+func (c offlineClient) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	return nil, c.offlineError()
+}
+
+// GetRange - fails offline.
+func (c offlineClient) GetRange(offset, length int64, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	return nil, c.offlineError()
+}
+
+// Put - fails offline without reading from reader.
+func (c offlineClient) Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide) (int64, *probe.Error) {
+	return 0, c.offlineError()
+}
+
+// PutObjectRetention - fails offline.
+func (c offlineClient) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error {
+	return c.offlineError()
+}
+
+// PutObjectLegalHold - fails offline.
+func (c offlineClient) PutObjectLegalHold(hold *minio.LegalHoldStatus) *probe.Error {
+	return c.offlineError()
+}
+
+// ShareDownload - fails offline.
+func (c offlineClient) ShareDownload(expires time.Duration) (string, *probe.Error) {
+	return "", c.offlineError()
+}
+
+// ShareUpload - fails offline.
+func (c offlineClient) ShareUpload(isRecursive bool, expires time.Duration, contentType string) (string, map[string]string, *probe.Error) {
+	return "", nil, c.offlineError()
+}
+
+// SharePut - fails offline.
+func (c offlineClient) SharePut(expires time.Duration) (string, *probe.Error) {
+	return "", c.offlineError()
+}
+
+// Watch - fails offline.
+func (c offlineClient) Watch(params watchParams) (*watchObject, *probe.Error) {
+	return nil, c.offlineError()
+}
+
+// Remove - drains contentCh and fails once offline.
+func (c offlineClient) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *clientContent) <-chan *probe.Error {
+	errorCh := make(chan *probe.Error, 1)
+	go func() {
+		defer close(errorCh)
+		for range contentCh {
+		}
+		errorCh <- c.offlineError()
+	}()
+	return errorCh
+}
+
+// GetObjectTagging - fails offline.
+func (c offlineClient) GetObjectTagging() (tagging.Tagging, *probe.Error) {
+	return tagging.Tagging{}, c.offlineError()
+}
+
+// SetObjectTagging - fails offline.
+func (c offlineClient) SetObjectTagging(tagMap map[string]string) *probe.Error {
+	return c.offlineError()
+}
+
+// DeleteObjectTagging - fails offline.
+func (c offlineClient) DeleteObjectTagging() *probe.Error {
+	return c.offlineError()
+}
@@ -0,0 +1,60 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+// Test that offline clients fail without sending requests.
+func (s *TestSuite) TestOfflineClient(c *C) {
+	globalOffline = true
+	defer func() { globalOffline = false }()
+
+	conf := newTestS3Config("http://127.0.0.1:1/bucket/object")
+	clnt, err := s3New(conf)
+	c.Assert(err, IsNil)
+	c.Assert(clnt.GetURL().String(), Equals, conf.HostURL)
+
+	_, err = clnt.Stat(false, false, false, nil)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(Offline)
+	c.Assert(ok, Equals, true)
+
+	_, err = clnt.Put(context.Background(), strings.NewReader("hello"), 5, nil, nil, nil)
+	c.Assert(err, NotNil)
+
+	var contents int
+	for content := range clnt.List(false, false, false, DirNone) {
+		c.Assert(content.Err, NotNil)
+		contents++
+	}
+	c.Assert(contents, Equals, 1)
+
+	contentCh := make(chan *clientContent, 1)
+	contentCh <- &clientContent{URL: clnt.GetURL()}
+	close(contentCh)
+	var errs int
+	for err := range clnt.Remove(false, false, contentCh) {
+		c.Assert(err, NotNil)
+		errs++
+	}
+	c.Assert(errs, Equals, 1)
+}
@@ -20,11 +20,13 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"hash/fnv"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -32,6 +34,8 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -42,6 +46,7 @@ import (
 	"github.com/minio/minio-go/v6/pkg/credentials"
 	"github.com/minio/minio-go/v6/pkg/encrypt"
 	"github.com/minio/minio-go/v6/pkg/policy"
+	"github.com/minio/minio-go/v6/pkg/s3signer"
 	"github.com/minio/minio-go/v6/pkg/s3utils"
 	"github.com/minio/minio/pkg/bucket/object/tagging"
 	"github.com/minio/minio/pkg/mimedb"
@@ -51,17 +56,49 @@ import (
 type s3Client struct {
 	mutex        *sync.Mutex
 	targetURL    *clientURL
-	api          *minio.Client
 	virtualStyle bool
+	delimiter    string
+	config       *Config
+
+	// api is replaced along with the region of config when a region
+	// redirect is followed, apiMutex guards both.
+	apiMutex   sync.RWMutex
+	api        *minio.Client
+	redirected bool
 }
 
 const (
-	amazonHostNameAccelerated = "s3-accelerate.amazonaws.com"
-	googleHostName            = "storage.googleapis.com"
-	serverEncryptionKeyPrefix = "x-amz-server-side-encryption"
+	amazonHostNameAccelerated          = "s3-accelerate.amazonaws.com"
+	amazonHostNameAcceleratedDualStack = "s3-accelerate.dualstack.amazonaws.com"
+	googleHostName                     = "storage.googleapis.com"
+	serverEncryptionKeyPrefix          = "x-amz-server-side-encryption"
 
 	defaultRecordDelimiter = "\n"
 	defaultFieldDelimiter  = ","
+
+	// Largest object copied with a single server side copy request.
+	maxSingleCopySize = 5 * 1024 * 1024 * 1024
+
+	// Largest object uploaded with a single PUT request.
+	maxSinglePutSize = 5 * 1024 * 1024 * 1024
+
+	// Objects of at least this size are uploaded in parts, unless the
+	// caller asks for another threshold.
+	defaultMultipartThreshold = 64 * 1024 * 1024
+
+	// Idle connections kept open by default, to all hosts and to each.
+	defaultMaxIdleConns = 1024
+
+	// Time allowed by default to establish a TCP connection.
+	defaultConnectTimeout = 10 * time.Second
+
+	// Smallest part and largest number of parts of a multipart upload.
+	minPartSize   = 5 * 1024 * 1024
+	maxPartsCount = 10000
+
+	// Parts are buffered in memory by each upload thread, they are not
+	// made larger than the minio-go default unless the object needs it.
+	maxBufferedPartSize = 128 * 1024 * 1024
 )
 
 const (
@@ -81,6 +118,490 @@ const (
 
 var timeSentinel = time.Unix(0, 0).UTC()
 
+// requestTimeoutTransport bounds the total duration of a request,
+// including reading the response body, unlike http.Client.Timeout this
+// is settable on a transport handed over to minio-go.
+type requestTimeoutTransport struct {
+	timeout   time.Duration
+	transport http.RoundTripper
+}
+
+func (t requestTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, e := t.transport.RoundTrip(req.WithContext(ctx))
+	if e != nil {
+		cancel()
+		return nil, e
+	}
+	resp.Body = &cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// readTimeoutTransport fails requests whose response body receives no
+// data for timeout while being read, a stalled transfer is cancelled
+// instead of hanging. Time spent between reads is not accounted, the
+// body may be consumed at the pace of a slower target.
+type readTimeoutTransport struct {
+	timeout   time.Duration
+	transport http.RoundTripper
+}
+
+func (t readTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, e := t.transport.RoundTrip(req.WithContext(ctx))
+	if e != nil {
+		cancel()
+		return nil, e
+	}
+	timer := time.AfterFunc(t.timeout, cancel)
+	timer.Stop()
+	resp.Body = &readTimeoutReadCloser{
+		cancelReadCloser: cancelReadCloser{ReadCloser: resp.Body, cancel: cancel},
+		timer:            timer,
+		timeout:          t.timeout,
+	}
+	return resp, nil
+}
+
+// readTimeoutReadCloser cancels its request when a read takes longer
+// than timeout.
+type readTimeoutReadCloser struct {
+	cancelReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *readTimeoutReadCloser) Read(p []byte) (int, error) {
+	r.timer.Reset(r.timeout)
+	defer r.timer.Stop()
+	return r.cancelReadCloser.Read(p)
+}
+
+func (r *readTimeoutReadCloser) Close() error {
+	r.timer.Stop()
+	return r.cancelReadCloser.Close()
+}
+
+// cancelReadCloser releases the request context once the body is closed.
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// retryStatusTransport retries requests answered with one of the
+// configured HTTP statuses, it replaces the built-in minio-go retry
+// policy when `--retry-on` is provided.
+type retryStatusTransport struct {
+	statuses  map[int]struct{}
+	maxRetry  int
+	transport http.RoundTripper
+}
+
+// isRequestReplayable tells if req can be sent again, requests with a
+// body only if the body can be re-created. minio-go sends its bodies
+// without GetBody and retries them itself by seeking them back.
+func isRequestReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func (t retryStatusTransport) RoundTrip(req *http.Request) (resp *http.Response, e error) {
+	canRetry := isRequestReplayable(req)
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	attempt := 0
+	for range newRetryTimerContinous(minio.DefaultRetryUnit, minio.DefaultRetryCap, minio.MaxJitter, doneCh) {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			r = req.Clone(req.Context())
+			if r.Body, e = req.GetBody(); e != nil {
+				return nil, e
+			}
+		}
+		attempt++
+
+		resp, e = t.transport.RoundTrip(r)
+		if !canRetry || attempt >= t.maxRetry || req.Context().Err() != nil {
+			return resp, e
+		}
+		if e == nil {
+			if _, ok := t.statuses[resp.StatusCode]; !ok {
+				return resp, nil
+			}
+		}
+		// The requests of a transfer are retried out of its budget.
+		if budget := requestRetryBudget(req); budget != nil {
+			if _, ok := budget.take(); !ok {
+				return resp, e
+			}
+		}
+		if e == nil {
+			// Drain and close the body so the connection can be reused.
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		globalMetrics.addRetry()
+	}
+	return resp, e
+}
+
+// minioRetryCodes - error codes minio-go retries whatever the status.
+var minioRetryCodes = map[string]struct{}{
+	"RequestError":          {},
+	"RequestTimeout":        {},
+	"Throttling":            {},
+	"ThrottlingException":   {},
+	"RequestLimitExceeded":  {},
+	"RequestThrottled":      {},
+	"InternalError":         {},
+	"ExpiredToken":          {},
+	"ExpiredTokenException": {},
+	"SlowDown":              {},
+}
+
+// finalAnswerTransport - keeps minio-go from retrying the requests of
+// a client which retries with retryStatusTransport, and the requests of
+// a transfer which has a retry budget, see retryBudget. minio.MaxRetry is
+// shared by every client and there is no per client setting, so this
+// relies on how executeMethod() of minio-go v6 handles answers: it
+// retries transport errors and some answers, but returns at once the
+// error of an answer whose body cannot be read. Transport errors and
+// answers minio-go would retry are handed over as such an answer, with
+// a body failing with the error minio-go would have returned. It must
+// wrap every other transport of the client, which never see these
+// answers. Requests retryStatusTransport can't replay are left to the
+// minio-go retry. TestFinalAnswerTransport pins the minio-go behavior.
+type finalAnswerTransport struct {
+	isStatusRetried bool
+	transport       http.RoundTripper
+}
+
+func (t finalAnswerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	isStatusRetried := t.isStatusRetried && isRequestReplayable(req)
+	if !isStatusRetried && requestRetryBudget(req) == nil {
+		return t.transport.RoundTrip(req)
+	}
+	resp, e := t.transport.RoundTrip(req)
+	if e != nil {
+		return &http.Response{
+			Status:  e.Error(),
+			Header:  make(http.Header),
+			Body:    errorBody{e},
+			Request: req,
+		}, nil
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusPartialContent:
+		return resp, nil
+	}
+	// Fail the same way as minio-go does for an unexpected answer.
+	body, e := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if e != nil {
+		return nil, e
+	}
+	errResp := minio.ErrorResponse{StatusCode: resp.StatusCode, Server: resp.Header.Get("Server")}
+	if xml.Unmarshal(body, &errResp) != nil {
+		errResp = minio.ErrorResponse{StatusCode: resp.StatusCode, Code: resp.Status, Message: resp.Status}
+	}
+	_, retried := minioRetryCodes[errResp.Code]
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		retried = true
+	}
+	if !retried {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	if errResp.RequestID == "" {
+		errResp.RequestID = resp.Header.Get("x-amz-request-id")
+	}
+	if errResp.HostID == "" {
+		errResp.HostID = resp.Header.Get("x-amz-id-2")
+	}
+	if errResp.Region == "" {
+		errResp.Region = resp.Header.Get("x-amz-bucket-region")
+	}
+	resp.Body = errorBody{errResp}
+	return resp, nil
+}
+
+// requestRetryBudget returns the retry budget of the transfer req is
+// made for, nil if it has none.
+func requestRetryBudget(req *http.Request) *retryBudget {
+	budget, _ := req.Context().Value(retryBudgetContextKey{}).(*retryBudget)
+	return budget
+}
+
+// errorBody - a response body failing with err.
+type errorBody struct {
+	err error
+}
+
+func (b errorBody) Read([]byte) (int, error) {
+	return 0, b.err
+}
+
+func (b errorBody) Close() error {
+	return nil
+}
+
+// streamedCopyContextKey holds the connection slot reserved for the
+// upload requests of a copy reading from a remote source, see
+// reserveStreamedCopy().
+type streamedCopyContextKey struct{}
+
+// reserveStreamedCopy takes a slot of limiter for the upload of a copy
+// reading from a remote source, before its download is sent. Copies
+// hold a slot of reservations as long, which has fewer slots than
+// limiter: downloads always get a slot in the end while the uploads
+// holding the others wait for the body they read. It returns the
+// context of the upload requests and a function giving the slots back.
+func reserveStreamedCopy(ctx context.Context, limiter, reservations chan struct{}) (context.Context, func(), *probe.Error) {
+	select {
+	case reservations <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, probe.NewError(ctx.Err())
+	}
+	select {
+	case limiter <- struct{}{}:
+	case <-ctx.Done():
+		<-reservations
+		return nil, nil, probe.NewError(ctx.Err())
+	}
+	// Upload requests take the slot by sending to it, as to limiter.
+	slot := make(chan struct{}, 1)
+	release := func() {
+		slot <- struct{}{}
+		<-limiter
+		<-reservations
+	}
+	return context.WithValue(ctx, streamedCopyContextKey{}, slot), release, nil
+}
+
+// lookupBucketLocation - sends the bucket location lookup of an object
+// storage URL now, minio-go makes it without the context of the request
+// needing it so it can't use the slot reserveStreamedCopy() reserved.
+// The location is cached for the later requests.
+func lookupBucketLocation(alias, urlStr string) *probe.Error {
+	clnt, err := newClientFromAlias(alias, urlStr)
+	if err != nil {
+		return err.Trace(alias, urlStr)
+	}
+	s3c, ok := clnt.(*s3Client)
+	if !ok {
+		return nil
+	}
+	bucket, _ := s3c.url2BucketAndObject()
+	if bucket == "" {
+		return nil
+	}
+	if _, e := s3c.client().GetBucketLocation(bucket); e != nil {
+		return probe.NewError(e).Trace(alias, urlStr)
+	}
+	return nil
+}
+
+// connLimitTransport - bounds the number of connections in use with a
+// semaphore shared by all clients. A slot is held until the response
+// body is read or closed. Uploads of a streamed copy use the slot
+// reserved for them first, or any free one.
+type connLimitTransport struct {
+	limiter   chan struct{}
+	transport http.RoundTripper
+}
+
+func (t connLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	slot, _ := req.Context().Value(streamedCopyContextKey{}).(chan struct{})
+	limiter := slot
+	select {
+	case slot <- struct{}{}:
+	default:
+		limiter = t.limiter
+		select {
+		case t.limiter <- struct{}{}:
+		case slot <- struct{}{}:
+			limiter = slot
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	resp, e := t.transport.RoundTrip(req)
+	if e != nil || resp.Body == nil || resp.Body == http.NoBody {
+		<-limiter
+		return resp, e
+	}
+	resp.Body = &connLimitBody{ReadCloser: resp.Body, limiter: limiter}
+	return resp, nil
+}
+
+// connLimitBody - releases the slot of its request once the body is
+// read to the end or closed.
+type connLimitBody struct {
+	io.ReadCloser
+	limiter chan struct{}
+	once    sync.Once
+}
+
+func (b *connLimitBody) release() {
+	b.once.Do(func() { <-b.limiter })
+}
+
+func (b *connLimitBody) Read(p []byte) (int, error) {
+	n, e := b.ReadCloser.Read(p)
+	if e != nil {
+		b.release()
+	}
+	return n, e
+}
+
+func (b *connLimitBody) Close() error {
+	e := b.ReadCloser.Close()
+	b.release()
+	return e
+}
+
+// ifMatchContextKey holds the ifMatchCondition of an upload performed
+// with that context.
+type ifMatchContextKey struct{}
+
+// ifMatchCondition - the ETag an object must still have for its upload
+// to be applied.
+type ifMatchCondition struct {
+	bucket, object string
+	etag           string
+}
+
+// isObjectRequest tells if req targets the object of the condition,
+// in path or virtual host style.
+func (cond ifMatchCondition) isObjectRequest(req *http.Request) bool {
+	if req.URL.Path == "/"+cond.bucket+"/"+cond.object {
+		return true
+	}
+	return req.URL.Path == "/"+cond.object && strings.HasPrefix(req.URL.Host, cond.bucket+".")
+}
+
+// preconditionTransport - sends If-Match on the requests which create
+// the object of the ifMatchCondition of the request context. Part
+// uploads are left alone, the precondition is checked once the upload
+// completes.
+type preconditionTransport struct {
+	transport http.RoundTripper
+}
+
+func (t preconditionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cond, ok := req.Context().Value(ifMatchContextKey{}).(ifMatchCondition)
+	if !ok || !cond.isObjectRequest(req) {
+		return t.transport.RoundTrip(req)
+	}
+	query := req.URL.Query()
+	isPutObject := req.Method == http.MethodPut && len(query) == 0
+	isCompleteUpload := req.Method == http.MethodPost && len(query) == 1 && query.Get("uploadId") != ""
+	if isPutObject || isCompleteUpload {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-Match", cond.etag)
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// bucketLocationTransport - answers bucket location lookups which give
+// no location, such as on hosts without the API, with defaultRegion so
+// that requests are signed for it instead of failing. Redirected
+// lookups answer the region of their x-amz-bucket-region header.
+type bucketLocationTransport struct {
+	defaultRegion string
+	transport     http.RoundTripper
+}
+
+func (t bucketLocationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query()
+	if _, ok := query["location"]; !ok || len(query) != 1 || req.Method != http.MethodGet {
+		return t.transport.RoundTrip(req)
+	}
+	resp, e := t.transport.RoundTrip(req)
+	if e != nil || resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound {
+		return resp, e
+	}
+	region := resp.Header.Get("x-amz-bucket-region")
+	if region == "" {
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			region = t.defaultRegion
+		default:
+			return resp, nil
+		}
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxRegionResponseSize))
+	resp.Body.Close()
+
+	var body bytes.Buffer
+	body.WriteString("<LocationConstraint>")
+	xml.EscapeText(&body, []byte(region))
+	body.WriteString("</LocationConstraint>")
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         resp.Proto,
+		ProtoMajor:    resp.ProtoMajor,
+		ProtoMinor:    resp.ProtoMinor,
+		Header:        http.Header{"Content-Type": []string{"application/xml"}},
+		Body:          ioutil.NopCloser(&body),
+		ContentLength: int64(body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// Payload hash of streaming signed requests and of an empty payload.
+const (
+	streamingPayload  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	emptyPayloadHash  = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	signV4Credential  = "Credential="
+	decodedLengthName = "X-Amz-Decoded-Content-Length"
+)
+
+// emptyPutTransport - uploads empty objects with an explicit
+// "Content-Length: 0". minio-go signs them as a stream of unset length,
+// which net/http sends chunked and strict servers refuse, so they are
+// signed again with the hash of an empty payload.
+type emptyPutTransport struct {
+	accessKey, secretKey string
+	transport            http.RoundTripper
+}
+
+func (t emptyPutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPut || req.Header.Get("X-Amz-Content-Sha256") != streamingPayload || req.Header.Get(decodedLengthName) != "0" {
+		return t.transport.RoundTrip(req)
+	}
+	// The region is the third element of the credential scope.
+	auth := req.Header.Get("Authorization")
+	i := strings.Index(auth, signV4Credential)
+	if i < 0 {
+		return t.transport.RoundTrip(req)
+	}
+	scope := strings.Split(strings.SplitN(auth[i+len(signV4Credential):], ",", 2)[0], "/")
+	if len(scope) < 3 {
+		return t.transport.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	emptyReq := req.Clone(req.Context())
+	emptyReq.Header.Del("Authorization")
+	emptyReq.Header.Del(decodedLengthName)
+	emptyReq.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	emptyReq.Body = http.NoBody
+	emptyReq.ContentLength = 0
+	emptyReq = s3signer.SignV4(*emptyReq, t.accessKey, t.secretKey, "", scope[2])
+	return t.transport.RoundTrip(emptyReq)
+}
+
 // newFactory encloses New function with client cache.
 func newFactory() func(config *Config) (Client, *probe.Error) {
 	clientCache := make(map[uint32]*minio.Client)
@@ -96,18 +617,43 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 			useTLS = false
 		}
 
+		rootCAs, err := getRootCAs(config.CACertFile)
+		if err != nil {
+			return nil, err.Trace(config.CACertFile)
+		}
+
 		// Instantiate s3
 		s3Clnt := &s3Client{}
 		// Allocate a new mutex.
 		s3Clnt.mutex = new(sync.Mutex)
 		// Save the target URL.
 		s3Clnt.targetURL = targetURL
+		// Save the listing delimiter.
+		s3Clnt.delimiter = config.Delimiter
+		// Save the config, region redirects create a client from it.
+		clientConfig := *config
+		s3Clnt.config = &clientConfig
 
 		// Save if target supports virtual host style.
 		hostName := targetURL.Host
-		s3Clnt.virtualStyle = isVirtualHostStyle(hostName, config.Lookup)
+		lookup := config.Lookup
+		if config.PathStyle {
+			lookup = minio.BucketLookupPath
+		}
+		s3Clnt.virtualStyle = isVirtualHostStyle(hostName, lookup)
 		isS3AcceleratedEndpoint := isAmazonAccelerated(hostName)
 
+		if config.DualStack {
+			dualStackHost, reason := amazonDualStackHost(hostName)
+			if reason == "" && isS3AcceleratedEndpoint && !s3Clnt.virtualStyle {
+				reason = "transfer acceleration does not support path style lookup"
+			}
+			if reason != "" {
+				return nil, probe.NewError(DualStackNotSupported{Host: hostName, Reason: reason})
+			}
+			hostName = dualStackHost
+		}
+
 		if s3Clnt.virtualStyle {
 			// If Google URL replace it with 'storage.googleapis.com'
 			if isGoogle(hostName) {
@@ -116,7 +662,15 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 		}
 		// Generate a hash out of s3Conf.
 		confHash := fnv.New32a()
-		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey))
+		confHash.Write([]byte(hostName + config.AccessKey + config.SecretKey + config.Region + config.CACertFile))
+		if config.DualStack {
+			// Same host may be reached without the IPv6 preference.
+			confHash.Write([]byte("dualstack"))
+		}
+		if lookup == minio.BucketLookupPath {
+			// Same host may be reached with virtual host style.
+			confHash.Write([]byte("path"))
+		}
 		confSum := confHash.Sum32()
 
 		// Lookup previous cache by hash.
@@ -137,8 +691,8 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 			options := minio.Options{
 				Creds:        creds,
 				Secure:       useTLS,
-				Region:       "",
-				BucketLookup: config.Lookup,
+				Region:       config.Region,
+				BucketLookup: lookup,
 			}
 
 			api, e = minio.NewWithOptions(hostName, &options)
@@ -146,65 +700,46 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 				return nil, probe.NewError(e)
 			}
 
-			tr := &http.Transport{
-				Proxy: http.ProxyFromEnvironment,
-				DialContext: (&net.Dialer{
-					Timeout:   30 * time.Second,
-					KeepAlive: 30 * time.Second,
-				}).DialContext,
-				MaxIdleConns:          1024,
-				MaxIdleConnsPerHost:   1024,
-				IdleConnTimeout:       90 * time.Second,
-				TLSHandshakeTimeout:   10 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-				// Set this value so that the underlying transport round-tripper
-				// doesn't try to auto decode the body of objects with
-				// content-encoding set to `gzip`.
-				//
-				// Refer:
-				//    https://golang.org/src/net/http/transport.go?h=roundTrip#L1843
-				DisableCompression: true,
-			}
-
-			if useTLS {
-				// Keep TLS config.
-				tlsConfig := &tls.Config{
-					RootCAs: globalRootCAs,
-					// Can't use SSLv3 because of POODLE and BEAST
-					// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
-					// Can't use TLSv1.1 because of RC4 cipher usage
-					MinVersion: tls.VersionTLS12,
-				}
-				if config.Insecure {
-					tlsConfig.InsecureSkipVerify = true
-				}
-				tr.TLSClientConfig = tlsConfig
-
-				// Because we create a custom TLSClientConfig, we have to opt-in to HTTP/2.
-				// See https://github.com/golang/go/issues/14275
-				//
-				// TODO: Enable http2.0 when upstream issues related to HTTP/2 are fixed.
-				//
-				// if e = http2.ConfigureTransport(tr); e != nil {
-				// 	return nil, probe.NewError(e)
-				// }
-			}
+			tr := newHostTransport(config, rootCAs, useTLS)
 
-			var transport http.RoundTripper = tr
+			transport := limitTransport(config, preconditionTransport{transport: emptyPutTransport{
+				accessKey: config.AccessKey,
+				secretKey: config.SecretKey,
+				transport: tr,
+			}})
 			if config.Debug {
-				if strings.EqualFold(config.Signature, "S3v4") {
+				if strings.EqualFold(config.Signature, "S3v4") || strings.EqualFold(config.Signature, "GCS") {
 					transport = httptracer.GetNewTraceTransport(newTraceV4(), transport)
 				} else if strings.EqualFold(config.Signature, "S3v2") {
 					transport = httptracer.GetNewTraceTransport(newTraceV2(), transport)
 				}
 			}
+			if len(config.RetryStatusCodes) > 0 {
+				transport = retryStatusTransport{
+					statuses:  config.RetryStatusCodes,
+					maxRetry:  defaultRetryAttempts,
+					transport: transport,
+				}
+			}
+
+			defaultRegion := config.DefaultRegion
+			if defaultRegion == "" {
+				defaultRegion = "us-east-1"
+			}
+			transport = bucketLocationTransport{defaultRegion: defaultRegion, transport: transport}
+
+			// Outermost, only minio-go sees the answers it makes up.
+			transport = finalAnswerTransport{
+				isStatusRetried: len(config.RetryStatusCodes) > 0,
+				transport:       transport,
+			}
 
 			// Set the new transport.
 			api.SetCustomTransport(transport)
 
 			// If Amazon Accelerated URL is requested enable it.
 			if isS3AcceleratedEndpoint {
-				api.SetS3TransferAccelerate(amazonHostNameAccelerated)
+				api.SetS3TransferAccelerate(hostName)
 			}
 
 			// Set app info.
@@ -217,6 +752,10 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 		// Store the new api object.
 		s3Clnt.api = api
 
+		if globalOffline {
+			// The configuration is checked but the host never reached.
+			return offlineClient{url: *targetURL}, nil
+		}
 		return s3Clnt, nil
 	}
 }
@@ -225,6 +764,143 @@ func newFactory() func(config *Config) (Client, *probe.Error) {
 // it also enables an internal trace transport.
 var s3New = newFactory()
 
+// newHostTransport returns a transport dialing with the timeouts and
+// local address of config, verifying TLS certificates with rootCAs.
+func newHostTransport(config *Config, rootCAs *x509.CertPool, useTLS bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+		LocalAddr: localTCPAddr(config.BindAddr),
+	}
+	dialContext := dialer.DialContext
+	if config.DualStack {
+		dialContext = preferIPv6Dial(dialer)
+	}
+
+	tr := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialContext,
+		MaxIdleConns:          config.maxIdleConns(),
+		MaxIdleConnsPerHost:   config.maxIdleConnsPerHost(),
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		// Set this value so that the underlying transport round-tripper
+		// doesn't try to auto decode the body of objects with
+		// content-encoding set to `gzip`.
+		//
+		// Refer:
+		//    https://golang.org/src/net/http/transport.go?h=roundTrip#L1843
+		DisableCompression: true,
+	}
+
+	if useTLS {
+		// Keep TLS config.
+		tlsConfig := &tls.Config{
+			RootCAs: rootCAs,
+			// Can't use SSLv3 because of POODLE and BEAST
+			// Can't use TLSv1.0 because of POODLE and BEAST using CBC cipher
+			// Can't use TLSv1.1 because of RC4 cipher usage
+			MinVersion: tls.VersionTLS12,
+		}
+		if config.Insecure {
+			tlsConfig.InsecureSkipVerify = true
+		}
+		tr.TLSClientConfig = tlsConfig
+
+		// Because we create a custom TLSClientConfig, we have to opt-in to HTTP/2.
+		// See https://github.com/golang/go/issues/14275
+		//
+		// TODO: Enable http2.0 when upstream issues related to HTTP/2 are fixed.
+		//
+		// if e = http2.ConfigureTransport(tr); e != nil {
+		// 	return nil, probe.NewError(e)
+		// }
+	}
+	return tr
+}
+
+// limitTransport wraps transport with the read and request timeouts and
+// the connection limit of config.
+func limitTransport(config *Config, transport http.RoundTripper) http.RoundTripper {
+	if config.ReadTimeout > 0 {
+		transport = readTimeoutTransport{timeout: config.ReadTimeout, transport: transport}
+	}
+	if config.RequestTimeout > 0 {
+		transport = requestTimeoutTransport{timeout: config.RequestTimeout, transport: transport}
+	}
+	if config.ConnLimiter != nil {
+		transport = connLimitTransport{limiter: config.ConnLimiter, transport: transport}
+	}
+	return transport
+}
+
+// Extracts the expected region from AuthorizationHeaderMalformed messages.
+var expectedRegionRgx = regexp.MustCompile(`expecting '([^']+)'`)
+
+// redirectedRegion returns the region a server redirects a request
+// signed for the wrong region to, or "" for any other error.
+func redirectedRegion(e error) string {
+	errResponse := minio.ToErrorResponse(e)
+	switch {
+	case errResponse.Code == "PermanentRedirect":
+	case errResponse.Code == "AuthorizationHeaderMalformed":
+	case errResponse.Code == "InvalidRegion":
+	case errResponse.StatusCode == http.StatusMovedPermanently:
+	case errResponse.StatusCode == http.StatusBadRequest && errResponse.Region != "":
+		// HEAD responses have no body, only the region header.
+	default:
+		return ""
+	}
+	if errResponse.Region != "" {
+		return errResponse.Region
+	}
+	if match := expectedRegionRgx.FindStringSubmatch(errResponse.Message); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// followRegionRedirect switches the client to the region a failed
+// request was redirected to and reports whether to retry the request.
+// Amazon S3 endpoints follow the region, other hosts keep theirs.
+// Requests of other goroutines redirected to the region the client
+// switched to in the meantime are retried as well.
+func (c *s3Client) followRegionRedirect(e error) bool {
+	if !c.config.RegionRedirect {
+		return false
+	}
+	region := redirectedRegion(e)
+	if region == "" {
+		return false
+	}
+	c.apiMutex.Lock()
+	defer c.apiMutex.Unlock()
+	if region == c.config.Region {
+		return c.redirected
+	}
+	config := *c.config
+	config.Region = region
+	clnt, err := s3New(&config)
+	if err != nil {
+		return false
+	}
+	c.api = clnt.(*s3Client).api
+	c.config.Region = region
+	c.redirected = true
+	saveRedirectedRegion(c.targetURL, region)
+	return true
+}
+
+// client returns the minio client of c, replaced when a region redirect
+// is followed.
+func (c *s3Client) client() *minio.Client {
+	c.apiMutex.RLock()
+	defer c.apiMutex.RUnlock()
+	return c.api
+}
+
 // GetURL get url.
 func (c *s3Client) GetURL() clientURL {
 	return *c.targetURL
@@ -240,7 +916,7 @@ func (c *s3Client) AddNotificationConfig(arn string, events []string, prefix, su
 	}
 
 	// Get any enabled notification.
-	mb, e := c.api.GetBucketNotification(bucket)
+	mb, e := c.client().GetBucketNotification(bucket)
 	if e != nil {
 		return probe.NewError(e)
 	}
@@ -286,7 +962,7 @@ func (c *s3Client) AddNotificationConfig(arn string, events []string, prefix, su
 	}
 
 	// Set the new bucket configuration
-	if err := c.api.SetBucketNotification(bucket, mb); err != nil {
+	if err := c.client().SetBucketNotification(bucket, mb); err != nil {
 		if ignoreExisting && strings.Contains(err.Error(), "An object key name filtering rule defined with overlapping prefixes, overlapping suffixes, or overlapping combinations of prefixes and suffixes for the same event types") {
 			return nil
 		}
@@ -300,13 +976,13 @@ func (c *s3Client) RemoveNotificationConfig(arn string) *probe.Error {
 	bucket, _ := c.url2BucketAndObject()
 	// Remove all notification configs if arn is empty
 	if arn == "" {
-		if err := c.api.RemoveAllBucketNotification(bucket); err != nil {
+		if err := c.client().RemoveAllBucketNotification(bucket); err != nil {
 			return probe.NewError(err)
 		}
 		return nil
 	}
 
-	mb, e := c.api.GetBucketNotification(bucket)
+	mb, e := c.client().GetBucketNotification(bucket)
 	if e != nil {
 		return probe.NewError(e)
 	}
@@ -329,7 +1005,7 @@ func (c *s3Client) RemoveNotificationConfig(arn string) *probe.Error {
 	}
 
 	// Set the new bucket configuration
-	if e := c.api.SetBucketNotification(bucket, mb); e != nil {
+	if e := c.client().SetBucketNotification(bucket, mb); e != nil {
 		return probe.NewError(e)
 	}
 	return nil
@@ -347,7 +1023,7 @@ type notificationConfig struct {
 func (c *s3Client) ListNotificationConfigs(arn string) ([]notificationConfig, *probe.Error) {
 	var configs []notificationConfig
 	bucket, _ := c.url2BucketAndObject()
-	mb, e := c.api.GetBucketNotification(bucket)
+	mb, e := c.client().GetBucketNotification(bucket)
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
@@ -565,7 +1241,7 @@ func (c *s3Client) Select(expression string, sse encrypt.ServerSide, selOpts Sel
 
 	opts.InputSerialization = selectObjectInputOpts(selOpts, object)
 	opts.OutputSerialization = selectObjectOutputOpts(selOpts, opts.InputSerialization)
-	reader, e := c.api.SelectObjectContent(context.Background(), bucket, object, opts)
+	reader, e := c.client().SelectObjectContent(context.Background(), bucket, object, opts)
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
@@ -574,7 +1250,7 @@ func (c *s3Client) Select(expression string, sse encrypt.ServerSide, selOpts Sel
 
 func (c *s3Client) watchOneBucket(bucket, prefix, suffix string, events []string, doneCh chan struct{}, eventChan chan EventInfo, errorChan chan *probe.Error) {
 	// Start listening on all bucket events.
-	eventsCh := c.api.ListenBucketNotification(bucket, prefix, suffix, events, doneCh)
+	eventsCh := c.client().ListenBucketNotification(bucket, prefix, suffix, events, doneCh)
 	for notificationInfo := range eventsCh {
 		if notificationInfo.Err != nil {
 			if nErr, ok := notificationInfo.Err.(minio.ErrorResponse); ok && nErr.Code == "APINotSupported" {
@@ -696,7 +1372,7 @@ func (c *s3Client) Watch(params watchParams) (*watchObject, *probe.Error) {
 	// The list of buckets to watch
 	var buckets []string
 	if bucket == "" {
-		bkts, err := c.api.ListBuckets()
+		bkts, err := c.client().ListBuckets()
 		if err != nil {
 			return nil, probe.NewError(err)
 		}
@@ -747,11 +1423,100 @@ func (c *s3Client) Watch(params watchParams) (*watchObject, *probe.Error) {
 
 // Get - get object with metadata.
 func (c *s3Client) Get(sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	return c.get(minio.GetObjectOptions{ServerSideEncryption: sse})
+}
+
+// GetRange - get a reader for length bytes of the object from offset,
+// up to its end if length is negative.
+func (c *s3Client) GetRange(offset, length int64, sse encrypt.ServerSide) (io.ReadCloser, *probe.Error) {
+	opts := minio.GetObjectOptions{ServerSideEncryption: sse}
+	if length == 0 {
+		// No range of zero bytes, stat to fail on missing objects.
+		bucket, object := c.url2BucketAndObject()
+		if _, err := c.getObjectStat(bucket, object, minio.StatObjectOptions{GetObjectOptions: opts}); err != nil {
+			return nil, err.Trace(c.GetURL().String())
+		}
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	end := int64(0)
+	if length > 0 {
+		end = offset + length - 1
+	}
+	if offset > 0 || length > 0 {
+		if e := opts.SetRange(offset, end); e != nil {
+			return nil, probe.NewError(e)
+		}
+	}
+	return c.get(opts)
+}
+
+// getObject returns the object at bucket/object once its GET answered,
+// minio.Object sends it on first use only which a read of no bytes is.
+func (c *s3Client) getObject(bucket, object string, opts minio.GetObjectOptions) (*minio.Object, error) {
+	obj, e := c.client().GetObject(bucket, object, opts)
+	if e != nil {
+		return nil, e
+	}
+	if _, e = obj.Read(nil); e != nil && e != io.EOF {
+		obj.Close()
+		return nil, e
+	}
+	return obj, nil
+}
+
+// objectReader - a minio.Object not sending its GET again when seeked
+// where it already is, as minio-go does with the body of an upload
+// before sending it.
+type objectReader struct {
+	*minio.Object
+	offset int64
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	n, e := r.Object.Read(p)
+	r.offset += int64(n)
+	return n, e
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekCurrent {
+		offset, whence = r.offset+offset, io.SeekStart
+	}
+	if whence == io.SeekStart && offset == r.offset {
+		return offset, nil
+	}
+	offset, e := r.Object.Seek(offset, whence)
+	if e == nil {
+		r.offset = offset
+	}
+	return offset, e
+}
+
+func (c *s3Client) get(opts minio.GetObjectOptions) (io.ReadCloser, *probe.Error) {
 	bucket, object := c.url2BucketAndObject()
-	opts := minio.GetObjectOptions{}
-	opts.ServerSideEncryption = sse
-	reader, e := c.api.GetObject(bucket, object, opts)
+	var reader io.ReadCloser
+	var e error
+	if opts.Header().Get("Range") != "" {
+		// The stat of a minio.Object drops its range, ranges are
+		// fetched at once instead and fail right away.
+		reader, _, _, e = minio.Core{Client: c.client()}.GetObject(bucket, object, opts)
+		if e != nil && c.followRegionRedirect(e) {
+			reader, _, _, e = minio.Core{Client: c.client()}.GetObject(bucket, object, opts)
+		}
+	} else {
+		var obj *minio.Object
+		obj, e = c.getObject(bucket, object, opts)
+		if e != nil && c.followRegionRedirect(e) {
+			obj, e = c.getObject(bucket, object, opts)
+		}
+		if obj != nil {
+			reader = &objectReader{Object: obj}
+		}
+	}
 	if e != nil {
+		if isErrKeyRequired(e, opts.ServerSideEncryption) {
+			return nil, probe.NewError(ObjectKeyRequired{Path: c.targetURL.String()})
+		}
 		errResponse := minio.ToErrorResponse(e)
 		if errResponse.Code == "NoSuchBucket" {
 			return nil, probe.NewError(BucketDoesNotExist{
@@ -785,15 +1550,35 @@ func (c *s3Client) Copy(source string, size int64, progress io.Reader, srcSSE, t
 	// Source object
 	src := minio.NewSourceInfo(tokens[1], tokens[2], srcSSE)
 
+	// Source headers are sent verbatim on single request copies,
+	// larger objects are copied in parts which If-Match can't cover.
+	ifMatch, ok := metadata[ifMatchMetaKey]
+	if ok {
+		delete(metadata, ifMatchMetaKey)
+		if size > maxSingleCopySize {
+			return probe.NewError(errors.New("--if-match is not supported for server side copies above 5GiB"))
+		}
+		src.Headers.Set("If-Match", ifMatch)
+	}
+
 	// Destination object
 	dst, e := minio.NewDestinationInfo(dstBucket, dstObject, tgtSSE, metadata)
 	if e != nil {
 		return probe.NewError(e)
 	}
 
-	if e = c.api.ComposeObjectWithProgress(dst, []minio.SourceInfo{src}, progress); e != nil {
+	e = c.client().ComposeObjectWithProgress(dst, []minio.SourceInfo{src}, progress)
+	if e != nil && c.followRegionRedirect(e) {
+		e = c.client().ComposeObjectWithProgress(dst, []minio.SourceInfo{src}, progress)
+	}
+	if e != nil {
 		errResponse := minio.ToErrorResponse(e)
 		if errResponse.Code == "AccessDenied" {
+			if tgtSSE == nil && c.isEncryptionRequired(dstBucket) {
+				return probe.NewError(EncryptionRequired{
+					Bucket: dstBucket,
+				})
+			}
 			return probe.NewError(PathInsufficientPermission{
 				Path: c.targetURL.String(),
 			})
@@ -811,6 +1596,12 @@ func (c *s3Client) Copy(source string, size int64, progress io.Reader, srcSSE, t
 		if errResponse.Code == "NoSuchKey" {
 			return probe.NewError(ObjectMissing{})
 		}
+		if errResponse.Code == "PreconditionFailed" {
+			return probe.NewError(PreconditionFailed{
+				Object: dstObject,
+				ETag:   ifMatch,
+			})
+		}
 		return probe.NewError(e)
 	}
 	return nil
@@ -871,6 +1662,29 @@ func (c *s3Client) Put(ctx context.Context, reader io.Reader, size int64, metada
 			retainUntilDate = t.UTC()
 		}
 	}
+
+	ifMatch, ok := metadata[ifMatchMetaKey]
+	if ok {
+		delete(metadata, ifMatchMetaKey)
+		ctx = context.WithValue(ctx, ifMatchContextKey{}, ifMatchCondition{bucket, object, ifMatch})
+	}
+
+	threshold := int64(defaultMultipartThreshold)
+	if value, ok := metadata[multipartThresholdMetaKey]; ok {
+		delete(metadata, multipartThresholdMetaKey)
+		if v, e := strconv.ParseInt(value, 10, 64); e == nil {
+			threshold = v
+		}
+	}
+
+	var streamPartSize int64
+	if value, ok := metadata[streamPartSizeMetaKey]; ok {
+		delete(metadata, streamPartSizeMetaKey)
+		if v, e := strconv.ParseInt(value, 10, 64); e == nil && v >= minPartSize {
+			streamPartSize = v
+		}
+	}
+
 	opts := minio.PutObjectOptions{
 		UserMetadata:         metadata,
 		Progress:             progress,
@@ -889,8 +1703,36 @@ func (c *s3Client) Put(ctx context.Context, reader io.Reader, size int64, metada
 	if lockModeStr != "" {
 		opts.Mode = &lockMode
 	}
-	n, e := c.api.PutObjectWithContext(ctx, bucket, object, reader, size, opts)
+	if isMemoryPressure() {
+		opts.NumThreads = 1
+	}
+	opts.PartSize = putPartSize(size, threshold)
+	if size < 0 && streamPartSize > 0 {
+		// Instead of parts as large as needed for the largest object.
+		opts.PartSize = uint64(streamPartSize)
+		// minio-go completes the upload after its last part whatever
+		// is left to read.
+		reader = &partsLimitReader{reader: reader, partSize: streamPartSize, remaining: streamPartSize * maxPartsCount}
+	}
+	// Uploads redirected to the region of their bucket are sent again
+	// if none of the body was read yet, as multipart uploads redirected
+	// when they are created.
+	counter := &countReader{reader: reader}
+	body := io.Reader(counter)
+	if seeker, ok := reader.(io.Seeker); ok {
+		body = struct {
+			io.Reader
+			io.Seeker
+		}{counter, seeker}
+	}
+	n, e := c.putObject(ctx, bucket, object, body, size, opts)
+	if e != nil && counter.n == 0 && c.followRegionRedirect(e) {
+		n, e = c.putObject(ctx, bucket, object, body, size, opts)
+	}
 	if e != nil {
+		if tooLarge, ok := e.(StreamTooLarge); ok {
+			return n, probe.NewError(tooLarge)
+		}
 		errResponse := minio.ToErrorResponse(e)
 		if errResponse.Code == "UnexpectedEOF" || e == io.EOF {
 			return n, probe.NewError(UnexpectedEOF{
@@ -899,6 +1741,11 @@ func (c *s3Client) Put(ctx context.Context, reader io.Reader, size int64, metada
 			})
 		}
 		if errResponse.Code == "AccessDenied" {
+			if sse == nil && c.isEncryptionRequired(bucket) {
+				return n, probe.NewError(EncryptionRequired{
+					Bucket: bucket,
+				})
+			}
 			return n, probe.NewError(PathInsufficientPermission{
 				Path: c.targetURL.String(),
 			})
@@ -913,6 +1760,12 @@ func (c *s3Client) Put(ctx context.Context, reader io.Reader, size int64, metada
 				Object: object,
 			})
 		}
+		if errResponse.Code == "PreconditionFailed" {
+			return n, probe.NewError(PreconditionFailed{
+				Object: object,
+				ETag:   ifMatch,
+			})
+		}
 		if errResponse.Code == "NoSuchBucket" {
 			return n, probe.NewError(BucketDoesNotExist{
 				Bucket: bucket,
@@ -926,11 +1779,49 @@ func (c *s3Client) Put(ctx context.Context, reader io.Reader, size int64, metada
 		if errResponse.Code == "NoSuchKey" {
 			return n, probe.NewError(ObjectMissing{})
 		}
+		if errResponse.Code == "NoSuchUpload" {
+			return n, probe.NewError(UploadNotFound{
+				Object: object,
+			})
+		}
 		return n, probe.NewError(e)
 	}
 	return n, nil
 }
 
+// isEncryptionRequired tells if the policy of bucket denies requests
+// depending on their server side encryption header, which explains an
+// upload without it being denied. A policy which cannot be read tells
+// nothing.
+func (c *s3Client) isEncryptionRequired(bucket string) bool {
+	bucketPolicy, e := c.client().GetBucketPolicy(bucket)
+	if e != nil || bucketPolicy == "" {
+		return false
+	}
+	var policy struct {
+		Statement []struct {
+			Effect    string
+			Condition map[string]map[string]interface{}
+		}
+	}
+	if e = json.Unmarshal([]byte(bucketPolicy), &policy); e != nil {
+		return false
+	}
+	for _, statement := range policy.Statement {
+		if !strings.EqualFold(statement.Effect, "Deny") {
+			continue
+		}
+		for _, condition := range statement.Condition {
+			for key := range condition {
+				if strings.EqualFold(key, "s3:x-amz-server-side-encryption") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // Remove incomplete uploads.
 func (c *s3Client) removeIncompleteObjects(bucket string, objectsCh <-chan string) <-chan minio.RemoveObjectError {
 	removeObjectErrorCh := make(chan minio.RemoveObjectError)
@@ -940,7 +1831,7 @@ func (c *s3Client) removeIncompleteObjects(bucket string, objectsCh <-chan strin
 		defer close(removeObjectErrorCh)
 
 		for object := range objectsCh {
-			if err := c.api.RemoveIncompleteUpload(bucket, object); err != nil {
+			if err := c.client().RemoveIncompleteUpload(bucket, object); err != nil {
 				removeObjectErrorCh <- minio.RemoveObjectError{ObjectName: object, Err: err}
 			}
 		}
@@ -950,7 +1841,7 @@ func (c *s3Client) removeIncompleteObjects(bucket string, objectsCh <-chan strin
 }
 
 func (c *s3Client) AddUserAgent(app string, version string) {
-	c.api.SetAppInfo(app, version)
+	c.client().SetAppInfo(app, version)
 }
 
 // Remove - remove object or bucket(s).
@@ -987,7 +1878,7 @@ func (c *s3Client) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *c
 				if isIncomplete {
 					statusCh = c.removeIncompleteObjects(bucket, objectsCh)
 				} else {
-					statusCh = c.api.RemoveObjects(bucket, objectsCh)
+					statusCh = c.client().RemoveObjects(bucket, objectsCh)
 				}
 			}
 
@@ -996,11 +1887,11 @@ func (c *s3Client) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *c
 					close(objectsCh)
 				}
 				for removeStatus := range statusCh {
-					errorCh <- probe.NewError(removeStatus.Err)
+					errorCh <- removeStatusError(removeStatus)
 				}
 				// Remove bucket if it qualifies.
 				if isRemoveBucket && !isIncomplete {
-					if err := c.api.RemoveBucket(prevBucket); err != nil {
+					if err := c.client().RemoveBucket(prevBucket); err != nil {
 						errorCh <- probe.NewError(err)
 					}
 				}
@@ -1009,7 +1900,7 @@ func (c *s3Client) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *c
 				if isIncomplete {
 					statusCh = c.removeIncompleteObjects(bucket, objectsCh)
 				} else {
-					statusCh = c.api.RemoveObjects(bucket, objectsCh)
+					statusCh = c.client().RemoveObjects(bucket, objectsCh)
 				}
 				prevBucket = bucket
 			}
@@ -1024,7 +1915,7 @@ func (c *s3Client) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *c
 					case objectsCh <- objectName:
 						sent = true
 					case removeStatus := <-statusCh:
-						errorCh <- probe.NewError(removeStatus.Err)
+						errorCh <- removeStatusError(removeStatus)
 					}
 				}
 			} else {
@@ -1042,12 +1933,12 @@ func (c *s3Client) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *c
 		// Write remove objects status to errorCh
 		if statusCh != nil {
 			for removeStatus := range statusCh {
-				errorCh <- probe.NewError(removeStatus.Err)
+				errorCh <- removeStatusError(removeStatus)
 			}
 		}
 		// Remove last bucket if it qualifies.
 		if isRemoveBucket && prevBucket != "" && !isIncomplete {
-			if err := c.api.RemoveBucket(prevBucket); err != nil {
+			if err := c.client().RemoveBucket(prevBucket); err != nil {
 				errorCh <- probe.NewError(err)
 			}
 		}
@@ -1055,6 +1946,15 @@ func (c *s3Client) Remove(isIncomplete, isRemoveBucket bool, contentCh <-chan *c
 	return errorCh
 }
 
+// removeStatusError converts the failure to remove an object of a
+// batch, which does not stop the removal of the others.
+func removeStatusError(status minio.RemoveObjectError) *probe.Error {
+	return probe.NewError(ObjectRemoveFailed{
+		Object: status.ObjectName,
+		Err:    status.Err,
+	}).Trace(status.ObjectName)
+}
+
 // MakeBucket - make a new bucket.
 func (c *s3Client) MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error {
 	bucket, object := c.url2BucketAndObject()
@@ -1070,7 +1970,7 @@ func (c *s3Client) MakeBucket(region string, ignoreExisting, withLock bool) *pro
 		}
 		var retried bool
 		for {
-			_, e := c.api.PutObject(bucket, object,
+			_, e := c.client().PutObject(bucket, object,
 				bytes.NewReader([]byte("")), 0, minio.PutObjectOptions{})
 			if e == nil {
 				return nil
@@ -1081,9 +1981,9 @@ func (c *s3Client) MakeBucket(region string, ignoreExisting, withLock bool) *pro
 			switch minio.ToErrorResponse(e).Code {
 			case "NoSuchBucket":
 				if withLock {
-					e = c.api.MakeBucketWithObjectLock(bucket, region)
+					e = c.client().MakeBucketWithObjectLock(bucket, region)
 				} else {
-					e = c.api.MakeBucket(bucket, region)
+					e = c.client().MakeBucket(bucket, region)
 				}
 				if e != nil {
 					return probe.NewError(e)
@@ -1097,21 +1997,34 @@ func (c *s3Client) MakeBucket(region string, ignoreExisting, withLock bool) *pro
 
 	var e error
 	if withLock {
-		e = c.api.MakeBucketWithObjectLock(bucket, region)
+		e = c.client().MakeBucketWithObjectLock(bucket, region)
 	} else {
-		e = c.api.MakeBucket(bucket, region)
+		e = c.client().MakeBucket(bucket, region)
 	}
 	if e != nil {
-		// Ignore bucket already existing error when ignoreExisting flag is enabled
-		if ignoreExisting {
-			switch minio.ToErrorResponse(e).Code {
-			case "BucketAlreadyOwnedByYou":
-				fallthrough
-			case "BucketAlreadyExists":
-				return nil
+		switch minio.ToErrorResponse(e).Code {
+		case "BucketAlreadyOwnedByYou":
+		case "BucketAlreadyExists":
+			// Some servers answer it for buckets of the account too,
+			// which are told apart by checking whether they exist.
+			exists, be := c.client().BucketExists(bucket)
+			if be != nil {
+				if minio.ToErrorResponse(be).StatusCode == http.StatusForbidden {
+					return probe.NewError(BucketNotAccessible{Bucket: bucket})
+				}
+				return probe.NewError(be)
+			}
+			if !exists {
+				return probe.NewError(e)
 			}
+		default:
+			return probe.NewError(e)
 		}
-		return probe.NewError(e)
+		// Ignore bucket already existing error when ignoreExisting flag is enabled
+		if ignoreExisting {
+			return nil
+		}
+		return probe.NewError(BucketExists{Bucket: bucket})
 	}
 	return nil
 }
@@ -1123,7 +2036,7 @@ func (c *s3Client) GetAccessRules() (map[string]string, *probe.Error) {
 		return map[string]string{}, probe.NewError(BucketNameEmpty{})
 	}
 	policies := map[string]string{}
-	policyStr, e := c.api.GetBucketPolicy(bucket)
+	policyStr, e := c.client().GetBucketPolicy(bucket)
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
@@ -1148,7 +2061,7 @@ func (c *s3Client) GetAccess() (string, string, *probe.Error) {
 	if bucket == "" {
 		return "", "", probe.NewError(BucketNameEmpty{})
 	}
-	policyStr, e := c.api.GetBucketPolicy(bucket)
+	policyStr, e := c.client().GetBucketPolicy(bucket)
 	if e != nil {
 		return "", "", probe.NewError(e)
 	}
@@ -1173,12 +2086,12 @@ func (c *s3Client) SetAccess(bucketPolicy string, isJSON bool) *probe.Error {
 		return probe.NewError(BucketNameEmpty{})
 	}
 	if isJSON {
-		if e := c.api.SetBucketPolicy(bucket, bucketPolicy); e != nil {
+		if e := c.client().SetBucketPolicy(bucket, bucketPolicy); e != nil {
 			return probe.NewError(e)
 		}
 		return nil
 	}
-	policyStr, e := c.api.GetBucketPolicy(bucket)
+	policyStr, e := c.client().GetBucketPolicy(bucket)
 	if e != nil {
 		return probe.NewError(e)
 	}
@@ -1190,7 +2103,7 @@ func (c *s3Client) SetAccess(bucketPolicy string, isJSON bool) *probe.Error {
 	}
 	p.Statements = policy.SetPolicy(p.Statements, policy.BucketPolicy(bucketPolicy), bucket, object)
 	if len(p.Statements) == 0 {
-		if e = c.api.SetBucketPolicy(bucket, ""); e != nil {
+		if e = c.client().SetBucketPolicy(bucket, ""); e != nil {
 			return probe.NewError(e)
 		}
 		return nil
@@ -1199,23 +2112,218 @@ func (c *s3Client) SetAccess(bucketPolicy string, isJSON bool) *probe.Error {
 	if e != nil {
 		return probe.NewError(e)
 	}
-	if e = c.api.SetBucketPolicy(bucket, string(policyB)); e != nil {
+	if e = c.client().SetBucketPolicy(bucket, string(policyB)); e != nil {
 		return probe.NewError(e)
 	}
 	return nil
 }
 
-// listObjectWrapper - select ObjectList version depending on the target hostname
+// isPrefixKey - returns true if key denotes a common prefix of a non
+// recursive listing.
+func (c *s3Client) isPrefixKey(key string) bool {
+	if strings.HasSuffix(key, string(c.targetURL.Separator)) {
+		return true
+	}
+	return c.isCustomDelimiter() && strings.HasSuffix(key, c.delimiter)
+}
+
+// isCustomDelimiter - returns true if listings use a delimiter other than "/".
+func (c *s3Client) isCustomDelimiter() bool {
+	return c.delimiter != "" && c.delimiter != string(c.targetURL.Separator)
+}
+
+// listObjectsDelimiter - lists a single level below prefix using the
+// configured delimiter, common prefixes are sent as entries carrying
+// the delimiter suffix in their key.
+func (c *s3Client) listObjectsDelimiter(bucket, prefix string) <-chan minio.ObjectInfo {
+	objectCh := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objectCh)
+		core := minio.Core{Client: c.client()}
+		var marker string
+		for {
+			var contents []minio.ObjectInfo
+			var prefixes []minio.CommonPrefix
+			var isTruncated bool
+			if c.isGoogle() {
+				result, e := core.ListObjects(bucket, prefix, marker, c.delimiter, 1000)
+				if e != nil {
+					objectCh <- minio.ObjectInfo{Err: e}
+					return
+				}
+				contents, prefixes, isTruncated, marker = result.Contents, result.CommonPrefixes, result.IsTruncated, result.NextMarker
+			} else {
+				result, e := core.ListObjectsV2(bucket, prefix, marker, true, c.delimiter, 1000, "")
+				if e != nil {
+					objectCh <- minio.ObjectInfo{Err: e}
+					return
+				}
+				contents, prefixes, isTruncated, marker = result.Contents, result.CommonPrefixes, result.IsTruncated, result.NextContinuationToken
+			}
+			for _, object := range contents {
+				object.ETag = strings.Trim(object.ETag, "\"")
+				objectCh <- object
+			}
+			for _, commonPrefix := range prefixes {
+				objectCh <- minio.ObjectInfo{Key: commonPrefix.Prefix}
+			}
+			if !isTruncated {
+				return
+			}
+		}
+	}()
+	return objectCh
+}
+
+// ListPage - lists one page of up to maxKeys objects and common
+// prefixes of a bucket, token and nextToken are continuation tokens of
+// ListObjectsV2, or markers of ListObjects for Google Cloud Storage.
+func (c *s3Client) ListPage(isRecursive bool, maxKeys int, token string) ([]*clientContent, string, *probe.Error) {
+	bucket, prefix := c.url2BucketAndObject()
+	if bucket == "" {
+		return nil, "", probe.NewError(BucketNameEmpty{})
+	}
+	delimiter := string(c.targetURL.Separator)
+	if c.isCustomDelimiter() {
+		delimiter = c.delimiter
+	}
+	if isRecursive {
+		delimiter = ""
+	}
+
+	core := minio.Core{Client: c.client()}
+	var objects []minio.ObjectInfo
+	var prefixes []minio.CommonPrefix
+	var nextToken string
+	if c.isGoogle() {
+		result, e := core.ListObjects(bucket, prefix, token, delimiter, maxKeys)
+		if e != nil {
+			return nil, "", probe.NewError(e)
+		}
+		objects, prefixes = result.Contents, result.CommonPrefixes
+		if result.IsTruncated {
+			nextToken = result.NextMarker
+			if nextToken == "" && len(objects) > 0 {
+				// Only sent with a delimiter.
+				nextToken = objects[len(objects)-1].Key
+			}
+		}
+	} else {
+		result, e := core.ListObjectsV2(bucket, prefix, token, true, delimiter, maxKeys, "")
+		if e != nil {
+			if minio.ToErrorResponse(e).Code == "InvalidArgument" && token != "" {
+				return nil, "", probe.NewError(ContinuationTokenInvalid{Token: token})
+			}
+			return nil, "", probe.NewError(e)
+		}
+		objects, prefixes = result.Contents, result.CommonPrefixes
+		if result.IsTruncated {
+			nextToken = result.NextContinuationToken
+		}
+	}
+
+	contents := make([]*clientContent, 0, len(objects)+len(prefixes))
+	for _, object := range objects {
+		object.ETag = strings.Trim(object.ETag, "\"")
+		contents = append(contents, c.objectInfo2ClientContent(bucket, object))
+	}
+	for _, commonPrefix := range prefixes {
+		contents = append(contents, c.objectInfo2ClientContent(bucket, minio.ObjectInfo{Key: commonPrefix.Prefix}))
+	}
+	// Common prefixes are listed apart by S3.
+	sort.SliceStable(contents, func(i, j int) bool {
+		return contents[i].URL.Path < contents[j].URL.Path
+	})
+	return contents, nextToken, nil
+}
+
+// ListAfter - lists the objects below the URL recursively in key order,
+// starting after the key startAfter.
+func (c *s3Client) ListAfter(startAfter string) <-chan *clientContent {
+	contentCh := make(chan *clientContent)
+	go func() {
+		defer close(contentCh)
+		bucket, prefix := c.url2BucketAndObject()
+		if bucket == "" {
+			contentCh <- &clientContent{Err: probe.NewError(BucketNameEmpty{})}
+			return
+		}
+		core := minio.Core{Client: c.client()}
+		// Markers of ListObjects start after the key as well.
+		marker, token := startAfter, ""
+		for {
+			var objects []minio.ObjectInfo
+			var isTruncated bool
+			if c.isGoogle() {
+				result, e := core.ListObjects(bucket, prefix, marker, "", 1000)
+				if e != nil {
+					contentCh <- &clientContent{Err: probe.NewError(e)}
+					return
+				}
+				objects, isTruncated, marker = result.Contents, result.IsTruncated, result.NextMarker
+				if marker == "" && len(objects) > 0 {
+					marker = objects[len(objects)-1].Key
+				}
+			} else {
+				result, e := core.ListObjectsV2(bucket, prefix, token, false, "", 1000, startAfter)
+				if e != nil {
+					contentCh <- &clientContent{Err: probe.NewError(e)}
+					return
+				}
+				objects, isTruncated, token = result.Contents, result.IsTruncated, result.NextContinuationToken
+			}
+			for _, object := range objects {
+				object.ETag = strings.Trim(object.ETag, "\"")
+				contentCh <- c.objectInfo2ClientContent(bucket, object)
+			}
+			if !isTruncated {
+				return
+			}
+		}
+	}()
+	return contentCh
+}
+
+// listObjectWrapper - list objects, listing again in the region the
+// first page was redirected to.
 func (c *s3Client) listObjectWrapper(bucket, object string, isRecursive bool, doneCh chan struct{}, metadata bool) <-chan minio.ObjectInfo {
-	if isGoogle(c.targetURL.Host) {
+	objectCh := c.listObjectVersion(bucket, object, isRecursive, doneCh, metadata)
+	if !c.config.RegionRedirect {
+		return objectCh
+	}
+	redirectCh := make(chan minio.ObjectInfo, 1)
+	go func() {
+		defer close(redirectCh)
+		objectInfo, ok := <-objectCh
+		if ok && objectInfo.Err != nil && c.followRegionRedirect(objectInfo.Err) {
+			objectCh = c.listObjectVersion(bucket, object, isRecursive, doneCh, metadata)
+			objectInfo, ok = <-objectCh
+		}
+		for ; ok; objectInfo, ok = <-objectCh {
+			select {
+			case redirectCh <- objectInfo:
+			case <-doneCh:
+				return
+			}
+		}
+	}()
+	return redirectCh
+}
+
+// listObjectVersion - select ObjectList version depending on the target hostname
+func (c *s3Client) listObjectVersion(bucket, object string, isRecursive bool, doneCh chan struct{}, metadata bool) <-chan minio.ObjectInfo {
+	if !isRecursive && c.isCustomDelimiter() {
+		return c.listObjectsDelimiter(bucket, object)
+	}
+	if c.isGoogle() {
 		// Google Cloud S3 layer doesn't implement ListObjectsV2 implementation
 		// https://github.com/minio/mc/issues/3073
-		return c.api.ListObjects(bucket, object, isRecursive, doneCh)
+		return c.client().ListObjects(bucket, object, isRecursive, doneCh)
 	}
 	if metadata {
-		return c.api.ListObjectsV2WithMetadata(bucket, object, isRecursive, doneCh)
+		return c.client().ListObjectsV2WithMetadata(bucket, object, isRecursive, doneCh)
 	}
-	return c.api.ListObjectsV2(bucket, object, isRecursive, doneCh)
+	return c.client().ListObjectsV2(bucket, object, isRecursive, doneCh)
 }
 
 // Stat - send a 'HEAD' on a bucket or object to fetch its metadata.
@@ -1249,10 +2357,13 @@ func (c *s3Client) Stat(isIncomplete, isFetchMeta, isPreserve bool, sse encrypt.
 
 	// Prefix to pass to minio-go listing in order to fetch a given object/directory
 	prefix := strings.TrimRight(object, string(c.targetURL.Separator))
+	if c.isCustomDelimiter() {
+		prefix = strings.TrimSuffix(prefix, c.delimiter)
+	}
 
 	// If the request is for incomplete upload stat, handle it here.
 	if isIncomplete {
-		for objectMultipartInfo := range c.api.ListIncompleteUploads(bucket, prefix, nonRecursive, nil) {
+		for objectMultipartInfo := range c.client().ListIncompleteUploads(bucket, prefix, nonRecursive, nil) {
 			if objectMultipartInfo.Err != nil {
 				return nil, probe.NewError(objectMultipartInfo.Err)
 			}
@@ -1281,9 +2392,14 @@ func (c *s3Client) Stat(isIncomplete, isFetchMeta, isPreserve bool, sse encrypt.
 
 	for objectStat := range c.listObjectWrapper(bucket, prefix, nonRecursive, nil, false) {
 		if objectStat.Err != nil {
+			// Policies may allow reading objects but not listing them,
+			// an object can still be found with a HEAD request.
+			if minio.ToErrorResponse(objectStat.Err).Code == "AccessDenied" && !strings.HasSuffix(object, string(c.targetURL.Separator)) {
+				return c.getObjectStat(bucket, object, opts)
+			}
 			return nil, probe.NewError(objectStat.Err)
 		}
-		if strings.HasSuffix(objectStat.Key, string(c.targetURL.Separator)) {
+		if c.isPrefixKey(objectStat.Key) {
 			objectMetadata.URL = *c.targetURL
 			objectMetadata.Type = os.ModeDir
 			if isFetchMeta {
@@ -1319,15 +2435,61 @@ func (c *s3Client) Stat(isIncomplete, isFetchMeta, isPreserve bool, sse encrypt.
 	return c.getObjectStat(bucket, object, opts)
 }
 
+// isErrKeyRequired tells if e answers a GET request made without the key
+// of an object encrypted with a customer provided key.
+func isErrKeyRequired(e error, sse encrypt.ServerSide) bool {
+	if sse != nil && sse.Type() == encrypt.SSEC {
+		return false
+	}
+	errResponse := minio.ToErrorResponse(e)
+	if errResponse.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	return errResponse.Code == "InvalidRequest" && strings.Contains(errResponse.Message, "Server Side Encryption")
+}
+
+// isHeadKeyRequired tells if e answers a HEAD request made without the
+// key of an object encrypted with a customer provided key. HEAD requests
+// fail with a 400 without a body for any reason, a GET of the first byte
+// tells if the key is the one.
+func (c *s3Client) isHeadKeyRequired(e error, bucket, object string, sse encrypt.ServerSide) bool {
+	if sse != nil && sse.Type() == encrypt.SSEC {
+		return false
+	}
+	errResponse := minio.ToErrorResponse(e)
+	if errResponse.StatusCode != http.StatusBadRequest || errResponse.Code != errResponse.Message || redirectedRegion(e) != "" {
+		return false
+	}
+	opts := minio.GetObjectOptions{ServerSideEncryption: sse}
+	if e := opts.SetRange(0, 0); e != nil {
+		return false
+	}
+	reader, _, _, e := minio.Core{Client: c.client()}.GetObject(bucket, object, opts)
+	if e != nil {
+		return isErrKeyRequired(e, sse)
+	}
+	// No reader for responses without object headers.
+	if reader != nil {
+		reader.Close()
+	}
+	return false
+}
+
 // getObjectStat returns the metadata of an object from a HEAD call.
 func (c *s3Client) getObjectStat(bucket, object string, opts minio.StatObjectOptions) (*clientContent, *probe.Error) {
 	objectMetadata := &clientContent{}
-	objectStat, e := c.api.StatObject(bucket, object, opts)
+	objectStat, e := c.client().StatObject(bucket, object, opts)
+	if e != nil && c.followRegionRedirect(e) {
+		objectStat, e = c.client().StatObject(bucket, object, opts)
+	}
 	if e != nil {
 		errResponse := minio.ToErrorResponse(e)
 		if errResponse.Code == "AccessDenied" {
 			return nil, probe.NewError(PathInsufficientPermission{Path: c.targetURL.String()})
 		}
+		if c.isHeadKeyRequired(e, bucket, object, opts.ServerSideEncryption) {
+			return nil, probe.NewError(ObjectKeyRequired{Path: c.targetURL.String()})
+		}
 		if errResponse.Code == "NoSuchBucket" {
 			return nil, probe.NewError(BucketDoesNotExist{
 				Bucket: bucket,
@@ -1368,13 +2530,378 @@ func isAmazonChina(host string) bool {
 }
 
 func isAmazonAccelerated(host string) bool {
-	return host == "s3-accelerate.amazonaws.com"
+	return host == amazonHostNameAccelerated || host == amazonHostNameAcceleratedDualStack
+}
+
+// amazonDualStackHost returns the dual-stack form of an Amazon S3
+// endpoint, or the reason why host has none.
+func amazonDualStackHost(host string) (dualStackHost, reason string) {
+	if isAmazonAccelerated(host) {
+		return amazonHostNameAcceleratedDualStack, ""
+	}
+	if !isAmazon(host) {
+		return "", "only Amazon S3 endpoints support it"
+	}
+	endpointURL := url.URL{Host: host}
+	if s3utils.IsAmazonFIPSEndpoint(endpointURL) {
+		return "", "use the dual-stack FIPS endpoint of the region instead"
+	}
+	region := s3utils.GetRegionFromURL(endpointURL)
+	if region == "" {
+		// 's3.amazonaws.com' and 's3-external-1.amazonaws.com'.
+		region = "us-east-1"
+	}
+	if isAmazonChina(host) {
+		return "s3.dualstack." + region + ".amazonaws.com.cn", ""
+	}
+	return "s3.dualstack." + region + ".amazonaws.com", ""
+}
+
+// putObject uploads reader to bucket/object, in parts with putParts
+// when size is known and large enough.
+func (c *s3Client) putObject(ctx context.Context, bucket, object string, reader io.Reader, size int64, opts minio.PutObjectOptions) (int64, error) {
+	if parts, ok := ctx.Value(uploadPartsContextKey{}).(*uploadParts); ok && size >= 0 && opts.PartSize <= uint64(size) {
+		// Left incomplete when cancelled, to continue on resume.
+		return c.putParts(ctx, bucket, object, reader, size, opts, parts)
+	}
+	if size >= 0 && opts.PartSize <= uint64(size) {
+		// minio-go aborts a failed multipart upload with the context of
+		// the upload, which is done when the upload is cancelled. The
+		// upload is aborted here instead, only the one started.
+		parts := &uploadParts{save: func(*uploadParts) {}}
+		n, e := c.putParts(ctx, bucket, object, reader, size, opts, parts)
+		if e != nil && parts.UploadID != "" {
+			minio.Core{Client: c.client()}.AbortMultipartUpload(bucket, object, parts.UploadID)
+		}
+		return n, e
+	}
+	return c.client().PutObjectWithContext(ctx, bucket, object, reader, size, opts)
+}
+
+// abortUpload - abort the multipart upload uploadID of the object, an
+// upload which is gone already is not an error.
+func (c *s3Client) abortUpload(uploadID string) *probe.Error {
+	bucket, object := c.url2BucketAndObject()
+	e := minio.Core{Client: c.client()}.AbortMultipartUpload(bucket, object, uploadID)
+	if e != nil && minio.ToErrorResponse(e).Code != "NoSuchUpload" {
+		return probe.NewError(e).Trace(bucket, object, uploadID)
+	}
+	return nil
+}
+
+// uploadPartsContextKey holds the *uploadParts of an upload performed
+// with that context.
+type uploadPartsContextKey struct{}
+
+// uploadPart - a part of a multipart upload.
+type uploadPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+}
+
+// uploadParts - the parts of a multipart upload done so far, kept by
+// copy sessions to upload only the missing parts on resume.
+type uploadParts struct {
+	UploadID string       `json:"uploadId"`
+	PartSize int64        `json:"partSize"`
+	Parts    []uploadPart `json:"parts"`
+
+	// save is called after each part, and with nil once the upload
+	// is complete.
+	save func(parts *uploadParts)
+}
+
+// putParts uploads reader as a multipart upload of size bytes, the
+// parts of parts which the target still has with the same ETag are
+// skipped. Up to opts.NumThreads parts are uploaded at the same time,
+// each recorded in parts once uploaded.
+func (c *s3Client) putParts(ctx context.Context, bucket, object string, reader io.Reader, size int64, opts minio.PutObjectOptions, parts *uploadParts) (int64, error) {
+	core := minio.Core{Client: c.client()}
+	partSize := int64(opts.PartSize)
+	budget, _ := ctx.Value(retryBudgetContextKey{}).(*retryBudget)
+
+	done := make(map[int]string)
+	if parts.UploadID != "" && parts.PartSize == partSize {
+		uploaded, e := c.listUploadedParts(core, bucket, object, parts.UploadID)
+		switch {
+		case e == nil:
+			for _, part := range parts.Parts {
+				if etag, ok := uploaded[part.Number]; ok && etag == part.ETag {
+					done[part.Number] = part.ETag
+				}
+			}
+		case minio.ToErrorResponse(e).Code == "NoSuchUpload":
+			parts.UploadID = ""
+		default:
+			return 0, e
+		}
+	} else if parts.UploadID != "" {
+		// Parts of another size can't be completed with the new ones.
+		core.AbortMultipartUpload(bucket, object, parts.UploadID)
+		parts.UploadID = ""
+	}
+
+	if parts.UploadID == "" {
+		uploadID, e := core.NewMultipartUpload(bucket, object, opts)
+		if e != nil {
+			return 0, e
+		}
+		parts.UploadID = uploadID
+		parts.PartSize = partSize
+	}
+	parts.Parts = parts.Parts[:0]
+	for number, etag := range done {
+		parts.Parts = append(parts.Parts, uploadPart{Number: number, ETag: etag})
+	}
+	parts.save(parts)
+
+	threads := int(opts.NumThreads)
+	if threads < 1 {
+		threads = 1
+	}
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var uploadErr error
+	// Bytes and ETags of the parts the target has, the workers add to
+	// them while done is only read.
+	var uploaded int64
+	etags := make(map[int]string, len(done))
+	for number, etag := range done {
+		etags[number] = etag
+	}
+	failed := func() error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return uploadErr
+	}
+	// Buffers are handed back by the workers once their part is uploaded.
+	buffers := make(chan []byte, threads)
+	for i := 0; i < threads; i++ {
+		buffers <- nil
+	}
+
+	var total int64
+	for number := 1; total < size && failed() == nil; number++ {
+		length := partSize
+		if size-total < length {
+			length = size - total
+		}
+		total += length
+		if _, ok := done[number]; ok {
+			if e := skipPart(reader, length); e != nil {
+				cancel()
+				wg.Wait()
+				return uploaded, e
+			}
+			mutex.Lock()
+			uploaded += length
+			mutex.Unlock()
+			skipProgress(opts.Progress, length)
+			continue
+		}
+
+		buf := <-buffers
+		if buf == nil {
+			buf = make([]byte, partSize)
+		}
+		if _, e := io.ReadFull(reader, buf[:length]); e != nil {
+			if e == io.ErrUnexpectedEOF {
+				e = io.EOF
+			}
+			cancel()
+			wg.Wait()
+			return uploaded, e
+		}
+		wg.Add(1)
+		go func(number int, buf []byte, length int64) {
+			defer wg.Done()
+			defer func() { buffers <- buf }()
+			part, e := core.PutObjectPartWithContext(uploadCtx, bucket, object, parts.UploadID, number,
+				bytes.NewReader(buf[:length]), length, "", "", opts.ServerSideEncryption)
+			// A failed part is sent again out of the retry budget of the
+			// transfer instead of restarting the whole upload.
+			for e != nil && isErrRetryable(probe.NewError(e), false) {
+				retry, ok := budget.take()
+				if !ok || !waitRetry(uploadCtx, budget.delay, retry) {
+					break
+				}
+				part, e = core.PutObjectPartWithContext(uploadCtx, bucket, object, parts.UploadID, number,
+					bytes.NewReader(buf[:length]), length, "", "", opts.ServerSideEncryption)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if e != nil {
+				if uploadErr == nil {
+					uploadErr = e
+					cancel()
+				}
+				return
+			}
+			etags[number] = part.ETag
+			uploaded += length
+			parts.Parts = append(parts.Parts, uploadPart{Number: number, ETag: part.ETag})
+			parts.save(parts)
+			if opts.Progress != nil {
+				io.CopyN(ioutil.Discard, opts.Progress, length)
+			}
+		}(number, buf, length)
+	}
+	wg.Wait()
+	if uploadErr != nil {
+		return uploaded, uploadErr
+	}
+
+	completeParts := make([]minio.CompletePart, 0, len(etags))
+	for number, etag := range etags {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: number, ETag: etag})
+	}
+	sort.Slice(completeParts, func(i, j int) bool {
+		return completeParts[i].PartNumber < completeParts[j].PartNumber
+	})
+	if _, e := core.CompleteMultipartUploadWithContext(ctx, bucket, object, parts.UploadID, completeParts); e != nil {
+		return total, e
+	}
+	parts.save(nil)
+	return total, nil
+}
+
+// listUploadedParts returns the ETags of the parts of an incomplete
+// upload by part number.
+func (c *s3Client) listUploadedParts(core minio.Core, bucket, object, uploadID string) (map[int]string, error) {
+	uploaded := make(map[int]string)
+	marker := 0
+	for {
+		result, e := core.ListObjectParts(bucket, object, uploadID, marker, maxPartsCount)
+		if e != nil {
+			return nil, e
+		}
+		for _, part := range result.ObjectParts {
+			uploaded[part.PartNumber] = strings.Trim(part.ETag, "\"")
+		}
+		if !result.IsTruncated {
+			return uploaded, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// skipProgress reports the length of a part which is already uploaded
+// to progress without accounting it as transferred.
+func skipProgress(progress io.Reader, length int64) {
+	switch p := progress.(type) {
+	case nil:
+	case interface{ skip(int64) }:
+		p.skip(length)
+	default:
+		io.CopyN(ioutil.Discard, p, length)
+	}
+}
+
+// skipPart moves reader past a part which is already uploaded. Readers
+// wrapping others may implement io.Seeker without moving when what
+// they wrap cannot seek, those are read past instead.
+func skipPart(reader io.Reader, length int64) error {
+	if seeker, ok := reader.(io.Seeker); ok {
+		start, e := seeker.Seek(0, io.SeekCurrent)
+		if e == nil {
+			end, e := seeker.Seek(length, io.SeekCurrent)
+			if e == nil && end == start+length {
+				return nil
+			}
+			if e == nil && end != start {
+				return errors.New("unable to skip an uploaded part, the source moved to an unexpected offset")
+			}
+		}
+	}
+	if _, e := io.CopyN(ioutil.Discard, reader, length); e != nil {
+		return io.EOF
+	}
+	return nil
+}
+
+// partsLimitReader - reads a stream of unknown size up to the bytes of
+// maxPartsCount parts, failing the read of the last bytes when the
+// stream is longer so that the upload is aborted instead of completed
+// with a truncated object.
+type partsLimitReader struct {
+	reader    io.Reader
+	partSize  int64
+	remaining int64
+}
+
+func (r *partsLimitReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) < r.remaining {
+		n, e := r.reader.Read(p)
+		r.remaining -= int64(n)
+		return n, e
+	}
+	n, e := io.ReadFull(r.reader, p[:r.remaining])
+	r.remaining -= int64(n)
+	if e != nil || r.remaining > 0 {
+		return n, e
+	}
+	var next [1]byte
+	if m, _ := io.ReadFull(r.reader, next[:]); m > 0 {
+		return 0, StreamTooLarge{PartSize: r.partSize}
+	}
+	return n, nil
+}
+
+// putPartSize returns the part size of an upload of size bytes, minio-go
+// uploads objects smaller than the part size at once. Objects smaller
+// than threshold get a single PUT, larger ones are uploaded in parts of
+// half of it up to maxBufferedPartSize, a multipart upload of fewer
+// parts costs more requests than it saves on retries. Objects of
+// unknown size keep the minio-go default, which fits the largest objects.
+func putPartSize(size, threshold int64) uint64 {
+	if size < 0 {
+		return 0
+	}
+	if size < threshold && size < maxSinglePutSize {
+		return uint64(size) + 1
+	}
+	partSize := threshold / 2
+	if partSize > maxBufferedPartSize {
+		partSize = maxBufferedPartSize
+	}
+	if fit := (size + maxPartsCount - 1) / maxPartsCount; partSize < fit {
+		partSize = fit
+	}
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	return uint64(partSize)
+}
+
+// preferIPv6Dial returns a DialContext trying IPv6 first, falling back
+// to any address family when no IPv6 connection can be established.
+func preferIPv6Dial(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if network == "tcp" {
+			if conn, err := dialer.DialContext(ctx, "tcp6", addr); err == nil {
+				return conn, nil
+			}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
 }
 
 func isGoogle(host string) bool {
 	return s3utils.IsGoogleEndpoint(url.URL{Host: host})
 }
 
+// isGoogle tells if the target is Google Cloud Storage, either by its
+// host or by the GCS API of its alias.
+func (c *s3Client) isGoogle() bool {
+	return c.config != nil && strings.EqualFold(c.config.Signature, "GCS") || isGoogle(c.targetURL.Host)
+}
+
 // Figure out if the URL is of 'virtual host' style.
 // Use lookup from config to see if dns/path style look
 // up should be used. If it is set to "auto", use virtual
@@ -1390,6 +2917,18 @@ func isVirtualHostStyle(host string, lookup minio.BucketLookupType) bool {
 	return isAmazon(host) && !isAmazonChina(host) || isGoogle(host) || isAmazonAccelerated(host)
 }
 
+// isPathStyleHost - returns true if buckets of host cannot be
+// addressed as sub-domains, that is for IP addresses and single
+// label names such as localhost, which MinIO servers are usually
+// reached by.
+func isPathStyleHost(host string) bool {
+	if h, _, e := net.SplitHostPort(host); e == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	return net.ParseIP(host) != nil || !strings.Contains(host, ".")
+}
+
 // url2BucketAndObject gives bucketName and objectName from URL path.
 func (c *s3Client) url2BucketAndObject() (bucketName, objectName string) {
 	path := c.targetURL.Path
@@ -1479,7 +3018,7 @@ func (c *s3Client) listIncompleteInRoutine(contentCh chan *clientContent) {
 	b, o := c.url2BucketAndObject()
 	switch {
 	case b == "" && o == "":
-		buckets, err := c.api.ListBuckets()
+		buckets, err := c.client().ListBuckets()
 		if err != nil {
 			contentCh <- &clientContent{
 				Err: probe.NewError(err),
@@ -1488,7 +3027,7 @@ func (c *s3Client) listIncompleteInRoutine(contentCh chan *clientContent) {
 		}
 		isRecursive := false
 		for _, bucket := range buckets {
-			for object := range c.api.ListIncompleteUploads(bucket.Name, o, isRecursive, nil) {
+			for object := range c.client().ListIncompleteUploads(bucket.Name, o, isRecursive, nil) {
 				if object.Err != nil {
 					contentCh <- &clientContent{
 						Err: probe.NewError(object.Err),
@@ -1516,7 +3055,7 @@ func (c *s3Client) listIncompleteInRoutine(contentCh chan *clientContent) {
 		}
 	default:
 		isRecursive := false
-		for object := range c.api.ListIncompleteUploads(b, o, isRecursive, nil) {
+		for object := range c.client().ListIncompleteUploads(b, o, isRecursive, nil) {
 			if object.Err != nil {
 				contentCh <- &clientContent{
 					Err: probe.NewError(object.Err),
@@ -1550,7 +3089,7 @@ func (c *s3Client) listIncompleteRecursiveInRoutine(contentCh chan *clientConten
 	b, o := c.url2BucketAndObject()
 	switch {
 	case b == "" && o == "":
-		buckets, err := c.api.ListBuckets()
+		buckets, err := c.client().ListBuckets()
 		if err != nil {
 			contentCh <- &clientContent{
 				Err: probe.NewError(err),
@@ -1559,7 +3098,7 @@ func (c *s3Client) listIncompleteRecursiveInRoutine(contentCh chan *clientConten
 		}
 		isRecursive := true
 		for _, bucket := range buckets {
-			for object := range c.api.ListIncompleteUploads(bucket.Name, o, isRecursive, nil) {
+			for object := range c.client().ListIncompleteUploads(bucket.Name, o, isRecursive, nil) {
 				if object.Err != nil {
 					contentCh <- &clientContent{
 						Err: probe.NewError(object.Err),
@@ -1578,7 +3117,7 @@ func (c *s3Client) listIncompleteRecursiveInRoutine(contentCh chan *clientConten
 		}
 	default:
 		isRecursive := true
-		for object := range c.api.ListIncompleteUploads(b, o, isRecursive, nil) {
+		for object := range c.client().ListIncompleteUploads(b, o, isRecursive, nil) {
 			if object.Err != nil {
 				contentCh <- &clientContent{
 					Err: probe.NewError(object.Err),
@@ -1627,7 +3166,7 @@ func (c *s3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *client
 	var listDir func(bucket, object string) bool
 	listDir = func(bucket, object string) (isStop bool) {
 		isRecursive := false
-		for entry := range c.api.ListIncompleteUploads(bucket, object, isRecursive, nil) {
+		for entry := range c.client().ListIncompleteUploads(bucket, object, isRecursive, nil) {
 			if entry.Err != nil {
 				url := *c.targetURL
 				url.Path = c.joinPath(bucket, object)
@@ -1670,7 +3209,7 @@ func (c *s3Client) listIncompleteRecursiveInRoutineDirOpt(contentCh chan *client
 	if bucket == "" && object == "" {
 		var e error
 		allBuckets = true
-		buckets, e = c.api.ListBuckets()
+		buckets, e = c.client().ListBuckets()
 		if e != nil {
 			contentCh <- &clientContent{Err: probe.NewError(e)}
 			return
@@ -1736,6 +3275,8 @@ func (c *s3Client) objectInfo2ClientContent(bucket string, entry minio.ObjectInf
 	content.ETag = entry.ETag
 	content.Time = entry.LastModified
 	content.Expires = entry.Expires
+	content.OwnerID = entry.Owner.ID
+	content.OwnerName = entry.Owner.DisplayName
 	content.Metadata = map[string]string{}
 	content.UserMetadata = map[string]string{}
 	for k, v := range entry.UserMetadata {
@@ -1744,7 +3285,7 @@ func (c *s3Client) objectInfo2ClientContent(bucket string, entry minio.ObjectInf
 	for k := range entry.Metadata {
 		content.Metadata[k] = entry.Metadata.Get(k)
 	}
-	if strings.HasSuffix(entry.Key, string(c.targetURL.Separator)) && entry.Size == 0 && entry.LastModified.IsZero() {
+	if c.isPrefixKey(entry.Key) && entry.Size == 0 && entry.LastModified.IsZero() {
 		content.Type = os.ModeDir
 		content.Time = time.Now()
 	} else {
@@ -1756,7 +3297,10 @@ func (c *s3Client) objectInfo2ClientContent(bucket string, entry minio.ObjectInf
 
 // Returns bucket stat info of current bucket.
 func (c *s3Client) bucketStat(bucket string) (*clientContent, *probe.Error) {
-	exists, e := c.api.BucketExists(bucket)
+	exists, e := c.client().BucketExists(bucket)
+	if e != nil && c.followRegionRedirect(e) {
+		exists, e = c.client().BucketExists(bucket)
+	}
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
@@ -1766,6 +3310,21 @@ func (c *s3Client) bucketStat(bucket string) (*clientContent, *probe.Error) {
 	return &clientContent{URL: *c.targetURL, Time: time.Unix(0, 0), Type: os.ModeDir}, nil
 }
 
+// bucketCreationDate returns when bucket was created, as reported by the
+// listing of all buckets.
+func (c *s3Client) bucketCreationDate(bucket string) (time.Time, *probe.Error) {
+	buckets, e := c.client().ListBuckets()
+	if e != nil {
+		return time.Time{}, probe.NewError(e)
+	}
+	for _, b := range buckets {
+		if b.Name == bucket {
+			return b.CreationDate, nil
+		}
+	}
+	return time.Time{}, probe.NewError(BucketDoesNotExist{Bucket: bucket})
+}
+
 // Recursively lists objects.
 func (c *s3Client) listRecursiveInRoutineDirOpt(contentCh chan *clientContent, dirOpt DirOpt, metadata bool) {
 	defer close(contentCh)
@@ -1816,7 +3375,7 @@ func (c *s3Client) listRecursiveInRoutineDirOpt(contentCh chan *clientContent, d
 	if bucket == "" && object == "" {
 		var e error
 		allBuckets = true
-		buckets, e = c.api.ListBuckets()
+		buckets, e = c.client().ListBuckets()
 		if e != nil {
 			contentCh <- &clientContent{Err: probe.NewError(e)}
 			return
@@ -1870,7 +3429,7 @@ func (c *s3Client) listInRoutine(contentCh chan *clientContent, metadata bool) {
 	b, o := c.url2BucketAndObject()
 	switch {
 	case b == "" && o == "":
-		buckets, e := c.api.ListBuckets()
+		buckets, e := c.client().ListBuckets()
 		if e != nil {
 			contentCh <- &clientContent{
 				Err: probe.NewError(e),
@@ -1905,7 +3464,7 @@ func (c *s3Client) listInRoutine(contentCh chan *clientContent, metadata bool) {
 			}
 
 			// Avoid sending an empty directory when we are specifically listing it
-			if strings.HasSuffix(object.Key, string(c.targetURL.Separator)) && o == object.Key {
+			if c.isPrefixKey(object.Key) && o == object.Key {
 				continue
 			}
 
@@ -1933,7 +3492,7 @@ func (c *s3Client) listRecursiveInRoutine(contentCh chan *clientContent, metadat
 	b, o := c.url2BucketAndObject()
 	switch {
 	case b == "" && o == "":
-		buckets, err := c.api.ListBuckets()
+		buckets, err := c.client().ListBuckets()
 		if err != nil {
 			contentCh <- &clientContent{
 				Err: probe.NewError(err),
@@ -1999,7 +3558,17 @@ func (c *s3Client) ShareDownload(expires time.Duration) (string, *probe.Error) {
 	bucket, object := c.url2BucketAndObject()
 	// No additional request parameters are set for the time being.
 	reqParams := make(url.Values)
-	presignedURL, e := c.api.PresignedGetObject(bucket, object, expires, reqParams)
+	presignedURL, e := c.client().PresignedGetObject(bucket, object, expires, reqParams)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	return presignedURL.String(), nil
+}
+
+// SharePut - get a presigned URL uploading the object with a PUT request.
+func (c *s3Client) SharePut(expires time.Duration) (string, *probe.Error) {
+	bucket, object := c.url2BucketAndObject()
+	presignedURL, e := c.client().PresignedPutObject(bucket, object, expires)
 	if e != nil {
 		return "", probe.NewError(e)
 	}
@@ -2029,7 +3598,7 @@ func (c *s3Client) ShareUpload(isRecursive bool, expires time.Duration, contentT
 			return "", nil, probe.NewError(e)
 		}
 	}
-	u, m, e := c.api.PresignedPostPolicy(p)
+	u, m, e := c.client().PresignedPostPolicy(p)
 	if e != nil {
 		return "", nil, probe.NewError(e)
 	}
@@ -2040,7 +3609,7 @@ func (c *s3Client) ShareUpload(isRecursive bool, expires time.Duration, contentT
 func (c *s3Client) SetObjectLockConfig(mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit) *probe.Error {
 	bucket, _ := c.url2BucketAndObject()
 
-	err := c.api.SetBucketObjectLockConfig(bucket, mode, validity, unit)
+	err := c.client().SetBucketObjectLockConfig(bucket, mode, validity, unit)
 	if err != nil {
 		return probe.NewError(err)
 	}
@@ -2057,7 +3626,7 @@ func (c *s3Client) PutObjectRetention(mode *minio.RetentionMode, retainUntilDate
 		Mode:             mode,
 		GovernanceBypass: bypassGovernance,
 	}
-	err := c.api.PutObjectRetention(bucket, object, opts)
+	err := c.client().PutObjectRetention(bucket, object, opts)
 	if err != nil {
 		return probe.NewError(err)
 	}
@@ -2071,7 +3640,7 @@ func (c *s3Client) PutObjectLegalHold(lhold *minio.LegalHoldStatus) *probe.Error
 	opts := minio.PutObjectLegalHoldOptions{
 		Status: lhold,
 	}
-	err := c.api.PutObjectLegalHold(bucket, object, opts)
+	err := c.client().PutObjectLegalHold(bucket, object, opts)
 	if err != nil {
 		return probe.NewError(err)
 	}
@@ -2082,7 +3651,7 @@ func (c *s3Client) PutObjectLegalHold(lhold *minio.LegalHoldStatus) *probe.Error
 func (c *s3Client) GetObjectLockConfig() (mode *minio.RetentionMode, validity *uint, unit *minio.ValidityUnit, perr *probe.Error) {
 	bucket, _ := c.url2BucketAndObject()
 
-	mode, validity, unit, err := c.api.GetBucketObjectLockConfig(bucket)
+	mode, validity, unit, err := c.client().GetBucketObjectLockConfig(bucket)
 	if err != nil {
 		return nil, nil, nil, probe.NewError(err)
 	}
@@ -2100,7 +3669,7 @@ func (c *s3Client) GetObjectTagging() (tagging.Tagging, *probe.Error) {
 	if objectName == "" {
 		return tagging.Tagging{}, probe.NewError(ObjectNameEmpty{})
 	}
-	tagXML, err := c.api.GetObjectTagging(bucketName, objectName)
+	tagXML, err := c.client().GetObjectTagging(bucketName, objectName)
 	if err != nil {
 		return tagging.Tagging{}, probe.NewError(err)
 	}
@@ -2121,7 +3690,7 @@ func (c *s3Client) SetObjectTagging(tagMap map[string]string) *probe.Error {
 	if objectName == "" {
 		return probe.NewError(ObjectNameEmpty{})
 	}
-	if err = c.api.PutObjectTagging(bucketName, objectName, tagMap); err != nil {
+	if err = c.client().PutObjectTagging(bucketName, objectName, tagMap); err != nil {
 		return probe.NewError(err)
 	}
 	return nil
@@ -2136,8 +3705,149 @@ func (c *s3Client) DeleteObjectTagging() *probe.Error {
 	if objectName == "" {
 		return probe.NewError(ObjectNameEmpty{})
 	}
-	if err := c.api.RemoveObjectTagging(bucketName, objectName); err != nil {
+	if err := c.client().RemoveObjectTagging(bucketName, objectName); err != nil {
 		return probe.NewError(err)
 	}
 	return nil
 }
+
+// multipartUploadContent - incomplete multipart upload along with the
+// parts uploaded so far.
+type multipartUploadContent struct {
+	URL       clientURL
+	UploadID  string
+	Initiated time.Time
+	Parts     int
+	Size      int64
+	Err       *probe.Error
+}
+
+// Maximum size of a bucket logging config.
+const maxBucketLoggingSize = 64 * 1024
+
+// bucketLogging - server access logging config of a bucket, logs are
+// written under TargetPrefix in TargetBucket when enabled.
+type bucketLogging struct {
+	XMLName        xml.Name `xml:"BucketLoggingStatus"`
+	LoggingEnabled *struct {
+		TargetBucket string
+		TargetPrefix string
+	}
+}
+
+// GetBucketLogging - get the server access logging config of the bucket.
+func (c *s3Client) GetBucketLogging() (bucketLogging, *probe.Error) {
+	var logging bucketLogging
+	bucket, _ := c.url2BucketAndObject()
+	if bucket == "" {
+		return logging, probe.NewError(BucketNameEmpty{})
+	}
+	// minio-go has no logging API, a presigned URL carries the signature.
+	u, e := c.client().Presign(http.MethodGet, bucket, "", time.Minute, url.Values{"logging": []string{""}})
+	if e != nil {
+		return logging, probe.NewError(e)
+	}
+	resp, e := newHTTPClient(c.config.ConnectTimeout, c.config.Insecure).Get(u.String())
+	if e != nil {
+		return logging, probe.NewError(e)
+	}
+	defer resp.Body.Close()
+	body, e := ioutil.ReadAll(io.LimitReader(resp.Body, maxBucketLoggingSize))
+	if e != nil {
+		return logging, probe.NewError(e)
+	}
+	if resp.StatusCode != http.StatusOK {
+		errResponse := minio.ErrorResponse{StatusCode: resp.StatusCode, Code: resp.Status, Message: resp.Status}
+		xml.Unmarshal(body, &errResponse)
+		if errResponse.Code == "NoSuchBucket" {
+			return logging, probe.NewError(BucketDoesNotExist{
+				Bucket: bucket,
+			})
+		}
+		return logging, probe.NewError(errResponse)
+	}
+	if e = xml.Unmarshal(body, &logging); e != nil {
+		return logging, probe.NewError(e)
+	}
+	return logging, nil
+}
+
+// ListMultipartUploads - lists all incomplete multipart uploads under
+// the current prefix initiated longer than olderThan ago, all of them
+// when olderThan is empty, each one carrying the number and total size
+// of the parts uploaded so far.
+func (c *s3Client) ListMultipartUploads(olderThan string) <-chan *multipartUploadContent {
+	uploadCh := make(chan *multipartUploadContent)
+	go func() {
+		defer close(uploadCh)
+		b, o := c.url2BucketAndObject()
+		var buckets []string
+		if b == "" {
+			bucketsInfo, err := c.client().ListBuckets()
+			if err != nil {
+				uploadCh <- &multipartUploadContent{Err: probe.NewError(err)}
+				return
+			}
+			for _, bucket := range bucketsInfo {
+				buckets = append(buckets, bucket.Name)
+			}
+		} else {
+			buckets = []string{b}
+		}
+		core := minio.Core{Client: c.client()}
+	bucketsLoop:
+		for _, bucket := range buckets {
+			var keyMarker, uploadIDMarker string
+			for {
+				result, err := core.ListMultipartUploads(bucket, o, keyMarker, uploadIDMarker, "", 1000)
+				if err != nil {
+					// Other buckets may still be listed.
+					uploadCh <- &multipartUploadContent{Err: probe.NewError(err).Trace(bucket)}
+					continue bucketsLoop
+				}
+				for _, upload := range result.Uploads {
+					// Parts are only listed for the uploads kept.
+					if olderThan != "" && isOlder(upload.Initiated, olderThan) {
+						continue
+					}
+					content := &multipartUploadContent{
+						UploadID:  upload.UploadID,
+						Initiated: upload.Initiated,
+					}
+					content.URL = *c.targetURL
+					content.URL.Path = c.joinPath(bucket, upload.Key)
+					content.Parts, content.Size, err = c.aggregateParts(core, bucket, upload.Key, upload.UploadID)
+					if err != nil {
+						content.Err = probe.NewError(err)
+					}
+					uploadCh <- content
+				}
+				if !result.IsTruncated {
+					break
+				}
+				keyMarker, uploadIDMarker = result.NextKeyMarker, result.NextUploadIDMarker
+			}
+		}
+	}()
+	return uploadCh
+}
+
+// aggregateParts returns the number and the total size of all parts
+// uploaded so far for a given upload id.
+func (c *s3Client) aggregateParts(core minio.Core, bucket, object, uploadID string) (parts int, size int64, err error) {
+	var partNumberMarker int
+	for {
+		result, err := core.ListObjectParts(bucket, object, uploadID, partNumberMarker, 1000)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, part := range result.ObjectParts {
+			parts++
+			size += part.Size
+		}
+		if !result.IsTruncated {
+			return parts, size, nil
+		}
+		partNumberMarker = result.NextPartNumberMarker
+	}
+}
@@ -20,12 +20,26 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"encoding/pem"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/minio/mc/pkg/hookreader"
 	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/credentials"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
 	. "gopkg.in/check.v1"
 )
 
@@ -163,11 +177,7 @@ func (s *TestSuite) TestBucketOperations(c *C) {
 	server := httptest.NewServer(bucket)
 	defer server.Close()
 
-	conf := new(Config)
-	conf.HostURL = server.URL + bucket.resource
-	conf.AccessKey = "WLGDGYAQYIGI833EV05A"
-	conf.SecretKey = "BYvgJM101sHngl2uzjXS/OBF/aMxAN06JrJ3qJlF"
-	conf.Signature = "S3v4"
+	conf := newTestS3Config(server.URL + bucket.resource)
 	s3c, err := s3New(conf)
 	c.Assert(err, IsNil)
 
@@ -211,11 +221,7 @@ func (s *TestSuite) TestObjectOperations(c *C) {
 	server := httptest.NewServer(object)
 	defer server.Close()
 
-	conf := new(Config)
-	conf.HostURL = server.URL + object.resource
-	conf.AccessKey = "WLGDGYAQYIGI833EV05A"
-	conf.SecretKey = "BYvgJM101sHngl2uzjXS/OBF/aMxAN06JrJ3qJlF"
-	conf.Signature = "S3v4"
+	conf := newTestS3Config(server.URL + object.resource)
 	s3c, err := s3New(conf)
 	c.Assert(err, IsNil)
 
@@ -261,3 +267,1385 @@ func (s *TestSuite) TestSelectCompressionType(c *C) {
 		c.Assert(cType, DeepEquals, test.compressionType)
 	}
 }
+
+// Test non recursive listing with a delimiter other than "/".
+func (s *TestSuite) TestListCustomDelimiter(c *C) {
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("delimiter") != "|" || r.URL.Query().Get("prefix") != "logs|" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"><Name>bucket</Name><Prefix>logs|</Prefix><KeyCount>2</KeyCount><MaxKeys>1000</MaxKeys><Delimiter>|</Delimiter><IsTruncated>false</IsTruncated><Contents><Key>logs|today</Key><LastModified>2020-05-21T18:24:21.097Z</LastModified><ETag>&quot;259d04a13802ae09c7e41be50ccc6baa&quot;</ETag><Size>10</Size><Owner><ID>02d6176db174dc93cb1b899f7c6078f08654445fe8cf1b6ce98d8855f66bdbf4</ID><DisplayName>minio</DisplayName></Owner><StorageClass>STANDARD</StorageClass></Contents><CommonPrefixes><Prefix>logs|2019|</Prefix></CommonPrefixes></ListBucketResult>"))
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/logs|")
+	conf.Delimiter = "|"
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	var files, dirs []string
+	for content := range s3c.List(false, false, false, DirNone) {
+		c.Assert(content.Err, IsNil)
+		if content.Type.IsDir() {
+			dirs = append(dirs, content.URL.Path)
+		} else {
+			files = append(files, content.URL.Path)
+			c.Assert(content.ETag, Equals, "259d04a13802ae09c7e41be50ccc6baa")
+			c.Assert(content.OwnerID, Equals, "02d6176db174dc93cb1b899f7c6078f08654445fe8cf1b6ce98d8855f66bdbf4")
+		}
+	}
+	c.Assert(files, DeepEquals, []string{"/bucket/logs|today"})
+	c.Assert(dirs, DeepEquals, []string{"/bucket/logs|2019|"})
+}
+
+// Test listing hosts of aliases with the GCS API, which have no V2 listing.
+func (s *TestSuite) TestListGoogleAPI(c *C) {
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("list-type") == "2" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		w.Write([]byte("<ListBucketResult xmlns=\"http://doc.s3.amazonaws.com/2006-03-01\"><Name>bucket</Name><Prefix></Prefix><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated><Contents><Key>today</Key><LastModified>2020-05-21T18:24:21.097Z</LastModified><ETag>&quot;259d04a13802ae09c7e41be50ccc6baa&quot;</ETag><Size>10</Size></Contents></ListBucketResult>"))
+	})
+	defer server.Close()
+
+	for _, api := range []string{"S3v4", "GCS"} {
+		conf := new(Config)
+		conf.HostURL = server.URL + "/bucket/"
+		conf.AccessKey = "GOOGTS7C7FUP3AIRVJTE"
+		conf.SecretKey = "bGoa+V7g/yqDXvKRqq+JTFn4uQZbPiQJo4pf9RzJ"
+		conf.Signature = api
+		s3c, err := s3New(conf)
+		c.Assert(err, IsNil)
+
+		contents, _, err := s3c.ListPage(true, 1000, "")
+		if api == "S3v4" {
+			c.Assert(err, NotNil)
+			continue
+		}
+		c.Assert(err, IsNil)
+		c.Assert(contents, HasLen, 1)
+		c.Assert(contents[0].URL.Path, Equals, "/bucket/today")
+	}
+}
+
+// Test that stalled response bodies fail, and slow readers don't.
+func (s *TestSuite) TestReadTimeoutTransport(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/stall" {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("second"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: readTimeoutTransport{timeout: 100 * time.Millisecond, transport: http.DefaultTransport}}
+	resp, e := client.Get(server.URL + "/stall")
+	c.Assert(e, IsNil)
+	start := time.Now()
+	_, e = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(e, NotNil)
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+
+	resp, e = client.Get(server.URL + "/slow-reader")
+	c.Assert(e, IsNil)
+	buf := make([]byte, 5)
+	_, e = io.ReadFull(resp.Body, buf)
+	c.Assert(e, IsNil)
+	time.Sleep(300 * time.Millisecond)
+	rest, e := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(e, IsNil)
+	c.Assert(string(buf)+string(rest), Equals, "firstsecond")
+}
+
+// Test that requests fail once they last longer than their timeout, even
+// when the body keeps receiving data.
+func (s *TestSuite) TestRequestTimeoutTransport(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/trickle" {
+			w.Write([]byte("body"))
+			return
+		}
+		for {
+			if _, e := w.Write([]byte("data")); e != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: requestTimeoutTransport{timeout: 200 * time.Millisecond, transport: http.DefaultTransport}}
+	resp, e := client.Get(server.URL + "/trickle")
+	c.Assert(e, IsNil)
+	start := time.Now()
+	_, e = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(e, NotNil)
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+
+	resp, e = client.Get(server.URL + "/fast")
+	c.Assert(e, IsNil)
+	body, e := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(e, IsNil)
+	c.Assert(string(body), Equals, "body")
+}
+
+// Test that host transports wait for response headers no longer than
+// configured.
+func (s *TestSuite) TestHostTransportTimeouts(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	tr := newHostTransport(&Config{ConnectTimeout: defaultConnectTimeout, ResponseHeaderTimeout: 100 * time.Millisecond}, nil, false)
+	defer tr.CloseIdleConnections()
+	start := time.Now()
+	_, e := (&http.Client{Transport: tr}).Get(server.URL)
+	c.Assert(e, NotNil)
+	c.Assert(time.Since(start) < 5*time.Second, Equals, true)
+}
+
+// Test that a connection slot is held until the response body is closed.
+func (s *TestSuite) TestConnLimitTransport(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	limiter := make(chan struct{}, 1)
+	client := &http.Client{Transport: connLimitTransport{limiter: limiter, transport: http.DefaultTransport}}
+	resp, e := client.Get(server.URL)
+	c.Assert(e, IsNil)
+	c.Assert(len(limiter), Equals, 1)
+
+	// Waits for the slot held by the open body.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, e := http.NewRequest(http.MethodGet, server.URL, nil)
+	c.Assert(e, IsNil)
+	_, e = client.Do(req.WithContext(ctx))
+	c.Assert(e, NotNil)
+
+	_, e = ioutil.ReadAll(resp.Body)
+	c.Assert(e, IsNil)
+	c.Assert(len(limiter), Equals, 0)
+	resp.Body.Close()
+	c.Assert(len(limiter), Equals, 0)
+}
+
+// Test that streamed copies never have more requests in flight than
+// connection slots, and do not wait on each other.
+func (s *TestSuite) TestConnLimitStreamedCopy(c *C) {
+	var inFlight, maxInFlight int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+				break
+			}
+		}
+		if r.Method == http.MethodPut {
+			ioutil.ReadAll(r.Body)
+			return
+		}
+		for i := 0; i < 5; i++ {
+			w.Write([]byte("data"))
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	const maxConns = 2
+	limiter := make(chan struct{}, maxConns)
+	reservations := make(chan struct{}, maxConns-1)
+	client := &http.Client{Transport: connLimitTransport{limiter: limiter, transport: http.DefaultTransport}}
+	copyObject := func() error {
+		ctx, release, err := reserveStreamedCopy(context.Background(), limiter, reservations)
+		if err != nil {
+			return err.ToGoError()
+		}
+		defer release()
+		// The source is opened once its upload has a slot.
+		resp, e := client.Get(server.URL)
+		if e != nil {
+			return e
+		}
+		reader := resp.Body
+		defer reader.Close()
+		// Parts are uploaded in parallel, one of them streams the source.
+		errCh := make(chan error, 3)
+		for _, body := range []io.Reader{reader, strings.NewReader("part"), strings.NewReader("part")} {
+			go func(body io.Reader) {
+				req, e := http.NewRequest(http.MethodPut, server.URL, ioutil.NopCloser(body))
+				if e == nil {
+					var resp *http.Response
+					if resp, e = client.Do(req.WithContext(ctx)); e == nil {
+						resp.Body.Close()
+					}
+				}
+				errCh <- e
+			}(body)
+		}
+		for i := 0; i < 3; i++ {
+			if e := <-errCh; e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- copyObject()
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		c.Fatal("streamed copies are waiting on each other")
+	}
+	close(errCh)
+	for e := range errCh {
+		c.Assert(e, IsNil)
+	}
+	c.Assert(atomic.LoadInt64(&maxInFlight) <= maxConns, Equals, true)
+	c.Assert(len(limiter), Equals, 0)
+}
+
+// Test rewriting of endpoints to their dual-stack form.
+func (s *TestSuite) TestAmazonDualStackHost(c *C) {
+	testCases := []struct {
+		host          string
+		dualStackHost string
+		supported     bool
+	}{
+		{"s3.amazonaws.com", "s3.dualstack.us-east-1.amazonaws.com", true},
+		{"s3-external-1.amazonaws.com", "s3.dualstack.us-east-1.amazonaws.com", true},
+		{"s3.eu-west-1.amazonaws.com", "s3.dualstack.eu-west-1.amazonaws.com", true},
+		{"s3-ap-southeast-2.amazonaws.com", "s3.dualstack.ap-southeast-2.amazonaws.com", true},
+		{"s3.dualstack.us-west-2.amazonaws.com", "s3.dualstack.us-west-2.amazonaws.com", true},
+		{"s3.cn-north-1.amazonaws.com.cn", "s3.dualstack.cn-north-1.amazonaws.com.cn", true},
+		{"s3-accelerate.amazonaws.com", "s3-accelerate.dualstack.amazonaws.com", true},
+		{"s3-fips.us-east-1.amazonaws.com", "", false},
+		{"play.min.io", "", false},
+		{"storage.googleapis.com", "", false},
+	}
+	for _, testCase := range testCases {
+		dualStackHost, reason := amazonDualStackHost(testCase.host)
+		c.Assert(reason == "", Equals, testCase.supported, Commentf("%s: %s", testCase.host, reason))
+		c.Assert(dualStackHost, Equals, testCase.dualStackHost)
+	}
+}
+
+func (s *TestSuite) TestPathStyle(c *C) {
+	testCases := []struct {
+		host      string
+		pathStyle bool
+	}{
+		{"localhost:9000", true},
+		{"minio", true},
+		{"127.0.0.1:9000", true},
+		{"[::1]:9000", true},
+		{"10.0.0.1", true},
+		{"play.min.io", false},
+		{"s3.amazonaws.com", false},
+	}
+	for _, testCase := range testCases {
+		c.Assert(isPathStyleHost(testCase.host), Equals, testCase.pathStyle, Commentf("%s", testCase.host))
+	}
+
+	for _, pathStyle := range []bool{false, true} {
+		conf := newTestS3Config("https://s3.amazonaws.com/bucket/object")
+		conf.Region = "us-east-1"
+		conf.PathStyle = pathStyle
+		clnt, err := s3New(conf)
+		c.Assert(err, IsNil)
+		s3c := clnt.(*s3Client)
+		c.Assert(s3c.virtualStyle, Equals, !pathStyle)
+		u, e := s3c.api.PresignedGetObject("bucket", "object", time.Hour, nil)
+		c.Assert(e, IsNil)
+		c.Assert(strings.HasPrefix(u.Host, "bucket."), Equals, !pathStyle, Commentf("%s", u))
+	}
+}
+
+// Test uploads and server side copies guarded by an ETag.
+func (s *TestSuite) TestIfMatchPrecondition(c *C) {
+	const currentETag = "\"5d41402abc4b2a76b9719d911017c592\""
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Length", "5")
+			w.Header().Set("ETag", currentETag)
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		case http.MethodPut:
+			if r.Header.Get("X-Amz-Meta-X-Mc-If-Match") != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != currentETag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				w.Write([]byte("<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>"))
+				return
+			}
+			if r.Header.Get("X-Amz-Copy-Source") != "" {
+				w.Write([]byte("<CopyObjectResult><LastModified>2006-01-02T15:04:05.000Z</LastModified><ETag>" + currentETag + "</ETag></CopyObjectResult>"))
+				return
+			}
+			w.Header().Set("ETag", currentETag)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	testCases := []struct {
+		ifMatch  string
+		isFailed bool
+	}{
+		{"", false},
+		{currentETag, false},
+		{"\"e80b5017098950fc58aad83c8c14978e\"", true},
+	}
+	for _, testCase := range testCases {
+		metadata := map[string]string{}
+		if testCase.ifMatch != "" {
+			metadata[ifMatchMetaKey] = testCase.ifMatch
+		}
+		_, err = s3c.Put(context.Background(), bytes.NewReader([]byte("hello")), 5, metadata, nil, nil)
+		_, isFailed := err.ToGoError().(PreconditionFailed)
+		c.Assert(isFailed, Equals, testCase.isFailed, Commentf("put with If-Match `%s`: %v", testCase.ifMatch, err))
+		if !testCase.isFailed {
+			c.Assert(err, IsNil)
+		}
+
+		metadata = map[string]string{}
+		if testCase.ifMatch != "" {
+			metadata[ifMatchMetaKey] = testCase.ifMatch
+		}
+		err = s3c.Copy("/bucket/source", 5, nil, nil, nil, metadata)
+		_, isFailed = err.ToGoError().(PreconditionFailed)
+		c.Assert(isFailed, Equals, testCase.isFailed, Commentf("copy with If-Match `%s`: %v", testCase.ifMatch, err))
+		if !testCase.isFailed {
+			c.Assert(err, IsNil)
+		}
+	}
+}
+
+// roundTripFunc - a http.RoundTripper calling the function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (s *TestSuite) TestPreconditionTransport(c *C) {
+	var ifMatch string
+	transport := preconditionTransport{roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		ifMatch = req.Header.Get("If-Match")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})}
+	ctx := context.WithValue(context.Background(), ifMatchContextKey{}, ifMatchCondition{"bucket", "dir/object", "etag"})
+
+	testCases := []struct {
+		method  string
+		url     string
+		ifMatch string
+	}{
+		{http.MethodPut, "http://localhost:9000/bucket/dir/object", "etag"},
+		{http.MethodPut, "http://bucket.localhost:9000/dir/object", "etag"},
+		{http.MethodPost, "http://localhost:9000/bucket/dir/object?uploadId=id", "etag"},
+		// Parts are checked on completion.
+		{http.MethodPut, "http://localhost:9000/bucket/dir/object?partNumber=1&uploadId=id", ""},
+		// Not the object of the condition.
+		{http.MethodPut, "http://localhost:9000/bucket/dir/other", ""},
+		{http.MethodPut, "http://localhost:9000/other/dir/object", ""},
+		{http.MethodPut, "http://localhost:9000/bucket", ""},
+		{http.MethodPut, "http://other.localhost:9000/dir/object", ""},
+	}
+	for i, testCase := range testCases {
+		req, e := http.NewRequest(testCase.method, testCase.url, nil)
+		c.Assert(e, IsNil)
+		ifMatch = ""
+		_, e = transport.RoundTrip(req.WithContext(ctx))
+		c.Assert(e, IsNil)
+		c.Assert(ifMatch, Equals, testCase.ifMatch, Commentf("Test %d", i+1))
+	}
+}
+
+func (s *TestSuite) TestGetRangeHeader(c *C) {
+	data := "hello world"
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", "\"5eb63bbbe01eeed093cb22bb8f5acdc3\"")
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		var start, end int64 = 0, int64(len(data)) - 1
+		if rng := r.Header.Get("Range"); rng != "" {
+			if _, e := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); e != nil {
+				fmt.Sscanf(rng, "bytes=%d-", &start)
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write([]byte(data[start : end+1]))
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	// The stat of a minio.Object made to follow redirects drops its range.
+	conf.RegionRedirect = true
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	testCases := []struct {
+		offset, length int64
+		expected       string
+	}{
+		{0, -1, "hello world"},
+		{6, -1, "world"},
+		{2, 3, "llo"},
+		{4, 0, ""},
+	}
+	for _, testCase := range testCases {
+		rc, err := s3c.GetRange(testCase.offset, testCase.length, nil)
+		c.Assert(err, IsNil)
+		got, e := ioutil.ReadAll(rc)
+		c.Assert(e, IsNil)
+		rc.Close()
+		c.Assert(string(got), Equals, testCase.expected)
+	}
+}
+
+func (s *TestSuite) TestKeyRequired(c *C) {
+	encrypted := true
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if !encrypted && r.Method == http.MethodGet {
+			w.Write([]byte("o"))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		if r.Method == http.MethodGet {
+			w.Write([]byte("<Error><Code>InvalidRequest</Code><Message>The object was stored using a form of Server Side Encryption. " +
+				"The correct parameters must be provided to retrieve the object.</Message></Error>"))
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	_, err = s3c.(*s3Client).getObjectStat("bucket", "object", minio.StatObjectOptions{})
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(ObjectKeyRequired)
+	c.Assert(ok, Equals, true)
+
+	_, err = s3c.Get(nil)
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(ObjectKeyRequired)
+	c.Assert(ok, Equals, true)
+
+	// A key was given, the error is not about a missing one.
+	sse, e := encrypt.NewSSEC([]byte("32byteslongsecretkeymustbegiven1"))
+	c.Assert(e, IsNil)
+	_, err = s3c.(*s3Client).getObjectStat("bucket", "object", minio.StatObjectOptions{GetObjectOptions: minio.GetObjectOptions{ServerSideEncryption: sse}})
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(ObjectKeyRequired)
+	c.Assert(ok, Equals, false)
+
+	// Any other bare 400 of a HEAD request.
+	encrypted = false
+	_, err = s3c.(*s3Client).getObjectStat("bucket", "object", minio.StatObjectOptions{})
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(ObjectKeyRequired)
+	c.Assert(ok, Equals, false)
+}
+
+// Test that a requested single PUT upload doesn't leak its marker.
+func (s *TestSuite) TestSinglePutUpload(c *C) {
+	var puts int
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.RawQuery != "" || r.Header.Get("X-Amz-Meta-X-Mc-Multipart-Threshold") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		puts++
+		w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	metadata := map[string]string{multipartThresholdMetaKey: "1024"}
+	n, err := s3c.Put(context.Background(), bytes.NewReader([]byte("hello")), 5, metadata, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(5))
+	c.Assert(puts, Equals, 1)
+}
+
+func (s *TestSuite) TestPutPartSize(c *C) {
+	const MiB = 1024 * 1024
+	testCases := []struct {
+		size, threshold int64
+		partSize        uint64
+	}{
+		// Unknown sizes keep the minio-go default.
+		{-1, defaultMultipartThreshold, 0},
+		{0, defaultMultipartThreshold, 1},
+		{63 * MiB, defaultMultipartThreshold, 63*MiB + 1},
+		{64 * MiB, defaultMultipartThreshold, 32 * MiB},
+		// Parts are at least 5MiB, and at most 10000 of them.
+		{64 * MiB, 8 * MiB, 5 * MiB},
+		{1000000 * MiB, defaultMultipartThreshold, 100 * MiB},
+		// A single PUT uploads at most 5GiB, parts buffered in memory
+		// are kept below 128MiB unless the object needs larger ones.
+		{maxSinglePutSize, maxSinglePutSize + 1, maxBufferedPartSize},
+		{maxPartsCount * 2 * maxBufferedPartSize, maxSinglePutSize, 2 * maxBufferedPartSize},
+	}
+	for i, testCase := range testCases {
+		partSize := putPartSize(testCase.size, testCase.threshold)
+		c.Assert(partSize, Equals, testCase.partSize, Commentf("Test %d", i+1))
+	}
+}
+
+// Test that a cancelled multipart upload is aborted.
+func (s *TestSuite) TestPutCancelledAbort(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	aborted := make(chan struct{})
+	var abortedOther int32
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost:
+			w.Write([]byte("<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload1</UploadId></InitiateMultipartUploadResult>"))
+		case r.Method == http.MethodPut:
+			// Cancelled while the parts are sent.
+			cancel()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.Method == http.MethodGet:
+			// Another upload of the same key is in progress.
+			w.Write([]byte("<ListMultipartUploadsResult><Bucket>bucket</Bucket><IsTruncated>false</IsTruncated>" +
+				"<Upload><Key>object</Key><UploadId>upload1</UploadId></Upload>" +
+				"<Upload><Key>object</Key><UploadId>upload2</UploadId></Upload></ListMultipartUploadsResult>"))
+		case r.Method == http.MethodDelete && query.Get("uploadId") == "upload1":
+			close(aborted)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			atomic.AddInt32(&abortedOther, 1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	data := make([]byte, 6*1024*1024)
+	metadata := map[string]string{multipartThresholdMetaKey: "1024"}
+	_, err = s3c.Put(ctx, bytes.NewReader(data), int64(len(data)), metadata, nil, nil)
+	c.Assert(err, NotNil)
+	select {
+	case <-aborted:
+	default:
+		c.Fatal("multipart upload was not aborted")
+	}
+	c.Assert(atomic.LoadInt32(&abortedOther), Equals, int32(0))
+}
+
+// Test that a stream of unknown size is uploaded in parts of the size
+// asked for.
+func (s *TestSuite) TestPutStreamPartSize(c *C) {
+	var mutex sync.Mutex
+	var partSizes []int64
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && r.URL.RawQuery == "uploads=":
+			w.Write([]byte("<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload1</UploadId></InitiateMultipartUploadResult>"))
+		case r.Method == http.MethodPut && query.Get("uploadId") == "upload1":
+			n, _ := io.Copy(ioutil.Discard, r.Body)
+			mutex.Lock()
+			partSizes = append(partSizes, n)
+			mutex.Unlock()
+			w.Header().Set("ETag", "\"etag"+query.Get("partNumber")+"\"")
+		case r.Method == http.MethodPost && query.Get("uploadId") == "upload1":
+			w.Write([]byte("<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	data := make([]byte, 2*minPartSize+1024)
+	metadata := map[string]string{streamPartSizeMetaKey: strconv.Itoa(minPartSize)}
+	n, err := s3c.Put(context.Background(), bytes.NewReader(data), -1, metadata, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	c.Assert(partSizes, DeepEquals, []int64{minPartSize, minPartSize, 1024})
+}
+
+// Test that an upload with recorded parts only sends the parts which
+// the target does not have with the recorded ETag.
+func (s *TestSuite) TestPutResumeParts(c *C) {
+	var mutex sync.Mutex
+	var sentParts []string
+	sentData := make(map[string][]byte)
+	var completed []byte
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodGet && query.Get("uploadId") == "upload1":
+			w.Write([]byte("<ListPartsResult><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload1</UploadId><IsTruncated>false</IsTruncated>" +
+				"<Part><PartNumber>1</PartNumber><ETag>\"etag1\"</ETag><Size>5242880</Size></Part>" +
+				"<Part><PartNumber>2</PartNumber><ETag>\"other\"</ETag><Size>5242880</Size></Part></ListPartsResult>"))
+		case r.Method == http.MethodPut && query.Get("uploadId") == "upload1":
+			body, _ := ioutil.ReadAll(r.Body)
+			mutex.Lock()
+			sentParts = append(sentParts, query.Get("partNumber"))
+			sentData[query.Get("partNumber")] = body
+			mutex.Unlock()
+			w.Header().Set("ETag", "\"new"+query.Get("partNumber")+"\"")
+		case r.Method == http.MethodPost && query.Get("uploadId") == "upload1":
+			completed, _ = ioutil.ReadAll(r.Body)
+			w.Write([]byte("<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	var saved []*uploadParts
+	parts := &uploadParts{
+		UploadID: "upload1",
+		PartSize: minPartSize,
+		Parts:    []uploadPart{{Number: 1, ETag: "etag1"}, {Number: 2, ETag: "etag2"}},
+		save:     func(parts *uploadParts) { saved = append(saved, parts) },
+	}
+	ctx := context.WithValue(context.Background(), uploadPartsContextKey{}, parts)
+
+	data := make([]byte, 2*minPartSize+1024)
+	for i := range data {
+		data[i] = byte(i / minPartSize)
+	}
+	metadata := map[string]string{multipartThresholdMetaKey: "1024"}
+	// Progress readers implement io.Seeker without moving when their
+	// source cannot seek, the skipped part must be read past.
+	reader := hookreader.NewHook(ioutil.NopCloser(bytes.NewReader(data)), newAccounter(0))
+	n, err := s3c.Put(ctx, reader, int64(len(data)), metadata, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+
+	// Part 1 is kept, part 2 changed on the target since it was recorded.
+	sort.Strings(sentParts)
+	c.Assert(sentParts, DeepEquals, []string{"2", "3"})
+	c.Assert(bytes.Equal(sentData["2"], data[minPartSize:2*minPartSize]), Equals, true)
+	c.Assert(bytes.Equal(sentData["3"], data[2*minPartSize:]), Equals, true)
+	sort.Slice(parts.Parts, func(i, j int) bool { return parts.Parts[i].Number < parts.Parts[j].Number })
+	c.Assert(strings.Contains(string(completed), "<PartNumber>1</PartNumber><ETag>etag1</ETag>"), Equals, true)
+	c.Assert(strings.Contains(string(completed), "<PartNumber>3</PartNumber><ETag>new3</ETag>"), Equals, true)
+	c.Assert(len(saved) > 0, Equals, true)
+	c.Assert(saved[len(saved)-1], IsNil)
+	c.Assert(parts.Parts, DeepEquals, []uploadPart{{Number: 1, ETag: "etag1"}, {Number: 2, ETag: "new2"}, {Number: 3, ETag: "new3"}})
+}
+
+// Test that a recorded upload with parts of another size is aborted
+// before the parts are uploaded again.
+func (s *TestSuite) TestPutResumePartSizeChanged(c *C) {
+	var mutex sync.Mutex
+	var aborted []string
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodDelete:
+			mutex.Lock()
+			aborted = append(aborted, query.Get("uploadId"))
+			mutex.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && (r.URL.RawQuery == "uploads=" || r.URL.RawQuery == "uploads"):
+			w.Write([]byte("<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload2</UploadId></InitiateMultipartUploadResult>"))
+		case r.Method == http.MethodPut && query.Get("uploadId") == "upload2":
+			ioutil.ReadAll(r.Body)
+			w.Header().Set("ETag", "\"etag"+query.Get("partNumber")+"\"")
+		case r.Method == http.MethodPost && query.Get("uploadId") == "upload2":
+			w.Write([]byte("<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	parts := &uploadParts{
+		UploadID: "upload1",
+		PartSize: 2 * minPartSize,
+		Parts:    []uploadPart{{Number: 1, ETag: "etag1"}},
+		save:     func(*uploadParts) {},
+	}
+	ctx := context.WithValue(context.Background(), uploadPartsContextKey{}, parts)
+	data := make([]byte, 2*minPartSize+1024)
+	metadata := map[string]string{multipartThresholdMetaKey: "1024"}
+	n, err := s3c.Put(ctx, bytes.NewReader(data), int64(len(data)), metadata, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	c.Assert(aborted, DeepEquals, []string{"upload1"})
+	c.Assert(parts.UploadID, Equals, "upload2")
+}
+
+// Test that a failed part of an upload with a retry budget is sent
+// again on its own, while the other parts are sent once.
+func (s *TestSuite) TestPutRetriesParts(c *C) {
+	var mutex sync.Mutex
+	sentParts := make(map[string]int)
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && (r.URL.RawQuery == "uploads=" || r.URL.RawQuery == "uploads"):
+			w.Write([]byte("<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><UploadId>upload1</UploadId></InitiateMultipartUploadResult>"))
+		case r.Method == http.MethodPut && query.Get("uploadId") == "upload1":
+			ioutil.ReadAll(r.Body)
+			mutex.Lock()
+			sentParts[query.Get("partNumber")]++
+			sent := sentParts[query.Get("partNumber")]
+			mutex.Unlock()
+			if query.Get("partNumber") == "2" && sent == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte("<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>"))
+				return
+			}
+			w.Header().Set("ETag", "\"etag"+query.Get("partNumber")+"\"")
+		case r.Method == http.MethodPost && query.Get("uploadId") == "upload1":
+			w.Write([]byte("<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>object</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/object")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	budget := &retryBudget{retries: 1, delay: time.Millisecond}
+	ctx := context.WithValue(context.Background(), retryBudgetContextKey{}, budget)
+	data := make([]byte, 2*minPartSize+1024)
+	metadata := map[string]string{multipartThresholdMetaKey: "1024"}
+	n, err := s3c.Put(ctx, bytes.NewReader(data), int64(len(data)), metadata, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	c.Assert(sentParts, DeepEquals, map[string]int{"1": 1, "2": 2, "3": 1})
+	_, ok := budget.take()
+	c.Assert(ok, Equals, false)
+}
+
+// Test that the keys of a batch remove which fail are reported
+// together with the key.
+func (s *TestSuite) TestRemovePartialFailure(c *C) {
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			w.Write([]byte("<DeleteResult><Deleted><Key>a</Key></Deleted>" +
+				"<Error><Key>b</Key><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>" +
+				"<Deleted><Key>c</Key></Deleted></DeleteResult>"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	contentCh := make(chan *clientContent, 3)
+	for _, key := range []string{"a", "b", "c"} {
+		contentCh <- &clientContent{URL: *newClientURL(server.URL + "/bucket/" + key)}
+	}
+	close(contentCh)
+
+	var failed []string
+	for err := range s3c.Remove(false, false, contentCh) {
+		e, ok := err.ToGoError().(ObjectRemoveFailed)
+		c.Assert(ok, Equals, true)
+		failed = append(failed, e.Object)
+	}
+	c.Assert(failed, DeepEquals, []string{"b"})
+}
+
+// Test that an upload denied by a policy requiring encryption is told
+// apart from other denials, and succeeds with encryption.
+func (s *TestSuite) TestPutEncryptionRequired(c *C) {
+	denyPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Principal":"*","Action":"s3:PutObject",` +
+		`"Resource":"arn:aws:s3:::bucket/*","Condition":{"Null":{"s3:x-amz-server-side-encryption":"true"}}}]}`
+	allowPolicy := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject",` +
+		`"Resource":"arn:aws:s3:::bucket/*"}]}`
+	for i, policy := range []string{denyPolicy, allowPolicy} {
+		server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.RawQuery == "policy=" || r.URL.RawQuery == "policy":
+				w.Write([]byte(policy))
+			case r.Method == http.MethodPut && r.Header.Get("X-Amz-Server-Side-Encryption") == "":
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>"))
+			case r.Method == http.MethodPut:
+				io.Copy(ioutil.Discard, r.Body)
+				w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		})
+
+		conf := newTestS3Config(server.URL + "/bucket/object")
+		s3c, err := s3New(conf)
+		c.Assert(err, IsNil)
+
+		data := []byte("hello")
+		_, err = s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), map[string]string{}, nil, nil)
+		c.Assert(err, NotNil)
+		_, isRequired := err.ToGoError().(EncryptionRequired)
+		c.Assert(isRequired, Equals, policy == denyPolicy, Commentf("Test %d", i+1))
+
+		if isRequired {
+			_, err = s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), map[string]string{}, nil, encrypt.NewSSE())
+			c.Assert(err, IsNil)
+		}
+		server.Close()
+	}
+}
+
+// Test that a page is listed from the continuation token and returns
+// the token of the next page.
+func (s *TestSuite) TestListPage(c *C) {
+	var query url.Values
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			query = r.URL.Query()
+			w.Write([]byte("<ListBucketResult><Name>bucket</Name><IsTruncated>true</IsTruncated><NextContinuationToken>token2</NextContinuationToken>" +
+				"<Contents><Key>dir/b</Key><Size>2</Size><ETag>\"etag-b\"</ETag><LastModified>2020-01-02T15:04:05.000Z</LastModified></Contents>" +
+				"<CommonPrefixes><Prefix>dir/a/</Prefix></CommonPrefixes></ListBucketResult>"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/dir/")
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	contents, nextToken, err := s3c.ListPage(false, 2, "token1")
+	c.Assert(err, IsNil)
+	c.Assert(query.Get("continuation-token"), Equals, "token1")
+	c.Assert(query.Get("max-keys"), Equals, "2")
+	c.Assert(query.Get("prefix"), Equals, "dir/")
+	c.Assert(query.Get("delimiter"), Equals, "/")
+	c.Assert(nextToken, Equals, "token2")
+	c.Assert(len(contents), Equals, 2)
+	c.Assert(contents[0].URL.Path, Equals, "/bucket/dir/a/")
+	c.Assert(contents[0].Type.IsDir(), Equals, true)
+	c.Assert(contents[1].URL.Path, Equals, "/bucket/dir/b")
+	c.Assert(contents[1].ETag, Equals, "etag-b")
+}
+
+// Test that a page is listed below the delimiter set with --delimiter.
+func (s *TestSuite) TestListPageCustomDelimiter(c *C) {
+	var query url.Values
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			query = r.URL.Query()
+			w.Write([]byte("<ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated>" +
+				"<Contents><Key>logs|b</Key><Size>2</Size><ETag>\"etag-b\"</ETag><LastModified>2020-01-02T15:04:05.000Z</LastModified></Contents>" +
+				"<CommonPrefixes><Prefix>logs|a|</Prefix></CommonPrefixes></ListBucketResult>"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL + "/bucket/logs|")
+	conf.Delimiter = "|"
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+
+	contents, nextToken, err := s3c.ListPage(false, 2, "")
+	c.Assert(err, IsNil)
+	c.Assert(query.Get("prefix"), Equals, "logs|")
+	c.Assert(query.Get("delimiter"), Equals, "|")
+	c.Assert(nextToken, Equals, "")
+	c.Assert(len(contents), Equals, 2)
+	c.Assert(contents[0].URL.Path, Equals, "/bucket/logs|a|")
+	c.Assert(contents[0].Type.IsDir(), Equals, true)
+	c.Assert(contents[1].URL.Path, Equals, "/bucket/logs|b")
+	c.Assert(contents[1].Type.IsDir(), Equals, false)
+
+	// Recursive pages are listed without a delimiter.
+	_, _, err = s3c.ListPage(true, 2, "")
+	c.Assert(err, IsNil)
+	c.Assert(query.Get("delimiter"), Equals, "")
+}
+
+// Test that requests signed for the wrong region are retried in the
+// region the server expects.
+func (s *TestSuite) TestRegionRedirect(c *C) {
+	var heads int32
+	var mutex sync.Mutex
+	uploaded := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			atomic.AddInt32(&heads, 1)
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/") {
+			w.Header().Set("x-amz-bucket-region", "eu-west-1")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("<Error><Code>AuthorizationHeaderMalformed</Code><Message>The authorization header is malformed; the region 'us-east-1' is wrong; expecting 'eu-west-1'</Message></Error>"))
+			return
+		}
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && (r.URL.RawQuery == "uploads=" || r.URL.RawQuery == "uploads"):
+			w.Write([]byte("<InitiateMultipartUploadResult><Bucket>upload</Bucket><Key>object</Key><UploadId>upload1</UploadId></InitiateMultipartUploadResult>"))
+			return
+		case r.Method == http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			mutex.Lock()
+			uploaded[query.Get("partNumber")] += len(body)
+			mutex.Unlock()
+			w.Header().Set("ETag", "\"etag"+query.Get("partNumber")+"\"")
+			return
+		case r.Method == http.MethodPost:
+			w.Write([]byte("<CompleteMultipartUploadResult><Bucket>upload</Bucket><Key>object</Key><ETag>\"done\"</ETag></CompleteMultipartUploadResult>"))
+			return
+		}
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	for _, redirect := range []bool{false, true} {
+		conf := newTestS3Config(server.URL + "/bucket/object")
+		conf.Region = "us-east-1"
+		conf.RegionRedirect = redirect
+		s3c, err := s3New(conf)
+		c.Assert(err, IsNil)
+
+		reader, err := s3c.Get(nil)
+		if !redirect {
+			c.Assert(err, NotNil)
+			c.Assert(redirectedBucketRegion(newClientURL(server.URL+"/bucket")), Equals, "")
+			continue
+		}
+		c.Assert(err, IsNil)
+		var buf bytes.Buffer
+		_, e := io.Copy(&buf, reader)
+		c.Assert(e, IsNil)
+		c.Assert(buf.String(), Equals, "hello")
+		c.Assert(redirectedBucketRegion(newClientURL(server.URL+"/bucket")), Equals, "eu-west-1")
+		c.Assert(redirectedBucketRegion(newClientURL(server.URL+"/other")), Equals, "")
+	}
+	// The redirect is seen on the GET itself.
+	c.Assert(atomic.LoadInt32(&heads), Equals, int32(0))
+
+	// Multipart uploads are redirected when they are created, before
+	// any of their body is read.
+	conf := newTestS3Config(server.URL + "/upload/object")
+	conf.Region = "us-east-1"
+	conf.RegionRedirect = true
+	s3c, err := s3New(conf)
+	c.Assert(err, IsNil)
+	data := make([]byte, minPartSize+1024)
+	metadata := map[string]string{multipartThresholdMetaKey: "1024"}
+	n, err := s3c.Put(context.Background(), bytes.NewReader(data), int64(len(data)), metadata, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(len(data)))
+	c.Assert(uploaded, DeepEquals, map[string]int{"1": minPartSize, "2": 1024})
+
+	// Requests of a client are redirected from several goroutines.
+	conf = newTestS3Config(server.URL + "/concurrent/object")
+	conf.Region = "us-east-1"
+	conf.RegionRedirect = true
+	s3c, err = s3New(conf)
+	c.Assert(err, IsNil)
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader, err := s3c.GetRange(0, 5, nil)
+			if err != nil {
+				errs <- err.ToGoError()
+				return
+			}
+			reader.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		c.Assert(e, IsNil)
+	}
+}
+
+func (s *TestSuite) TestBucketLocationFallback(c *C) {
+	testCases := []struct {
+		status int
+		header string
+		region string
+	}{
+		{http.StatusNotImplemented, "", "us-east-1"},
+		{http.StatusMethodNotAllowed, "", "us-east-1"},
+		{http.StatusMovedPermanently, "", "us-east-1"},
+		{http.StatusMovedPermanently, "eu-central-1", "eu-central-1"},
+	}
+	for i, testCase := range testCases {
+		var signedRegion string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.URL.Query()["location"]; ok {
+				if testCase.header != "" {
+					w.Header().Set("x-amz-bucket-region", testCase.header)
+				}
+				w.WriteHeader(testCase.status)
+				return
+			}
+			auth := r.Header.Get("Authorization")
+			scope := strings.Split(strings.SplitN(auth[strings.Index(auth, signV4Credential)+len(signV4Credential):], ",", 2)[0], "/")
+			signedRegion = scope[2]
+			w.Header().Set("Content-Length", "5")
+			w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Write([]byte("hello"))
+		}))
+
+		conf := newTestS3Config(server.URL + "/bucket/object")
+		s3c, err := s3New(conf)
+		c.Assert(err, IsNil)
+		reader, err := s3c.Get(nil)
+		c.Assert(err, IsNil, Commentf("Test %d", i+1))
+		data, e := ioutil.ReadAll(reader)
+		c.Assert(e, IsNil, Commentf("Test %d", i+1))
+		c.Assert(string(data), Equals, "hello")
+		c.Assert(signedRegion, Equals, testCase.region, Commentf("Test %d", i+1))
+		server.Close()
+	}
+}
+
+func (s *TestSuite) TestRetryStatusCodes(c *C) {
+	// The first two requests of the object fail.
+	testCases := []struct {
+		status   int
+		code     string
+		requests int32
+		success  bool
+	}{
+		{http.StatusBadGateway, "BadGateway", 3, true},
+		// Not listed, minio-go does not retry them either.
+		{http.StatusServiceUnavailable, "SlowDown", 1, false},
+		{http.StatusInternalServerError, "InternalError", 1, false},
+	}
+	for i, testCase := range testCases {
+		var requests int32
+		server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) <= 2 {
+				w.WriteHeader(testCase.status)
+				w.Write([]byte("<Error><Code>" + testCase.code + "</Code><Message>failed</Message></Error>"))
+				return
+			}
+			w.Header().Set("Content-Length", "5")
+			w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Write([]byte("hello"))
+		})
+
+		conf := newTestS3Config(server.URL + "/bucket/object")
+		conf.RetryStatusCodes = map[int]struct{}{http.StatusBadGateway: {}}
+		s3c, err := s3New(conf)
+		c.Assert(err, IsNil)
+		var e error
+		reader, err := s3c.GetRange(0, 5, nil)
+		if err != nil {
+			e = err.ToGoError()
+		} else {
+			_, e = ioutil.ReadAll(reader)
+			reader.Close()
+		}
+		c.Assert(e == nil, Equals, testCase.success, Commentf("Test %d: %v", i+1, e))
+		if !testCase.success {
+			c.Assert(minio.ToErrorResponse(e).Code, Equals, testCase.code, Commentf("Test %d", i+1))
+		}
+		c.Assert(atomic.LoadInt32(&requests), Equals, testCase.requests, Commentf("Test %d", i+1))
+		server.Close()
+	}
+}
+
+// Test the answers finalAnswerTransport hands over to the vendored
+// minio-go: they must be returned at once, with the error of the last
+// attempt of retryStatusTransport.
+func (s *TestSuite) TestFinalAnswerTransport(c *C) {
+	testCases := []struct {
+		status        int // zero closes the connection
+		code          string
+		isRetryStatus bool
+		retries       int // the retry budget of a transfer, none if negative
+		requests      int32
+	}{
+		// Transport errors are only retried by retryStatusTransport,
+		// the error of the connection is returned.
+		{0, "EOF", true, -1, 2},
+		// Listed, retried by retryStatusTransport only.
+		{http.StatusBadGateway, "BadGateway", true, -1, 2},
+		// Not listed, minio-go does not retry them either.
+		{http.StatusServiceUnavailable, "SlowDown", true, -1, 1},
+		// Never retried, minio-go reads the answer itself.
+		{http.StatusNotFound, "NoSuchKey", true, -1, 1},
+		// The requests of a transfer are retried out of its budget.
+		{http.StatusBadGateway, "BadGateway", true, 0, 1},
+		{http.StatusBadGateway, "BadGateway", true, 1, 2},
+		{http.StatusServiceUnavailable, "SlowDown", false, 1, 1},
+		{0, "EOF", false, 1, 1},
+	}
+	for i, testCase := range testCases {
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if testCase.status == 0 {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.WriteHeader(testCase.status)
+			w.Write([]byte("<Error><Code>" + testCase.code + "</Code><Message>failed</Message></Error>"))
+		}))
+
+		api, e := minio.NewWithOptions(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+			Creds:  credentials.NewStaticV4(testAccessKey, testSecretKey, ""),
+			Region: "us-east-1",
+		})
+		c.Assert(e, IsNil)
+		var transport http.RoundTripper = http.DefaultTransport
+		if testCase.isRetryStatus {
+			transport = retryStatusTransport{
+				statuses:  map[int]struct{}{http.StatusBadGateway: {}},
+				maxRetry:  2,
+				transport: transport,
+			}
+		}
+		api.SetCustomTransport(finalAnswerTransport{isStatusRetried: testCase.isRetryStatus, transport: transport})
+		ctx := context.Background()
+		if testCase.retries >= 0 {
+			ctx = context.WithValue(ctx, retryBudgetContextKey{}, &retryBudget{retries: testCase.retries})
+		}
+		_, _, _, e = minio.Core{Client: api}.GetObjectWithContext(ctx, "bucket", "object", minio.GetObjectOptions{})
+		c.Assert(e, NotNil, Commentf("Test %d", i+1))
+		c.Assert(minio.ToErrorResponse(e).Code, Equals, testCase.code, Commentf("Test %d: %v", i+1, e))
+		c.Assert(atomic.LoadInt32(&requests), Equals, testCase.requests, Commentf("Test %d", i+1))
+		server.Close()
+	}
+
+	// Uploads can't be replayed by retryStatusTransport, minio-go
+	// retries them with their whole body.
+	var requests int32
+	var received atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<Error><Code>SlowDown</Code><Message>failed</Message></Error>"))
+			return
+		}
+		received.Store(string(body))
+		w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+	}))
+	defer server.Close()
+	api, e := minio.NewWithOptions(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4(testAccessKey, testSecretKey, ""),
+		Region: "us-east-1",
+	})
+	c.Assert(e, IsNil)
+	api.SetCustomTransport(finalAnswerTransport{isStatusRetried: true, transport: retryStatusTransport{
+		statuses:  map[int]struct{}{http.StatusServiceUnavailable: {}},
+		maxRetry:  2,
+		transport: http.DefaultTransport,
+	}})
+	_, e = minio.Core{Client: api}.PutObject("bucket", "object", strings.NewReader("hello"), 5, "", "", nil, nil)
+	c.Assert(e, IsNil)
+	c.Assert(atomic.LoadInt32(&requests), Equals, int32(2))
+	c.Assert(strings.Contains(received.Load().(string), "\r\nhello\r\n"), Equals, true)
+}
+
+func (s *TestSuite) TestCACertFile(c *C) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "5")
+		w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	dir, e := ioutil.TempDir("", "mc-ca-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	c.Assert(ioutil.WriteFile(caFile, caCert, 0600), IsNil)
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	c.Assert(ioutil.WriteFile(invalidFile, []byte("not a certificate"), 0600), IsNil)
+
+	testCases := []struct {
+		caFile   string
+		newError bool
+	}{
+		{caFile, false},
+		{invalidFile, true},
+		{filepath.Join(dir, "missing.pem"), true},
+	}
+	for i, testCase := range testCases {
+		conf := newTestS3Config(server.URL + "/bucket/object")
+		conf.Region = "us-east-1"
+		conf.CACertFile = testCase.caFile
+		s3c, err := s3New(conf)
+		if testCase.newError {
+			c.Assert(err, NotNil, Commentf("Test %d", i+1))
+			_, ok := err.ToGoError().(CACertInvalid)
+			c.Assert(ok, Equals, true, Commentf("Test %d", i+1))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("Test %d", i+1))
+		reader, err := s3c.Get(nil)
+		c.Assert(err, IsNil, Commentf("Test %d", i+1))
+		data, e := ioutil.ReadAll(reader)
+		c.Assert(e, IsNil, Commentf("Test %d", i+1))
+		c.Assert(string(data), Equals, "hello", Commentf("Test %d", i+1))
+	}
+}
+
+func (s *TestSuite) TestMakeBucketExists(c *C) {
+	testCases := []struct {
+		code           string
+		headStatus     int
+		ignoreExisting bool
+		err            error
+	}{
+		{"BucketAlreadyOwnedByYou", http.StatusOK, false, BucketExists{}},
+		{"BucketAlreadyOwnedByYou", http.StatusOK, true, nil},
+		{"BucketAlreadyExists", http.StatusOK, false, BucketExists{}},
+		{"BucketAlreadyExists", http.StatusOK, true, nil},
+		{"BucketAlreadyExists", http.StatusForbidden, false, BucketNotAccessible{}},
+		{"BucketAlreadyExists", http.StatusForbidden, true, BucketNotAccessible{}},
+		// Only a denied access tells the bucket cannot be accessed.
+		{"BucketAlreadyExists", http.StatusNotImplemented, true, minio.ErrorResponse{}},
+		{"BucketAlreadyExists", http.StatusNotFound, true, minio.ErrorResponse{}},
+	}
+	for i, testCase := range testCases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPut:
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte("<Error><Code>" + testCase.code + "</Code><Message>exists</Message><BucketName>bucket</BucketName></Error>"))
+			case http.MethodHead:
+				w.WriteHeader(testCase.headStatus)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		conf := newTestS3Config(server.URL + "/bucket")
+		conf.Region = "us-east-1"
+		s3c, err := s3New(conf)
+		c.Assert(err, IsNil)
+		err = s3c.MakeBucket("us-east-1", testCase.ignoreExisting, false)
+		if testCase.err == nil {
+			c.Assert(err, IsNil, Commentf("Test %d", i+1))
+		} else {
+			c.Assert(err, NotNil, Commentf("Test %d", i+1))
+			c.Assert(err.ToGoError(), FitsTypeOf, testCase.err, Commentf("Test %d", i+1))
+		}
+		server.Close()
+	}
+}
+
+func (s *TestSuite) TestPartsLimitReader(c *C) {
+	testCases := []struct {
+		data     string
+		tooLarge bool
+	}{
+		{"", false},
+		{"abc", false},
+		{"abcd", false},
+		{"abcde", true},
+	}
+	for i, testCase := range testCases {
+		reader := &partsLimitReader{reader: strings.NewReader(testCase.data), partSize: 2, remaining: 4}
+		// Read parts the way minio-go does for streams of unknown size.
+		var read string
+		var e error
+		buf := make([]byte, 2)
+		for part := 0; part < 2; part++ {
+			var n int
+			n, e = io.ReadFull(reader, buf)
+			read += string(buf[:n])
+			if e != nil {
+				break
+			}
+		}
+		if testCase.tooLarge {
+			c.Assert(e, FitsTypeOf, StreamTooLarge{}, Commentf("Test %d", i+1))
+			continue
+		}
+		c.Assert(e == nil || e == io.EOF || e == io.ErrUnexpectedEOF, Equals, true, Commentf("Test %d: %v", i+1, e))
+		c.Assert(read, Equals, testCase.data, Commentf("Test %d", i+1))
+	}
+}
@@ -163,6 +163,20 @@ func (u clientURL) String() string {
 	return buf.String()
 }
 
+// dirDelimiter returns the suffix of the folders below u, the listing
+// delimiter set with --delimiter for objects on object storage.
+func dirDelimiter(u clientURL) string {
+	separator := string(u.Separator)
+	if u.Type != objectStorage {
+		return separator
+	}
+	// Buckets are always delimited by the separator.
+	if tokens := splitStr(strings.TrimPrefix(u.Path, separator), separator, 2); tokens[1] == "" {
+		return separator
+	}
+	return globalListDelimiter
+}
+
 // urlJoinPath Join a path to existing URL.
 func urlJoinPath(url1, url2 string) string {
 	u1 := newClientURL(url1)
@@ -176,10 +190,24 @@ func url2Stat(urlStr string, isFetchMeta, fileAttr bool, encKeyDB map[string][]p
 	if err != nil {
 		return nil, nil, err.Trace(urlStr)
 	}
+	return statClient(client, urlStr, isFetchMeta, fileAttr, encKeyDB)
+}
+
+// sourceURL2Stat returns stat info for the URL of a source to copy
+// from, see newSourceClient.
+func sourceURL2Stat(urlStr string, isFetchMeta, fileAttr bool, encKeyDB map[string][]prefixSSEPair) (client Client, content *clientContent, err *probe.Error) {
+	client, err = newSourceClient(urlStr)
+	if err != nil {
+		return nil, nil, err.Trace(urlStr)
+	}
+	return statClient(client, urlStr, isFetchMeta, fileAttr, encKeyDB)
+}
+
+func statClient(client Client, urlStr string, isFetchMeta, fileAttr bool, encKeyDB map[string][]prefixSSEPair) (Client, *clientContent, *probe.Error) {
 	alias, _ := url2Alias(urlStr)
 	sse := getSSE(urlStr, encKeyDB[alias])
 
-	content, err = client.Stat(false, isFetchMeta, fileAttr, sse)
+	content, err := client.Stat(false, isFetchMeta, fileAttr, sse)
 	if err != nil {
 		return nil, nil, err.Trace(urlStr)
 	}
@@ -19,6 +19,7 @@ package cmd
 import (
 	"context"
 	"io"
+	"net"
 	"os"
 	"time"
 
@@ -48,6 +49,9 @@ type Client interface {
 	// Common operations
 	Stat(isIncomplete, isFetchMeta, isPreserve bool, sse encrypt.ServerSide) (content *clientContent, err *probe.Error)
 	List(isRecursive, isIncomplete, isFetchMeta bool, showDir DirOpt) <-chan *clientContent
+	// ListPage lists up to maxKeys entries following the page which
+	// returned token, nextToken is empty after the last page.
+	ListPage(isRecursive bool, maxKeys int, token string) (contents []*clientContent, nextToken string, err *probe.Error)
 
 	// Bucket operations
 	MakeBucket(region string, ignoreExisting, withLock bool) *probe.Error
@@ -67,6 +71,9 @@ type Client interface {
 
 	// I/O operations with metadata.
 	Get(sse encrypt.ServerSide) (reader io.ReadCloser, err *probe.Error)
+	// GetRange reads length bytes from offset, or up to the end if
+	// length is negative.
+	GetRange(offset, length int64, sse encrypt.ServerSide) (reader io.ReadCloser, err *probe.Error)
 	Put(ctx context.Context, reader io.Reader, size int64, metadata map[string]string, progress io.Reader, sse encrypt.ServerSide) (n int64, err *probe.Error)
 	// Object Locking related API
 	PutObjectRetention(mode *minio.RetentionMode, retainUntilDate *time.Time, bypassGovernance bool) *probe.Error
@@ -75,6 +82,7 @@ type Client interface {
 	// I/O operations with expiration
 	ShareDownload(expires time.Duration) (string, *probe.Error)
 	ShareUpload(bool, time.Duration, string) (string, map[string]string, *probe.Error)
+	SharePut(expires time.Duration) (string, *probe.Error)
 
 	// Watch events
 	Watch(params watchParams) (*watchObject, *probe.Error)
@@ -105,6 +113,8 @@ type clientContent struct {
 	ETag         string
 	Expires      time.Time
 	Retention    bool
+	OwnerID      string
+	OwnerName    string
 	Err          *probe.Error
 }
 
@@ -120,6 +130,64 @@ type Config struct {
 	Debug       bool
 	Insecure    bool
 	Lookup      minio.BucketLookupType
+
+	// Address buckets in the URL path whatever the lookup is.
+	PathStyle bool
+
+	// PEM file of CAs trusted in addition to those of the system and
+	// the MinIO config, if set.
+	CACertFile string
+
+	ConnectTimeout        time.Duration
+	ResponseHeaderTimeout time.Duration
+	ReadTimeout           time.Duration
+	RequestTimeout        time.Duration
+
+	// Idle connections kept open to all hosts and to each host,
+	// defaultMaxIdleConns when zero.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+
+	// HTTP statuses to retry on, the minio-go defaults are used when empty.
+	RetryStatusCodes map[int]struct{}
+
+	// Semaphore bounding requests in flight across all hosts, if set.
+	ConnLimiter chan struct{}
+
+	// Delimiter of non recursive listings, "/" when empty.
+	Delimiter string
+
+	// Rewrite Amazon S3 endpoints to their dual-stack form and dial IPv6 first.
+	DualStack bool
+
+	// Region signing requests, looked up per bucket when empty.
+	Region string
+
+	// Region of buckets whose location lookup gives none, "us-east-1"
+	// when empty.
+	DefaultRegion string
+
+	// Retry requests redirected to the region of their bucket.
+	RegionRedirect bool
+
+	// Local address of outgoing connections, chosen by the system when nil.
+	BindAddr net.IP
+}
+
+// maxIdleConns returns the idle connections kept open to all hosts.
+func (c *Config) maxIdleConns() int {
+	if c.MaxIdleConns > 0 {
+		return c.MaxIdleConns
+	}
+	return defaultMaxIdleConns
+}
+
+// maxIdleConnsPerHost returns the idle connections kept open to each host.
+func (c *Config) maxIdleConnsPerHost() int {
+	if c.MaxIdleConnsPerHost > 0 {
+		return c.MaxIdleConnsPerHost
+	}
+	return defaultMaxIdleConns
 }
 
 // SelectObjectOpts - opts entered for select API
@@ -17,21 +17,33 @@
 package cmd
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"golang.org/x/net/http/httpguts"
 	"gopkg.in/h2non/filetype.v1"
 
 	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/hookreader"
 	"github.com/minio/mc/pkg/probe"
 	minio "github.com/minio/minio-go/v6"
 	"github.com/minio/minio-go/v6/pkg/encrypt"
@@ -105,6 +117,19 @@ func getEncKeys(ctx *cli.Context) (map[string][]prefixSSEPair, *probe.Error) {
 // If it exists, we can easily check if it is a folder, if it doesn't exist,
 // we can guess if the url is a folder from how it looks.
 func isAliasURLDir(aliasURL string, keys map[string][]prefixSSEPair) bool {
+	_, expandedURL, _ := mustExpandAlias(aliasURL)
+
+	// A bucket root, with or without a trailing separator, is always
+	// treated as a folder so that `mc cp file alias/bucket` creates
+	// `alias/bucket/file` instead of an object with an empty key.
+	if expandedURL != aliasURL {
+		u := newClientURL(expandedURL)
+		tokens := splitStr(u.Path, string(u.Separator), 3)
+		if tokens[1] != "" && tokens[2] == "" {
+			return true
+		}
+	}
+
 	// If the target url exists, check if it is a directory
 	// and return immediately.
 	_, targetContent, err := url2Stat(aliasURL, false, false, keys)
@@ -112,8 +137,6 @@ func isAliasURLDir(aliasURL string, keys map[string][]prefixSSEPair) bool {
 		return targetContent.Type.IsDir()
 	}
 
-	_, expandedURL, _ := mustExpandAlias(aliasURL)
-
 	// Check if targetURL is an FS or S3 aliased url
 	if expandedURL == aliasURL {
 		// This is an FS url, check if the url has a separator at the end
@@ -162,22 +185,44 @@ func getSourceStreamFromURL(urlStr string, encKeyDB map[string][]prefixSSEPair)
 	return reader, err
 }
 
+// getSourceRangeFromURL gets a reader for length bytes of URL from
+// offset, up to its end if length is negative.
+func getSourceRangeFromURL(urlStr string, offset, length int64, encKeyDB map[string][]prefixSSEPair) (reader io.ReadCloser, err *probe.Error) {
+	alias, urlStrFull, _, err := expandAlias(urlStr)
+	if err != nil {
+		return nil, err.Trace(urlStr)
+	}
+	sourceClnt, err := newClientFromAlias(alias, urlStrFull)
+	if err != nil {
+		return nil, err.Trace(alias, urlStrFull)
+	}
+	reader, err = sourceClnt.GetRange(offset, length, getSSE(urlStr, encKeyDB[alias]))
+	if err != nil {
+		return nil, err.Trace(alias, urlStrFull)
+	}
+	return reader, nil
+}
+
 // getSourceStream gets a reader from URL.
 func getSourceStream(alias string, urlStr string, fetchStat bool, sse encrypt.ServerSide) (reader io.ReadCloser, metadata map[string]string, err *probe.Error) {
 	sourceClnt, err := newClientFromAlias(alias, urlStr)
 	if err != nil {
 		return nil, nil, err.Trace(alias, urlStr)
 	}
+	// Stat before the source is opened, a copy limited by
+	// --max-connections has no other connection while it reads it.
+	var st *clientContent
+	if fetchStat {
+		if st, err = sourceClnt.Stat(false, true, false, sse); err != nil {
+			return nil, nil, err.Trace(alias, urlStr)
+		}
+	}
 	reader, err = sourceClnt.Get(sse)
 	if err != nil {
 		return nil, nil, err.Trace(alias, urlStr)
 	}
 	metadata = make(map[string]string)
 	if fetchStat {
-		st, err := sourceClnt.Stat(false, true, false, sse)
-		if err != nil {
-			return nil, nil, err.Trace(alias, urlStr)
-		}
 		for k, v := range st.Metadata {
 			if httpguts.ValidHeaderFieldName(k) &&
 				httpguts.ValidHeaderFieldValue(v) {
@@ -203,16 +248,132 @@ func getSourceStream(alias string, urlStr string, fetchStat bool, sse encrypt.Se
 					}
 					ctype = kind.MIME.Value
 					if ctype == "" {
-						ctype = "application/octet-stream"
+						// Not a known binary format, sniff for text and markup.
+						// The charset is left to the detection below.
+						ctype, _, _ = mime.ParseMediaType(http.DetectContentType(buf[:n]))
 					}
 					metadata["Content-Type"] = ctype
 				}
 			}
+			// Text content is further annotated with its charset.
+			if ctype := metadata["Content-Type"]; isTextContentType(ctype) {
+				if _, params, _ := mime.ParseMediaType(ctype); params["charset"] == "" {
+					var buf [512]byte
+					n, _ := io.ReadFull(reader, buf[:])
+					if _, e := s.Seek(0, io.SeekStart); e != nil {
+						return nil, nil, probe.NewError(e)
+					}
+					metadata["Content-Type"] = setContentTypeCharset(ctype, detectCharset(buf[:n], n == len(buf)))
+				}
+			}
 		}
 	}
 	return reader, metadata, nil
 }
 
+// charsetMetaKey carries a caller requested charset down to the upload,
+// it is folded into Content-Type and never sent as a header.
+const charsetMetaKey = "X-Mc-Charset"
+
+// ifMatchMetaKey carries the ETag the target must still have for an
+// upload to overwrite it, object storage clients send it as If-Match.
+const ifMatchMetaKey = "X-Mc-If-Match"
+
+// compressMetaKey asks for a streamed upload to be gzip compressed if
+// its Content-Type is compressible, it is never sent as a header.
+const compressMetaKey = "X-Mc-Compress"
+
+// autoSSEMetaKey asks for an upload denied by a bucket policy requiring
+// encryption to be made again with SSE-S3, it is never sent as a header.
+const autoSSEMetaKey = "X-Mc-Auto-Sse"
+
+// multipartThresholdMetaKey asks object storage clients to upload an
+// object with one PUT request if it is smaller than the size in bytes
+// of its value, it is never sent as a header.
+const multipartThresholdMetaKey = "X-Mc-Multipart-Threshold"
+
+// streamPartSizeMetaKey asks object storage clients to upload a stream
+// of unknown size in parts of the size in bytes of its value, buffered
+// one at a time, it is never sent as a header.
+const streamPartSizeMetaKey = "X-Mc-Stream-Part-Size"
+
+// appendMetaKey asks filesystem targets to append the bytes of the
+// source past the current size of the target instead of replacing it.
+// Its value is the fileID of a local source, when known, to check that
+// the target was appended from the same file, see appendSourceXattr.
+const appendMetaKey = "X-Mc-Append"
+
+// checksumMetaKey asks for the checksum of a streamed upload to be
+// stored in the user metadata of the target, its value names the
+// algorithm, see checksumMetadataKey. It is never sent as a header.
+const checksumMetaKey = "X-Mc-Checksum"
+
+// noServerSideMetaKey asks for an object to be streamed through mc even
+// when the target endpoint could copy it by itself, it is never sent as
+// a header.
+const noServerSideMetaKey = "X-Mc-No-Server-Side"
+
+// maxInMemoryCompressSize - objects up to this size are compressed in
+// memory and uploaded with their compressed length, larger ones are
+// compressed while they are uploaded in parts of compressPartSize.
+const maxInMemoryCompressSize = minPartSize
+
+// compressPartSize returns the part size of the compressed upload of
+// length bytes, the smallest one fitting them in maxPartsCount parts.
+// Compressed data may be a little larger than the source.
+func compressPartSize(length int64) int64 {
+	length += length/100 + 1
+	partSize := (length + maxPartsCount - 1) / maxPartsCount
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	return partSize
+}
+
+// compressSourceStream - gzip compresses length bytes of reader, the
+// returned length is -1 when it is not known upfront. progress counts
+// the bytes read from reader so that it keeps matching the source size.
+func compressSourceStream(reader io.Reader, length int64, progress io.Reader) (io.ReadCloser, int64, *probe.Error) {
+	reader = hookreader.NewHook(reader, progress)
+	compress := func(w io.Writer) error {
+		gw := gzip.NewWriter(w)
+		n, e := io.Copy(gw, reader)
+		if e != nil {
+			return e
+		}
+		// The compressed length can't tell a truncated source.
+		if length >= 0 && n != length {
+			return UnexpectedEOF{TotalSize: length, TotalWritten: n}
+		}
+		return gw.Close()
+	}
+	if length >= 0 && length <= maxInMemoryCompressSize {
+		var buf bytes.Buffer
+		if e := compress(&buf); e != nil {
+			return nil, 0, probe.NewError(e)
+		}
+		return ioutil.NopCloser(&buf), int64(buf.Len()), nil
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(compress(pw))
+	}()
+	return pr, -1, nil
+}
+
+// applyCharsetOverride - replaces the charset of a text Content-Type
+// with the one requested through charsetMetaKey, if any.
+func applyCharsetOverride(metadata, targetMetadata map[string]string) {
+	charset, ok := targetMetadata[charsetMetaKey]
+	delete(metadata, charsetMetaKey)
+	if !ok {
+		return
+	}
+	if ctype, ok := metadata["Content-Type"]; ok {
+		metadata["Content-Type"] = setContentTypeCharset(ctype, charset)
+	}
+}
+
 // putTargetRetention sets retention headers if any
 func putTargetRetention(ctx context.Context, alias string, urlStr string, metadata map[string]string) *probe.Error {
 	targetClnt, err := newClientFromAlias(alias, urlStr)
@@ -253,8 +414,9 @@ func putTargetStream(ctx context.Context, alias string, urlStr string, reader io
 	return n, nil
 }
 
-// putTargetStreamWithURL writes to URL from reader. If length=-1, read until EOF.
-func putTargetStreamWithURL(urlStr string, reader io.Reader, size int64, sse encrypt.ServerSide) (int64, *probe.Error) {
+// putTargetStreamWithURL writes to URL from reader. If length=-1, read until EOF
+// in parts of partSize, the default of the client when zero.
+func putTargetStreamWithURL(urlStr string, reader io.Reader, size, partSize int64, sse encrypt.ServerSide) (int64, *probe.Error) {
 	alias, urlStrFull, _, err := expandAlias(urlStr)
 	if err != nil {
 		return 0, err.Trace(alias, urlStr)
@@ -263,6 +425,9 @@ func putTargetStreamWithURL(urlStr string, reader io.Reader, size int64, sse enc
 	metadata := map[string]string{
 		"Content-Type": contentType,
 	}
+	if partSize > 0 {
+		metadata[streamPartSizeMetaKey] = strconv.FormatInt(partSize, 10)
+	}
 	return putTargetStream(context.Background(), alias, urlStrFull, reader, size, metadata, nil, sse)
 }
 
@@ -318,9 +483,66 @@ func getAllMetadata(sourceAlias, sourceURLStr string, srcSSE encrypt.ServerSide,
 	return filterMetadata(metadata), nil
 }
 
-// uploadSourceToTargetURL - uploads to targetURL from source.
-// optionally optimizes copy for object sizes <= 5GiB by using
-// server side copy operation.
+// removeTargetURL - removes the target object of sURLs, appName is
+// added to the user agent so that watchers can ignore removals made
+// by mc itself.
+func removeTargetURL(sURLs URLs, appName string) URLs {
+	// Construct proper path with alias.
+	targetWithAlias := filepath.Join(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
+	clnt, pErr := newClient(targetWithAlias)
+	if pErr != nil {
+		return sURLs.WithError(pErr)
+	}
+	clnt.AddUserAgent(appName, Version)
+	contentCh := make(chan *clientContent, 1)
+	contentCh <- &clientContent{URL: *newClientURL(sURLs.TargetContent.URL.Path)}
+	close(contentCh)
+	isRemoveBucket := false
+	errorCh := clnt.Remove(false, isRemoveBucket, contentCh)
+	for pErr := range errorCh {
+		if pErr != nil {
+			switch pErr.ToGoError().(type) {
+			case PathInsufficientPermission:
+				// Ignore Permission error.
+				continue
+			}
+			return sURLs.WithError(pErr)
+		}
+	}
+
+	return sURLs.WithError(nil)
+}
+
+// isServerSideCopy tells if the target endpoint can copy the source of
+// urls by itself, which is the case for sources of the same alias and
+// of another alias for the same endpoint and credentials.
+func isServerSideCopy(urls URLs) bool {
+	if _, ok := urls.TargetContent.Metadata[noServerSideMetaKey]; ok {
+		return false
+	}
+	if urls.SourceAlias == "" && urls.SourceContent.URL.Type == objectStorage ||
+		urls.TargetAlias == "" && urls.TargetContent.URL.Type == objectStorage {
+		// Plain HTTP(S) URLs have no server to copy on.
+		return false
+	}
+	if urls.SourceAlias == urls.TargetAlias {
+		return true
+	}
+	if urls.SourceContent.URL.Type != objectStorage || urls.TargetContent.URL.Type != objectStorage {
+		return false
+	}
+	sourceCfg, targetCfg := mustGetHostConfig(urls.SourceAlias), mustGetHostConfig(urls.TargetAlias)
+	if sourceCfg == nil || targetCfg == nil {
+		return false
+	}
+	sourceHost, targetHost := newClientURL(sourceCfg.URL), newClientURL(targetCfg.URL)
+	return strings.EqualFold(sourceHost.Host, targetHost.Host) && sourceHost.Scheme == targetHost.Scheme &&
+		sourceCfg.AccessKey == targetCfg.AccessKey && sourceCfg.SecretKey == targetCfg.SecretKey
+}
+
+// uploadSourceToTargetURL - uploads to targetURL from source, sources
+// the target endpoint can reach are copied server side, in parts above
+// 5GiB.
 func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader, encKeyDB map[string][]prefixSSEPair) URLs {
 	sourceAlias := urls.SourceAlias
 	sourceURL := urls.SourceContent.URL
@@ -332,12 +554,19 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 
 	srcSSE := getSSE(sourcePath, encKeyDB[sourceAlias])
 	tgtSSE := getSSE(targetPath, encKeyDB[targetAlias])
+	_, isAutoSSE := urls.TargetContent.Metadata[autoSSEMetaKey]
+	checksum := urls.TargetContent.Metadata[checksumMetaKey]
+	delete(urls.TargetContent.Metadata, checksumMetaKey)
+	// Conditional writes which may have been applied are not retried.
+	isConditional := urls.TargetContent.Metadata[ifMatchMetaKey] != ""
 
 	var err *probe.Error
 	var metadata = map[string]string{}
+	// Step of the transfer which failed, see errorReportEntry.
+	var operation string
 
 	// Optimize for server side copy if the host is same.
-	if sourceAlias == targetAlias {
+	if isServerSideCopy(urls) {
 		for k, v := range urls.SourceContent.UserMetadata {
 			metadata[k] = v
 		}
@@ -349,62 +578,402 @@ func uploadSourceToTargetURL(ctx context.Context, urls URLs, progress io.Reader,
 		if len(metadata) == 0 {
 			metadata, err = getAllMetadata(sourceAlias, sourceURL.String(), srcSSE, urls)
 			if err != nil {
-				return urls.WithError(err.Trace(sourceURL.String()))
+				return urls.WithOperationError("get", err.Trace(sourceURL.String()))
 			}
 		}
 
+		// Values requested by the caller take precedence over the source.
+		for _, k := range []string{mtimeMetaKey, "Content-Type", "Content-Disposition", "Cache-Control", ifMatchMetaKey, appendMetaKey, "X-Amz-Storage-Class"} {
+			if v, ok := urls.TargetContent.Metadata[k]; ok {
+				metadata[k] = v
+			}
+		}
+		for k, v := range urls.TargetContent.UserMetadata {
+			metadata[k] = v
+		}
+		applyCharsetOverride(metadata, urls.TargetContent.Metadata)
+		// The copy keeps the checksum its source was stored with.
+		if algorithm, _ := storedChecksum(metadata); checksum != "" && algorithm != checksum {
+			sum, err := checksumSource(urls, checksum, srcSSE)
+			if err != nil {
+				return urls.WithOperationError("get", err.Trace(sourceURL.String()))
+			}
+			metadata[checksumMetadataKey(checksum)] = sum
+		}
+
 		sourcePath := filepath.ToSlash(sourceURL.Path)
 		if urls.SourceContent.Retention {
-			err = putTargetRetention(ctx, targetAlias, targetURL.String(), metadata)
-			return urls.WithError(err.Trace(sourceURL.String()))
+			if err = putTargetRetention(ctx, targetAlias, targetURL.String(), metadata); err != nil {
+				return urls.WithOperationError("put", err.Trace(sourceURL.String()))
+			}
+			return urls.WithError(nil)
 		}
+		// Server side copies are retried by minio-go alone, their
+		// requests can't carry the retry budget of a transfer.
+		operation = "copy"
 		err = copySourceToTargetURL(targetAlias, targetURL.String(), sourcePath, length,
 			progress, srcSSE, tgtSSE, filterMetadata(metadata))
+		if isAutoSSE && tgtSSE == nil && isErrEncryptionRequired(err) {
+			err = copySourceToTargetURL(targetAlias, targetURL.String(), sourcePath, length,
+				progress, srcSSE, encrypt.NewSSE(), filterMetadata(metadata))
+		}
 	} else {
 		if len(metadata) == 0 {
 			metadata, err = getAllMetadata(sourceAlias, sourceURL.String(), srcSSE, urls)
 			if err != nil {
-				return urls.WithError(err.Trace(sourceURL.String()))
+				return urls.WithOperationError("get", err.Trace(sourceURL.String()))
 			}
 		}
 		if urls.SourceContent.Retention {
-			err = putTargetRetention(ctx, targetAlias, targetURL.String(), metadata)
-			return urls.WithError(err.Trace(sourceURL.String()))
+			if err = putTargetRetention(ctx, targetAlias, targetURL.String(), metadata); err != nil {
+				return urls.WithOperationError("put", err.Trace(sourceURL.String()))
+			}
+			return urls.WithError(nil)
 		}
-		var reader io.ReadCloser
-		// Proceed with regular stream copy.
-		reader, metadata, err = getSourceStream(sourceAlias, sourceURL.String(), true, srcSSE)
-		if err != nil {
-			return urls.WithError(err.Trace(sourceURL.String()))
+		// Proceed with regular stream copy. A multipart upload lost by a
+		// restart of the target, or a source read ending before length
+		// bytes, is tried again with a fresh stream. Filesystem targets
+		// resume from the part already written.
+		var n int64
+		var isCompressed bool
+		var budget *retryBudget
+		if urls.retries > 0 {
+			budget = &retryBudget{retries: urls.retries, delay: urls.retryDelay}
+			ctx = context.WithValue(ctx, retryBudgetContextKey{}, budget)
 		}
-		defer reader.Close()
-		// Get metadata from target content as well
-		for k, v := range urls.TargetContent.Metadata {
-			metadata[k] = v
+		restartable := &restartProgress{progress: progress}
+		for restarts := 0; ; restarts++ {
+			urls.attempts = restarts + 1
+			n, isCompressed, operation, err = putSourceStream(ctx, urls, checksum, restartable, srcSSE, tgtSSE)
+			if err == nil && length >= 0 && restartable.sent < length {
+				// The source ended early.
+				operation = "get"
+				err = probe.NewError(UnexpectedEOF{
+					TotalSize:    length,
+					TotalWritten: restartable.sent,
+				})
+			}
+			if isAutoSSE && tgtSSE == nil && isErrEncryptionRequired(err) {
+				tgtSSE = encrypt.NewSSE()
+				restartable.restart()
+				continue
+			}
+			if err == nil {
+				break
+			}
+			if restarts < maxUploadRestarts && isErrUploadRestartable(err) && globalContext.Err() == nil {
+				errorIf(err.Trace(targetURL.String()), "Retrying upload of `"+targetURL.String()+"`.")
+				restartable.restart()
+				continue
+			}
+			// Transient failures are retried with a growing delay.
+			if !isErrRetryable(err, isConditional) {
+				break
+			}
+			retry, ok := budget.take()
+			if !ok {
+				break
+			}
+			errorIf(err.Trace(targetURL.String()), "Retrying upload of `"+targetURL.String()+"`.")
+			if !waitRetry(ctx, budget.delay, retry) {
+				break
+			}
+			restartable.restart()
 		}
-		// Get userMetadata from target content as well
-		for k, v := range urls.TargetContent.UserMetadata {
-			metadata[k] = v
+		if err == nil && isCompressed {
+			// Let the caller know what was stored.
+			urls.TargetContent.Size = n
+			urls.TargetContent.Metadata["Content-Encoding"] = "gzip"
 		}
-		_, err = putTargetStream(ctx, targetAlias, targetURL.String(), reader, length, filterMetadata(metadata),
-			progress, tgtSSE)
 	}
 	if err != nil {
-		return urls.WithError(err.Trace(sourceURL.String()))
+		if urls.attempts > 1 {
+			return urls.WithOperationError(operation, err.Trace(sourceURL.String(), fmt.Sprintf("attempts=%d", urls.attempts)))
+		}
+		return urls.WithOperationError(operation, err.Trace(sourceURL.String()))
 	}
 
 	return urls.WithError(nil)
 }
 
+// maxUploadRestarts is the number of times a failed upload is started
+// again before giving up, see isErrUploadRestartable.
+const maxUploadRestarts = 3
+
+// isErrUploadRestartable tells if an upload failed in a way that a new
+// attempt from the start of the source may fix.
+func isErrUploadRestartable(err *probe.Error) bool {
+	switch err.ToGoError().(type) {
+	case UploadNotFound, UnexpectedEOF:
+		return true
+	}
+	return false
+}
+
+// isErrTransient tells if a transfer failed in a way that may not happen
+// again, a server error, a timeout or a lost connection. Hosts which
+// can't be resolved or refuse connections are not retried.
+func isErrTransient(err *probe.Error) bool {
+	e := err.ToGoError()
+	if errResponse, ok := e.(minio.ErrorResponse); ok {
+		return errResponse.StatusCode >= http.StatusInternalServerError ||
+			errResponse.Code == "SlowDown" || errResponse.Code == "RequestTimeout"
+	}
+	if _, ok := e.(UnexpectedEOF); ok {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(e, &dnsErr) {
+		return dnsErr.IsTimeout
+	}
+	if errors.Is(e, syscall.ECONNREFUSED) {
+		return false
+	}
+	if errors.Is(e, syscall.ECONNRESET) || errors.Is(e, syscall.EPIPE) || errors.Is(e, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(e, &netErr) && netErr.Timeout()
+}
+
+// isErrRejected tells if a transient error was answered before the
+// request was applied, so that a conditional request can be tried
+// again. Other server errors and lost connections may come after the
+// object was written, a new attempt would find it changed.
+func isErrRejected(err *probe.Error) bool {
+	errResponse, ok := err.ToGoError().(minio.ErrorResponse)
+	return ok && (errResponse.StatusCode == http.StatusServiceUnavailable ||
+		errResponse.Code == "SlowDown" || errResponse.Code == "RequestTimeout")
+}
+
+// isErrRetryable tells if a transfer which failed with err is tried
+// again, conditional transfers only when err is rejected.
+func isErrRetryable(err *probe.Error, isConditional bool) bool {
+	return isErrTransient(err) && (!isConditional || isErrRejected(err))
+}
+
+// retryBudgetContextKey holds the *retryBudget of a transfer made with
+// that context.
+type retryBudgetContextKey struct{}
+
+// retryBudget - the retries of a transfer, shared by its restarts and
+// the retries of its requests so that --retry bounds them all. minio-go
+// does not retry the requests of a transfer with a budget, see
+// finalAnswerTransport.
+type retryBudget struct {
+	retries int
+	delay   time.Duration
+	used    int32
+}
+
+// take uses a retry of the budget, it returns the number of the retry
+// from 0 and false once none is left.
+func (b *retryBudget) take() (int, bool) {
+	if b == nil {
+		return 0, false
+	}
+	retry := int(atomic.AddInt32(&b.used, 1)) - 1
+	return retry, retry < b.retries
+}
+
+// retryJitter - a retry waits between half and all of its delay.
+const retryJitter = 0.5
+
+// waitRetry waits before the retry of a failed transfer, the delay is
+// doubled at every retry up to minio.DefaultRetryCap or delay itself if
+// it is longer. It returns false if ctx is done or the command is
+// interrupted before, the copies of a session run under a context the
+// interrupt does not cancel so only their current attempt finishes.
+func waitRetry(ctx context.Context, delay time.Duration, retry int) bool {
+	if globalContext.Err() != nil {
+		return false
+	}
+	cap := minio.DefaultRetryCap
+	if delay > cap {
+		cap = delay
+	}
+	timer := time.NewTimer(exponentialBackoffWait(delay, cap, retryJitter, retry))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-globalContext.Done():
+		return false
+	}
+}
+
+// isErrEncryptionRequired tells if an upload was denied for being made
+// without server side encryption.
+func isErrEncryptionRequired(err *probe.Error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.ToGoError().(EncryptionRequired)
+	return ok
+}
+
+// putSourceStream streams the source object of urls to its target,
+// returning the number of bytes stored and whether they were compressed,
+// or on failure the operation which failed, either "get" or "put". The
+// checksum of the source made with algorithm checksum is stored with the
+// target when set.
+func putSourceStream(ctx context.Context, urls URLs, checksum string, progress io.Reader, srcSSE, tgtSSE encrypt.ServerSide) (int64, bool, string, *probe.Error) {
+	sourceURL := urls.SourceContent.URL
+	length := urls.SourceContent.Size
+	if sourceURL.Type == objectStorage && globalConnLimiter != nil {
+		// The slot of the upload is taken before the source is read,
+		// see reserveStreamedCopy().
+		var release func()
+		var err *probe.Error
+		if urls.TargetContent.URL.Type == objectStorage {
+			if err = lookupBucketLocation(urls.TargetAlias, urls.TargetContent.URL.String()); err != nil {
+				return 0, false, "put", err.Trace(sourceURL.String())
+			}
+		}
+		if ctx, release, err = reserveStreamedCopy(ctx, globalConnLimiter, globalStreamedCopyLimiter); err != nil {
+			return 0, false, "get", err.Trace(sourceURL.String())
+		}
+		defer release()
+	}
+	reader, metadata, err := getSourceStream(urls.SourceAlias, sourceURL.String(), true, srcSSE)
+	if err != nil {
+		return 0, false, "get", err.Trace(sourceURL.String())
+	}
+	defer reader.Close()
+	var sum string
+	var hasher hash.Hash
+	if checksum != "" {
+		if sum, hasher, err = sourceChecksum(urls, reader, checksum, srcSSE); err != nil {
+			return 0, false, "get", err.Trace(sourceURL.String())
+		}
+	}
+	if hasher != nil {
+		reader = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(reader, hasher), reader}
+	}
+	if sourceURL.Type == objectStorage {
+		reader = globalDownloadLimiter.wrap(reader)
+	}
+	// Get metadata from target content as well
+	for k, v := range urls.TargetContent.Metadata {
+		metadata[k] = v
+	}
+	// Get userMetadata from target content as well
+	for k, v := range urls.TargetContent.UserMetadata {
+		metadata[k] = v
+	}
+	applyCharsetOverride(metadata, urls.TargetContent.Metadata)
+	if checksum != "" {
+		metadata[checksumMetadataKey(checksum)] = sum
+	}
+	delete(metadata, autoSSEMetaKey)
+	delete(metadata, noServerSideMetaKey)
+	isCompressed := false
+	if _, ok := metadata[compressMetaKey]; ok {
+		delete(metadata, compressMetaKey)
+		if metadata["Content-Encoding"] == "" && isCompressibleContentType(metadata["Content-Type"]) {
+			sourceLength := length
+			if reader, length, err = compressSourceStream(reader, length, progress); err != nil {
+				return 0, false, "get", err.Trace(sourceURL.String())
+			}
+			defer reader.Close()
+			if _, ok := metadata[streamPartSizeMetaKey]; !ok && length < 0 && sourceLength >= 0 {
+				metadata[streamPartSizeMetaKey] = strconv.FormatInt(compressPartSize(sourceLength), 10)
+			}
+			progress = nil
+			metadata["Content-Encoding"] = "gzip"
+			isCompressed = true
+		}
+	}
+	if urls.TargetContent.URL.Type == objectStorage {
+		reader = globalUploadLimiter.wrap(reader)
+	}
+	n, err := putTargetStream(ctx, urls.TargetAlias, urls.TargetContent.URL.String(), reader, length,
+		filterMetadata(metadata), progress, tgtSSE)
+	// The stored checksum was computed by reading the source before,
+	// a copy it no longer describes is removed.
+	if err == nil && hasher != nil && hex.EncodeToString(hasher.Sum(nil)) != sum {
+		err = errChecksumMismatch(sourceURL.String(), checksum)
+		if removed := removeTargetURL(urls, uaCopyAppName); removed.Error != nil {
+			errorIf(removed.Error.Trace(urls.TargetContent.URL.String()),
+				"Unable to remove `%s` after its source changed.", urls.TargetContent.URL.String())
+		}
+		return n, isCompressed, "get", err
+	}
+	return n, isCompressed, "put", err
+}
+
+// restartProgress forwards upload progress to the wrapped reader, an
+// upload started again only reports the bytes beyond what the previous
+// attempts already reported. sent is the number of bytes read from the
+// source by the current attempt. Reported bytes are accounted to
+// globalMetrics unless they were uploaded before a resume, see skip.
+type restartProgress struct {
+	progress io.Reader
+	reported int64
+	sent     int64
+}
+
+func (r *restartProgress) Read(p []byte) (int, error) {
+	n := len(p)
+	if forward := r.forward(int64(n)); forward > 0 {
+		if r.progress != nil {
+			r.progress.Read(p[:forward])
+		}
+		globalMetrics.addTransferred(forward)
+	}
+	return n, nil
+}
+
+// skip reports n bytes the target already had from a previous run of
+// the copy, they are not accounted as transferred.
+func (r *restartProgress) skip(n int64) {
+	if forward := r.forward(n); forward > 0 && r.progress != nil {
+		io.CopyN(ioutil.Discard, r.progress, forward)
+	}
+}
+
+// forward moves the current attempt n bytes further and returns how
+// many of them were not reported yet.
+func (r *restartProgress) forward(n int64) int64 {
+	r.sent += n
+	if r.sent <= r.reported {
+		return 0
+	}
+	forward := r.sent - r.reported
+	if forward > n {
+		forward = n
+	}
+	r.reported += forward
+	return forward
+}
+
+// restart is called before the upload is started again.
+func (r *restartProgress) restart() {
+	r.sent = 0
+}
+
 // newClientFromAlias gives a new client interface for matching
 // alias entry in the mc config file. If no matching host config entry
-// is found, fs client is returned.
+// is found, a read-only client of HTTP(S) URLs or the fs client is
+// returned.
 func newClientFromAlias(alias, urlStr string) (Client, *probe.Error) {
 	alias, _, hostCfg, err := expandAlias(alias)
 	if err != nil {
 		return nil, err.Trace(alias, urlStr)
 	}
 
+	if hostCfg == nil && urlRgx.MatchString(urlStr) {
+		httpClient, err := httpNew(urlStr)
+		if err != nil {
+			return nil, err.Trace(urlStr)
+		}
+		return httpClient, nil
+	}
 	if hostCfg == nil {
 		// No matching host config. So we treat it like a
 		// filesystem.
@@ -416,6 +985,17 @@ func newClientFromAlias(alias, urlStr string) (Client, *probe.Error) {
 	}
 
 	s3Config := newS3Config(urlStr, hostCfg)
+	if isAzureHost(hostCfg) {
+		azureClient, err := azureNew(s3Config)
+		if err != nil {
+			return nil, err.Trace(alias, urlStr)
+		}
+		return azureClient, nil
+	}
+	s3Config.Region = redirectedBucketRegion(newClientURL(urlStr))
+	if s3Config.Region == "" {
+		s3Config.Region = resolveHostRegion(alias, hostCfg)
+	}
 
 	s3Client, err := s3New(s3Config)
 	if err != nil {
@@ -441,6 +1021,16 @@ func newClient(aliasedURL string) (Client, *probe.Error) {
 	return newClientFromAlias(alias, urlStrFull)
 }
 
+// newSourceClient gives a new client interface of a source to copy
+// from, which may also be a plain HTTP(S) URL without an alias.
+func newSourceClient(aliasedURL string) (Client, *probe.Error) {
+	alias, urlStrFull, _, err := expandAlias(aliasedURL)
+	if err != nil {
+		return nil, err.Trace(aliasedURL)
+	}
+	return newClientFromAlias(alias, urlStrFull)
+}
+
 // Return the file attribute value present in metadata
 func getFileAttrMeta(sURLs URLs, encKeyDB map[string][]prefixSSEPair) (string, *probe.Error) {
 	sourceAlias := sURLs.SourceAlias
@@ -40,7 +40,31 @@ var hostAddFlags = []cli.Flag{
 	},
 	cli.StringFlag{
 		Name:  "api",
-		Usage: "API signature. Valid options are '[S3v4, S3v2]'",
+		Usage: "API signature. Valid options are '[S3v4, S3v2, GCS, Azure]'",
+	},
+	cli.StringFlag{
+		Name:  "region",
+		Usage: "region of the server, looked up on first contact when empty",
+	},
+	cli.StringFlag{
+		Name:  "region-lookup",
+		Usage: "region lookup of the server, either 'location' or the path of an endpoint answering the region",
+	},
+	cli.BoolFlag{
+		Name:  "save-region",
+		Usage: "save the region looked up on first contact to the configuration file",
+	},
+	cli.StringFlag{
+		Name:  "part-size",
+		Usage: "part size of multipart uploads to the host when cp is not given --multipart-threshold",
+	},
+	cli.IntFlag{
+		Name:  "parallel",
+		Usage: "number of objects copied at the same time to the host when cp is not given --parallel",
+	},
+	cli.IntFlag{
+		Name:  "max-keys",
+		Usage: "number of entries of a page listed by ls on the host when it is not given --max-keys",
 	},
 }
 var configHostAddCmd = cli.Command{
@@ -88,6 +112,31 @@ EXAMPLES:
      {{.Prompt}} echo -e "BKIKJAA5BMMU2RHO6IBB\nV8f1CwQqAcwo80UEIJEjc5gVQUSSx5ohQ9GSrr12" | \
                  {{.HelpName}} mys3 https://s3.amazonaws.com --api "s3v4" --lookup "dns"
      {{.EnableHistory}}
+
+  6. Add a storage service answering its region at /region, saving the region after first contact.
+     {{.DisableHistory}}
+     {{.Prompt}} {{.HelpName}} mystore https://store.example.com minio minio123 --region-lookup /region --save-region
+     {{.EnableHistory}}
+
+  7. Add a local MinIO service copied to with 32 objects at a time in parts of 16MiB.
+     {{.DisableHistory}}
+     {{.Prompt}} {{.HelpName}} myminio http://localhost:9000 minio minio123 --parallel 32 --part-size 16MiB
+     {{.EnableHistory}}
+
+  8. Add Google Cloud Storage reached through a private endpoint under "mygcs" alias, with HMAC keys.
+     {{.DisableHistory}}
+     {{.Prompt}} {{.HelpName}} mygcs https://storage-gcs.p.googleapis.com GOOGTS7C7FUP3AIRVJTE bGoa+V7g/yqDXvKRqq+JTFn4uQZbPiQJo4pf9RzJ --api "GCS"
+     {{.EnableHistory}}
+
+  9. Add the Azure Blob Storage account "myaccount" under "myazure" alias, the secret key is the account key.
+     {{.DisableHistory}}
+     {{.Prompt}} {{.HelpName}} myazure https://myaccount.blob.core.windows.net myaccount bXlrZXkgaXMgYmFzZTY0IGVuY29kZWQ=
+     {{.EnableHistory}}
+
+  10. Add a MinIO service whose certificate is signed by a private CA, the CA is trusted for this host only.
+     {{.DisableHistory}}
+     {{.Prompt}} {{.HelpName}} myminio https://minio.internal:9000 minio minio123 --ca-cert /etc/ssl/internal-ca.pem
+     {{.EnableHistory}}
 `,
 }
 
@@ -125,13 +174,32 @@ func checkConfigHostAddSyntax(ctx *cli.Context, accessKey string, secretKey stri
 
 	if api != "" && !isValidAPI(api) { // Empty value set to default "S3v4".
 		fatalIf(errInvalidArgument().Trace(api),
-			"Unrecognized API signature. Valid options are `[S3v4, S3v2]`.")
+			"Unrecognized API signature. Valid options are `[S3v4, S3v2, GCS, Azure]`.")
 	}
 
 	if !isValidLookup(bucketLookup) {
 		fatalIf(errInvalidArgument().Trace(bucketLookup),
 			"Unrecognized bucket lookup. Valid options are `[dns,auto, path]`.")
 	}
+
+	if regionLookup := ctx.String("region-lookup"); !isValidRegionLookup(regionLookup) {
+		fatalIf(errInvalidArgument().Trace(regionLookup),
+			"Unrecognized region lookup. Valid options are `location` or a path starting with `/`.")
+	}
+
+	if partSize := ctx.String("part-size"); partSize != "" {
+		_, err := partSizeThreshold(partSize)
+		fatalIf(err.Trace(partSize), "Invalid part size `"+partSize+"`.")
+	}
+
+	if parallel := ctx.Int("parallel"); parallel < 0 || parallel > maxParallelWorkers {
+		fatalIf(errInvalidArgument().Trace(ctx.String("parallel")),
+			fmt.Sprintf("Invalid --parallel `%d`, expecting at most %d.", parallel, maxParallelWorkers))
+	}
+
+	if maxKeys := ctx.Int("max-keys"); ctx.IsSet("max-keys") && (maxKeys < 1 || maxKeys > 1000) {
+		fatalIf(errInvalidArgument().Trace(ctx.String("max-keys")), "--max-keys must be between 1 and 1000.")
+	}
 }
 
 // addHost - add a host config.
@@ -163,12 +231,14 @@ func probeS3Signature(accessKey, secretKey, url string) (string, *probe.Error) {
 	// Test s3 connection for API auto probe
 	s3Config := &Config{
 		// S3 connection parameters
-		Insecure:  globalInsecure,
-		AccessKey: accessKey,
-		SecretKey: secretKey,
-		Signature: "s3v4",
-		HostURL:   urlJoinPath(url, probeBucketName),
-		Debug:     globalDebug,
+		Insecure:   globalInsecure,
+		CACertFile: globalCACertFile,
+		PathStyle:  globalPathStyle,
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		Signature:  "s3v4",
+		HostURL:    urlJoinPath(url, probeBucketName),
+		Debug:      globalDebug,
 	}
 
 	s3Client, err := s3New(s3Config)
@@ -218,6 +288,11 @@ func BuildS3Config(url, accessKey, secretKey, api, lookup string) (*Config, *pro
 		s3Config.Signature = api
 		return s3Config, nil
 	}
+	if isAzure(newClientURL(url).Host) {
+		// Azure Blob Storage is not probed, its accounts have no S3 API.
+		s3Config.Signature = "Azure"
+		return s3Config, nil
+	}
 	// Probe S3 signature version
 	api, err := probeS3Signature(accessKey, secretKey, url)
 	if err != nil {
@@ -287,6 +362,16 @@ func mainConfigHostAdd(ctx *cli.Context) error {
 		SecretKey: s3Config.SecretKey,
 		API:       s3Config.Signature,
 		Lookup:    lookup,
+
+		Region:       ctx.String("region"),
+		RegionLookup: ctx.String("region-lookup"),
+		SaveRegion:   ctx.Bool("save-region"),
+
+		PartSize: ctx.String("part-size"),
+		Parallel: ctx.Int("parallel"),
+		MaxKeys:  ctx.Int("max-keys"),
+
+		CACert: globalCACertFile,
 	}) // Add a host with specified credentials.
 	return nil
 }
@@ -42,6 +42,22 @@ type hostConfigV9 struct {
 	SecretKey string `json:"secretKey"`
 	API       string `json:"api"`
 	Lookup    string `json:"lookup"`
+
+	// Region signing requests, looked up through RegionLookup when
+	// empty and saved back here if SaveRegion is set.
+	Region       string `json:"region,omitempty"`
+	RegionLookup string `json:"regionLookup,omitempty"`
+	SaveRegion   bool   `json:"saveRegion,omitempty"`
+
+	// Defaults of the host for cp --multipart-threshold, --parallel and
+	// ls --max-keys when they are not given, zero keeps the mc default.
+	PartSize string `json:"partSize,omitempty"`
+	Parallel int    `json:"parallel,omitempty"`
+	MaxKeys  int    `json:"maxKeys,omitempty"`
+
+	// PEM file of the CAs trusted for the host, in addition to the
+	// system and MinIO config CAs.
+	CACert string `json:"caCert,omitempty"`
 }
 
 // configV8 config version.
@@ -200,6 +200,16 @@ func mustGetHostConfig(alias string) *hostConfigV9 {
 	return hostCfg
 }
 
+// hostConfigOf returns the host config of the alias of urlStr, nil
+// for local paths, to look up the defaults of the host.
+func hostConfigOf(urlStr string) *hostConfigV9 {
+	_, _, hostCfg, err := expandAlias(urlStr)
+	if err != nil {
+		return nil
+	}
+	return hostCfg
+}
+
 // parse url usually obtained from env.
 func parseEnvURL(envURL string) (*url.URL, string, string, *probe.Error) {
 	u, e := url.Parse(envURL)
@@ -16,7 +16,12 @@
 
 package cmd
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
 
 // Tests valid host URL functionality.
 func TestParseEnvURLStr(t *testing.T) {
@@ -76,3 +81,52 @@ func TestParseEnvURLStrInvalid(t *testing.T) {
 		t.Fatalf("Expected failure")
 	}
 }
+
+func TestResolveHostRegion(t *testing.T) {
+	var lookups int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		switch r.URL.Path {
+		case "/json":
+			w.Write([]byte(`{"region": "eu-west-3"}`))
+		case "/text":
+			w.Write([]byte("ap-south-1\n"))
+		case "/empty":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		hostCfg *hostConfigV9
+		region  string
+		lookups int32
+	}{
+		{nil, "", 0},
+		{&hostConfigV9{URL: server.URL, Region: "us-west-2", RegionLookup: "/json"}, "us-west-2", 0},
+		{&hostConfigV9{URL: server.URL}, "", 0},
+		{&hostConfigV9{URL: server.URL, RegionLookup: regionLookupLocation}, "", 0},
+		{&hostConfigV9{URL: server.URL, RegionLookup: "/json"}, "eu-west-3", 1},
+		{&hostConfigV9{URL: server.URL, RegionLookup: "/text"}, "ap-south-1", 1},
+		{&hostConfigV9{URL: server.URL, RegionLookup: "/empty"}, globalDefaultRegion, 1},
+		{&hostConfigV9{URL: server.URL, RegionLookup: "/missing"}, globalDefaultRegion, 1},
+	}
+	for i, testCase := range testCases {
+		// Every host is looked up once, then served from the cache.
+		for j := 0; j < 2; j++ {
+			atomic.StoreInt32(&lookups, 0)
+			region := resolveHostRegion("regiontest", testCase.hostCfg)
+			if region != testCase.region {
+				t.Fatalf("Test %d: expected region `%s`, found `%s`", i+1, testCase.region, region)
+			}
+			expectedLookups := testCase.lookups
+			if j > 0 {
+				expectedLookups = 0
+			}
+			if lookups != expectedLookups {
+				t.Fatalf("Test %d: expected %d lookups, found %d", i+1, expectedLookups, lookups)
+			}
+		}
+	}
+}
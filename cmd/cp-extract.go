@@ -0,0 +1,354 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+)
+
+// tarMemberBufferSize - members up to this size are read in memory, to
+// be uploaded in parallel while the archive is read further.
+const tarMemberBufferSize = 8 * 1024 * 1024
+
+// tarMember - a member of a tar archive uploaded by cp --extract, kept
+// by its session to be skipped on resume.
+type tarMember struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// tarMemberName returns the key of a member under the target, members
+// which would be written outside of it are refused.
+func tarMemberName(name string) (string, bool) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+// tarSource - the reader of an archive, keeping the errors of its source
+// apart from those of the uploads reading its members.
+type tarSource struct {
+	reader io.Reader
+	err    error
+}
+
+func (s *tarSource) Read(p []byte) (int, error) {
+	n, e := s.reader.Read(p)
+	if e != nil && e != io.EOF && s.err == nil {
+		s.err = e
+	}
+	return n, e
+}
+
+// tarExtract uploads the regular members of a tar archive under a
+// target folder, see cp --extract.
+type tarExtract struct {
+	sourceAlias, sourceURL string
+	targetAlias, targetURL string
+	srcSSE, tgtSSE         encrypt.ServerSide
+	parallel               int
+	pg                     ProgressReader
+	spaceCheck             *freeSpaceCheck
+
+	mutex sync.Mutex
+	// Members uploaded so far by name, those of the session on resume.
+	done map[string]tarMember
+	// record is called with each member once uploaded, and with nil
+	// once a member has to be uploaded again.
+	record func(name string, member *tarMember)
+	// Sizes of the members counted in the progress total.
+	listed map[string]int64
+	failed int64
+}
+
+// run extracts the archive. An archive which fails to be read is read
+// again from its start, skipping the members uploaded before.
+func (x *tarExtract) run(ctx context.Context) *probe.Error {
+	for restarts := 0; ; restarts++ {
+		reader, _, err := getSourceStream(x.sourceAlias, x.sourceURL, false, x.srcSSE)
+		if err != nil {
+			return err.Trace(x.sourceURL)
+		}
+		isSourceErr, err := x.extract(ctx, reader)
+		reader.Close()
+		if err == nil || !isSourceErr || restarts >= maxUploadRestarts || ctx.Err() != nil {
+			return err
+		}
+		errorIf(err.Trace(x.sourceURL), "Unable to read `%s`, reading it again from the start.", x.sourceURL)
+	}
+}
+
+// extract uploads the members of the archive read from reader, it tells
+// if an error comes from reading the source rather than the archive.
+func (x *tarExtract) extract(ctx context.Context, reader io.Reader) (isSourceErr bool, err *probe.Error) {
+	source := &tarSource{reader: reader}
+	// Members uploaded before are seeked past when the source can seek,
+	// they are read through otherwise and their checksum verified.
+	seeker, isSeekable := reader.(io.Seeker)
+	tr := tar.NewReader(source)
+	if isSeekable {
+		tr = tar.NewReader(struct {
+			io.Reader
+			io.Seeker
+		}{source, seeker})
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	slots := make(chan struct{}, x.parallel)
+	for ctx.Err() == nil {
+		header, e := tr.Next()
+		if e == io.EOF {
+			return false, nil
+		}
+		if e != nil {
+			return source.err != nil, probe.NewError(e).Trace(x.sourceURL)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, ok := tarMemberName(header.Name)
+		if !ok {
+			warningIf(probe.NewError(TarMemberOutside{Name: header.Name}), "Skipping `%s`.", header.Name)
+			continue
+		}
+		member := tarMember{Name: name, Size: header.Size}
+		if _, ok := x.listed[name]; !ok {
+			x.listed[name] = header.Size
+			var total int64
+			for _, size := range x.listed {
+				total += size
+			}
+			if err := x.spaceCheck.verify(total); err != nil {
+				return false, err.Trace(x.targetURL)
+			}
+			x.pg.SetTotal(total)
+		}
+
+		x.mutex.Lock()
+		uploaded, isDone := x.done[name]
+		x.mutex.Unlock()
+		if isDone && uploaded.Size == member.Size {
+			if !isSeekable {
+				hasher := sha256.New()
+				if _, e = io.Copy(hasher, tr); e != nil {
+					return source.err != nil, probe.NewError(e).Trace(x.sourceURL, name)
+				}
+				if hex.EncodeToString(hasher.Sum(nil)) != uploaded.SHA256 {
+					warningIf(probe.NewError(TarMemberChanged{Name: name}), "Uploading `%s` again when the session is resumed.", name)
+					x.forget(name)
+					atomic.AddInt64(&x.failed, 1)
+					continue
+				}
+			}
+			doCopyFake(x.memberURLs(member), x.pg)
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return false, nil
+		}
+		if member.Size <= tarMemberBufferSize {
+			buf := make([]byte, member.Size)
+			if _, e = io.ReadFull(tr, buf); e != nil {
+				<-slots
+				return source.err != nil, probe.NewError(e).Trace(x.sourceURL, name)
+			}
+			sum := sha256.Sum256(buf)
+			member.SHA256 = hex.EncodeToString(sum[:])
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				if x.upload(ctx, member, bytes.NewReader(buf)) {
+					x.markDone(member)
+				}
+			}()
+			continue
+		}
+		hasher := sha256.New()
+		isUploaded := x.upload(ctx, member, io.TeeReader(tr, hasher))
+		<-slots
+		if source.err != nil {
+			return true, probe.NewError(source.err).Trace(x.sourceURL, name)
+		}
+		if isUploaded {
+			member.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+			x.markDone(member)
+		}
+	}
+	return false, nil
+}
+
+// memberURLs returns the URLs of the copy of a member, as shown.
+func (x *tarExtract) memberURLs(member tarMember) URLs {
+	return URLs{
+		SourceAlias:   x.sourceAlias,
+		SourceContent: &clientContent{URL: *newClientURL(urlJoinPath(x.sourceURL, member.Name)), Size: member.Size},
+		TargetAlias:   x.targetAlias,
+		TargetContent: &clientContent{URL: *newClientURL(urlJoinPath(x.targetURL, member.Name))},
+	}
+}
+
+// upload uploads a member read from reader, failures are reported and
+// counted.
+func (x *tarExtract) upload(ctx context.Context, member tarMember, reader io.Reader) bool {
+	urls := x.memberURLs(member)
+	printCopyMessage(urls, x.pg)
+	targetURL := urls.TargetContent.URL.String()
+	metadata := map[string]string{"Content-Type": guessURLContentType(member.Name)}
+	n, err := putTargetStream(ctx, x.targetAlias, targetURL, reader, member.Size, metadata, x.pg, x.tgtSSE)
+	if err == nil && n != member.Size {
+		err = probe.NewError(UnexpectedEOF{TotalSize: member.Size, TotalWritten: n})
+	}
+	if err != nil {
+		atomic.AddInt64(&x.failed, 1)
+		sourceURL := urls.SourceContent.URL.String()
+		errorIf(err.Trace(sourceURL), "Failed to copy `%s`.", sourceURL)
+		globalErrorReport.add(sourceURL, "put", 1, err)
+		return false
+	}
+	return true
+}
+
+func (x *tarExtract) markDone(member tarMember) {
+	x.mutex.Lock()
+	x.done[member.Name] = member
+	x.mutex.Unlock()
+	x.record(member.Name, &member)
+}
+
+func (x *tarExtract) forget(name string) {
+	x.mutex.Lock()
+	delete(x.done, name)
+	x.mutex.Unlock()
+	x.record(name, nil)
+}
+
+// doExtractSession uploads the members of the tar archive which is the
+// source of cp --extract under its target, a session records each
+// member uploaded to skip it on resume.
+func doExtractSession(cli *cli.Context, session *sessionV8, args []string, encKeyDB map[string][]prefixSSEPair) error {
+	parallelWorkers := cli.Int("parallel")
+	if session != nil {
+		args = session.Header.CommandArgs
+		parallelWorkers = session.Header.CommandIntFlags["parallel"]
+	}
+	if hostCfg := hostConfigOf(args[len(args)-1]); hostCfg != nil && parallelWorkers == 0 {
+		parallelWorkers = hostCfg.Parallel
+	}
+	if parallelWorkers == 0 {
+		parallelWorkers = runtime.NumCPU()
+	}
+
+	var pg ProgressReader
+	if !globalQuiet && !globalJSON {
+		pg = newProgressBar(0)
+	} else {
+		pg = newAccounter(0)
+	}
+
+	sourceAlias, sourceURL, _ := mustExpandAlias(args[0])
+	targetAlias, targetURL, _ := mustExpandAlias(args[1])
+	x := &tarExtract{
+		sourceAlias: sourceAlias,
+		sourceURL:   sourceURL,
+		targetAlias: targetAlias,
+		targetURL:   targetURL,
+		srcSSE:      getSSE(sourceURL, encKeyDB[sourceAlias]),
+		tgtSSE:      getSSE(targetURL, encKeyDB[targetAlias]),
+		parallel:    parallelWorkers,
+		pg:          pg,
+		done:        make(map[string]tarMember),
+		record:      func(string, *tarMember) {},
+		listed:      make(map[string]int64),
+	}
+	// Archives extracted from scratch are verified to fit a local target.
+	if session == nil || len(session.Header.Extracted) == 0 {
+		var err *probe.Error
+		x.spaceCheck, err = newFreeSpaceCheck(cli, args[1])
+		fatalIf(err, "Unable to start copying.")
+	}
+	if session != nil {
+		checkpoint, err := newSessionCheckpoint(session.Header.CommandStringFlags["checkpoint-interval"])
+		fatalIf(err, "Unable to parse checkpoint interval.")
+		for name, member := range session.Header.Extracted {
+			x.done[name] = member
+		}
+		x.record = func(name string, member *tarMember) {
+			session.mutex.Lock()
+			if member == nil {
+				delete(session.Header.Extracted, name)
+			} else {
+				if session.Header.Extracted == nil {
+					session.Header.Extracted = make(map[string]tarMember)
+				}
+				session.Header.Extracted[name] = *member
+			}
+			session.mutex.Unlock()
+			if checkpoint.due() {
+				errorIf(session.Save().Trace(session.SessionID), "Unable to save session.")
+			}
+		}
+	}
+
+	err := x.run(globalContext)
+	if progressReader, ok := pg.(*progressBar); ok {
+		if progressReader.ProgressBar.Get() > 0 {
+			progressReader.ProgressBar.Finish()
+		}
+	} else if accntReader, ok := pg.(*accounter); ok {
+		printMsg(accntReader.Stat())
+	}
+	if session != nil && globalContext.Err() != nil {
+		globalErrorReport.write()
+		return session.CloseAndDie()
+	}
+	if err != nil {
+		errorIf(err.Trace(args...), "Unable to extract `%s`.", args[0])
+		globalErrorReport.add(args[0], "get", 1, err)
+	}
+	if err != nil || atomic.LoadInt64(&x.failed) > 0 {
+		if session != nil {
+			globalErrorReport.write()
+			if e := session.copyCloseAndDie(session.Header.CommandBoolFlags["session"]); e != nil {
+				return e
+			}
+		}
+		return exitStatus(globalErrorExitStatus)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestTarMemberName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"a.txt", "a.txt", true},
+		{"./dir/b.txt", "dir/b.txt", true},
+		{"/etc/passwd", "etc/passwd", true},
+		{"dir/../c.txt", "c.txt", true},
+		{"../evil", "", false},
+		{"dir/../../evil", "", false},
+		{"..", "", false},
+		{"./", "", false},
+	}
+	for i, testCase := range testCases {
+		name, ok := tarMemberName(testCase.name)
+		if name != testCase.expected || ok != testCase.ok {
+			t.Errorf("Test %d: expected %q %v, found %q %v", i+1, testCase.expected, testCase.ok, name, ok)
+		}
+	}
+}
+
+// tarArchive returns a tar archive of the given files in order, along
+// with a folder entry.
+func tarArchive(t *testing.T, files ...string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if e := tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}); e != nil {
+		t.Fatal(e)
+	}
+	for i := 0; i < len(files); i += 2 {
+		if e := tw.WriteHeader(&tar.Header{Name: files[i], Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(files[i+1]))}); e != nil {
+			t.Fatal(e)
+		}
+		if _, e := tw.Write([]byte(files[i+1])); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e := tw.Close(); e != nil {
+		t.Fatal(e)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTarExtract(t *testing.T) {
+	dir, e := ioutil.TempDir("", "mc-extract-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	defer useEmptyMcConfig()()
+
+	archive := tarArchive(t, "a.txt", "first", "dir/b.txt", "second", "../evil", "outside", "c.txt", "third")
+	source := filepath.Join(dir, "archive.tar")
+	if e = ioutil.WriteFile(source, archive, 0600); e != nil {
+		t.Fatal(e)
+	}
+	newExtract := func(target string, done map[string]tarMember) (*tarExtract, map[string]*tarMember) {
+		recorded := make(map[string]*tarMember)
+		var mutex sync.Mutex
+		return &tarExtract{
+			sourceURL: source,
+			targetURL: target,
+			parallel:  2,
+			pg:        newAccounter(0),
+			done:      done,
+			record: func(name string, member *tarMember) {
+				mutex.Lock()
+				recorded[name] = member
+				mutex.Unlock()
+			},
+			listed: make(map[string]int64),
+		}, recorded
+	}
+	read := func(path string) string {
+		data, e := ioutil.ReadFile(path)
+		if e != nil {
+			return ""
+		}
+		return string(data)
+	}
+
+	// Regular files are uploaded, the folder and a member outside of
+	// the target are not.
+	target := filepath.Join(dir, "target")
+	x, recorded := newExtract(target, make(map[string]tarMember))
+	if err := x.run(context.Background()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	for name, data := range map[string]string{"a.txt": "first", "dir/b.txt": "second", "c.txt": "third"} {
+		if found := read(filepath.Join(target, name)); found != data {
+			t.Errorf("expected %q in %s, found %q", data, name, found)
+		}
+		member := recorded[name]
+		if member == nil || member.Size != int64(len(data)) || member.SHA256 != sha256Hex(data) {
+			t.Errorf("expected %s to be recorded with its checksum, found %v", name, member)
+		}
+	}
+	if len(recorded) != 3 || x.failed != 0 {
+		t.Fatalf("expected 3 members uploaded without failure, found %d and %d failures", len(recorded), x.failed)
+	}
+	if _, e = os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(e) {
+		t.Fatal("expected no file outside of the target")
+	}
+
+	// Resumed, the members uploaded before are skipped.
+	target = filepath.Join(dir, "resumed")
+	x, recorded = newExtract(target, map[string]tarMember{
+		"a.txt": {Name: "a.txt", Size: 5, SHA256: sha256Hex("first")},
+	})
+	if err := x.run(context.Background()); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if read(filepath.Join(target, "a.txt")) != "" || read(filepath.Join(target, "c.txt")) != "third" || len(recorded) != 2 {
+		t.Fatalf("expected only the members left to be uploaded, found %v", recorded)
+	}
+
+	// A source which can't seek fails while read, it is read again and
+	// the members uploaded before are verified as they are read past.
+	target = filepath.Join(dir, "reread")
+	x, recorded = newExtract(target, make(map[string]tarMember))
+	isSourceErr, err := x.extract(context.Background(), failingReader{io.LimitReader(bytes.NewReader(archive), 3*512)})
+	if err == nil || !isSourceErr {
+		t.Fatalf("expected a source error, found %v", err)
+	}
+	if len(recorded) != 1 || recorded["a.txt"] == nil {
+		t.Fatalf("expected a.txt to be uploaded before the failure, found %v", recorded)
+	}
+	x.done["dir/b.txt"] = tarMember{Name: "dir/b.txt", Size: 6, SHA256: sha256Hex("other")}
+	if _, err = x.extract(context.Background(), bytes.NewBuffer(archive)); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if read(filepath.Join(target, "c.txt")) != "third" {
+		t.Fatal("expected the members left to be uploaded once read again")
+	}
+	// Changed since it was uploaded, it is left for the next resume.
+	if member, ok := recorded["dir/b.txt"]; !ok || member != nil || x.failed != 1 {
+		t.Fatalf("expected the changed member to be forgotten, found %v and %d failures", member, x.failed)
+	}
+}
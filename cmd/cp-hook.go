@@ -0,0 +1,226 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+// copyHooks - commands run after each object copy, `--on-success` once
+// it is copied and `--on-failure` when it could not be. The words of a
+// command are split as a shell would before placeholders are replaced,
+// so that object names with spaces or shell characters stay a single
+// argument.
+type copyHooks struct {
+	onSuccess []string
+	onFailure []string
+	isStrict  bool
+}
+
+// newCopyHooks returns nil when no hook is configured.
+func newCopyHooks(onSuccess, onFailure string, isStrict bool) (*copyHooks, *probe.Error) {
+	if onSuccess == "" && onFailure == "" {
+		return nil, nil
+	}
+	successArgs, err := splitHookCommand(onSuccess)
+	if err != nil {
+		return nil, err.Trace(onSuccess)
+	}
+	failureArgs, err := splitHookCommand(onFailure)
+	if err != nil {
+		return nil, err.Trace(onFailure)
+	}
+	return &copyHooks{
+		onSuccess: successArgs,
+		onFailure: failureArgs,
+		isStrict:  isStrict,
+	}, nil
+}
+
+// splitHookCommand splits a command into words on spaces outside of
+// quotes. Single quotes keep their content as is, a backslash escapes
+// the next character outside of them.
+func splitHookCommand(command string) ([]string, *probe.Error) {
+	var args []string
+	var word strings.Builder
+	var quote rune
+	inWord, escaped := false, false
+	for _, r := range command {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' && r != '$' && r != '`' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, probe.NewError(errors.New("unterminated quote or escape in hook command"))
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
+
+// run executes the hooks matching the outcome of cpURLs.
+func (h *copyHooks) run(cpURLs URLs) URLs {
+	return h.runOnFailure(h.runOnSuccess(cpURLs, false))
+}
+
+// runOnSuccess runs `--on-success` once cpURLs is copied. A failing hook
+// is only reported, unless `--strict-hooks` makes it fail the object. A
+// move whose source is removed already is not failed anymore, moves run
+// it before removing their source instead.
+func (h *copyHooks) runOnSuccess(cpURLs URLs, isSourceRemoved bool) URLs {
+	if h == nil || cpURLs.SourceContent == nil || cpURLs.TargetContent == nil {
+		return cpURLs
+	}
+	if cpURLs.Error != nil || len(h.onSuccess) == 0 {
+		return cpURLs
+	}
+	sourceURL := cpURLs.SourceContent.URL.String()
+	if err := execCopyHook(h.onSuccess, cpURLs); err != nil {
+		if h.isStrict && !isSourceRemoved {
+			return cpURLs.WithError(err.Trace(sourceURL))
+		}
+		if !globalQuiet && !globalJSON {
+			console.Eraseline()
+		}
+		errorIf(err.Trace(sourceURL), "Unable to run --on-success hook for `%s`.", sourceURL)
+	}
+	return cpURLs
+}
+
+// runOnFailure runs `--on-failure` when cpURLs could not be copied.
+func (h *copyHooks) runOnFailure(cpURLs URLs) URLs {
+	if h == nil || cpURLs.SourceContent == nil || cpURLs.TargetContent == nil {
+		return cpURLs
+	}
+	if cpURLs.Error == nil || len(h.onFailure) == 0 {
+		return cpURLs
+	}
+	sourceURL := cpURLs.SourceContent.URL.String()
+	if err := execCopyHook(h.onFailure, cpURLs); err != nil {
+		if !globalQuiet && !globalJSON {
+			console.Eraseline()
+		}
+		errorIf(err.Trace(sourceURL), "Unable to run --on-failure hook for `%s`.", sourceURL)
+	}
+	return cpURLs
+}
+
+// execCopyHook runs the command with its placeholders replaced.
+func execCopyHook(command []string, cpURLs URLs) *probe.Error {
+	values := copyHookValues(command, cpURLs)
+	placeholders := make([]string, 0, 2*len(values))
+	for placeholder, value := range values {
+		placeholders = append(placeholders, placeholder, value)
+	}
+	// Replaced at once, values are not searched for placeholders.
+	replacer := strings.NewReplacer(placeholders...)
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = replacer.Replace(arg)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(globalContext, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	if e := cmd.Run(); e != nil {
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			e = errors.New(e.Error() + ": " + output)
+		}
+		return errHookFailed(args[0], e)
+	}
+	return nil
+}
+
+// copyHookValues - values of the placeholders available to hooks.
+//
+//	{source}  source of the copy, prefixed with its alias
+//	{target}  target of the copy, prefixed with its alias
+//	{key}     object name within its bucket, or path of a local target
+//	{size}    size in bytes
+//	{etag}    ETag of the copied object, empty for local targets
+//	{error}   reason of the failure, empty after a successful copy
+func copyHookValues(command []string, cpURLs URLs) map[string]string {
+	sourceURL := cpURLs.SourceContent.URL
+	targetURL := cpURLs.TargetContent.URL
+
+	key := targetURL.Path
+	if cpURLs.TargetAlias != "" {
+		// Trim the bucket name.
+		if parts := strings.SplitN(strings.TrimPrefix(key, "/"), "/", 2); len(parts) == 2 {
+			key = parts[1]
+		}
+	}
+
+	values := map[string]string{
+		"{source}": filepath.ToSlash(filepath.Join(cpURLs.SourceAlias, sourceURL.Path)),
+		"{target}": filepath.ToSlash(filepath.Join(cpURLs.TargetAlias, targetURL.Path)),
+		"{key}":    key,
+		"{size}":   strconv.FormatInt(cpURLs.SourceContent.Size, 10),
+		"{etag}":   "",
+		"{error}":  "",
+	}
+	if cpURLs.Error != nil {
+		values["{error}"] = cpURLs.Error.ToGoError().Error()
+	} else if strings.Contains(strings.Join(command, " "), "{etag}") && cpURLs.TargetAlias != "" {
+		// Only look up the ETag when a hook asks for it.
+		if clnt, err := newClientFromAlias(cpURLs.TargetAlias, targetURL.String()); err == nil {
+			if content, err := clnt.Stat(false, false, false, nil); err == nil {
+				values["{etag}"] = content.ETag
+			}
+		}
+	}
+	return values
+}
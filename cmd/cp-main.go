@@ -19,19 +19,29 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	humanize "github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/minio/cli"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
+	"github.com/minio/minio/pkg/disk"
+	"golang.org/x/net/http/httpguts"
 )
 
 // cp command flags.
@@ -57,9 +67,17 @@ var (
 			Name:  "encrypt",
 			Usage: "encrypt/decrypt objects (using server-side encryption with server managed keys)",
 		},
+		cli.StringFlag{
+			Name:  "sse",
+			Usage: "encrypt uploaded object(s) with server managed keys, AES256 for SSE-S3 or aws:kms for SSE-KMS",
+		},
+		cli.StringFlag{
+			Name:  "sse-kms-key-id",
+			Usage: "KMS key id used with --sse aws:kms, by default the default key of the server",
+		},
 		cli.StringFlag{
 			Name:  "attr",
-			Usage: "add custom metadata for the object",
+			Usage: "add custom metadata for the object, as a comma separated list of key=value",
 		},
 		cli.BoolFlag{
 			Name:  "continue, c",
@@ -69,11 +87,173 @@ var (
 			Name:  "preserve, a",
 			Usage: "preserve filesystem attributes (mode, ownership, timestamps)",
 		},
+		cli.BoolFlag{
+			Name:  "preserve-mtime",
+			Usage: "preserve source modification time on target",
+		},
+		cli.StringFlag{
+			Name:  "checkpoint-interval",
+			Usage: "save copy session every N objects or every DURATION, lower values lose less progress on crash but slow down copying",
+			Value: defaultCheckpointInterval,
+		},
+		cli.StringFlag{
+			Name:  "content-disposition",
+			Usage: "set Content-Disposition header on uploaded object(s)",
+		},
+		cli.StringFlag{
+			Name:  "content-type",
+			Usage: "set Content-Type header on uploaded object(s) instead of detecting it from the extension and contents",
+		},
+		cli.StringFlag{
+			Name:  "cache-control",
+			Usage: "set Cache-Control header on uploaded object(s)",
+		},
+		cli.StringFlag{
+			Name:  "charset",
+			Usage: "set the charset of uploaded text object(s) instead of detecting it",
+		},
+		cli.BoolFlag{
+			Name:  "auto-sse",
+			Usage: "upload again with SSE-S3 when the bucket policy denies uploads without encryption",
+		},
+		cli.BoolFlag{
+			Name:  "compress-auto",
+			Usage: "gzip compress text, json, csv and xml objects on upload, server side copies are left as is",
+		},
+		cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "report the compression ratio of every object compressed by --compress-auto",
+		},
+		cli.StringFlag{
+			Name:  "if-match",
+			Usage: "overwrite the target object only if its ETag still matches this value",
+		},
+		cli.IntFlag{
+			Name:  "max-key-length",
+			Usage: "longest object name in bytes the target accepts, local targets are checked against the OS limits",
+			Value: defaultMaxKeyLength,
+		},
+		cli.BoolFlag{
+			Name:  "skip-long-keys",
+			Usage: "skip objects whose name is too long for the target instead of failing them",
+		},
+		cli.StringFlag{
+			Name:  "on-success",
+			Usage: "command run after each copied object, with {source}, {target}, {key}, {size} and {etag} replaced",
+		},
+		cli.StringFlag{
+			Name:  "on-failure",
+			Usage: "command run after each object which failed to copy, {error} is replaced with the reason",
+		},
+		cli.BoolFlag{
+			Name:  "strict-hooks",
+			Usage: "fail the object when its --on-success command fails, mv keeps its source then unless renamed locally",
+		},
+		cli.IntFlag{
+			Name:  "list-workers",
+			Usage: "number of sources listed at the same time when copying several sources",
+			Value: 1,
+		},
+		cli.BoolFlag{
+			Name:  "append",
+			Usage: "append the bytes of the source past the size of an existing local target, failing if the source got smaller",
+		},
+		cli.IntFlag{
+			Name:  "retry",
+			Usage: "number of retries of an upload failing with a server error or a lost connection, shared by its parts",
+			Value: defaultCopyRetries,
+		},
+		cli.StringFlag{
+			Name:  "retry-delay",
+			Usage: "delay before the first retry of an object, doubled at every retry",
+			Value: defaultCopyRetryDelay,
+		},
+		cli.BoolFlag{
+			Name:  "no-server-side",
+			Usage: "stream objects through mc instead of having an endpoint copy objects it stores by itself",
+		},
+		cli.StringFlag{
+			Name:  "checksum",
+			Usage: "store the checksum of the source made with this algorithm, sha256 or md5, in the metadata of the target, sources which cannot be read at an offset are read twice, server side copies read their source unless it stores a checksum made with this algorithm already",
+		},
+		cli.StringFlag{
+			Name:  "protect-window",
+			Usage: "skip targets modified within this duration instead of overwriting them, e.g. 10m",
+		},
+		cli.StringSliceFlag{
+			Name:  "exclude",
+			Usage: "exclude source object(s) whose path relative to the source matches this pattern, a pattern ending with '/' excludes whole folders",
+		},
+		cli.IntFlag{
+			Name:  "parallel",
+			Usage: "number of objects copied at the same time, by default the number configured for the target host or workers added while the transfer speeds up",
+		},
+		cli.StringFlag{
+			Name:  "multipart-threshold",
+			Usage: "upload objects smaller than this size with a single PUT and larger ones in parts of half this size, at most 5GiB, by default twice the part size configured for the target host",
+			Value: humanize.IBytes(defaultMultipartThreshold),
+		},
+		cli.StringFlag{
+			Name:  "slash-conflict",
+			Usage: "copy to a filesystem of an object which is also a folder, 'skip' or 'rename' to KEY" + slashConflictSuffix,
+			Value: slashConflictSkip,
+		},
+		cli.BoolFlag{
+			Name:  "stream",
+			Usage: "with --continue, start copying while the sources are still listed instead of listing them all first, the sources are then listed by a single worker",
+		},
+		cli.BoolFlag{
+			Name:  "recompute-totals",
+			Usage: "count the size and number of objects left to copy from the listing saved by a resumed session",
+		},
+		cli.BoolFlag{
+			Name:  "repair",
+			Usage: "with --continue, list the sources again to rebuild a corrupt session data file, objects up to the last one copied are skipped",
+		},
+		cli.StringFlag{
+			Name:  "limit-upload",
+			Usage: "limit the aggregate rate of uploads to object storage, e.g. \"512KiB/s\" or \"10MB/s\"",
+		},
+		cli.StringFlag{
+			Name:  "limit-download",
+			Usage: "limit the aggregate rate of downloads from object storage, e.g. \"512KiB/s\" or \"10MB/s\"",
+		},
+		cli.StringFlag{
+			Name:  "metrics-addr",
+			Usage: "serve Prometheus metrics of the transfer on this address, e.g. \":9090\"",
+		},
+		cli.StringFlag{
+			Name:  "throughput-log",
+			Usage: "write transferred bytes and objects to this CSV file every second",
+		},
+		cli.StringFlag{
+			Name:  "error-report",
+			Usage: "write every failure of the run to this JSON file once it is over",
+		},
+		cli.BoolFlag{
+			Name:  "allow-empty",
+			Usage: "succeed without copying anything if a recursive source does not exist",
+		},
+		cli.BoolFlag{
+			Name:  "no-follow-target-symlink",
+			Usage: "refuse to copy into a local target folder which is a symbolic link",
+		},
+		cli.BoolFlag{
+			Name:  "no-space-check",
+			Usage: "skip verifying that the sources fit the free space of a local target as they are listed",
+		},
+		cli.BoolFlag{
+			Name:  "extract",
+			Usage: "upload the files of the tar archive SOURCE under TARGET, resumed with --continue",
+		},
 	}
 )
 
 // ErrInvalidMetadata reflects invalid metadata format
-var ErrInvalidMetadata = errors.New("specified metadata should be of form key1=value1;key2=value2;... and so on")
+var ErrInvalidMetadata = errors.New("specified metadata should be of form key1=value1,key2=value2,... and so on")
+
+// ErrInvalidMetadataHeader reflects metadata which cannot be sent as an HTTP header
+var ErrInvalidMetadataHeader = errors.New("specified metadata keys should be valid header names and values should not contain control characters")
 
 // Copy command.
 var cpCmd = cli.Command{
@@ -127,10 +307,11 @@ EXAMPLES:
       base64 encoded string as key.
       {{.Prompt}} {{.HelpName}} --recursive --encrypt-key "s3/documents/=MzJieXRlc2xvbmdzZWNyZWFiY2RlZmcJZ2l2ZW5uMjE=,myminio/documents/=MzJieXRlc2xvbmdzZWNyZWFiY2RlZmcJZ2l2ZW5uMjE=" s3/documents/ myminio/documents/
 
-  11. Copy a list of objects from local file system to MinIO cloud storage with specified metadata, separated by ";"
-      {{.Prompt}} {{.HelpName}} --attr "key1=value1;key2=value2" Music/*.mp4 play/mybucket/
+  11. Copy a list of objects from local file system to MinIO cloud storage with specified metadata, separated by ",".
+      {{.Prompt}} {{.HelpName}} --attr "key1=value1,key2=value2" Music/*.mp4 play/mybucket/
 
-  12. Copy a folder recursively from MinIO cloud storage to Amazon S3 cloud storage with Cache-Control and custom metadata, separated by ";".
+  12. Copy a folder recursively from MinIO cloud storage to Amazon S3 cloud storage with Cache-Control and custom metadata,
+      separated by ";" to keep the commas of the Cache-Control value.
       {{.Prompt}} {{.HelpName}} --attr "Cache-Control=max-age=90000,min-fresh=9000;key1=value1;key2=value2" --recursive play/mybucket/burningman2011/ s3/mybucket/
 
   13. Copy a text file to an object storage and assign REDUCED_REDUNDANCY storage-class to the uploaded object.
@@ -143,10 +324,115 @@ EXAMPLES:
       {{.Prompt}} {{.HelpName}} -a myobject.txt play/mybucket
 
   16. Copy a text file to an object storage with object lock mode set to 'GOVERNANCE' with retention date.
-      {{.Prompt}} {{.HelpName}} --attr "x-amz-object-lock-mode=GOVERNANCE;x-amz-object-lock-retain-until-date=2020-01-11T01:57:02Z" locked.txt play/locked-bucket/
+      {{.Prompt}} {{.HelpName}} --attr "x-amz-object-lock-mode=GOVERNANCE,x-amz-object-lock-retain-until-date=2020-01-11T01:57:02Z" locked.txt play/locked-bucket/
+
+  17. Copy a large number of small files and save the copy session only every 1000 objects. A crash
+      may re-copy up to 1000 objects on resume, in exchange for fewer session writes.
+      {{.Prompt}} {{.HelpName}} --recursive --continue --checkpoint-interval 1000 dir/ play/mybucket
+
+  18. Copy a bucket to a sparse local volume without verifying its free space.
+      {{.Prompt}} {{.HelpName}} --recursive --no-space-check play/mybucket/ /mnt/sparse/
+
+  19. Copy a folder recursively to an object storage keeping the modification time of every file.
+      {{.Prompt}} {{.HelpName}} --recursive --preserve-mtime dir/ play/mybucket
+
+  20. Copy a file to be served as a download with a one day browser cache.
+      {{.Prompt}} {{.HelpName}} --content-disposition 'attachment; filename="report.pdf"' --cache-control "public, max-age=86400" report.pdf play/mybucket
+
+  21. Copy a folder recursively exposing transfer metrics at http://localhost:9090/metrics.
+      {{.Prompt}} {{.HelpName}} --recursive --metrics-addr :9090 dir/ play/mybucket
+
+  22. Copy a folder of latin-1 encoded text files, labelling them 'text/plain; charset=iso-8859-1'.
+      {{.Prompt}} {{.HelpName}} --recursive --charset iso-8859-1 legacy-docs/ play/mybucket
+
+  23. Copy today's logs from a nightly job, a prefix which was not created yet is not an error.
+      {{.Prompt}} {{.HelpName}} --recursive --allow-empty play/logs/2020-05-21/ /var/backups/logs/
+
+  24. Copy a large bucket recording the throughput over time in a CSV file.
+      {{.Prompt}} {{.HelpName}} --recursive --throughput-log tput.csv s3/archive/ play/archive/
+
+  25. Update the metadata of an object in place by copying it onto itself.
+      {{.Prompt}} {{.HelpName}} --attr "key1=value1" --cache-control "max-age=3600" play/mybucket/object.txt play/mybucket/object.txt
+
+  26. Overwrite an object only if nobody changed it since its ETag was read.
+      {{.Prompt}} {{.HelpName}} --if-match "d41d8cd98f00b204e9800998ecf8427e" config.json play/mybucket/config.json
+
+  27. Back up every bucket whose name starts with 'logs-', each into a folder named after the bucket.
+      {{.Prompt}} {{.HelpName}} --recursive 'play/logs-*' /mnt/backups/
+
+  28. Register every uploaded object in a catalog, failing the object if registration fails.
+      {{.Prompt}} {{.HelpName}} --recursive --on-success "catalog add {key} {size} {etag}" --strict-hooks photos/ play/mybucket
+
+  29. Skip objects whose names are longer than the 255 bytes accepted by a legacy gateway.
+      {{.Prompt}} {{.HelpName}} --recursive --max-key-length 255 --skip-long-keys photos/ legacy/mybucket
+
+  30. Compress logs and reports on upload, leaving images and archives as they are.
+      {{.Prompt}} {{.HelpName}} --recursive --compress-auto --verbose reports/ s3/mybucket/reports
+
+  31. Copy many buckets into a backup folder, listing four of them at a time.
+      {{.Prompt}} {{.HelpName}} --recursive --list-workers 4 's3/logs-*' /mnt/backups/
+
+  32. Resume an interrupted copy whose saved totals are off, counting them again for an accurate progress bar.
+      {{.Prompt}} {{.HelpName}} --recursive --continue --recompute-totals s3/incoming /mnt/archive/
+
+  33. Upload videos of up to 1GiB with a single PUT each, larger ones in parts.
+      {{.Prompt}} {{.HelpName}} --recursive --multipart-threshold 1GiB videos/ s3/mybucket/videos
+
+  34. Download a bucket holding both an object 'foo' and objects under 'foo/', saving the object as 'foo__file'.
+      {{.Prompt}} {{.HelpName}} --recursive --slash-conflict rename s3/mybucket /mnt/mybucket/
+
+  35. Start copying a bucket with millions of objects right away in a resumable session, the totals grow while it is listed.
+      {{.Prompt}} {{.HelpName}} --recursive --continue --stream s3/mybucket /mnt/mybucket/
+
+  36. Download a bucket without using more than 10MB per second of the link, also when the session is resumed.
+      {{.Prompt}} {{.HelpName}} --recursive --continue --limit-download 10MB/s s3/mybucket /mnt/mybucket/
+
+  37. Copy a folder and save details of the objects which could not be copied for a monitoring system.
+      {{.Prompt}} {{.HelpName}} --recursive --error-report report.json dir/ s3/mybucket/dir
+
+  38. Copy a folder to a bucket whose policy denies unencrypted uploads, encrypting with SSE-S3 where it is required.
+      {{.Prompt}} {{.HelpName}} --recursive --auto-sse backups/ s3/compliance-bucket/
+
+  39. Copy a folder of many small files, copying 32 of them at a time.
+      {{.Prompt}} {{.HelpName}} --recursive --parallel 32 thumbnails/ s3/mybucket/thumbnails/
+
+  40. Copy a project folder without temporary files and installed node modules.
+      {{.Prompt}} {{.HelpName}} --recursive --exclude "*.tmp" --exclude "node_modules/" project/ s3/mybucket/project/
+
+  41. Copy a folder from a scheduled job, without overwriting objects written in the last 10 minutes by another run.
+      {{.Prompt}} {{.HelpName}} --recursive --protect-window 10m reports/ s3/mybucket/reports/
+
+  42. Ship the lines added to a log since the last run to a local archive.
+      {{.Prompt}} {{.HelpName}} --append /var/log/app.log /mnt/archive/app.log
+
+  43. Copy a folder storing the SHA-256 checksum of each file, verified later with 'mc scan'.
+      {{.Prompt}} {{.HelpName}} --recursive --checksum sha256 archive/ s3/mybucket/archive/
+
+  44. Copy a bucket to another bucket of the same endpoint, reading and writing each object instead of a server side copy.
+      {{.Prompt}} {{.HelpName}} --recursive --no-server-side s3/mybucket/ s3/otherbucket/
+
+  45. Copy over an unreliable network, trying each object up to 6 times with delays starting at 5 seconds.
+      {{.Prompt}} {{.HelpName}} --recursive --retry 5 --retry-delay 5s backups/ s3/mybucket/backups/
+
+  46. Copy a folder encrypting the objects with SSE-KMS using a given KMS key.
+      {{.Prompt}} {{.HelpName}} --recursive --sse aws:kms --sse-kms-key-id my-minio-key backups/ s3/mybucket/backups/
+
+  47. Copy a file without extension to be rendered by browsers as a web page.
+      {{.Prompt}} {{.HelpName}} --content-type "text/html; charset=utf-8" site/index play/mybucket/index
+
+  48. Resume a session whose data file was truncated, listing the sources again and skipping the objects already copied.
+      {{.Prompt}} {{.HelpName}} --recursive --continue --repair backups/ s3/mybucket/backups/
+
+  49. Copy a file published on a web server into a bucket, without downloading it first.
+      {{.Prompt}} {{.HelpName}} https://example.com/file.iso s3/mybucket/
+
+  50. Upload the files of a tar archive to a bucket, skipping those already uploaded when resumed.
+      {{.Prompt}} {{.HelpName}} --extract --continue backup.tar s3/mybucket/backup/
 `,
 }
 
+const uaCopyAppName = "mc-cp"
+
 // copyMessage container for file copy messages
 type copyMessage struct {
 	Status     string `json:"status"`
@@ -171,6 +457,92 @@ func (c copyMessage) JSON() string {
 	return string(copyMessageBytes)
 }
 
+// verifyDoneMessage container for the objects checked by --verify-done.
+type verifyDoneMessage struct {
+	Status   string `json:"status"`
+	Verified int64  `json:"verified"`
+	Requeued int64  `json:"requeued"`
+}
+
+// String colorized verify done message
+func (v verifyDoneMessage) String() string {
+	return console.Colorize("VerifyDone", fmt.Sprintf("Verified %d object(s) copied before the session was interrupted, copied %d of them again.", v.Verified, v.Requeued))
+}
+
+// JSON jsonified verify done message
+func (v verifyDoneMessage) JSON() string {
+	v.Status = "success"
+	msgBytes, e := json.MarshalIndent(v, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
+
+// copyPlanMessage container for the copy checked in offline mode.
+type copyPlanMessage struct {
+	Status  string   `json:"status"`
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+	Type    string   `json:"type"`
+}
+
+// String colorized copy plan message
+func (c copyPlanMessage) String() string {
+	return console.Colorize("Copy", fmt.Sprintf("`%s` -> `%s` (%s)", strings.Join(c.Sources, "`, `"), c.Target, c.Type))
+}
+
+// JSON jsonified copy plan message
+func (c copyPlanMessage) JSON() string {
+	c.Status = "success"
+	copyPlanMessageBytes, e := json.MarshalIndent(c, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(copyPlanMessageBytes)
+}
+
+// compressMessage container for the compression ratio of an object.
+type compressMessage struct {
+	Status         string  `json:"status"`
+	Source         string  `json:"source"`
+	Size           int64   `json:"size"`
+	CompressedSize int64   `json:"compressedSize"`
+	Ratio          float64 `json:"ratio"`
+}
+
+// String colorized compression message
+func (c compressMessage) String() string {
+	return console.Colorize("Copy", fmt.Sprintf("`%s`: compressed %s to %s (%.1f%%)", c.Source,
+		humanize.IBytes(uint64(c.Size)), humanize.IBytes(uint64(c.CompressedSize)), c.Ratio*100))
+}
+
+// JSON jsonified compression message
+func (c compressMessage) JSON() string {
+	c.Status = "success"
+	compressMessageBytes, e := json.MarshalIndent(c, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(compressMessageBytes)
+}
+
+// printCompressRatio reports the stored size of an object compressed
+// on upload.
+func printCompressRatio(cpURLs URLs) {
+	if cpURLs.Error != nil || cpURLs.TargetContent.Metadata["Content-Encoding"] != "gzip" {
+		return
+	}
+	msg := compressMessage{
+		Source:         filepath.ToSlash(filepath.Join(cpURLs.SourceAlias, cpURLs.SourceContent.URL.Path)),
+		Size:           cpURLs.SourceContent.Size,
+		CompressedSize: cpURLs.TargetContent.Size,
+	}
+	if msg.Size > 0 {
+		msg.Ratio = float64(msg.CompressedSize) / float64(msg.Size)
+	}
+	if !globalQuiet && !globalJSON {
+		console.Eraseline()
+	}
+	printMsg(msg)
+}
+
 // Progress - an interface which describes current amount
 // of data written.
 type Progress interface {
@@ -192,6 +564,12 @@ func doCopy(ctx context.Context, cpURLs URLs, pg ProgressReader, encKeyDB map[st
 		return cpURLs
 	}
 
+	printCopyMessage(cpURLs, pg)
+	return uploadSourceToTargetURL(ctx, cpURLs, pg, encKeyDB)
+}
+
+// printCopyMessage shows the copy of cpURLs as started.
+func printCopyMessage(cpURLs URLs, pg ProgressReader) {
 	sourceAlias := cpURLs.SourceAlias
 	sourceURL := cpURLs.SourceContent.URL
 	targetAlias := cpURLs.TargetAlias
@@ -202,6 +580,10 @@ func doCopy(ctx context.Context, cpURLs URLs, pg ProgressReader, encKeyDB map[st
 		progressReader.SetCaption(cpURLs.SourceContent.URL.String() + ": ")
 	} else {
 		sourcePath := filepath.ToSlash(filepath.Join(sourceAlias, sourceURL.Path))
+		if sourceAlias == "" && sourceURL.Type == objectStorage {
+			// Plain HTTP(S) URLs are shown whole.
+			sourcePath = sourceURL.String()
+		}
 		targetPath := filepath.ToSlash(filepath.Join(targetAlias, targetURL.Path))
 		printMsg(copyMessage{
 			Source:     sourcePath,
@@ -211,7 +593,55 @@ func doCopy(ctx context.Context, cpURLs URLs, pg ProgressReader, encKeyDB map[st
 			TotalSize:  cpURLs.TotalSize,
 		})
 	}
-	return uploadSourceToTargetURL(ctx, cpURLs, pg, encKeyDB)
+}
+
+// copyTargetRoot returns the local folder named as target of the
+// copy, empty for object storage targets.
+func copyTargetRoot(targetURL string) string {
+	targetAlias, expandedURL, _ := mustExpandAlias(targetURL)
+	if targetAlias != "" || newClientURL(expandedURL).Type != fileSystem {
+		return ""
+	}
+	return filepath.Clean(expandedURL)
+}
+
+// checkTargetSymlinks verifies that no existing folder between rootPath
+// and the file at targetPath is a symbolic link. rootPath itself may be
+// one, the folders below it must not lead the copy out of it.
+func checkTargetSymlinks(rootPath, targetPath string) *probe.Error {
+	relPath, e := filepath.Rel(rootPath, filepath.Dir(targetPath))
+	if e != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+		return nil
+	}
+	dirPath := rootPath
+	for _, name := range strings.Split(relPath, string(filepath.Separator)) {
+		dirPath = filepath.Join(dirPath, name)
+		st, e := os.Lstat(dirPath)
+		if os.IsNotExist(e) {
+			// Remaining folders are created by the copy.
+			return nil
+		}
+		if e != nil {
+			return probe.NewError(e)
+		}
+		if st.Mode()&os.ModeSymlink != 0 {
+			return errTargetIsSymlink(dirPath)
+		}
+	}
+	return nil
+}
+
+// appendSourceID returns the value of appendMetaKey for source, its
+// fileID when it is a local file.
+func appendSourceID(source *clientContent) string {
+	if source.URL.Type == fileSystem {
+		if fi, e := os.Stat(source.URL.Path); e == nil {
+			if id := fileID(fi); id != "" {
+				return id
+			}
+		}
+	}
+	return "true"
 }
 
 // doCopyFake - Perform a fake copy to update the progress bar appropriately.
@@ -222,8 +652,9 @@ func doCopyFake(cpURLs URLs, pg Progress) URLs {
 	return cpURLs
 }
 
-// doPrepareCopyURLs scans the source URL and prepares a list of objects for copying.
-func doPrepareCopyURLs(session *sessionV8, cancelCopy context.CancelFunc) (totalBytes, totalObjects int64) {
+// prepareSessionCopyURLs lists the sources of a session with the
+// options it was started with.
+func prepareSessionCopyURLs(session *sessionV8, excludes *excludeFilter) <-chan URLs {
 	// Separate source and target. 'cp' can take only one target,
 	// but any number of sources.
 	sourceURLs := session.Header.CommandArgs[:len(session.Header.CommandArgs)-1]
@@ -231,6 +662,7 @@ func doPrepareCopyURLs(session *sessionV8, cancelCopy context.CancelFunc) (total
 
 	// Access recursive flag inside the session header.
 	isRecursive := session.Header.CommandBoolFlags["recursive"]
+	isAllowEmpty := session.Header.CommandBoolFlags["allow-empty"]
 
 	olderThan := session.Header.CommandStringFlags["older-than"]
 	newerThan := session.Header.CommandStringFlags["newer-than"]
@@ -239,6 +671,53 @@ func doPrepareCopyURLs(session *sessionV8, cancelCopy context.CancelFunc) (total
 	encKeyDB, err := parseAndValidateEncryptionKeys(encryptKeys, encrypt)
 	fatalIf(err, "Unable to parse encryption keys.")
 
+	// Streamed sessions resume past the last copied URL of a new
+	// listing, only a single worker lists the sources in the same order.
+	listWorkers := 1
+	if value, ok := session.Header.CommandIntFlags["list-workers"]; ok && !session.Header.CommandBoolFlags["stream"] {
+		listWorkers = value
+	}
+
+	slashConflict := session.Header.CommandStringFlags["slash-conflict"]
+
+	return prepareCopyURLs(sourceURLs, targetURL, isRecursive, isAllowEmpty, encKeyDB, olderThan, newerThan, listWorkers, slashConflict, excludes)
+}
+
+// sessionExcludeFilter returns a filter of the --exclude patterns of
+// a session, saved one per line.
+func sessionExcludeFilter(session *sessionV8) *excludeFilter {
+	patterns := session.Header.CommandStringFlags["exclude"]
+	if patterns == "" {
+		return nil
+	}
+	return newExcludeFilter(strings.Split(patterns, "\n"))
+}
+
+// recomputeCopyTotals counts the size and number of the objects listed
+// in the data file of a resumed session, which are those its resume
+// copies, and saves them in the session header.
+func recomputeCopyTotals(session *sessionV8) (totalBytes, totalObjects int64, err *probe.Error) {
+	urlScanner := bufio.NewScanner(session.NewDataReader())
+	for urlScanner.Scan() {
+		var cpURLs URLs
+		// Reported when read to be copied.
+		if e := json.Unmarshal(urlScanner.Bytes(), &cpURLs); e != nil || cpURLs.SourceContent == nil {
+			continue
+		}
+		totalBytes += cpURLs.SourceContent.Size
+		totalObjects++
+	}
+	if e := urlScanner.Err(); e != nil {
+		return 0, 0, probe.NewError(e)
+	}
+
+	session.Header.TotalBytes = totalBytes
+	session.Header.TotalObjects = totalObjects
+	return totalBytes, totalObjects, session.Save()
+}
+
+// doPrepareCopyURLs scans the source URL and prepares a list of objects for copying.
+func doPrepareCopyURLs(session *sessionV8, excludes *excludeFilter, cancelCopy context.CancelFunc) (totalBytes, totalObjects int64, prepareErr error) {
 	// Create a session data file to store the processed URLs.
 	dataFP := session.NewDataWriter()
 
@@ -247,7 +726,7 @@ func doPrepareCopyURLs(session *sessionV8, cancelCopy context.CancelFunc) (total
 		scanBar = scanBarFactory()
 	}
 
-	URLsCh := prepareCopyURLs(sourceURLs, targetURL, isRecursive, encKeyDB, olderThan, newerThan)
+	URLsCh := prepareSessionCopyURLs(session, excludes)
 	done := false
 	for !done {
 		select {
@@ -266,6 +745,8 @@ func doPrepareCopyURLs(session *sessionV8, cancelCopy context.CancelFunc) (total
 				} else {
 					errorIf(cpURLs.Error.Trace(), "Unable to prepare URL for copying.")
 				}
+				globalErrorReport.add("", "list", 1, cpURLs.Error)
+				prepareErr = exitStatus(globalErrorExitStatus)
 				break
 			}
 
@@ -299,12 +780,148 @@ func doPrepareCopyURLs(session *sessionV8, cancelCopy context.CancelFunc) (total
 	return
 }
 
-func doCopySession(cli *cli.Context, session *sessionV8, encKeyDB map[string][]prefixSSEPair) error {
-	ctx, cancelCopy := context.WithCancel(globalContext)
+// doStreamCopyURLs - lists the sources of a session while they are copied,
+// each URL is appended to the session data file and sent to cpURLsCh as
+// soon as it is listed. Copying starts right away and the progress bar
+// total grows with the listing, which suits large or slow to list
+// sources. Listing everything first with doPrepareCopyURLs gives exact
+// totals before the first transfer, which suits sources small enough to
+// be listed quickly. An interrupted listing is started over on resume.
+func doStreamCopyURLs(session *sessionV8, excludes *excludeFilter, spaceCheck *freeSpaceCheck, pg ProgressReader, cpURLsCh chan<- URLs) (prepareErr error) {
+	dataFP := session.NewDataWriter()
+
+	var totalBytes, totalObjects int64
+	var isFull bool
+	for cpURLs := range prepareSessionCopyURLs(session, excludes) {
+		if isFull {
+			continue
+		}
+		if cpURLs.Error != nil {
+			// Print in new line and adjust to top so that we don't print over the ongoing progress bar
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			if strings.Contains(cpURLs.Error.ToGoError().Error(), " is a folder.") {
+				errorIf(cpURLs.Error.Trace(), "Folder cannot be copied. Please use `...` suffix.")
+			} else {
+				errorIf(cpURLs.Error.Trace(), "Unable to prepare URL for copying.")
+			}
+			globalErrorReport.add("", "list", 1, cpURLs.Error)
+			prepareErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		// The listing is drained, its totals are not saved for the
+		// sources to be listed again on resume.
+		if err := spaceCheck.verify(totalBytes + cpURLs.SourceContent.Size); err != nil {
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			errorIf(err, "Unable to copy all sources.")
+			prepareErr = exitStatus(globalErrorExitStatus)
+			isFull = true
+			continue
+		}
+
+		jsonData, e := json.Marshal(cpURLs)
+		if e != nil {
+			session.Delete()
+			fatalIf(probe.NewError(e), "Unable to prepare URL for copying. Error in JSON marshaling.")
+		}
+		// Checkpoints save the session while the data file grows.
+		session.mutex.Lock()
+		dataFP.Write(append(jsonData, '\n'))
+		session.mutex.Unlock()
+
+		totalBytes += cpURLs.SourceContent.Size
+		totalObjects++
+		pg.SetTotal(totalBytes)
+		cpURLsCh <- cpURLs
+	}
+
+	if isFull {
+		return prepareErr
+	}
+	session.mutex.Lock()
+	session.Header.TotalBytes = totalBytes
+	session.Header.TotalObjects = totalObjects
+	session.mutex.Unlock()
+	return prepareErr
+}
+
+// errCopyNotStarted - the copy of an object was not started before the
+// session was interrupted.
+var errCopyNotStarted = errors.New("copy not started before the interruption")
+
+// drainCopyStatus waits for the objects being copied when a session is
+// interrupted and records them as copied, objects not started yet are
+// left for the resume. Failed objects are not tried again and a second
+// interrupt exits right away, see trapSignals.
+func drainCopyStatus(session *sessionV8, watermark *copyWatermark, statusCh <-chan URLs) {
+	if !globalQuiet && !globalJSON {
+		console.Errorln("Interrupted, finishing the objects being copied. Interrupt again to exit now.")
+	}
+	for cpURLs := range statusCh {
+		if cpURLs.Error != nil && cpURLs.Error.ToGoError() == errCopyNotStarted {
+			continue
+		}
+		if cpURLs.Error != nil {
+			errorIf(cpURLs.Error.Trace(cpURLs.SourceContent.URL.String()),
+				"Failed to copy `%s`.", cpURLs.SourceContent.URL.String())
+			globalErrorReport.add(cpURLs.SourceContent.URL.String(),
+				cpURLs.operation, cpURLs.attempts, cpURLs.Error)
+		}
+		if lastCopied, ok := watermark.complete(cpURLs.seq, cpURLs.SourceContent.URL.String()); ok {
+			session.mutex.Lock()
+			session.Header.LastCopied = lastCopied
+			session.mutex.Unlock()
+		}
+	}
+}
+
+// readSessionCopyURLs - sends the URLs of the session data file to
+// cpURLsCh, closing it when done.
+func readSessionCopyURLs(session *sessionV8, cpURLsCh chan<- URLs) {
+	// Prepare URL scanner from session data file.
+	urlScanner := bufio.NewScanner(session.NewDataReader())
+	for {
+		if !urlScanner.Scan() || urlScanner.Err() != nil {
+			close(cpURLsCh)
+			break
+		}
+
+		var cpURLs URLs
+		if e := json.Unmarshal([]byte(urlScanner.Text()), &cpURLs); e != nil {
+			errorIf(probe.NewError(e), "Unable to unmarshal %s", urlScanner.Text())
+			continue
+		}
+
+		cpURLsCh <- cpURLs
+	}
+}
+
+func doCopySession(cli *cli.Context, session *sessionV8, args []string, encKeyDB map[string][]prefixSSEPair) error {
+	// Objects being copied by a session finish when interrupted, so
+	// that it is saved past them, see drainCopyStatus(). Their retries
+	// still stop on the interrupt, see waitRetry().
+	parentCtx := globalContext
+	if session != nil {
+		parentCtx = context.Background()
+	}
+	ctx, cancelCopy := context.WithCancel(parentCtx)
 	defer cancelCopy()
 
-	var isCopied func(string) bool
+	var marker *resumeMarker
 	var totalObjects, totalBytes int64
+	var checkpoint *sessionCheckpoint
+
+	// Set if any source could not be prepared for copying.
+	var prepareErr error
+
+	// Only counts the sources listed by this run.
+	excludes := newExcludeFilter(cli.StringSlice("exclude"))
+	if session != nil {
+		excludes = sessionExcludeFilter(session)
+	}
 
 	var cpURLsCh = make(chan URLs, 10000)
 
@@ -318,51 +935,71 @@ func doCopySession(cli *cli.Context, session *sessionV8, encKeyDB map[string][]p
 		pg = newAccounter(totalBytes)
 	}
 
-	if session != nil {
-		// isCopied returns true if an object has been already copied
-		// or not. This is useful when we resume from a session.
-		isCopied = isLastFactory(session.Header.LastCopied)
-
-		if !session.HasData() {
-			totalBytes, totalObjects = doPrepareCopyURLs(session, cancelCopy)
-		} else {
-			totalBytes, totalObjects = session.Header.TotalBytes, session.Header.TotalObjects
+	// Sources listed from scratch are verified to fit a local target,
+	// not those of a session which copied some of them already.
+	var spaceCheck *freeSpaceCheck
+	if session == nil || !session.HasData() {
+		commandArgs := args
+		if session != nil {
+			commandArgs = session.Header.CommandArgs
 		}
+		var err *probe.Error
+		spaceCheck, err = newFreeSpaceCheck(cli, commandArgs[len(commandArgs)-1])
+		fatalIf(err, "Unable to start copying.")
+	}
 
-		pg.SetTotal(totalBytes)
-
-		go func() {
-			// Prepare URL scanner from session data file.
-			urlScanner := bufio.NewScanner(session.NewDataReader())
-			for {
-				if !urlScanner.Scan() || urlScanner.Err() != nil {
-					close(cpURLsCh)
-					break
-				}
-
-				var cpURLs URLs
-				if e := json.Unmarshal([]byte(urlScanner.Text()), &cpURLs); e != nil {
-					errorIf(probe.NewError(e), "Unable to unmarshal %s", urlScanner.Text())
-					continue
+	if session != nil {
+		// marker tells if an object has been already copied or not.
+		// This is useful when we resume from a session.
+		marker = newResumeMarker(session.Header.LastCopied)
+
+		var err *probe.Error
+		checkpoint, err = newSessionCheckpoint(session.Header.CommandStringFlags["checkpoint-interval"])
+		fatalIf(err, "Unable to parse checkpoint interval.")
+
+		// Totals of a streamed session are only saved once it is listed.
+		isStream := session.Header.CommandBoolFlags["stream"]
+		if isStream && (!session.HasData() || session.isRelisted() || cli.Bool("repair")) {
+			session.Header.TotalBytes, session.Header.TotalObjects = 0, 0
+			go func() {
+				prepareErr = doStreamCopyURLs(session, excludes, spaceCheck, pg, cpURLsCh)
+				close(cpURLsCh)
+			}()
+		} else {
+			if !session.HasData() || cli.Bool("repair") {
+				totalBytes, totalObjects, prepareErr = doPrepareCopyURLs(session, excludes, cancelCopy)
+				if err = spaceCheck.verify(totalBytes); err != nil {
+					session.Delete()
+					fatalIf(err, "Unable to start copying.")
 				}
-
-				cpURLsCh <- cpURLs
+			} else if cli.Bool("recompute-totals") {
+				var err *probe.Error
+				totalBytes, totalObjects, err = recomputeCopyTotals(session)
+				fatalIf(err.Trace(session.SessionID), "Unable to count the objects of session.")
+			} else {
+				totalBytes, totalObjects = session.Header.TotalBytes, session.Header.TotalObjects
 			}
 
-		}()
+			pg.SetTotal(totalBytes)
+
+			go readSessionCopyURLs(session, cpURLsCh)
+		}
 	} else {
-		sourceURLs := cli.Args()[:len(cli.Args())-1]
-		targetURL := cli.Args()[len(cli.Args())-1] // Last one is target
+		sourceURLs := args[:len(args)-1]
+		targetURL := args[len(args)-1] // Last one is target
 
 		// Access recursive flag inside the session header.
 		isRecursive := cli.Bool("recursive")
+		isAllowEmpty := cli.Bool("allow-empty")
 		olderThan := cli.String("older-than")
 		newerThan := cli.String("newer-than")
+		listWorkers := cli.Int("list-workers")
+		slashConflict := cli.String("slash-conflict")
 
 		go func() {
 			totalBytes := int64(0)
-			for cpURLs := range prepareCopyURLs(sourceURLs, targetURL, isRecursive,
-				encKeyDB, olderThan, newerThan) {
+			for cpURLs := range prepareCopyURLs(sourceURLs, targetURL, isRecursive, isAllowEmpty,
+				encKeyDB, olderThan, newerThan, listWorkers, slashConflict, excludes) {
 				if cpURLs.Error != nil {
 					// Print in new line and adjust to top so that we
 					// don't print over the ongoing scan bar
@@ -377,21 +1014,146 @@ func doCopySession(cli *cli.Context, session *sessionV8, encKeyDB map[string][]p
 						errorIf(cpURLs.Error.Trace(),
 							"Unable to start copying.")
 					}
+					globalErrorReport.add("", "list", 1, cpURLs.Error)
+					// Read once cpURLsCh is closed and drained.
+					prepareErr = exitStatus(globalErrorExitStatus)
 					break
-				} else {
-					totalBytes += cpURLs.SourceContent.Size
-					pg.SetTotal(totalBytes)
 				}
+				totalBytes += cpURLs.SourceContent.Size
+				if err := spaceCheck.verify(totalBytes); err != nil {
+					if !globalQuiet && !globalJSON {
+						console.Eraseline()
+					}
+					errorIf(err, "Unable to copy all sources.")
+					prepareErr = exitStatus(globalErrorExitStatus)
+					break
+				}
+				pg.SetTotal(totalBytes)
 				cpURLsCh <- cpURLs
 			}
 			close(cpURLsCh)
 		}()
 	}
 
+	// Resumed sessions keep the headers they were started with.
+	var userMetaMap map[string]string
+	if attr := cli.String("attr"); attr != "" {
+		userMetaMap, _ = getMetaDataEntry(attr)
+	}
+	contentDisposition := cli.String("content-disposition")
+	contentType := cli.String("content-type")
+	cacheControl := cli.String("cache-control")
+	charset := cli.String("charset")
+	ifMatch := cli.String("if-match")
+	onSuccess, onFailure, isStrictHooks := cli.String("on-success"), cli.String("on-failure"), cli.Bool("strict-hooks")
+	isCompressAuto := cli.Bool("compress-auto")
+	isAutoSSE := cli.Bool("auto-sse")
+	isAppend := cli.Bool("append")
+	isPreserveMtime := cli.Bool("preserve-mtime")
+	isMove := cli.Command.Name == "mv"
+	storageClass := cli.String("storage-class")
+	isPreserve := cli.Bool("preserve")
+	isVerbose := cli.Bool("verbose")
+	parallelWorkers := cli.Int("parallel")
+	maxKeyLength := cli.Int("max-key-length")
+	isSkipLongKeys := cli.Bool("skip-long-keys")
+	// Left empty when not given, for the target host default to apply.
+	multipartThreshold := ""
+	if cli.IsSet("multipart-threshold") {
+		multipartThreshold = cli.String("multipart-threshold")
+	}
+	protectWindow := cli.String("protect-window")
+	checksum := cli.String("checksum")
+	isNoServerSide := cli.Bool("no-server-side")
+	retries := cli.Int("retry")
+	retryDelay := cli.String("retry-delay")
+	if session != nil {
+		userMetaMap = session.Header.UserMetaData
+		contentDisposition = session.Header.CommandStringFlags["content-disposition"]
+		contentType = session.Header.CommandStringFlags["content-type"]
+		cacheControl = session.Header.CommandStringFlags["cache-control"]
+		charset = session.Header.CommandStringFlags["charset"]
+		ifMatch = session.Header.CommandStringFlags["if-match"]
+		onSuccess = session.Header.CommandStringFlags["on-success"]
+		onFailure = session.Header.CommandStringFlags["on-failure"]
+		isStrictHooks = session.Header.CommandBoolFlags["strict-hooks"]
+		maxKeyLength = defaultMaxKeyLength
+		if value, ok := session.Header.CommandIntFlags["max-key-length"]; ok {
+			maxKeyLength = value
+		}
+		isSkipLongKeys = session.Header.CommandBoolFlags["skip-long-keys"]
+		isCompressAuto = session.Header.CommandBoolFlags["compress-auto"]
+		isAutoSSE = session.Header.CommandBoolFlags["auto-sse"]
+		isAppend = session.Header.CommandBoolFlags["append"]
+		isPreserveMtime = session.Header.CommandBoolFlags["preserve-mtime"]
+		isVerbose = session.Header.CommandBoolFlags["verbose"]
+		// Sessions are also resumed by 'mc session resume'.
+		isMove = session.Header.CommandType == "mv"
+		storageClass = session.Header.CommandStringFlags["storage-class"]
+		isPreserve = session.Header.CommandBoolFlags["preserve"]
+		parallelWorkers = session.Header.CommandIntFlags["parallel"]
+		// Sessions started before the option, or without it, use the
+		// default of the target host.
+		multipartThreshold = session.Header.CommandStringFlags["multipart-threshold"]
+		protectWindow = session.Header.CommandStringFlags["protect-window"]
+		checksum = session.Header.CommandStringFlags["checksum"]
+		isNoServerSide = session.Header.CommandBoolFlags["no-server-side"]
+		// Sessions started before the options did not retry.
+		retries = session.Header.CommandIntFlags["retry"]
+		retryDelay = defaultCopyRetryDelay
+		if value, ok := session.Header.CommandStringFlags["retry-delay"]; ok {
+			retryDelay = value
+		}
+	}
+	retryDuration, e := time.ParseDuration(retryDelay)
+	fatalIf(probe.NewError(e).Trace(retryDelay), "Unable to parse --retry-delay.")
+	multipartSize, err := parseMultipartThreshold(multipartThreshold)
+	fatalIf(err.Trace(multipartThreshold), "Unable to parse --multipart-threshold.")
+
+	commandArgs := args
+	if session != nil {
+		commandArgs = session.Header.CommandArgs
+	}
+	// Defaults of the target host apply to the flags not given.
+	if hostCfg := hostConfigOf(commandArgs[len(commandArgs)-1]); hostCfg != nil {
+		if multipartSize == 0 {
+			multipartSize, err = partSizeThreshold(hostCfg.PartSize)
+			fatalIf(err.Trace(hostCfg.PartSize), "Unable to parse the part size of the target host.")
+		}
+		if parallelWorkers == 0 {
+			parallelWorkers = hostCfg.Parallel
+		}
+	}
+	hooks, err := newCopyHooks(onSuccess, onFailure, isStrictHooks)
+	fatalIf(err, "Unable to parse --on-success or --on-failure.")
+	protectDuration, err := parseProtectWindow(protectWindow)
+	fatalIf(err.Trace(protectWindow), "Unable to parse --protect-window.")
+
+	limitUpload, limitDownload := cli.String("limit-upload"), cli.String("limit-download")
+	if session != nil {
+		limitUpload = session.Header.CommandStringFlags["limit-upload"]
+		limitDownload = session.Header.CommandStringFlags["limit-download"]
+	}
+	fatalIf(setBandwidthLimits(limitUpload, limitDownload), "Unable to limit bandwidth.")
+
+	targetRoot := copyTargetRoot(commandArgs[len(commandArgs)-1])
+	longKeys := &keyList{}
+	// Objects copied before the interruption checked by 'mc session
+	// resume --verify-done'.
+	isVerifyDone := session != nil && cli.Bool("verify-done")
+	var verified verifyDoneMessage
+
 	var quitCh = make(chan struct{})
 	var statusCh = make(chan URLs)
 
-	parallel, queueCh := newParallelManager(statusCh)
+	parallel, queueCh := newParallelManager(statusCh, false, parallelWorkers)
+
+	// Copies complete out of order, only URLs copied along with all
+	// those listed before them are saved as the last copied.
+	var watermark *copyWatermark
+	if session != nil {
+		watermark = newCopyWatermark()
+	}
 
 	go func() {
 		gracefulStop := func() {
@@ -400,15 +1162,63 @@ func doCopySession(cli *cli.Context, session *sessionV8, encKeyDB map[string][]p
 			close(statusCh)
 		}
 
+		urlsCh := cpURLsCh
+		// Size of the objects skipped as copied before the interruption,
+		// counted once skipped. Objects copied again by --verify-done are
+		// not copied once more if the session data file is read again.
+		var skippedBytes int64
+		var doneCopies sync.WaitGroup
+		var requeued sync.Map
+		skipCopy := func(cpURLs URLs) URLs {
+			atomic.AddInt64(&skippedBytes, cpURLs.SourceContent.Size)
+			return doCopyFake(cpURLs, pg)
+		}
+		// queueSkip queues an object which is not copied. Those skipped
+		// while looking for the last copied object are skipped again
+		// once the session data file is read again.
+		queueSkip := func(cpURLs URLs) {
+			isSeeking := marker != nil
+			if isSeeking {
+				doneCopies.Add(1)
+			}
+			queueCh <- func() URLs {
+				if isSeeking {
+					defer doneCopies.Done()
+				}
+				return skipCopy(cpURLs)
+			}
+		}
 		for {
+			// Stop queuing once interrupted, even with URLs ready.
+			select {
+			case <-quitCh:
+				gracefulStop()
+				return
+			default:
+			}
 			select {
 			case <-quitCh:
 				gracefulStop()
 				return
-			case cpURLs, ok := <-cpURLsCh:
+			case cpURLs, ok := <-urlsCh:
 				if !ok {
-					gracefulStop()
-					return
+					if marker == nil || !marker.missing() {
+						gracefulStop()
+						return
+					}
+					// Everything was skipped while looking for the last
+					// copied object, copy the session data file again.
+					errorIf(probe.NewError(LastCopiedMissing{marker.lastURL}),
+						"Unable to resume where the session stopped, copying all objects again.")
+					doneCopies.Wait()
+					marker = nil
+					if progressReader, ok := pg.(*progressBar); ok {
+						progressReader.ProgressBar.Add64(-atomic.LoadInt64(&skippedBytes))
+					}
+					longKeys.reset()
+					urlsCh = make(chan URLs, 10000)
+					go readSessionCopyURLs(session, urlsCh)
+					continue
 				}
 
 				// Save total count.
@@ -423,20 +1233,53 @@ func doCopySession(cli *cli.Context, session *sessionV8, encKeyDB map[string][]p
 				// Initialize target user metadata.
 				cpURLs.TargetContent.UserMetadata = make(map[string]string)
 
+				if contentDisposition != "" {
+					cpURLs.TargetContent.Metadata["Content-Disposition"] = contentDisposition
+				}
+				if contentType != "" {
+					cpURLs.TargetContent.Metadata["Content-Type"] = contentType
+				}
+				if cacheControl != "" {
+					cpURLs.TargetContent.Metadata["Cache-Control"] = cacheControl
+				}
+				if charset != "" {
+					cpURLs.TargetContent.Metadata[charsetMetaKey] = charset
+				}
+				if ifMatch != "" {
+					cpURLs.TargetContent.Metadata[ifMatchMetaKey] = ifMatch
+				}
+				if isCompressAuto {
+					cpURLs.TargetContent.Metadata[compressMetaKey] = "auto"
+				}
+				if isAutoSSE {
+					cpURLs.TargetContent.Metadata[autoSSEMetaKey] = "true"
+				}
+				if isAppend {
+					cpURLs.TargetContent.Metadata[appendMetaKey] = appendSourceID(cpURLs.SourceContent)
+				}
+				if checksum != "" {
+					cpURLs.TargetContent.Metadata[checksumMetaKey] = checksum
+				}
+				if isNoServerSide {
+					cpURLs.TargetContent.Metadata[noServerSideMetaKey] = "true"
+				}
+				cpURLs.retries = retries
+				cpURLs.retryDelay = retryDuration
+				if multipartSize > 0 {
+					cpURLs.TargetContent.Metadata[multipartThresholdMetaKey] = strconv.FormatInt(multipartSize, 10)
+				}
+
 				// Check and handle storage class if passed in command line args
-				if storageClass := cli.String("storage-class"); storageClass != "" {
+				if storageClass != "" {
 					cpURLs.TargetContent.Metadata["X-Amz-Storage-Class"] = storageClass
 				}
 
-				if cli.String("attr") != "" {
-					userMetaMap, _ := getMetaDataEntry(cli.String("attr"))
-					for metaDataKey, metaDataVal := range userMetaMap {
-						cpURLs.TargetContent.UserMetadata[metaDataKey] = metaDataVal
-					}
+				for metaDataKey, metaDataVal := range userMetaMap {
+					cpURLs.TargetContent.UserMetadata[metaDataKey] = metaDataVal
 				}
 
 				// If one needs to store the file system information by passing -a flag
-				if preserve := cli.Bool("preserve"); preserve {
+				if isPreserve {
 					attrValue, pErr := getFileAttrMeta(cpURLs, encKeyDB)
 					if pErr != nil {
 						errorIf(pErr, "Unable to fetch file meta info for %s", cpURLs.SourceAlias)
@@ -447,14 +1290,133 @@ func doCopySession(cli *cli.Context, session *sessionV8, encKeyDB map[string][]p
 						cpURLs.TargetContent.Metadata["mc-attrs"] = attrValue
 					}
 				}
+
+				// Keep source modification time, stored as metadata on object storage.
+				if isPreserveMtime {
+					cpURLs.TargetContent.Metadata[mtimeMetaKey] = getContentModTime(cpURLs.SourceContent).Format(time.RFC3339Nano)
+				}
+
+				if watermark != nil {
+					cpURLs.seq = watermark.queue()
+				}
+
+				// Copying onto itself is only meaningful to rewrite metadata.
+				// Moving onto itself would remove the only copy.
+				if isSameSourceTarget(cpURLs) && (isMove || !isInPlaceMetadataUpdate(cpURLs)) {
+					if !globalQuiet && !globalJSON {
+						console.Eraseline()
+					}
+					sourceURL := cpURLs.SourceContent.URL.String()
+					warningIf(errSameSourceTarget(sourceURL), "Skipping `%s`.", sourceURL)
+					queueSkip(cpURLs)
+					continue
+				}
+
+				if cpURLs.Error == nil {
+					if err := checkKeyLength(cpURLs, maxKeyLength); err != nil {
+						longKeys.add(cpURLs.TargetContent.URL.Path)
+						if isSkipLongKeys {
+							if !globalQuiet && !globalJSON {
+								console.Eraseline()
+							}
+							warningIf(err.Trace(cpURLs.TargetContent.URL.Path), "Skipping `%s`.", cpURLs.SourceContent.URL.String())
+							queueSkip(cpURLs)
+							continue
+						}
+						cpURLs.Error = err.Trace(cpURLs.TargetContent.URL.Path)
+					}
+				}
+
+				if targetRoot != "" && cpURLs.Error == nil {
+					if err := checkTargetSymlinks(targetRoot, cpURLs.TargetContent.URL.Path); err != nil {
+						cpURLs.Error = err.Trace(cpURLs.TargetContent.URL.Path)
+					}
+				}
+
 				// Verify if previously copied, notify progress bar.
-				if isCopied != nil && isCopied(cpURLs.SourceContent.URL.String()) {
+				isDone := marker != nil && marker.isDone(cpURLs.SourceContent.URL.String())
+				if isDone {
+					doneCopies.Add(1)
+				} else if _, ok := requeued.Load(cpURLs.SourceContent.URL.String()); ok {
 					queueCh <- func() URLs {
-						return doCopyFake(cpURLs, pg)
+						return cpURLs
+					}
+					continue
+				}
+				// Moves interrupted before removing their source finish it.
+				copyDone := func(cpURLs URLs) URLs {
+					cpURLs = skipCopy(cpURLs)
+					if isMove {
+						cpURLs = finishMove(cpURLs, session != nil, encKeyDB)
+					}
+					return cpURLs
+				}
+				if isDone && (!isVerifyDone || cpURLs.Error != nil) {
+					queueCh <- func() URLs {
+						defer doneCopies.Done()
+						return copyDone(cpURLs)
 					}
 				} else {
+					copyCtx := ctx
+					if session != nil {
+						copyCtx = session.withUploadParts(ctx, cpURLs, checkpoint)
+					}
 					queueCh <- func() URLs {
-						return doCopy(ctx, cpURLs, pg, encKeyDB)
+						if isDone {
+							defer doneCopies.Done()
+						}
+						// Handed over while interrupted, left for the resume.
+						if session != nil && globalContext.Err() != nil {
+							cpURLs.Error = probe.NewError(errCopyNotStarted)
+							return cpURLs
+						}
+						if isDone {
+							atomic.AddInt64(&verified.Verified, 1)
+							mismatch, err := verifyCopied(cpURLs, encKeyDB)
+							if mismatch == nil && err == nil {
+								return copyDone(cpURLs)
+							}
+							if !globalQuiet && !globalJSON {
+								console.Eraseline()
+							}
+							if mismatch != nil {
+								warningIf(mismatch, "Copying `%s` again.", cpURLs.SourceContent.URL.String())
+							} else {
+								errorIf(err, "Unable to verify the copy of `%s`, copying it again.", cpURLs.SourceContent.URL.String())
+							}
+							requeued.Store(cpURLs.SourceContent.URL.String(), true)
+							atomic.AddInt64(&verified.Requeued, 1)
+						}
+						if protectDuration > 0 && cpURLs.Error == nil {
+							protected, err := checkProtectWindow(cpURLs, protectDuration, encKeyDB)
+							if err != nil {
+								cpURLs.Error = err
+								return cpURLs
+							}
+							if protected != nil {
+								if !globalQuiet && !globalJSON {
+									console.Eraseline()
+								}
+								warningIf(protected, "Skipping `%s`.", cpURLs.SourceContent.URL.String())
+								return doCopyFake(cpURLs, pg)
+							}
+						}
+						switch {
+						case isMove && isLocalMove(cpURLs):
+							// Renamed, the source is gone whatever the hook does.
+							cpURLs = renameMove(copyCtx, cpURLs, pg, session != nil, encKeyDB)
+							cpURLs = hooks.runOnFailure(hooks.runOnSuccess(cpURLs, true))
+						case isMove:
+							// A strict hook which fails keeps the source.
+							cpURLs = hooks.runOnSuccess(doCopy(copyCtx, cpURLs, pg, encKeyDB), false)
+							cpURLs = hooks.runOnFailure(finishMove(cpURLs, session != nil, encKeyDB))
+						default:
+							cpURLs = hooks.run(doCopy(copyCtx, cpURLs, pg, encKeyDB))
+						}
+						if isVerbose {
+							printCompressRatio(cpURLs)
+						}
+						return cpURLs
 					}
 				}
 			}
@@ -468,23 +1430,39 @@ loop:
 		select {
 		case <-globalContext.Done():
 			close(quitCh)
-			cancelCopy()
 			// Receive interrupt notification.
 			if !globalQuiet && !globalJSON {
 				console.Eraseline()
 			}
 			if session != nil {
-				session.CloseAndDie()
+				drainCopyStatus(session, watermark, statusCh)
+				globalErrorReport.write()
+				return session.CloseAndDie()
 			}
+			cancelCopy()
 			break loop
 		case cpURLs, ok := <-statusCh:
 			// Status channel is closed, we should return.
 			if !ok {
+				if retErr == nil {
+					retErr = prepareErr
+				}
 				break loop
 			}
+			if session != nil {
+				// Failed URLs are passed over like before, only
+				// critical errors stop the session.
+				if lastCopied, ok := watermark.complete(cpURLs.seq, cpURLs.SourceContent.URL.String()); ok {
+					// Uploads in parts save the session as they go.
+					session.mutex.Lock()
+					session.Header.LastCopied = lastCopied
+					session.mutex.Unlock()
+				}
+			}
 			if cpURLs.Error == nil {
-				if session != nil {
-					session.Header.LastCopied = cpURLs.SourceContent.URL.String()
+				// Session is always saved on interrupt or failure via
+				// Close(), so only periodic checkpoints are throttled.
+				if session != nil && checkpoint.due() {
 					session.Save()
 				}
 			} else {
@@ -497,8 +1475,13 @@ loop:
 				if !globalQuiet && !globalJSON {
 					console.Eraseline()
 				}
-				errorIf(cpURLs.Error.Trace(cpURLs.SourceContent.URL.String()),
-					fmt.Sprintf("Failed to copy `%s`.", cpURLs.SourceContent.URL.String()))
+				failedMsg := fmt.Sprintf("Failed to copy `%s`.", cpURLs.SourceContent.URL.String())
+				if cpURLs.attempts > 1 {
+					failedMsg = fmt.Sprintf("Failed to copy `%s` after %d attempts.", cpURLs.SourceContent.URL.String(), cpURLs.attempts)
+				}
+				errorIf(cpURLs.Error.Trace(cpURLs.SourceContent.URL.String()), failedMsg)
+				globalErrorReport.add(cpURLs.SourceContent.URL.String(),
+					cpURLs.operation, cpURLs.attempts, cpURLs.Error)
 				if isErrIgnored(cpURLs.Error) {
 					continue loop
 				}
@@ -507,7 +1490,10 @@ loop:
 					// For critical errors we should exit. Session
 					// can be resumed after the user figures out
 					// the  problem.
-					session.copyCloseAndDie(session.Header.CommandBoolFlags["session"])
+					globalErrorReport.write()
+					if e := session.copyCloseAndDie(session.Header.CommandBoolFlags["session"]); e != nil {
+						return e
+					}
 				}
 			}
 		}
@@ -523,22 +1509,311 @@ loop:
 		}
 	}
 
+	if keys := longKeys.list(); len(keys) > 0 {
+		if isSkipLongKeys {
+			warningIf(errKeysTooLong(keys).Trace(), "Objects with names too long for the target were skipped.")
+		} else {
+			errorIf(errKeysTooLong(keys).Trace(), "Objects with names too long for the target were not copied.")
+		}
+	}
+	excludes.printExcluded()
+	if isVerifyDone {
+		printMsg(verified)
+	}
+
 	return retErr
 }
 
+// parseProtectWindow parses --protect-window, an empty value protects
+// nothing.
+func parseProtectWindow(value string) (time.Duration, *probe.Error) {
+	if value == "" {
+		return 0, nil
+	}
+	window, e := time.ParseDuration(value)
+	if e != nil {
+		return 0, probe.NewError(e)
+	}
+	if window <= 0 {
+		return 0, errInvalidArgument()
+	}
+	return window, nil
+}
+
+// checkProtectWindow returns an errTargetProtected error as protected
+// when the target of cpURLs exists and was modified less than window
+// ago, and err when its age cannot be told. Missing targets are left to
+// the copy to create.
+func checkProtectWindow(cpURLs URLs, window time.Duration, encKeyDB map[string][]prefixSSEPair) (protected, err *probe.Error) {
+	targetURL := cpURLs.TargetContent.URL.String()
+	clnt, err := newClientFromAlias(cpURLs.TargetAlias, targetURL)
+	if err != nil {
+		return nil, err.Trace(targetURL)
+	}
+	targetPath := filepath.ToSlash(filepath.Join(cpURLs.TargetAlias, cpURLs.TargetContent.URL.Path))
+	st, err := clnt.Stat(false, false, false, getSSE(targetPath, encKeyDB[cpURLs.TargetAlias]))
+	if err != nil {
+		if isErrSourceMissing(err) {
+			return nil, nil
+		}
+		return nil, err.Trace(targetURL)
+	}
+	if age := UTCNow().Sub(st.Time); age < window {
+		return errTargetProtected(targetPath, age, window).Trace(targetURL), nil
+	}
+	return nil, nil
+}
+
+// verifyCopied returns an errCopiedMismatch error as mismatch when the
+// target of cpURLs, recorded as copied by a session, is missing, of
+// another size than the source or does not match the checksum stored
+// with it, and err when it cannot be verified. Targets compressed by
+// --compress are decompressed to compare their size.
+func verifyCopied(cpURLs URLs, encKeyDB map[string][]prefixSSEPair) (mismatch, err *probe.Error) {
+	targetURL := cpURLs.TargetContent.URL.String()
+	clnt, err := newClientFromAlias(cpURLs.TargetAlias, targetURL)
+	if err != nil {
+		return nil, err.Trace(targetURL)
+	}
+	targetPath := filepath.ToSlash(filepath.Join(cpURLs.TargetAlias, cpURLs.TargetContent.URL.Path))
+	sse := getSSE(targetPath, encKeyDB[cpURLs.TargetAlias])
+	st, err := clnt.Stat(false, true, false, sse)
+	if err != nil {
+		if isErrSourceMissing(err) {
+			return errCopiedMismatch(targetPath, "is missing").Trace(targetURL), nil
+		}
+		return nil, err.Trace(targetURL)
+	}
+	contentEncoding := st.Metadata["Content-Encoding"]
+	isCompressed := contentEncoding != "" && cpURLs.SourceContent.Metadata["Content-Encoding"] != contentEncoding
+	sourceSize := cpURLs.SourceContent.Size
+	if !isCompressed && st.Size != sourceSize {
+		return errCopiedMismatch(targetPath, fmt.Sprintf("has %d bytes instead of %d", st.Size, sourceSize)).Trace(targetURL), nil
+	}
+	algorithm, sum := storedChecksum(st.Metadata)
+	if algorithm == "" && !isCompressed {
+		return nil, nil
+	}
+	reader, err := clnt.Get(sse)
+	if err != nil {
+		return nil, err.Trace(targetURL)
+	}
+	defer reader.Close()
+	if clnt.GetURL().Type == objectStorage {
+		reader = globalDownloadLimiter.wrap(reader)
+	}
+	var decoded io.Reader = reader
+	if isCompressed {
+		var e error
+		if decoded, isCompressed, e = decodeContentEncoding(reader, contentEncoding); e != nil {
+			if isGzipDecodeErr(e) {
+				return errCopiedMismatch(targetPath, "is not stored with its "+contentEncoding+" encoding").Trace(targetURL), nil
+			}
+			return nil, probe.NewError(e).Trace(targetURL)
+		}
+	}
+	var hasher hash.Hash
+	var w io.Writer = ioutil.Discard
+	if algorithm != "" {
+		hasher = newChecksumHash(algorithm)
+		w = hasher
+	}
+	n, e := io.Copy(w, decoded)
+	if e != nil {
+		if isCompressed && isGzipDecodeErr(e) {
+			return errCopiedMismatch(targetPath, "is not stored with its "+contentEncoding+" encoding").Trace(targetURL), nil
+		}
+		return nil, probe.NewError(e).Trace(targetURL)
+	}
+	if isCompressed && n != sourceSize {
+		return errCopiedMismatch(targetPath, fmt.Sprintf("has %d bytes once decompressed instead of %d", n, sourceSize)).Trace(targetURL), nil
+	}
+	if hasher != nil && hex.EncodeToString(hasher.Sum(nil)) != sum {
+		return errCopiedMismatch(targetPath, "no longer matches its "+algorithm+" checksum").Trace(targetURL), nil
+	}
+	return nil, nil
+}
+
+// parseMultipartThreshold returns the size below which objects are
+// uploaded with a single PUT, see putPartSize. An empty value leaves
+// the choice to the object storage client.
+func parseMultipartThreshold(value string) (int64, *probe.Error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, e := humanize.ParseBytes(value)
+	if e != nil {
+		return 0, probe.NewError(e)
+	}
+	if size > maxSinglePutSize {
+		return 0, probe.NewError(errors.New("a single PUT uploads at most 5GiB"))
+	}
+	return int64(size), nil
+}
+
+// partSizeThreshold returns the multipart threshold uploading in parts
+// of partSize, zero for an empty value.
+func partSizeThreshold(partSize string) (int64, *probe.Error) {
+	if partSize == "" {
+		return 0, nil
+	}
+	size, e := humanize.ParseBytes(partSize)
+	if e != nil {
+		return 0, probe.NewError(e)
+	}
+	if size < minPartSize {
+		return 0, probe.NewError(errors.New("parts are at least 5MiB"))
+	}
+	if 2*size > maxSinglePutSize {
+		return 0, probe.NewError(errors.New("parts are at most half of the 5GiB of a single PUT"))
+	}
+	return int64(2 * size), nil
+}
+
+// defaultMaxKeyLength - longest object name accepted by Amazon S3.
+const defaultMaxKeyLength = 1024
+
+// Objects failing with a transient error are copied up to three more
+// times, one second after the first failure and twice as long after
+// every other one, see waitRetry.
+const (
+	defaultCopyRetries    = 3
+	defaultCopyRetryDelay = "1s"
+)
+
+// checkKeyLength verifies that the target name of cpURLs fits in
+// maxKeyLength bytes, or within the path limits of the OS for local
+// targets.
+func checkKeyLength(cpURLs URLs, maxKeyLength int) *probe.Error {
+	targetURL := cpURLs.TargetContent.URL
+	if targetURL.Type == fileSystem {
+		if len(targetURL.Path) > fsMaxPathLength {
+			return probe.NewError(KeyTooLong{Key: targetURL.Path, Length: len(targetURL.Path), Max: fsMaxPathLength})
+		}
+		for _, name := range strings.Split(filepath.ToSlash(targetURL.Path), "/") {
+			if len(name) > fsMaxNameLength {
+				return probe.NewError(KeyTooLong{Key: name, Length: len(name), Max: fsMaxNameLength})
+			}
+		}
+		return nil
+	}
+	if maxKeyLength <= 0 {
+		return nil
+	}
+	// Object name excludes the bucket.
+	key := strings.TrimPrefix(targetURL.Path, string(targetURL.Separator))
+	if i := strings.Index(key, string(targetURL.Separator)); i >= 0 {
+		key = key[i+1:]
+	}
+	if len(key) > maxKeyLength {
+		return probe.NewError(KeyTooLong{Key: key, Length: len(key), Max: maxKeyLength})
+	}
+	return nil
+}
+
+// keyList - names collected while copying, safe for concurrent use.
+type keyList struct {
+	mutex sync.Mutex
+	keys  []string
+}
+
+func (l *keyList) add(key string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.keys = append(l.keys, key)
+}
+
+func (l *keyList) reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.keys = nil
+}
+
+func (l *keyList) list() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.keys...)
+}
+
 // validate the passed metadataString and populate the map
 func getMetaDataEntry(metadataString string) (map[string]string, *probe.Error) {
+	// Lists separated by ';' keep the commas of their values, such as
+	// those of a Cache-Control header.
+	separator := ","
+	if strings.Contains(metadataString, ";") {
+		separator = ";"
+	}
 	metaDataMap := make(map[string]string)
-	for _, metaData := range strings.Split(metadataString, ";") {
+	for _, metaData := range strings.Split(metadataString, separator) {
+		// Left by a trailing separator.
+		if metaData == "" {
+			continue
+		}
 		metaDataEntry := strings.SplitN(metaData, "=", 2)
 		if len(metaDataEntry) != 2 {
 			return nil, probe.NewError(ErrInvalidMetadata)
 		}
+		if !httpguts.ValidHeaderFieldName(metaDataEntry[0]) || !httpguts.ValidHeaderFieldValue(metaDataEntry[1]) {
+			return nil, probe.NewError(ErrInvalidMetadataHeader).Trace(metaData)
+		}
 		metaDataMap[http.CanonicalHeaderKey(metaDataEntry[0])] = metaDataEntry[1]
 	}
 	return metaDataMap, nil
 }
 
+// freeSpaceCheck compares the size of the sources of a copy, as they
+// are listed, to the free space of its local filesystem target.
+type freeSpaceCheck struct {
+	path string
+	free uint64
+}
+
+// newFreeSpaceCheck returns the free space check of the target of a
+// copy, nil with --no-space-check or when the target is not on a local
+// filesystem reporting its free space.
+func newFreeSpaceCheck(cli *cli.Context, targetURL string) (*freeSpaceCheck, *probe.Error) {
+	if cli.Bool("no-space-check") {
+		return nil, nil
+	}
+	targetAlias, expandedURL, _ := mustExpandAlias(targetURL)
+	if targetAlias != "" || newClientURL(expandedURL).Type != fileSystem {
+		return nil, nil
+	}
+
+	// Target may not exist yet, statfs the closest existing parent.
+	path := filepath.Clean(expandedURL)
+	for {
+		if _, e := os.Stat(path); e == nil {
+			break
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return nil, nil
+		}
+		path = parent
+	}
+
+	info, e := disk.GetInfo(path)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(path)
+	}
+	// Platforms without statfs support report zero, nothing to verify.
+	if info.Total == 0 {
+		return nil, nil
+	}
+	return &freeSpaceCheck{path: path, free: info.Free}, nil
+}
+
+// verify returns an error once the sources listed so far, totalBytes
+// in size, no longer fit the free space. Sources are verified before
+// they are queued, those queued before the error fit.
+func (c *freeSpaceCheck) verify(totalBytes int64) *probe.Error {
+	if c == nil || uint64(totalBytes) <= c.free {
+		return nil
+	}
+	return errInsufficientSpace(c.path, uint64(totalBytes), c.free)
+}
+
 // mainCopy is the entry point for cp command.
 func mainCopy(ctx *cli.Context) error {
 	// Parse encryption keys per command.
@@ -552,11 +1827,29 @@ func mainCopy(ctx *cli.Context) error {
 		fatalIf(err, "Unable to parse attribute %v", ctx.String("attr"))
 	}
 
-	// check 'copy' cli arguments.
-	checkCopySyntax(ctx, encKeyDB)
+	// Replace bucket patterns with the buckets they match.
+	args := []string(ctx.Args())
+	if len(args) >= 2 {
+		sourceURLs, err := expandBucketPatterns(args[:len(args)-1], ctx.Bool("allow-empty"))
+		fatalIf(err, "Unable to expand source bucket patterns.")
+		if len(sourceURLs) == 0 {
+			// Nothing matched and --allow-empty was given.
+			return nil
+		}
+		args = append(sourceURLs, args[len(args)-1])
+	}
 
 	// Additional command speific theme customization.
 	console.SetColor("Copy", color.New(color.FgGreen, color.Bold))
+	console.SetColor("Excluded", color.New(color.FgYellow))
+
+	if globalOffline {
+		checkCopyOffline(ctx, args, encKeyDB)
+		return nil
+	}
+
+	// check 'copy' cli arguments.
+	checkCopySyntax(ctx, args, encKeyDB)
 
 	recursive := ctx.Bool("recursive")
 	olderThan := ctx.String("older-than")
@@ -573,27 +1866,88 @@ func mainCopy(ctx *cli.Context) error {
 	}
 	sse := ctx.String("encrypt")
 
+	defer startTransferMetrics(ctx)()
+
+	if reportPath := ctx.String("error-report"); reportPath != "" {
+		writeReport, err := startErrorReport(ctx.Command.Name, reportPath)
+		fatalIf(err.Trace(reportPath), "Unable to create error report.")
+		defer writeReport()
+	}
+
 	var session *sessionV8
 
+	sseAlgorithm, sseKMSKeyID := ctx.String("sse"), ctx.String("sse-kms-key-id")
 	if ctx.Bool("continue") {
-		sessionID := getHash("cp", ctx.Args())
+		// Moves are resumed by mv, see mvCmd.
+		command := ctx.Command.Name
+		sessionID := getHash(command, ctx.Args())
 		if isSessionExists(sessionID) {
-			session, err = loadSessionV8(sessionID)
-			fatalIf(err.Trace(sessionID), "Unable to load session.")
+			if ctx.Bool("repair") {
+				session, err = repairSessionV8(sessionID)
+			} else {
+				session, err = resumeSessionV8(sessionID)
+			}
+			if err != nil {
+				if _, ok := err.ToGoError().(SessionDataCorrupt); ok {
+					fatalIf(err.Trace(sessionID), "Unable to load session, resume with --repair to list the sources again.")
+				}
+				fatalIf(err.Trace(sessionID), "Unable to load session.")
+			}
+			// Keep encrypting the objects left like the session started.
+			sseAlgorithm = session.Header.CommandStringFlags["sse"]
+			sseKMSKeyID = session.Header.CommandStringFlags["sse-kms-key-id"]
 		} else {
+			expireSessions()
 			session = newSessionV8(sessionID)
-			session.Header.CommandType = "cp"
+			session.Header.CommandType = command
 			session.Header.CommandBoolFlags["recursive"] = recursive
+			session.Header.CommandBoolFlags["allow-empty"] = ctx.Bool("allow-empty")
 			session.Header.CommandStringFlags["older-than"] = olderThan
 			session.Header.CommandStringFlags["newer-than"] = newerThan
 			session.Header.CommandStringFlags["storage-class"] = storageClass
 			session.Header.CommandStringFlags["encrypt-key"] = sseKeys
 			session.Header.CommandStringFlags["encrypt"] = sse
+			session.Header.CommandStringFlags["sse"] = sseAlgorithm
+			session.Header.CommandStringFlags["sse-kms-key-id"] = sseKMSKeyID
 			session.Header.CommandBoolFlags["session"] = ctx.Bool("continue")
+			session.Header.CommandStringFlags["checkpoint-interval"] = ctx.String("checkpoint-interval")
+			session.Header.CommandStringFlags["content-disposition"] = ctx.String("content-disposition")
+			session.Header.CommandStringFlags["content-type"] = ctx.String("content-type")
+			session.Header.CommandStringFlags["cache-control"] = ctx.String("cache-control")
+			session.Header.CommandStringFlags["charset"] = ctx.String("charset")
+			session.Header.CommandStringFlags["if-match"] = ctx.String("if-match")
+			session.Header.CommandStringFlags["on-success"] = ctx.String("on-success")
+			session.Header.CommandStringFlags["on-failure"] = ctx.String("on-failure")
+			session.Header.CommandBoolFlags["strict-hooks"] = ctx.Bool("strict-hooks")
+			session.Header.CommandIntFlags["max-key-length"] = ctx.Int("max-key-length")
+			session.Header.CommandBoolFlags["skip-long-keys"] = ctx.Bool("skip-long-keys")
+			session.Header.CommandBoolFlags["compress-auto"] = ctx.Bool("compress-auto")
+			session.Header.CommandBoolFlags["auto-sse"] = ctx.Bool("auto-sse")
+			session.Header.CommandBoolFlags["verbose"] = ctx.Bool("verbose")
+			session.Header.CommandIntFlags["list-workers"] = ctx.Int("list-workers")
+			session.Header.CommandIntFlags["parallel"] = ctx.Int("parallel")
+			session.Header.CommandStringFlags["slash-conflict"] = ctx.String("slash-conflict")
+			session.Header.CommandStringFlags["exclude"] = strings.Join(ctx.StringSlice("exclude"), "\n")
+			session.Header.CommandStringFlags["protect-window"] = ctx.String("protect-window")
+			session.Header.CommandBoolFlags["append"] = ctx.Bool("append")
+			session.Header.CommandStringFlags["checksum"] = ctx.String("checksum")
+			session.Header.CommandBoolFlags["no-server-side"] = ctx.Bool("no-server-side")
+			session.Header.CommandIntFlags["retry"] = ctx.Int("retry")
+			session.Header.CommandStringFlags["retry-delay"] = ctx.String("retry-delay")
+			if ctx.IsSet("multipart-threshold") {
+				session.Header.CommandStringFlags["multipart-threshold"] = ctx.String("multipart-threshold")
+			}
+			session.Header.CommandBoolFlags["stream"] = ctx.Bool("stream")
+			session.Header.CommandStringFlags["limit-upload"] = ctx.String("limit-upload")
+			session.Header.CommandStringFlags["limit-download"] = ctx.String("limit-download")
+			session.Header.CommandBoolFlags["extract"] = ctx.Bool("extract")
 
 			if ctx.Bool("preserve") {
 				session.Header.CommandBoolFlags["preserve"] = ctx.Bool("preserve")
 			}
+			if ctx.Bool("preserve-mtime") {
+				session.Header.CommandBoolFlags["preserve-mtime"] = ctx.Bool("preserve-mtime")
+			}
 			session.Header.UserMetaData = userMetaMap
 
 			var e error
@@ -603,12 +1957,30 @@ func mainCopy(ctx *cli.Context) error {
 			}
 
 			// extract URLs.
-			session.Header.CommandArgs = ctx.Args()
+			session.Header.CommandArgs = args
 		}
 	}
 
-	e := doCopySession(ctx, session, encKeyDB)
-	if session != nil {
+	return runCopySession(ctx, session, args, encKeyDB, sseAlgorithm, sseKMSKeyID)
+}
+
+// runCopySession copies or extracts args, resuming session when it is
+// not nil, and removes the session once done unless it is terminated
+// to be resumed again.
+func runCopySession(ctx *cli.Context, session *sessionV8, args []string, encKeyDB map[string][]prefixSSEPair, sseAlgorithm, sseKMSKeyID string) error {
+	targetURL := args[len(args)-1]
+	targetAlias, _ := url2Alias(targetURL)
+	tgtSSE, err := parseTargetSSE(sseAlgorithm, sseKMSKeyID, targetURL, encKeyDB[targetAlias])
+	fatalIf(err.Trace(targetURL), "Invalid server side encryption.")
+	addTargetSSE(encKeyDB, targetURL, tgtSSE)
+
+	var e error
+	if ctx.Bool("extract") || session != nil && session.Header.CommandBoolFlags["extract"] {
+		e = doExtractSession(ctx, session, args, encKeyDB)
+	} else {
+		e = doCopySession(ctx, session, args, encKeyDB)
+	}
+	if session != nil && e != errSessionTerminated {
 		session.Delete()
 	}
 
@@ -17,8 +17,22 @@
 package cmd
 
 import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/disk"
 )
 
 func TestParseMetaData(t *testing.T) {
@@ -38,6 +52,17 @@ func TestParseMetaData(t *testing.T) {
 		{"key1:value1;key2:value2", nil, ErrInvalidMetadata, false},
 		// using no delimiter
 		{"key1:value1:key2:value2", nil, ErrInvalidMetadata, false},
+		// success scenario using , as delimiter
+		{"key1=value1,key2=value2", map[string]string{"Key1": "value1", "Key2": "value2"}, nil, true},
+		// single value with commas, kept by a trailing ;
+		{"Cache-Control=max-age=90000,min-fresh=9000;", map[string]string{"Cache-Control": "max-age=90000,min-fresh=9000"}, nil, true},
+		// standard and x-amz-meta- headers
+		{"x-amz-meta-owner=alice,cache-control=max-age=3600", map[string]string{"X-Amz-Meta-Owner": "alice", "Cache-Control": "max-age=3600"}, nil, true},
+		// key which is no header name
+		{"owner name=alice", nil, ErrInvalidMetadataHeader, false},
+		{"owner=alice,=bob", nil, ErrInvalidMetadataHeader, false},
+		// value with a line break
+		{"owner=alice\r\nx-amz-acl: public-read", nil, ErrInvalidMetadataHeader, false},
 	}
 
 	for idx, testCase := range metaDataCases {
@@ -61,3 +86,431 @@ func TestParseMetaData(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckTargetSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on windows")
+	}
+	tmpDir, e := ioutil.TempDir("", "cp-main-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// backups -> data, data/escape -> outside
+	dataDir := filepath.Join(tmpDir, "data")
+	outsideDir := filepath.Join(tmpDir, "outside")
+	for _, dir := range []string{filepath.Join(dataDir, "sub"), outsideDir} {
+		if e = os.MkdirAll(dir, 0755); e != nil {
+			t.Fatal(e)
+		}
+	}
+	rootLink := filepath.Join(tmpDir, "backups")
+	if e = os.Symlink(dataDir, rootLink); e != nil {
+		t.Fatal(e)
+	}
+	if e = os.Symlink(outsideDir, filepath.Join(dataDir, "escape")); e != nil {
+		t.Fatal(e)
+	}
+
+	testCases := []struct {
+		rootPath   string
+		targetPath string
+		expectErr  bool
+	}{
+		{rootLink, filepath.Join(rootLink, "file.txt"), false},
+		{rootLink, filepath.Join(rootLink, "sub", "file.txt"), false},
+		{rootLink, filepath.Join(rootLink, "missing", "dir", "file.txt"), false},
+		{rootLink, filepath.Join(rootLink, "escape", "file.txt"), true},
+		{rootLink, filepath.Join(rootLink, "escape", "dir", "file.txt"), true},
+		{dataDir, filepath.Join(dataDir, "escape", "file.txt"), true},
+		// Copy of a single file, the target names the file itself.
+		{filepath.Join(rootLink, "file.txt"), filepath.Join(rootLink, "file.txt"), false},
+	}
+	for i, testCase := range testCases {
+		err := checkTargetSymlinks(testCase.rootPath, testCase.targetPath)
+		if testCase.expectErr != (err != nil) {
+			t.Fatalf("Test %d: expected error %t, found %v", i+1, testCase.expectErr, err)
+		}
+	}
+}
+
+func TestCopyHookValues(t *testing.T) {
+	testCases := []struct {
+		targetAlias string
+		targetURL   string
+		key         string
+		target      string
+	}{
+		{"play", "https://play.min.io/mybucket/dir/a b.txt", "dir/a b.txt", "play/mybucket/dir/a b.txt"},
+		{"play", "https://play.min.io/mybucket/a.txt", "a.txt", "play/mybucket/a.txt"},
+		{"", "/tmp/dir/a.txt", "/tmp/dir/a.txt", "/tmp/dir/a.txt"},
+	}
+	for i, testCase := range testCases {
+		cpURLs := URLs{
+			SourceAlias:   "",
+			SourceContent: &clientContent{URL: *newClientURL("/src/a.txt"), Size: 42},
+			TargetAlias:   testCase.targetAlias,
+			TargetContent: &clientContent{URL: *newClientURL(testCase.targetURL)},
+		}
+		values := copyHookValues([]string{"register", "{key}"}, cpURLs)
+		if values["{key}"] != testCase.key {
+			t.Errorf("Test %d: expected key %q, got %q", i+1, testCase.key, values["{key}"])
+		}
+		if values["{target}"] != testCase.target {
+			t.Errorf("Test %d: expected target %q, got %q", i+1, testCase.target, values["{target}"])
+		}
+		if values["{size}"] != "42" || values["{error}"] != "" {
+			t.Errorf("Test %d: unexpected size %q or error %q", i+1, values["{size}"], values["{error}"])
+		}
+	}
+}
+
+func TestSplitHookCommand(t *testing.T) {
+	testCases := []struct {
+		command   string
+		args      []string
+		expectErr bool
+	}{
+		{"", nil, false},
+		{"  catalog add {key}  {size} ", []string{"catalog", "add", "{key}", "{size}"}, false},
+		{`notify "copied {key}" 'as {target}'`, []string{"notify", "copied {key}", "as {target}"}, false},
+		{`echo a\ b "c \"d\" \e" 'f\g' ""`, []string{"echo", "a b", `c "d" \e`, `f\g`, ""}, false},
+		{`echo "unterminated`, nil, true},
+		{`echo trailing\`, nil, true},
+	}
+	for i, testCase := range testCases {
+		args, err := splitHookCommand(testCase.command)
+		if testCase.expectErr != (err != nil) {
+			t.Fatalf("Test %d: expected error %t, found %v", i+1, testCase.expectErr, err)
+		}
+		if !reflect.DeepEqual(args, testCase.args) {
+			t.Errorf("Test %d: expected %q, got %q", i+1, testCase.args, args)
+		}
+	}
+}
+
+func TestExecCopyHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook runs a shell")
+	}
+	tmpDir, e := ioutil.TempDir("", "cp-hook-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+	output := filepath.Join(tmpDir, "output")
+
+	hooks, err := newCopyHooks(`sh -c 'printf "%s|%s" "$1" "$2" > `+output+`' hook {key} "{source}"`, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Values are not searched for placeholders.
+	cpURLs := URLs{
+		SourceContent: &clientContent{URL: *newClientURL("/src/{size} x.txt"), Size: 42},
+		TargetContent: &clientContent{URL: *newClientURL(filepath.Join(tmpDir, "{source} y.txt"))},
+	}
+	if cpURLs = hooks.run(cpURLs); cpURLs.Error != nil {
+		t.Fatal(cpURLs.Error)
+	}
+	got, e := ioutil.ReadFile(output)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if expected := filepath.Join(tmpDir, "{source} y.txt") + "|/src/{size} x.txt"; string(got) != expected {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+
+	// A failing strict hook fails the object, unless a move removed its
+	// source already.
+	hooks, err = newCopyHooks("false", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls := hooks.runOnSuccess(cpURLs, false); urls.Error == nil {
+		t.Fatal("expected the object to fail")
+	}
+	if urls := hooks.runOnSuccess(cpURLs, true); urls.Error != nil {
+		t.Fatalf("unexpected error %s", urls.Error)
+	}
+}
+
+func TestCheckKeyLength(t *testing.T) {
+	longName := strings.Repeat("a", fsMaxNameLength+1)
+	testCases := []struct {
+		targetURL    string
+		maxKeyLength int
+		isTooLong    bool
+	}{
+		// The bucket name is not part of the key.
+		{"https://play.min.io/mybucket/0123456789", 10, false},
+		{"https://play.min.io/mybucket/dir/0123456789", 10, true},
+		{"https://play.min.io/mybucket/dir/0123456789", 0, false},
+		// Local targets ignore the configured maximum.
+		{"/tmp/dir/0123456789", 5, false},
+		{"/tmp/" + longName + "/a.txt", defaultMaxKeyLength, true},
+		{"/tmp/" + strings.Repeat("dir/", fsMaxPathLength/4), defaultMaxKeyLength, true},
+	}
+	for i, testCase := range testCases {
+		cpURLs := URLs{TargetContent: &clientContent{URL: *newClientURL(testCase.targetURL)}}
+		err := checkKeyLength(cpURLs, testCase.maxKeyLength)
+		if (err != nil) != testCase.isTooLong {
+			t.Fatalf("Test %d: expected too long %t, got %v", i+1, testCase.isTooLong, err)
+		}
+		if err != nil {
+			if _, ok := err.ToGoError().(KeyTooLong); !ok {
+				t.Fatalf("Test %d: expected KeyTooLong, got %T", i+1, err.ToGoError())
+			}
+		}
+	}
+}
+
+func TestParseMultipartThreshold(t *testing.T) {
+	testCases := []struct {
+		value   string
+		size    int64
+		success bool
+	}{
+		{"", 0, true},
+		{"64 MiB", defaultMultipartThreshold, true},
+		{"1GiB", 1024 * 1024 * 1024, true},
+		{"5GiB", 5 * 1024 * 1024 * 1024, true},
+		{"5GiB1", 0, false},
+		{"6GiB", 0, false},
+		{"large", 0, false},
+	}
+	for i, testCase := range testCases {
+		size, err := parseMultipartThreshold(testCase.value)
+		if testCase.success != (err == nil) {
+			t.Fatalf("Test %d: expected success %t, found error %v", i+1, testCase.success, err)
+		}
+		if size != testCase.size {
+			t.Fatalf("Test %d: expected %d, found %d", i+1, testCase.size, size)
+		}
+	}
+}
+
+func TestPartSizeThreshold(t *testing.T) {
+	testCases := []struct {
+		partSize  string
+		threshold int64
+		success   bool
+	}{
+		{"", 0, true},
+		{"16MiB", 32 * 1024 * 1024, true},
+		{"5MiB", 10 * 1024 * 1024, true},
+		{"2.5GiB", 5 * 1024 * 1024 * 1024, true},
+		{"4MiB", 0, false},
+		{"3GiB", 0, false},
+		{"large", 0, false},
+	}
+	for i, testCase := range testCases {
+		threshold, err := partSizeThreshold(testCase.partSize)
+		if testCase.success != (err == nil) {
+			t.Fatalf("Test %d: expected success %t, found error %v", i+1, testCase.success, err)
+		}
+		if threshold != testCase.threshold {
+			t.Fatalf("Test %d: expected %d, found %d", i+1, testCase.threshold, threshold)
+		}
+	}
+}
+
+func TestCheckProtectWindow(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	tmpDir, e := ioutil.TempDir("", "cp-main-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fresh, old := filepath.Join(tmpDir, "fresh"), filepath.Join(tmpDir, "old")
+	for _, name := range []string{fresh, old} {
+		if e = ioutil.WriteFile(name, []byte("data"), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+	hourAgo := time.Now().Add(-time.Hour)
+	if e = os.Chtimes(old, hourAgo, hourAgo); e != nil {
+		t.Fatal(e)
+	}
+
+	// Targets whose age can't be told are not copied over either.
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	defer server.Close()
+
+	testCases := []struct {
+		targetPath      string
+		expectProtected bool
+		expectErr       bool
+	}{
+		{fresh, true, false},
+		{old, false, false},
+		{filepath.Join(tmpDir, "missing"), false, false},
+		{server.URL + "/bucket/denied", false, true},
+	}
+	for i, testCase := range testCases {
+		cpURLs := URLs{TargetContent: &clientContent{URL: *newClientURL(testCase.targetPath)}}
+		protected, err := checkProtectWindow(cpURLs, 10*time.Minute, nil)
+		if testCase.expectProtected != (protected != nil) || testCase.expectErr != (err != nil) {
+			t.Fatalf("Test %d: expected protected %t and error %t, found %v and %v", i+1, testCase.expectProtected, testCase.expectErr, protected, err)
+		}
+	}
+}
+
+func TestVerifyCopied(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	tmpDir, e := ioutil.TempDir("", "cp-main-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+	if e = ioutil.WriteFile(filepath.Join(tmpDir, "object"), []byte("hello"), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	testCases := []struct {
+		target     string
+		sourceSize int64
+		success    bool
+	}{
+		{"object", 5, true},
+		{"object", 42, false},
+		{"missing", 5, false},
+	}
+	for i, testCase := range testCases {
+		cpURLs := URLs{
+			SourceContent: &clientContent{URL: *newClientURL("/src/object"), Size: testCase.sourceSize},
+			TargetContent: &clientContent{URL: *newClientURL(filepath.Join(tmpDir, testCase.target))},
+		}
+		mismatch, err := verifyCopied(cpURLs, nil)
+		if err != nil {
+			t.Fatalf("Test %d: unable to verify: %v", i+1, err)
+		}
+		if testCase.success != (mismatch == nil) {
+			t.Fatalf("Test %d: expected success %t, found mismatch %v", i+1, testCase.success, mismatch)
+		}
+	}
+}
+
+func TestVerifyCopiedObjects(t *testing.T) {
+	data := []byte("hello hello hello hello")
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	gw.Write(data)
+	gw.Close()
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket/" {
+			// Nothing listed, objects are found with a HEAD request.
+			w.Write([]byte("<ListBucketResult><Name>bucket</Name><IsTruncated>false</IsTruncated></ListBucketResult>"))
+			return
+		}
+		body := data
+		switch r.URL.Path {
+		case "/bucket/compressed", "/bucket/truncated":
+			body = compressed.Bytes()
+			if r.URL.Path == "/bucket/truncated" {
+				body = body[:len(body)-4]
+			}
+			w.Header().Set("Content-Encoding", "gzip")
+		case "/bucket/broken":
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		case "/bucket/missing":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", "\"etag\"")
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("verifytest", server.URL)()
+
+	testCases := []struct {
+		object   string
+		mismatch bool
+		err      bool
+	}{
+		{"plain", false, false},
+		// Size of the object compressed by --compress.
+		{"compressed", false, false},
+		{"truncated", true, false},
+		{"missing", true, false},
+		// Not known to be missing, checked again on the next resume.
+		{"broken", false, true},
+	}
+	for i, testCase := range testCases {
+		cpURLs := URLs{
+			SourceContent: &clientContent{URL: *newClientURL("/src/object"), Size: int64(len(data))},
+			TargetAlias:   "verifytest",
+			TargetContent: &clientContent{URL: *newClientURL(server.URL + "/bucket/" + testCase.object)},
+		}
+		mismatch, err := verifyCopied(cpURLs, nil)
+		if testCase.mismatch != (mismatch != nil) || testCase.err != (err != nil) {
+			t.Fatalf("Test %d: expected mismatch %t and error %t, found %v and %v", i+1, testCase.mismatch, testCase.err, mismatch, err)
+		}
+	}
+}
+
+func TestFreeSpaceCheck(t *testing.T) {
+	defer useEmptyMcConfig()()
+	defer setTestAlias("spacetest", "https://localhost:9000")()
+
+	root, e := ioutil.TempDir("", "free-space-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(root)
+
+	info, e := disk.GetInfo(root)
+	if e != nil || info.Total == 0 {
+		t.Skip("free space is not known on this platform")
+	}
+	newContext := func(args ...string) *cli.Context {
+		set := flag.NewFlagSet("cp", flag.ContinueOnError)
+		for _, f := range cpCmd.Flags {
+			f.Apply(set)
+		}
+		if e := set.Parse(args); e != nil {
+			t.Fatal(e)
+		}
+		return cli.NewContext(nil, set, nil)
+	}
+
+	testCases := []struct {
+		ctx     *cli.Context
+		target  string
+		checked bool
+	}{
+		// Missing targets are verified on their closest parent.
+		{newContext(), filepath.Join(root, "target", "sub"), true},
+		// Only local targets are verified.
+		{newContext(), "spacetest/bucket/", false},
+		{newContext("--no-space-check"), filepath.Join(root, "target"), false},
+	}
+	for i, testCase := range testCases {
+		check, err := newFreeSpaceCheck(testCase.ctx, testCase.target)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, err)
+		}
+		if testCase.checked != (check != nil) {
+			t.Fatalf("Test %d: expected check %t, found %v", i+1, testCase.checked, check)
+		}
+		if err = check.verify(4); err != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, err)
+		}
+		// Sources bigger than the free space of the volume.
+		if err = check.verify(int64(info.Free) + 1<<30); testCase.checked != (err != nil) {
+			t.Fatalf("Test %d: expected error %t, found %v", i+1, testCase.checked, err)
+		}
+	}
+}
@@ -18,19 +18,25 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
 )
 
-func checkCopySyntax(ctx *cli.Context, encKeyDB map[string][]prefixSSEPair) {
+// checkCopySyntax verifies the source and target URLs, which are the
+// arguments of the command once bucket patterns are expanded.
+func checkCopySyntax(ctx *cli.Context, URLs []string, encKeyDB map[string][]prefixSSEPair) {
 	if len(ctx.Args()) < 2 {
-		cli.ShowCommandHelpAndExit(ctx, "cp", 1) // last argument is exit code.
+		cli.ShowCommandHelpAndExit(ctx, ctx.Command.Name, 1) // last argument is exit code.
 	}
 
 	// extract URLs.
-	URLs := ctx.Args()
 	if len(URLs) < 2 {
 		fatalIf(errDummy().Trace(ctx.Args()...), fmt.Sprintf("Unable to parse source and target arguments."))
 	}
@@ -38,21 +44,31 @@ func checkCopySyntax(ctx *cli.Context, encKeyDB map[string][]prefixSSEPair) {
 	srcURLs := URLs[:len(URLs)-1]
 	tgtURL := URLs[len(URLs)-1]
 	isRecursive := ctx.Bool("recursive")
+	isAllowEmpty := ctx.Bool("allow-empty")
 
 	// Verify if source(s) exists.
 	for _, srcURL := range srcURLs {
-		_, _, err := url2Stat(srcURL, false, false, encKeyDB)
-		if err != nil {
+		_, _, err := sourceURL2Stat(srcURL, false, false, encKeyDB)
+		if err != nil && !(isAllowEmpty && isRecursive && isErrSourceMissing(err)) {
 			console.Fatalf("Unable to validate source %s\n", srcURL)
 		}
 	}
 
-	// Check if bucket name is passed for URL type arguments.
-	url := newClientURL(tgtURL)
-	if url.Host != "" {
-		if url.Path == string(url.Separator) {
-			fatalIf(errInvalidArgument().Trace(), fmt.Sprintf("Target `%s` does not contain bucket name.", tgtURL))
-		}
+	checkCopyOptions(ctx, srcURLs, tgtURL)
+
+	// Plain HTTP(S) URLs are only read from.
+	if _, err := newClient(tgtURL); err != nil {
+		fatalIf(err.Trace(tgtURL), "Unable to initialize target `"+tgtURL+"`.")
+	}
+
+	tgtAlias, _ := url2Alias(tgtURL)
+	if _, err := parseTargetSSE(ctx.String("sse"), ctx.String("sse-kms-key-id"), tgtURL, encKeyDB[tgtAlias]); err != nil {
+		fatalIf(err.Trace(tgtURL), "Invalid server side encryption.")
+	}
+
+	if ctx.Bool("extract") {
+		checkCopyExtractSyntax(srcURLs, isRecursive, encKeyDB)
+		return
 	}
 
 	// Guess CopyURLsType based on source and target URLs.
@@ -67,17 +83,186 @@ func checkCopySyntax(ctx *cli.Context, encKeyDB map[string][]prefixSSEPair) {
 	case copyURLsTypeB: // File -> Folder.
 		checkCopySyntaxTypeB(srcURLs, tgtURL, encKeyDB)
 	case copyURLsTypeC: // Folder... -> Folder.
-		checkCopySyntaxTypeC(srcURLs, tgtURL, isRecursive, encKeyDB)
+		checkCopySyntaxTypeC(srcURLs, tgtURL, isRecursive, isAllowEmpty, encKeyDB)
 	case copyURLsTypeD: // File1...FileN -> Folder.
 		checkCopySyntaxTypeD(srcURLs, tgtURL, encKeyDB)
 	default:
 		fatalIf(errInvalidArgument().Trace(), "Unable to guess the type of copy operation.")
 	}
+}
+
+// checkCopyOptions verifies the target bucket and the flags, none of
+// which needs access to the source or the target.
+func checkCopyOptions(ctx *cli.Context, srcURLs []string, tgtURL string) {
+	isRecursive := ctx.Bool("recursive")
+
+	// Check if bucket name is passed for URL type arguments.
+	url := newClientURL(tgtURL)
+	if url.Host != "" {
+		if url.Path == string(url.Separator) {
+			fatalIf(errInvalidArgument().Trace(), fmt.Sprintf("Target `%s` does not contain bucket name.", tgtURL))
+		}
+	}
 
 	// Preserve functionality not supported for windows
 	if ctx.Bool("preserve") && runtime.GOOS == "windows" {
 		fatalIf(errInvalidArgument().Trace(), "Permissions are not preserved on windows platform.")
 	}
+
+	if _, err := newCopyHooks(ctx.String("on-success"), ctx.String("on-failure"), false); err != nil {
+		fatalIf(err, "Unable to parse --on-success or --on-failure.")
+	}
+	if value := ctx.String("content-disposition"); value != "" && !isValidContentDisposition(value) {
+		fatalIf(errInvalidArgument().Trace(value), "Invalid Content-Disposition `"+value+"`.")
+	}
+	if value := ctx.String("content-type"); value != "" && !isValidContentType(value) {
+		fatalIf(errInvalidArgument().Trace(value), "Invalid Content-Type `"+value+"`.")
+	}
+	if value := ctx.String("cache-control"); value != "" && !isValidCacheControl(value) {
+		fatalIf(errInvalidArgument().Trace(value), "Invalid Cache-Control `"+value+"`.")
+	}
+	if value := ctx.String("charset"); value != "" && !isValidCharset(value) {
+		fatalIf(errInvalidArgument().Trace(value), "Invalid charset `"+value+"`.")
+	}
+	if ctx.Bool("no-follow-target-symlink") {
+		if targetRoot := copyTargetRoot(tgtURL); targetRoot != "" {
+			if st, e := os.Lstat(targetRoot); e == nil && st.Mode()&os.ModeSymlink != 0 {
+				fatalIf(errTargetIsSymlink(tgtURL).Trace(tgtURL), "Unable to copy into `"+tgtURL+"`.")
+			}
+		}
+	}
+	if ctx.Bool("compress-auto") {
+		if targetAlias, expandedURL, _ := mustExpandAlias(tgtURL); targetAlias == "" && newClientURL(expandedURL).Type == fileSystem {
+			fatalIf(errInvalidArgument().Trace(tgtURL), "--compress-auto is only supported for object storage targets.")
+		}
+	}
+	if ctx.Bool("append") {
+		if targetAlias, expandedURL, _ := mustExpandAlias(tgtURL); targetAlias != "" || newClientURL(expandedURL).Type != fileSystem {
+			fatalIf(errInvalidArgument().Trace(tgtURL), "--append is only supported for local targets, object storage cannot append to objects.")
+		}
+	}
+	if value := ctx.String("checksum"); value != "" {
+		if newChecksumHash(value) == nil {
+			fatalIf(errInvalidArgument().Trace(value), "Invalid --checksum `"+value+"`, expecting `sha256` or `md5`.")
+		}
+		if targetAlias, expandedURL, _ := mustExpandAlias(tgtURL); targetAlias == "" && newClientURL(expandedURL).Type == fileSystem {
+			fatalIf(errInvalidArgument().Trace(tgtURL), "--checksum is only supported for object storage targets.")
+		}
+		if ctx.Bool("compress-auto") {
+			fatalIf(errInvalidArgument().Trace(value), "--checksum cannot be used with --compress-auto.")
+		}
+	}
+	if value := ctx.String("slash-conflict"); !isValidSlashConflict(value) {
+		fatalIf(errInvalidArgument().Trace(value), "Invalid --slash-conflict `"+value+"`, expecting `skip` or `rename`.")
+	}
+	if value := ctx.String("multipart-threshold"); value != "" {
+		_, err := parseMultipartThreshold(value)
+		fatalIf(err.Trace(value), "Invalid --multipart-threshold `"+value+"`.")
+	}
+	if value := ctx.String("protect-window"); value != "" {
+		_, err := parseProtectWindow(value)
+		fatalIf(err.Trace(value), "Invalid --protect-window `"+value+"`.")
+	}
+	if value := ctx.Int("retry"); value < 0 {
+		fatalIf(errInvalidArgument().Trace(strconv.Itoa(value)), "Invalid --retry `"+strconv.Itoa(value)+"`.")
+	}
+	if value := ctx.String("retry-delay"); value != "" {
+		delay, e := time.ParseDuration(value)
+		if e != nil {
+			fatalIf(probe.NewError(e).Trace(value), "Invalid --retry-delay `"+value+"`.")
+		}
+		if delay < 0 {
+			fatalIf(errInvalidArgument().Trace(value), "Invalid --retry-delay `"+value+"`, it must not be negative.")
+		}
+	}
+	if value := ctx.Int("parallel"); value < 0 || value > maxParallelWorkers {
+		fatalIf(errInvalidArgument().Trace(strconv.Itoa(value)),
+			fmt.Sprintf("Invalid --parallel `%d`, expecting at most %d.", value, maxParallelWorkers))
+	}
+	for _, name := range []string{"limit-upload", "limit-download"} {
+		if value := ctx.String(name); value != "" {
+			_, err := parseBandwidthLimit(value)
+			fatalIf(err.Trace(value), "Invalid --"+name+" `"+value+"`.")
+		}
+	}
+	if ctx.Bool("repair") && !ctx.Bool("continue") {
+		fatalIf(errInvalidArgument().Trace(), "--repair rebuilds the data file of a session, it requires --continue.")
+	}
+	if ctx.IsSet("if-match") {
+		checkCopyIfMatch(ctx.String("if-match"), srcURLs, tgtURL, isRecursive)
+	}
+}
+
+// Descriptions of the copy types shown in offline mode.
+var copyURLsTypeNames = map[copyURLsType]string{
+	copyURLsTypeInvalid: "unknown",
+	copyURLsTypeA:       "file to file",
+	copyURLsTypeB:       "file to folder",
+	copyURLsTypeC:       "folder to folder",
+	copyURLsTypeD:       "files to folder",
+}
+
+// checkCopyOffline verifies the arguments of an offline copy and prints
+// the copy that would run. Remote sources can't be stat'ed offline, so
+// the copy type of a single remote source is unknown without --recursive.
+func checkCopyOffline(ctx *cli.Context, URLs []string, encKeyDB map[string][]prefixSSEPair) {
+	if len(ctx.Args()) < 2 {
+		cli.ShowCommandHelpAndExit(ctx, ctx.Command.Name, 1) // last argument is exit code.
+	}
+	if len(URLs) < 2 {
+		fatalIf(errDummy().Trace(ctx.Args()...), "Unable to parse source and target arguments.")
+	}
+	for i, URL := range URLs {
+		newURLClient := newClient
+		if i < len(URLs)-1 {
+			newURLClient = newSourceClient
+		}
+		_, err := newURLClient(URL)
+		fatalIf(err.Trace(URL), "Unable to initialize `"+URL+"`.")
+	}
+
+	srcURLs := URLs[:len(URLs)-1]
+	tgtURL := URLs[len(URLs)-1]
+	checkCopyOptions(ctx, srcURLs, tgtURL)
+
+	isRecursive := ctx.Bool("recursive")
+	copyURLsType, err := guessCopyURLType(srcURLs, tgtURL, isRecursive, encKeyDB)
+	if err != nil {
+		if _, ok := err.ToGoError().(Offline); !ok {
+			fatalIf(err.Trace(URLs...), "Unable to guess the type of copy operation.")
+		}
+		if isRecursive {
+			copyURLsType = copyURLsTypeC
+		}
+	}
+	printMsg(copyPlanMessage{
+		Sources: srcURLs,
+		Target:  tgtURL,
+		Type:    copyURLsTypeNames[copyURLsType],
+	})
+}
+
+// checkCopyIfMatch verifies that an ETag precondition names a single
+// object on object storage, an ETag can't describe several targets.
+func checkCopyIfMatch(etag string, srcURLs []string, tgtURL string, isRecursive bool) {
+	if strings.Trim(etag, "\" ") == "" {
+		fatalIf(errInvalidArgument().Trace(etag), "--if-match requires a non empty ETag.")
+	}
+	if isRecursive || len(srcURLs) != 1 {
+		fatalIf(errInvalidArgument().Trace(srcURLs...), "--if-match can only be used to copy a single object.")
+	}
+	if targetAlias, expandedURL, _ := mustExpandAlias(tgtURL); targetAlias == "" && newClientURL(expandedURL).Type == fileSystem {
+		fatalIf(errInvalidArgument().Trace(tgtURL), "--if-match is only supported for object storage targets.")
+	}
+}
+
+// checkCopyExtractSyntax verifies that the source of --extract is a
+// single archive, its files are uploaded under the target folder.
+func checkCopyExtractSyntax(srcURLs []string, isRecursive bool, keys map[string][]prefixSSEPair) {
+	if isRecursive || len(srcURLs) != 1 {
+		fatalIf(errInvalidArgument().Trace(srcURLs...), "--extract uploads the files of a single tar archive.")
+	}
+	checkCopySyntaxTypeA(srcURLs, "", keys)
 }
 
 // checkCopySyntaxTypeA verifies if the source and target are valid file arguments.
@@ -87,7 +272,7 @@ func checkCopySyntaxTypeA(srcURLs []string, tgtURL string, keys map[string][]pre
 		fatalIf(errInvalidArgument().Trace(), "Invalid number of source arguments.")
 	}
 	srcURL := srcURLs[0]
-	_, srcContent, err := url2Stat(srcURL, false, false, keys)
+	_, srcContent, err := sourceURL2Stat(srcURL, false, false, keys)
 	fatalIf(err.Trace(srcURL), "Unable to stat source `"+srcURL+"`.")
 
 	if !srcContent.Type.IsRegular() {
@@ -102,7 +287,7 @@ func checkCopySyntaxTypeB(srcURLs []string, tgtURL string, keys map[string][]pre
 		fatalIf(errInvalidArgument().Trace(), "Invalid number of source arguments.")
 	}
 	srcURL := srcURLs[0]
-	_, srcContent, err := url2Stat(srcURL, false, false, keys)
+	_, srcContent, err := sourceURL2Stat(srcURL, false, false, keys)
 	fatalIf(err.Trace(srcURL), "Unable to stat source `"+srcURL+"`.")
 
 	if !srcContent.Type.IsRegular() {
@@ -118,7 +303,7 @@ func checkCopySyntaxTypeB(srcURLs []string, tgtURL string, keys map[string][]pre
 }
 
 // checkCopySyntaxTypeC verifies if the source is a valid recursive dir and target is a valid folder.
-func checkCopySyntaxTypeC(srcURLs []string, tgtURL string, isRecursive bool, keys map[string][]prefixSSEPair) {
+func checkCopySyntaxTypeC(srcURLs []string, tgtURL string, isRecursive, isAllowEmpty bool, keys map[string][]prefixSSEPair) {
 	// Check source.
 	if len(srcURLs) != 1 {
 		fatalIf(errInvalidArgument().Trace(), "Invalid number of source arguments.")
@@ -132,11 +317,11 @@ func checkCopySyntaxTypeC(srcURLs []string, tgtURL string, isRecursive bool, key
 	}
 
 	for _, srcURL := range srcURLs {
-		c, srcContent, err := url2Stat(srcURL, false, false, keys)
+		c, srcContent, err := sourceURL2Stat(srcURL, false, false, keys)
 		// incomplete uploads are not necessary for copy operation, no need to verify for them.
 		isIncomplete := false
 		if err != nil {
-			if !isURLPrefixExists(srcURL, isIncomplete) {
+			if !isURLPrefixExists(srcURL, isIncomplete) && !(isAllowEmpty && isErrSourceMissing(err)) {
 				fatalIf(err.Trace(srcURL), "Unable to stat source `"+srcURL+"`.")
 			}
 			// No more check here, continue to the next source url
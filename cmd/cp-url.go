@@ -17,8 +17,10 @@
 package cmd
 
 import (
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/minio/mc/pkg/probe"
 )
@@ -53,8 +55,13 @@ const (
 func guessCopyURLType(sourceURLs []string, targetURL string, isRecursive bool, keys map[string][]prefixSSEPair) (copyURLsType, *probe.Error) {
 	if len(sourceURLs) == 1 { // 1 Source, 1 Target
 		sourceURL := sourceURLs[0]
-		_, sourceContent, err := url2Stat(sourceURL, false, false, keys)
+		_, sourceContent, err := sourceURL2Stat(sourceURL, false, false, keys)
 		if err != nil {
+			// A missing folder is reported by Type C, which tells it
+			// apart from an empty one.
+			if isRecursive && isErrSourceMissing(err) {
+				return copyURLsTypeC, nil
+			}
 			return copyURLsTypeInvalid, err
 		}
 
@@ -88,7 +95,7 @@ func prepareCopyURLsTypeA(sourceURL string, targetURL string, encKeyDB map[strin
 	// Find alias and expanded clientURL.
 	targetAlias, targetURL, _ := mustExpandAlias(targetURL)
 
-	_, sourceContent, err := url2Stat(sourceURL, false, false, encKeyDB)
+	_, sourceContent, err := sourceURL2Stat(sourceURL, false, false, encKeyDB)
 	if err != nil {
 		// Source does not exist or insufficient privileges.
 		return URLs{Error: err.Trace(sourceURL)}
@@ -121,7 +128,7 @@ func prepareCopyURLsTypeB(sourceURL string, targetURL string, encKeyDB map[strin
 	// Find alias and expanded clientURL.
 	targetAlias, targetURL, _ := mustExpandAlias(targetURL)
 
-	_, sourceContent, err := url2Stat(sourceURL, false, false, encKeyDB)
+	_, sourceContent, err := sourceURL2Stat(sourceURL, false, false, encKeyDB)
 	if err != nil {
 		// Source does not exist or insufficient privileges.
 		return URLs{Error: err.Trace(sourceURL)}
@@ -149,7 +156,9 @@ func makeCopyContentTypeB(sourceAlias string, sourceContent *clientContent, targ
 
 // SINGLE SOURCE - Type C: copy(d1..., d2) -> []copy(d1/f, d1/d2/f) -> []A
 // prepareCopyRecursiveURLTypeC - prepares target and source clientURLs for copying.
-func prepareCopyURLsTypeC(sourceURL, targetURL string, isRecursive bool, encKeyDB map[string][]prefixSSEPair) <-chan URLs {
+// A source without any object is fine if it exists, a missing source is
+// an error unless isAllowEmpty is set.
+func prepareCopyURLsTypeC(sourceURL, targetURL string, isRecursive, isAllowEmpty bool, encKeyDB map[string][]prefixSSEPair, slashConflict string, excludes *excludeFilter) <-chan URLs {
 	// Extract alias before fiddling with the clientURL.
 	sourceAlias, _, _ := mustExpandAlias(sourceURL)
 	// Find alias and expanded clientURL.
@@ -157,33 +166,174 @@ func prepareCopyURLsTypeC(sourceURL, targetURL string, isRecursive bool, encKeyD
 	copyURLsCh := make(chan URLs)
 	go func(sourceURL, targetURL string, copyURLsCh chan URLs) {
 		defer close(copyURLsCh)
-		sourceClient, err := newClient(sourceURL)
+		sourceClient, err := newSourceClient(sourceURL)
 		if err != nil {
 			// Source initialization failed.
 			copyURLsCh <- URLs{Error: err.Trace(sourceURL)}
 			return
 		}
 
+		// A filesystem can't hold an object and a folder of the same name.
+		var conflicts *slashConflictFilter
+		if slashConflict != "" && sourceAlias != "" && targetAlias == "" {
+			conflicts = &slashConflictFilter{resolution: slashConflict}
+		}
+
 		isIncomplete := false
+		isEmpty, isMissing := true, false
 		for sourceContent := range sourceClient.List(isRecursive, isIncomplete, false, DirNone) {
 			if sourceContent.Err != nil {
+				if isErrSourceMissing(sourceContent.Err) {
+					isMissing = true
+					continue
+				}
 				// Listing failed.
 				copyURLsCh <- URLs{Error: sourceContent.Err.Trace(sourceClient.GetURL().String())}
 				continue
 			}
+			isEmpty = false
 
 			if !sourceContent.Type.IsRegular() {
 				// Source is not a regular file. Skip it for copy.
 				continue
 			}
+			if excludes.skip(sourceSuffix(sourceClient.GetURL(), sourceContent)) {
+				continue
+			}
 
 			// All OK.. We can proceed. Type B: source is a file, target is a folder and exists.
-			copyURLsCh <- makeCopyContentTypeC(sourceAlias, sourceClient.GetURL(), sourceContent, targetAlias, targetURL, encKeyDB)
+			cpURLs := makeCopyContentTypeC(sourceAlias, sourceClient.GetURL(), sourceContent, targetAlias, targetURL, encKeyDB)
+			if conflicts == nil {
+				copyURLsCh <- cpURLs
+				continue
+			}
+			for _, readyURLs := range conflicts.add(cpURLs) {
+				copyURLsCh <- readyURLs
+			}
+		}
+		if conflicts != nil {
+			for _, readyURLs := range conflicts.flush() {
+				copyURLsCh <- readyURLs
+			}
+		}
+		if !isEmpty {
+			return
+		}
+
+		// Nothing was listed, find out if the source exists at all.
+		if !isMissing {
+			_, err = sourceClient.Stat(isIncomplete, false, false, nil)
+			if err == nil {
+				// Existing but empty folder, nothing to copy.
+				return
+			}
+			if !isErrSourceMissing(err) {
+				copyURLsCh <- URLs{Error: err.Trace(sourceURL)}
+				return
+			}
+		}
+		if !isAllowEmpty {
+			copyURLsCh <- URLs{Error: errSourceNotFound(sourceURL).Trace(sourceURL)}
 		}
 	}(sourceURL, targetURL, copyURLsCh)
 	return copyURLsCh
 }
 
+// Resolutions of an object whose key is also the folder of other objects.
+const (
+	slashConflictSkip   = "skip"
+	slashConflictRename = "rename"
+)
+
+// Suffix appended to objects renamed by slashConflictRename.
+const slashConflictSuffix = "__file"
+
+// isValidSlashConflict - validates a --slash-conflict resolution.
+func isValidSlashConflict(resolution string) bool {
+	return resolution == slashConflictSkip || resolution == slashConflictRename
+}
+
+// slashConflictFilter holds back objects copied to a filesystem until
+// the listing is past the folder of the same name. Listings are sorted,
+// `foo` comes before `foo-bar`, and both before `foo/bar`. Renamed
+// objects are held back until the listing is past their new key, in
+// case the source has an object of that name too.
+type slashConflictFilter struct {
+	resolution string
+	// Objects held back, each one the parent prefix of the next. An
+	// object listed before the folder of the last one is listed below
+	// its name, so they are never more than the length of a key.
+	pending []URLs
+	renamed []URLs
+}
+
+// add returns the URLs which are ready to copy once cpURLs is listed.
+func (f *slashConflictFilter) add(cpURLs URLs) (ready []URLs) {
+	sourcePath := cpURLs.SourceContent.URL.Path
+
+	var renamed []URLs
+	for _, r := range f.renamed {
+		renamedPath := r.SourceContent.URL.Path + slashConflictSuffix
+		switch {
+		case sourcePath == renamedPath:
+			sourceURL := r.SourceContent.URL.String()
+			warningIf(probe.NewError(ObjectIsFolder{Object: sourceURL}),
+				"Skipping `%s`, `%s` exists in the source.", sourceURL, cpURLs.SourceContent.URL.String())
+		case sourcePath < renamedPath:
+			renamed = append(renamed, r)
+		default:
+			ready = append(ready, r)
+		}
+	}
+	f.renamed = renamed
+
+	// Once the listing is past the folder of the last object held back,
+	// it may be past those of its parent prefixes too.
+	var passed []URLs
+	for len(f.pending) > 0 {
+		last := len(f.pending) - 1
+		p := f.pending[last]
+		folder := p.SourceContent.URL.Path + string(p.SourceContent.URL.Separator)
+		if sourcePath < folder {
+			// Objects of the folder may still follow.
+			break
+		}
+		f.pending = f.pending[:last]
+		if strings.HasPrefix(sourcePath, folder) {
+			f.resolve(p)
+			continue
+		}
+		passed = append(passed, p)
+	}
+	// Ready in the order listed.
+	for i := len(passed) - 1; i >= 0; i-- {
+		ready = append(ready, passed[i])
+	}
+	f.pending = append(f.pending, cpURLs)
+	return ready
+}
+
+// flush returns the URLs held back at the end of the listing.
+func (f *slashConflictFilter) flush() []URLs {
+	ready := append(f.pending, f.renamed...)
+	f.pending, f.renamed = nil, nil
+	return ready
+}
+
+// resolve applies the resolution to an object which is also a folder.
+func (f *slashConflictFilter) resolve(cpURLs URLs) {
+	sourceURL := cpURLs.SourceContent.URL.String()
+	if f.resolution == slashConflictRename {
+		targetContent := *cpURLs.TargetContent
+		targetContent.URL.Path += slashConflictSuffix
+		cpURLs.TargetContent = &targetContent
+		warningIf(probe.NewError(ObjectIsFolder{Object: sourceURL}), "Copying `%s` to `%s`.", sourceURL, targetContent.URL.String())
+		f.renamed = append(f.renamed, cpURLs)
+		return
+	}
+	warningIf(probe.NewError(ObjectIsFolder{Object: sourceURL}), "Skipping `%s`.", sourceURL)
+}
+
 // makeCopyContentTypeC - CopyURLs content for copying.
 func makeCopyContentTypeC(sourceAlias string, sourceURL clientURL, sourceContent *clientContent, targetAlias string, targetURL string, encKeyDB map[string][]prefixSSEPair) URLs {
 	newSourceURL := sourceContent.URL
@@ -204,21 +354,40 @@ func makeCopyContentTypeC(sourceAlias string, sourceURL clientURL, sourceContent
 
 // MULTI-SOURCE - Type D: copy([](f|d...), d) -> []B
 // prepareCopyURLsTypeE - prepares target and source clientURLs for copying.
-func prepareCopyURLsTypeD(sourceURLs []string, targetURL string, isRecursive bool, encKeyDB map[string][]prefixSSEPair) <-chan URLs {
+// Up to listWorkers sources are listed at the same time, the URLs of
+// different sources are then interleaved.
+func prepareCopyURLsTypeD(sourceURLs []string, targetURL string, isRecursive, isAllowEmpty bool, encKeyDB map[string][]prefixSSEPair, listWorkers int, slashConflict string, excludes *excludeFilter) <-chan URLs {
+	if listWorkers < 1 {
+		listWorkers = 1
+	}
 	copyURLsCh := make(chan URLs)
-	go func(sourceURLs []string, targetURL string, copyURLsCh chan URLs) {
-		defer close(copyURLsCh)
-		for _, sourceURL := range sourceURLs {
-			for cpURLs := range prepareCopyURLsTypeC(sourceURL, targetURL, isRecursive, encKeyDB) {
-				copyURLsCh <- cpURLs
+	sourceURLCh := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < listWorkers && i < len(sourceURLs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sourceURL := range sourceURLCh {
+				for cpURLs := range prepareCopyURLsTypeC(sourceURL, targetURL, isRecursive, isAllowEmpty, encKeyDB, slashConflict, excludes) {
+					copyURLsCh <- cpURLs
+				}
 			}
+		}()
+	}
+	go func() {
+		for _, sourceURL := range sourceURLs {
+			sourceURLCh <- sourceURL
 		}
-	}(sourceURLs, targetURL, copyURLsCh)
+		close(sourceURLCh)
+		// Close only once every source is listed.
+		wg.Wait()
+		close(copyURLsCh)
+	}()
 	return copyURLsCh
 }
 
 // prepareCopyURLs - prepares target and source clientURLs for copying.
-func prepareCopyURLs(sourceURLs []string, targetURL string, isRecursive bool, encKeyDB map[string][]prefixSSEPair, olderThan, newerThan string) chan URLs {
+func prepareCopyURLs(sourceURLs []string, targetURL string, isRecursive, isAllowEmpty bool, encKeyDB map[string][]prefixSSEPair, olderThan, newerThan string, listWorkers int, slashConflict string, excludes *excludeFilter) chan URLs {
 	copyURLsCh := make(chan URLs)
 	go func(sourceURLs []string, targetURL string, copyURLsCh chan URLs, encKeyDB map[string][]prefixSSEPair) {
 		defer close(copyURLsCh)
@@ -231,11 +400,11 @@ func prepareCopyURLs(sourceURLs []string, targetURL string, isRecursive bool, en
 		case copyURLsTypeB:
 			copyURLsCh <- prepareCopyURLsTypeB(sourceURLs[0], targetURL, encKeyDB)
 		case copyURLsTypeC:
-			for cURLs := range prepareCopyURLsTypeC(sourceURLs[0], targetURL, isRecursive, encKeyDB) {
+			for cURLs := range prepareCopyURLsTypeC(sourceURLs[0], targetURL, isRecursive, isAllowEmpty, encKeyDB, slashConflict, excludes) {
 				copyURLsCh <- cURLs
 			}
 		case copyURLsTypeD:
-			for cURLs := range prepareCopyURLsTypeD(sourceURLs, targetURL, isRecursive, encKeyDB) {
+			for cURLs := range prepareCopyURLsTypeD(sourceURLs, targetURL, isRecursive, isAllowEmpty, encKeyDB, listWorkers, slashConflict, excludes) {
 				copyURLsCh <- cURLs
 			}
 		default:
@@ -263,3 +432,87 @@ func prepareCopyURLs(sourceURLs []string, targetURL string, isRecursive bool, en
 
 	return finalCopyURLsCh
 }
+
+// isSameSourceTarget returns true when cpURLs copies a file or an
+// object onto itself.
+func isSameSourceTarget(cpURLs URLs) bool {
+	if cpURLs.SourceContent == nil || cpURLs.TargetContent == nil {
+		return false
+	}
+	if cpURLs.SourceAlias != cpURLs.TargetAlias {
+		return false
+	}
+	sourceURL, targetURL := cpURLs.SourceContent.URL, cpURLs.TargetContent.URL
+	if sourceURL.Type != targetURL.Type || sourceURL.Host != targetURL.Host {
+		return false
+	}
+	if sourceURL.Type == fileSystem {
+		sourcePath, e1 := filepath.Abs(sourceURL.Path)
+		targetPath, e2 := filepath.Abs(targetURL.Path)
+		return e1 == nil && e2 == nil && sourcePath == targetPath
+	}
+	return sourceURL.Path == targetURL.Path
+}
+
+// isInPlaceMetadataUpdate returns true when copying an object onto
+// itself changes its metadata or storage class, object storage applies
+// it with a server side copy. Files have no metadata to update.
+func isInPlaceMetadataUpdate(cpURLs URLs) bool {
+	if cpURLs.TargetContent.URL.Type != objectStorage {
+		return false
+	}
+	if len(cpURLs.TargetContent.UserMetadata) > 0 {
+		return true
+	}
+	for _, k := range []string{"Content-Type", "Content-Disposition", "Cache-Control", charsetMetaKey, "X-Amz-Storage-Class"} {
+		if _, ok := cpURLs.TargetContent.Metadata[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBucketPatterns replaces the sources whose bucket is a pattern,
+// such as `alias/backup-*`, with one source per matching bucket. The
+// expanded sources have no trailing separator, so that a recursive
+// copy keeps each bucket name in the target path.
+func expandBucketPatterns(sourceURLs []string, isAllowEmpty bool) ([]string, *probe.Error) {
+	var expandedURLs []string
+	for _, sourceURL := range sourceURLs {
+		alias, _, hostCfg := mustExpandAlias(sourceURL)
+		_, aliasPath := url2Alias(sourceURL)
+		parts := strings.SplitN(filepath.ToSlash(aliasPath), "/", 2)
+		bucketPattern := parts[0]
+		if hostCfg == nil || !strings.ContainsAny(bucketPattern, "*?[") {
+			expandedURLs = append(expandedURLs, sourceURL)
+			continue
+		}
+		if len(parts) == 2 && parts[1] != "" {
+			// Only whole buckets can be matched.
+			return nil, errInvalidSource(sourceURL).Trace(sourceURL)
+		}
+		if _, e := path.Match(bucketPattern, ""); e != nil {
+			return nil, probe.NewError(e).Trace(sourceURL)
+		}
+
+		clnt, err := newClient(alias)
+		if err != nil {
+			return nil, err.Trace(sourceURL)
+		}
+		isMatched := false
+		for content := range clnt.List(false, false, false, DirNone) {
+			if content.Err != nil {
+				return nil, content.Err.Trace(sourceURL)
+			}
+			bucket := path.Base(content.URL.Path)
+			if ok, _ := path.Match(bucketPattern, bucket); ok {
+				expandedURLs = append(expandedURLs, alias+"/"+bucket)
+				isMatched = true
+			}
+		}
+		if !isMatched && !isAllowEmpty {
+			return nil, errSourceNotFound(sourceURL).Trace(sourceURL)
+		}
+	}
+	return expandedURLs, nil
+}
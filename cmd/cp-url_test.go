@@ -0,0 +1,490 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+func TestCopyFileToBucketRoot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" && r.URL.Path == "/bucket" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	// Use an empty config, the alias is provided through the environment.
+	defer useEmptyMcConfig()()
+	defer setTestAlias("cptest", server.URL)()
+
+	tmpDir, e := ioutil.TempDir("", "cp-url-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceURL := filepath.Join(tmpDir, "file.txt")
+	if e = ioutil.WriteFile(sourceURL, []byte("hello"), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	for i, targetURL := range []string{"cptest/bucket", "cptest/bucket/"} {
+		copyURLsType, err := guessCopyURLType([]string{sourceURL}, targetURL, false, nil)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, err)
+		}
+		if copyURLsType != copyURLsTypeB {
+			t.Fatalf("Test %d: expected copy type B, found %d", i+1, copyURLsType)
+		}
+
+		cpURLs := prepareCopyURLsTypeB(sourceURL, targetURL, nil)
+		if cpURLs.Error != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, cpURLs.Error)
+		}
+		if cpURLs.TargetAlias != "cptest" {
+			t.Fatalf("Test %d: expected target alias `cptest`, found `%s`", i+1, cpURLs.TargetAlias)
+		}
+		if cpURLs.TargetContent.URL.Path != "/bucket/file.txt" {
+			t.Fatalf("Test %d: expected target `/bucket/file.txt`, found `%s`", i+1, cpURLs.TargetContent.URL.Path)
+		}
+	}
+}
+
+func TestCopyURLsTypeCMissingSource(t *testing.T) {
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/empty", "/empty/":
+			if r.Method == "HEAD" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.Write([]byte("<ListBucketResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>empty</Name><KeyCount>0</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated></ListBucketResult>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method != "HEAD" {
+				w.Write([]byte("<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message><BucketName>missing</BucketName></Error>"))
+			}
+		}
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("cptest", server.URL)()
+
+	tmpDir, e := ioutil.TempDir("", "cp-url-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	emptyDir := filepath.Join(tmpDir, "empty")
+	if e = os.Mkdir(emptyDir, 0755); e != nil {
+		t.Fatal(e)
+	}
+	targetURL := filepath.Join(tmpDir, "target")
+
+	testCases := []struct {
+		sourceURL    string
+		isAllowEmpty bool
+		expectErr    bool
+	}{
+		{emptyDir, false, false},
+		{emptyDir, true, false},
+		{filepath.Join(tmpDir, "missing"), false, true},
+		{filepath.Join(tmpDir, "missing"), true, false},
+		{"cptest/empty", false, false},
+		{"cptest/missing", false, true},
+		{"cptest/missing", true, false},
+	}
+
+	for i, testCase := range testCases {
+		var copyURLs []URLs
+		for cpURLs := range prepareCopyURLsTypeC(testCase.sourceURL, targetURL, true, testCase.isAllowEmpty, nil, "", nil) {
+			copyURLs = append(copyURLs, cpURLs)
+		}
+		if !testCase.expectErr {
+			if len(copyURLs) != 0 {
+				t.Fatalf("Test %d: expected nothing to copy, found %v", i+1, copyURLs)
+			}
+			continue
+		}
+		if len(copyURLs) != 1 || copyURLs[0].Error == nil {
+			t.Fatalf("Test %d: expected a single error, found %v", i+1, copyURLs)
+		}
+		if _, ok := copyURLs[0].Error.ToGoError().(sourceNotFoundErr); !ok {
+			t.Fatalf("Test %d: expected source not found, found %s", i+1, copyURLs[0].Error)
+		}
+	}
+}
+
+func TestPrepareCopyURLsExclude(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	tmpDir, e := ioutil.TempDir("", "cp-url-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceDir := filepath.Join(tmpDir, "source")
+	for _, name := range []string{"a.txt", "b.tmp", "dir/c.tmp", "node_modules/d.js", "dir/node_modules/e.js"} {
+		name = filepath.Join(sourceDir, filepath.FromSlash(name))
+		if e = os.MkdirAll(filepath.Dir(name), 0755); e != nil {
+			t.Fatal(e)
+		}
+		if e = ioutil.WriteFile(name, []byte("data"), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	excludes := newExcludeFilter([]string{"*.tmp", "node_modules/"})
+	var copied []string
+	for cpURLs := range prepareCopyURLsTypeC(sourceDir, filepath.Join(tmpDir, "target"), true, false, nil, "", excludes) {
+		if cpURLs.Error != nil {
+			t.Fatal(cpURLs.Error)
+		}
+		copied = append(copied, sourceSuffix(*newClientURL(sourceDir), cpURLs.SourceContent))
+	}
+	sort.Strings(copied)
+	if expected := []string{"a.txt", "dir/node_modules/e.js"}; !reflect.DeepEqual(copied, expected) {
+		t.Fatalf("expected %v to be copied, found %v", expected, copied)
+	}
+	if excludes.excluded != 3 {
+		t.Fatalf("expected 3 excluded, found %d", excludes.excluded)
+	}
+}
+
+func TestCopySameSourceTarget(t *testing.T) {
+	var copyRequests []*http.Request
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "HEAD" && r.URL.Path == "/bucket/object":
+			w.Header().Set("Content-Length", "5")
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("ETag", "\"5d41402abc4b2a76b9719d911017c592\"")
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.WriteHeader(http.StatusOK)
+		case r.Method == "HEAD" && r.URL.Path == "/bucket":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == "GET" && (r.URL.Path == "/bucket" || r.URL.Path == "/bucket/"):
+			w.Write([]byte("<ListBucketResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>bucket</Name><KeyCount>1</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>" +
+				"<Contents><Key>object</Key><LastModified>2006-01-02T15:04:05.000Z</LastModified><Size>5</Size></Contents></ListBucketResult>"))
+		case r.Method == "PUT" && r.Header.Get("X-Amz-Copy-Source") != "":
+			copyRequests = append(copyRequests, r)
+			w.Write([]byte("<CopyObjectResult><LastModified>2006-01-02T15:04:05.000Z</LastModified><ETag>\"5d41402abc4b2a76b9719d911017c592\"</ETag></CopyObjectResult>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("cptest", server.URL)()
+
+	tmpDir, e := ioutil.TempDir("", "cp-url-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceFile := filepath.Join(tmpDir, "file.txt")
+	if e = ioutil.WriteFile(sourceFile, []byte("hello"), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	testCases := []struct {
+		sourceURL    string
+		targetURL    string
+		metadata     map[string]string
+		userMetadata map[string]string
+		isSame       bool
+		isInPlace    bool
+	}{
+		{sourceFile, sourceFile, nil, nil, true, false},
+		{sourceFile, sourceFile, nil, map[string]string{"key1": "value1"}, true, false},
+		{sourceFile, tmpDir, nil, nil, true, false},
+		{sourceFile, filepath.Join(tmpDir, "other.txt"), nil, nil, false, false},
+		{"cptest/bucket/object", "cptest/bucket/object", nil, nil, true, false},
+		{"cptest/bucket/object", "cptest/bucket/object", nil, map[string]string{"key1": "value1"}, true, true},
+		{"cptest/bucket/object", "cptest/bucket/", nil, map[string]string{"key1": "value1"}, true, true},
+		{"cptest/bucket/object", "cptest/bucket/other", nil, nil, false, false},
+		// Moved to another storage class with --storage-class.
+		{"cptest/bucket/object", "cptest/bucket/object", map[string]string{"X-Amz-Storage-Class": "STANDARD_IA"}, nil, true, true},
+	}
+
+	for i, testCase := range testCases {
+		copyURLsType, err := guessCopyURLType([]string{testCase.sourceURL}, testCase.targetURL, false, nil)
+		if err != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, err)
+		}
+		var cpURLs URLs
+		if copyURLsType == copyURLsTypeA {
+			cpURLs = prepareCopyURLsTypeA(testCase.sourceURL, testCase.targetURL, nil)
+		} else {
+			cpURLs = prepareCopyURLsTypeB(testCase.sourceURL, testCase.targetURL, nil)
+		}
+		if cpURLs.Error != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, cpURLs.Error)
+		}
+		cpURLs.TargetContent.Metadata = map[string]string{}
+		for k, v := range testCase.metadata {
+			cpURLs.TargetContent.Metadata[k] = v
+		}
+		cpURLs.TargetContent.UserMetadata = map[string]string{}
+		for k, v := range testCase.userMetadata {
+			cpURLs.TargetContent.UserMetadata[k] = v
+		}
+
+		if isSame := isSameSourceTarget(cpURLs); isSame != testCase.isSame {
+			t.Fatalf("Test %d: expected same source and target %t, found %t", i+1, testCase.isSame, isSame)
+		}
+		if !testCase.isSame {
+			continue
+		}
+		if isInPlace := isInPlaceMetadataUpdate(cpURLs); isInPlace != testCase.isInPlace {
+			t.Fatalf("Test %d: expected in-place update %t, found %t", i+1, testCase.isInPlace, isInPlace)
+		}
+		if !testCase.isInPlace {
+			continue
+		}
+
+		copyRequests = nil
+		if cpURLs = uploadSourceToTargetURL(context.Background(), cpURLs, nil, nil); cpURLs.Error != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, cpURLs.Error)
+		}
+		if len(copyRequests) != 1 {
+			t.Fatalf("Test %d: expected a single server side copy, found %d", i+1, len(copyRequests))
+		}
+		r := copyRequests[0]
+		if r.URL.Path != "/bucket/object" {
+			t.Fatalf("Test %d: expected copy onto `/bucket/object`, found `%s`", i+1, r.URL.Path)
+		}
+		if directive := r.Header.Get("X-Amz-Metadata-Directive"); directive != "REPLACE" {
+			t.Fatalf("Test %d: expected metadata directive REPLACE, found `%s`", i+1, directive)
+		}
+		for k, v := range testCase.metadata {
+			if value := r.Header.Get(k); value != v {
+				t.Fatalf("Test %d: expected header `%s: %s`, found `%s`", i+1, k, v, value)
+			}
+		}
+		for k, v := range testCase.userMetadata {
+			if value := r.Header.Get("X-Amz-Meta-" + k); value != v {
+				t.Fatalf("Test %d: expected metadata `%s=%s`, found `%s`", i+1, k, v, value)
+			}
+		}
+	}
+}
+
+func TestExpandBucketPatterns(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" && r.URL.Path == "/" {
+			w.Write([]byte("<ListAllMyBucketsResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Owner><ID>minio</ID><DisplayName>minio</DisplayName></Owner><Buckets>" +
+				"<Bucket><Name>logs-eu</Name><CreationDate>2020-05-21T18:24:21.097Z</CreationDate></Bucket>" +
+				"<Bucket><Name>logs-us</Name><CreationDate>2020-05-21T18:24:21.097Z</CreationDate></Bucket>" +
+				"<Bucket><Name>media</Name><CreationDate>2020-05-21T18:24:21.097Z</CreationDate></Bucket>" +
+				"</Buckets></ListAllMyBucketsResult>"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("cptest", server.URL)()
+
+	testCases := []struct {
+		sourceURLs   []string
+		isAllowEmpty bool
+		expandedURLs []string
+		expectErr    bool
+	}{
+		{[]string{"cptest/logs-*"}, false, []string{"cptest/logs-eu", "cptest/logs-us"}, false},
+		{[]string{"cptest/logs-*/"}, false, []string{"cptest/logs-eu", "cptest/logs-us"}, false},
+		{[]string{"cptest/logs-?u"}, false, []string{"cptest/logs-eu"}, false},
+		{[]string{"cptest/logs-[^e]*", "cptest/media/"}, false, []string{"cptest/logs-us", "cptest/media/"}, false},
+		// Patterns in object names and local paths are left alone.
+		{[]string{"cptest/media/*.jpg"}, false, []string{"cptest/media/*.jpg"}, false},
+		{[]string{"local/logs-*"}, false, []string{"local/logs-*"}, false},
+		{[]string{"cptest/backup-*"}, false, nil, true},
+		{[]string{"cptest/backup-*"}, true, nil, false},
+		{[]string{"cptest/logs-["}, false, nil, true},
+		{[]string{"cptest/logs-*/2020/"}, false, nil, true},
+	}
+
+	for i, testCase := range testCases {
+		expandedURLs, err := expandBucketPatterns(testCase.sourceURLs, testCase.isAllowEmpty)
+		if testCase.expectErr != (err != nil) {
+			t.Fatalf("Test %d: expected error %t, found %v", i+1, testCase.expectErr, err)
+		}
+		if !reflect.DeepEqual(expandedURLs, testCase.expandedURLs) {
+			t.Fatalf("Test %d: expected %v, found %v", i+1, testCase.expandedURLs, expandedURLs)
+		}
+	}
+}
+
+func TestPrepareCopyURLsTypeDListWorkers(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	tmpDir, e := ioutil.TempDir("", "cp-url-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var sourceURLs []string
+	expected := map[string]string{}
+	for _, dir := range []string{"a", "b", "c", "d", "e"} {
+		for _, name := range []string{"1.txt", "2.txt"} {
+			sourcePath := filepath.Join(tmpDir, "src", dir, name)
+			if e = os.MkdirAll(filepath.Dir(sourcePath), 0755); e != nil {
+				t.Fatal(e)
+			}
+			if e = ioutil.WriteFile(sourcePath, []byte(dir+name), 0644); e != nil {
+				t.Fatal(e)
+			}
+			expected[sourcePath] = filepath.Join(tmpDir, "target", dir, name)
+		}
+		sourceURLs = append(sourceURLs, filepath.Join(tmpDir, "src", dir))
+	}
+	// A missing source is reported along with the others.
+	missingURL := filepath.Join(tmpDir, "src", "missing")
+	sourceURLs = append(sourceURLs, missingURL)
+	targetURL := filepath.Join(tmpDir, "target")
+
+	for _, listWorkers := range []int{0, 1, 3, 10} {
+		found := map[string]string{}
+		var errs []*probe.Error
+		for cpURLs := range prepareCopyURLsTypeD(sourceURLs, targetURL, true, false, nil, listWorkers, "", nil) {
+			if cpURLs.Error != nil {
+				errs = append(errs, cpURLs.Error)
+				continue
+			}
+			found[cpURLs.SourceContent.URL.Path] = cpURLs.TargetContent.URL.Path
+		}
+		if !reflect.DeepEqual(found, expected) {
+			t.Fatalf("%d workers: expected %v, found %v", listWorkers, expected, found)
+		}
+		if len(errs) != 1 || !strings.Contains(fmt.Sprint(errs[0].CallTrace), missingURL) {
+			t.Fatalf("%d workers: expected an error for `%s`, found %v", listWorkers, missingURL, errs)
+		}
+	}
+}
+
+func TestCopyURLsTypeCSlashConflict(t *testing.T) {
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		var contents string
+		for _, key := range []string{"bar", "foo", "foo-bar", "foo/bar", "foo/baz/qux", "fooz"} {
+			contents += "<Contents><Key>" + key + "</Key><LastModified>2020-01-01T00:00:00.000Z</LastModified><ETag>\"etag\"</ETag><Size>5</Size><StorageClass>STANDARD</StorageClass></Contents>"
+		}
+		w.Write([]byte("<ListBucketResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>bucket</Name><KeyCount>6</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>" + contents + "</ListBucketResult>"))
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("cptest", server.URL)()
+
+	tmpDir, e := ioutil.TempDir("", "cp-url-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	testCases := []struct {
+		slashConflict string
+		targets       []string
+	}{
+		{"", []string{"bar", "foo", "foo-bar", "foo/bar", "foo/baz/qux", "fooz"}},
+		{slashConflictSkip, []string{"bar", "foo-bar", "foo/bar", "foo/baz/qux", "fooz"}},
+		// Renamed objects wait for the listing to pass their new key.
+		{slashConflictRename, []string{"bar", "foo-bar", "foo/bar", "foo" + slashConflictSuffix, "foo/baz/qux", "fooz"}},
+	}
+
+	for i, testCase := range testCases {
+		var targets []string
+		for cpURLs := range prepareCopyURLsTypeC("cptest/bucket", tmpDir, true, false, nil, testCase.slashConflict, nil) {
+			if cpURLs.Error != nil {
+				t.Fatalf("Test %d: unexpected error %s", i+1, cpURLs.Error)
+			}
+			target := strings.TrimPrefix(cpURLs.TargetContent.URL.Path, filepath.Join(tmpDir, "bucket")+string(filepath.Separator))
+			targets = append(targets, filepath.ToSlash(target))
+		}
+		if !reflect.DeepEqual(targets, testCase.targets) {
+			t.Fatalf("Test %d: expected targets %v, found %v", i+1, testCase.targets, targets)
+		}
+	}
+}
+
+func TestSlashConflictFilter(t *testing.T) {
+	testCases := []struct {
+		resolution string
+		keys       []string
+		targets    []string
+	}{
+		// Every held back object is checked, not only the first one.
+		{slashConflictSkip, []string{"a", "a-b", "a-b/c", "a/x"}, []string{"a-b/c", "a/x"}},
+		{slashConflictRename, []string{"a", "a-b", "a-b/c", "a/x"}, []string{"a-b" + slashConflictSuffix, "a-b/c", "a/x", "a" + slashConflictSuffix}},
+		// A source object already named like the renamed one wins.
+		{slashConflictRename, []string{"foo", "foo/bar", "foo" + slashConflictSuffix}, []string{"foo/bar", "foo" + slashConflictSuffix}},
+		// Passing the folders of parent prefixes at once, in the order listed.
+		{slashConflictSkip, []string{"a", "a-b", "a-b-c", "b", "b/c"}, []string{"a", "a-b", "a-b-c", "b/c"}},
+	}
+	for i, testCase := range testCases {
+		filter := &slashConflictFilter{resolution: testCase.resolution}
+		var ready []URLs
+		for _, key := range testCase.keys {
+			ready = append(ready, filter.add(URLs{
+				SourceContent: &clientContent{URL: *newClientURL("/bucket/" + key)},
+				TargetContent: &clientContent{URL: *newClientURL("/target/" + key)},
+			})...)
+		}
+		ready = append(ready, filter.flush()...)
+		var targets []string
+		for _, cpURLs := range ready {
+			targets = append(targets, strings.TrimPrefix(cpURLs.TargetContent.URL.Path, "/target/"))
+		}
+		if !reflect.DeepEqual(targets, testCase.targets) {
+			t.Fatalf("Test %d: expected targets %v, found %v", i+1, testCase.targets, targets)
+		}
+	}
+
+	// Objects which are no parent prefix of the next are not held back.
+	filter := &slashConflictFilter{resolution: slashConflictSkip}
+	for i := 0; i < 1000; i++ {
+		filter.add(URLs{
+			SourceContent: &clientContent{URL: *newClientURL(fmt.Sprintf("/bucket/dir/%04d", i))},
+			TargetContent: &clientContent{URL: *newClientURL(fmt.Sprintf("/target/dir/%04d", i))},
+		})
+		if len(filter.pending) != 1 {
+			t.Fatalf("expected a single object held back, found %d", len(filter.pending))
+		}
+	}
+}
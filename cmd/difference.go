@@ -37,6 +37,7 @@ const (
 	differInSize                       // differs in size
 	differInMetadata                   // differs in metadata
 	differInType                       // differs in type, exfile/directory
+	differInTime                       // source was modified after target
 	differInFirst                      // only in source (FIRST)
 	differInSecond                     // only in target (SECOND)
 )
@@ -51,6 +52,8 @@ func (d differType) String() string {
 		return "metadata"
 	case differInType:
 		return "type"
+	case differInTime:
+		return "time"
 	case differInFirst:
 		return "only-in-first"
 	case differInSecond:
@@ -62,6 +65,23 @@ func (d differType) String() string {
 const multiMasterETagKey = "X-Amz-Meta-Mm-Etag"
 const multiMasterSTagKey = "X-Amz-Meta-Mm-Stag"
 
+// mtimeMetaKey carries the source modification time of objects copied
+// with --preserve-mtime, since S3 does not allow setting last-modified.
+const mtimeMetaKey = "X-Amz-Meta-Mc-Mtime"
+
+// getContentModTime returns the preserved modification time of an entry if
+// present, otherwise the last-modified time reported by the backend.
+func getContentModTime(c *clientContent) time.Time {
+	for _, m := range []map[string]string{c.UserMetadata, c.Metadata} {
+		if v, ok := m[mtimeMetaKey]; ok {
+			if t, e := time.Parse(time.RFC3339Nano, v); e == nil {
+				return t
+			}
+		}
+	}
+	return c.Time
+}
+
 func eTagMatch(src, tgt *clientContent) bool {
 	if tgt.UserMetadata[multiMasterETagKey] != "" {
 		if tgt.UserMetadata[multiMasterETagKey] == src.UserMetadata[multiMasterETagKey] || tgt.UserMetadata[multiMasterETagKey] == src.ETag {
@@ -73,12 +93,17 @@ func eTagMatch(src, tgt *clientContent) bool {
 			return true
 		}
 	}
+	// Filesystem entries carry no ETag, an empty match says nothing
+	// about the content so let the size comparison decide.
+	if src.ETag == "" || tgt.ETag == "" {
+		return false
+	}
 	return src.ETag == tgt.ETag
 }
 
 func metadataEqual(m1, m2 map[string]string) bool {
 	for k, v := range m1 {
-		if k == multiMasterETagKey || k == multiMasterSTagKey {
+		if k == multiMasterETagKey || k == multiMasterSTagKey || k == mtimeMetaKey {
 			continue
 		}
 		if m2[k] != v {
@@ -86,7 +111,7 @@ func metadataEqual(m1, m2 map[string]string) bool {
 		}
 	}
 	for k, v := range m2 {
-		if k == multiMasterETagKey || k == multiMasterSTagKey {
+		if k == multiMasterETagKey || k == multiMasterSTagKey || k == mtimeMetaKey {
 			continue
 		}
 		if m1[k] != v {
@@ -96,15 +121,15 @@ func metadataEqual(m1, m2 map[string]string) bool {
 	return true
 }
 
-func objectDifference(sourceClnt, targetClnt Client, sourceURL, targetURL string, isMetadata bool) (diffCh chan diffMessage) {
-	return difference(sourceClnt, targetClnt, sourceURL, targetURL, isMetadata, true, false, DirNone)
+func objectDifference(sourceClnt, targetClnt Client, sourceURL, targetURL string, isMetadata, isMtime bool) (diffCh chan diffMessage) {
+	return difference(sourceClnt, targetClnt, sourceURL, targetURL, isMetadata, isMtime, true, false, DirNone)
 }
 
 func dirDifference(sourceClnt, targetClnt Client, sourceURL, targetURL string) (diffCh chan diffMessage) {
-	return difference(sourceClnt, targetClnt, sourceURL, targetURL, false, false, true, DirFirst)
+	return difference(sourceClnt, targetClnt, sourceURL, targetURL, false, false, false, true, DirFirst)
 }
 
-func differenceInternal(sourceClnt, targetClnt Client, sourceURL, targetURL string, isMetadata bool, isRecursive, returnSimilar bool, dirOpt DirOpt, diffCh chan<- diffMessage) *probe.Error {
+func differenceInternal(sourceClnt, targetClnt Client, sourceURL, targetURL string, isMetadata, isMtime bool, isRecursive, returnSimilar bool, dirOpt DirOpt, diffCh chan<- diffMessage) *probe.Error {
 	// Set default values for listing.
 	isIncomplete := false // we will not compare any incomplete objects.
 	srcCh := sourceClnt.List(isRecursive, isIncomplete, isMetadata, dirOpt)
@@ -203,8 +228,11 @@ func differenceInternal(sourceClnt, targetClnt Client, sourceURL, targetURL stri
 					firstContent:  srcCtnt,
 					secondContent: tgtCtnt,
 				}
+				srcCtnt, srcOk = <-srcCh
+				tgtCtnt, tgtOk = <-tgtCh
 				continue
 			}
+			differed := true
 			if eTagMatch(srcCtnt, tgtCtnt) {
 				// If ETag matches, only thing that can differ is metadata.
 				if isMetadata &&
@@ -218,6 +246,8 @@ func differenceInternal(sourceClnt, targetClnt Client, sourceURL, targetURL stri
 						firstContent:  srcCtnt,
 						secondContent: tgtCtnt,
 					}
+				} else {
+					differed = false
 				}
 			} else if (srcType.IsRegular() && tgtType.IsRegular()) && srcSize != tgtSize {
 				// Regular files differing in size.
@@ -228,6 +258,15 @@ func differenceInternal(sourceClnt, targetClnt Client, sourceURL, targetURL stri
 					firstContent:  srcCtnt,
 					secondContent: tgtCtnt,
 				}
+			} else if isMtime && getContentModTime(srcCtnt).Truncate(time.Second).After(getContentModTime(tgtCtnt).Truncate(time.Second)) {
+				// Source modified after the time preserved on target.
+				diffCh <- diffMessage{
+					FirstURL:      srcCtnt.URL.String(),
+					SecondURL:     tgtCtnt.URL.String(),
+					Diff:          differInTime,
+					firstContent:  srcCtnt,
+					secondContent: tgtCtnt,
+				}
 			} else if isMetadata &&
 				!metadataEqual(srcCtnt.UserMetadata, tgtCtnt.UserMetadata) &&
 				!metadataEqual(srcCtnt.Metadata, tgtCtnt.Metadata) {
@@ -240,10 +279,12 @@ func differenceInternal(sourceClnt, targetClnt Client, sourceURL, targetURL stri
 					firstContent:  srcCtnt,
 					secondContent: tgtCtnt,
 				}
+			} else {
+				differed = false
 			}
 
 			// No differ
-			if returnSimilar {
+			if returnSimilar && !differed {
 				diffCh <- diffMessage{
 					FirstURL:      srcCtnt.URL.String(),
 					SecondURL:     tgtCtnt.URL.String(),
@@ -271,7 +312,7 @@ func differenceInternal(sourceClnt, targetClnt Client, sourceURL, targetURL stri
 
 // objectDifference function finds the difference between all objects
 // recursively in sorted order from source and target.
-func difference(sourceClnt, targetClnt Client, sourceURL, targetURL string, isMetadata bool, isRecursive, returnSimilar bool, dirOpt DirOpt) (diffCh chan diffMessage) {
+func difference(sourceClnt, targetClnt Client, sourceURL, targetURL string, isMetadata, isMtime bool, isRecursive, returnSimilar bool, dirOpt DirOpt) (diffCh chan diffMessage) {
 	diffCh = make(chan diffMessage, 10000)
 
 	go func() {
@@ -282,7 +323,7 @@ func difference(sourceClnt, targetClnt Client, sourceURL, targetURL string, isMe
 
 		for range newRetryTimerContinous(time.Second, time.Second*30, minio.MaxJitter, doneCh) {
 			err := differenceInternal(sourceClnt, targetClnt, sourceURL, targetURL,
-				isMetadata, isRecursive, returnSimilar, dirOpt, diffCh)
+				isMetadata, isMtime, isRecursive, returnSimilar, dirOpt, diffCh)
 			if err != nil {
 				// handle this specifically for filesystem related errors.
 				switch err.ToGoError().(type) {
@@ -17,7 +17,12 @@
 package cmd
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 var testCases = []struct {
@@ -37,6 +42,10 @@ var testCases = []struct {
 	{[]string{"*.txt"}, "file/abc/bcd/def.txt", true},
 	{[]string{".*"}, ".sys", true},
 	{[]string{"*."}, ".sys.", true},
+	{[]string{"node_modules/"}, "node_modules/a/b.js", true},
+	{[]string{"node_modules/"}, "node_modules", false},
+	{[]string{"node_modules/"}, "src/node_modules/a.js", false},
+	{[]string{"*/node_modules/"}, "src/node_modules/a.js", true},
 }
 
 func TestExcludeOptions(t *testing.T) {
@@ -46,3 +55,133 @@ func TestExcludeOptions(t *testing.T) {
 		}
 	}
 }
+
+func TestContentModTime(t *testing.T) {
+	lastModified := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	preserved := time.Date(2019, 1, 2, 3, 4, 5, 6, time.UTC)
+	testCases := []struct {
+		content  *clientContent
+		expected time.Time
+	}{
+		{&clientContent{Time: lastModified}, lastModified},
+		{&clientContent{Time: lastModified, UserMetadata: map[string]string{mtimeMetaKey: preserved.Format(time.RFC3339Nano)}}, preserved},
+		{&clientContent{Time: lastModified, Metadata: map[string]string{mtimeMetaKey: preserved.Format(time.RFC3339Nano)}}, preserved},
+		{&clientContent{Time: lastModified, UserMetadata: map[string]string{mtimeMetaKey: "garbage"}}, lastModified},
+	}
+	for i, testCase := range testCases {
+		if got := getContentModTime(testCase.content); !got.Equal(testCase.expected) {
+			t.Fatalf("Test %d: expected %s, got %s", i+1, testCase.expected, got)
+		}
+	}
+}
+
+func TestObjectDifferenceModTime(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	tmpDir, e := ioutil.TempDir("", "difference-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceDir, targetDir := filepath.Join(tmpDir, "source"), filepath.Join(tmpDir, "target")
+	hourAgo := time.Now().Add(-time.Hour)
+	for _, dir := range []string{sourceDir, targetDir} {
+		if e = os.Mkdir(dir, 0755); e != nil {
+			t.Fatal(e)
+		}
+		for _, name := range []string{"same", "changed"} {
+			if e = ioutil.WriteFile(filepath.Join(dir, name), []byte("data"), 0644); e != nil {
+				t.Fatal(e)
+			}
+		}
+	}
+	// Written to the target after the source was last modified.
+	if e = os.Chtimes(filepath.Join(sourceDir, "same"), hourAgo, hourAgo); e != nil {
+		t.Fatal(e)
+	}
+	if e = os.Chtimes(filepath.Join(targetDir, "changed"), hourAgo, hourAgo); e != nil {
+		t.Fatal(e)
+	}
+
+	sourceClnt, err := newClient(sourceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetClnt, err := newClient(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, isMtime := range []bool{false, true} {
+		diffs := make(map[string]differType)
+		for diff := range objectDifference(sourceClnt, targetClnt, sourceDir, targetDir, false, isMtime) {
+			if diff.Error != nil {
+				t.Fatal(diff.Error)
+			}
+			diffs[filepath.Base(diff.FirstURL)] = diff.Diff
+		}
+		expected := differInNone
+		if isMtime {
+			expected = differInTime
+		}
+		if diffs["same"] != differInNone || diffs["changed"] != expected {
+			t.Fatalf("isMtime %t: unexpected differences %v", isMtime, diffs)
+		}
+	}
+}
+
+func TestDifferenceReturnSimilar(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	tmpDir, e := ioutil.TempDir("", "difference-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceDir, targetDir := filepath.Join(tmpDir, "source")+"/", filepath.Join(tmpDir, "target")+"/"
+	for path, data := range map[string]string{
+		sourceDir + "same":  "data",
+		sourceDir + "size":  "data",
+		targetDir + "same":  "data",
+		targetDir + "size":  "longer data",
+		targetDir + "extra": "extra",
+	} {
+		if e = os.MkdirAll(filepath.Dir(path), 0755); e != nil {
+			t.Fatal(e)
+		}
+		if e = ioutil.WriteFile(path, []byte(data), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	sourceClnt, err := newClient(sourceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetClnt, err := newClient(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Entries without an ETag are compared by size, and every entry is
+	// reported once, either as a difference or as similar.
+	diffs := make(map[string][]differType)
+	for diff := range difference(sourceClnt, targetClnt, sourceDir, targetDir, false, false, true, true, DirNone) {
+		if diff.Error != nil {
+			t.Fatal(diff.Error)
+		}
+		name := filepath.Base(diff.FirstURL)
+		if diff.FirstURL == "" {
+			name = filepath.Base(diff.SecondURL)
+		}
+		diffs[name] = append(diffs[name], diff.Diff)
+	}
+	expected := map[string][]differType{
+		"same":  {differInNone},
+		"size":  {differInSize},
+		"extra": {differInSecond},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Fatalf("expected %v, got %v", expected, diffs)
+	}
+}
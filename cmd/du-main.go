@@ -18,10 +18,9 @@ package cmd
 
 import (
 	"fmt"
-	"net/url"
+	"path"
 	"strings"
 
-	humanize "github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/minio/cli"
 	json "github.com/minio/mc/pkg/colorjson"
@@ -68,6 +67,9 @@ EXAMPLES:
 
    2. Summarize disk usage of 'louis' prefix in 'jazz-songs' bucket upto two levels.
       {{.Prompt}} {{.HelpName}} --depth=2 s3/jazz-songs/louis/
+
+   3. Summarize disk usage of every folder in 'jazz-songs' bucket, in JSON.
+      {{.Prompt}} {{.HelpName}} --json --recursive s3/jazz-songs
 `,
 }
 
@@ -80,7 +82,7 @@ type duMessage struct {
 
 // Colorized message for console printing.
 func (r duMessage) String() string {
-	humanSize := strings.Join(strings.Fields(humanize.IBytes(uint64(r.Size))), "")
+	humanSize := humanizeSize(r.Size)
 
 	return fmt.Sprintf("%s\t%s", console.Colorize("Size", humanSize),
 		console.Colorize("Prefix", r.Prefix))
@@ -93,6 +95,86 @@ func (r duMessage) JSON() string {
 	return string(msgBytes)
 }
 
+// duPrefix is the running total of a folder prefix whose objects
+// are still being listed.
+type duPrefix struct {
+	name string
+	size int64
+}
+
+// duAccumulator sums object sizes streamed from a recursive listing
+// into their folder prefixes. Listings visit each prefix contiguously,
+// so a prefix is complete, and printed, as soon as an object outside
+// of it shows up. Only prefixes up to depth levels are kept in memory.
+type duAccumulator struct {
+	depth    int
+	prefixes []duPrefix
+	printFn  func(prefix string, size int64)
+}
+
+func newDuAccumulator(root string, depth int, printFn func(prefix string, size int64)) *duAccumulator {
+	return &duAccumulator{
+		depth:    depth,
+		prefixes: []duPrefix{{name: root}},
+		printFn:  printFn,
+	}
+}
+
+// add accounts size to the object at objectPath, a '/' separated path
+// relative to the root prefix.
+func (d *duAccumulator) add(objectPath string, size int64) {
+	dirs := strings.Split(objectPath, "/")
+	dirs = dirs[:len(dirs)-1]
+	// Sub-prefixes from depth onwards are never printed, their
+	// sizes are accounted to the deepest printed prefix instead.
+	if d.depth >= 0 {
+		maxDirs := d.depth - 1
+		if maxDirs < 0 {
+			maxDirs = 0
+		}
+		if len(dirs) > maxDirs {
+			dirs = dirs[:maxDirs]
+		}
+	}
+
+	// Close the open prefixes this object is not part of.
+	common := 0
+	for common < len(dirs) && common+1 < len(d.prefixes) &&
+		d.prefixes[common+1].name == path.Join(d.prefixes[common].name, dirs[common]) {
+		common++
+	}
+	for len(d.prefixes) > common+1 {
+		d.pop()
+	}
+	for _, dir := range dirs[common:] {
+		parent := d.prefixes[len(d.prefixes)-1]
+		d.prefixes = append(d.prefixes, duPrefix{name: path.Join(parent.name, dir)})
+	}
+	d.prefixes[len(d.prefixes)-1].size += size
+}
+
+// pop closes the innermost open prefix and adds its total to its parent.
+func (d *duAccumulator) pop() {
+	last := d.prefixes[len(d.prefixes)-1]
+	d.prefixes = d.prefixes[:len(d.prefixes)-1]
+	if len(d.prefixes) > 0 {
+		d.prefixes[len(d.prefixes)-1].size += last.size
+	}
+	if d.depth != 0 {
+		d.printFn(last.name, last.size)
+	}
+}
+
+// close flushes all open prefixes and returns the total of the root prefix.
+func (d *duAccumulator) close() int64 {
+	for len(d.prefixes) > 1 {
+		d.pop()
+	}
+	total := d.prefixes[0].size
+	d.pop()
+	return total
+}
+
 func du(urlStr string, depth int, encKeyDB map[string][]prefixSSEPair) (int64, error) {
 	targetAlias, targetURL, _ := mustExpandAlias(urlStr)
 	if !strings.HasSuffix(targetURL, "/") {
@@ -105,11 +187,21 @@ func du(urlStr string, depth int, encKeyDB map[string][]prefixSSEPair) (int64, e
 		return 0, exitStatus(globalErrorExitStatus) // End of journey.
 	}
 
-	isRecursive := false
+	rootURL := clnt.GetURL()
+	root := strings.Trim(strings.Replace(rootURL.Path, string(rootURL.Separator), "/", -1), "/")
+	acc := newDuAccumulator(root, depth, func(prefix string, size int64) {
+		printMsg(duMessage{
+			Prefix: prefix,
+			Size:   size,
+			Status: "success",
+		})
+	})
+
+	// A single recursive listing, sizes are accumulated as objects
+	// stream in instead of listing every folder on its own.
+	isRecursive := true
 	isIncomplete := false
-	contentCh := clnt.List(isRecursive, isIncomplete, false, DirFirst)
-	size := int64(0)
-	for content := range contentCh {
+	for content := range clnt.List(isRecursive, isIncomplete, false, DirNone) {
 		if content.Err != nil {
 			switch content.Err.ToGoError().(type) {
 			// handle this specifically for filesystem related errors.
@@ -122,44 +214,13 @@ func du(urlStr string, depth int, encKeyDB map[string][]prefixSSEPair) (int64, e
 			errorIf(content.Err.Trace(urlStr), "Failed to find disk usage of `"+urlStr+"` recursively.")
 			return 0, exitStatus(globalErrorExitStatus)
 		}
-		if content.URL.String() == targetURL {
-			continue
-		}
-
 		if content.Type.IsDir() {
-			depth := depth
-			if depth > 0 {
-				depth--
-			}
-
-			subDirAlias := content.URL.Path
-			if targetAlias != "" {
-				subDirAlias = targetAlias + "/" + content.URL.Path
-			}
-			used, err := du(subDirAlias, depth, encKeyDB)
-			if err != nil {
-				return 0, err
-			}
-			size += used
-		} else {
-			size += content.Size
-		}
-	}
-
-	if depth != 0 {
-		u, err := url.Parse(targetURL)
-		if err != nil {
-			panic(err)
+			continue
 		}
-
-		printMsg(duMessage{
-			Prefix: strings.Trim(u.Path, "/"),
-			Size:   size,
-			Status: "success",
-		})
+		acc.add(sourceSuffix(rootURL, content), content.Size)
 	}
 
-	return size, nil
+	return acc.close(), nil
 }
 
 // main for du command.
@@ -0,0 +1,68 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDuAccumulator(t *testing.T) {
+	type object struct {
+		path string
+		size int64
+	}
+	objects := []object{
+		{"a/x", 1},
+		{"a/b/y", 2},
+		{"a/b/c/z", 4},
+		{"a/d/w", 8},
+		{"e", 16},
+		{"f/g/v", 32},
+	}
+	testCases := []struct {
+		depth    int
+		expected []string
+	}{
+		// Nothing is printed.
+		{0, nil},
+		// Only the total of the root prefix.
+		{1, []string{"bucket=63"}},
+		// Sub-prefixes one level below the root.
+		{2, []string{"bucket/a=15", "bucket/f=32", "bucket=63"}},
+		// Every prefix, children before their parents.
+		{-1, []string{"bucket/a/b/c=4", "bucket/a/b=6", "bucket/a/d=8", "bucket/a=15",
+			"bucket/f/g=32", "bucket/f=32", "bucket=63"}},
+	}
+
+	for i, testCase := range testCases {
+		var printed []string
+		acc := newDuAccumulator("bucket", testCase.depth, func(prefix string, size int64) {
+			printed = append(printed, prefix+"="+strconv.FormatInt(size, 10))
+		})
+		for _, o := range objects {
+			acc.add(o.path, o.size)
+		}
+		if total := acc.close(); total != 63 {
+			t.Errorf("Test %d: expected total 63, got %d", i+1, total)
+		}
+		if !reflect.DeepEqual(printed, testCase.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.expected, printed)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+// errorReport - failures of a run collected for `--error-report`, and
+// written as a single JSON document once the run is over.
+type errorReport struct {
+	mutex   sync.Mutex
+	file    *os.File
+	Command string             `json:"command"`
+	Start   time.Time          `json:"start"`
+	End     time.Time          `json:"end"`
+	Total   int                `json:"total"`
+	Errors  []errorReportEntry `json:"errors"`
+}
+
+// errorReportEntry - a single failure, operation is one of "list",
+// "get", "put", "copy" or "delete".
+type errorReportEntry struct {
+	URL       string             `json:"url,omitempty"`
+	Operation string             `json:"operation"`
+	Attempts  int                `json:"attempts"`
+	Message   string             `json:"message"`
+	Trace     []probe.TracePoint `json:"trace,omitempty"`
+}
+
+// Report of the running command, nil when none was asked for.
+var globalErrorReport *errorReport
+
+// add records a failure, it does nothing without a report. Failures
+// without an operation are reported as copies.
+func (r *errorReport) add(URL, operation string, attempts int, err *probe.Error) {
+	if r == nil || err == nil {
+		return
+	}
+	if operation == "" {
+		operation = "copy"
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Errors = append(r.Errors, errorReportEntry{
+		URL:       URL,
+		Operation: operation,
+		Attempts:  attempts,
+		Message:   err.ToGoError().Error(),
+		Trace:     err.CallTrace,
+	})
+}
+
+// startErrorReport sets up globalErrorReport for command, the file at
+// path is created right away to fail early. The returned function
+// writes the report.
+func startErrorReport(command, path string) (func(), *probe.Error) {
+	f, e := os.Create(path)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	globalErrorReport = &errorReport{Command: command, Start: UTCNow(), file: f}
+	return globalErrorReport.write, nil
+}
+
+// write saves the report once, it must also be called before exiting
+// on a critical error or an interruption.
+func (r *errorReport) write() {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		return
+	}
+	defer func() {
+		r.file.Close()
+		r.file = nil
+	}()
+
+	r.End = UTCNow()
+	r.Total = len(r.Errors)
+	if r.Errors == nil {
+		r.Errors = []errorReportEntry{}
+	}
+	data, e := json.MarshalIndent(r, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal error report.")
+	_, e = r.file.Write(append(data, '\n'))
+	errorIf(probe.NewError(e).Trace(r.file.Name()), "Unable to write error report.")
+}
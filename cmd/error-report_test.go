@@ -0,0 +1,131 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+func TestErrorReport(t *testing.T) {
+	dir, e := ioutil.TempDir("", "mc-error-report-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { globalErrorReport = nil }()
+
+	path := filepath.Join(dir, "report.json")
+	writeReport, err := startErrorReport("cp", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	globalErrorReport.add("s3/bucket/a", "get", 3, probe.NewError(errors.New("read failed")).Trace("s3/bucket/a"))
+	globalErrorReport.add("", "list", 0, probe.NewError(errors.New("no such bucket")))
+	globalErrorReport.add("s3/bucket/b", "put", 1, nil)
+	globalErrorReport.add("s3/bucket/c", "", 1, probe.NewError(errors.New("hook failed")))
+	writeReport()
+	// A second write must not truncate the report.
+	writeReport()
+
+	data, e := ioutil.ReadFile(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var report struct {
+		Command string
+		Total   int
+		Errors  []errorReportEntry
+	}
+	if e = json.Unmarshal(data, &report); e != nil {
+		t.Fatal(e)
+	}
+	if report.Command != "cp" || report.Total != 3 || len(report.Errors) != 3 {
+		t.Fatalf("unexpected report: %s", data)
+	}
+	first := report.Errors[0]
+	if first.URL != "s3/bucket/a" || first.Operation != "get" || first.Attempts != 3 ||
+		first.Message != "read failed" || len(first.Trace) == 0 {
+		t.Errorf("unexpected first entry: %+v", first)
+	}
+	if second := report.Errors[1]; second.Operation != "list" || second.Attempts != 1 {
+		t.Errorf("unexpected second entry: %+v", second)
+	}
+	if third := report.Errors[2]; third.Operation != "copy" {
+		t.Errorf("unexpected third entry: %+v", third)
+	}
+}
+
+func TestTransferOperation(t *testing.T) {
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	dir, e := ioutil.TempDir("", "mc-error-report-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("ertest", server.URL)()
+
+	source := filepath.Join(dir, "source")
+	if e = ioutil.WriteFile(source, []byte("hello"), 0644); e != nil {
+		t.Fatal(e)
+	}
+	testCases := []struct {
+		source      string
+		targetAlias string
+		target      string
+		operation   string
+	}{
+		{filepath.Join(dir, "missing"), "ertest", server.URL + "/bucket/object", "get"},
+		{source, "ertest", server.URL + "/bucket/object", "put"},
+		// Local files are copied by the filesystem, under a file here.
+		{source, "", filepath.Join(source, "target"), "copy"},
+	}
+	for i, testCase := range testCases {
+		urls := URLs{
+			SourceContent: &clientContent{URL: *newClientURL(testCase.source), Size: 5},
+			TargetAlias:   testCase.targetAlias,
+			TargetContent: &clientContent{URL: *newClientURL(testCase.target), Metadata: map[string]string{}},
+		}
+		urls = uploadSourceToTargetURL(context.Background(), urls, nil, nil)
+		if urls.Error == nil {
+			t.Fatalf("Test %d: expected an error", i+1)
+		}
+		if urls.operation != testCase.operation {
+			t.Errorf("Test %d: expected operation %s, found %s", i+1, testCase.operation, urls.operation)
+		}
+	}
+}
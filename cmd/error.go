@@ -155,3 +155,34 @@ func errorIf(err *probe.Error, msg string, data ...interface{}) {
 	}
 	console.Errorln(fmt.Sprintf("%s %s", msg, err))
 }
+
+// warningIf prints a message for err which does not fail the command,
+// such as an object skipped on purpose.
+func warningIf(err *probe.Error, msg string, data ...interface{}) {
+	if err == nil {
+		return
+	}
+	if globalJSON {
+		json, e := json.MarshalIndent(struct {
+			Status  string       `json:"status"`
+			Warning errorMessage `json:"warning"`
+		}{
+			Status: "warning",
+			Warning: errorMessage{
+				Message: fmt.Sprintf(msg, data...),
+				Type:    "warning",
+				Cause: causeMessage{
+					Message: err.ToGoError().Error(),
+					Error:   err.ToGoError(),
+				},
+				SysInfo: err.SysInfo,
+			},
+		}, "", " ")
+		if e != nil {
+			console.Fatalln(probe.NewError(e))
+		}
+		console.Println(string(json))
+		return
+	}
+	console.Infoln(fmt.Sprintf("%s %s", fmt.Sprintf(msg, data...), err.ToGoError()))
+}
@@ -0,0 +1,93 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+// excludeFilter skips the source entries matching the --exclude
+// patterns, counting them to report once the command is over.
+type excludeFilter struct {
+	excluded int64 // keep first for 64bit alignment, see ParallelManager
+	patterns []string
+}
+
+// newExcludeFilter returns nil, which excludes nothing, without patterns.
+func newExcludeFilter(patterns []string) *excludeFilter {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return &excludeFilter{patterns: patterns}
+}
+
+// skip returns true and counts srcSuffix when it matches a pattern,
+// srcSuffix is the path of the entry relative to the source.
+func (f *excludeFilter) skip(srcSuffix string) bool {
+	if f == nil || !matchExcludeOptions(f.patterns, srcSuffix) {
+		return false
+	}
+	atomic.AddInt64(&f.excluded, 1)
+	return true
+}
+
+// matches is skip without counting, for entries which are not sources.
+func (f *excludeFilter) matches(suffix string) bool {
+	return f != nil && matchExcludeOptions(f.patterns, suffix)
+}
+
+// printExcluded prints the number of entries skipped, if any.
+func (f *excludeFilter) printExcluded() {
+	if f == nil {
+		return
+	}
+	if excluded := atomic.LoadInt64(&f.excluded); excluded > 0 {
+		printMsg(excludeMessage{Excluded: excluded})
+	}
+}
+
+// excludeMessage container for the number of excluded entries.
+type excludeMessage struct {
+	Status   string `json:"status"`
+	Excluded int64  `json:"excluded"`
+}
+
+// String colorized exclude message.
+func (e excludeMessage) String() string {
+	return console.Colorize("Excluded", fmt.Sprintf("Excluded %d object(s) matching --exclude.", e.Excluded))
+}
+
+// JSON jsonified exclude message.
+func (e excludeMessage) JSON() string {
+	e.Status = "success"
+	msgBytes, err := json.MarshalIndent(e, "", " ")
+	fatalIf(probe.NewError(err), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
+
+// sourceSuffix returns the path of content relative to the listed
+// sourceURL, with forward slashes.
+func sourceSuffix(sourceURL clientURL, content *clientContent) string {
+	suffix := strings.TrimPrefix(content.URL.Path, sourceURL.Path)
+	return strings.TrimPrefix(strings.Replace(suffix, string(sourceURL.Separator), "/", -1), "/")
+}
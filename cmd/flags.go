@@ -54,6 +54,78 @@ var globalFlags = []cli.Flag{
 		Name:  "insecure",
 		Usage: "disable SSL certificate verification",
 	},
+	cli.StringFlag{
+		Name:  "ca-cert",
+		Usage: "PEM file of CA certificates trusted for all hosts, overrides the CA certificate of the host config",
+	},
+	cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "maximum time to wait on the network for a connection, response headers or body data, the more specific timeouts take precedence",
+	},
+	cli.DurationFlag{
+		Name:  "connect-timeout",
+		Usage: "maximum time to wait for a TCP connection to be established",
+		Value: defaultConnectTimeout,
+	},
+	cli.DurationFlag{
+		Name:  "response-header-timeout",
+		Usage: "maximum time to wait for response headers once a request is written, 0 disables it",
+	},
+	cli.DurationFlag{
+		Name:  "read-timeout",
+		Usage: "maximum time to wait for data while reading a response body, 0 disables it",
+	},
+	cli.DurationFlag{
+		Name:  "request-timeout",
+		Usage: "maximum time for a complete request including the body transfer, 0 disables it",
+	},
+	cli.IntFlag{
+		Name:  "max-idle-conns",
+		Usage: "maximum number of idle connections kept open to all hosts",
+		Value: defaultMaxIdleConns,
+	},
+	cli.IntFlag{
+		Name:  "max-idle-conns-per-host",
+		Usage: "maximum number of idle connections kept open to each host, size it to the number of parallel transfers",
+		Value: defaultMaxIdleConns,
+	},
+	cli.StringFlag{
+		Name:  "retry-on",
+		Usage: "comma separated list of HTTP status codes to retry on, replaces the default retry policy",
+	},
+	cli.IntFlag{
+		Name:  "max-connections",
+		Usage: "maximum number of connections in use to all hosts, shared by every operation",
+	},
+	cli.BoolFlag{
+		Name:  "dualstack",
+		Usage: "use Amazon S3 dual-stack endpoints and prefer IPv6 connections",
+	},
+	cli.BoolFlag{
+		Name:  "path-style",
+		Usage: "address buckets in the URL path of all hosts, overrides the lookup of the host config",
+	},
+	cli.StringFlag{
+		Name:   "default-region",
+		Usage:  "region used when the region lookup of a host or the location lookup of a bucket gives none",
+		EnvVar: "MC_DEFAULT_REGION",
+	},
+	cli.BoolFlag{
+		Name:  "no-region-redirect",
+		Usage: "fail requests sent to the wrong region instead of retrying them in the region of the bucket",
+	},
+	cli.BoolFlag{
+		Name:  "offline",
+		Usage: "check arguments and configuration without any network access",
+	},
+	cli.StringFlag{
+		Name:  "bind-addr",
+		Usage: "send requests from this local IP address or from the first address of this network interface",
+	},
+	cli.StringFlag{
+		Name:  "mem-limit",
+		Usage: "run fewer transfers at once while the heap of mc is larger than this size, e.g. \"1GiB\"",
+	},
 }
 
 // Flags common across all I/O commands such as cp, mirror, stat, pipe etc.
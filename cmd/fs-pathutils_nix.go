@@ -18,6 +18,22 @@
 
 package cmd
 
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
 func normalizePath(path string) string {
 	return path
 }
+
+// fileID returns the device and inode of fi, which tell apart a file
+// replaced by another one of the same name.
+func fileID(fi os.FileInfo) string {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+}
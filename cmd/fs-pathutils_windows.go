@@ -19,6 +19,7 @@
 package cmd
 
 import (
+	"os"
 	"path/filepath"
 	"syscall"
 )
@@ -33,3 +34,8 @@ func normalizePath(path string) string {
 	}
 	return path
 }
+
+// fileID is not known on Windows, file infos do not carry file indexes.
+func fileID(fi os.FileInfo) string {
+	return ""
+}
@@ -20,8 +20,15 @@ package cmd
 import (
 	"context"
 	"crypto/x509"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+	"unicode/utf8"
 
+	humanize "github.com/dustin/go-humanize"
 	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
 )
 
@@ -51,6 +58,37 @@ var (
 	globalNoColor  = false // No Color flag set via command line
 	globalInsecure = false // Insecure flag set via command line
 
+	globalCACertFile string // CA certificate file of all hosts set via command line
+
+	globalConnectTimeout        = defaultConnectTimeout // Dial timeout set via command line
+	globalResponseHeaderTimeout time.Duration           // Response header timeout set via command line
+	globalReadTimeout           time.Duration           // Response body stall timeout set via command line
+	globalRequestTimeout        time.Duration           // Whole request timeout set via command line
+
+	globalMaxIdleConns        = defaultMaxIdleConns // Idle connection pool size set via command line
+	globalMaxIdleConnsPerHost = defaultMaxIdleConns // Idle connections per host set via command line
+
+	globalRetryStatusCodes map[int]struct{} // Retryable HTTP statuses set via command line
+
+	globalConnLimiter         chan struct{} // Shared request semaphore sized via command line
+	globalStreamedCopyLimiter chan struct{} // Slots of globalConnLimiter streamed copies may reserve
+
+	globalListDelimiter = "/" // Object storage listing delimiter set via command line
+
+	globalDualStack = false // Amazon S3 dual-stack endpoints set via command line
+
+	globalPathStyle = false // Path style bucket addressing of all hosts set via command line
+
+	globalDefaultRegion = "us-east-1" // Region of hosts whose region lookup failed, set via command line
+
+	globalRegionRedirect = true // Follow redirects to the region of a bucket, disabled via command line
+
+	globalOffline = false // Fail requests to hosts instead of sending them, set via command line
+
+	globalBindAddr net.IP // Local address of outgoing connections set via command line
+
+	globalMemoryLimit uint64 // Heap size above which transfers are reduced, set via command line
+
 	globalContext, globalCancel = context.WithCancel(context.Background())
 )
 
@@ -68,7 +106,6 @@ func setGlobals(quiet, debug, json, noColor, insecure bool) {
 	globalDebug = globalDebug || debug
 	globalJSON = globalJSON || json
 	globalNoColor = globalNoColor || noColor
-	globalInsecure = globalInsecure || insecure
 
 	// Enable debug messages if requested.
 	if globalDebug {
@@ -79,6 +116,12 @@ func setGlobals(quiet, debug, json, noColor, insecure bool) {
 	if globalNoColor || globalQuiet {
 		console.SetColorOff()
 	}
+
+	if insecure && !globalInsecure {
+		console.Errorln("TLS certificate verification is disabled by --insecure, " +
+			"connections to all hosts can be intercepted without notice.")
+	}
+	globalInsecure = globalInsecure || insecure
 }
 
 // Set global states. NOTE: It is deliberately kept monolithic to ensure we dont miss out any flags.
@@ -89,5 +132,90 @@ func setGlobalsFromContext(ctx *cli.Context) error {
 	noColor := ctx.IsSet("no-color")
 	insecure := ctx.IsSet("insecure")
 	setGlobals(quiet, debug, json, noColor, insecure)
+
+	if ctx.IsSet("ca-cert") {
+		caFile, e := filepath.Abs(ctx.String("ca-cert"))
+		fatalIf(probe.NewError(e), "Unable to use --ca-cert.")
+		_, err := getRootCAs(caFile)
+		fatalIf(err.Trace(caFile), "Unable to use --ca-cert.")
+		globalCACertFile = caFile
+	}
+	if ctx.IsSet("timeout") {
+		timeout := ctx.Duration("timeout")
+		globalConnectTimeout = timeout
+		globalResponseHeaderTimeout = timeout
+		globalReadTimeout = timeout
+	}
+	if ctx.IsSet("connect-timeout") {
+		globalConnectTimeout = ctx.Duration("connect-timeout")
+	}
+	if ctx.IsSet("response-header-timeout") {
+		globalResponseHeaderTimeout = ctx.Duration("response-header-timeout")
+	}
+	if ctx.IsSet("read-timeout") {
+		globalReadTimeout = ctx.Duration("read-timeout")
+	}
+	if ctx.IsSet("request-timeout") {
+		globalRequestTimeout = ctx.Duration("request-timeout")
+	}
+	for _, name := range []string{"max-idle-conns", "max-idle-conns-per-host"} {
+		if ctx.IsSet(name) && ctx.Int(name) <= 0 {
+			fatalIf(errInvalidArgument().Trace(strconv.Itoa(ctx.Int(name))), "--"+name+" must be greater than zero.")
+		}
+	}
+	if ctx.IsSet("max-idle-conns") {
+		globalMaxIdleConns = ctx.Int("max-idle-conns")
+	}
+	if ctx.IsSet("max-idle-conns-per-host") {
+		globalMaxIdleConnsPerHost = ctx.Int("max-idle-conns-per-host")
+	}
+	if ctx.IsSet("retry-on") {
+		statuses, err := parseRetryStatusCodes(ctx.String("retry-on"))
+		fatalIf(err.Trace(ctx.String("retry-on")), "Unable to parse --retry-on.")
+		globalRetryStatusCodes = statuses
+	}
+	if ctx.IsSet("delimiter") {
+		delimiter := ctx.String("delimiter")
+		if utf8.RuneCountInString(delimiter) != 1 {
+			fatalIf(errInvalidArgument().Trace(delimiter), "--delimiter must be a single character.")
+		}
+		globalListDelimiter = delimiter
+	}
+	if ctx.IsSet("dualstack") {
+		globalDualStack = true
+	}
+	if ctx.IsSet("path-style") {
+		globalPathStyle = true
+	}
+	if region := ctx.String("default-region"); region != "" {
+		globalDefaultRegion = region
+	}
+	if ctx.IsSet("no-region-redirect") {
+		globalRegionRedirect = false
+	}
+	if ctx.IsSet("offline") {
+		globalOffline = true
+	}
+	if ctx.IsSet("bind-addr") {
+		bindAddr, err := parseBindAddr(ctx.String("bind-addr"))
+		fatalIf(err.Trace(ctx.String("bind-addr")), "Unable to use --bind-addr.")
+		globalBindAddr = bindAddr
+	}
+	if ctx.IsSet("mem-limit") {
+		limit, e := humanize.ParseBytes(ctx.String("mem-limit"))
+		if e != nil || limit == 0 {
+			fatalIf(errInvalidArgument().Trace(ctx.String("mem-limit")), "Unable to parse --mem-limit.")
+		}
+		globalMemoryLimit = limit
+	}
+	if ctx.IsSet("max-connections") && globalConnLimiter == nil {
+		maxConns := ctx.Int("max-connections")
+		// A copy between remote hosts downloads and uploads at once.
+		if maxConns < 2 {
+			fatalIf(errInvalidArgument().Trace(strconv.Itoa(maxConns)), "--max-connections must be at least 2.")
+		}
+		globalConnLimiter = make(chan struct{}, maxConns)
+		globalStreamedCopyLimiter = make(chan struct{}, maxConns-1)
+	}
 	return nil
 }
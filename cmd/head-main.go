@@ -37,6 +37,10 @@ var (
 			Usage: "print the first 'n' lines",
 			Value: 10,
 		},
+		cli.Int64Flag{
+			Name:  "c,bytes",
+			Usage: "print the first 'n' bytes instead of lines",
+		},
 	}
 )
 
@@ -60,7 +64,8 @@ ENVIRONMENT VARIABLES:
   MC_ENCRYPT_KEY:  list of comma delimited prefix=secret values
 
 NOTE:
-  '{{.HelpName}}' automatically decompresses 'gzip', 'bzip2' compressed objects.
+  '{{.HelpName}}' automatically decompresses 'gzip', 'bzip2' compressed objects. The first bytes
+  of other objects are fetched with a ranged request, without downloading the rest.
 
 EXAMPLES:
   1. Display only first line from a 'gzip' compressed object on Amazon S3.
@@ -72,36 +77,54 @@ EXAMPLES:
   3. Display only first line from server encrypted object on Amazon S3. In case the encryption key contains non-printable character like tab, pass the
      base64 encoded string as key.
      {{.Prompt}} {{.HelpName}} --encrypt-key "s3/json-data=MzJieXRlc2xvbmdzZWNyZXRrZQltdXN0YmVnaXZlbjE="  s3/json-data/population.json
+
+  4. Display the first kilobyte of a large log object on Amazon S3.
+     {{.Prompt}} {{.HelpName}} --bytes 1024 s3/logs/access-2020-03-01.log
 `,
 }
 
-// headURL displays contents of a URL to stdout.
-func headURL(sourceURL string, encKeyDB map[string][]prefixSSEPair, nlines int64) *probe.Error {
+// headURL displays the first nlines lines of a URL to stdout, or its
+// first nbytes bytes when nbytes is not negative.
+func headURL(sourceURL string, encKeyDB map[string][]prefixSSEPair, nlines, nbytes int64) *probe.Error {
 	var reader io.ReadCloser
 	switch sourceURL {
 	case "-":
 		reader = os.Stdin
 	default:
-		var err *probe.Error
-		var metadata map[string]string
-		if reader, metadata, err = getSourceStreamMetadataFromURL(sourceURL, encKeyDB); err != nil {
+		_, content, err := url2Stat(sourceURL, true, false, encKeyDB)
+		if err != nil {
 			return err.Trace(sourceURL)
 		}
-		ctype := metadata["Content-Type"]
-		if strings.Contains(ctype, "gzip") {
-			var e error
-			reader, e = gzip.NewReader(reader)
+		ctype := content.Metadata["Content-Type"]
+		isCompressed := strings.Contains(ctype, "gzip") || strings.Contains(ctype, "bzip")
+		if nbytes >= 0 && !isCompressed {
+			// Only the requested bytes are fetched, a range past the
+			// end of the object is not satisfiable.
+			if content.Size < nbytes {
+				nbytes = content.Size
+			}
+			reader, err = getSourceRangeFromURL(sourceURL, 0, nbytes, encKeyDB)
+		} else {
+			reader, err = getSourceStreamFromURL(sourceURL, encKeyDB)
+		}
+		if err != nil {
+			return err.Trace(sourceURL)
+		}
+		defer reader.Close()
+		switch {
+		case strings.Contains(ctype, "gzip"):
+			gzReader, e := gzip.NewReader(reader)
 			if e != nil {
 				return probe.NewError(e)
 			}
-			defer reader.Close()
-		} else if strings.Contains(ctype, "bzip") {
-			defer reader.Close()
+			reader = gzReader
+		case strings.Contains(ctype, "bzip"):
 			reader = ioutil.NopCloser(bzip2.NewReader(reader))
-		} else {
-			defer reader.Close()
 		}
 	}
+	if nbytes >= 0 {
+		return catOut(io.LimitReader(reader, nbytes), -1).Trace(sourceURL)
+	}
 	return headOut(reader, nlines).Trace(sourceURL)
 }
 
@@ -127,7 +150,8 @@ func headOut(r io.Reader, nlines int64) *probe.Error {
 		nlines = 10
 	}
 
-	for scn.Scan() && nlines > 0 {
+	// Stop before scanning past the last line asked for.
+	for nlines > 0 && scn.Scan() {
 		if _, e := stdout.Write(scn.Bytes()); e != nil {
 			switch e := e.(type) {
 			case *os.PathError:
@@ -149,27 +173,48 @@ func headOut(r io.Reader, nlines int64) *probe.Error {
 	return nil
 }
 
+// checkHeadSyntax - validate head flags.
+func checkHeadSyntax(ctx *cli.Context) {
+	if ctx.IsSet("bytes") {
+		if ctx.IsSet("lines") {
+			fatalIf(errInvalidArgument().Trace(), "--lines and --bytes cannot be used together.")
+		}
+		if ctx.Int64("bytes") < 0 {
+			fatalIf(errInvalidArgument().Trace(), "--bytes cannot be negative.")
+		}
+	}
+}
+
 // mainHead is the main entry point for head command.
 func mainHead(ctx *cli.Context) error {
 	// Parse encryption keys per command.
 	encKeyDB, err := getEncKeys(ctx)
 	fatalIf(err, "Unable to parse encryption keys.")
 
+	// check 'head' cli arguments.
+	checkHeadSyntax(ctx)
+
 	// Set command flags from context.
 	stdinMode := false
 	if !ctx.Args().Present() {
 		stdinMode = true
 	}
 
+	nlines := ctx.Int64("lines")
+	nbytes := int64(-1)
+	if ctx.IsSet("bytes") {
+		nbytes = ctx.Int64("bytes")
+	}
+
 	// handle std input data.
 	if stdinMode {
-		fatalIf(headOut(os.Stdin, ctx.Int64("lines")).Trace(), "Unable to read from standard input.")
+		fatalIf(headURL("-", nil, nlines, nbytes).Trace(), "Unable to read from standard input.")
 		return nil
 	}
 
 	// Convert arguments to URLs: expand alias, fix format.
 	for _, url := range ctx.Args() {
-		fatalIf(headURL(url, encKeyDB, ctx.Int64("lines")).Trace(url), "Unable to read from `"+url+"`.")
+		fatalIf(headURL(url, encKeyDB, nlines, nbytes).Trace(url), "Unable to read from `"+url+"`.")
 	}
 
 	return nil
@@ -0,0 +1,144 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// lineReader returns one line per read and fails once its lines are
+// exhausted, to catch reads past the lines asked for.
+type lineReader struct {
+	lines []string
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, errors.New("read past the last line")
+	}
+	n := copy(p, r.lines[0])
+	r.lines = r.lines[1:]
+	return n, nil
+}
+
+func TestHeadOutStopsReading(t *testing.T) {
+	devNull, e := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer devNull.Close()
+	savedStdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = savedStdout }()
+
+	for nlines := int64(0); nlines <= 3; nlines++ {
+		r := &lineReader{lines: []string{"one\n", "two\n", "three\n"}}
+		if err := headOut(r, nlines); err != nil {
+			t.Fatalf("%d lines: %s", nlines, err)
+		}
+	}
+	// Asking for more lines than there are reads until the error.
+	r := &lineReader{lines: []string{"one\n", "two\n", "three\n"}}
+	if err := headOut(r, 4); err == nil {
+		t.Fatal("Expected the read error for 4 lines, got success")
+	}
+}
+
+func TestHeadURLBytes(t *testing.T) {
+	data := "hello world"
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		object := data
+		if r.URL.Path == "/bucket/empty" || r.URL.Query().Get("prefix") == "empty" {
+			object = ""
+		}
+		w.Header().Set("ETag", "\"etag\"")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		switch {
+		case r.URL.Path == "/bucket/":
+			key := r.URL.Query().Get("prefix")
+			fmt.Fprintf(w, "<ListBucketResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>bucket</Name><Prefix>%s</Prefix><KeyCount>1</KeyCount><MaxKeys>1000</MaxKeys><Delimiter>/</Delimiter><IsTruncated>false</IsTruncated><Contents><Key>%s</Key><LastModified>2006-01-02T15:04:05.000Z</LastModified><ETag>&quot;etag&quot;</ETag><Size>%d</Size><StorageClass>STANDARD</StorageClass></Contents></ListBucketResult>", key, key, len(object))
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Length", strconv.Itoa(len(object)))
+		case r.Method == http.MethodGet:
+			rangeHeader := r.Header.Get("Range")
+			if rangeHeader == "" {
+				t.Errorf("%s: the whole object was fetched", r.URL.Path)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			var start, end int
+			if _, e := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); e != nil || end >= len(object) {
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				w.Write([]byte("<Error><Code>InvalidRange</Code><Message>The requested range is not satisfiable</Message></Error>"))
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(object)))
+			w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(object[start : end+1]))
+		}
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("headtest", server.URL)()
+
+	out, e := ioutil.TempFile("", "head-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+	savedStdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = savedStdout }()
+
+	testCases := []struct {
+		url    string
+		nbytes int64
+		output string
+	}{
+		{"headtest/bucket/object", 5, "hello"},
+		// More bytes than the object has.
+		{"headtest/bucket/object", 100, data},
+		{"headtest/bucket/empty", 5, ""},
+	}
+	for i, testCase := range testCases {
+		if _, e = out.Seek(0, 0); e != nil {
+			t.Fatal(e)
+		}
+		if e = out.Truncate(0); e != nil {
+			t.Fatal(e)
+		}
+		if err := headURL(testCase.url, nil, -1, testCase.nbytes); err != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, err)
+		}
+		output, e := ioutil.ReadFile(out.Name())
+		if e != nil {
+			t.Fatal(e)
+		}
+		if string(output) != testCase.output {
+			t.Errorf("Test %d: expected %q, got %q", i+1, testCase.output, output)
+		}
+	}
+}
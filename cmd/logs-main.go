@@ -0,0 +1,302 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/cli"
+	json "github.com/minio/mc/pkg/colorjson"
+	"github.com/minio/mc/pkg/ioutils"
+	"github.com/minio/mc/pkg/probe"
+)
+
+var (
+	logsFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "stream log objects written in this long, e.g. 7d10h31s",
+			Value: "1h",
+		},
+	}
+)
+
+var logsCmd = cli.Command{
+	Name:   "logs",
+	Usage:  "stream server access logs of a bucket",
+	Action: mainLogs,
+	Before: setGlobalsFromContext,
+	Flags:  append(logsFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Reads the server access logging config of the bucket and prints the log
+  objects written to its logging target, oldest first. With --json every
+  record is parsed into its fields.
+
+EXAMPLES:
+  1. Print the access logs of the last hour of a bucket.
+     {{.Prompt}} {{.HelpName}} s3/mybucket
+
+  2. Print the access logs of the last two days as JSON records.
+     {{.Prompt}} {{.HelpName}} --json --since 2d s3/mybucket
+`,
+}
+
+// Layout of the time of server access log records.
+const accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogRecord - fields of a server access log record, in the order
+// they are logged. Fields logged as "-" are left empty.
+type accessLogRecord struct {
+	BucketOwner        string    `json:"bucketOwner,omitempty"`
+	Bucket             string    `json:"bucket,omitempty"`
+	Time               time.Time `json:"time"`
+	RemoteIP           string    `json:"remoteIP,omitempty"`
+	Requester          string    `json:"requester,omitempty"`
+	RequestID          string    `json:"requestID,omitempty"`
+	Operation          string    `json:"operation,omitempty"`
+	Key                string    `json:"key,omitempty"`
+	RequestURI         string    `json:"requestURI,omitempty"`
+	HTTPStatus         int       `json:"httpStatus,omitempty"`
+	ErrorCode          string    `json:"errorCode,omitempty"`
+	BytesSent          int64     `json:"bytesSent,omitempty"`
+	ObjectSize         int64     `json:"objectSize,omitempty"`
+	TotalTime          int64     `json:"totalTime,omitempty"`
+	TurnAroundTime     int64     `json:"turnAroundTime,omitempty"`
+	Referrer           string    `json:"referrer,omitempty"`
+	UserAgent          string    `json:"userAgent,omitempty"`
+	VersionID          string    `json:"versionID,omitempty"`
+	HostID             string    `json:"hostID,omitempty"`
+	SignatureVersion   string    `json:"signatureVersion,omitempty"`
+	CipherSuite        string    `json:"cipherSuite,omitempty"`
+	AuthenticationType string    `json:"authenticationType,omitempty"`
+	HostHeader         string    `json:"hostHeader,omitempty"`
+	TLSVersion         string    `json:"tlsVersion,omitempty"`
+}
+
+// splitAccessLogRecord splits a record into its fields, which are
+// separated by spaces unless quoted or in brackets.
+func splitAccessLogRecord(line string) (fields []string) {
+	for line = strings.TrimLeft(line, " "); line != ""; line = strings.TrimLeft(line, " ") {
+		end := " "
+		switch line[0] {
+		case '"':
+			end = "\""
+		case '[':
+			end = "]"
+		}
+		if end != " " {
+			line = line[1:]
+		}
+		i := strings.Index(line, end)
+		if i < 0 {
+			i = len(line)
+		}
+		fields = append(fields, line[:i])
+		line = line[i:]
+		if end != " " && line != "" {
+			line = line[1:]
+		}
+	}
+	return fields
+}
+
+// parseAccessLogRecord parses a server access log record, records with
+// less than the first nine fields or without a valid time are rejected.
+func parseAccessLogRecord(line string) (record accessLogRecord, ok bool) {
+	fields := splitAccessLogRecord(line)
+	if len(fields) < 9 {
+		return record, false
+	}
+	t, e := time.Parse(accessLogTimeLayout, fields[2])
+	if e != nil {
+		return record, false
+	}
+	record.Time = t.UTC()
+
+	values := []*string{
+		&record.BucketOwner, &record.Bucket, nil, &record.RemoteIP,
+		&record.Requester, &record.RequestID, &record.Operation, &record.Key,
+		&record.RequestURI, nil, &record.ErrorCode, nil, nil, nil, nil,
+		&record.Referrer, &record.UserAgent, &record.VersionID, &record.HostID,
+		&record.SignatureVersion, &record.CipherSuite, &record.AuthenticationType,
+		&record.HostHeader, &record.TLSVersion,
+	}
+	numbers := map[int]*int64{
+		11: &record.BytesSent, 12: &record.ObjectSize,
+		13: &record.TotalTime, 14: &record.TurnAroundTime,
+	}
+	for i, field := range fields {
+		if i >= len(values) || field == "-" {
+			continue
+		}
+		if values[i] != nil {
+			*values[i] = field
+		} else if number, ok := numbers[i]; ok {
+			*number, _ = strconv.ParseInt(field, 10, 64)
+		} else if i == 9 {
+			record.HTTPStatus, _ = strconv.Atoi(field)
+		}
+	}
+	return record, true
+}
+
+// logsMessage container for a server access log record.
+type logsMessage struct {
+	Status string `json:"status"`
+	Object string `json:"object"`
+	Record string `json:"record,omitempty"`
+	*accessLogRecord
+}
+
+// String prints the record as logged.
+func (l logsMessage) String() string {
+	return l.Record
+}
+
+// JSON prints the fields of the record, or the record itself when it
+// can't be parsed.
+func (l logsMessage) JSON() string {
+	l.Status = "success"
+	if record, ok := parseAccessLogRecord(l.Record); ok {
+		l.Record = ""
+		l.accessLogRecord = &record
+	}
+	logsMessageBytes, e := json.MarshalIndent(l, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(logsMessageBytes)
+}
+
+// checkLogsSyntax - validate all the passed arguments
+func checkLogsSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "logs", 1) // last argument is exit code
+	}
+	if _, err := parseLogsSince(ctx.String("since")); err != nil {
+		fatalIf(err.Trace(ctx.String("since")), "Invalid --since `"+ctx.String("since")+"`.")
+	}
+}
+
+// parseLogsSince returns the oldest time of the log objects to print.
+func parseLogsSince(since string) (time.Time, *probe.Error) {
+	d, e := ioutils.ParseDurationTime(since)
+	if e != nil {
+		return time.Time{}, probe.NewError(e)
+	}
+	return UTCNow().Add(-d), nil
+}
+
+// Layout of the time log objects are named after, following the
+// prefix of the logging target.
+const accessLogKeyTimeLayout = "2006-01-02-15-04-05"
+
+// listAccessLogObjects lists the log objects under logsURL written
+// since the given time. Log objects are named after the time they are
+// written, they are listed oldest first starting after the key of that
+// time.
+func listAccessLogObjects(logsURL string, since time.Time) (<-chan *clientContent, *probe.Error) {
+	clnt, err := newClient(logsURL)
+	if err != nil {
+		return nil, err.Trace(logsURL)
+	}
+	s3Client, ok := clnt.(*s3Client)
+	if !ok {
+		return nil, errDummy().Trace(logsURL)
+	}
+	_, prefix := s3Client.url2BucketAndObject()
+	return s3Client.ListAfter(prefix + since.UTC().Format(accessLogKeyTimeLayout)), nil
+}
+
+// Maximum size of a server access log record.
+const maxAccessLogRecordSize = 1024 * 1024
+
+// printAccessLogObject prints the records of a log object.
+func printAccessLogObject(alias string, content *clientContent) *probe.Error {
+	urlStr := content.URL.String()
+	reader, _, err := getSourceStream(alias, urlStr, false, nil)
+	if err != nil {
+		return err.Trace(urlStr)
+	}
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxAccessLogRecordSize)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			printMsg(logsMessage{Object: urlStr, Record: line})
+		}
+	}
+	if e := scanner.Err(); e != nil {
+		return probe.NewError(e).Trace(urlStr)
+	}
+	return nil
+}
+
+func mainLogs(ctx *cli.Context) error {
+	checkLogsSyntax(ctx)
+
+	targetURL := ctx.Args().First()
+	since, _ := parseLogsSince(ctx.String("since"))
+
+	client, err := newClient(targetURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+
+	s3Client, ok := client.(*s3Client)
+	if !ok {
+		fatalIf(errDummy().Trace(targetURL), "The provided url doesn't point to a S3 server.")
+	}
+
+	logging, err := s3Client.GetBucketLogging()
+	fatalIf(err.Trace(targetURL), "Unable to get the logging config of `"+targetURL+"`.")
+	if logging.LoggingEnabled == nil || logging.LoggingEnabled.TargetBucket == "" {
+		fatalIf(errDummy().Trace(targetURL), "Server access logging is not enabled for `"+targetURL+"`.")
+	}
+
+	// Log objects are read through the alias of the logged bucket.
+	alias, _ := url2Alias(targetURL)
+	logsURL := alias + "/" + logging.LoggingEnabled.TargetBucket + "/" + logging.LoggingEnabled.TargetPrefix
+	contentCh, err := listAccessLogObjects(logsURL, since)
+	fatalIf(err, "Unable to list the access logs in `"+logsURL+"`.")
+
+	var cErr error
+	for content := range contentCh {
+		if content.Err != nil {
+			errorIf(content.Err.Trace(logsURL), "Unable to list the access logs in `"+logsURL+"`.")
+			cErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		if !content.Type.IsRegular() {
+			continue
+		}
+		if err = printAccessLogObject(alias, content); err != nil {
+			errorIf(err, "Unable to read the access log `"+content.URL.String()+"`.")
+			cErr = exitStatus(globalErrorExitStatus)
+		}
+	}
+	return cErr
+}
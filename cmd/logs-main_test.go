@@ -0,0 +1,142 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAccessLogRecord(t *testing.T) {
+	testCases := []struct {
+		line   string
+		record accessLogRecord
+		ok     bool
+	}{
+		{
+			`79a5 awsexamplebucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a5 3E57427F3EXAMPLE REST.GET.VERSIONING - "GET /awsexamplebucket1?versioning HTTP/1.1" 200 - 113 - 7 - "-" "S3Console/0.4" - s9lzHYrFp76Z SigV2 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader awsexamplebucket1.s3.us-west-1.amazonaws.com TLSV1.1`,
+			accessLogRecord{
+				BucketOwner:        "79a5",
+				Bucket:             "awsexamplebucket1",
+				Time:               time.Date(2019, time.February, 6, 0, 0, 38, 0, time.UTC),
+				RemoteIP:           "192.0.2.3",
+				Requester:          "79a5",
+				RequestID:          "3E57427F3EXAMPLE",
+				Operation:          "REST.GET.VERSIONING",
+				RequestURI:         "GET /awsexamplebucket1?versioning HTTP/1.1",
+				HTTPStatus:         200,
+				BytesSent:          113,
+				TotalTime:          7,
+				UserAgent:          "S3Console/0.4",
+				HostID:             "s9lzHYrFp76Z",
+				SignatureVersion:   "SigV2",
+				CipherSuite:        "ECDHE-RSA-AES128-GCM-SHA256",
+				AuthenticationType: "AuthHeader",
+				HostHeader:         "awsexamplebucket1.s3.us-west-1.amazonaws.com",
+				TLSVersion:         "TLSV1.1",
+			},
+			true,
+		},
+		{
+			`owner bucket [06/Feb/2019:02:00:38 +0200] 192.0.2.3 - ID REST.PUT.OBJECT photos/a.jpg "PUT /bucket/photos/a.jpg HTTP/1.1" 403 AccessDenied 243 1024`,
+			accessLogRecord{
+				BucketOwner: "owner",
+				Bucket:      "bucket",
+				Time:        time.Date(2019, time.February, 6, 0, 0, 38, 0, time.UTC),
+				RemoteIP:    "192.0.2.3",
+				RequestID:   "ID",
+				Operation:   "REST.PUT.OBJECT",
+				Key:         "photos/a.jpg",
+				RequestURI:  "PUT /bucket/photos/a.jpg HTTP/1.1",
+				HTTPStatus:  403,
+				ErrorCode:   "AccessDenied",
+				BytesSent:   243,
+				ObjectSize:  1024,
+			},
+			true,
+		},
+		{`owner bucket [yesterday] 192.0.2.3 - ID REST.GET.OBJECT key "GET / HTTP/1.1"`, accessLogRecord{}, false},
+		{`owner bucket [06/Feb/2019:00:00:38 +0000] 192.0.2.3`, accessLogRecord{}, false},
+		{``, accessLogRecord{}, false},
+	}
+	for i, testCase := range testCases {
+		record, ok := parseAccessLogRecord(testCase.line)
+		if ok != testCase.ok {
+			t.Fatalf("Test %d: expected ok %t, found %t", i+1, testCase.ok, ok)
+		}
+		if ok && !reflect.DeepEqual(record, testCase.record) {
+			t.Fatalf("Test %d: expected %+v, found %+v", i+1, testCase.record, record)
+		}
+	}
+
+	msg := logsMessage{Object: "s3/logs/a", Record: testCases[1].line}
+	if msg.String() != testCases[1].line {
+		t.Fatalf("Expected the record as logged, found `%s`", msg.String())
+	}
+	if output := msg.JSON(); !strings.Contains(output, `"AccessDenied"`) || strings.Contains(output, `"record"`) {
+		t.Fatalf("Expected the fields of the record, found %s", output)
+	}
+	msg.Record = "not a record"
+	if output := msg.JSON(); !strings.Contains(output, `"not a record"`) {
+		t.Fatalf("Expected the unparsed record, found %s", output)
+	}
+}
+
+func TestListAccessLogObjects(t *testing.T) {
+	var startAfter []string
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case query.Get("continuation-token") == "":
+			startAfter = append(startAfter, query.Get("start-after"))
+			w.Write([]byte("<ListBucketResult><Name>logbucket</Name><IsTruncated>true</IsTruncated><NextContinuationToken>page2</NextContinuationToken>" +
+				"<Contents><Key>logs/2020-01-02-15-04-06-A</Key><Size>1</Size><LastModified>2020-01-02T15:04:06.000Z</LastModified></Contents></ListBucketResult>"))
+		default:
+			startAfter = append(startAfter, query.Get("start-after"))
+			w.Write([]byte("<ListBucketResult><Name>logbucket</Name><IsTruncated>false</IsTruncated>" +
+				"<Contents><Key>logs/2020-01-02-16-00-00-B</Key><Size>1</Size><LastModified>2020-01-02T16:00:00.000Z</LastModified></Contents></ListBucketResult>"))
+		}
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("logstest", server.URL)()
+
+	since := time.Date(2020, 1, 2, 16, 4, 5, 0, time.FixedZone("", 3600))
+	contentCh, err := listAccessLogObjects("logstest/logbucket/logs/", since)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	var keys []string
+	for content := range contentCh {
+		if content.Err != nil {
+			t.Fatalf("unexpected error %s", content.Err)
+		}
+		keys = append(keys, content.URL.Path)
+	}
+	// Listed after the key of the time given, page after page.
+	expected := []string{"logs/2020-01-02-15-04-05", "logs/2020-01-02-15-04-05"}
+	if !reflect.DeepEqual(startAfter, expected) {
+		t.Fatalf("expected listings to start after %v, found %v", expected, startAfter)
+	}
+	if expected = []string{"/logbucket/logs/2020-01-02-15-04-06-A", "/logbucket/logs/2020-01-02-16-00-00-B"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected %v, found %v", expected, keys)
+	}
+}
@@ -35,6 +35,31 @@ var (
 			Name:  "incomplete, I",
 			Usage: "list incomplete uploads",
 		},
+		cli.StringFlag{
+			Name:  "delimiter",
+			Usage: "single character delimiting the object hierarchy on object storage",
+			Value: "/",
+		},
+		cli.StringFlag{
+			Name:  "owner",
+			Usage: "list only object(s) owned by this canonical user id",
+		},
+		cli.BoolFlag{
+			Name:  "bytes",
+			Usage: "print exact sizes in bytes instead of human readable ones",
+		},
+		cli.StringFlag{
+			Name:  "template",
+			Usage: "print each entry with a template of {key} {size} {hsize} {modtime} {etag} {type} {owner} placeholders",
+		},
+		cli.IntFlag{
+			Name:  "max-keys",
+			Usage: "list a single page of at most this many entries and print the token of the next page, with --continuation-token alone the page size configured for the host or 1000",
+		},
+		cli.StringFlag{
+			Name:  "continuation-token",
+			Usage: "list the page following the one which printed this token",
+		},
 	}
 )
 
@@ -72,6 +97,22 @@ EXAMPLES:
 
   6. List incomplete (previously failed) uploads of objects on Amazon S3.
      {{.Prompt}} {{.HelpName}} --incomplete s3/mybucket
+
+  7. List the top level of a bucket whose object names use '|' as hierarchy delimiter.
+     {{.Prompt}} {{.HelpName}} --delimiter '|' s3/mybucket
+
+  8. List objects uploaded to a shared bucket by a given account, the owner is part of the JSON output.
+     {{.Prompt}} {{.HelpName}} --recursive --json --owner 75aa57f09aa0c8caeab4f8c24e99d10f8e7faeebf76c078efc7c6caea54ba06a s3/shared
+
+  9. List the size in bytes, name and modification time of all objects as tab separated columns.
+     {{.Prompt}} {{.HelpName}} --recursive --template '{size}\t{key}\t{modtime}' s3/mybucket
+
+  10. List the first 100 objects of a bucket, then the next 100 with the token printed after them.
+     {{.Prompt}} {{.HelpName}} --recursive --max-keys 100 s3/mybucket
+     {{.Prompt}} {{.HelpName}} --recursive --max-keys 100 --continuation-token 1ueGcxLPRx1Tr/XYExHnhbYLgveDs2J/wm36Hy4vbOwM= s3/mybucket
+
+  11. List a bucket with the exact size of each object in bytes.
+     {{.Prompt}} {{.HelpName}} --bytes s3/mybucket
 `,
 }
 
@@ -86,10 +127,29 @@ func checkListSyntax(ctx *cli.Context) {
 			fatalIf(errInvalidArgument().Trace(args...), "Unable to validate empty argument.")
 		}
 	}
+	if ctx.IsSet("template") {
+		if globalJSON {
+			fatalIf(errInvalidArgument().Trace(), "--template can't be used with --json.")
+		}
+		_, err := newListTemplate(ctx.String("template"))
+		fatalIf(err.Trace(ctx.String("template")), "Unable to parse --template.")
+	}
 	// extract URLs.
 	URLs := ctx.Args()
 	isIncomplete := ctx.Bool("incomplete")
 
+	if ctx.IsSet("max-keys") || ctx.IsSet("continuation-token") {
+		if maxKeys := ctx.Int("max-keys"); ctx.IsSet("max-keys") && (maxKeys < 1 || maxKeys > 1000) {
+			fatalIf(errInvalidArgument().Trace(ctx.String("max-keys")), "--max-keys must be between 1 and 1000.")
+		}
+		if isIncomplete {
+			fatalIf(errInvalidArgument().Trace(), "--max-keys and --continuation-token can't be used with --incomplete.")
+		}
+		if len(URLs) > 1 {
+			fatalIf(errInvalidArgument().Trace(URLs...), "A single page is listed from one target only.")
+		}
+	}
+
 	for _, url := range URLs {
 		_, _, err := url2Stat(url, false, false, nil)
 		if err != nil && !isURLPrefixExists(url, isIncomplete) {
@@ -119,6 +179,13 @@ func mainList(ctx *cli.Context) error {
 	// Set command flags from context.
 	isRecursive := ctx.Bool("recursive")
 	isIncomplete := ctx.Bool("incomplete")
+	isBytes := ctx.Bool("bytes")
+	ownerID := ctx.String("owner")
+	var template *listTemplate
+	if ctx.IsSet("template") {
+		template, _ = newListTemplate(ctx.String("template"))
+	}
+	isPaged := ctx.IsSet("max-keys") || ctx.IsSet("continuation-token")
 
 	args := ctx.Args()
 	// mimic operating system tool behavior.
@@ -131,17 +198,33 @@ func mainList(ctx *cli.Context) error {
 		clnt, err := newClient(targetURL)
 		fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
 
-		if !strings.HasSuffix(targetURL, string(clnt.GetURL().Separator)) {
+		delimiter := dirDelimiter(clnt.GetURL())
+		if !strings.HasSuffix(targetURL, string(clnt.GetURL().Separator)) && !strings.HasSuffix(targetURL, delimiter) {
 			var st *clientContent
 			st, err = clnt.Stat(isIncomplete, false, false, nil)
 			if st != nil && err == nil && st.Type.IsDir() {
-				targetURL = targetURL + string(clnt.GetURL().Separator)
+				targetURL = targetURL + delimiter
 				clnt, err = newClient(targetURL)
 				fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
 			}
 		}
 
-		if e := doList(clnt, isRecursive, isIncomplete); e != nil {
+		if ownerID != "" && clnt.GetURL().Type == fileSystem {
+			fatalIf(errInvalidArgument().Trace(targetURL), "`--owner` is only supported on object storage.")
+		}
+
+		if isPaged {
+			maxKeys := ctx.Int("max-keys")
+			if hostCfg := hostConfigOf(targetURL); maxKeys == 0 && hostCfg != nil {
+				maxKeys = hostCfg.MaxKeys
+			}
+			if maxKeys == 0 {
+				maxKeys = 1000
+			}
+			cErr = doListPage(clnt, isRecursive, isBytes, maxKeys, ctx.String("continuation-token"), ownerID, template)
+			continue
+		}
+		if e := doList(clnt, isRecursive, isIncomplete, isBytes, ownerID, template); e != nil {
 			cErr = e
 		}
 	}
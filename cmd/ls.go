@@ -17,13 +17,15 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
-	humanize "github.com/dustin/go-humanize"
 	json "github.com/minio/mc/pkg/colorjson"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
@@ -36,18 +38,28 @@ const (
 
 // contentMessage container for content message structure.
 type contentMessage struct {
-	Status   string    `json:"status"`
-	Filetype string    `json:"type"`
-	Time     time.Time `json:"lastModified"`
-	Size     int64     `json:"size"`
-	Key      string    `json:"key"`
-	ETag     string    `json:"etag"`
+	Status   string        `json:"status"`
+	Filetype string        `json:"type"`
+	Time     time.Time     `json:"lastModified"`
+	Size     int64         `json:"size"`
+	Key      string        `json:"key"`
+	ETag     string        `json:"etag"`
+	Owner    *contentOwner `json:"owner,omitempty"`
+
+	// Print the exact size instead of a human readable one.
+	isBytes bool
+}
+
+// contentOwner of an object as reported by object storage.
+type contentOwner struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"name,omitempty"`
 }
 
 // String colorized string message.
 func (c contentMessage) String() string {
 	message := console.Colorize("Time", fmt.Sprintf("[%s] ", c.Time.Format(printDate)))
-	message = message + console.Colorize("Size", fmt.Sprintf("%7s ", strings.Join(strings.Fields(humanize.IBytes(uint64(c.Size))), "")))
+	message = message + console.Colorize("Size", fmt.Sprintf("%7s ", c.printSize()))
 	message = func() string {
 		if c.Filetype == "folder" {
 			return message + console.Colorize("Dir", c.Key)
@@ -57,6 +69,17 @@ func (c contentMessage) String() string {
 	return message
 }
 
+// printSize - size to print, folders have none.
+func (c contentMessage) printSize() string {
+	switch {
+	case c.Filetype == "folder":
+		return "-"
+	case c.isBytes:
+		return strconv.FormatInt(c.Size, 10)
+	}
+	return humanizeSize(c.Size)
+}
+
 // JSON jsonified content message.
 func (c contentMessage) JSON() string {
 	c.Status = "success"
@@ -83,11 +106,79 @@ func parseContent(c *clientContent) contentMessage {
 	md5sum := strings.TrimPrefix(c.ETag, "\"")
 	md5sum = strings.TrimSuffix(md5sum, "\"")
 	content.ETag = md5sum
+	if c.OwnerID != "" {
+		content.Owner = &contentOwner{ID: c.OwnerID, DisplayName: c.OwnerName}
+	}
 	// Convert OS Type to match console file printing style.
 	content.Key = getKey(c)
 	return content
 }
 
+// listTemplateFields - placeholders of ls --template, each one
+// replaced by a field of the listed content.
+//
+//	{key}      name relative to the listed folder
+//	{size}     size in bytes
+//	{hsize}    human readable size
+//	{modtime}  last modified time in RFC3339 format
+//	{etag}     ETag, empty on a local filesystem
+//	{type}     either file or folder
+//	{owner}    canonical id of the owner, empty when not reported
+var listTemplateFields = map[string]func(c contentMessage) string{
+	"key":     func(c contentMessage) string { return c.Key },
+	"size":    func(c contentMessage) string { return strconv.FormatInt(c.Size, 10) },
+	"hsize":   func(c contentMessage) string { return humanizeSize(c.Size) },
+	"modtime": func(c contentMessage) string { return c.Time.Format(time.RFC3339) },
+	"etag":    func(c contentMessage) string { return c.ETag },
+	"type":    func(c contentMessage) string { return c.Filetype },
+	"owner": func(c contentMessage) string {
+		if c.Owner == nil {
+			return ""
+		}
+		return c.Owner.ID
+	},
+}
+
+var listTemplatePlaceholderRgx = regexp.MustCompile(`\{[^{}]*\}`)
+
+// listTemplate - output format of a listed entry, made of literal
+// text and fields in turn.
+type listTemplate struct {
+	literals []string
+	fields   []func(c contentMessage) string
+}
+
+// newListTemplate parses a template with brace placeholders, \t and \n
+// in the template stand for a tab and a new line.
+func newListTemplate(template string) (*listTemplate, *probe.Error) {
+	template = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n").Replace(template)
+	t := &listTemplate{}
+	var last int
+	for _, loc := range listTemplatePlaceholderRgx.FindAllStringIndex(template, -1) {
+		name := template[loc[0]+1 : loc[1]-1]
+		field, ok := listTemplateFields[name]
+		if !ok {
+			return nil, probe.NewError(errors.New("unknown placeholder `" + template[loc[0]:loc[1]] + "`"))
+		}
+		t.literals = append(t.literals, template[last:loc[0]])
+		t.fields = append(t.fields, field)
+		last = loc[1]
+	}
+	t.literals = append(t.literals, template[last:])
+	return t, nil
+}
+
+// format returns the template filled with the fields of c.
+func (t *listTemplate) format(c contentMessage) string {
+	var b strings.Builder
+	for i, field := range t.fields {
+		b.WriteString(t.literals[i])
+		b.WriteString(field(c))
+	}
+	b.WriteString(t.literals[len(t.fields)])
+	return b.String()
+}
+
 // get content key
 func getKey(c *clientContent) string {
 	sep := "/"
@@ -98,58 +189,143 @@ func getKey(c *clientContent) string {
 		sep = "\\"
 	}
 
-	if c.Type.IsDir() && !strings.HasSuffix(c.URL.Path, sep) {
+	if c.Type.IsDir() && !strings.HasSuffix(c.URL.Path, sep) && !strings.HasSuffix(c.URL.Path, globalListDelimiter) {
 		return fmt.Sprintf("%s%s", c.URL.Path, sep)
 	}
 	return c.URL.Path
 }
 
-// doList - list all entities inside a folder.
-func doList(clnt Client, isRecursive, isIncomplete bool) error {
-	prefixPath := clnt.GetURL().Path
-	separator := string(clnt.GetURL().Separator)
-	if !strings.HasSuffix(prefixPath, separator) {
-		prefixPath = prefixPath[:strings.LastIndex(prefixPath, separator)+1]
-	}
+// doList - list all entities inside a folder, objects not owned by
+// ownerID are skipped unless it is empty. Entries are printed with
+// template when it is not nil.
+func doList(clnt Client, isRecursive, isIncomplete, isBytes bool, ownerID string, template *listTemplate) error {
+	printer := newListPrinter(clnt, isBytes, ownerID, template)
 	var cErr error
 	for content := range clnt.List(isRecursive, isIncomplete, false, DirNone) {
-		if content.Err != nil {
-			switch content.Err.ToGoError().(type) {
-			// handle this specifically for filesystem related errors.
-			case BrokenSymlink:
-				errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to list broken link.")
-				continue
-			case TooManyLevelsSymlink:
-				errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to list too many levels link.")
-				continue
-			case PathNotFound:
-				errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to list folder.")
-				continue
-			case PathInsufficientPermission:
-				errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to list folder.")
-				continue
-			}
-			errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to list folder.")
-			cErr = exitStatus(globalErrorExitStatus) // Set the exit status.
-			continue
+		if e := printer.print(content); e != nil {
+			cErr = e
 		}
+	}
+	return cErr
+}
 
-		if content.StorageClass == s3StorageClassGlacier {
-			continue
-		}
+// listPageMessage - the token to list the page after the one printed.
+type listPageMessage struct {
+	Status    string `json:"status"`
+	NextToken string `json:"nextContinuationToken"`
+}
 
-		// Convert any os specific delimiters to "/".
-		contentURL := filepath.ToSlash(content.URL.Path)
-		prefixPath = filepath.ToSlash(prefixPath)
+func (m listPageMessage) String() string {
+	return "Continue with --continuation-token " + m.NextToken
+}
+
+func (m listPageMessage) JSON() string {
+	m.Status = "success"
+	msgBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(msgBytes)
+}
 
-		// Trim prefix of current working dir
-		prefixPath = strings.TrimPrefix(prefixPath, "."+separator)
-		// Trim prefix path from the content path.
-		contentURL = strings.TrimPrefix(contentURL, prefixPath)
-		content.URL.Path = contentURL
-		parsedContent := parseContent(content)
-		// Print colorized or jsonized content info.
-		printMsg(parsedContent)
+// doListPage - list a single page of up to maxKeys entries following
+// the page token was printed with, then the token of the next page.
+func doListPage(clnt Client, isRecursive, isBytes bool, maxKeys int, token, ownerID string, template *listTemplate) error {
+	contents, nextToken, err := clnt.ListPage(isRecursive, maxKeys, token)
+	if err != nil {
+		errorIf(err.Trace(clnt.GetURL().String()), "Unable to list folder.")
+		return exitStatus(globalErrorExitStatus)
+	}
+	printer := newListPrinter(clnt, isBytes, ownerID, template)
+	var cErr error
+	for _, content := range contents {
+		if e := printer.print(content); e != nil {
+			cErr = e
+		}
+	}
+	if nextToken != "" {
+		printMsg(listPageMessage{NextToken: nextToken})
 	}
 	return cErr
 }
+
+// listPrinter prints the entries listed by a client relative to the
+// listed URL.
+type listPrinter struct {
+	clnt       Client
+	prefixPath string
+	separator  string
+	isBytes    bool
+	ownerID    string
+	template   *listTemplate
+}
+
+func newListPrinter(clnt Client, isBytes bool, ownerID string, template *listTemplate) *listPrinter {
+	prefixPath := clnt.GetURL().Path
+	separator := string(clnt.GetURL().Separator)
+	delimiter := dirDelimiter(clnt.GetURL())
+	if !strings.HasSuffix(prefixPath, separator) && !strings.HasSuffix(prefixPath, delimiter) {
+		// Entries are printed relative to the folder holding the
+		// listed prefix, the innermost of both hierarchies.
+		i := strings.LastIndex(prefixPath, separator)
+		if j := strings.LastIndex(prefixPath, delimiter); j > i {
+			i = j
+		}
+		prefixPath = prefixPath[:i+1]
+	}
+	// Convert any os specific delimiters to "/".
+	prefixPath = filepath.ToSlash(prefixPath)
+	// Trim prefix of current working dir
+	prefixPath = strings.TrimPrefix(prefixPath, "."+separator)
+	return &listPrinter{
+		clnt:       clnt,
+		prefixPath: prefixPath,
+		separator:  separator,
+		isBytes:    isBytes,
+		ownerID:    ownerID,
+		template:   template,
+	}
+}
+
+// print prints content, or the error it was listed with. Errors other
+// than those of broken files and folders are returned as exit status.
+func (p *listPrinter) print(content *clientContent) error {
+	if content.Err != nil {
+		switch content.Err.ToGoError().(type) {
+		// handle this specifically for filesystem related errors.
+		case BrokenSymlink:
+			errorIf(content.Err.Trace(p.clnt.GetURL().String()), "Unable to list broken link.")
+			return nil
+		case TooManyLevelsSymlink:
+			errorIf(content.Err.Trace(p.clnt.GetURL().String()), "Unable to list too many levels link.")
+			return nil
+		case PathNotFound:
+			errorIf(content.Err.Trace(p.clnt.GetURL().String()), "Unable to list folder.")
+			return nil
+		case PathInsufficientPermission:
+			errorIf(content.Err.Trace(p.clnt.GetURL().String()), "Unable to list folder.")
+			return nil
+		}
+		errorIf(content.Err.Trace(p.clnt.GetURL().String()), "Unable to list folder.")
+		return exitStatus(globalErrorExitStatus) // Set the exit status.
+	}
+
+	if content.StorageClass == s3StorageClassGlacier {
+		return nil
+	}
+
+	// Folders carry no owner, keep them navigable.
+	if p.ownerID != "" && !content.Type.IsDir() && content.OwnerID != p.ownerID {
+		return nil
+	}
+
+	// Trim prefix path from the content path.
+	content.URL.Path = strings.TrimPrefix(filepath.ToSlash(content.URL.Path), p.prefixPath)
+	parsedContent := parseContent(content)
+	parsedContent.isBytes = p.isBytes
+	if p.template != nil {
+		console.Println(p.template.format(parsedContent))
+		return nil
+	}
+	// Print colorized or jsonized content info.
+	printMsg(parsedContent)
+	return nil
+}
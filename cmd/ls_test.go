@@ -15,3 +15,99 @@
  */
 
 package cmd
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestListTemplate(t *testing.T) {
+	content := contentMessage{
+		Filetype: "file",
+		Time:     time.Date(2020, 3, 1, 10, 30, 0, 0, time.UTC),
+		Size:     2048,
+		Key:      "dir/object.txt",
+		ETag:     "abc",
+		Owner:    &contentOwner{ID: "owner-id"},
+	}
+	testCases := []struct {
+		template string
+		output   string
+		success  bool
+	}{
+		{`{size}\t{key}\t{modtime}`, "2048\tdir/object.txt\t2020-03-01T10:30:00Z", true},
+		{"{hsize} {etag} {type} {owner}", "2.0KiB abc file owner-id", true},
+		{`literal\\t{key}\n`, "literal\\tdir/object.txt\n", true},
+		{"no placeholders", "no placeholders", true},
+		{"", "", true},
+		{"{key} {name}", "", false},
+		{"{}", "", false},
+	}
+	for i, testCase := range testCases {
+		template, err := newListTemplate(testCase.template)
+		if testCase.success != (err == nil) {
+			t.Fatalf("Test %d: expected success %t, found error %v", i+1, testCase.success, err)
+		}
+		if err != nil {
+			continue
+		}
+		if output := template.format(content); output != testCase.output {
+			t.Fatalf("Test %d: expected %q, found %q", i+1, testCase.output, output)
+		}
+	}
+}
+
+func TestListPrintSize(t *testing.T) {
+	testCases := []struct {
+		content contentMessage
+		size    string
+	}{
+		{contentMessage{Filetype: "file", Size: 1288490189}, "1.2GiB"},
+		{contentMessage{Filetype: "file", Size: 1288490189, isBytes: true}, "1288490189"},
+		{contentMessage{Filetype: "file", Size: 0}, "0B"},
+		// Folders and unknown sizes.
+		{contentMessage{Filetype: "folder", Size: 4096}, "-"},
+		{contentMessage{Filetype: "folder", Size: 4096, isBytes: true}, "-"},
+		{contentMessage{Filetype: "file", Size: -1}, "-"},
+	}
+	for i, testCase := range testCases {
+		if size := testCase.content.printSize(); size != testCase.size {
+			t.Errorf("Test %d: expected %s, got %s", i+1, testCase.size, size)
+		}
+	}
+}
+
+// Test entries listed with --delimiter are printed relative to the
+// listed folder.
+func TestListPrinterDelimiter(t *testing.T) {
+	savedDelimiter := globalListDelimiter
+	globalListDelimiter = "|"
+	defer func() { globalListDelimiter = savedDelimiter }()
+
+	testCases := []struct {
+		url        string
+		prefixPath string
+	}{
+		{"http://localhost:9000/bucket/logs|", "/bucket/logs|"},
+		{"http://localhost:9000/bucket/logs|2019|day", "/bucket/logs|2019|"},
+		{"http://localhost:9000/bucket/dir/logs", "/bucket/dir/"},
+		{"http://localhost:9000/bucket", "/"},
+		{"http://localhost:9000/bucket/", "/bucket/"},
+	}
+	for i, testCase := range testCases {
+		clnt, err := s3New(&Config{HostURL: testCase.url, Signature: "S3v4", Delimiter: "|"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if printer := newListPrinter(clnt, false, "", nil); printer.prefixPath != testCase.prefixPath {
+			t.Errorf("Test %d: expected %q, found %q", i+1, testCase.prefixPath, printer.prefixPath)
+		}
+	}
+
+	// Folders already end with the delimiter.
+	dir := &clientContent{URL: *newClientURL("2019|"), Type: os.ModeDir}
+	if key := getKey(dir); key != "2019|" {
+		t.Errorf("expected 2019|, found %s", key)
+	}
+}
@@ -317,6 +317,7 @@ var appCmds = []cli.Command{
 	mbCmd,
 	rbCmd,
 	cpCmd,
+	mvCmd,
 	mirrorCmd,
 	catCmd,
 	headCmd,
@@ -331,9 +332,15 @@ var appCmds = []cli.Command{
 	retentionCmd,
 	legalHoldCmd,
 	diffCmd,
+	reconcileCmd,
+	scanCmd,
+	mpuCmd,
+	cleanupTempsCmd,
+	sessionCmd,
 	rmCmd,
 	eventCmd,
 	watchCmd,
+	logsCmd,
 	policyCmd,
 	tagCmd,
 	adminCmd,
@@ -354,7 +361,7 @@ func registerApp(name string) *cli.App {
 	app := cli.NewApp()
 	app.Name = name
 	app.Action = func(ctx *cli.Context) {
-		if strings.HasPrefix(ReleaseTag, "RELEASE.") {
+		if strings.HasPrefix(ReleaseTag, "RELEASE.") && !globalOffline {
 			// Check for new updates from dl.min.io.
 			checkUpdate(ctx)
 		}
@@ -141,6 +141,8 @@ func mainMakeBucket(ctx *cli.Context) error {
 				errorIf(err.Trace(targetURL), "Unable to make bucket, please use `mc mb %s/<your-bucket-name>`.", targetURL)
 			case BucketNameTopLevel:
 				errorIf(err.Trace(targetURL), "Unable to make prefix, please use `mc mb %s/`.", targetURL)
+			case BucketExists:
+				errorIf(err.Trace(targetURL), "Unable to make bucket `"+targetURL+"`, use --ignore-existing to leave existing buckets alone.")
 			default:
 				errorIf(err.Trace(targetURL), "Unable to make bucket `"+targetURL+"`.")
 			}
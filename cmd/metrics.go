@@ -0,0 +1,192 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+)
+
+// metricsRegistry - counters of the copy pipeline exposed by
+// `--metrics-addr` in Prometheus text format.
+type metricsRegistry struct {
+	// Keep 64bit counters first for atomic alignment on 32 bit machines.
+	transferredBytes int64
+	succeededObjects int64
+	failedObjects    int64
+	retries          int64
+
+	// Throughput is sampled on scrape, at most once a second.
+	mutex      sync.Mutex
+	lastBytes  int64
+	lastSample time.Time
+	throughput float64
+}
+
+var globalMetrics = &metricsRegistry{lastSample: UTCNow()}
+
+// taskDone accounts for a single finished copy or remove task.
+func (m *metricsRegistry) taskDone(urls URLs) {
+	if urls.Error != nil {
+		atomic.AddInt64(&m.failedObjects, 1)
+		return
+	}
+	atomic.AddInt64(&m.succeededObjects, 1)
+}
+
+// addTransferred accounts for n bytes sent for the first time, bytes
+// sent again by a retry or a restart of an upload are not, see
+// restartProgress.
+func (m *metricsRegistry) addTransferred(n int64) {
+	atomic.AddInt64(&m.transferredBytes, n)
+}
+
+// addRetry accounts for a request sent again.
+func (m *metricsRegistry) addRetry() {
+	atomic.AddInt64(&m.retries, 1)
+}
+
+func (m *metricsRegistry) currentThroughput(transferred int64) float64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := UTCNow()
+	if elapsed := now.Sub(m.lastSample); elapsed >= time.Second {
+		m.throughput = float64(transferred-m.lastBytes) / elapsed.Seconds()
+		m.lastBytes, m.lastSample = transferred, now
+	}
+	return m.throughput
+}
+
+func (m *metricsRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	transferred := atomic.LoadInt64(&m.transferredBytes)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP mc_transferred_bytes_total Total bytes of objects transferred.\n")
+	fmt.Fprintf(w, "# TYPE mc_transferred_bytes_total counter\n")
+	fmt.Fprintf(w, "mc_transferred_bytes_total %d\n", transferred)
+	fmt.Fprintf(w, "# HELP mc_objects_total Total objects processed by status.\n")
+	fmt.Fprintf(w, "# TYPE mc_objects_total counter\n")
+	fmt.Fprintf(w, "mc_objects_total{status=\"success\"} %d\n", atomic.LoadInt64(&m.succeededObjects))
+	fmt.Fprintf(w, "mc_objects_total{status=\"failed\"} %d\n", atomic.LoadInt64(&m.failedObjects))
+	fmt.Fprintf(w, "# HELP mc_throughput_bytes_per_second Bytes transferred per second since the previous sample.\n")
+	fmt.Fprintf(w, "# TYPE mc_throughput_bytes_per_second gauge\n")
+	fmt.Fprintf(w, "mc_throughput_bytes_per_second %g\n", m.currentThroughput(transferred))
+	fmt.Fprintf(w, "# HELP mc_retries_total Total requests retried.\n")
+	fmt.Fprintf(w, "# TYPE mc_retries_total counter\n")
+	fmt.Fprintf(w, "mc_retries_total %d\n", atomic.LoadInt64(&m.retries))
+}
+
+// startTransferMetrics starts what `--metrics-addr` and
+// `--throughput-log` ask for, the returned function stops them.
+func startTransferMetrics(ctx *cli.Context) func() {
+	stopMetrics, stopLog := func() {}, func() {}
+	if addr := ctx.String("metrics-addr"); addr != "" {
+		var err *probe.Error
+		stopMetrics, err = startMetricsServer(addr)
+		fatalIf(err.Trace(addr), "Unable to start metrics server.")
+	}
+	if logPath := ctx.String("throughput-log"); logPath != "" {
+		var err *probe.Error
+		stopLog, err = startThroughputLog(logPath)
+		fatalIf(err.Trace(logPath), "Unable to create throughput log.")
+	}
+	return func() {
+		stopLog()
+		stopMetrics()
+	}
+}
+
+// startMetricsServer serves globalMetrics on addr under `/metrics`,
+// the returned function shuts the server down.
+func startMetricsServer(addr string) (func(), *probe.Error) {
+	listener, e := net.Listen("tcp", addr)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", globalMetrics)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
+
+// throughputLogInterval is the time between two rows of `--throughput-log`.
+const throughputLogInterval = time.Second
+
+// startThroughputLog appends a CSV row of the globalMetrics counters to
+// a new file at path every throughputLogInterval. Rows are flushed as they are written so that
+// an interrupted transfer leaves a usable log, the returned function
+// writes a last row and closes the file.
+func startThroughputLog(path string) (func(), *probe.Error) {
+	f, e := os.Create(path)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "timestamp,bytes_total,bytes_delta,objects_done")
+	if e = w.Flush(); e != nil {
+		f.Close()
+		return nil, probe.NewError(e)
+	}
+
+	var lastBytes int64
+	writeRow := func() {
+		transferred := atomic.LoadInt64(&globalMetrics.transferredBytes)
+		fmt.Fprintf(w, "%s,%d,%d,%d\n", UTCNow().Format(time.RFC3339), transferred,
+			transferred-lastBytes, atomic.LoadInt64(&globalMetrics.succeededObjects))
+		lastBytes = transferred
+		w.Flush()
+	}
+
+	doneCh := make(chan struct{})
+	stoppedCh := make(chan struct{})
+	go func() {
+		defer close(stoppedCh)
+		ticker := time.NewTicker(throughputLogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				writeRow()
+			case <-doneCh:
+				writeRow()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(doneCh)
+		<-stoppedCh
+		f.Close()
+	}, nil
+}
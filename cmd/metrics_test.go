@@ -0,0 +1,118 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+// newTestMetrics replaces globalMetrics with empty counters until the
+// returned function is called.
+func newTestMetrics() func() {
+	saved := globalMetrics
+	globalMetrics = &metricsRegistry{lastSample: UTCNow()}
+	return func() { globalMetrics = saved }
+}
+
+func TestRestartProgressMetrics(t *testing.T) {
+	defer newTestMetrics()()
+
+	var reported progressCounter
+	restartable := &restartProgress{progress: &reported}
+	data := make([]byte, 10)
+	// A part uploaded before a resume is reported, not transferred.
+	restartable.skip(4)
+	restartable.Read(data[:6])
+	// A retry sends the first bytes again.
+	restartable.restart()
+	restartable.Read(data)
+	restartable.Read(data[:5])
+
+	if reported != 15 {
+		t.Errorf("expected 15 bytes reported, got %d", reported)
+	}
+	if transferred := globalMetrics.transferredBytes; transferred != 11 {
+		t.Errorf("expected 11 bytes transferred, got %d", transferred)
+	}
+}
+
+func TestMetricsServeHTTP(t *testing.T) {
+	defer newTestMetrics()()
+
+	globalMetrics.addTransferred(1024)
+	globalMetrics.taskDone(URLs{SourceContent: &clientContent{Size: 1024}})
+	globalMetrics.taskDone(URLs{SourceContent: &clientContent{Size: 1024}})
+	globalMetrics.taskDone(URLs{Error: probe.NewError(os.ErrNotExist)})
+	globalMetrics.addRetry()
+
+	recorder := httptest.NewRecorder()
+	globalMetrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"mc_transferred_bytes_total 1024\n",
+		"mc_objects_total{status=\"success\"} 2\n",
+		"mc_objects_total{status=\"failed\"} 1\n",
+		"mc_retries_total 1\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected %q in\n%s", line, body)
+		}
+	}
+}
+
+func TestThroughputLog(t *testing.T) {
+	defer newTestMetrics()()
+
+	tmpDir, e := ioutil.TempDir("", "throughput-log-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "tput.csv")
+	stopLog, err := startThroughputLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalMetrics.addTransferred(100)
+	globalMetrics.taskDone(URLs{})
+	stopLog()
+
+	data, e := ioutil.ReadFile(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	rows := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(rows) != 2 || rows[0] != "timestamp,bytes_total,bytes_delta,objects_done" {
+		t.Fatalf("unexpected log %q", data)
+	}
+	fields := strings.Split(rows[1], ",")
+	if _, e = time.Parse(time.RFC3339, fields[0]); e != nil {
+		t.Errorf("unexpected timestamp %q", fields[0])
+	}
+	if strings.Join(fields[1:], ",") != "100,100,1" {
+		t.Errorf("expected 100,100,1 got %s", strings.Join(fields[1:], ","))
+	}
+}
@@ -17,14 +17,18 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
@@ -38,24 +42,23 @@ import (
 var (
 	mirrorFlags = []cli.Flag{
 		cli.BoolFlag{
-			Name:   "force",
-			Usage:  "force allows forced overwrite or removal of object(s) on target",
-			Hidden: true, // Hidden since this option is deprecated.
+			Name:  "force",
+			Usage: "mirror every object, overwriting those which already match on target",
 		},
 		cli.BoolFlag{
 			Name:  "overwrite",
 			Usage: "overwrite object(s) on target",
 		},
 		cli.BoolFlag{
-			Name:  "fake",
-			Usage: "perform a fake mirror operation",
+			Name:  "fake, dry-run",
+			Usage: "perform a fake mirror operation, printing the object(s) --remove would remove",
 		},
 		cli.BoolFlag{
 			Name:  "watch, w",
 			Usage: "watch and synchronize changes",
 		},
 		cli.BoolFlag{
-			Name:  "remove",
+			Name:  "remove, delete",
 			Usage: "remove extraneous object(s) on target",
 		},
 		cli.StringFlag{
@@ -67,13 +70,17 @@ var (
 			Name:  "preserve, a",
 			Usage: "preserve file(s)/object(s) attributes and bucket policy rules on target bucket(s)",
 		},
+		cli.BoolFlag{
+			Name:  "preserve-mtime",
+			Usage: "preserve source modification time on target and use it to detect changes",
+		},
 		cli.StringFlag{
 			Name:  "multi-master",
 			Usage: `multi-master multi-site setup, "value" is the site tag for the multi-master deployment`,
 		},
 		cli.StringSliceFlag{
 			Name:  "exclude",
-			Usage: "exclude object(s) that match specified object name pattern",
+			Usage: "exclude object(s) that match specified object name pattern, a pattern ending with '/' excludes whole folders",
 		},
 		cli.StringFlag{
 			Name:  "older-than",
@@ -93,7 +100,43 @@ var (
 		},
 		cli.StringFlag{
 			Name:  "attr",
-			Usage: "add custom metadata for all objects",
+			Usage: "add custom metadata for all objects, as a comma separated list of key=value",
+		},
+		cli.StringFlag{
+			Name:  "limit-upload",
+			Usage: "limit the aggregate rate of uploads to object storage, e.g. \"512KiB/s\" or \"10MB/s\"",
+		},
+		cli.StringFlag{
+			Name:  "limit-download",
+			Usage: "limit the aggregate rate of downloads from object storage, e.g. \"512KiB/s\" or \"10MB/s\"",
+		},
+		cli.StringFlag{
+			Name:  "metrics-addr",
+			Usage: "serve Prometheus metrics of the transfer on this address, e.g. \":9090\"",
+		},
+		cli.StringFlag{
+			Name:  "throughput-log",
+			Usage: "write transferred bytes and objects to this CSV file every second",
+		},
+		cli.StringFlag{
+			Name:  "error-report",
+			Usage: "write every failure of the run to this JSON file once it is over",
+		},
+		cli.BoolFlag{
+			Name:  "ramp-up",
+			Usage: "start with a single worker and gradually add more up to --parallel if set, backing off on errors",
+		},
+		cli.IntFlag{
+			Name:  "parallel",
+			Usage: "number of objects copied at the same time, by default workers are added while the transfer speeds up",
+		},
+		cli.BoolFlag{
+			Name:  "staged",
+			Usage: "upload to a temporary prefix and publish all object(s) only after every upload succeeded",
+		},
+		cli.BoolFlag{
+			Name:  "continue, c",
+			Usage: "create or resume a mirror session, recording the object(s) mirrored to check them with 'mc session resume --verify-done'",
 		},
 	}
 )
@@ -109,7 +152,7 @@ var mirrorCmd = cli.Command{
   {{.HelpName}} - {{.Usage}}
 
 USAGE:
-  {{.HelpName}} [FLAGS] SOURCE TARGET
+  {{.HelpName}} [FLAGS] SOURCE TARGET [TARGET...]
 
 FLAGS:
   {{range .VisibleFlags}}{{.}}
@@ -159,8 +202,8 @@ EXAMPLES:
       non-printable character like tab, pass the base64 encoded string as key.
       {{.Prompt}} {{.HelpName}} --encrypt-key "s3/photos/=32byteslongsecretkeymustbegiven1,play/archive/=MzJieXRlc2xvbmdzZWNyZXRrZQltdXN0YmVnaXZlbjE=" s3/photos/ play/archive/
 
-  13. Update 'Cache-Control' header on all existing objects recursively.
-      {{.Prompt}} {{.HelpName}} --attr "Cache-Control=max-age=90000,min-fresh=9000" myminio/video-files myminio/video-files
+  13. Update 'Cache-Control' header on all existing objects recursively, a list separated by ";" keeps the commas of its values.
+      {{.Prompt}} {{.HelpName}} --attr "Cache-Control=max-age=90000,min-fresh=9000;" myminio/video-files myminio/video-files
 
   14. Mirror a local folder recursively to Amazon S3 cloud storage and preserve all local file attributes.
       {{.Prompt}} {{.HelpName}} -a backup/ s3/archive
@@ -168,6 +211,46 @@ EXAMPLES:
   15. Cross mirror between sites in a multi-master deployment.
       Site-A: {{.Prompt}} {{.HelpName}} --watch --multi-master splunk-smartstore1 siteA siteB
       Site-B: {{.Prompt}} {{.HelpName}} --watch --multi-master splunk-smartstore1 siteB siteA
+
+  16. Mirror a local folder to Amazon S3 cloud storage keeping file modification times, later runs
+      update objects whose source was modified after the preserved time.
+      {{.Prompt}} {{.HelpName}} --preserve-mtime --overwrite backup/ s3/archive
+
+  17. Mirror a large bucket to a freshly started MinIO server without flooding it with requests at once.
+      {{.Prompt}} {{.HelpName}} --ramp-up s3/archive myminio/archive
+
+  18. Continuously mirror a local folder exposing transfer metrics at http://localhost:9090/metrics.
+      {{.Prompt}} {{.HelpName}} --watch --metrics-addr :9090 /var/lib/backups play/backups
+
+  19. Publish a static website, new pages only replace the old ones once all of them are uploaded.
+      The swap runs as server side copies after the upload, the changes of the run are listed
+      in '.mc-manifest.json' at the target root.
+      {{.Prompt}} {{.HelpName}} --staged --overwrite --remove public/ s3/www
+
+  20. Mirror a bucket to another site recording the throughput over time in a CSV file.
+      {{.Prompt}} {{.HelpName}} --throughput-log tput.csv s3/archive play/archive
+
+  21. Mirror a local folder over a shared link, uploading at most 2MiB per second in total.
+      {{.Prompt}} {{.HelpName}} --limit-upload 2MiB/s /var/lib/backups s3/backups
+
+  22. Mirror a bucket and keep the objects which failed in a JSON report.
+      {{.Prompt}} {{.HelpName}} --error-report failures.json s3/photos play/photos
+
+  23. Mirror a bucket of many small objects, copying 32 of them at a time.
+      {{.Prompt}} {{.HelpName}} --parallel 32 s3/thumbnails play/thumbnails
+
+  24. Mirror a local folder both to a bucket on Amazon S3 and to a local backup disk.
+      {{.Prompt}} {{.HelpName}} ~/photos s3/backup-photos /mnt/backup/photos
+
+  25. Preview the extraneous objects which mirroring with --remove would delete from a backup bucket.
+      {{.Prompt}} {{.HelpName}} --remove --dry-run play/photos/2014 s3/backup-photos/2014
+
+  26. Mirror every object of a bucket again, even those whose size and modification time match on the target.
+      {{.Prompt}} {{.HelpName}} --force s3/archive play/archive
+
+  27. Mirror a bucket in a session, once interrupted resume it checking the objects mirrored before first.
+      {{.Prompt}} {{.HelpName}} --continue s3/archive play/archive
+      {{.Prompt}} mc session resume --verify-done mirror-7b2f0e9c
 `,
 }
 
@@ -201,15 +284,28 @@ type mirrorJob struct {
 
 	isFake, isRemove, isOverwrite bool
 	isWatch, isPreserve           bool
+	isPreserveMtime, isRampUp     bool
+	isForce                       bool
 	olderThan, newerThan          string
 	storageClass                  string
 	userMetadata                  map[string]string
 
 	excludeOptions []string
+	excludes       *excludeFilter
 	encKeyDB       map[string][]prefixSSEPair
 
 	multiMasterEnable bool
 	multiMasterSTag   string
+
+	// Set for staged mirrors only.
+	stage *mirrorStage
+
+	// Set for mirrors run in a session, see mirror --continue.
+	session    *sessionV8
+	checkpoint *sessionCheckpoint
+	// Objects recorded by the session checked before mirroring.
+	isVerifyDone bool
+	verified     verifyDoneMessage
 }
 
 // mirrorMessage container for file mirror messages
@@ -242,30 +338,7 @@ func (mj *mirrorJob) doRemove(sURLs URLs) URLs {
 		return sURLs.WithError(nil)
 	}
 
-	// Construct proper path with alias.
-	targetWithAlias := filepath.Join(sURLs.TargetAlias, sURLs.TargetContent.URL.Path)
-	clnt, pErr := newClient(targetWithAlias)
-	if pErr != nil {
-		return sURLs.WithError(pErr)
-	}
-	clnt.AddUserAgent(uaMirrorAppName, Version)
-	contentCh := make(chan *clientContent, 1)
-	contentCh <- &clientContent{URL: *newClientURL(sURLs.TargetContent.URL.Path)}
-	close(contentCh)
-	isRemoveBucket := false
-	errorCh := clnt.Remove(false, isRemoveBucket, contentCh)
-	for pErr := range errorCh {
-		if pErr != nil {
-			switch pErr.ToGoError().(type) {
-			case PathInsufficientPermission:
-				// Ignore Permission error.
-				continue
-			}
-			return sURLs.WithError(pErr)
-		}
-	}
-
-	return sURLs.WithError(nil)
+	return removeTargetURL(sURLs, uaMirrorAppName)
 }
 
 // doMirror - Mirror an object to multiple destination. URLs status contains a copy of sURLs and error if any.
@@ -321,6 +394,10 @@ func (mj *mirrorJob) doMirror(ctx context.Context, cancelMirror context.CancelFu
 		}
 	}
 
+	if mj.isPreserveMtime {
+		sURLs.TargetContent.Metadata[mtimeMetaKey] = getContentModTime(sURLs.SourceContent).Format(time.RFC3339Nano)
+	}
+
 	// Initialize additional target user metadata.
 	sURLs.TargetContent.UserMetadata = mj.userMetadata
 
@@ -349,15 +426,19 @@ func (mj *mirrorJob) monitorMirrorStatus() (errDuringMirror bool) {
 				if !isErrIgnored(sURLs.Error) {
 					errorIf(sURLs.Error.Trace(sURLs.SourceContent.URL.String()),
 						fmt.Sprintf("Failed to copy `%s`.", sURLs.SourceContent.URL.String()))
+					globalErrorReport.add(sURLs.SourceContent.URL.String(),
+						sURLs.operation, sURLs.attempts, sURLs.Error)
 					errDuringMirror = true
 				}
 			case sURLs.TargetContent != nil:
 				// When sURLs.SourceContent is nil, we know that we have an error related to removing
 				errorIf(sURLs.Error.Trace(sURLs.TargetContent.URL.String()),
 					fmt.Sprintf("Failed to remove `%s`.", sURLs.TargetContent.URL.String()))
+				globalErrorReport.add(sURLs.TargetContent.URL.String(), "delete", 1, sURLs.Error)
 				errDuringMirror = true
 			default:
 				errorIf(sURLs.Error.Trace(), "Failed to perform mirroring.")
+				globalErrorReport.add("", "list", 1, sURLs.Error)
 				errDuringMirror = true
 			}
 			if mj.multiMasterEnable {
@@ -367,10 +448,21 @@ func (mj *mirrorJob) monitorMirrorStatus() (errDuringMirror bool) {
 		}
 
 		if sURLs.SourceContent != nil {
+			if sURLs.Error == nil && mj.session != nil {
+				mj.recordMirrored(sURLs)
+			}
 		} else if sURLs.TargetContent != nil {
 			// Construct user facing message and path.
 			targetPath := filepath.ToSlash(filepath.Join(sURLs.TargetAlias, sURLs.TargetContent.URL.Path))
 			size := sURLs.TargetContent.Size
+			if mj.isFake {
+				// Removals are previewed under the progress bar as well.
+				if !globalQuiet && !globalJSON {
+					console.Eraseline()
+				}
+				printMsg(rmMessage{Key: targetPath, Size: size})
+				continue
+			}
 			mj.status.PrintMsg(rmMessage{Key: targetPath, Size: size})
 		}
 	}
@@ -378,6 +470,69 @@ func (mj *mirrorJob) monitorMirrorStatus() (errDuringMirror bool) {
 	return
 }
 
+// recordMirrored appends an object mirrored in a session to its data
+// file, see verifyDone.
+func (mj *mirrorJob) recordMirrored(sURLs URLs) {
+	data, e := json.Marshal(sURLs)
+	if e == nil {
+		_, e = mj.session.DataFP.Write(append(data, '\n'))
+	}
+	if e != nil {
+		errorIf(probe.NewError(e).Trace(mj.session.SessionID), "Unable to record `%s` in session.", sURLs.SourceContent.URL.String())
+		return
+	}
+	mj.session.mutex.Lock()
+	mj.session.Header.LastCopied = sURLs.SourceContent.URL.String()
+	mj.session.mutex.Unlock()
+	if mj.checkpoint.due() {
+		errorIf(mj.session.Save().Trace(mj.session.SessionID), "Unable to save session.")
+	}
+}
+
+// verifyDone checks the targets of the objects recorded by the session
+// before this run, those which are missing, of another size or not
+// matching their stored checksum are mirrored again.
+func (mj *mirrorJob) verifyDone(ctx context.Context, cancelMirror context.CancelFunc) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Objects mirrored again are recorded again, they are not read.
+	urlScanner := bufio.NewScanner(mj.session.newDataSnapshotReader())
+	for urlScanner.Scan() {
+		var sURLs URLs
+		if e := json.Unmarshal(urlScanner.Bytes(), &sURLs); e != nil || sURLs.SourceContent == nil || sURLs.TargetContent == nil {
+			continue
+		}
+		wg.Add(1)
+		mj.queueCh <- func() URLs {
+			defer wg.Done()
+			atomic.AddInt64(&mj.verified.Verified, 1)
+			mismatch, err := verifyCopied(sURLs, mj.encKeyDB)
+			if mismatch == nil && err == nil {
+				return URLs{}
+			}
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			if mismatch != nil {
+				warningIf(mismatch, "Mirroring `%s` again.", sURLs.SourceContent.URL.String())
+			} else {
+				errorIf(err, "Unable to verify the mirror of `%s`, mirroring it again.", sURLs.SourceContent.URL.String())
+			}
+			atomic.AddInt64(&mj.verified.Requeued, 1)
+			mj.status.Add(sURLs.SourceContent.Size)
+			mj.status.SetTotal(mj.status.Get()).Update()
+			mj.status.AddCounts(1)
+			sURLs.TotalSize = mj.status.Get()
+			sURLs.TotalCount = mj.status.GetCounts()
+			return mj.doMirror(ctx, cancelMirror, sURLs)
+		}
+	}
+	if e := urlScanner.Err(); e != nil {
+		errorIf(probe.NewError(e).Trace(mj.session.SessionID), "Unable to read the objects mirrored by session.")
+	}
+}
+
 // this goroutine will watch for notifications, and add modified objects to the queue
 func (mj *mirrorJob) watchMirror(ctx context.Context, cancelMirror context.CancelFunc) {
 	for {
@@ -415,7 +570,7 @@ func (mj *mirrorJob) watchMirror(ctx context.Context, cancelMirror context.Cance
 			// joined to the targetURL.
 			sourceSuffix := strings.TrimPrefix(eventPath, sourceURLFull)
 			//Skip the object, if it matches the Exclude options provided
-			if matchExcludeOptions(mj.excludeOptions, sourceSuffix) {
+			if mj.excludes.skip(sourceSuffix) {
 				continue
 			}
 
@@ -547,8 +702,19 @@ func (mj *mirrorJob) startMirror(ctx context.Context, cancelMirror context.Cance
 	mj.m.Lock()
 	defer mj.m.Unlock()
 
-	isMetadata := len(mj.userMetadata) > 0 || mj.isPreserve
-	URLsCh := prepareMirrorURLs(mj.sourceURL, mj.targetURL, mj.isFake, mj.isOverwrite, mj.isRemove, isMetadata, mj.excludeOptions, mj.encKeyDB)
+	// Objects found again by the comparison below are not verified.
+	if mj.isVerifyDone {
+		mj.verifyDone(ctx, cancelMirror)
+	}
+
+	// Preserved modification times are stored as object metadata.
+	isMetadata := len(mj.userMetadata) > 0 || mj.isPreserve || mj.isPreserveMtime
+	// Objects of the same size are also mirrored again when the source
+	// was modified after the target was written, without --overwrite.
+	// Only preserved times need the metadata of the target, the others
+	// are last modified times.
+	isMtime := true
+	URLsCh := prepareMirrorURLs(mj.sourceURL, mj.targetURL, mj.isFake, mj.isOverwrite, mj.isForce, mj.isRemove, isMetadata, isMtime, mj.excludes, mj.encKeyDB)
 
 	for {
 		select {
@@ -584,6 +750,16 @@ func (mj *mirrorJob) startMirror(ctx context.Context, cancelMirror context.Cance
 			// Save totalSize.
 			sURLs.TotalSize = mj.status.Get()
 
+			if mj.stage != nil {
+				if sURLs.SourceContent != nil {
+					sURLs = mj.stage.stage(sURLs)
+				} else if sURLs.TargetContent != nil && mj.isRemove {
+					// Extraneous objects are removed on publish.
+					mj.stage.stageRemove(sURLs)
+					continue
+				}
+			}
+
 			if sURLs.SourceContent != nil {
 				mj.queueCh <- func() URLs {
 					return mj.doMirror(ctx, cancelMirror, sURLs)
@@ -644,7 +820,7 @@ func (mj *mirrorJob) mirror(ctx context.Context, cancelMirror context.CancelFunc
 	return mj.monitorMirrorStatus()
 }
 
-func newMirrorJob(srcURL, dstURL string, isFake, isRemove, isOverwrite, isWatch, isPreserve, multiMasterEnable bool, excludeOptions []string, olderThan, newerThan string, storageClass string, multiMasterSTag string, userMetadata map[string]string, encKeyDB map[string][]prefixSSEPair) *mirrorJob {
+func newMirrorJob(srcURL, dstURL string, isFake, isRemove, isOverwrite, isWatch, isPreserve, isPreserveMtime, isForce, isRampUp, isStaged, multiMasterEnable bool, parallel int, excludeOptions []string, olderThan, newerThan string, storageClass string, multiMasterSTag string, userMetadata map[string]string, encKeyDB map[string][]prefixSSEPair) *mirrorJob {
 	if multiMasterEnable {
 		isPreserve = true
 	}
@@ -659,6 +835,9 @@ func newMirrorJob(srcURL, dstURL string, isFake, isRemove, isOverwrite, isWatch,
 		isOverwrite:       isOverwrite,
 		isWatch:           isWatch,
 		isPreserve:        isPreserve,
+		isPreserveMtime:   isPreserveMtime,
+		isForce:           isForce,
+		isRampUp:          isRampUp,
 		excludeOptions:    excludeOptions,
 		olderThan:         olderThan,
 		newerThan:         newerThan,
@@ -671,7 +850,13 @@ func newMirrorJob(srcURL, dstURL string, isFake, isRemove, isOverwrite, isWatch,
 		multiMasterSTag:   multiMasterSTag,
 	}
 
-	mj.parallel, mj.queueCh = newParallelManager(mj.statusCh)
+	if isStaged && !isFake {
+		mj.stage = newMirrorStage(dstURL)
+		mj.excludeOptions = append(mj.stage.excludeOptions(), excludeOptions...)
+	}
+	mj.excludes = newExcludeFilter(mj.excludeOptions)
+
+	mj.parallel, mj.queueCh = newParallelManager(mj.statusCh, mj.isRampUp, parallel)
 
 	// we'll define the status to use here,
 	// do we want the quiet status? or the progressbar
@@ -739,8 +924,13 @@ func getEventPathURLWin(srcURL, eventPath string) string {
 
 // runMirror - mirrors all buckets to another S3 server
 func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]prefixSSEPair) bool {
-	// This is kept for backward compatibility, `--force` means
-	// --overwrite.
+	return runMirrorSession(srcURL, dstURL, ctx, encKeyDB, nil, false)
+}
+
+// runMirrorSession - runMirror recording the objects mirrored in
+// session when it is not nil, see doMirrorSession.
+func runMirrorSession(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]prefixSSEPair, session *sessionV8, isVerifyDone bool) bool {
+	// Objects mirrored unconditionally with `--force` are overwritten.
 	isOverwrite := ctx.Bool("force")
 	if !isOverwrite {
 		isOverwrite = ctx.Bool("overwrite")
@@ -757,7 +947,18 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 	srcClt, err := newClient(srcURL)
 	fatalIf(err, "Unable to initialize `"+srcURL+"`.")
 
+	multiMasterSTag := ctx.String("multi-master")
+	multiMasterEnable := multiMasterSTag != ""
+
+	// Failing to start mirroring to a target only stops that target
+	// when there are others to mirror to.
+	isKeepGoing := multiMasterEnable || len(ctx.Args()) > 2
+
 	dstClt, err := newClient(dstURL)
+	if isKeepGoing && err != nil {
+		errorIf(err, "Unable to initialize `"+dstURL+"`.")
+		return true
+	}
 	fatalIf(err, "Unable to initialize `"+dstURL+"`.")
 
 	mirrorAllBuckets := (dstClt.GetURL().Type == objectStorage &&
@@ -771,12 +972,13 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 		dstURL = urlJoinPath(dstURL, srcClt.GetURL().Path)
 
 		dstClt, err = newClient(dstURL)
+		if isKeepGoing && err != nil {
+			errorIf(err, "Unable to initialize `"+dstURL+"`.")
+			return true
+		}
 		fatalIf(err, "Unable to initialize `"+dstURL+"`.")
 	}
 
-	multiMasterSTag := ctx.String("multi-master")
-	multiMasterEnable := multiMasterSTag != ""
-
 	// Create a new mirror job and execute it
 	mj := newMirrorJob(srcURL, dstURL,
 		ctx.Bool("fake"),
@@ -784,7 +986,12 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 		isOverwrite,
 		ctx.Bool("watch"),
 		ctx.Bool("a"),
+		ctx.Bool("preserve-mtime"),
+		ctx.Bool("force"),
+		ctx.Bool("ramp-up"),
+		ctx.Bool("staged"),
 		multiMasterEnable,
+		ctx.Int("parallel"),
 		ctx.StringSlice("exclude"),
 		ctx.String("older-than"),
 		ctx.String("newer-than"),
@@ -792,17 +999,41 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 		multiMasterSTag,
 		userMetaMap,
 		encKeyDB)
+	if isKeepGoing && !multiMasterEnable {
+		// Targets are mirrored together, progress bars would
+		// overwrite each other.
+		mj.status = NewQuietStatus(mj.parallel)
+	}
+	if session != nil {
+		mj.session = session
+		mj.isVerifyDone = isVerifyDone
+		var err *probe.Error
+		mj.checkpoint, err = newSessionCheckpoint(session.Header.CommandStringFlags["checkpoint-interval"])
+		fatalIf(err, "Unable to parse checkpoint interval.")
+	}
 
 	go func() {
 		<-globalContext.Done()
+		globalErrorReport.write()
+		if session != nil {
+			session.CloseAndDie()
+		}
 		os.Exit(globalErrorExitStatus)
 	}()
 
+	if mirrorAllBuckets && mj.stage != nil {
+		if isKeepGoing {
+			errorIf(errInvalidArgument().Trace(dstURL), "`--staged` requires a bucket or folder as target.")
+			return true
+		}
+		fatalIf(errInvalidArgument().Trace(dstURL), "`--staged` requires a bucket or folder as target.")
+	}
+
 	if mirrorAllBuckets {
 		// Synchronize buckets using dirDifference function
 		for d := range dirDifference(srcClt, dstClt, srcURL, dstURL) {
 			if d.Error != nil {
-				if mj.multiMasterEnable {
+				if isKeepGoing {
 					errorIf(d.Error, "Failed to start mirroring.")
 					return true
 				}
@@ -861,7 +1092,7 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 
 		// Create bucket if it doesn't exist at destination.
 		// ignore if already exists.
-		if mj.multiMasterEnable {
+		if isKeepGoing {
 			err = dstClt.MakeBucket(ctx.String("region"), true, withLock)
 			errorIf(err, "Unable to create bucket at `"+dstURL+"`.")
 			if err != nil {
@@ -904,7 +1135,21 @@ func runMirror(srcURL, dstURL string, ctx *cli.Context, encKeyDB map[string][]pr
 	defer cancelMirror()
 
 	// Start mirroring job
-	return mj.mirror(ctxt, cancelMirror)
+	errDuringMirror := mj.mirror(ctxt, cancelMirror)
+	mj.excludes.printExcluded()
+	if mj.isVerifyDone {
+		printMsg(mj.verified)
+	}
+	if mj.stage != nil {
+		// Nothing is published unless every object made it to staging.
+		if !errDuringMirror {
+			err := mj.stage.publish(encKeyDB)
+			errorIf(err, "Unable to publish staged objects to `"+dstURL+"`.")
+			errDuringMirror = err != nil
+		}
+		errorIf(mj.stage.cleanup(), "Unable to remove staging folder of `"+dstURL+"`, remove it with `mc cleanup-temps "+dstURL+"`.")
+	}
+	return errDuringMirror
 }
 
 // Main entry point for mirror command.
@@ -918,30 +1163,189 @@ func mainMirror(ctx *cli.Context) error {
 
 	// Additional command specific theme customization.
 	console.SetColor("Mirror", color.New(color.FgGreen, color.Bold))
+	console.SetColor("Excluded", color.New(color.FgYellow))
+
+	err = setBandwidthLimits(ctx.String("limit-upload"), ctx.String("limit-download"))
+	fatalIf(err, "Unable to limit bandwidth.")
+
+	defer startTransferMetrics(ctx)()
+
+	if reportPath := ctx.String("error-report"); reportPath != "" {
+		writeReport, err := startErrorReport("mirror", reportPath)
+		fatalIf(err.Trace(reportPath), "Unable to create error report.")
+		defer writeReport()
+	}
 
 	args := ctx.Args()
 
-	srcURL := args[0]
-	tgtURL := args[1]
-	srcFI, e := os.Stat(srcURL)
-	if e == nil && srcFI.IsDir() && !filepath.IsAbs(srcURL) {
-		origSrcURL := srcURL
-		// Changing relative path to absolute path, if it is a local directory.
-		// Save original in case of error
-		if srcURL, e = filepath.Abs(srcURL); e != nil {
-			srcURL = origSrcURL
+	if ctx.Bool("continue") {
+		var session *sessionV8
+		sessionID := getHash("mirror", args)
+		if isSessionExists(sessionID) {
+			session, err = resumeSessionV8(sessionID)
+			fatalIf(err.Trace(sessionID), "Unable to load session.")
+		} else {
+			expireSessions()
+			session = newMirrorSession(ctx, sessionID, args)
 		}
+		return doMirrorSession(session, false)
 	}
+
+	srcURL := mirrorSourceURL(args[0])
 	if ctx.String("multi-master") != "" {
 		for {
-			runMirror(srcURL, tgtURL, ctx, encKeyDB)
+			runMirror(srcURL, args[1], ctx, encKeyDB)
 			time.Sleep(time.Second * 2)
 		}
 	}
 
-	if errorDetected := runMirror(srcURL, tgtURL, ctx, encKeyDB); errorDetected {
+	errorDetected := mirrorTargets(args[1:], func(tgtURL string) bool {
+		return runMirror(srcURL, tgtURL, ctx, encKeyDB)
+	})
+	if errorDetected {
 		return exitStatus(globalErrorExitStatus)
 	}
 
 	return nil
 }
+
+// mirrorSourceURL - the absolute path of a local source folder, other
+// sources are returned as they are.
+func mirrorSourceURL(srcURL string) string {
+	srcFI, e := os.Stat(srcURL)
+	if e == nil && srcFI.IsDir() && !filepath.IsAbs(srcURL) {
+		// Keep the original in case of error.
+		if absURL, e := filepath.Abs(srcURL); e == nil {
+			return absURL
+		}
+	}
+	return srcURL
+}
+
+// newMirrorSession - starts a session of mirror --continue, the flags
+// it is resumed with are saved in its header.
+func newMirrorSession(ctx *cli.Context, sessionID string, args []string) *sessionV8 {
+	session := newSessionV8(sessionID)
+	session.Header.CommandType = "mirror"
+	session.Header.CommandArgs = args
+	for _, name := range []string{"force", "overwrite", "remove", "preserve", "preserve-mtime", "ramp-up"} {
+		session.Header.CommandBoolFlags[name] = ctx.Bool(name)
+	}
+	for _, name := range []string{"region", "older-than", "newer-than", "storage-class", "attr", "limit-upload", "limit-download"} {
+		session.Header.CommandStringFlags[name] = ctx.String(name)
+	}
+	session.Header.CommandStringFlags["exclude"] = strings.Join(ctx.StringSlice("exclude"), "\n")
+	session.Header.CommandIntFlags["parallel"] = ctx.Int("parallel")
+
+	// Keys are saved as getEncKeys reads them, from the environment
+	// when not given.
+	sseServer := os.Getenv("MC_ENCRYPT")
+	if prefix := ctx.String("encrypt"); prefix != "" {
+		sseServer = prefix
+	}
+	sseKeys := os.Getenv("MC_ENCRYPT_KEY")
+	if key := ctx.String("encrypt-key"); key != "" {
+		sseKeys = key
+	}
+	if sseKeys != "" {
+		var err *probe.Error
+		sseKeys, err = getDecodedKey(sseKeys)
+		fatalIf(err, "Unable to parse encryption keys.")
+	}
+	session.Header.CommandStringFlags["encrypt-key"] = sseKeys
+	session.Header.CommandStringFlags["encrypt"] = sseServer
+
+	var e error
+	if session.Header.RootPath, e = os.Getwd(); e != nil {
+		session.Delete()
+		fatalIf(probe.NewError(e), "Unable to get current working folder.")
+	}
+	return session
+}
+
+// mirrorSessionContext - the context of the mirror command which
+// started session, with the flags saved in its header.
+func mirrorSessionContext(session *sessionV8) *cli.Context {
+	set := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	for _, flags := range [][]cli.Flag{mirrorFlags, ioFlags, globalFlags} {
+		for _, f := range flags {
+			f.Apply(set)
+		}
+	}
+	var e error
+	for name, value := range session.Header.CommandBoolFlags {
+		if e == nil {
+			e = set.Set(name, strconv.FormatBool(value))
+		}
+	}
+	for name, value := range session.Header.CommandIntFlags {
+		if e == nil {
+			e = set.Set(name, strconv.Itoa(value))
+		}
+	}
+	for name, value := range session.Header.CommandStringFlags {
+		switch {
+		case e != nil:
+		case name == "exclude":
+			for _, pattern := range strings.Split(value, "\n") {
+				if pattern != "" && e == nil {
+					e = set.Set(name, pattern)
+				}
+			}
+		default:
+			e = set.Set(name, value)
+		}
+	}
+	if e == nil {
+		e = set.Parse(session.Header.CommandArgs)
+	}
+	fatalIf(probe.NewError(e).Trace(session.SessionID), "Unable to load the flags of session.")
+	return cli.NewContext(nil, set, nil)
+}
+
+// doMirrorSession - mirrors the source of session to its target with
+// the flags it was started with, recording each object mirrored in its
+// data file. With isVerifyDone the objects recorded by an earlier run
+// are checked first. The session is removed once mirrored without error.
+func doMirrorSession(session *sessionV8, isVerifyDone bool) error {
+	ctx := mirrorSessionContext(session)
+	encKeyDB, err := parseAndValidateEncryptionKeys(session.Header.CommandStringFlags["encrypt-key"], session.Header.CommandStringFlags["encrypt"])
+	fatalIf(err, "Unable to parse encryption keys.")
+	err = setBandwidthLimits(session.Header.CommandStringFlags["limit-upload"], session.Header.CommandStringFlags["limit-download"])
+	fatalIf(err, "Unable to limit bandwidth.")
+
+	if session.HasData() {
+		session.NewDataAppender()
+	} else {
+		session.NewDataWriter()
+	}
+
+	args := session.Header.CommandArgs
+	if runMirrorSession(mirrorSourceURL(args[0]), args[1], ctx, encKeyDB, session, isVerifyDone) {
+		return session.copyCloseAndDie(true)
+	}
+	session.Delete()
+	return nil
+}
+
+// mirrorTargets - mirrors to all targets at the same time, each target
+// having its own clients a failing target does not stop the others.
+// Returns true when mirroring to any of them failed.
+func mirrorTargets(tgtURLs []string, mirror func(tgtURL string) bool) bool {
+	errs := make([]bool, len(tgtURLs))
+	var wg sync.WaitGroup
+	for i, tgtURL := range tgtURLs {
+		wg.Add(1)
+		go func(i int, tgtURL string) {
+			defer wg.Done()
+			errs[i] = mirror(tgtURL)
+		}(i, tgtURL)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err {
+			return true
+		}
+	}
+	return false
+}
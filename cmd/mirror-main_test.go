@@ -0,0 +1,290 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/cli"
+)
+
+func TestMirrorTargets(t *testing.T) {
+	tgtURLs := []string{"s3/bucket", "/backup/a", "/backup/b"}
+
+	// Every target waits for the others to start.
+	var started sync.WaitGroup
+	started.Add(len(tgtURLs))
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+
+	var mutex sync.Mutex
+	var mirrored []string
+	errorDetected := mirrorTargets(tgtURLs, func(tgtURL string) bool {
+		started.Done()
+		select {
+		case <-allStarted:
+		case <-time.After(10 * time.Second):
+			t.Errorf("%s: targets are not mirrored at the same time", tgtURL)
+			return true
+		}
+		mutex.Lock()
+		mirrored = append(mirrored, tgtURL)
+		mutex.Unlock()
+		return tgtURL == "/backup/a"
+	})
+	if !errorDetected {
+		t.Fatal("the failing target was not reported")
+	}
+	if len(mirrored) != len(tgtURLs) {
+		t.Fatalf("expected %d mirrored targets, got %v", len(tgtURLs), mirrored)
+	}
+
+	if mirrorTargets(tgtURLs[1:2], func(string) bool { return false }) {
+		t.Fatal("a target which did not fail was reported")
+	}
+}
+
+func TestRunMirrorKeepsGoing(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	root, e := ioutil.TempDir("", "mirror-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(root)
+
+	srcDir := filepath.Join(root, "src")
+	if e = os.MkdirAll(srcDir, 0700); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(filepath.Join(srcDir, "object"), []byte("data"), 0600); e != nil {
+		t.Fatal(e)
+	}
+	// A folder cannot be created below a file.
+	blocker := filepath.Join(root, "blocker")
+	if e = ioutil.WriteFile(blocker, nil, 0600); e != nil {
+		t.Fatal(e)
+	}
+	badTarget := filepath.Join(blocker, "target")
+	goodTarget := filepath.Join(root, "target")
+
+	set := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	for _, f := range mirrorCmd.Flags {
+		f.Apply(set)
+	}
+	if e = set.Parse([]string{srcDir, badTarget, goodTarget}); e != nil {
+		t.Fatal(e)
+	}
+	ctx := cli.NewContext(nil, set, nil)
+
+	if !runMirror(srcDir, badTarget, ctx, nil) {
+		t.Fatal("mirroring below a file did not fail")
+	}
+	if runMirror(srcDir, goodTarget, ctx, nil) {
+		t.Fatal("mirroring to a folder failed")
+	}
+	data, e := ioutil.ReadFile(filepath.Join(goodTarget, "object"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(data) != "data" {
+		t.Fatalf("expected the source object, got %q", data)
+	}
+}
+
+func TestRunMirrorAgain(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	root, e := ioutil.TempDir("", "mirror-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(root)
+
+	srcDir, tgtDir := filepath.Join(root, "src"), filepath.Join(root, "target")
+	if e = os.MkdirAll(srcDir, 0700); e != nil {
+		t.Fatal(e)
+	}
+	for _, name := range []string{"same", "changed"} {
+		if e = ioutil.WriteFile(filepath.Join(srcDir, name), []byte("data"), 0600); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	set := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	for _, f := range mirrorCmd.Flags {
+		f.Apply(set)
+	}
+	if e = set.Parse([]string{srcDir, tgtDir}); e != nil {
+		t.Fatal(e)
+	}
+	ctx := cli.NewContext(nil, set, nil)
+
+	// Mirroring a mirrored tree again changes nothing and fails nothing.
+	for i := 0; i < 2; i++ {
+		if runMirror(srcDir, tgtDir, ctx, nil) {
+			t.Fatalf("run %d of mirror failed", i+1)
+		}
+	}
+
+	// A source modified since it was mirrored is mirrored again, even
+	// with the same size and without --overwrite.
+	changed := filepath.Join(srcDir, "changed")
+	if e = ioutil.WriteFile(changed, []byte("DATA"), 0600); e != nil {
+		t.Fatal(e)
+	}
+	later := time.Now().Add(time.Hour)
+	if e = os.Chtimes(changed, later, later); e != nil {
+		t.Fatal(e)
+	}
+	if runMirror(srcDir, tgtDir, ctx, nil) {
+		t.Fatal("mirroring a modified source failed")
+	}
+	data, e := ioutil.ReadFile(filepath.Join(tgtDir, "changed"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(data) != "DATA" {
+		t.Fatalf("expected the modified source, got %q", data)
+	}
+
+	// Targets which match their source are only mirrored again with
+	// --force.
+	same := filepath.Join(tgtDir, "same")
+	if e = ioutil.WriteFile(same, []byte("DATA"), 0600); e != nil {
+		t.Fatal(e)
+	}
+	for _, isForce := range []bool{false, true} {
+		if e = set.Set("force", strconv.FormatBool(isForce)); e != nil {
+			t.Fatal(e)
+		}
+		if runMirror(srcDir, tgtDir, ctx, nil) {
+			t.Fatalf("mirroring with force %t failed", isForce)
+		}
+		expected := "DATA"
+		if isForce {
+			expected = "data"
+		}
+		if data, e = ioutil.ReadFile(same); e != nil {
+			t.Fatal(e)
+		}
+		if string(data) != expected {
+			t.Fatalf("force %t: expected %q, got %q", isForce, expected, data)
+		}
+	}
+}
+
+func TestMirrorSession(t *testing.T) {
+	defer useEmptyMcConfig()()
+	if err := createSessionDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	root, e := ioutil.TempDir("", "mirror-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(root)
+
+	srcDir, tgtDir := filepath.Join(root, "src"), filepath.Join(root, "target")
+	if e = os.MkdirAll(srcDir, 0700); e != nil {
+		t.Fatal(e)
+	}
+	for name, data := range map[string]string{"a": "first", "b": "second"} {
+		if e = ioutil.WriteFile(filepath.Join(srcDir, name), []byte(data), 0600); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	set := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	for _, f := range mirrorCmd.Flags {
+		f.Apply(set)
+	}
+	args := []string{"--overwrite", "--parallel", "4", "--exclude", "*.tmp", "--exclude", "cache/", srcDir, tgtDir}
+	if e = set.Parse(args); e != nil {
+		t.Fatal(e)
+	}
+	session := newMirrorSession(cli.NewContext(nil, set, nil), getHash("mirror", set.Args()), set.Args())
+	defer session.Delete()
+
+	// Resumed with the flags it was started with.
+	ctx := mirrorSessionContext(session)
+	if !ctx.Bool("overwrite") || ctx.Int("parallel") != 4 || len(ctx.StringSlice("exclude")) != 2 || ctx.Args().Get(1) != tgtDir {
+		t.Fatalf("unexpected flags %v %d %v %v", ctx.Bool("overwrite"), ctx.Int("parallel"), ctx.StringSlice("exclude"), ctx.Args())
+	}
+
+	records := func() (sources []string) {
+		urlScanner := bufio.NewScanner(session.NewDataReader())
+		for urlScanner.Scan() {
+			var sURLs URLs
+			if e := json.Unmarshal(urlScanner.Bytes(), &sURLs); e != nil {
+				t.Fatal(e)
+			}
+			sources = append(sources, filepath.Base(sURLs.SourceContent.URL.Path))
+		}
+		sort.Strings(sources)
+		return sources
+	}
+
+	// Every object mirrored is recorded.
+	session.NewDataWriter()
+	if runMirrorSession(srcDir, tgtDir, ctx, nil, session, false) {
+		t.Fatal("mirroring in a session failed")
+	}
+	if sources := records(); !reflect.DeepEqual(sources, []string{"a", "b"}) || !session.HasData() {
+		t.Fatalf("expected a and b to be recorded, found %v", sources)
+	}
+
+	// Objects recorded whose target was lost are mirrored again before
+	// the source is compared with the target.
+	if e = os.Remove(filepath.Join(tgtDir, "a")); e != nil {
+		t.Fatal(e)
+	}
+	session.NewDataAppender()
+	mj := newMirrorJob(srcDir, tgtDir, false, false, false, false, false, false, false, false, false, false, 0, nil, "", "", "", "", nil, nil)
+	mj.session, mj.isVerifyDone = session, true
+	mj.checkpoint, _ = newSessionCheckpoint("")
+	mirrorCtx, cancelMirror := context.WithCancel(context.Background())
+	defer cancelMirror()
+	if mj.mirror(mirrorCtx, cancelMirror) {
+		t.Fatal("resuming the session failed")
+	}
+	if mj.verified.Verified != 2 || mj.verified.Requeued != 1 {
+		t.Fatalf("expected 2 objects verified and 1 mirrored again, found %+v", mj.verified)
+	}
+	if data, e := ioutil.ReadFile(filepath.Join(tgtDir, "a")); e != nil || string(data) != "first" {
+		t.Fatalf("expected a to be mirrored again, found %q %v", data, e)
+	}
+	if sources := records(); !reflect.DeepEqual(sources, []string{"a", "a", "b"}) {
+		t.Fatalf("expected a to be recorded again, found %v", sources)
+	}
+}
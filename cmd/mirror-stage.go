@@ -0,0 +1,198 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+// A staged mirror (`mirror --staged`) uploads every new or changed
+// object below a temporary prefix of the target first and only after
+// all uploads succeeded swaps them into place with server side copies,
+// removes extraneous objects and writes a manifest of what was
+// published. If any upload fails nothing is published.
+//
+// Object storage has no multi-object transaction, so the swap itself
+// is not atomic: while it runs a reader may still observe a mix of old
+// and new objects. Staging reduces that window from the duration of
+// the whole transfer to that of the server side copies. The manifest
+// is replaced in a single PUT once the swap is done, readers can watch
+// its id to learn when a new set is complete.
+const (
+	// stagingFolder is the folder below the target holding the staging
+	// folder of each mirror run, named after a random id of the run, as
+	// in .mc-tmp/<id>/<key>.
+	stagingFolder = ".mc-tmp"
+
+	// stagingManifestName is the manifest object written at the target
+	// root after a successful publish.
+	stagingManifestName = ".mc-manifest.json"
+)
+
+// stagedObject is a single entry of a staged mirror, Key is relative to
+// the mirror target.
+type stagedObject struct {
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+	Remove bool   `json:"-"`
+}
+
+// stagingManifest lists the objects published and removed by a single
+// staged mirror run, unchanged objects are not listed.
+type stagingManifest struct {
+	Version   string         `json:"version"`
+	ID        string         `json:"id"`
+	Published time.Time      `json:"published"`
+	Objects   []stagedObject `json:"objects"`
+	Removed   []string       `json:"removed,omitempty"`
+}
+
+// mirrorStage tracks the objects of a staged mirror run.
+type mirrorStage struct {
+	id string
+
+	// expanded target and staging folder, with a trailing separator.
+	targetAlias string
+	targetURL   string
+	stagingURL  string
+
+	objects []stagedObject
+}
+
+func newMirrorStage(targetURL string) *mirrorStage {
+	separator := string(newClientURL(targetURL).Separator)
+	if !strings.HasSuffix(targetURL, separator) {
+		targetURL = targetURL + separator
+	}
+	targetAlias, expandedURL, _ := mustExpandAlias(targetURL)
+	id := newRandomID(8)
+	return &mirrorStage{
+		id:          id,
+		targetAlias: targetAlias,
+		targetURL:   expandedURL,
+		stagingURL:  urlJoinPath(expandedURL, stagingFolder+"/"+id) + separator,
+	}
+}
+
+// excludeOptions - staging folders, including leftovers of aborted
+// runs, and the manifest are never part of the mirrored set.
+func (s *mirrorStage) excludeOptions() []string {
+	return []string{stagingFolder + "/", stagingManifestName}
+}
+
+// stage redirects an upload into the staging folder.
+func (s *mirrorStage) stage(sURLs URLs) URLs {
+	key := strings.TrimPrefix(sURLs.TargetContent.URL.String(), s.targetURL)
+	s.objects = append(s.objects, stagedObject{Key: key, Size: sURLs.SourceContent.Size})
+	sURLs.TargetContent = &clientContent{URL: *newClientURL(s.stagingURL + key)}
+	return sURLs
+}
+
+// stageRemove defers the removal of an extraneous object to publish.
+func (s *mirrorStage) stageRemove(sURLs URLs) {
+	key := strings.TrimPrefix(sURLs.TargetContent.URL.String(), s.targetURL)
+	s.objects = append(s.objects, stagedObject{Key: key, Remove: true})
+}
+
+// publish swaps all staged objects into place, applies the deferred
+// removals and writes the manifest.
+func (s *mirrorStage) publish(encKeyDB map[string][]prefixSSEPair) *probe.Error {
+	manifest := stagingManifest{
+		Version:   "1",
+		ID:        s.id,
+		Published: UTCNow(),
+		Objects:   []stagedObject{},
+	}
+	// Publish new objects before removing old ones, at no point readers
+	// see less than either set.
+	for _, object := range s.objects {
+		if object.Remove {
+			continue
+		}
+		targetURL := s.targetURL + object.Key
+		stagedURL := newClientURL(s.stagingURL + object.Key)
+		srcSSE := getSSE(filepath.ToSlash(filepath.Join(s.targetAlias, stagedURL.Path)), encKeyDB[s.targetAlias])
+		tgtSSE := getSSE(filepath.ToSlash(filepath.Join(s.targetAlias, newClientURL(targetURL).Path)), encKeyDB[s.targetAlias])
+		if err := copySourceToTargetURL(s.targetAlias, targetURL, filepath.ToSlash(stagedURL.Path), object.Size, nil, srcSSE, tgtSSE, nil); err != nil {
+			return err.Trace(stagedURL.String(), targetURL)
+		}
+		manifest.Objects = append(manifest.Objects, object)
+	}
+	for _, object := range s.objects {
+		if !object.Remove {
+			continue
+		}
+		targetURL := s.targetURL + object.Key
+		sURLs := URLs{
+			TargetAlias:   s.targetAlias,
+			TargetContent: &clientContent{URL: *newClientURL(targetURL)},
+		}
+		if err := removeTargetURL(sURLs, uaMirrorAppName).Error; err != nil {
+			return err.Trace(targetURL)
+		}
+		manifest.Removed = append(manifest.Removed, object.Key)
+	}
+
+	manifestBytes, e := json.MarshalIndent(manifest, "", " ")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	manifestURL := s.targetURL + stagingManifestName
+	metadata := map[string]string{"Content-Type": "application/json"}
+	_, err := putTargetStream(context.Background(), s.targetAlias, manifestURL, bytes.NewReader(manifestBytes),
+		int64(len(manifestBytes)), metadata, nil, nil)
+	return err
+}
+
+// cleanup removes the staging folder.
+func (s *mirrorStage) cleanup() *probe.Error {
+	return removeStagingFolder(s.targetAlias, s.stagingURL)
+}
+
+// removeStagingFolder removes a staging folder and everything below it,
+// stagingURL is expanded and ends with a separator.
+func removeStagingFolder(targetAlias, stagingURL string) *probe.Error {
+	clnt, err := newClientFromAlias(targetAlias, stagingURL)
+	if err != nil {
+		return err.Trace(stagingURL)
+	}
+	contentCh := make(chan *clientContent)
+	errorCh := clnt.Remove(false, false, contentCh)
+	for content := range clnt.List(true, false, false, DirNone) {
+		if content.Err != nil {
+			continue
+		}
+		select {
+		case contentCh <- content:
+		case err := <-errorCh:
+			close(contentCh)
+			return err.Trace(stagingURL)
+		}
+	}
+	close(contentCh)
+	if err := <-errorCh; err != nil {
+		return err.Trace(stagingURL)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/minio/cli"
+)
+
+// stageObjects stages an upload of each of the objects to s, written
+// below its staging folder the way the mirror does.
+func stageObjects(t *testing.T, s *mirrorStage, objects map[string]string) {
+	for key, data := range objects {
+		sURLs := s.stage(URLs{
+			SourceContent: &clientContent{Size: int64(len(data))},
+			TargetContent: &clientContent{URL: *newClientURL(s.targetURL + key)},
+		})
+		stagedPath := sURLs.TargetContent.URL.Path
+		if e := os.MkdirAll(filepath.Dir(stagedPath), 0755); e != nil {
+			t.Fatal(e)
+		}
+		if e := ioutil.WriteFile(stagedPath, []byte(data), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+}
+
+func TestMirrorStagePublish(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	targetDir, e := ioutil.TempDir("", "mc-stage-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(targetDir)
+	for name, data := range map[string]string{"index.html": "old", "extra": "extra"} {
+		if e = ioutil.WriteFile(filepath.Join(targetDir, name), []byte(data), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	s := newMirrorStage(targetDir)
+	stagingDir := filepath.Join(targetDir, stagingFolder, s.id)
+	if s.stagingURL != stagingDir+"/" {
+		t.Fatalf("expected staging below %s, found %s", stagingDir, s.stagingURL)
+	}
+	stageObjects(t, s, map[string]string{"index.html": "new", "css/site.css": "css"})
+	s.stageRemove(URLs{TargetContent: &clientContent{URL: *newClientURL(filepath.Join(targetDir, "extra"))}})
+
+	// Nothing is in place before publish.
+	data, e := ioutil.ReadFile(filepath.Join(targetDir, "index.html"))
+	if e != nil || string(data) != "old" {
+		t.Fatalf("expected the old object before publish, found %q %v", data, e)
+	}
+
+	if err := s.publish(nil); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	for name, expected := range map[string]string{"index.html": "new", "css/site.css": "css"} {
+		if data, e = ioutil.ReadFile(filepath.Join(targetDir, name)); e != nil || string(data) != expected {
+			t.Fatalf("expected %q published as %s, found %q %v", expected, name, data, e)
+		}
+	}
+	if _, e = os.Stat(filepath.Join(targetDir, "extra")); !os.IsNotExist(e) {
+		t.Fatal("expected the extraneous object to be removed on publish")
+	}
+
+	data, e = ioutil.ReadFile(filepath.Join(targetDir, stagingManifestName))
+	if e != nil {
+		t.Fatal(e)
+	}
+	var manifest stagingManifest
+	if e = json.Unmarshal(data, &manifest); e != nil {
+		t.Fatal(e)
+	}
+	if manifest.ID != s.id || len(manifest.Objects) != 2 || !reflect.DeepEqual(manifest.Removed, []string{"extra"}) {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	if err := s.cleanup(); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if files := listFiles(t, stagingDir); len(files) != 0 {
+		t.Fatalf("expected the staging folder to be emptied, found %v", files)
+	}
+}
+
+func TestMirrorStageFailure(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	targetDir, e := ioutil.TempDir("", "mc-stage-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(targetDir)
+	if e = ioutil.WriteFile(filepath.Join(targetDir, "extra"), []byte("extra"), 0644); e != nil {
+		t.Fatal(e)
+	}
+
+	// A staged object which went missing fails the publish before any
+	// object is removed, the staging folder is cleaned up all the same.
+	s := newMirrorStage(targetDir)
+	stagingDir := filepath.Join(targetDir, stagingFolder, s.id)
+	stageObjects(t, s, map[string]string{"a": "a"})
+	s.stage(URLs{
+		SourceContent: &clientContent{Size: 1},
+		TargetContent: &clientContent{URL: *newClientURL(filepath.Join(targetDir, "missing"))},
+	})
+	s.stageRemove(URLs{TargetContent: &clientContent{URL: *newClientURL(filepath.Join(targetDir, "extra"))}})
+	if err := s.publish(nil); err == nil {
+		t.Fatal("expected publish to fail")
+	}
+	if _, e = os.Stat(filepath.Join(targetDir, "extra")); e != nil {
+		t.Fatal("expected no object to be removed by a failed publish")
+	}
+	if _, e = os.Stat(filepath.Join(targetDir, stagingManifestName)); !os.IsNotExist(e) {
+		t.Fatal("expected no manifest to be written by a failed publish")
+	}
+	if err := s.cleanup(); err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if files := listFiles(t, stagingDir); len(files) != 0 {
+		t.Fatalf("expected the staging folder to be emptied, found %v", files)
+	}
+}
+
+func TestRunMirrorStaged(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	root, e := ioutil.TempDir("", "mirror-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(root)
+	srcDir := filepath.Join(root, "src")
+	if e = os.MkdirAll(srcDir, 0700); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(filepath.Join(srcDir, "object"), []byte("data"), 0600); e != nil {
+		t.Fatal(e)
+	}
+	goodTarget := filepath.Join(root, "target")
+	// Staging folders can't be created below a file.
+	badTarget := filepath.Join(root, "bad")
+	if e = os.MkdirAll(badTarget, 0700); e != nil {
+		t.Fatal(e)
+	}
+	if e = ioutil.WriteFile(filepath.Join(badTarget, stagingFolder), nil, 0600); e != nil {
+		t.Fatal(e)
+	}
+
+	set := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	for _, f := range mirrorCmd.Flags {
+		f.Apply(set)
+	}
+	if e = set.Parse([]string{"--staged", srcDir, goodTarget}); e != nil {
+		t.Fatal(e)
+	}
+	ctx := cli.NewContext(nil, set, nil)
+
+	if runMirror(srcDir, goodTarget, ctx, nil) {
+		t.Fatal("staged mirror failed")
+	}
+	if files := listFiles(t, goodTarget); !reflect.DeepEqual(files, []string{stagingManifestName, "object"}) {
+		t.Fatalf("expected the object published along with the manifest, found %v", files)
+	}
+
+	if !runMirror(srcDir, badTarget, ctx, nil) {
+		t.Fatal("staged mirror did not fail")
+	}
+	if _, e = os.Stat(filepath.Join(badTarget, "object")); !os.IsNotExist(e) {
+		t.Fatal("expected nothing to be published when staging failed")
+	}
+}
+
+// listFiles returns the files below dir, relative to it.
+func listFiles(t *testing.T, dir string) []string {
+	var files []string
+	e := filepath.Walk(dir, func(path string, info os.FileInfo, e error) error {
+		if os.IsNotExist(e) {
+			return nil
+		}
+		if e != nil || info.IsDir() {
+			return e
+		}
+		rel, e := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return e
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	return files
+}
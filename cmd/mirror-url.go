@@ -19,6 +19,7 @@ package cmd
 import (
 	"fmt"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/minio/cli"
@@ -32,39 +33,56 @@ import (
 
 // checkMirrorSyntax(URLs []string)
 func checkMirrorSyntax(ctx *cli.Context, encKeyDB map[string][]prefixSSEPair) {
-	if len(ctx.Args()) != 2 {
+	if len(ctx.Args()) < 2 {
 		cli.ShowCommandHelpAndExit(ctx, "mirror", 1) // last argument is exit code.
 	}
 
 	// extract URLs.
 	URLs := ctx.Args()
 	srcURL := URLs[0]
-	tgtURL := URLs[1]
+	tgtURLs := URLs[1:]
 
-	if ctx.Bool("force") && ctx.Bool("remove") {
-		errorIf(errInvalidArgument().Trace(URLs...), "`--force` is deprecated please use `--overwrite` instead with `--remove` for the same functionality.")
-	} else if ctx.Bool("force") {
-		errorIf(errInvalidArgument().Trace(URLs...), "`--force` is deprecated please use `--overwrite` instead for the same functionality.")
+	if parallel := ctx.Int("parallel"); parallel < 0 || parallel > maxParallelWorkers {
+		fatalIf(errInvalidArgument().Trace(strconv.Itoa(parallel)),
+			fmt.Sprintf("Invalid `--parallel` %d, expecting at most %d.", parallel, maxParallelWorkers))
 	}
 
-	tgtClientURL := newClientURL(tgtURL)
-	if tgtClientURL.Host != "" {
-		if tgtClientURL.Path == string(tgtClientURL.Separator) {
-			fatalIf(errInvalidArgument().Trace(tgtURL),
-				fmt.Sprintf("Target `%s` does not contain bucket name.", tgtURL))
-		}
+	if ctx.Bool("staged") && (ctx.Bool("watch") || ctx.String("multi-master") != "") {
+		fatalIf(errInvalidArgument().Trace(URLs...), "`--staged` cannot be used with `--watch` or `--multi-master`.")
+	}
+
+	// Sessions record the objects mirrored to a single target by a run
+	// which ends.
+	if ctx.Bool("continue") && (len(tgtURLs) > 1 || ctx.Bool("watch") || ctx.String("multi-master") != "" || ctx.Bool("fake") || ctx.Bool("staged")) {
+		fatalIf(errInvalidArgument().Trace(URLs...), "`--continue` mirrors to a single target, it cannot be used with `--watch`, `--multi-master`, `--fake` or `--staged`.")
+	}
+
+	// Watching and multi-master mirror until interrupted.
+	if len(tgtURLs) > 1 && (ctx.Bool("watch") || ctx.String("multi-master") != "") {
+		fatalIf(errInvalidArgument().Trace(URLs...), "`--watch` and `--multi-master` cannot be used with several targets.")
 	}
 
 	_, expandedSourcePath, _ := mustExpandAlias(srcURL)
 	srcClient := newClientURL(expandedSourcePath)
-	_, expandedTargetPath, _ := mustExpandAlias(tgtURL)
-	destClient := newClientURL(expandedTargetPath)
-
-	// Mirror with preserve option on windows
-	// only works for object storage to object storage
-	if runtime.GOOS == "windows" && ctx.Bool("a") {
-		if srcClient.Type == fileSystem || destClient.Type == fileSystem {
-			errorIf(errInvalidArgument(), "Preserve functionality on windows support object storage to object storage transfer only.")
+
+	for _, tgtURL := range tgtURLs {
+		tgtClientURL := newClientURL(tgtURL)
+		if tgtClientURL.Host != "" {
+			if tgtClientURL.Path == string(tgtClientURL.Separator) {
+				fatalIf(errInvalidArgument().Trace(tgtURL),
+					fmt.Sprintf("Target `%s` does not contain bucket name.", tgtURL))
+			}
+		}
+
+		_, expandedTargetPath, _ := mustExpandAlias(tgtURL)
+		destClient := newClientURL(expandedTargetPath)
+
+		// Mirror with preserve option on windows
+		// only works for object storage to object storage
+		if runtime.GOOS == "windows" && ctx.Bool("a") {
+			if srcClient.Type == fileSystem || destClient.Type == fileSystem {
+				errorIf(errInvalidArgument(), "Preserve functionality on windows support object storage to object storage transfer only.")
+			}
 		}
 	}
 
@@ -91,11 +109,20 @@ func matchExcludeOptions(excludeOptions []string, srcSuffix string) bool {
 		if wildcard.Match(pattern, srcSuffix) {
 			return true
 		}
+		// A pattern ending with a slash excludes whole folders.
+		if !strings.HasSuffix(pattern, "/") {
+			continue
+		}
+		for i := range srcSuffix {
+			if srcSuffix[i] == '/' && wildcard.Match(pattern, srcSuffix[:i+1]) {
+				return true
+			}
+		}
 	}
 	return false
 }
 
-func deltaSourceTarget(sourceURL, targetURL string, isFake, isOverwrite, isRemove, isMetadata bool, excludeOptions []string, URLsCh chan<- URLs, encKeyDB map[string][]prefixSSEPair) {
+func deltaSourceTarget(sourceURL, targetURL string, isFake, isOverwrite, isForce, isRemove, isMetadata, isMtime bool, excludes *excludeFilter, URLsCh chan<- URLs, encKeyDB map[string][]prefixSSEPair) {
 	// source and targets are always directories
 	sourceSeparator := string(newClientURL(sourceURL).Separator)
 	if !strings.HasSuffix(sourceURL, sourceSeparator) {
@@ -125,7 +152,9 @@ func deltaSourceTarget(sourceURL, targetURL string, isFake, isOverwrite, isRemov
 	}
 
 	// List both source and target, compare and return values through channel.
-	for diffMsg := range objectDifference(sourceClnt, targetClnt, sourceURL, targetURL, isMetadata) {
+	// Objects which match are only sent to be mirrored with --force.
+	isRecursive, returnSimilar := true, isForce
+	for diffMsg := range difference(sourceClnt, targetClnt, sourceURL, targetURL, isMetadata, isMtime, isRecursive, returnSimilar, DirNone) {
 		if diffMsg.Error != nil {
 			// Send all errors through the channel
 			URLsCh <- URLs{Error: diffMsg.Error}
@@ -134,24 +163,24 @@ func deltaSourceTarget(sourceURL, targetURL string, isFake, isOverwrite, isRemov
 
 		srcSuffix := strings.TrimPrefix(diffMsg.FirstURL, sourceURL)
 		//Skip the source object if it matches the Exclude options provided
-		if matchExcludeOptions(excludeOptions, srcSuffix) {
+		if excludes.skip(srcSuffix) {
 			continue
 		}
 
 		tgtSuffix := strings.TrimPrefix(diffMsg.SecondURL, targetURL)
 		//Skip the target object if it matches the Exclude options provided
-		if matchExcludeOptions(excludeOptions, tgtSuffix) {
+		if excludes.matches(tgtSuffix) {
 			continue
 		}
 
 		switch diffMsg.Diff {
-		case differInNone:
-			// No difference, continue.
 		case differInType:
 			URLsCh <- URLs{Error: errInvalidTarget(diffMsg.SecondURL)}
-		case differInSize, differInMetadata, differInETag:
-			if !isOverwrite && !isFake {
-				// Size or time or etag differs but --overwrite not set.
+		case differInSize, differInMetadata, differInETag, differInTime:
+			// Sources modified after their target was written are
+			// always mirrored again, other changes need --overwrite.
+			if !isOverwrite && !isFake && diffMsg.Diff != differInTime {
+				// Size or etag differs but --overwrite not set.
 				URLsCh <- URLs{Error: errOverWriteNotAllowed(diffMsg.SecondURL)}
 				continue
 			}
@@ -167,7 +196,11 @@ func deltaSourceTarget(sourceURL, targetURL string, isFake, isOverwrite, isRemov
 				TargetAlias:   targetAlias,
 				TargetContent: targetContent,
 			}
-		case differInFirst:
+		case differInFirst, differInNone:
+			// No difference, copied again only with --force.
+			if diffMsg.Diff == differInNone && !isForce {
+				continue
+			}
 			// Only in first, always copy.
 			sourceSuffix := strings.TrimPrefix(diffMsg.FirstURL, sourceURL)
 			targetPath := urlJoinPath(targetURL, sourceSuffix)
@@ -196,8 +229,8 @@ func deltaSourceTarget(sourceURL, targetURL string, isFake, isOverwrite, isRemov
 }
 
 // Prepares urls that need to be copied or removed based on requested options.
-func prepareMirrorURLs(sourceURL string, targetURL string, isFake, isOverwrite, isRemove, isMetadata bool, excludeOptions []string, encKeyDB map[string][]prefixSSEPair) <-chan URLs {
+func prepareMirrorURLs(sourceURL string, targetURL string, isFake, isOverwrite, isForce, isRemove, isMetadata, isMtime bool, excludes *excludeFilter, encKeyDB map[string][]prefixSSEPair) <-chan URLs {
 	URLsCh := make(chan URLs)
-	go deltaSourceTarget(sourceURL, targetURL, isFake, isOverwrite, isRemove, isMetadata, excludeOptions, URLsCh, encKeyDB)
+	go deltaSourceTarget(sourceURL, targetURL, isFake, isOverwrite, isForce, isRemove, isMetadata, isMtime, excludes, URLsCh, encKeyDB)
 	return URLsCh
 }
@@ -0,0 +1,148 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	json "github.com/minio/mc/pkg/colorjson"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+var (
+	mpuListFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "older-than",
+			Usage: "list uploads initiated longer than specified time, e.g. 7d10h31s",
+		},
+	}
+)
+
+var mpuListCmd = cli.Command{
+	Name:   "list",
+	Usage:  "list incomplete multipart uploads with their age and parts",
+	Action: mainMPUList,
+	Before: setGlobalsFromContext,
+	Flags:  append(mpuListFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. List all incomplete uploads in a bucket.
+     {{.Prompt}} {{.HelpName}} s3/mybucket
+
+  2. List incomplete uploads under a prefix initiated more than a week ago.
+     {{.Prompt}} {{.HelpName}} --older-than 7d s3/mybucket/backups/
+
+  3. List stale uploads across all buckets as JSON, for use in cleanup scripts.
+     {{.Prompt}} {{.HelpName}} --json --older-than 30d s3
+`,
+}
+
+// mpuListMessage container for a single incomplete upload.
+type mpuListMessage struct {
+	Status    string    `json:"status"`
+	Key       string    `json:"key"`
+	UploadID  string    `json:"uploadId"`
+	Initiated time.Time `json:"initiated"`
+	Age       string    `json:"age"`
+	Parts     int       `json:"parts"`
+	Size      int64     `json:"size"`
+}
+
+func (m mpuListMessage) String() string {
+	msg := console.Colorize("Time", fmt.Sprintf("[%s] ", m.Initiated.Format(printDate)))
+	msg += console.Colorize("Size", fmt.Sprintf("%7s ", humanizeSize(m.Size)))
+	msg += console.Colorize("Parts", fmt.Sprintf("%5d parts ", m.Parts))
+	msg += console.Colorize("Key", m.Key)
+	msg += console.Colorize("UploadID", " "+m.UploadID)
+	return msg
+}
+
+func (m mpuListMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkMPUListSyntax - validate all the passed arguments
+func checkMPUListSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "list", 1) // last argument is exit code
+	}
+}
+
+func mainMPUList(ctx *cli.Context) error {
+	console.SetColor("Time", color.New(color.FgGreen))
+	console.SetColor("Size", color.New(color.FgYellow))
+	console.SetColor("Parts", color.New(color.FgCyan))
+	console.SetColor("Key", color.New(color.Bold))
+	console.SetColor("UploadID", color.New(color.FgWhite))
+
+	checkMPUListSyntax(ctx)
+
+	targetURL := ctx.Args().First()
+	olderThan := ctx.String("older-than")
+
+	client, err := newClient(targetURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+
+	s3Client, ok := client.(*s3Client)
+	if !ok {
+		fatalIf(errDummy().Trace(targetURL), "The provided url doesn't point to a S3 server.")
+	}
+
+	return listMPUs(s3Client, targetURL, olderThan, func(m mpuListMessage) {
+		printMsg(m)
+	})
+}
+
+// listMPUs passes the incomplete uploads of clnt initiated longer than
+// olderThan ago to fn, all of them when olderThan is empty. Listing
+// errors are reported and listing goes on.
+func listMPUs(clnt *s3Client, targetURL, olderThan string, fn func(mpuListMessage)) error {
+	var cErr error
+	now := UTCNow()
+	for upload := range clnt.ListMultipartUploads(olderThan) {
+		if upload.Err != nil {
+			errorIf(upload.Err.Trace(targetURL), "Unable to list incomplete uploads.")
+			cErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		fn(mpuListMessage{
+			Key:       strings.TrimPrefix(upload.URL.Path, string(upload.URL.Separator)),
+			UploadID:  upload.UploadID,
+			Initiated: upload.Initiated,
+			Age:       now.Sub(upload.Initiated).Round(time.Second).String(),
+			Parts:     upload.Parts,
+			Size:      upload.Size,
+		})
+	}
+	return cErr
+}
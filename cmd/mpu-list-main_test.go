@@ -0,0 +1,116 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListMPUs(t *testing.T) {
+	recent := UTCNow().Add(-time.Hour).Format(time.RFC3339)
+	uploadsPages := map[string]string{
+		// Truncated after the first upload.
+		"": `<Upload><Key>old</Key><UploadId>u1</UploadId><Initiated>2000-01-01T00:00:00Z</Initiated></Upload>
+			<IsTruncated>true</IsTruncated><NextKeyMarker>old</NextKeyMarker><NextUploadIdMarker>u1</NextUploadIdMarker>`,
+		"old": `<Upload><Key>recent</Key><UploadId>u2</UploadId><Initiated>` + recent + `</Initiated></Upload>
+			<IsTruncated>false</IsTruncated>`,
+	}
+	partsPages := map[string]string{
+		// Truncated after the second part.
+		"u1/0": `<Part><PartNumber>1</PartNumber><Size>5</Size></Part><Part><PartNumber>2</PartNumber><Size>6</Size></Part>
+			<IsTruncated>true</IsTruncated><NextPartNumberMarker>2</NextPartNumberMarker>`,
+		"u1/2": `<Part><PartNumber>3</PartNumber><Size>7</Size></Part><IsTruncated>false</IsTruncated>`,
+		"u2/0": `<Part><PartNumber>1</PartNumber><Size>1</Size></Part><IsTruncated>false</IsTruncated>`,
+	}
+	var mutex sync.Mutex
+	partsListed := make(map[string]bool)
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if uploadID := query.Get("uploadId"); uploadID != "" {
+			mutex.Lock()
+			partsListed[uploadID] = true
+			mutex.Unlock()
+		}
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`<ListAllMyBucketsResult><Buckets>
+				<Bucket><Name>denied</Name><CreationDate>2000-01-01T00:00:00Z</CreationDate></Bucket>
+				<Bucket><Name>bucket</Name><CreationDate>2000-01-01T00:00:00Z</CreationDate></Bucket>
+				</Buckets></ListAllMyBucketsResult>`))
+		case r.URL.Path == "/denied/" || r.URL.Path == "/denied":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied.</Message></Error>`))
+		case query.Get("uploadId") != "":
+			page, ok := partsPages[query.Get("uploadId")+"/"+query.Get("part-number-marker")]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("<ListPartsResult><Bucket>bucket</Bucket>" + page + "</ListPartsResult>"))
+		default:
+			page, ok := uploadsPages[query.Get("key-marker")]
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("<ListMultipartUploadsResult><Bucket>bucket</Bucket>" + page + "</ListMultipartUploadsResult>"))
+		}
+	})
+	defer server.Close()
+
+	conf := newTestS3Config(server.URL)
+	clnt, err := s3New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		olderThan   string
+		expected    []string
+		partsListed map[string]bool
+	}{
+		{"", []string{"bucket/old u1 3 18", "bucket/recent u2 1 1"}, map[string]bool{"u1": true, "u2": true}},
+		// Parts are not listed for the uploads filtered out.
+		{"1d", []string{"bucket/old u1 3 18"}, map[string]bool{"u1": true}},
+	}
+	for i, testCase := range testCases {
+		mutex.Lock()
+		partsListed = make(map[string]bool)
+		mutex.Unlock()
+		var listed []string
+		e := listMPUs(clnt.(*s3Client), server.URL, testCase.olderThan, func(m mpuListMessage) {
+			listed = append(listed, fmt.Sprintf("%s %s %d %d", m.Key, m.UploadID, m.Parts, m.Size))
+		})
+		// The denied bucket fails the listing, not the buckets after it.
+		if e == nil {
+			t.Errorf("Test %d: expected an error for the denied bucket", i+1)
+		}
+		if !reflect.DeepEqual(listed, testCase.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i+1, testCase.expected, listed)
+		}
+		mutex.Lock()
+		if !reflect.DeepEqual(partsListed, testCase.partsListed) {
+			t.Errorf("Test %d: expected the parts of %v to be listed, got %v", i+1, testCase.partsListed, partsListed)
+		}
+		mutex.Unlock()
+	}
+}
@@ -0,0 +1,189 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+)
+
+// Move command, it runs the copy of cp and removes each source once
+// its copy is confirmed on the target.
+var mvCmd = cli.Command{
+	Name:   "mv",
+	Usage:  "move objects",
+	Action: mainCopy,
+	Before: setGlobalsFromContext,
+	Flags:  append(append(cpFlags, ioFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] SOURCE [SOURCE...] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+ENVIRONMENT VARIABLES:
+  MC_ENCRYPT:      list of comma delimited prefixes
+  MC_ENCRYPT_KEY:  list of comma delimited prefix=secret values
+
+EXAMPLES:
+  01. Move a list of objects from local file system to Amazon S3 cloud storage.
+      {{.Prompt}} {{.HelpName}} Music/*.ogg s3/jukebox/
+
+  02. Move a prefix to another bucket on the same host.
+      {{.Prompt}} {{.HelpName}} --recursive play/mybucket/burningman2011/ play/archive/burningman2011/
+
+  03. Rename an object.
+      {{.Prompt}} {{.HelpName}} play/mybucket/report.csv play/mybucket/report-2011.csv
+
+  04. Move a folder to an object storage in a session, run the same command again to resume it.
+      {{.Prompt}} {{.HelpName}} --recursive --continue dir/ play/mybucket
+`,
+}
+
+// renameFile renames local files, replaced by tests.
+var renameFile = os.Rename
+
+// isLocalMove tells if cpURLs moves a local file to a local target.
+func isLocalMove(cpURLs URLs) bool {
+	return cpURLs.SourceAlias == "" && cpURLs.SourceContent.URL.Type == fileSystem &&
+		cpURLs.TargetAlias == "" && cpURLs.TargetContent.URL.Type == fileSystem
+}
+
+// renameMove moves a local file to a local target by renaming it. Files
+// are only copied across devices, keeping their mode and modification
+// time, then removed by finishMove() once their copy is confirmed.
+func renameMove(ctx context.Context, cpURLs URLs, pg ProgressReader, isResumed bool, encKeyDB map[string][]prefixSSEPair) URLs {
+	if cpURLs.Error != nil {
+		return finishMove(cpURLs, isResumed, encKeyDB)
+	}
+	sourcePath, targetPath := cpURLs.SourceContent.URL.Path, cpURLs.TargetContent.URL.Path
+	st, e := os.Stat(sourcePath)
+	if e != nil {
+		return finishMove(cpURLs.WithError(probe.NewError(e).Trace(sourcePath)), isResumed, encKeyDB)
+	}
+	if e = os.MkdirAll(filepath.Dir(targetPath), 0777); e != nil {
+		return cpURLs.WithError(probe.NewError(e).Trace(targetPath))
+	}
+	printCopyMessage(cpURLs, pg)
+	e = renameFile(sourcePath, targetPath)
+	if e == nil {
+		return doCopyFake(cpURLs, pg)
+	}
+	if !isSysErrCrossDevice(e) {
+		return cpURLs.WithError(probe.NewError(e).Trace(sourcePath, targetPath))
+	}
+
+	cpURLs = uploadSourceToTargetURL(ctx, cpURLs, pg, encKeyDB)
+	if cpURLs.Error == nil {
+		if e = os.Chmod(targetPath, st.Mode().Perm()); e == nil {
+			e = os.Chtimes(targetPath, st.ModTime(), st.ModTime())
+		}
+		if e != nil {
+			cpURLs = cpURLs.WithError(probe.NewError(e).Trace(targetPath))
+		}
+	}
+	return finishMove(cpURLs, isResumed, encKeyDB)
+}
+
+// finishMove removes the source of a copy made by mv, unless the copy
+// failed or its target cannot be confirmed. A resumed session may list
+// sources which it already moved before it got interrupted, those are
+// done when their target is there.
+func finishMove(cpURLs URLs, isResumed bool, encKeyDB map[string][]prefixSSEPair) URLs {
+	if cpURLs.Error != nil {
+		if isResumed && isErrSourceMissing(cpURLs.Error) && confirmMoveTarget(cpURLs, encKeyDB) == nil {
+			return cpURLs.WithError(nil)
+		}
+		return cpURLs
+	}
+	if err := confirmMoveTarget(cpURLs, encKeyDB); err != nil {
+		return cpURLs.WithError(err)
+	}
+
+	sourceURL := cpURLs.SourceContent.URL.String()
+	clnt, err := newClientFromAlias(cpURLs.SourceAlias, sourceURL)
+	if err != nil {
+		return cpURLs.WithError(err.Trace(sourceURL))
+	}
+	contentCh := make(chan *clientContent, 1)
+	contentCh <- &clientContent{URL: cpURLs.SourceContent.URL}
+	close(contentCh)
+	isRemoveBucket := false
+	for err := range clnt.Remove(false, isRemoveBucket, contentCh) {
+		// Removed before the session got interrupted.
+		if err != nil && isResumed && os.IsNotExist(err.ToGoError()) {
+			continue
+		}
+		if err != nil {
+			return cpURLs.WithOperationError("delete", err.Trace(sourceURL))
+		}
+	}
+	return cpURLs.WithError(nil)
+}
+
+// confirmMoveTarget checks that the target of cpURLs exists with the
+// size which was copied and, when both can be compared, the ETag of the
+// source.
+func confirmMoveTarget(cpURLs URLs, encKeyDB map[string][]prefixSSEPair) *probe.Error {
+	targetURL := cpURLs.TargetContent.URL.String()
+	clnt, err := newClientFromAlias(cpURLs.TargetAlias, targetURL)
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	targetPath := filepath.ToSlash(filepath.Join(cpURLs.TargetAlias, cpURLs.TargetContent.URL.Path))
+	st, err := clnt.Stat(false, false, false, getSSE(targetPath, encKeyDB[cpURLs.TargetAlias]))
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	size := cpURLs.SourceContent.Size
+	if cpURLs.TargetContent.Metadata["Content-Encoding"] == "gzip" && cpURLs.TargetContent.Size > 0 {
+		// Compressed on upload.
+		size = cpURLs.TargetContent.Size
+	}
+	if st.Size != size {
+		return errMoveNotConfirmed(targetURL, fmt.Sprintf("has %d bytes instead of %d", st.Size, size)).Trace(targetURL)
+	}
+	if isPlainETag(cpURLs.SourceContent) && isPlainETag(st) && size == cpURLs.SourceContent.Size &&
+		cpURLs.SourceContent.ETag != st.ETag {
+		return errMoveNotConfirmed(targetURL, fmt.Sprintf("has ETag %s instead of %s", st.ETag, cpURLs.SourceContent.ETag)).Trace(targetURL)
+	}
+	return nil
+}
+
+// isPlainETag tells if content has the ETag of a single PUT without
+// encryption, the MD5 sum of its data, which is the same for all of
+// its copies. Multipart and encrypted objects have other ETags.
+func isPlainETag(content *clientContent) bool {
+	if len(content.ETag) != 32 || strings.Contains(content.ETag, "-") {
+		return false
+	}
+	for k := range content.Metadata {
+		if strings.HasPrefix(strings.ToLower(k), "x-amz-server-side-encryption") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+func TestFinishMove(t *testing.T) {
+	dir, e := ioutil.TempDir("", "mc-mv-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	defer useEmptyMcConfig()()
+
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if e := ioutil.WriteFile(path, []byte(data), 0600); e != nil {
+			t.Fatal(e)
+		}
+		return path
+	}
+	moveURLs := func(source, target string, size int64) URLs {
+		return URLs{
+			SourceContent: &clientContent{URL: *newClientURL(source), Size: size},
+			TargetContent: &clientContent{URL: *newClientURL(target), Metadata: map[string]string{}},
+		}
+	}
+	exists := func(path string) bool {
+		_, e := os.Stat(path)
+		return e == nil
+	}
+
+	// The source is removed once the target has all of it.
+	source, target := write("a", "hello"), write("a.moved", "hello")
+	if urls := finishMove(moveURLs(source, target, 5), false, nil); urls.Error != nil {
+		t.Fatalf("unexpected error: %v", urls.Error)
+	}
+	if exists(source) {
+		t.Error("source of a confirmed move was kept")
+	}
+
+	// A short target keeps the source.
+	source, target = write("b", "hello"), write("b.moved", "he")
+	if urls := finishMove(moveURLs(source, target, 5), false, nil); urls.Error == nil {
+		t.Error("expected an error for a short target")
+	}
+	if !exists(source) {
+		t.Error("source of an unconfirmed move was removed")
+	}
+
+	// A failed copy keeps the source even when the target looks right.
+	source, target = write("c", "hello"), write("c.moved", "hello")
+	urls := moveURLs(source, target, 5).WithError(probe.NewError(errors.New("upload failed")))
+	if urls = finishMove(urls, false, nil); urls.Error == nil {
+		t.Error("expected the copy error to be kept")
+	}
+	if !exists(source) {
+		t.Error("source of a failed copy was removed")
+	}
+
+	// A resumed session finds the sources it moved before gone.
+	target = write("d.moved", "hello")
+	missing := moveURLs(filepath.Join(dir, "d"), target, 5).WithError(probe.NewError(PathNotFound{Path: "d"}))
+	if urls = finishMove(missing, true, nil); urls.Error != nil {
+		t.Errorf("unexpected error on resume: %v", urls.Error)
+	}
+	if urls = finishMove(missing, false, nil); urls.Error == nil {
+		t.Error("expected a missing source to fail outside of a session")
+	}
+
+	// A resumed session may also have removed a source it lists as copied.
+	target = write("e.moved", "hello")
+	if urls = finishMove(moveURLs(filepath.Join(dir, "e"), target, 5), true, nil); urls.Error != nil {
+		t.Errorf("unexpected error on resume: %v", urls.Error)
+	}
+}
+
+func TestRenameMove(t *testing.T) {
+	dir, e := ioutil.TempDir("", "mc-mv-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	defer useEmptyMcConfig()()
+	savedQuiet := globalQuiet
+	globalQuiet = true
+	defer func() { globalQuiet = savedQuiet }()
+
+	modTime := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	// move moves name and tells if its target is the renamed source.
+	move := func(name string) bool {
+		source := filepath.Join(dir, name)
+		if e := ioutil.WriteFile(source, []byte("hello"), 0640); e != nil {
+			t.Fatal(e)
+		}
+		if e := os.Chtimes(source, modTime, modTime); e != nil {
+			t.Fatal(e)
+		}
+		st, e := os.Stat(source)
+		if e != nil {
+			t.Fatal(e)
+		}
+		target := filepath.Join(dir, "moved", name)
+		urls := URLs{
+			SourceContent: &clientContent{URL: *newClientURL(source), Size: 5},
+			TargetContent: &clientContent{URL: *newClientURL(target), Metadata: map[string]string{}},
+		}
+		if !isLocalMove(urls) {
+			t.Fatal("expected a local move")
+		}
+		if urls = renameMove(context.Background(), urls, newAccounter(5), false, nil); urls.Error != nil {
+			t.Fatalf("unexpected error: %v", urls.Error)
+		}
+		if _, e := os.Stat(source); !os.IsNotExist(e) {
+			t.Errorf("source %s was kept", name)
+		}
+		moved, e := os.Stat(target)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if data, _ := ioutil.ReadFile(target); string(data) != "hello" {
+			t.Errorf("unexpected target data %q", data)
+		}
+		if moved.Mode() != st.Mode() || !moved.ModTime().Equal(modTime) {
+			t.Errorf("expected mode %v and time %v, got %v and %v", st.Mode(), modTime, moved.Mode(), moved.ModTime())
+		}
+		return os.SameFile(st, moved)
+	}
+
+	// On the same device the file is renamed.
+	if !move("a") {
+		t.Error("expected the source to be renamed")
+	}
+
+	// Across devices it is copied, then removed.
+	savedRenameFile := renameFile
+	renameFile = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	defer func() { renameFile = savedRenameFile }()
+	if move("b") {
+		t.Error("expected the source to be copied")
+	}
+}
+
+func TestIsPlainETag(t *testing.T) {
+	testCases := []struct {
+		etag     string
+		metadata map[string]string
+		plain    bool
+	}{
+		{"5d41402abc4b2a76b9719d911017c592", nil, true},
+		{"5d41402abc4b2a76b9719d911017c592", map[string]string{"X-Amz-Server-Side-Encryption": "aws:kms"}, false},
+		{"5d41402abc4b2a76b9719d911017c5-2", nil, false},
+		{"", nil, false},
+	}
+	for i, testCase := range testCases {
+		content := &clientContent{ETag: testCase.etag, Metadata: testCase.metadata}
+		if plain := isPlainETag(content); plain != testCase.plain {
+			t.Errorf("Test %d: expected %t, got %t", i+1, testCase.plain, plain)
+		}
+	}
+}
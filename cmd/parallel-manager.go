@@ -32,8 +32,22 @@ const (
 
 	// Number of workers added per bandwidth monitoring.
 	defaultWorkerFactor = 2
+
+	// Tick at which workers are doubled during ramp-up
+	rampUpPeriod = time.Second
+
+	// Tick at which the heap is checked against --mem-limit
+	memoryPeriod = time.Second
 )
 
+// Set while the heap exceeds --mem-limit, uploads then buffer a single
+// part at a time.
+var globalMemoryPressure int32
+
+func isMemoryPressure() bool {
+	return atomic.LoadInt32(&globalMemoryPressure) == 1
+}
+
 // ParallelManager - helps manage parallel workers to run tasks
 type ParallelManager struct {
 	// Calculate sent bytes.
@@ -42,12 +56,29 @@ type ParallelManager struct {
 	// aligned at 64bit. See https://github.com/golang/go/issues/599
 	sentBytes int64
 
+	// Executed and failed tasks, used to detect error spikes
+	// during ramp-up.
+	totalTasks  int64
+	failedTasks int64
+
 	// Synchronize workers
 	wg *sync.WaitGroup
 
 	// Current threads number
 	workersNum uint32
 
+	// Upper limit of threads, lowered during ramp-up
+	maxWorkers uint32
+
+	// Number of threads asked for when ramping up, never exceeded once
+	// reached. Zero lets monitorProgress add threads after ramp-up.
+	fixedWorkers uint32
+
+	// Limit and number of threads before the heap exceeded --mem-limit,
+	// zero when not reduced. Only used by watchMemory.
+	memoryMaxWorkers uint32
+	memoryWorkersNum uint32
+
 	// Channel to receive tasks to run
 	queueCh chan func() URLs
 	// Channel to send back results
@@ -58,7 +89,7 @@ type ParallelManager struct {
 
 // addWorker creates a new worker to process tasks
 func (p *ParallelManager) addWorker() {
-	if atomic.LoadUint32(&p.workersNum) >= maxParallelWorkers {
+	if atomic.LoadUint32(&p.workersNum) >= atomic.LoadUint32(&p.maxWorkers) {
 		// Number of maximum workers is reached, no need to
 		// to create a new one.
 		return
@@ -80,11 +111,36 @@ func (p *ParallelManager) addWorker() {
 			}
 			// Execute the task and send the result
 			// to result channel.
-			p.resultCh <- fn()
+			urls := fn()
+			if urls.Error != nil {
+				atomic.AddInt64(&p.failedTasks, 1)
+			}
+			atomic.AddInt64(&p.totalTasks, 1)
+			globalMetrics.taskDone(urls)
+			p.resultCh <- urls
+
+			if p.removeWorker() {
+				p.wg.Done()
+				return
+			}
 		}
 	}()
 }
 
+// removeWorker returns true, and accounts for it, when the calling
+// worker must quit because the number of threads exceeds the limit.
+func (p *ParallelManager) removeWorker() bool {
+	for {
+		workersNum := atomic.LoadUint32(&p.workersNum)
+		if workersNum <= atomic.LoadUint32(&p.maxWorkers) {
+			return false
+		}
+		if atomic.CompareAndSwapUint32(&p.workersNum, workersNum, workersNum-1) {
+			return true
+		}
+	}
+}
+
 func (p *ParallelManager) Read(b []byte) (n int, err error) {
 	atomic.AddInt64(&p.sentBytes, int64(len(b)))
 	return len(b), nil
@@ -134,22 +190,166 @@ func (p *ParallelManager) monitorProgress() {
 	}()
 }
 
+// rampUp starts from a single worker and runs rampUpTick every tick
+// until the workers are ramped up, the regular bandwidth monitoring
+// then takes over unless a fixed number of workers was asked for.
+func (p *ParallelManager) rampUp() {
+	go func() {
+		ticker := time.NewTicker(rampUpPeriod)
+		defer ticker.Stop()
+
+		var prevTotal, prevFailed int64
+		rampUpWorkers := p.fixedWorkers
+		if rampUpWorkers == 0 {
+			rampUpWorkers = uint32(runtime.NumCPU())
+		}
+
+		for {
+			select {
+			case <-p.stopMonitorCh:
+				// Ordered to quit immediately
+				return
+			case <-ticker.C:
+				total := atomic.LoadInt64(&p.totalTasks)
+				failed := atomic.LoadInt64(&p.failedTasks)
+				tasks, errs := total-prevTotal, failed-prevFailed
+				prevTotal, prevFailed = total, failed
+
+				if p.rampUpTick(tasks, errs, rampUpWorkers) {
+					if p.fixedWorkers == 0 {
+						p.monitorProgress()
+					}
+					return
+				}
+			}
+		}
+	}()
+}
+
+// rampUpTick doubles the workers up to rampUpWorkers, halving them
+// instead if most of the tasks of the last tick failed. The workers
+// are left alone while memoryTick reduces them. It returns true once
+// ramped up, the limit is then lifted unless the workers are fixed.
+func (p *ParallelManager) rampUpTick(tasks, errs int64, rampUpWorkers uint32) bool {
+	if isMemoryPressure() {
+		return false
+	}
+
+	workers := atomic.LoadUint32(&p.maxWorkers)
+	if tasks > 0 && errs*2 > tasks {
+		// Errors spiked, back off.
+		if workers > 1 {
+			atomic.StoreUint32(&p.maxWorkers, workers/2)
+		}
+		return false
+	}
+
+	if workers >= rampUpWorkers {
+		if p.fixedWorkers == 0 {
+			atomic.StoreUint32(&p.maxWorkers, maxParallelWorkers)
+		}
+		return true
+	}
+
+	workers *= 2
+	if workers > rampUpWorkers {
+		workers = rampUpWorkers
+	}
+	atomic.StoreUint32(&p.maxWorkers, workers)
+	for atomic.LoadUint32(&p.workersNum) < workers {
+		p.addWorker()
+	}
+	return false
+}
+
+// watchMemory checks the heap every tick, see memoryTick.
+func (p *ParallelManager) watchMemory(limit uint64) {
+	go func() {
+		ticker := time.NewTicker(memoryPeriod)
+		defer ticker.Stop()
+
+		var memStats runtime.MemStats
+		for {
+			select {
+			case <-p.stopMonitorCh:
+				// Ordered to quit immediately
+				return
+			case <-ticker.C:
+				runtime.ReadMemStats(&memStats)
+				p.memoryTick(memStats.HeapInuse, limit)
+			}
+		}
+	}()
+}
+
+// memoryTick halves the workers while heapInuse exceeds limit, down to
+// a single one. The workers are restored once the heap is back below
+// three quarters of the limit.
+func (p *ParallelManager) memoryTick(heapInuse, limit uint64) {
+	if heapInuse > limit {
+		if p.memoryMaxWorkers == 0 {
+			p.memoryMaxWorkers = atomic.LoadUint32(&p.maxWorkers)
+			p.memoryWorkersNum = atomic.LoadUint32(&p.workersNum)
+			atomic.StoreInt32(&globalMemoryPressure, 1)
+		}
+		if workers := atomic.LoadUint32(&p.maxWorkers); workers > 1 {
+			atomic.StoreUint32(&p.maxWorkers, workers/2)
+		}
+		return
+	}
+	if p.memoryMaxWorkers == 0 || heapInuse > limit/4*3 {
+		return
+	}
+	atomic.StoreUint32(&p.maxWorkers, p.memoryMaxWorkers)
+	for atomic.LoadUint32(&p.workersNum) < p.memoryWorkersNum {
+		p.addWorker()
+	}
+	p.memoryMaxWorkers, p.memoryWorkersNum = 0, 0
+	atomic.StoreInt32(&globalMemoryPressure, 0)
+}
+
 // Wait for all workers to finish tasks before shutting down Parallel
 func (p *ParallelManager) wait() {
 	p.wg.Wait()
 	close(p.stopMonitorCh)
 }
 
-// newParallelManager starts new workers waiting for executing tasks
-func newParallelManager(resultCh chan URLs) (*ParallelManager, chan func() URLs) {
+// newParallelManager starts new workers waiting for executing tasks,
+// with rampUp only one worker is started and more are added gradually.
+// A positive workers runs at most that many, all of them started at
+// once unless ramping up.
+func newParallelManager(resultCh chan URLs, rampUp bool, workers int) (*ParallelManager, chan func() URLs) {
 	p := &ParallelManager{
 		wg:            &sync.WaitGroup{},
 		workersNum:    0,
+		maxWorkers:    maxParallelWorkers,
 		stopMonitorCh: make(chan struct{}),
 		queueCh:       make(chan func() URLs),
 		resultCh:      resultCh,
 	}
 
+	if globalMemoryLimit > 0 {
+		p.watchMemory(globalMemoryLimit)
+	}
+
+	if rampUp {
+		if workers > 0 {
+			p.fixedWorkers = uint32(workers)
+		}
+		p.maxWorkers = 1
+		p.addWorker()
+		p.rampUp()
+		return p, p.queueCh
+	}
+
+	if workers > 0 {
+		p.maxWorkers = uint32(workers)
+		for i := 0; i < workers; i++ {
+			p.addWorker()
+		}
+		return p, p.queueCh
+	}
+
 	// Start with runtime.NumCPU().
 	for i := 0; i < runtime.NumCPU(); i++ {
 		p.addWorker()
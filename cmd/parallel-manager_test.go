@@ -0,0 +1,147 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallelManagerMemoryTick(t *testing.T) {
+	resultCh := make(chan URLs)
+	p, queueCh := newParallelManager(resultCh, false, 0)
+	defer func() {
+		close(queueCh)
+		p.wait()
+	}()
+	maxWorkers := atomic.LoadUint32(&p.maxWorkers)
+	const limit = 1000
+
+	p.memoryTick(limit+1, limit)
+	if workers := atomic.LoadUint32(&p.maxWorkers); workers != maxWorkers/2 {
+		t.Fatalf("expected %d workers under pressure, found %d", maxWorkers/2, workers)
+	}
+	if !isMemoryPressure() {
+		t.Fatal("expected memory pressure")
+	}
+	for i := 0; i < 10; i++ {
+		p.memoryTick(limit+1, limit)
+	}
+	if workers := atomic.LoadUint32(&p.maxWorkers); workers != 1 {
+		t.Fatalf("expected a single worker, found %d", workers)
+	}
+
+	// Still close to the limit, nothing changes.
+	p.memoryTick(limit-1, limit)
+	if workers := atomic.LoadUint32(&p.maxWorkers); workers != 1 {
+		t.Fatalf("expected a single worker, found %d", workers)
+	}
+
+	p.memoryTick(limit/2, limit)
+	if workers := atomic.LoadUint32(&p.maxWorkers); workers != maxWorkers {
+		t.Fatalf("expected %d workers once restored, found %d", maxWorkers, workers)
+	}
+	if isMemoryPressure() {
+		t.Fatal("expected no memory pressure")
+	}
+}
+
+func TestParallelManagerRampUpMemory(t *testing.T) {
+	resultCh := make(chan URLs)
+	p, queueCh := newParallelManager(resultCh, false, 1)
+	defer func() {
+		close(queueCh)
+		p.wait()
+	}()
+	const limit = 1000
+
+	if p.rampUpTick(0, 0, 4) || atomic.LoadUint32(&p.maxWorkers) != 2 {
+		t.Fatalf("expected 2 workers, found %d", atomic.LoadUint32(&p.maxWorkers))
+	}
+	p.memoryTick(limit+1, limit)
+	// Ramping up does not undo the reduction.
+	for i := 0; i < 3; i++ {
+		if p.rampUpTick(0, 0, 4) {
+			t.Fatal("expected no ramp up under memory pressure")
+		}
+	}
+	if workers := atomic.LoadUint32(&p.maxWorkers); workers != 1 {
+		t.Fatalf("expected a single worker under pressure, found %d", workers)
+	}
+
+	p.memoryTick(limit/2, limit)
+	if p.rampUpTick(0, 0, 4) || atomic.LoadUint32(&p.maxWorkers) != 4 {
+		t.Fatalf("expected 4 workers, found %d", atomic.LoadUint32(&p.maxWorkers))
+	}
+	if !p.rampUpTick(0, 0, 4) || atomic.LoadUint32(&p.maxWorkers) != maxParallelWorkers {
+		t.Fatalf("expected %d workers once ramped up, found %d", maxParallelWorkers, atomic.LoadUint32(&p.maxWorkers))
+	}
+}
+
+func TestParallelManagerWorkers(t *testing.T) {
+	resultCh := make(chan URLs)
+	p, queueCh := newParallelManager(resultCh, false, 3)
+	if workers := atomic.LoadUint32(&p.workersNum); workers != 3 {
+		t.Fatalf("expected 3 workers, found %d", workers)
+	}
+
+	// Every worker holds a task until all of them are running.
+	var running sync.WaitGroup
+	running.Add(3)
+	for i := 0; i < 3; i++ {
+		queueCh <- func() URLs {
+			running.Done()
+			running.Wait()
+			return URLs{}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		<-resultCh
+	}
+	close(queueCh)
+	p.wait()
+	if workers := atomic.LoadUint32(&p.maxWorkers); workers != 3 {
+		t.Fatalf("expected at most 3 workers, found %d", workers)
+	}
+}
+
+func TestParallelManagerRampUpWorkers(t *testing.T) {
+	resultCh := make(chan URLs)
+	p, queueCh := newParallelManager(resultCh, true, 4)
+	if workers := atomic.LoadUint32(&p.workersNum); workers != 1 {
+		t.Fatalf("expected a single worker to start with, found %d", workers)
+	}
+	close(queueCh)
+	p.wait()
+
+	p, queueCh = newParallelManager(resultCh, false, 1)
+	defer func() {
+		close(queueCh)
+		p.wait()
+	}()
+	p.fixedWorkers = 4
+	for _, expected := range []uint32{2, 4} {
+		if p.rampUpTick(0, 0, 4) || atomic.LoadUint32(&p.maxWorkers) != expected {
+			t.Fatalf("expected %d workers, found %d", expected, atomic.LoadUint32(&p.maxWorkers))
+		}
+	}
+	// Ramped up to the workers asked for, no more are added.
+	if !p.rampUpTick(0, 0, 4) || atomic.LoadUint32(&p.maxWorkers) != 4 {
+		t.Fatalf("expected 4 workers once ramped up, found %d", atomic.LoadUint32(&p.maxWorkers))
+	}
+}
@@ -17,6 +17,7 @@
 package cmd
 
 import (
+	"io"
 	"os"
 	"syscall"
 
@@ -30,6 +31,18 @@ var (
 			Name:  "encrypt",
 			Usage: "encrypt objects (using server-side encryption with server managed keys)",
 		},
+		cli.StringFlag{
+			Name:  "sse",
+			Usage: "encrypt uploaded object(s) with server managed keys, AES256 for SSE-S3 or aws:kms for SSE-KMS",
+		},
+		cli.StringFlag{
+			Name:  "sse-kms-key-id",
+			Usage: "KMS key id used with --sse aws:kms, by default the default key of the server",
+		},
+		cli.StringFlag{
+			Name:  "part-size",
+			Usage: "upload to object storage in parts of this size, buffered in memory one at a time, the stream is limited to 10000 parts of this size, by default the part size configured for the host or large enough for a 5TiB object",
+		},
 	}
 )
 
@@ -65,10 +78,20 @@ EXAMPLES:
 
   4. Stream MySQL database dump to Amazon S3 directly.
      {{.Prompt}} mysqldump -u root -p ******* accountsdb | {{.HelpName}} s3/sql-backups/backups/accountsdb-oct-9-2015.sql
+
+  5. Stream a backup archive to Amazon S3, buffering parts of 16MiB instead of 640MiB to spare memory.
+     {{.Prompt}} tar cz mydir | {{.HelpName}} --part-size 16MiB s3/backups/mydir.tgz
+
+  6. Stream a database dump to Amazon S3 encrypted with SSE-S3.
+     {{.Prompt}} mysqldump -u root -p ******* accountsdb | {{.HelpName}} --sse AES256 s3/sql-backups/accountsdb.sql
 `,
 }
 
-func pipe(targetURL string, encKeyDB map[string][]prefixSSEPair) *probe.Error {
+// defaultStreamPartSize - the part size minio-go uploads streams of
+// unknown size in, large enough for a 5TiB object.
+const defaultStreamPartSize = 640 * 1024 * 1024
+
+func pipe(targetURL, partSize string, encKeyDB map[string][]prefixSSEPair) *probe.Error {
 	if targetURL == "" {
 		// When no target is specified, pipe cat's stdin to stdout.
 		return catOut(os.Stdin, -1).Trace()
@@ -76,10 +99,30 @@ func pipe(targetURL string, encKeyDB map[string][]prefixSSEPair) *probe.Error {
 	alias, _ := url2Alias(targetURL)
 	sseKey := getSSE(targetURL, encKeyDB[alias])
 
+	if hostCfg := hostConfigOf(targetURL); partSize == "" && hostCfg != nil {
+		partSize = hostCfg.PartSize
+	}
+	threshold, err := partSizeThreshold(partSize)
+	if err != nil {
+		return err.Trace(partSize)
+	}
+
+	streamPartSize := threshold / 2
+	if streamPartSize == 0 {
+		streamPartSize = defaultStreamPartSize
+	}
+
 	// Stream from stdin to multiple objects until EOF.
 	// Ignore size, since os.Stat() would not return proper size all the time
-	// for local filesystem for example /proc files.
-	_, err := putTargetStreamWithURL(targetURL, os.Stdin, -1, sseKey)
+	// for local filesystem for example /proc files. Uploads are completed
+	// after their last part whatever is left to read, stdin larger than
+	// its parts fails the upload instead.
+	stdin := &countReader{reader: &partsLimitReader{
+		reader:    os.Stdin,
+		partSize:  streamPartSize,
+		remaining: streamPartSize * maxPartsCount,
+	}}
+	n, err := putTargetStreamWithURL(targetURL, stdin, -1, streamPartSize, sseKey)
 	// TODO: See if this check is necessary.
 	switch e := err.ToGoError().(type) {
 	case *os.PathError:
@@ -88,7 +131,25 @@ func pipe(targetURL string, encKeyDB map[string][]prefixSSEPair) *probe.Error {
 			return nil
 		}
 	}
-	return err.Trace(targetURL)
+	if err != nil {
+		return err.Trace(targetURL)
+	}
+	if n != stdin.n {
+		return probe.NewError(UnexpectedEOF{TotalSize: stdin.n, TotalWritten: n}).Trace(targetURL)
+	}
+	return nil
+}
+
+// countReader - counts the bytes read from reader.
+type countReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, e := r.reader.Read(p)
+	r.n += int64(n)
+	return n, e
 }
 
 // check pipe input arguments.
@@ -96,6 +157,10 @@ func checkPipeSyntax(ctx *cli.Context) {
 	if len(ctx.Args()) > 1 {
 		cli.ShowCommandHelpAndExit(ctx, "pipe", 1) // last argument is exit code.
 	}
+	if partSize := ctx.String("part-size"); partSize != "" {
+		_, err := partSizeThreshold(partSize)
+		fatalIf(err.Trace(partSize), "Invalid --part-size `"+partSize+"`.")
+	}
 }
 
 // mainPipe is the main entry point for pipe command.
@@ -108,12 +173,17 @@ func mainPipe(ctx *cli.Context) error {
 	checkPipeSyntax(ctx)
 
 	if len(ctx.Args()) == 0 {
-		err = pipe("", nil)
+		err = pipe("", "", nil)
 		fatalIf(err.Trace("stdout"), "Unable to write to one or more targets.")
 	} else {
 		// extract URLs.
 		URLs := ctx.Args()
-		err = pipe(URLs[0], encKeyDB)
+		alias, _ := url2Alias(URLs[0])
+		tgtSSE, err := parseTargetSSE(ctx.String("sse"), ctx.String("sse-kms-key-id"), URLs[0], encKeyDB[alias])
+		fatalIf(err.Trace(URLs[0]), "Invalid server side encryption.")
+		addTargetSSE(encKeyDB, URLs[0], tgtSSE)
+
+		err = pipe(URLs[0], ctx.String("part-size"), encKeyDB)
 		fatalIf(err.Trace(URLs[0]), "Unable to write to one or more targets.")
 	}
 
@@ -124,7 +124,8 @@ func deleteBucket(url string) *probe.Error {
 	contentCh := make(chan *clientContent)
 	errorCh := clnt.Remove(isIncomplete, isRemoveBucket, contentCh)
 
-	for content := range clnt.List(true, false, false, DirLast) {
+	// Never leave a folder marker behind its content.
+	for content := range removeChildrenFirst(clnt.List(true, false, false, DirLast)) {
 		if content.Err != nil {
 			switch content.Err.ToGoError().(type) {
 			case PathInsufficientPermission:
@@ -0,0 +1,561 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+// reconcile specific flags.
+var (
+	reconcileFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "remove",
+			Usage: "remove object(s) on target which are not present on source",
+		},
+		cli.BoolFlag{
+			Name:  "continue, c",
+			Usage: "create or resume reconcile session",
+		},
+		cli.StringFlag{
+			Name:  "checkpoint-interval",
+			Usage: "save reconcile session every N objects or every DURATION",
+			Value: defaultCheckpointInterval,
+		},
+		cli.StringFlag{
+			Name:  "metrics-addr",
+			Usage: "serve Prometheus metrics of the transfer on this address, e.g. \":9090\"",
+		},
+		cli.StringFlag{
+			Name:  "throughput-log",
+			Usage: "write transferred bytes and objects to this CSV file every second",
+		},
+		cli.StringFlag{
+			Name:  "delimiter",
+			Usage: "single character delimiting the object hierarchy on object storage",
+			Value: "/",
+		},
+	}
+)
+
+// Reconcile command.
+var reconcileCmd = cli.Command{
+	Name:   "reconcile",
+	Usage:  "apply the differences between source and target in one pass",
+	Action: mainReconcile,
+	Before: setGlobalsFromContext,
+	Flags:  append(append(reconcileFlags, ioFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] SOURCE TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Reconcile computes the difference between SOURCE and TARGET in object name and size, then
+  copies objects only found on SOURCE, overwrites objects which changed and, with '--remove',
+  deletes objects only found on TARGET. A summary of every category is printed at the end.
+
+ENVIRONMENT VARIABLES:
+  MC_ENCRYPT_KEY:  list of comma delimited prefix=secret values
+
+EXAMPLES:
+  1. Reconcile a local folder with a bucket on MinIO cloud storage.
+     {{.Prompt}} {{.HelpName}} ~/Photos play/mybucket/Photos
+
+  2. Reconcile two buckets and remove extraneous objects on the target.
+     {{.Prompt}} {{.HelpName}} --remove s3/photos play/backup-photos
+
+  3. Reconcile two buckets in a session, run the same command again to resume if interrupted.
+     {{.Prompt}} {{.HelpName}} --continue --remove s3/photos play/backup-photos
+
+  4. Reconcile two buckets exposing transfer metrics at http://localhost:9090/metrics.
+     {{.Prompt}} {{.HelpName}} --metrics-addr :9090 s3/photos play/backup-photos
+
+  5. Reconcile a bucket recording the throughput over time in a CSV file.
+     {{.Prompt}} {{.HelpName}} --throughput-log tput.csv s3/photos play/backup-photos
+
+  6. Reconcile the objects below 'logs|' whose names use '|' as hierarchy delimiter.
+     {{.Prompt}} {{.HelpName}} --delimiter '|' s3/mybucket/logs play/backup/logs
+`,
+}
+
+const uaReconcileAppName = "mc-reconcile"
+
+// reconcileMessage container for reconcile summary.
+type reconcileMessage struct {
+	Status    string `json:"status"`
+	Copied    int64  `json:"copied"`
+	Updated   int64  `json:"updated"`
+	Deleted   int64  `json:"deleted"`
+	Unchanged int64  `json:"unchanged"`
+}
+
+// String colorized reconcile summary.
+func (r reconcileMessage) String() string {
+	return console.Colorize("Reconcile", fmt.Sprintf("Copied: %d, Updated: %d, Deleted: %d, Unchanged: %d",
+		r.Copied, r.Updated, r.Deleted, r.Unchanged))
+}
+
+// JSON jsonified reconcile summary.
+func (r reconcileMessage) JSON() string {
+	r.Status = "success"
+	reconcileMessageBytes, e := json.MarshalIndent(r, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(reconcileMessageBytes)
+}
+
+// reconcileKey names an action in the session, deletes are keyed by
+// target and copies or updates by source.
+func reconcileKey(urls URLs) string {
+	if urls.SourceContent != nil {
+		return urls.SourceContent.URL.String()
+	}
+	return urls.TargetContent.URL.String()
+}
+
+// Session header keys for saved summary counters.
+const (
+	reconcileCopiedKey    = "copied"
+	reconcileUpdatedKey   = "updated"
+	reconcileDeletedKey   = "deleted"
+	reconcileUnchangedKey = "unchanged"
+)
+
+// doPrepareReconcileURLs computes the difference and saves every action
+// into the session data file, it returns false when interrupted.
+func doPrepareReconcileURLs(session *sessionV8, cancelReconcile context.CancelFunc) (totalBytes, totalObjects, unchanged int64, ok bool) {
+	sourceURL := session.Header.CommandArgs[0]
+	targetURL := session.Header.CommandArgs[1]
+	isRemove := session.Header.CommandBoolFlags["remove"]
+
+	// Create a session data file to store the processed URLs.
+	dataFP := session.NewDataWriter()
+
+	var scanBar scanBarFunc
+	if !globalQuiet && !globalJSON { // set up progress bar
+		scanBar = scanBarFactory()
+	}
+
+	reconcileCh := prepareReconcileURLs(sourceURL, targetURL, isRemove)
+	done := false
+	for !done {
+		select {
+		case r, ok := <-reconcileCh:
+			if !ok { // Done with URL preparation
+				done = true
+				break
+			}
+			if r.Error != nil {
+				// Print in new line and adjust to top so that we don't print over the ongoing scan bar
+				if !globalQuiet && !globalJSON {
+					console.Eraseline()
+				}
+				errorIf(r.Error.Trace(), "Unable to prepare URL for reconciling.")
+				break
+			}
+			if r.Op == reconcileOpUnchanged {
+				unchanged++
+				break
+			}
+
+			jsonData, e := json.Marshal(r)
+			if e != nil {
+				session.Delete()
+				fatalIf(probe.NewError(e), "Unable to prepare URL for reconciling. Error in JSON marshaling.")
+			}
+			dataFP.Write(jsonData)
+			dataFP.Write([]byte{'\n'})
+			if !globalQuiet && !globalJSON {
+				scanBar(reconcileKey(r.URLs))
+			}
+
+			if r.SourceContent != nil {
+				totalBytes += r.SourceContent.Size
+			}
+			totalObjects++
+		case <-globalContext.Done():
+			cancelReconcile()
+			// Print in new line and adjust to top so that we don't print over the ongoing scan bar
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			return totalBytes, totalObjects, unchanged, false
+		}
+	}
+
+	session.Header.TotalBytes = totalBytes
+	session.Header.TotalObjects = totalObjects
+	session.Header.CommandIntFlags[reconcileUnchangedKey] = int(unchanged)
+	session.Save()
+	return totalBytes, totalObjects, unchanged, true
+}
+
+func doReconcileSession(cli *cli.Context, session *sessionV8, encKeyDB map[string][]prefixSSEPair) error {
+	ctx, cancelReconcile := context.WithCancel(globalContext)
+	defer cancelReconcile()
+
+	var isDone func(string) bool
+	var checkpoint *sessionCheckpoint
+	var summary reconcileMessage
+	var totalBytes int64
+
+	var reconcileCh = make(chan reconcileURLs, 10000)
+
+	// Store a progress bar or an accounter
+	var pg ProgressReader
+
+	// Enable progress bar reader only during default mode.
+	if !globalQuiet && !globalJSON { // set up progress bar
+		pg = newProgressBar(totalBytes)
+	} else {
+		pg = newAccounter(totalBytes)
+	}
+
+	if session != nil {
+		// isDone returns true if an action was already applied before
+		// this session got interrupted.
+		isDone = isLastFactory(session.Header.LastCopied)
+
+		var err *probe.Error
+		checkpoint, err = newSessionCheckpoint(session.Header.CommandStringFlags["checkpoint-interval"])
+		fatalIf(err, "Unable to parse checkpoint interval.")
+
+		if !session.HasData() {
+			var ok bool
+			totalBytes, _, summary.Unchanged, ok = doPrepareReconcileURLs(session, cancelReconcile)
+			if !ok {
+				// Interrupted during the URL scanning, the session is dropped.
+				return nil
+			}
+		} else {
+			totalBytes = session.Header.TotalBytes
+			summary.Copied = int64(session.Header.CommandIntFlags[reconcileCopiedKey])
+			summary.Updated = int64(session.Header.CommandIntFlags[reconcileUpdatedKey])
+			summary.Deleted = int64(session.Header.CommandIntFlags[reconcileDeletedKey])
+			summary.Unchanged = int64(session.Header.CommandIntFlags[reconcileUnchangedKey])
+		}
+
+		pg.SetTotal(totalBytes)
+
+		go func() {
+			// Prepare URL scanner from session data file.
+			urlScanner := bufio.NewScanner(session.NewDataReader())
+			for {
+				if !urlScanner.Scan() || urlScanner.Err() != nil {
+					close(reconcileCh)
+					break
+				}
+
+				var r reconcileURLs
+				if e := json.Unmarshal([]byte(urlScanner.Text()), &r); e != nil {
+					errorIf(probe.NewError(e), "Unable to unmarshal %s", urlScanner.Text())
+					continue
+				}
+
+				reconcileCh <- r
+			}
+		}()
+	} else {
+		sourceURL := cli.Args().Get(0)
+		targetURL := cli.Args().Get(1)
+
+		go func() {
+			totalBytes := int64(0)
+			for r := range prepareReconcileURLs(sourceURL, targetURL, cli.Bool("remove")) {
+				if r.Error == nil && r.Op == reconcileOpUnchanged {
+					atomic.AddInt64(&summary.Unchanged, 1)
+					continue
+				}
+				if r.Error == nil && r.SourceContent != nil {
+					totalBytes += r.SourceContent.Size
+					pg.SetTotal(totalBytes)
+				}
+				reconcileCh <- r
+			}
+			close(reconcileCh)
+		}()
+	}
+
+	var quitCh = make(chan struct{})
+	var statusCh = make(chan URLs)
+
+	parallel, queueCh := newParallelManager(statusCh, false, 0)
+
+	// Actions complete out of order, only those applied along with all
+	// those listed before them are saved as the last applied.
+	var watermark *copyWatermark
+	if session != nil {
+		watermark = newCopyWatermark()
+	}
+
+	go func() {
+		gracefulStop := func() {
+			close(queueCh)
+			parallel.wait()
+			close(statusCh)
+		}
+
+		for {
+			select {
+			case <-quitCh:
+				gracefulStop()
+				return
+			case r, ok := <-reconcileCh:
+				if !ok {
+					gracefulStop()
+					return
+				}
+
+				urls := r.URLs
+				if urls.Error != nil {
+					if watermark != nil {
+						urls.seq = watermark.queue()
+					}
+					queueCh <- func() URLs {
+						return urls
+					}
+					continue
+				}
+
+				var counter *int64
+				switch r.Op {
+				case reconcileOpCopy:
+					counter = &summary.Copied
+				case reconcileOpUpdate:
+					counter = &summary.Updated
+				case reconcileOpDelete:
+					counter = &summary.Deleted
+				default:
+					continue
+				}
+				if watermark != nil {
+					urls.seq = watermark.queue()
+				}
+
+				// Verify if previously applied, notify progress bar.
+				if isDone != nil && isDone(reconcileKey(urls)) {
+					queueCh <- func() URLs {
+						if urls.SourceContent == nil {
+							return urls
+						}
+						return doCopyFake(urls, pg)
+					}
+					continue
+				}
+
+				if r.Op == reconcileOpDelete {
+					queueCh <- func() URLs {
+						urls = removeTargetURL(urls, uaReconcileAppName)
+						if urls.Error == nil {
+							atomic.AddInt64(counter, 1)
+						}
+						return urls
+					}
+					continue
+				}
+
+				// Initialize target metadata.
+				urls.TargetContent.Metadata = make(map[string]string)
+
+				// Initialize target user metadata.
+				urls.TargetContent.UserMetadata = make(map[string]string)
+
+				queueCh <- func() URLs {
+					urls = doCopy(ctx, urls, pg, encKeyDB)
+					if urls.Error == nil {
+						atomic.AddInt64(counter, 1)
+					}
+					return urls
+				}
+			}
+		}
+	}()
+
+	// saveSummary records the counters so far in the session header.
+	saveSummary := func() {
+		session.Header.CommandIntFlags[reconcileCopiedKey] = int(atomic.LoadInt64(&summary.Copied))
+		session.Header.CommandIntFlags[reconcileUpdatedKey] = int(atomic.LoadInt64(&summary.Updated))
+		session.Header.CommandIntFlags[reconcileDeletedKey] = int(atomic.LoadInt64(&summary.Deleted))
+	}
+
+	var retErr error
+
+loop:
+	for {
+		select {
+		case <-globalContext.Done():
+			close(quitCh)
+			cancelReconcile()
+			// Receive interrupt notification.
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			if session != nil {
+				saveSummary()
+				return session.CloseAndDie()
+			}
+			break loop
+		case urls, ok := <-statusCh:
+			// Status channel is closed, we should return.
+			if !ok {
+				break loop
+			}
+			if session != nil {
+				// Failed actions are passed over like applied ones, only
+				// critical errors stop the session.
+				key := ""
+				if urls.SourceContent != nil || urls.TargetContent != nil {
+					key = reconcileKey(urls)
+				}
+				if lastApplied, ok := watermark.complete(urls.seq, key); ok && lastApplied != "" {
+					session.Header.LastCopied = lastApplied
+				}
+			}
+			if urls.Error == nil {
+				if urls.SourceContent == nil && urls.TargetContent != nil {
+					if !globalQuiet && !globalJSON {
+						console.Eraseline()
+					}
+					targetPath := filepath.ToSlash(filepath.Join(urls.TargetAlias, urls.TargetContent.URL.Path))
+					printMsg(rmMessage{Key: targetPath, Size: urls.TargetContent.Size})
+				}
+				if session != nil {
+					saveSummary()
+					if checkpoint.due() {
+						session.Save()
+					}
+				}
+				continue
+			}
+
+			// Set exit status for any reconcile error
+			retErr = exitStatus(globalErrorExitStatus)
+
+			// Print in new line and adjust to top so that we
+			// don't print over the ongoing progress bar.
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			switch {
+			case urls.SourceContent != nil:
+				errorIf(urls.Error.Trace(urls.SourceContent.URL.String()),
+					fmt.Sprintf("Failed to copy `%s`.", urls.SourceContent.URL.String()))
+			case urls.TargetContent != nil:
+				errorIf(urls.Error.Trace(urls.TargetContent.URL.String()),
+					fmt.Sprintf("Failed to remove `%s`.", urls.TargetContent.URL.String()))
+			default:
+				errorIf(urls.Error.Trace(), "Failed to reconcile.")
+			}
+			if isErrIgnored(urls.Error) {
+				continue loop
+			}
+
+			if session != nil {
+				// For critical errors we should exit. Session
+				// can be resumed after the user figures out
+				// the  problem.
+				saveSummary()
+				return session.copyCloseAndDie(true)
+			}
+		}
+	}
+
+	if progressReader, ok := pg.(*progressBar); ok {
+		if progressReader.ProgressBar.Get() > 0 {
+			progressReader.ProgressBar.Finish()
+		}
+	}
+
+	printMsg(reconcileMessage{
+		Copied:    atomic.LoadInt64(&summary.Copied),
+		Updated:   atomic.LoadInt64(&summary.Updated),
+		Deleted:   atomic.LoadInt64(&summary.Deleted),
+		Unchanged: atomic.LoadInt64(&summary.Unchanged),
+	})
+
+	return retErr
+}
+
+// mainReconcile is the entry point for reconcile command.
+func mainReconcile(ctx *cli.Context) error {
+	// Parse encryption keys per command.
+	encKeyDB, err := getEncKeys(ctx)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	// check 'reconcile' cli arguments.
+	checkReconcileSyntax(ctx, encKeyDB)
+
+	// Additional command specific theme customization.
+	console.SetColor("Copy", color.New(color.FgGreen, color.Bold))
+	console.SetColor("Remove", color.New(color.FgRed, color.Bold))
+	console.SetColor("Reconcile", color.New(color.FgCyan, color.Bold))
+
+	defer startTransferMetrics(ctx)()
+
+	var session *sessionV8
+
+	if ctx.Bool("continue") {
+		sessionID := getHash("reconcile", ctx.Args())
+		if isSessionExists(sessionID) {
+			session, err = resumeSessionV8(sessionID)
+			fatalIf(err.Trace(sessionID), "Unable to load session.")
+			if delimiter := session.Header.CommandStringFlags["delimiter"]; delimiter != "" {
+				globalListDelimiter = delimiter
+			}
+		} else {
+			expireSessions()
+			session = newSessionV8(sessionID)
+			session.Header.CommandType = "reconcile"
+			session.Header.CommandBoolFlags["remove"] = ctx.Bool("remove")
+			session.Header.CommandBoolFlags["session"] = ctx.Bool("continue")
+			session.Header.CommandStringFlags["checkpoint-interval"] = ctx.String("checkpoint-interval")
+			session.Header.CommandStringFlags["delimiter"] = globalListDelimiter
+
+			var e error
+			if session.Header.RootPath, e = os.Getwd(); e != nil {
+				session.Delete()
+				fatalIf(probe.NewError(e), "Unable to get current working folder.")
+			}
+
+			// extract URLs.
+			session.Header.CommandArgs = ctx.Args()
+		}
+	}
+
+	e := doReconcileSession(ctx, session, encKeyDB)
+	if session != nil && e != errSessionTerminated {
+		session.Delete()
+	}
+
+	return e
+}
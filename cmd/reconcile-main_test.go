@@ -0,0 +1,247 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/cli"
+)
+
+// writeReconcileDirs creates a source and a target folder for each
+// kind of reconcile action.
+func writeReconcileDirs(t *testing.T, dir string) (sourceDir, targetDir string) {
+	sourceDir, targetDir = filepath.Join(dir, "source"), filepath.Join(dir, "target")
+	for path, data := range map[string]string{
+		filepath.Join(sourceDir, "new"):     "new",
+		filepath.Join(sourceDir, "same"):    "data",
+		filepath.Join(sourceDir, "changed"): "new data",
+		filepath.Join(targetDir, "same"):    "data",
+		filepath.Join(targetDir, "changed"): "old",
+		filepath.Join(targetDir, "extra"):   "extra",
+	} {
+		if e := os.MkdirAll(filepath.Dir(path), 0755); e != nil {
+			t.Fatal(e)
+		}
+		if e := ioutil.WriteFile(path, []byte(data), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+	return sourceDir, targetDir
+}
+
+func TestPrepareReconcileURLs(t *testing.T) {
+	defer useEmptyMcConfig()()
+
+	dir, e := ioutil.TempDir("", "mc-reconcile-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	sourceDir, targetDir := writeReconcileDirs(t, dir)
+
+	for _, isRemove := range []bool{false, true} {
+		ops := make(map[string]reconcileOp)
+		for r := range prepareReconcileURLs(sourceDir, targetDir, isRemove) {
+			if r.Error != nil {
+				t.Fatal(r.Error)
+			}
+			ops[filepath.Base(reconcileKey(r.URLs))] = r.Op
+		}
+		expected := map[string]reconcileOp{
+			"new":     reconcileOpCopy,
+			"same":    reconcileOpUnchanged,
+			"changed": reconcileOpUpdate,
+		}
+		if isRemove {
+			expected["extra"] = reconcileOpDelete
+		}
+		if !reflect.DeepEqual(ops, expected) {
+			t.Fatalf("isRemove %t: expected %v, got %v", isRemove, expected, ops)
+		}
+	}
+}
+
+func TestReconcileSession(t *testing.T) {
+	defer useEmptyMcConfig()()
+	savedQuiet := globalQuiet
+	globalQuiet = true
+	defer func() { globalQuiet = savedQuiet }()
+	if err := createSessionDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, e := ioutil.TempDir("", "mc-reconcile-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	sourceDir, targetDir := writeReconcileDirs(t, dir)
+
+	session := newSessionV8(getHash("reconcile", []string{sourceDir, targetDir}))
+	session.Header.CommandType = "reconcile"
+	session.Header.CommandArgs = []string{sourceDir, targetDir}
+	session.Header.CommandBoolFlags["remove"] = true
+	defer session.Delete()
+
+	if e = doReconcileSession(nil, session, nil); e != nil {
+		t.Fatal(e)
+	}
+	for key, expected := range map[string]int{
+		reconcileCopiedKey:    1,
+		reconcileUpdatedKey:   1,
+		reconcileDeletedKey:   1,
+		reconcileUnchangedKey: 1,
+	} {
+		if got := session.Header.CommandIntFlags[key]; got != expected {
+			t.Fatalf("%s: expected %d, got %d", key, expected, got)
+		}
+	}
+	// Actions complete in any order, the session resumes after the last
+	// one listed.
+	var last reconcileURLs
+	for scanner := bufio.NewScanner(session.NewDataReader()); scanner.Scan(); {
+		if e = json.Unmarshal(scanner.Bytes(), &last); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if key := reconcileKey(last.URLs); session.Header.LastCopied != key {
+		t.Fatalf("expected to resume after %s, got %s", key, session.Header.LastCopied)
+	}
+
+	var names []string
+	infos, e := ioutil.ReadDir(targetDir)
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"changed", "new", "same"}) {
+		t.Fatalf("unexpected target content %v", names)
+	}
+	data, e := ioutil.ReadFile(filepath.Join(targetDir, "changed"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if string(data) != "new data" {
+		t.Fatalf("changed object was not updated, got %q", data)
+	}
+}
+
+func TestReconcileMissingTarget(t *testing.T) {
+	defer useEmptyMcConfig()()
+	savedQuiet := globalQuiet
+	globalQuiet = true
+	defer func() { globalQuiet = savedQuiet }()
+	if err := createSessionDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, e := ioutil.TempDir("", "mc-reconcile-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+	sourceDir, _ := writeReconcileDirs(t, dir)
+	targetDir := filepath.Join(dir, "missing", "target")
+
+	// A first reconcile creates its target.
+	set := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	for _, f := range reconcileCmd.Flags {
+		f.Apply(set)
+	}
+	if e = set.Parse([]string{sourceDir, targetDir}); e != nil {
+		t.Fatal(e)
+	}
+	checkReconcileSyntax(cli.NewContext(nil, set, nil), nil)
+
+	session := newSessionV8(getHash("reconcile", []string{sourceDir, targetDir}))
+	session.Header.CommandType = "reconcile"
+	session.Header.CommandArgs = []string{sourceDir, targetDir}
+	defer session.Delete()
+
+	if e = doReconcileSession(nil, session, nil); e != nil {
+		t.Fatal(e)
+	}
+	if got := session.Header.CommandIntFlags[reconcileCopiedKey]; got != 3 {
+		t.Fatalf("expected 3 copied objects, got %d", got)
+	}
+	for _, name := range []string{"new", "same", "changed"} {
+		if _, e = os.Stat(filepath.Join(targetDir, name)); e != nil {
+			t.Fatal(e)
+		}
+	}
+}
+
+// Test reconciling objects whose names use a delimiter other than "/".
+func TestPrepareReconcileURLsDelimiter(t *testing.T) {
+	var mutex sync.Mutex
+	var prefixes []string
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		prefix := r.URL.Query().Get("prefix")
+		mutex.Lock()
+		prefixes = append(prefixes, prefix)
+		mutex.Unlock()
+		var contents string
+		for _, key := range []string{"logs|a", "logs|b|c", "logsextra"} {
+			if strings.HasPrefix(key, prefix) {
+				contents += fmt.Sprintf("<Contents><Key>%s</Key><LastModified>2006-01-02T15:04:05.000Z</LastModified><ETag>&quot;etag&quot;</ETag><Size>1</Size><StorageClass>STANDARD</StorageClass></Contents>", key)
+			}
+		}
+		fmt.Fprintf(w, "<ListBucketResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>bucket</Name><Prefix>%s</Prefix><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>%s</ListBucketResult>", prefix, contents)
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("reconciletest", server.URL)()
+	savedDelimiter := globalListDelimiter
+	globalListDelimiter = "|"
+	defer func() { globalListDelimiter = savedDelimiter }()
+
+	targets := make(map[string]reconcileOp)
+	for r := range prepareReconcileURLs("reconciletest/bucket/logs", "reconciletest/bucket/archive", false) {
+		if r.Error != nil {
+			t.Fatal(r.Error)
+		}
+		targets[r.TargetContent.URL.Path] = r.Op
+	}
+	expected := map[string]reconcileOp{
+		"/bucket/archive|a":   reconcileOpCopy,
+		"/bucket/archive|b|c": reconcileOpCopy,
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Fatalf("expected %v, got %v", expected, targets)
+	}
+	sort.Strings(prefixes)
+	if !reflect.DeepEqual(prefixes, []string{"archive|", "logs|"}) {
+		t.Fatalf("expected source and target to be listed below their delimiter, got %v", prefixes)
+	}
+}
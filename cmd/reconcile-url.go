@@ -0,0 +1,155 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minio/cli"
+)
+
+// reconcileOp is the action required to bring a target in line with
+// its source.
+type reconcileOp string
+
+const (
+	reconcileOpCopy      reconcileOp = "copy"      // only in source
+	reconcileOpUpdate    reconcileOp = "update"    // in both, but changed
+	reconcileOpDelete    reconcileOp = "delete"    // only in target
+	reconcileOpUnchanged reconcileOp = "unchanged" // identical on both sides
+)
+
+// reconcileURLs is a single planned reconcile action, it is stored
+// line by line in the session data file.
+type reconcileURLs struct {
+	Op reconcileOp `json:"op"`
+	URLs
+}
+
+// checkReconcileSyntax - validate all the passed arguments.
+func checkReconcileSyntax(ctx *cli.Context, encKeyDB map[string][]prefixSSEPair) {
+	if len(ctx.Args()) != 2 {
+		cli.ShowCommandHelpAndExit(ctx, "reconcile", 1) // last argument is exit code.
+	}
+
+	for _, url := range ctx.Args() {
+		if strings.TrimSpace(url) == "" {
+			fatalIf(errInvalidArgument().Trace(ctx.Args()...), "Unable to validate empty argument.")
+		}
+	}
+
+	// Only the source must exist, the target is created on demand.
+	srcURL := ctx.Args().Get(0)
+	_, srcContent, err := url2Stat(srcURL, false, false, encKeyDB)
+	// incomplete uploads are not necessary for copy operation, no need to verify for them.
+	isIncomplete := false
+	if err != nil && !isURLPrefixExists(srcURL, isIncomplete) {
+		fatalIf(err.Trace(srcURL), fmt.Sprintf("Unable to stat source `%s`.", srcURL))
+	}
+	if err == nil && !srcContent.Type.IsDir() {
+		fatalIf(errInvalidArgument().Trace(srcURL), fmt.Sprintf("Source `%s` is not a folder.", srcURL))
+	}
+}
+
+// prepareReconcileURLs lists source and target and classifies every
+// object into a reconcileOp. Target only objects are skipped unless
+// isRemove is set.
+func prepareReconcileURLs(sourceURL, targetURL string, isRemove bool) <-chan reconcileURLs {
+	reconcileCh := make(chan reconcileURLs)
+	go func() {
+		defer close(reconcileCh)
+
+		sourceAlias, sourceURL, _ := mustExpandAlias(sourceURL)
+		targetAlias, targetURL, _ := mustExpandAlias(targetURL)
+
+		// Source and targets are always directories, delimited as listed.
+		sourceDelimiter := dirDelimiter(*newClientURL(sourceURL))
+		if !strings.HasSuffix(sourceURL, sourceDelimiter) {
+			sourceURL = sourceURL + sourceDelimiter
+		}
+		targetDelimiter := dirDelimiter(*newClientURL(targetURL))
+		if !strings.HasSuffix(targetURL, targetDelimiter) {
+			targetURL = targetURL + targetDelimiter
+		}
+
+		sourceClnt, err := newClientFromAlias(sourceAlias, sourceURL)
+		if err != nil {
+			reconcileCh <- reconcileURLs{URLs: URLs{Error: err.Trace(sourceAlias, sourceURL)}}
+			return
+		}
+		targetClnt, err := newClientFromAlias(targetAlias, targetURL)
+		if err != nil {
+			reconcileCh <- reconcileURLs{URLs: URLs{Error: err.Trace(targetAlias, targetURL)}}
+			return
+		}
+
+		isMetadata, isMtime, isRecursive, returnSimilar := false, false, true, true
+		for diffMsg := range difference(sourceClnt, targetClnt, sourceURL, targetURL, isMetadata, isMtime, isRecursive, returnSimilar, DirNone) {
+			if diffMsg.Error != nil {
+				reconcileCh <- reconcileURLs{URLs: URLs{Error: diffMsg.Error}}
+				continue
+			}
+
+			var op reconcileOp
+			switch diffMsg.Diff {
+			case differInNone:
+				op = reconcileOpUnchanged
+			case differInFirst:
+				op = reconcileOpCopy
+			case differInSize, differInMetadata, differInETag, differInTime:
+				op = reconcileOpUpdate
+			case differInSecond:
+				if !isRemove {
+					continue
+				}
+				reconcileCh <- reconcileURLs{
+					Op: reconcileOpDelete,
+					URLs: URLs{
+						TargetAlias:   targetAlias,
+						TargetContent: diffMsg.secondContent,
+					},
+				}
+				continue
+			case differInType:
+				reconcileCh <- reconcileURLs{URLs: URLs{Error: errInvalidTarget(diffMsg.SecondURL)}}
+				continue
+			default:
+				reconcileCh <- reconcileURLs{
+					URLs: URLs{Error: errUnrecognizedDiffType(diffMsg.Diff).Trace(diffMsg.FirstURL, diffMsg.SecondURL)},
+				}
+				continue
+			}
+
+			sourceSuffix := strings.TrimPrefix(diffMsg.FirstURL, sourceURL)
+			targetPath := targetURL + sourceSuffix
+			if targetDelimiter == string(newClientURL(targetURL).Separator) {
+				targetPath = urlJoinPath(targetURL, sourceSuffix)
+			}
+			reconcileCh <- reconcileURLs{
+				Op: op,
+				URLs: URLs{
+					SourceAlias:   sourceAlias,
+					SourceContent: diffMsg.firstContent,
+					TargetAlias:   targetAlias,
+					TargetContent: &clientContent{URL: *newClientURL(targetPath)},
+				},
+			}
+		}
+	}()
+	return reconcileCh
+}
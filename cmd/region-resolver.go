@@ -0,0 +1,180 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+// regionLookupLocation leaves the region to the bucket location
+// lookup (GET ?location) done on first access of every bucket.
+const regionLookupLocation = "location"
+
+// Maximum size of a region metadata response.
+const maxRegionResponseSize = 4096
+
+// regionResolver finds the region of a host which has none configured.
+type regionResolver interface {
+	resolveRegion(hostCfg *hostConfigV9) (string, *probe.Error)
+}
+
+// locationRegionResolver resolves no region upfront, minio-go then
+// looks up the location of each bucket and caches it.
+type locationRegionResolver struct{}
+
+func (locationRegionResolver) resolveRegion(hostCfg *hostConfigV9) (string, *probe.Error) {
+	return "", nil
+}
+
+// metadataRegionResolver reads the region from an endpoint of the host,
+// answering either {"region": "..."} or the region as plain text.
+type metadataRegionResolver struct {
+	path string
+}
+
+func (r metadataRegionResolver) resolveRegion(hostCfg *hostConfigV9) (string, *probe.Error) {
+	metadataURL := urlJoinPath(hostCfg.URL, r.path)
+	resp, e := newHTTPClient(globalConnectTimeout, globalInsecure).Get(metadataURL)
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", probe.NewError(errors.New("region metadata endpoint answered " + resp.Status))
+	}
+	body, e := ioutil.ReadAll(io.LimitReader(resp.Body, maxRegionResponseSize))
+	if e != nil {
+		return "", probe.NewError(e)
+	}
+	var metadata struct {
+		Region string `json:"region"`
+	}
+	region := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &metadata) == nil {
+		region = metadata.Region
+	}
+	if region == "" || strings.ContainsAny(region, " \t\r\n") {
+		return "", probe.NewError(errors.New("region metadata endpoint answered no region"))
+	}
+	return region, nil
+}
+
+// newRegionResolver returns the resolver selected by the regionLookup
+// of a host, either "location" or the path of a metadata endpoint.
+func newRegionResolver(regionLookup string) regionResolver {
+	if regionLookup == "" || regionLookup == regionLookupLocation {
+		return locationRegionResolver{}
+	}
+	return metadataRegionResolver{path: regionLookup}
+}
+
+// isValidRegionLookup - validates the region lookup of a host.
+func isValidRegionLookup(regionLookup string) bool {
+	return regionLookup == "" || regionLookup == regionLookupLocation || strings.HasPrefix(regionLookup, "/")
+}
+
+// Regions resolved so far, by host URL and region lookup.
+var regionCache = struct {
+	sync.Mutex
+	regions map[string]string
+}{regions: make(map[string]string)}
+
+// resolveHostRegion returns the region to sign requests to a host with,
+// looking it up on first contact when none is configured. A failed
+// lookup falls back to globalDefaultRegion.
+func resolveHostRegion(alias string, hostCfg *hostConfigV9) string {
+	if hostCfg == nil {
+		return ""
+	}
+	if hostCfg.Region != "" {
+		return hostCfg.Region
+	}
+	if globalOffline {
+		return ""
+	}
+
+	cacheKey := hostCfg.URL + " " + hostCfg.RegionLookup
+	regionCache.Lock()
+	defer regionCache.Unlock()
+	if region, ok := regionCache.regions[cacheKey]; ok {
+		return region
+	}
+	region, err := newRegionResolver(hostCfg.RegionLookup).resolveRegion(hostCfg)
+	if err != nil {
+		errorIf(err.Trace(alias, hostCfg.URL), "Unable to look up the region of `"+alias+"`, using `"+globalDefaultRegion+"`.")
+		region = globalDefaultRegion
+	} else if region != "" && hostCfg.SaveRegion {
+		saveHostRegion(alias, region)
+	}
+	regionCache.regions[cacheKey] = region
+	return region
+}
+
+// Regions buckets redirected requests to, by scheme, host and bucket.
+// They win over the configured region for the rest of the command.
+var redirectedRegions = struct {
+	sync.Mutex
+	regions map[string]string
+}{regions: make(map[string]string)}
+
+// redirectedRegionKey returns the scheme, host and bucket of a URL.
+func redirectedRegionKey(u *clientURL) string {
+	bucket := strings.SplitN(strings.TrimPrefix(u.Path, string(u.Separator)), string(u.Separator), 2)[0]
+	return u.Scheme + "://" + u.Host + "/" + bucket
+}
+
+// saveRedirectedRegion remembers the region the bucket of a URL
+// redirected to.
+func saveRedirectedRegion(u *clientURL, region string) {
+	redirectedRegions.Lock()
+	defer redirectedRegions.Unlock()
+	redirectedRegions.regions[redirectedRegionKey(u)] = region
+}
+
+// redirectedBucketRegion returns the region the bucket of a URL
+// redirected to, if any.
+func redirectedBucketRegion(u *clientURL) string {
+	redirectedRegions.Lock()
+	defer redirectedRegions.Unlock()
+	return redirectedRegions.regions[redirectedRegionKey(u)]
+}
+
+// saveHostRegion stores a looked up region in the host config, hosts
+// given through the environment are left alone.
+func saveHostRegion(alias, region string) {
+	mcCfg, err := loadMcConfig()
+	if err != nil {
+		errorIf(err.Trace(alias), "Unable to save the region of `"+alias+"`.")
+		return
+	}
+	hostCfg, ok := mcCfg.Hosts[alias]
+	if !ok {
+		return
+	}
+	hostCfg.Region = region
+	mcCfg.Hosts[alias] = hostCfg
+	err = saveMcConfig(mcCfg)
+	errorIf(err.Trace(alias), "Unable to save the region of `"+alias+"`.")
+}
@@ -50,10 +50,9 @@ func (r *lockedRandSource) Seed(seed int64) {
 // Introduce a new locked random seed.
 var random = rand.New(&lockedRandSource{src: rand.NewSource(time.Now().UTC().UnixNano())})
 
-// newRetryTimerContinous creates a timer with exponentially increasing delays forever.
-func newRetryTimerContinous(unit time.Duration, cap time.Duration, jitter float64, doneCh chan struct{}) <-chan int {
-	attemptCh := make(chan int)
-
+// exponentialBackoffWait computes the exponential backoff duration according to
+// https://www.awsarchitectureblog.com/2015/03/backoff.html
+func exponentialBackoffWait(unit time.Duration, cap time.Duration, jitter float64, attempt int) time.Duration {
 	// normalize jitter to the range [0, 1.0]
 	if jitter < minio.NoJitter {
 		jitter = minio.NoJitter
@@ -62,24 +61,25 @@ func newRetryTimerContinous(unit time.Duration, cap time.Duration, jitter float6
 		jitter = minio.MaxJitter
 	}
 
-	// computes the exponential backoff duration according to
-	// https://www.awsarchitectureblog.com/2015/03/backoff.html
-	exponentialBackoffWait := func(attempt int) time.Duration {
-		// 1<<uint(attempt) below could overflow, so limit the value of attempt
-		maxAttempt := 30
-		if attempt > maxAttempt {
-			attempt = maxAttempt
-		}
-		//sleep = random_between(0, min(cap, base * 2 ** attempt))
-		sleep := unit * time.Duration(1<<uint(attempt))
-		if sleep > cap {
-			sleep = cap
-		}
-		if jitter != minio.NoJitter {
-			sleep -= time.Duration(random.Float64() * float64(sleep) * jitter)
-		}
-		return sleep
+	// 1<<uint(attempt) below could overflow, so limit the value of attempt
+	maxAttempt := 30
+	if attempt > maxAttempt {
+		attempt = maxAttempt
 	}
+	//sleep = random_between(0, min(cap, base * 2 ** attempt))
+	sleep := unit * time.Duration(1<<uint(attempt))
+	if sleep > cap {
+		sleep = cap
+	}
+	if jitter != minio.NoJitter {
+		sleep -= time.Duration(random.Float64() * float64(sleep) * jitter)
+	}
+	return sleep
+}
+
+// newRetryTimerContinous creates a timer with exponentially increasing delays forever.
+func newRetryTimerContinous(unit time.Duration, cap time.Duration, jitter float64, doneCh chan struct{}) <-chan int {
+	attemptCh := make(chan int)
 
 	go func() {
 		defer close(attemptCh)
@@ -93,7 +93,7 @@ func newRetryTimerContinous(unit time.Duration, cap time.Duration, jitter float6
 				// Stop the routine.
 				return
 			}
-			time.Sleep(exponentialBackoffWait(nextBackoff))
+			time.Sleep(exponentialBackoffWait(unit, cap, jitter, nextBackoff))
 		}
 	}()
 	return attemptCh
@@ -50,8 +50,8 @@ var (
 			Usage: "remove incomplete uploads",
 		},
 		cli.BoolFlag{
-			Name:  "fake",
-			Usage: "perform a fake remove operation",
+			Name:  "fake, dry-run",
+			Usage: "perform a fake remove operation, only printing what would be removed",
 		},
 		cli.BoolFlag{
 			Name:  "stdin",
@@ -65,9 +65,22 @@ var (
 			Name:  "newer-than",
 			Usage: "remove objects newer than L days, M hours and N minutes",
 		},
+		cli.StringFlag{
+			Name:  "remove-order",
+			Usage: "order of a recursive remove, \"children-first\" or \"listing\"",
+			Value: removeOrderChildrenFirst,
+		},
 	}
 )
 
+// Orders of a recursive remove.
+const (
+	// Folder markers are removed after everything below them.
+	removeOrderChildrenFirst = "children-first"
+	// Objects are removed in listing order.
+	removeOrderListing = "listing"
+)
+
 // remove a file or folder.
 var rmCmd = cli.Command{
 	Name:   "rm",
@@ -117,6 +130,12 @@ EXAMPLES:
 
   10. Remove an encrypted object from Amazon S3 cloud storage.
       {{.Prompt}} {{.HelpName}} --encrypt-key "s3/sql-backups/=32byteslongsecretkeymustbegiven1" s3/sql-backups/1999/old-backup.tgz
+
+  11. Remove all objects recursively in listing order, removing folder markers before their content.
+      {{.Prompt}} {{.HelpName}} --recursive --force --remove-order listing s3/jazz-songs/louis/
+
+  12. List the objects a recursive remove of the prefix 'louis' would delete, without deleting them.
+      {{.Prompt}} {{.HelpName}} --recursive --force --dry-run s3/jazz-songs/louis/
 `,
 }
 
@@ -178,6 +197,42 @@ func checkRmSyntax(ctx *cli.Context, encKeyDB map[string][]prefixSSEPair) {
 		fatalIf(errDummy().Trace(),
 			"This operation results in site-wide removal of objects. If you are really sure, retry this command with ‘--dangerous’ and ‘--force’ flags.")
 	}
+	switch order := ctx.String("remove-order"); order {
+	case removeOrderChildrenFirst, removeOrderListing:
+	default:
+		fatalIf(errInvalidArgument().Trace(order), "Unknown remove order `"+order+"`.")
+	}
+}
+
+// removeChildrenFirst - holds back folder markers (keys ending with
+// a separator) of a recursive listing until all objects below them
+// are passed on. Listings are sorted, the content of a folder always
+// directly follows its marker, so only the markers of the current
+// path are held back at any time.
+func removeChildrenFirst(contentCh <-chan *clientContent) <-chan *clientContent {
+	orderedCh := make(chan *clientContent)
+	go func() {
+		defer close(orderedCh)
+		var markers []*clientContent
+		for content := range contentCh {
+			if content.Err == nil {
+				path := content.URL.Path
+				for len(markers) > 0 && !strings.HasPrefix(path, markers[len(markers)-1].URL.Path) {
+					orderedCh <- markers[len(markers)-1]
+					markers = markers[:len(markers)-1]
+				}
+				if strings.HasSuffix(path, string(content.URL.Separator)) {
+					markers = append(markers, content)
+					continue
+				}
+			}
+			orderedCh <- content
+		}
+		for i := len(markers) - 1; i >= 0; i-- {
+			orderedCh <- markers[i]
+		}
+	}()
+	return orderedCh
 }
 
 func removeSingle(url string, isIncomplete bool, isFake, isForce bool, olderThan, newerThan string, encKeyDB map[string][]prefixSSEPair) error {
@@ -243,7 +298,7 @@ func removeSingle(url string, isIncomplete bool, isFake, isForce bool, olderThan
 	return nil
 }
 
-func removeRecursive(url string, isIncomplete bool, isFake bool, olderThan, newerThan string, encKeyDB map[string][]prefixSSEPair) error {
+func removeRecursive(url string, isIncomplete bool, isFake, isChildrenFirst bool, olderThan, newerThan string, encKeyDB map[string][]prefixSSEPair) error {
 	targetAlias, targetURL, _ := mustExpandAlias(url)
 	clnt, pErr := newClientFromAlias(targetAlias, targetURL)
 	if pErr != nil {
@@ -255,8 +310,25 @@ func removeRecursive(url string, isIncomplete bool, isFake bool, olderThan, newe
 
 	errorCh := clnt.Remove(isIncomplete, isRemoveBucket, contentCh)
 
+	// Objects which could not be removed are reported one by one, the
+	// remove goes on with the others.
+	var failed bool
+	reportRemoveError := func(pErr *probe.Error) {
+		errorIf(pErr.Trace(url), "Failed to remove `"+url+"` recursively.")
+		switch pErr.ToGoError().(type) {
+		case PathInsufficientPermission:
+			// Ignore Permission error.
+			return
+		}
+		failed = true
+	}
+
 	isRecursive := true
-	for content := range clnt.List(isRecursive, isIncomplete, false, DirNone) {
+	listCh := clnt.List(isRecursive, isIncomplete, false, DirNone)
+	if isChildrenFirst {
+		listCh = removeChildrenFirst(listCh)
+	}
+	for content := range listCh {
 		if content.Err != nil {
 			errorIf(content.Err.Trace(url), "Failed to remove `"+url+"` recursively.")
 			switch content.Err.ToGoError().(type) {
@@ -296,14 +368,7 @@ func removeRecursive(url string, isIncomplete bool, isFake bool, olderThan, newe
 				case contentCh <- content:
 					sent = true
 				case pErr := <-errorCh:
-					errorIf(pErr.Trace(urlString), "Failed to remove `"+urlString+"`.")
-					switch pErr.ToGoError().(type) {
-					case PathInsufficientPermission:
-						// Ignore Permission error.
-						continue
-					}
-					close(contentCh)
-					return exitStatus(globalErrorExitStatus)
+					reportRemoveError(pErr)
 				}
 			}
 		}
@@ -311,15 +376,12 @@ func removeRecursive(url string, isIncomplete bool, isFake bool, olderThan, newe
 
 	close(contentCh)
 	for pErr := range errorCh {
-		errorIf(pErr.Trace(url), "Failed to remove `"+url+"` recursively.")
-		switch pErr.ToGoError().(type) {
-		case PathInsufficientPermission:
-			// Ignore Permission error.
-			continue
-		}
-		return exitStatus(globalErrorExitStatus)
+		reportRemoveError(pErr)
 	}
 
+	if failed {
+		return exitStatus(globalErrorExitStatus)
+	}
 	return nil
 }
 
@@ -340,6 +402,7 @@ func mainRm(ctx *cli.Context) error {
 	olderThan := ctx.String("older-than")
 	newerThan := ctx.String("newer-than")
 	isForce := ctx.Bool("force")
+	isChildrenFirst := ctx.String("remove-order") == removeOrderChildrenFirst
 
 	// Set color.
 	console.SetColor("Remove", color.New(color.FgGreen, color.Bold))
@@ -349,7 +412,7 @@ func mainRm(ctx *cli.Context) error {
 	// Support multiple targets.
 	for _, url := range ctx.Args() {
 		if isRecursive {
-			e = removeRecursive(url, isIncomplete, isFake, olderThan, newerThan, encKeyDB)
+			e = removeRecursive(url, isIncomplete, isFake, isChildrenFirst, olderThan, newerThan, encKeyDB)
 		} else {
 			e = removeSingle(url, isIncomplete, isFake, isForce, olderThan, newerThan, encKeyDB)
 		}
@@ -367,7 +430,7 @@ func mainRm(ctx *cli.Context) error {
 	for scanner.Scan() {
 		url := scanner.Text()
 		if isRecursive {
-			e = removeRecursive(url, isIncomplete, isFake, olderThan, newerThan, encKeyDB)
+			e = removeRecursive(url, isIncomplete, isFake, isChildrenFirst, olderThan, newerThan, encKeyDB)
 		} else {
 			e = removeSingle(url, isIncomplete, isFake, isForce, olderThan, newerThan, encKeyDB)
 		}
@@ -0,0 +1,73 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveChildrenFirst(t *testing.T) {
+	testCases := []struct {
+		listed   []string
+		expected []string
+	}{
+		// No folder markers, listing order is kept.
+		{
+			[]string{"/bucket/a", "/bucket/b/c", "/bucket/d"},
+			[]string{"/bucket/a", "/bucket/b/c", "/bucket/d"},
+		},
+		// Nested markers follow their content.
+		{
+			[]string{"/bucket/a/", "/bucket/a/b/", "/bucket/a/b/c", "/bucket/a/b/d", "/bucket/a/e", "/bucket/f"},
+			[]string{"/bucket/a/b/c", "/bucket/a/b/d", "/bucket/a/b/", "/bucket/a/e", "/bucket/a/", "/bucket/f"},
+		},
+		// Sibling markers, a key sharing the marker name is not its child.
+		{
+			[]string{"/bucket/a/", "/bucket/a/x", "/bucket/ab", "/bucket/b/", "/bucket/b/y"},
+			[]string{"/bucket/a/x", "/bucket/a/", "/bucket/ab", "/bucket/b/y", "/bucket/b/"},
+		},
+		// Empty markers and markers at the end of the listing.
+		{
+			[]string{"/bucket/a/", "/bucket/b/", "/bucket/b/c/", "/bucket/b/c/d/"},
+			[]string{"/bucket/a/", "/bucket/b/c/d/", "/bucket/b/c/", "/bucket/b/"},
+		},
+		// A folder listed after its content (DirLast) stays there.
+		{
+			[]string{"/bucket/a/", "/bucket/a/b", "/bucket/a/", "/bucket/c"},
+			[]string{"/bucket/a/b", "/bucket/a/", "/bucket/a/", "/bucket/c"},
+		},
+	}
+
+	for i, testCase := range testCases {
+		contentCh := make(chan *clientContent)
+		go func(listed []string) {
+			defer close(contentCh)
+			for _, path := range listed {
+				contentCh <- &clientContent{URL: *newClientURL("https://localhost:9000" + path)}
+			}
+		}(testCase.listed)
+
+		var removed []string
+		for content := range removeChildrenFirst(contentCh) {
+			removed = append(removed, content.URL.Path)
+		}
+		if !reflect.DeepEqual(removed, testCase.expected) {
+			t.Fatalf("Test %d: expected %v, found %v", i+1, testCase.expected, removed)
+		}
+	}
+}
@@ -0,0 +1,576 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	json "github.com/minio/mc/pkg/colorjson"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+	"github.com/minio/minio/pkg/console"
+)
+
+// scan specific flags.
+var (
+	scanFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "repair-from",
+			Usage: "re-upload objects failing the scan from this local folder",
+		},
+		cli.BoolFlag{
+			Name:  "quick",
+			Usage: "compare the checksum stored with an object by 'cp --checksum' with its local copy instead of reading the object",
+		},
+		cli.BoolFlag{
+			Name:  "continue, c",
+			Usage: "create or resume scan session",
+		},
+		cli.StringFlag{
+			Name:  "checkpoint-interval",
+			Usage: "save scan session every N objects or every DURATION",
+			Value: defaultCheckpointInterval,
+		},
+	}
+)
+
+// Scan command.
+var scanCmd = cli.Command{
+	Name:   "scan",
+	Usage:  "verify the integrity of objects and repair them from a local copy",
+	Action: mainScan,
+	Before: setGlobalsFromContext,
+	Flags:  append(append(scanFlags, ioFlags...), globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] TARGET
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+DESCRIPTION:
+  Scan reads every object below TARGET and compares its MD5 with the ETag. Objects uploaded in
+  several parts have no MD5 for an ETag, they are compared with their local copy if one is
+  given with '--repair-from' and reported as unverified otherwise. Objects copied with
+  'mc cp --checksum' are compared with the checksum stored in their metadata instead.
+
+  With '--quick', the stored checksum is trusted to describe the object: it is compared with
+  the local copy given with '--repair-from' and the object is not read. Objects without a
+  stored checksum are read as usual.
+
+  With '--repair-from', an object failing the scan is uploaded again from the file at the same
+  relative path, provided that the file itself matches the ETag of the object.
+
+ENVIRONMENT VARIABLES:
+  MC_ENCRYPT_KEY:  list of comma delimited prefix=secret values
+
+EXAMPLES:
+  1. Scan all objects of a bucket.
+     {{.Prompt}} {{.HelpName}} s3/archive
+
+  2. Scan a prefix and repair corrupted objects from a local copy.
+     {{.Prompt}} {{.HelpName}} --repair-from /mnt/archive/2019/ s3/archive/2019/
+
+  3. Scan a large bucket in a session, run the same command again to resume if interrupted.
+     {{.Prompt}} {{.HelpName}} --continue --repair-from /mnt/archive/ s3/archive
+
+  4. Quickly check objects copied with '--checksum' against their local copy, without downloading them.
+     {{.Prompt}} {{.HelpName}} --quick --repair-from /mnt/archive/ s3/archive
+`,
+}
+
+// Result of scanning an object.
+const (
+	scanStatusVerified   = "verified"
+	scanStatusUnverified = "unverified"
+	scanStatusRepaired   = "repaired"
+	scanStatusFailed     = "failed"
+)
+
+// Session header keys for saved scan counters.
+const (
+	scanVerifiedKey   = "verified"
+	scanUnverifiedKey = "unverified"
+	scanRepairedKey   = "repaired"
+	scanFailedKey     = "failed"
+)
+
+// scanMessage container for an object which did not pass the scan.
+type scanMessage struct {
+	Status string `json:"status"`
+	Result string `json:"result"`
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+	ETag   string `json:"etag"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// String colorized scan message.
+func (s scanMessage) String() string {
+	msg := console.Colorize("Scan"+strings.Title(s.Result), fmt.Sprintf("%-10s ", s.Result))
+	msg += "`" + s.Key + "`"
+	if s.Reason != "" {
+		msg += ", " + s.Reason
+	}
+	return msg + "."
+}
+
+// JSON jsonified scan message.
+func (s scanMessage) JSON() string {
+	s.Status = "success"
+	scanMessageBytes, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(scanMessageBytes)
+}
+
+// scanSummaryMessage container for scan summary.
+type scanSummaryMessage struct {
+	Status     string `json:"status"`
+	Verified   int    `json:"verified"`
+	Unverified int    `json:"unverified"`
+	Repaired   int    `json:"repaired"`
+	Failed     int    `json:"failed"`
+}
+
+// String colorized scan summary.
+func (s scanSummaryMessage) String() string {
+	return console.Colorize("Scan", fmt.Sprintf("Verified: %d, Unverified: %d, Repaired: %d, Failed: %d",
+		s.Verified, s.Unverified, s.Repaired, s.Failed))
+}
+
+// JSON jsonified scan summary.
+func (s scanSummaryMessage) JSON() string {
+	s.Status = "success"
+	scanMessageBytes, e := json.MarshalIndent(s, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+
+	return string(scanMessageBytes)
+}
+
+// md5ETagRgx - ETag of an object uploaded in a single part.
+var md5ETagRgx = regexp.MustCompile("^[0-9a-f]{32}$")
+
+// objectScanner verifies the objects below a target, repairing them from
+// a local folder if one is set.
+type objectScanner struct {
+	targetAlias string
+	targetURL   string
+	repairFrom  string
+	quick       bool
+	encKeyDB    map[string][]prefixSSEPair
+}
+
+// scan reads an object and compares it with its stored checksum or
+// its ETag, or with its local copy when the ETag is not an MD5.
+func (s objectScanner) scan(content *clientContent) scanMessage {
+	msg := scanMessage{
+		Key:  filepath.ToSlash(filepath.Join(s.targetAlias, content.URL.Path)),
+		Size: content.Size,
+		ETag: strings.Trim(content.ETag, "\""),
+	}
+	sse := getSSE(msg.Key, s.encKeyDB[s.targetAlias])
+
+	if s.quick {
+		algorithm, storedSum, reason := s.storedChecksum(content, sse)
+		if reason != "" {
+			msg.Result, msg.Reason = scanStatusFailed, reason
+			return msg
+		}
+		if storedSum != "" {
+			return s.quickScan(msg, content, algorithm, storedSum)
+		}
+	}
+
+	remoteSum, algorithm, storedSum, sum, reason := s.readObject(content, sse)
+	if reason == "" {
+		switch {
+		case storedSum != "":
+			if sum == storedSum {
+				msg.Result = scanStatusVerified
+				return msg
+			}
+			reason = "content does not match its stored " + algorithm + " checksum"
+		case md5ETagRgx.MatchString(msg.ETag) && sse == nil:
+			if remoteSum == msg.ETag {
+				msg.Result = scanStatusVerified
+				return msg
+			}
+			reason = "content does not match its ETag"
+		case s.repairFrom != "":
+			localSum, err := checksumFile(s.localPath(content), "md5")
+			if err != nil {
+				msg.Result, msg.Reason = scanStatusUnverified, "no local copy to compare with"
+				return msg
+			}
+			if remoteSum == localSum {
+				msg.Result = scanStatusVerified
+				return msg
+			}
+			reason = "content does not match its local copy"
+		default:
+			msg.Result, msg.Reason = scanStatusUnverified, "ETag is not an MD5"
+			return msg
+		}
+	}
+
+	if s.repairFrom == "" {
+		msg.Result, msg.Reason = scanStatusFailed, reason
+		return msg
+	}
+	if err := s.repair(content, msg.ETag, algorithm, storedSum, sse); err != nil {
+		msg.Result, msg.Reason = scanStatusFailed, reason+", "+err.ToGoError().Error()
+		return msg
+	}
+	msg.Result, msg.Reason = scanStatusRepaired, reason
+	return msg
+}
+
+// quickScan compares the checksum stored with an object with the one of
+// its local copy, without reading the object.
+func (s objectScanner) quickScan(msg scanMessage, content *clientContent, algorithm, storedSum string) scanMessage {
+	localSum, err := checksumFile(s.localPath(content), algorithm)
+	if err != nil {
+		msg.Result, msg.Reason = scanStatusUnverified, "no local copy to compare with"
+		return msg
+	}
+	if localSum == storedSum {
+		msg.Result = scanStatusVerified
+		return msg
+	}
+	msg.Result, msg.Reason = scanStatusFailed, "stored "+algorithm+" checksum does not match the local copy"
+	return msg
+}
+
+// storedChecksum returns the checksum stored with an object by
+// 'cp --checksum', or why the object could not be stat'ed.
+func (s objectScanner) storedChecksum(content *clientContent, sse encrypt.ServerSide) (algorithm, sum, reason string) {
+	clnt, err := newClientFromAlias(s.targetAlias, content.URL.String())
+	if err != nil {
+		return "", "", err.ToGoError().Error()
+	}
+	st, err := clnt.Stat(false, true, false, sse)
+	if err != nil {
+		return "", "", err.ToGoError().Error()
+	}
+	algorithm, sum = storedChecksum(st.Metadata)
+	return algorithm, sum, ""
+}
+
+// readObject streams an object and returns its MD5, along with the
+// checksum stored with it by 'cp --checksum' and the one of its content
+// made with the same algorithm, or why it could not be read entirely.
+// The stored checksum is taken from the response to the download when
+// the client returns it, objects are not stat'ed one by one.
+func (s objectScanner) readObject(content *clientContent, sse encrypt.ServerSide) (md5sum, algorithm, storedSum, sum, reason string) {
+	clnt, err := newClientFromAlias(s.targetAlias, content.URL.String())
+	if err != nil {
+		return "", "", "", "", err.ToGoError().Error()
+	}
+	reader, err := clnt.Get(sse)
+	if err != nil {
+		return "", "", "", "", err.ToGoError().Error()
+	}
+	defer reader.Close()
+	if object, ok := reader.(*objectReader); ok {
+		info, e := object.Stat()
+		if e != nil {
+			return "", "", "", "", e.Error()
+		}
+		metadata := make(map[string]string, len(info.Metadata))
+		for k := range info.Metadata {
+			metadata[k] = info.Metadata.Get(k)
+		}
+		algorithm, storedSum = storedChecksum(metadata)
+	} else if algorithm, storedSum, reason = s.storedChecksum(content, sse); reason != "" {
+		return "", "", "", "", reason
+	}
+	var r io.Reader = reader
+	hasher := newChecksumHash(algorithm)
+	if hasher != nil {
+		r = io.TeeReader(reader, hasher)
+	}
+	md5sum, n, e := checksumReader(r, "md5")
+	if e != nil {
+		return "", "", "", "", e.Error()
+	}
+	if n != content.Size {
+		return "", "", "", "", fmt.Sprintf("read %d bytes of %d", n, content.Size)
+	}
+	if hasher != nil {
+		sum = hex.EncodeToString(hasher.Sum(nil))
+	}
+	return md5sum, algorithm, storedSum, sum, ""
+}
+
+// localPath returns the path of the local copy of an object.
+func (s objectScanner) localPath(content *clientContent) string {
+	key := strings.TrimPrefix(content.URL.String(), s.targetURL)
+	return filepath.Join(s.repairFrom, filepath.FromSlash(key))
+}
+
+// repair uploads the local copy of an object again. A local copy which
+// does not match the stored checksum or a single part ETag is not
+// uploaded.
+func (s objectScanner) repair(content *clientContent, etag, algorithm, storedSum string, sse encrypt.ServerSide) *probe.Error {
+	localPath := s.localPath(content)
+	if storedSum != "" {
+		localSum, err := checksumFile(localPath, algorithm)
+		if err != nil {
+			return err.Trace(localPath)
+		}
+		if localSum != storedSum {
+			return probe.NewError(fmt.Errorf("local copy `%s` does not match the stored %s checksum either", localPath, algorithm))
+		}
+	} else if md5ETagRgx.MatchString(etag) && sse == nil {
+		localSum, err := checksumFile(localPath, "md5")
+		if err != nil {
+			return err.Trace(localPath)
+		}
+		if localSum != etag {
+			return probe.NewError(fmt.Errorf("local copy `%s` does not match the ETag either", localPath))
+		}
+	}
+	_, localContent, err := url2Stat(localPath, false, false, nil)
+	if err != nil {
+		return err.Trace(localPath)
+	}
+
+	// Keep the headers and user metadata of the object.
+	clnt, err := newClientFromAlias(s.targetAlias, content.URL.String())
+	if err != nil {
+		return err.Trace(content.URL.String())
+	}
+	st, err := clnt.Stat(false, true, false, sse)
+	if err != nil {
+		return err.Trace(content.URL.String())
+	}
+	metadata := map[string]string{}
+	for k, v := range st.Metadata {
+		switch k {
+		case "Content-Type", "Content-Encoding", "Content-Disposition", "Content-Language", "Cache-Control", "X-Amz-Storage-Class":
+			metadata[k] = v
+		default:
+			if strings.HasPrefix(k, "X-Amz-Meta-") {
+				metadata[k] = v
+			}
+		}
+	}
+
+	urls := uploadSourceToTargetURL(globalContext, URLs{
+		SourceContent: localContent,
+		TargetAlias:   s.targetAlias,
+		TargetContent: &clientContent{URL: content.URL, Metadata: metadata, UserMetadata: map[string]string{}},
+		encKeyDB:      s.encKeyDB,
+	}, nil, s.encKeyDB)
+	return urls.Error
+}
+
+// checkScanSyntax - validate all the passed arguments
+func checkScanSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "scan", 1) // last argument is exit code
+	}
+	if ctx.Bool("quick") && ctx.String("repair-from") == "" {
+		fatalIf(errInvalidArgument(), "--quick needs a local copy to compare with, given with --repair-from.")
+	}
+	if repairFrom := ctx.String("repair-from"); repairFrom != "" {
+		st, e := os.Stat(repairFrom)
+		fatalIf(probe.NewError(e).Trace(repairFrom), "Unable to access `"+repairFrom+"`.")
+		if !st.IsDir() {
+			fatalIf(errInvalidArgument().Trace(repairFrom), "`"+repairFrom+"` is not a folder.")
+		}
+	}
+}
+
+func doScanSession(ctx *cli.Context, session *sessionV8, encKeyDB map[string][]prefixSSEPair) error {
+	args := ctx.Args()
+	repairFrom := ctx.String("repair-from")
+	isQuick := ctx.Bool("quick")
+	checkpointInterval := ctx.String("checkpoint-interval")
+	var summary scanSummaryMessage
+	var lastScanned string
+	if session != nil {
+		args = session.Header.CommandArgs
+		repairFrom = session.Header.CommandStringFlags["repair-from"]
+		isQuick = session.Header.CommandBoolFlags["quick"]
+		checkpointInterval = session.Header.CommandStringFlags["checkpoint-interval"]
+		lastScanned = session.Header.LastCopied
+		summary.Verified = session.Header.CommandIntFlags[scanVerifiedKey]
+		summary.Unverified = session.Header.CommandIntFlags[scanUnverifiedKey]
+		summary.Repaired = session.Header.CommandIntFlags[scanRepairedKey]
+		summary.Failed = session.Header.CommandIntFlags[scanFailedKey]
+	}
+	checkpoint, err := newSessionCheckpoint(checkpointInterval)
+	fatalIf(err, "Unable to parse checkpoint interval.")
+
+	targetURL := args[0]
+	separator := string(newClientURL(targetURL).Separator)
+	if !strings.HasSuffix(targetURL, separator) {
+		targetURL = targetURL + separator
+	}
+	targetAlias, expandedURL, _ := mustExpandAlias(targetURL)
+	clnt, err := newClientFromAlias(targetAlias, expandedURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+	if _, ok := clnt.(*s3Client); !ok {
+		fatalIf(errDummy().Trace(targetURL), "The provided url doesn't point to a S3 server.")
+	}
+
+	scanner := objectScanner{
+		targetAlias: targetAlias,
+		targetURL:   expandedURL,
+		repairFrom:  repairFrom,
+		quick:       isQuick,
+		encKeyDB:    encKeyDB,
+	}
+
+	// saveSummary records the counters so far in the session header.
+	saveSummary := func() {
+		session.Header.CommandIntFlags[scanVerifiedKey] = summary.Verified
+		session.Header.CommandIntFlags[scanUnverifiedKey] = summary.Unverified
+		session.Header.CommandIntFlags[scanRepairedKey] = summary.Repaired
+		session.Header.CommandIntFlags[scanFailedKey] = summary.Failed
+	}
+
+	var scanBar scanBarFunc
+	if !globalQuiet && !globalJSON {
+		scanBar = scanBarFactory()
+	}
+
+	var retErr error
+	for content := range clnt.List(true, false, false, DirNone) {
+		select {
+		case <-globalContext.Done():
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			if session != nil {
+				saveSummary()
+				return session.CloseAndDie()
+			}
+			return exitStatus(globalErrorExitStatus)
+		default:
+		}
+		if content.Err != nil {
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			errorIf(content.Err.Trace(targetURL), "Unable to list `%s`.", targetURL)
+			retErr = exitStatus(globalErrorExitStatus)
+			continue
+		}
+		// Objects are listed in lexical order, those up to the last one
+		// of an interrupted session were already scanned.
+		if lastScanned != "" && content.URL.String() <= lastScanned {
+			continue
+		}
+		if scanBar != nil {
+			scanBar(content.URL.String())
+		}
+
+		msg := scanner.scan(content)
+		switch msg.Result {
+		case scanStatusVerified:
+			summary.Verified++
+		case scanStatusUnverified:
+			summary.Unverified++
+		case scanStatusRepaired:
+			summary.Repaired++
+		case scanStatusFailed:
+			summary.Failed++
+			retErr = exitStatus(globalErrorExitStatus)
+		}
+		if msg.Result != scanStatusVerified {
+			if !globalQuiet && !globalJSON {
+				console.Eraseline()
+			}
+			printMsg(msg)
+		}
+
+		if session != nil {
+			session.Header.LastCopied = content.URL.String()
+			saveSummary()
+			if checkpoint.due() {
+				session.Save()
+			}
+		}
+	}
+
+	if !globalQuiet && !globalJSON {
+		console.Eraseline()
+	}
+	printMsg(summary)
+	return retErr
+}
+
+// mainScan is the entry point for scan command.
+func mainScan(ctx *cli.Context) error {
+	// Parse encryption keys per command.
+	encKeyDB, err := getEncKeys(ctx)
+	fatalIf(err, "Unable to parse encryption keys.")
+
+	checkScanSyntax(ctx)
+
+	// Additional command specific theme customization.
+	console.SetColor("Scan", color.New(color.FgCyan, color.Bold))
+	console.SetColor("ScanUnverified", color.New(color.FgYellow))
+	console.SetColor("ScanRepaired", color.New(color.FgGreen, color.Bold))
+	console.SetColor("ScanFailed", color.New(color.FgRed, color.Bold))
+
+	var session *sessionV8
+
+	if ctx.Bool("continue") {
+		sessionID := getHash("scan", ctx.Args())
+		if isSessionExists(sessionID) {
+			session, err = resumeSessionV8(sessionID)
+			fatalIf(err.Trace(sessionID), "Unable to load session.")
+		} else {
+			expireSessions()
+			session = newSessionV8(sessionID)
+			session.Header.CommandType = "scan"
+			session.Header.CommandStringFlags["repair-from"] = ctx.String("repair-from")
+			session.Header.CommandBoolFlags["quick"] = ctx.Bool("quick")
+			session.Header.CommandStringFlags["checkpoint-interval"] = ctx.String("checkpoint-interval")
+
+			var e error
+			if session.Header.RootPath, e = os.Getwd(); e != nil {
+				session.Delete()
+				fatalIf(probe.NewError(e), "Unable to get current working folder.")
+			}
+
+			// extract URLs.
+			session.Header.CommandArgs = ctx.Args()
+		}
+	}
+
+	e := doScanSession(ctx, session, encKeyDB)
+	if session != nil && e != errSessionTerminated {
+		session.Delete()
+	}
+
+	return e
+}
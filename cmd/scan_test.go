@@ -0,0 +1,153 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestObjectScanner(t *testing.T) {
+	const helloETag = "5d41402abc4b2a76b9719d911017c592"
+	const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	objects := map[string]string{
+		"good":        "hello",
+		"bad":         "hellx",
+		"bad2":        "hellx",
+		"multi":       "hello",
+		"stamped":     "hello",
+		"stamped-bad": "hellx",
+	}
+	var mutex sync.Mutex
+	uploads := map[string]string{}
+	var heads int32
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bucket" || r.URL.Path == "/bucket/" {
+			if r.Method == http.MethodGet {
+				prefix := r.URL.Query().Get("prefix")
+				w.Write([]byte("<ListBucketResult xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"><Name>bucket</Name><IsTruncated>false</IsTruncated>" +
+					"<Contents><Key>" + prefix + "</Key><LastModified>2006-01-02T15:04:05.000Z</LastModified><Size>5</Size></Contents></ListBucketResult>"))
+			}
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch r.Method {
+		case http.MethodHead, http.MethodGet:
+			content, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+			w.Header().Set("ETag", "\""+helloETag+"\"")
+			w.Header().Set("X-Amz-Meta-Owner", "archive")
+			if strings.HasPrefix(key, "stamped") {
+				w.Header().Set("X-Amz-Meta-Mc-Sha256", helloSHA256)
+			}
+			if r.Method == http.MethodHead {
+				atomic.AddInt32(&heads, 1)
+				w.Header().Set("Content-Length", "5")
+				return
+			}
+			w.Write([]byte(content))
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			mutex.Lock()
+			uploads[key] = string(body) + ";" + r.Header.Get("X-Amz-Meta-Owner")
+			mutex.Unlock()
+			w.Header().Set("ETag", "\""+helloETag+"\"")
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("scantest", server.URL)()
+
+	repairDir, e := ioutil.TempDir("", "scan-test-")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(repairDir)
+	for name, content := range map[string]string{"bad": "hello", "bad2": "hellz", "multi": "hello", "stamped-bad": "hello"} {
+		if e = ioutil.WriteFile(filepath.Join(repairDir, name), []byte(content), 0644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	testCases := []struct {
+		key        string
+		etag       string
+		repairFrom string
+		quick      bool
+		result     string
+		upload     string
+	}{
+		{"good", helloETag, "", false, scanStatusVerified, ""},
+		{"bad", helloETag, "", false, scanStatusFailed, ""},
+		{"multi", "0123456789abcdef0123456789abcdef-2", "", false, scanStatusUnverified, ""},
+		{"bad", helloETag, repairDir, false, scanStatusRepaired, "hello;archive"},
+		// Compared with the local copy, which is identical.
+		{"multi", "0123456789abcdef0123456789abcdef-2", repairDir, false, scanStatusVerified, ""},
+		// The local copy is damaged as well, it is not uploaded.
+		{"bad2", helloETag, repairDir, false, scanStatusFailed, ""},
+		{"good", helloETag, repairDir, false, scanStatusVerified, ""},
+		// Multipart objects with a stored checksum are verified with it.
+		{"stamped", "0123456789abcdef0123456789abcdef-2", "", false, scanStatusVerified, ""},
+		{"stamped-bad", "0123456789abcdef0123456789abcdef-2", "", false, scanStatusFailed, ""},
+		{"stamped-bad", "0123456789abcdef0123456789abcdef-2", repairDir, false, scanStatusRepaired, "hello;archive"},
+		// A quick scan trusts the stored checksum and does not read the object.
+		{"stamped-bad", "0123456789abcdef0123456789abcdef-2", repairDir, true, scanStatusVerified, ""},
+		// Objects without a stored checksum are read.
+		{"bad", helloETag, repairDir, true, scanStatusRepaired, "hello;archive"},
+	}
+	for i, testCase := range testCases {
+		uploads = map[string]string{}
+		atomic.StoreInt32(&heads, 0)
+		scanner := objectScanner{
+			targetAlias: "scantest",
+			targetURL:   server.URL + "/bucket/",
+			repairFrom:  testCase.repairFrom,
+			quick:       testCase.quick,
+		}
+		content := &clientContent{
+			URL:  *newClientURL(server.URL + "/bucket/" + testCase.key),
+			Size: 5,
+			ETag: "\"" + testCase.etag + "\"",
+		}
+		msg := scanner.scan(content)
+		if msg.Result != testCase.result {
+			t.Fatalf("Test %d: expected %s, got %s (%s)", i+1, testCase.result, msg.Result, msg.Reason)
+		}
+		if uploads[testCase.key] != testCase.upload {
+			t.Fatalf("Test %d: expected upload %q, got %q", i+1, testCase.upload, uploads[testCase.key])
+		}
+		// The stored checksum comes with the download, which stats the
+		// object once, scans do not stat it again.
+		if n := atomic.LoadInt32(&heads); !testCase.quick && testCase.upload == "" && n > 1 {
+			t.Fatalf("Test %d: expected a single HEAD request, got %d", i+1, n)
+		}
+	}
+}
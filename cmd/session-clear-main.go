@@ -0,0 +1,110 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	json "github.com/minio/mc/pkg/colorjson"
+	"github.com/minio/mc/pkg/ioutils"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+var (
+	sessionClearFlags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "older-than",
+			Usage: "clear sessions last saved longer than specified time, e.g. 7d10h31s",
+			Value: "0",
+		},
+	}
+)
+
+var sessionClearCmd = cli.Command{
+	Name:   "clear",
+	Usage:  "clear saved sessions",
+	Action: mainSessionClear,
+	Before: setGlobalsFromContext,
+	Flags:  append(sessionClearFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS]
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+ENVIRONMENT VARIABLES:
+  MC_SESSION_EXPIRY:  sessions older than this are cleared when a new session starts, "0" disables it (default "30d")
+
+EXAMPLES:
+  1. Clear all saved sessions, except those used in the last 3 minutes which may be running.
+     {{.Prompt}} {{.HelpName}}
+
+  2. Clear sessions not saved for more than a week.
+     {{.Prompt}} {{.HelpName}} --older-than 7d
+`,
+}
+
+// sessionClearMessage container for a cleared session.
+type sessionClearMessage struct {
+	Status      string    `json:"status"`
+	SessionID   string    `json:"sessionId"`
+	Time        time.Time `json:"time"`
+	CommandType string    `json:"commandType"`
+}
+
+func (m sessionClearMessage) String() string {
+	msg := console.Colorize("SessionTime", fmt.Sprintf("[%s] ", m.Time.Local().Format(printDate)))
+	msg += console.Colorize("SessionID", m.SessionID)
+	return "Cleared session " + msg
+}
+
+func (m sessionClearMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// checkSessionClearSyntax - validate all the passed arguments
+func checkSessionClearSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		cli.ShowCommandHelpAndExit(ctx, "clear", 1) // last argument is exit code
+	}
+	if _, e := ioutils.ParseDurationTime(ctx.String("older-than")); e != nil {
+		fatalIf(probe.NewError(e).Trace(ctx.String("older-than")), "Unable to parse --older-than.")
+	}
+}
+
+func mainSessionClear(ctx *cli.Context) error {
+	console.SetColor("SessionTime", color.New(color.FgGreen))
+	console.SetColor("SessionID", color.New(color.FgYellow, color.Bold))
+
+	checkSessionClearSyntax(ctx)
+
+	olderThan, _ := ioutils.ParseDurationTime(ctx.String("older-than"))
+	for _, msg := range clearSessions(olderThan) {
+		printMsg(msg)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+)
+
+var sessionExportCmd = cli.Command{
+	Name:   "export",
+	Usage:  "write a saved session to STDOUT, to be resumed on another machine",
+	Action: mainSessionExport,
+	Before: setGlobalsFromContext,
+	Flags:  globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} SESSION-ID
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Move a session to a server and resume it there with the same command.
+     {{.Prompt}} {{.HelpName}} cp-3fd8a91e > session.tar
+     {{.Prompt}} scp session.tar server:
+     {{.Prompt}} ssh server mc session import < session.tar
+`,
+}
+
+// exportSession - writes the header and the data file of a session as
+// a tar archive, sessions are imported under the same ID so that the
+// command which started them finds them.
+func exportSession(sid string, w io.Writer) *probe.Error {
+	// Refuse sessions this mc can't resume either.
+	if _, err := loadSessionV8Header(sid); err != nil {
+		return err.Trace(sid)
+	}
+	sessionFile, err := getSessionFile(sid)
+	if err != nil {
+		return err.Trace(sid)
+	}
+	sessionDataFile, err := getSessionDataFile(sid)
+	if err != nil {
+		return err.Trace(sid)
+	}
+
+	tw := tar.NewWriter(w)
+	for _, name := range []string{sessionFile, sessionDataFile} {
+		if err = addTarFile(tw, name); err != nil {
+			// Sessions which lost their data file restart on resume.
+			if os.IsNotExist(err.ToGoError()) && name == sessionDataFile {
+				continue
+			}
+			return err.Trace(sid)
+		}
+	}
+	if e := tw.Close(); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// addTarFile - adds a file of the session folder to a tar archive.
+func addTarFile(tw *tar.Writer, name string) *probe.Error {
+	f, e := os.Open(name)
+	if e != nil {
+		return probe.NewError(e)
+	}
+	defer f.Close()
+
+	st, e := f.Stat()
+	if e != nil {
+		return probe.NewError(e)
+	}
+	hdr, e := tar.FileInfoHeader(st, "")
+	if e != nil {
+		return probe.NewError(e)
+	}
+	if e = tw.WriteHeader(hdr); e != nil {
+		return probe.NewError(e)
+	}
+	// Copy the size in the header, the data file may still grow.
+	if _, e = io.CopyN(tw, f, st.Size()); e != nil {
+		return probe.NewError(e)
+	}
+	return nil
+}
+
+// checkSessionExportSyntax - validate all the passed arguments
+func checkSessionExportSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "export", 1) // last argument is exit code
+	}
+}
+
+func mainSessionExport(ctx *cli.Context) error {
+	checkSessionExportSyntax(ctx)
+
+	sid := ctx.Args().First()
+	if !isSessionExists(sid) {
+		fatalIf(errDummy().Trace(sid), "Session `"+sid+"` not found.")
+	}
+	fatalIf(exportSession(sid, os.Stdout).Trace(sid), "Unable to export session `"+sid+"`.")
+	return nil
+}
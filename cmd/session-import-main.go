@@ -0,0 +1,192 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	json "github.com/minio/mc/pkg/colorjson"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+var sessionImportCmd = cli.Command{
+	Name:   "import",
+	Usage:  "add a session exported with 'mc session export' from STDIN",
+	Action: mainSessionImport,
+	Before: setGlobalsFromContext,
+	Flags:  globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}}
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Add a session exported on another machine, then resume it with the command which started it.
+     {{.Prompt}} {{.HelpName}} < session.tar
+     {{.Prompt}} mc cp --recursive --continue backups/ s3/mybucket/backups/
+`,
+}
+
+// sessionImportMessage container for an imported session.
+type sessionImportMessage struct {
+	Status      string   `json:"status"`
+	SessionID   string   `json:"sessionId"`
+	CommandType string   `json:"commandType"`
+	CommandArgs []string `json:"commandArgs"`
+}
+
+func (m sessionImportMessage) String() string {
+	return console.Colorize("SessionImport", "Imported session `"+m.SessionID+"` of `"+
+		strings.TrimSpace(m.CommandType+" "+strings.Join(m.CommandArgs, " "))+"`.")
+}
+
+func (m sessionImportMessage) JSON() string {
+	m.Status = "success"
+	jsonMessageBytes, e := json.MarshalIndent(m, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(jsonMessageBytes)
+}
+
+// importSession - adds the session of an archive written by
+// exportSession() to the session folder, an existing session with the
+// same ID is never overwritten.
+func importSession(r io.Reader) (*sessionV8, *probe.Error) {
+	sessionDir, err := getSessionDir()
+	if err != nil {
+		return nil, err.Trace()
+	}
+
+	var sid, header string
+	var imported []string
+	cleanup := func() {
+		for _, name := range imported {
+			os.Remove(name)
+		}
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, e := tr.Next()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			cleanup()
+			return nil, probe.NewError(e)
+		}
+
+		// Only the two files of one session are accepted, never a path.
+		name := hdr.Name
+		ext := filepath.Ext(name)
+		id := strings.TrimSuffix(name, ext)
+		if (ext != ".json" && ext != ".data") || id == "" || filepath.Base(name) != name ||
+			hdr.Typeflag != tar.TypeReg || (sid != "" && id != sid) {
+			cleanup()
+			return nil, probe.NewError(errors.New("unexpected entry `" + name + "` in session archive"))
+		}
+		if sid == "" {
+			if err = checkNewSessionID(id); err != nil {
+				return nil, err.Trace(id)
+			}
+			sid = id
+		}
+
+		// Written next to the session files, renamed once all are in.
+		tmpFile := filepath.Join(sessionDir, name+".import")
+		f, e := os.OpenFile(tmpFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if e != nil {
+			cleanup()
+			return nil, probe.NewError(e)
+		}
+		imported = append(imported, tmpFile)
+		_, e = io.Copy(f, tr)
+		if e == nil {
+			e = f.Sync()
+		}
+		f.Close()
+		if e != nil {
+			cleanup()
+			return nil, probe.NewError(e)
+		}
+		if ext == ".json" {
+			header = tmpFile
+		}
+	}
+	if header == "" {
+		cleanup()
+		return nil, probe.NewError(errors.New("no session header in session archive"))
+	}
+
+	// The header goes last, a session exists once its header does.
+	for i, name := range imported {
+		if name == header {
+			imported[i], imported[len(imported)-1] = imported[len(imported)-1], imported[i]
+		}
+	}
+	for _, name := range imported {
+		if e := os.Rename(name, strings.TrimSuffix(name, ".import")); e != nil {
+			cleanup()
+			return nil, probe.NewError(e)
+		}
+	}
+
+	s, err := loadSessionV8Header(sid)
+	if err != nil {
+		// Not resumable here, drop it again.
+		os.Remove(strings.TrimSuffix(header, ".import"))
+		if dataFile, derr := getSessionDataFile(sid); derr == nil {
+			os.Remove(dataFile)
+		}
+		return nil, err.Trace(sid)
+	}
+	return s, nil
+}
+
+// checkSessionImportSyntax - validate all the passed arguments
+func checkSessionImportSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		cli.ShowCommandHelpAndExit(ctx, "import", 1) // last argument is exit code
+	}
+}
+
+func mainSessionImport(ctx *cli.Context) error {
+	console.SetColor("SessionImport", color.New(color.FgGreen, color.Bold))
+
+	checkSessionImportSyntax(ctx)
+
+	s, err := importSession(os.Stdin)
+	fatalIf(err.Trace(), "Unable to import session.")
+
+	printMsg(sessionImportMessage{
+		SessionID:   s.SessionID,
+		CommandType: s.Header.CommandType,
+		CommandArgs: s.Header.CommandArgs,
+	})
+	return nil
+}
@@ -0,0 +1,82 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/minio/minio/pkg/console"
+)
+
+var sessionListCmd = cli.Command{
+	Name:   "list",
+	Usage:  "list saved sessions and their progress",
+	Action: mainSessionList,
+	Before: setGlobalsFromContext,
+	Flags:  globalFlags,
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS]
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. List all saved sessions with how far they got.
+     {{.Prompt}} {{.HelpName}}
+
+  2. List saved sessions as JSON.
+     {{.Prompt}} {{.HelpName}} --json
+`,
+}
+
+// checkSessionListSyntax - validate all the passed arguments
+func checkSessionListSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 0 {
+		cli.ShowCommandHelpAndExit(ctx, "list", 1) // last argument is exit code
+	}
+}
+
+func mainSessionList(ctx *cli.Context) error {
+	console.SetColor("SessionID", color.New(color.FgYellow, color.Bold))
+	console.SetColor("SessionTime", color.New(color.FgGreen))
+	console.SetColor("Command", color.New(color.FgWhite, color.Bold))
+	console.SetColor("Progress", color.New(color.FgCyan))
+
+	checkSessionListSyntax(ctx)
+
+	var sessions []*sessionV8
+	for _, sid := range getSessionIDs() {
+		s, err := loadSessionV8Header(sid)
+		if err != nil {
+			errorIf(err.Trace(sid), "Unable to load session `"+sid+"`, skipping.")
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Header.When.Before(sessions[j].Header.When)
+	})
+	for _, s := range sessions {
+		printMsg(s)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import "github.com/minio/cli"
+
+var sessionCmd = cli.Command{
+	Name:            "session",
+	Usage:           "manage saved sessions of cp, mirror, reconcile and scan",
+	HideHelpCommand: true,
+	Action:          mainSession,
+	Before:          setGlobalsFromContext,
+	Flags:           globalFlags,
+	Subcommands: []cli.Command{
+		sessionListCmd,
+		sessionClearCmd,
+		sessionExportCmd,
+		sessionImportCmd,
+		sessionResumeCmd,
+	},
+}
+
+// mainSession is the handle for "mc session" command.
+func mainSession(ctx *cli.Context) error {
+	cli.ShowCommandHelp(ctx, ctx.Args().First())
+	return nil
+	// Sub-commands like "list", "clear" have their own main.
+}
@@ -17,6 +17,7 @@
 package cmd
 
 import (
+	"io"
 	"os"
 	"strconv"
 
@@ -25,6 +26,57 @@ import (
 	"github.com/minio/minio/pkg/quick"
 )
 
+// Migrates session data files without a format header, which hold the
+// same URL lines, to data format '1' by prepending the header.
+func migrateSessionDataV0ToV1() {
+	for _, sid := range getSessionIDs() {
+		sessionDataFile, err := getSessionDataFile(sid)
+		fatalIf(err.Trace(sid), "Unable to get session data file.")
+
+		dataFile, e := os.Open(sessionDataFile)
+		if e != nil {
+			// Sessions which lost their data file are restarted on resume.
+			continue
+		}
+		// Empty data files read the same in any format.
+		st, e := dataFile.Stat()
+		if e != nil || st.Size() == 0 {
+			dataFile.Close()
+			continue
+		}
+		format, _, e := readSessionDataFormat(dataFile)
+		if e != nil || format != sessionDataFormatV0 {
+			dataFile.Close()
+			continue
+		}
+
+		tmpFile := sessionDataFile + ".tmp"
+		migrated, e := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if e != nil {
+			dataFile.Close()
+			fatalIf(probe.NewError(e).Trace(sid, tmpFile), "Unable to migrate session data from '0' to '1'.")
+		}
+		_, e = io.WriteString(migrated, sessionDataHeader(sessionDataFormatV1))
+		if e == nil {
+			_, e = io.Copy(migrated, dataFile)
+		}
+		if e == nil {
+			e = migrated.Sync()
+		}
+		migrated.Close()
+		dataFile.Close()
+		if e == nil {
+			e = os.Rename(tmpFile, sessionDataFile)
+		}
+		if e != nil {
+			os.Remove(tmpFile)
+			fatalIf(probe.NewError(e).Trace(sid, sessionDataFile), "Unable to migrate session data from '0' to '1'.")
+		}
+
+		console.Println("Successfully migrated `" + sessionDataFile + "` from data format `0` to `1`.")
+	}
+}
+
 // Migrates session header version '7' to '8'. The only
 // change was the adding of insecure global flag
 func migrateSessionV7ToV8() {
@@ -34,7 +86,8 @@ func migrateSessionV7ToV8() {
 			if os.IsNotExist(err.ToGoError()) {
 				continue
 			}
-			fatalIf(err.Trace(sid), "Unable to load version `7`. Migration failed please report this issue at https://github.com/minio/mc/issues.")
+			// Already reported by migrateSessionV5ToV6().
+			continue
 		}
 
 		// Close underlying session data file.
@@ -89,7 +142,8 @@ func migrateSessionV6ToV7() {
 			if os.IsNotExist(err.ToGoError()) {
 				continue
 			}
-			fatalIf(err.Trace(sid), "Unable to load version `6`. Migration failed please report this issue at https://github.com/minio/mc/issues.")
+			// Already reported by migrateSessionV5ToV6().
+			continue
 		}
 
 		sessionVersion, e := strconv.Atoi(sV6Header.Version)
@@ -129,6 +183,27 @@ func migrateSessionV6ToV7() {
 	}
 }
 
+// moveSessionAside renames the files of session sid for them to be kept
+// but no longer listed, returning the new name of its header.
+func moveSessionAside(sid string) (string, *probe.Error) {
+	sessionFile, err := getSessionFile(sid)
+	if err != nil {
+		return "", err.Trace(sid)
+	}
+	sessionDataFile, err := getSessionDataFile(sid)
+	if err != nil {
+		return "", err.Trace(sid)
+	}
+	aside := sessionFile + ".corrupt"
+	if e := os.Rename(sessionFile, aside); e != nil {
+		return "", probe.NewError(e)
+	}
+	if e := os.Rename(sessionDataFile, sessionDataFile+".corrupt"); e != nil && !os.IsNotExist(e) {
+		return "", probe.NewError(e)
+	}
+	return aside, nil
+}
+
 // Migrate session version '5' to version '6', all older sessions are
 // in-fact removed and not migrated. All session files from '6' and
 // above should be migrated - See: migrateSessionV6ToV7().
@@ -139,7 +214,18 @@ func migrateSessionV5ToV6() {
 			if os.IsNotExist(err.ToGoError()) {
 				continue
 			}
-			fatalIf(err.Trace(sid), "Unable to load version `6`. Migration failed please report this issue at https://github.com/minio/mc/issues.")
+			// A corrupt header must not keep every command from
+			// starting, nor be reported by every one of them.
+			if _, ok := err.ToGoError().(*os.PathError); ok {
+				errorIf(err.Trace(sid), "Unable to load session `"+sid+"`, skipping migration.")
+				continue
+			}
+			if aside, moveErr := moveSessionAside(sid); moveErr != nil {
+				errorIf(moveErr.Trace(sid), "Unable to move corrupt session `"+sid+"` aside.")
+			} else {
+				errorIf(err.Trace(sid), "Unable to load session `"+sid+"`, moved it aside to `"+aside+"`.")
+			}
+			continue
 		}
 
 		sessionVersion, e := strconv.Atoi(sV6Header.Version)
@@ -142,7 +142,7 @@ func loadSessionV7(sid string) (*sessionV7, *probe.Error) {
 	if e != nil {
 		return nil, probe.NewError(e)
 	}
-	s.DataFP = &sessionDataFP{false, dataFile}
+	s.DataFP = &sessionDataFP{File: dataFile}
 
 	return s, nil
 }
@@ -0,0 +1,117 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
+	"github.com/minio/minio/pkg/console"
+)
+
+var (
+	sessionResumeFlags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "verify-done",
+			Usage: "check the targets of objects copied before the session was interrupted and copy again those missing, of another size or not matching their stored checksum",
+		},
+	}
+)
+
+var sessionResumeCmd = cli.Command{
+	Name:   "resume",
+	Usage:  "resume a saved session of cp, mv or mirror",
+	Action: mainSessionResume,
+	Before: setGlobalsFromContext,
+	Flags:  append(sessionResumeFlags, globalFlags...),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS] SESSION-ID
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}
+EXAMPLES:
+  1. Resume a session listed by 'mc session list', with the flags and in the folder it was started with.
+     {{.Prompt}} {{.HelpName}} cp-3fd8a91e
+
+  2. Resume an interrupted mirror, first mirroring again the objects it mirrored which the crash left incomplete.
+     {{.Prompt}} {{.HelpName}} --verify-done mirror-7b2f0e9c
+`,
+}
+
+// checkSessionResumeSyntax - validate all the passed arguments
+func checkSessionResumeSyntax(ctx *cli.Context) {
+	if len(ctx.Args()) != 1 {
+		cli.ShowCommandHelpAndExit(ctx, "resume", 1) // last argument is exit code
+	}
+}
+
+// resumeSession resumes the session sid, cp, mv and mirror sessions
+// are resumed here, the others by the command which started them.
+func resumeSession(ctx *cli.Context, sid string) error {
+	s, err := loadSessionV8Header(sid)
+	fatalIf(err.Trace(sid), "Unable to load session.")
+
+	switch s.Header.CommandType {
+	case "cp", "mv", "mirror":
+	default:
+		fatalIf(errInvalidArgument().Trace(sid), "Sessions of `mc "+s.Header.CommandType+"` are resumed by running it again with --continue.")
+	}
+
+	// Arguments are relative to the folder the session was started in.
+	if s.Header.RootPath != "" {
+		if e := os.Chdir(s.Header.RootPath); e != nil {
+			fatalIf(probe.NewError(e).Trace(s.Header.RootPath), "Unable to change to the folder of session.")
+		}
+	}
+
+	session, err := resumeSessionV8(sid)
+	if err != nil {
+		if _, ok := err.ToGoError().(SessionDataCorrupt); ok && s.Header.CommandType != "mirror" {
+			fatalIf(err.Trace(sid), "Unable to load session, resume it with `mc "+s.Header.CommandType+" --continue --repair`.")
+		}
+		fatalIf(err.Trace(sid), "Unable to load session.")
+	}
+
+	if session.Header.CommandType == "mirror" {
+		return doMirrorSession(session, ctx.Bool("verify-done"))
+	}
+	encKeyDB, err := parseAndValidateEncryptionKeys(session.Header.CommandStringFlags["encrypt-key"], session.Header.CommandStringFlags["encrypt"])
+	fatalIf(err, "Unable to parse encryption keys.")
+	return runCopySession(ctx, session, session.Header.CommandArgs, encKeyDB,
+		session.Header.CommandStringFlags["sse"], session.Header.CommandStringFlags["sse-kms-key-id"])
+}
+
+func mainSessionResume(ctx *cli.Context) error {
+	console.SetColor("Copy", color.New(color.FgGreen, color.Bold))
+	console.SetColor("Mirror", color.New(color.FgGreen, color.Bold))
+	console.SetColor("Excluded", color.New(color.FgYellow))
+	console.SetColor("VerifyDone", color.New(color.FgCyan))
+
+	checkSessionResumeSyntax(ctx)
+
+	sid := ctx.Args().First()
+	if !isSessionExists(sid) {
+		fatalIf(errDummy().Trace(sid), "Session `"+sid+"` not found.")
+	}
+	return resumeSession(ctx, sid)
+}
@@ -19,10 +19,14 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -51,6 +55,39 @@ type sessionV8Header struct {
 	TotalBytes         int64             `json:"totalBytes"`
 	TotalObjects       int64             `json:"totalObjects"`
 	UserMetaData       map[string]string `json:"metaData"`
+	// Multipart uploads in flight by target URL, missing from
+	// sessions saved before they were recorded.
+	Uploads map[string]sessionUpload `json:"uploads,omitempty"`
+	// Members of the archive uploaded by cp --extract, by name.
+	Extracted map[string]tarMember `json:"extracted,omitempty"`
+}
+
+// sessionUpload - the parts uploaded of a source, which are only
+// reused while the source is unchanged.
+type sessionUpload struct {
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"lastModified"`
+	Parts   uploadParts `json:"parts"`
+	// Alias of the target, missing from sessions saved before.
+	Alias string `json:"alias,omitempty"`
+}
+
+// abortSessionUpload - abort a multipart upload of a session which won't
+// be completed, so that its parts are not left behind on the target.
+func abortSessionUpload(targetURL string, upload sessionUpload) *probe.Error {
+	if upload.Alias == "" || upload.Parts.UploadID == "" {
+		return nil
+	}
+	clnt, err := newClientFromAlias(upload.Alias, targetURL)
+	if err != nil {
+		return err.Trace(upload.Alias, targetURL)
+	}
+	// Uncommitted Azure blocks are removed by the service itself.
+	s3c, ok := clnt.(*s3Client)
+	if !ok {
+		return nil
+	}
+	return s3c.abortUpload(upload.Parts.UploadID).Trace(targetURL)
 }
 
 // sessionMessage container for session messages
@@ -60,6 +97,12 @@ type sessionMessage struct {
 	Time        time.Time `json:"time"`
 	CommandType string    `json:"commandType"`
 	CommandArgs []string  `json:"commandArgs"`
+
+	BytesDone    int64   `json:"bytesDone"`
+	TotalBytes   int64   `json:"totalBytes"`
+	ObjectsDone  int64   `json:"objectsDone"`
+	TotalObjects int64   `json:"totalObjects"`
+	Progress     float64 `json:"progress"`
 }
 
 // sessionV8 resumable session container.
@@ -68,34 +111,232 @@ type sessionV8 struct {
 	SessionID string
 	mutex     *sync.Mutex
 	DataFP    *sessionDataFP
+	// Ends keepActive once the session is no longer used.
+	stopActive func()
 }
 
-// sessionDataFP data file pointer.
+// sessionDataFP data file pointer. The file offset belongs to the
+// writer, readers keep their own offset, see sessionDataReader, so
+// that the data file can be read while URLs are appended to it.
 type sessionDataFP struct {
+	mutex sync.Mutex
 	dirty bool
 	*os.File
 }
 
 func (file *sessionDataFP) Write(p []byte) (int, error) {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
 	file.dirty = true
 	return file.File.Write(p)
 }
 
+// sync commits written data to disk.
+func (file *sessionDataFP) sync() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if !file.dirty {
+		return nil
+	}
+	if e := file.File.Sync(); e != nil {
+		return e
+	}
+	file.dirty = false
+	return nil
+}
+
+// truncate empties the data file, writes start over after the format
+// header at its beginning.
+func (file *sessionDataFP) truncate() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	if _, e := file.File.Seek(0, io.SeekStart); e != nil {
+		return e
+	}
+	if e := file.File.Truncate(0); e != nil {
+		return e
+	}
+	file.dirty = true
+	_, e := io.WriteString(file.File, sessionDataHeader(sessionDataFormat))
+	return e
+}
+
+// seekEnd moves writes past the data written so far.
+func (file *sessionDataFP) seekEnd() error {
+	file.mutex.Lock()
+	defer file.mutex.Unlock()
+
+	_, e := file.File.Seek(0, io.SeekEnd)
+	return e
+}
+
+// Session data files begin with a line naming their format, files
+// without it are from before the format was recorded, see
+// migrateSessionDataV0ToV1(). Both hold one JSON URL per line.
+const (
+	sessionDataMagic = "#mc-session-data"
+
+	sessionDataFormatV0 = 0
+	sessionDataFormatV1 = 1
+
+	// Format of the data files written by this mc.
+	sessionDataFormat = sessionDataFormatV1
+)
+
+// sessionDataHeader - the first line of a data file of a format.
+func sessionDataHeader(format int) string {
+	return sessionDataMagic + " " + strconv.Itoa(format) + "\n"
+}
+
+// readSessionDataFormat returns the format of a data file and the
+// offset at which its URLs start.
+func readSessionDataFormat(file *os.File) (format int, offset int64, e error) {
+	buf := make([]byte, len(sessionDataMagic)+16)
+	n, e := file.ReadAt(buf, 0)
+	if e != nil && e != io.EOF {
+		return 0, 0, e
+	}
+	buf = buf[:n]
+	if !bytes.HasPrefix(buf, []byte(sessionDataMagic+" ")) {
+		return sessionDataFormatV0, 0, nil
+	}
+	end := bytes.IndexByte(buf, '\n')
+	if end < 0 {
+		return 0, 0, errors.New("unterminated data format header")
+	}
+	format, e = strconv.Atoi(string(buf[len(sessionDataMagic)+1 : end]))
+	if e != nil {
+		return 0, 0, errors.New("invalid data format header")
+	}
+	return format, int64(end + 1), nil
+}
+
+// newSessionDataReader - reader of the URLs of a data file, in any of
+// the formats known by this mc.
+func newSessionDataReader(file *os.File) io.Reader {
+	format, offset, e := readSessionDataFormat(file)
+	if e != nil {
+		return &errorReader{e}
+	}
+	switch format {
+	case sessionDataFormatV0, sessionDataFormatV1:
+		return &sessionDataReader{file: file, offset: offset}
+	default:
+		return &errorReader{fmt.Errorf("unsupported session data format %d", format)}
+	}
+}
+
+// errorReader fails every read with err.
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// sessionDataReader reads the data file from its own offset, it does
+// not move the file offset used by writes.
+type sessionDataReader struct {
+	file   *os.File
+	offset int64
+}
+
+func (r *sessionDataReader) Read(p []byte) (int, error) {
+	n, e := r.file.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if e == io.EOF && n > 0 {
+		// Return EOF with the next read.
+		e = nil
+	}
+	return n, e
+}
+
+// progress returns the bytes and objects a session is done with, the
+// data file is read in listing order up to the last copied URL.
+func (s sessionV8) progress() (bytesDone, objectsDone int64) {
+	if s.Header.LastCopied == "" {
+		return 0, 0
+	}
+	dataFile, err := getSessionDataFile(s.SessionID)
+	if err != nil {
+		return 0, 0
+	}
+	f, e := os.Open(dataFile)
+	if e != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	urlScanner := bufio.NewScanner(newSessionDataReader(f))
+	for urlScanner.Scan() {
+		// Copy and reconcile sessions both save URLs per line.
+		var urls URLs
+		if e = json.Unmarshal(urlScanner.Bytes(), &urls); e != nil {
+			continue
+		}
+		if urls.SourceContent != nil {
+			bytesDone += urls.SourceContent.Size
+		}
+		objectsDone++
+		if reconcileKey(urls) == s.Header.LastCopied {
+			return bytesDone, objectsDone
+		}
+	}
+	// Without the last copied URL nothing is known to be done.
+	return 0, 0
+}
+
+// progressPercent - done as a percentage of total, 0 until a total is
+// known.
+func progressPercent(done, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	if done >= total {
+		return 100
+	}
+	return float64(done) * 100 / float64(total)
+}
+
+// sessionProgressPercent - progress by bytes, or by objects for
+// sessions of empty objects only.
+func sessionProgressPercent(bytesDone, totalBytes, objectsDone, totalObjects int64) float64 {
+	if totalBytes > 0 {
+		return progressPercent(bytesDone, totalBytes)
+	}
+	return progressPercent(objectsDone, totalObjects)
+}
+
 // String colorized session message.
 func (s sessionV8) String() string {
+	bytesDone, objectsDone := s.progress()
+	percent := sessionProgressPercent(bytesDone, s.Header.TotalBytes, objectsDone, s.Header.TotalObjects)
+
 	message := console.Colorize("SessionID", fmt.Sprintf("%s -> ", s.SessionID))
 	message = message + console.Colorize("SessionTime", fmt.Sprintf("[%s]", s.Header.When.Local().Format(printDate)))
 	message = message + console.Colorize("Command", fmt.Sprintf(" %s %s", s.Header.CommandType, strings.Join(s.Header.CommandArgs, " ")))
+	message = message + console.Colorize("Progress", fmt.Sprintf(" %.1f%% (%s/%s, %d/%d objects)", percent,
+		humanizeSize(bytesDone), humanizeSize(s.Header.TotalBytes), objectsDone, s.Header.TotalObjects))
 	return message
 }
 
 // JSON jsonified session message.
 func (s sessionV8) JSON() string {
+	bytesDone, objectsDone := s.progress()
 	sessionMsg := sessionMessage{
-		SessionID:   s.SessionID,
-		Time:        s.Header.When.Local(),
-		CommandType: s.Header.CommandType,
-		CommandArgs: s.Header.CommandArgs,
+		SessionID:    s.SessionID,
+		Time:         s.Header.When.Local(),
+		CommandType:  s.Header.CommandType,
+		CommandArgs:  s.Header.CommandArgs,
+		BytesDone:    bytesDone,
+		TotalBytes:   s.Header.TotalBytes,
+		ObjectsDone:  objectsDone,
+		TotalObjects: s.Header.TotalObjects,
+		Progress:     sessionProgressPercent(bytesDone, s.Header.TotalBytes, objectsDone, s.Header.TotalObjects),
 	}
 	sessionMsg.Status = "success"
 	sessionBytes, e := json.MarshalIndent(sessionMsg, "", " ")
@@ -104,8 +345,228 @@ func (s sessionV8) JSON() string {
 	return string(sessionBytes)
 }
 
+// SessionDataMissing - session header exists but its data file is gone.
+type SessionDataMissing struct {
+	SessionID string
+}
+
+func (e SessionDataMissing) Error() string {
+	return "Data file of session `" + e.SessionID + "` is missing."
+}
+
+// SessionDataCorrupt - session data file does not match its header,
+// resuming would skip objects which were never copied.
+type SessionDataCorrupt struct {
+	SessionID string
+	Reason    string
+}
+
+func (e SessionDataCorrupt) Error() string {
+	return "Data file of session `" + e.SessionID + "` is corrupt, " + e.Reason + "."
+}
+
+// SessionDataTooNew - session data file was written by a newer mc in a
+// format this mc can't read.
+type SessionDataTooNew struct {
+	SessionID string
+	Format    int
+}
+
+func (e SessionDataTooNew) Error() string {
+	return "Data file of session `" + e.SessionID + "` was written by a newer mc with data format " +
+		strconv.Itoa(e.Format) + ", please upgrade mc to resume it."
+}
+
+// SessionTooNew - session was written by a newer mc, its header may
+// hold fields this mc would misinterpret.
+type SessionTooNew struct {
+	SessionID string
+	Version   string
+}
+
+func (e SessionTooNew) Error() string {
+	return "Session `" + e.SessionID + "` was created by a newer mc with session version " + e.Version +
+		", please upgrade mc to resume it."
+}
+
+// LastCopiedMissing - the last object copied by a session is no longer
+// listed, so the objects copied before it can't be told apart.
+type LastCopiedMissing struct {
+	URL string
+}
+
+func (e LastCopiedMissing) Error() string {
+	return "Last copied object `" + e.URL + "` is no longer in the source."
+}
+
+// isSessionVersionNewer returns true for the session versions above the
+// one supported by this mc.
+func isSessionVersionNewer(version string) bool {
+	current, _ := strconv.Atoi(globalSessionConfigVersion)
+	v, e := strconv.Atoi(version)
+	return e == nil && v > current
+}
+
 // loadSessionV8 - reads session file if exists and re-initiates internal variables
 func loadSessionV8(sid string) (*sessionV8, *probe.Error) {
+	s, err := loadSessionV8Header(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionDataFile, err := getSessionDataFile(s.SessionID)
+	if err != nil {
+		return nil, err.Trace(sid, s.Header.Version)
+	}
+
+	// Streamed sessions list their sources again on resume.
+	dataFile, e := os.OpenFile(sessionDataFile, os.O_RDWR, 0600)
+	if e != nil {
+		if os.IsNotExist(e) {
+			return nil, probe.NewError(SessionDataMissing{SessionID: sid})
+		}
+		return nil, probe.NewError(e)
+	}
+	s.DataFP = &sessionDataFP{File: dataFile}
+
+	format, _, e := readSessionDataFormat(dataFile)
+	if e != nil {
+		dataFile.Close()
+		return nil, probe.NewError(SessionDataCorrupt{SessionID: sid, Reason: e.Error()})
+	}
+	if format > sessionDataFormat {
+		dataFile.Close()
+		return nil, probe.NewError(SessionDataTooNew{SessionID: sid, Format: format})
+	}
+
+	return s, nil
+}
+
+// checkSessionData - a session which copied objects already must have
+// a data file listing them, one JSON document per line.
+func checkSessionData(s *sessionV8) *probe.Error {
+	line := 0
+	urlScanner := bufio.NewScanner(s.NewDataReader())
+	for urlScanner.Scan() {
+		line++
+		if !json.Valid(urlScanner.Bytes()) {
+			return probe.NewError(SessionDataCorrupt{
+				SessionID: s.SessionID,
+				Reason:    fmt.Sprintf("line %d is not valid JSON", line),
+			})
+		}
+	}
+	if e := urlScanner.Err(); e != nil {
+		return probe.NewError(SessionDataCorrupt{
+			SessionID: s.SessionID,
+			Reason:    fmt.Sprintf("line %d is unreadable: %s", line+1, e),
+		})
+	}
+	if line == 0 {
+		return probe.NewError(SessionDataCorrupt{SessionID: s.SessionID, Reason: "it is empty"})
+	}
+	return nil
+}
+
+// isDataListed tells if the session lists the sources it works on in
+// its data file. Scan sessions only keep the last object they scanned.
+func (s sessionV8) isDataListed() bool {
+	switch s.Header.CommandType {
+	case "cp":
+		// Extractions record the members they uploaded instead.
+		return !s.Header.CommandBoolFlags["extract"]
+	case "mv", "mirror", "reconcile":
+		return true
+	}
+	return false
+}
+
+// isRelisted tells if resuming the session lists its sources again
+// instead of reading them from its data file, as streamed sessions do
+// until they are listed once.
+func (s sessionV8) isRelisted() bool {
+	return s.Header.CommandBoolFlags["stream"] && s.Header.TotalObjects == 0
+}
+
+// repairSessionV8 - keeps the header of a session along with the last
+// copied object and starts over with an empty data file, so that the
+// source is listed again and the objects up to the last copied one are
+// skipped. Streamed sessions are listed again as they are copied.
+func repairSessionV8(sid string) (*sessionV8, *probe.Error) {
+	s, err := loadSessionV8Header(sid)
+	if err != nil {
+		return nil, err
+	}
+	s.Header.TotalBytes = 0
+	s.Header.TotalObjects = 0
+
+	sessionDataFile, err := getSessionDataFile(s.SessionID)
+	if err != nil {
+		return nil, err.Trace(sid, s.Header.Version)
+	}
+
+	dataFile, e := os.Create(sessionDataFile)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	s.DataFP = &sessionDataFP{File: dataFile}
+	s.keepActive()
+
+	return s, nil
+}
+
+// restartSessionV8 - keeps the header of a session which lost its data
+// file and starts over with an empty one, so that the source is listed
+// again from scratch with the arguments the session was started with.
+func restartSessionV8(sid string) (*sessionV8, *probe.Error) {
+	s, err := loadSessionV8Header(sid)
+	if err != nil {
+		return nil, err
+	}
+	s.Header.LastCopied = ""
+	s.Header.LastRemoved = ""
+	s.Header.TotalBytes = 0
+	s.Header.TotalObjects = 0
+
+	sessionDataFile, err := getSessionDataFile(s.SessionID)
+	if err != nil {
+		return nil, err.Trace(sid, s.Header.Version)
+	}
+
+	dataFile, e := os.Create(sessionDataFile)
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	s.DataFP = &sessionDataFP{File: dataFile}
+	s.keepActive()
+
+	return s, nil
+}
+
+// resumeSessionV8 - loads a session to resume, checking the data file
+// it reads the sources from, and restarting it when its data file is
+// missing instead of failing.
+func resumeSessionV8(sid string) (*sessionV8, *probe.Error) {
+	s, err := loadSessionV8(sid)
+	if err == nil {
+		if s.HasData() && s.isDataListed() && !s.isRelisted() {
+			if err = checkSessionData(s); err != nil {
+				s.DataFP.Close()
+				return nil, err.Trace(sid)
+			}
+		}
+		s.keepActive()
+		return s, nil
+	}
+	if _, ok := err.ToGoError().(SessionDataMissing); !ok {
+		return nil, err
+	}
+	errorIf(err.Trace(sid), "Restarting session `%s` from scratch.", sid)
+	return restartSessionV8(sid)
+}
+
+// loadSessionV8Header - reads the session file without its data file.
+func loadSessionV8Header(sid string) (*sessionV8, *probe.Error) {
 	if !isSessionDirExists() {
 		return nil, errInvalidArgument().Trace()
 	}
@@ -138,6 +599,9 @@ func loadSessionV8(sid string) (*sessionV8, *probe.Error) {
 
 	// Validate if the version matches with expected current version.
 	sV8Header := qs.Data().(*sessionV8Header)
+	if isSessionVersionNewer(sV8Header.Version) {
+		return nil, probe.NewError(SessionTooNew{SessionID: sid, Version: sV8Header.Version})
+	}
 	if sV8Header.Version != globalSessionConfigVersion {
 		msg := fmt.Sprintf("Session header version %s does not match mc session version %s.\n",
 			sV8Header.Version, globalSessionConfigVersion)
@@ -147,17 +611,6 @@ func loadSessionV8(sid string) (*sessionV8, *probe.Error) {
 	s.mutex = new(sync.Mutex)
 	s.Header = sV8Header
 
-	sessionDataFile, err := getSessionDataFile(s.SessionID)
-	if err != nil {
-		return nil, err.Trace(sid, s.Header.Version)
-	}
-
-	dataFile, e := os.Open(sessionDataFile)
-	if e != nil {
-		return nil, probe.NewError(e)
-	}
-	s.DataFP = &sessionDataFP{false, dataFile}
-
 	return s, nil
 }
 
@@ -179,13 +632,11 @@ func newSessionV8(sessionID string) *sessionV8 {
 	s.mutex = new(sync.Mutex)
 	s.SessionID = sessionID
 
-	sessionDataFile, err := getSessionDataFile(s.SessionID)
-	fatalIf(err.Trace(s.SessionID), "Unable to create session data file \""+sessionDataFile+"\".")
-
-	dataFile, e := os.Create(sessionDataFile)
-	fatalIf(probe.NewError(e), "Unable to create session data file \""+sessionDataFile+"\".")
+	dataFile, err := createSessionDataFile(s.SessionID)
+	fatalIf(err, "Unable to create session.")
 
-	s.DataFP = &sessionDataFP{false, dataFile}
+	s.DataFP = &sessionDataFP{File: dataFile}
+	s.keepActive()
 
 	// Capture state of global flags.
 	s.setGlobals()
@@ -198,33 +649,53 @@ func (s sessionV8) HasData() bool {
 	return s.Header.LastCopied != "" || s.Header.LastRemoved != ""
 }
 
-// NewDataReader provides reader interface to session data file.
+// NewDataReader provides reader interface to the URLs of the session
+// data file, from its beginning. Readers are independent of each other
+// and of writers.
 func (s *sessionV8) NewDataReader() io.Reader {
 	// DataFP is always intitialized, either via new or load functions.
-	s.DataFP.Seek(0, io.SeekStart)
-	return io.Reader(s.DataFP)
+	return newSessionDataReader(s.DataFP.File)
 }
 
-// NewDataReader provides writer interface to session data file.
+// NewDataWriter provides writer interface to session data file.
 func (s *sessionV8) NewDataWriter() io.Writer {
 	// DataFP is always intitialized, either via new or load functions.
-	s.DataFP.Seek(0, io.SeekStart)
-	// when moving to file position 0 we want to truncate the file as well,
+	// Writes start over at file position 0, truncate the file as well,
 	// otherwise we'll partly overwrite existing data
-	s.DataFP.Truncate(0)
+	s.DataFP.truncate()
 	return io.Writer(s.DataFP)
 }
 
+// NewDataAppender provides writer interface to session data file,
+// appending to the URLs it holds already.
+func (s *sessionV8) NewDataAppender() io.Writer {
+	// DataFP is always intitialized, either via new or load functions.
+	s.DataFP.seekEnd()
+	return io.Writer(s.DataFP)
+}
+
+// newDataSnapshotReader provides reader interface to the URLs of the
+// session data file like NewDataReader, up to those it holds when the
+// reader is created. URLs appended later on are not read.
+func (s *sessionV8) newDataSnapshotReader() io.Reader {
+	fi, e := s.DataFP.Stat()
+	if e != nil {
+		return &errorReader{e}
+	}
+	reader := s.NewDataReader()
+	if r, ok := reader.(*sessionDataReader); ok {
+		return io.LimitReader(r, fi.Size()-r.offset)
+	}
+	return reader
+}
+
 // Save this session.
 func (s *sessionV8) Save() *probe.Error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if s.DataFP.dirty {
-		if err := s.DataFP.Sync(); err != nil {
-			return probe.NewError(err)
-		}
-		s.DataFP.dirty = false
+	if err := s.DataFP.sync(); err != nil {
+		return probe.NewError(err)
 	}
 
 	qs, e := quick.NewConfig(s.Header, nil)
@@ -310,10 +781,36 @@ func (s *sessionV8) save() *probe.Error {
 	return nil
 }
 
+// keepActive touches the data file of a session used by this command
+// until it is closed, so that it is not cleared meanwhile, see
+// isSessionActive.
+func (s *sessionV8) keepActive() {
+	active := make(chan struct{})
+	var once sync.Once
+	// Shared by copies of the session, see CloseAndDie.
+	s.stopActive = func() { once.Do(func() { close(active) }) }
+	go func(dataFile string) {
+		ticker := time.NewTicker(sessionActiveWindow / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				now := time.Now()
+				os.Chtimes(dataFile, now, now)
+			case <-active:
+				return
+			}
+		}
+	}(s.DataFP.Name())
+}
+
 // Close ends this session and removes all associated session files.
 func (s *sessionV8) Close() *probe.Error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if s.stopActive != nil {
+		s.stopActive()
+	}
 
 	if err := s.DataFP.Close(); err != nil {
 		return probe.NewError(err)
@@ -323,10 +820,18 @@ func (s *sessionV8) Close() *probe.Error {
 	return s.save()
 }
 
-// Delete removes all the session files.
+// Delete removes all the session files, and aborts the multipart
+// uploads it left incomplete.
 func (s *sessionV8) Delete() *probe.Error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	if s.stopActive != nil {
+		s.stopActive()
+	}
+
+	for targetURL, upload := range s.Header.Uploads {
+		errorIf(abortSessionUpload(targetURL, upload), "Unable to abort the upload of `"+targetURL+"`.")
+	}
 
 	if s.DataFP != nil {
 		name := s.DataFP.Name()
@@ -339,6 +844,15 @@ func (s *sessionV8) Delete() *probe.Error {
 		if e := os.Remove(name); e != nil {
 			return probe.NewError(e)
 		}
+	} else {
+		// Sessions loaded without their data, remove it by name.
+		dataFile, err := getSessionDataFile(s.SessionID)
+		if err != nil {
+			return err.Trace(s.SessionID)
+		}
+		if e := os.Remove(dataFile); e != nil && !os.IsNotExist(e) {
+			return probe.NewError(e)
+		}
 	}
 
 	// Fetch the session file.
@@ -358,42 +872,112 @@ func (s *sessionV8) Delete() *probe.Error {
 	return nil
 }
 
-// Close a session and exit.
-func (s sessionV8) CloseAndDie() {
+// errSessionTerminated is returned once a session is closed for resume,
+// the command exits with it and must not delete the session files.
+var errSessionTerminated = exitStatus(globalErrorExitStatus)
+
+// CloseAndDie closes a session, leaving it to be resumed, and returns
+// errSessionTerminated for the command to exit with.
+func (s sessionV8) CloseAndDie() error {
 	s.Close()
-	console.Fatalln("Session safely terminated. Run the same command to resume copy again.")
+	console.Errorln("Session safely terminated. Run the same command to resume copy again.")
+	return errSessionTerminated
 }
 
-func (s sessionV8) copyCloseAndDie(sessionFlag bool) {
+// copyCloseAndDie closes a session after a critical copy error. Only a
+// session started with --continue is kept, otherwise the data file is
+// closed and nil is returned.
+func (s sessionV8) copyCloseAndDie(sessionFlag bool) error {
 	if sessionFlag {
 		s.Close()
-		console.Fatalln("Command terminated safely. Run this command to resume copy again.")
-	} else {
-		s.mutex.Lock()
-		defer s.mutex.Unlock()
+		console.Errorln("Command terminated safely. Run this command to resume copy again.")
+		return errSessionTerminated
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.DataFP.Close() // ignore error.
+	return nil
+}
 
-		s.DataFP.Close() // ignore error.
+// withUploadParts returns ctx with the parts of cpURLs uploaded so far
+// by this session, a multipart upload using it records each new part in
+// the session header, saved when checkpoint allows it.
+func (s *sessionV8) withUploadParts(ctx context.Context, cpURLs URLs, checkpoint *sessionCheckpoint) context.Context {
+	source := cpURLs.SourceContent
+	targetURL := cpURLs.TargetContent.URL.String()
+
+	s.mutex.Lock()
+	upload, ok := s.Header.Uploads[targetURL]
+	s.mutex.Unlock()
+
+	parts := &uploadParts{}
+	if ok && upload.Size == source.Size && upload.ModTime.Equal(source.Time) {
+		*parts = upload.Parts
+		parts.Parts = append([]uploadPart(nil), upload.Parts.Parts...)
+	} else if ok {
+		// The parts of a source changed since can't be reused.
+		errorIf(abortSessionUpload(targetURL, upload), "Unable to abort the previous upload of `"+targetURL+"`.")
+		s.mutex.Lock()
+		delete(s.Header.Uploads, targetURL)
+		s.mutex.Unlock()
 	}
+	parts.save = func(parts *uploadParts) {
+		s.mutex.Lock()
+		if parts == nil {
+			delete(s.Header.Uploads, targetURL)
+		} else {
+			if s.Header.Uploads == nil {
+				s.Header.Uploads = make(map[string]sessionUpload)
+			}
+			upload := sessionUpload{Size: source.Size, ModTime: source.Time, Parts: *parts, Alias: cpURLs.TargetAlias}
+			upload.Parts.Parts = append([]uploadPart(nil), parts.Parts...)
+			s.Header.Uploads[targetURL] = upload
+		}
+		s.mutex.Unlock()
+		// Completed uploads are saved with their object.
+		if parts != nil && checkpoint.partDue() {
+			errorIf(s.Save().Trace(s.SessionID), "Unable to save session.")
+		}
+	}
+	return context.WithValue(ctx, uploadPartsContextKey{}, parts)
 }
 
 // Create a factory function to simplify checking if
 // object was last operated on.
 func isLastFactory(lastURL string) func(string) bool {
-	last := true // closure
-	return func(sourceURL string) bool {
-		if sourceURL == "" {
-			fatalIf(errInvalidArgument().Trace(), "Empty source argument passed.")
-		}
-		if lastURL == "" {
-			return false
-		}
+	return newResumeMarker(lastURL).isDone
+}
 
-		if last {
-			if lastURL == sourceURL {
-				last = false // from next call onwards we say false.
-			}
-			return true
-		}
+// resumeMarker skips the URLs of a session up to and including the
+// last one operated on before it got interrupted.
+type resumeMarker struct {
+	lastURL string
+	found   bool
+}
+
+func newResumeMarker(lastURL string) *resumeMarker {
+	return &resumeMarker{lastURL: lastURL}
+}
+
+// isDone returns true for the URLs listed before the marker and for
+// the marker itself, URLs must be passed in listing order.
+func (m *resumeMarker) isDone(sourceURL string) bool {
+	if sourceURL == "" {
+		fatalIf(errInvalidArgument().Trace(), "Empty source argument passed.")
+	}
+	if m.lastURL == "" || m.found {
 		return false
 	}
+	if m.lastURL == sourceURL {
+		m.found = true // from next call onwards we say false.
+	}
+	return true
+}
+
+// missing returns true when the whole listing was passed to isDone
+// without the marker, e.g. because it was removed from the source
+// since the session was interrupted.
+func (m *resumeMarker) missing() bool {
+	return m.lastURL != "" && !m.found
 }
@@ -21,8 +21,12 @@ import (
 	"encoding/hex"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/minio/mc/pkg/ioutils"
 	"github.com/minio/mc/pkg/probe"
 )
 
@@ -36,6 +40,9 @@ func migrateSession() {
 
 	// Migrate V7 to V8
 	migrateSessionV7ToV8()
+
+	// Migrate data files to the format with a header.
+	migrateSessionDataV0ToV1()
 }
 
 // createSessionDir - create session directory.
@@ -96,6 +103,41 @@ func isSessionExists(sid string) bool {
 	return true // Session exists.
 }
 
+// checkNewSessionID - a new session with the id of an existing one
+// would overwrite its files, refuse it.
+func checkNewSessionID(sid string) *probe.Error {
+	if isSessionExists(sid) {
+		return errSessionExists(sid)
+	}
+	return nil
+}
+
+// createSessionDataFile creates the data file of a new session, failing
+// if one exists already, such as when the same command is started twice
+// at once. Data files of sessions which never saved their header and
+// are no longer active are replaced.
+func createSessionDataFile(sid string) (*os.File, *probe.Error) {
+	sessionDataFile, err := getSessionDataFile(sid)
+	if err != nil {
+		return nil, err.Trace(sid)
+	}
+	dataFile, e := os.OpenFile(sessionDataFile, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+	if os.IsExist(e) && !isSessionExists(sid) && !isSessionActive(sid) {
+		// Left by a session which died before saving its header, there
+		// is nothing to resume from it.
+		if e = os.Remove(sessionDataFile); e == nil || os.IsNotExist(e) {
+			dataFile, e = os.OpenFile(sessionDataFile, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		}
+	}
+	if os.IsExist(e) {
+		return nil, errSessionExists(sid)
+	}
+	if e != nil {
+		return nil, probe.NewError(e).Trace(sessionDataFile)
+	}
+	return dataFile, nil
+}
+
 // getSessionDataFile - get session data file for a given session.
 func getSessionDataFile(sid string) (string, *probe.Error) {
 	sessionDir, err := getSessionDir()
@@ -131,3 +173,286 @@ func getHash(prefix string, args []string) string {
 
 	return prefix + "-" + hex.EncodeToString(hasher.Sum(nil))
 }
+
+// Default checkpoint interval for copy sessions, saving the session
+// header at most once a second keeps resume accurate without paying
+// for a disk sync on every tiny object.
+const defaultCheckpointInterval = "1s"
+
+// sessionCheckpoint decides when a session header should be persisted,
+// either after every N objects or after a fixed wall-clock interval.
+type sessionCheckpoint struct {
+	objects  int
+	interval time.Duration
+
+	mutex     sync.Mutex
+	pending   int
+	lastSaved time.Time
+}
+
+// newSessionCheckpoint parses a checkpoint interval, a plain integer is
+// treated as an object count and anything else as a duration.
+func newSessionCheckpoint(interval string) (*sessionCheckpoint, *probe.Error) {
+	if interval == "" {
+		interval = defaultCheckpointInterval
+	}
+	if n, e := strconv.Atoi(interval); e == nil {
+		if n <= 0 {
+			return nil, errInvalidArgument().Trace(interval)
+		}
+		return &sessionCheckpoint{objects: n}, nil
+	}
+	d, e := time.ParseDuration(interval)
+	if e != nil {
+		return nil, probe.NewError(e).Trace(interval)
+	}
+	if d <= 0 {
+		return nil, errInvalidArgument().Trace(interval)
+	}
+	return &sessionCheckpoint{interval: d, lastSaved: time.Now()}, nil
+}
+
+// due records one more completed object and reports whether the
+// session should be saved now.
+func (c *sessionCheckpoint) due() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pending++
+	if c.objects > 0 {
+		if c.pending < c.objects {
+			return false
+		}
+	} else if time.Since(c.lastSaved) < c.interval {
+		return false
+	}
+	c.pending = 0
+	c.lastSaved = time.Now()
+	return true
+}
+
+// partDue reports whether the session should be saved after a part of
+// a multipart upload, parts are saved at the checkpoint interval or
+// the default one when checkpoints count objects.
+func (c *sessionCheckpoint) partDue() bool {
+	if c == nil {
+		return true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	interval := c.interval
+	if interval == 0 {
+		interval = time.Second
+	}
+	if time.Since(c.lastSaved) < interval {
+		return false
+	}
+	c.lastSaved = time.Now()
+	return true
+}
+
+// copyWatermark follows the URLs of a session in the order they were
+// queued to find the last one completed along with all the earlier
+// ones, the only URL safe to resume after.
+type copyWatermark struct {
+	queued int64
+	next   int64
+	done   map[int64]string
+}
+
+func newCopyWatermark() *copyWatermark {
+	return &copyWatermark{done: make(map[int64]string)}
+}
+
+// queue returns the position of the next queued URL.
+func (w *copyWatermark) queue() int64 {
+	w.queued++
+	return w.queued
+}
+
+// complete records the URL queued at seq and returns the new last
+// completed URL, ok is false while an earlier URL is still pending.
+func (w *copyWatermark) complete(seq int64, url string) (lastURL string, ok bool) {
+	w.done[seq] = url
+	for {
+		u, found := w.done[w.next+1]
+		if !found {
+			return lastURL, ok
+		}
+		delete(w.done, w.next+1)
+		w.next++
+		lastURL, ok = u, true
+	}
+}
+
+// Sessions left untouched longer than this are removed when a new
+// session starts, MC_SESSION_EXPIRY overrides it and "0" turns the
+// cleanup off.
+const defaultSessionExpiry = "30d"
+
+// getSessionExpiry - get the age after which sessions are expired.
+func getSessionExpiry() (time.Duration, *probe.Error) {
+	expiry := os.Getenv("MC_SESSION_EXPIRY")
+	if expiry == "" {
+		expiry = defaultSessionExpiry
+	}
+	d, e := ioutils.ParseDurationTime(expiry)
+	if e != nil {
+		return 0, probe.NewError(e).Trace(expiry)
+	}
+	if d < 0 {
+		return 0, errInvalidArgument().Trace(expiry)
+	}
+	return d, nil
+}
+
+// getSessionTime - a session is as recent as its start or its last
+// saved header, whichever is later.
+func getSessionTime(s *sessionV8) time.Time {
+	when := s.Header.When
+	sessionFile, err := getSessionFile(s.SessionID)
+	if err != nil {
+		return when
+	}
+	if st, e := os.Stat(sessionFile); e == nil && st.ModTime().After(when) {
+		return st.ModTime()
+	}
+	return when
+}
+
+// sessionActiveWindow - sessions saved or touched within it are assumed
+// to be in use by a running command, see keepActive.
+const sessionActiveWindow = 3 * time.Minute
+
+// isSessionActive tells if session sid may be in use by a running
+// command.
+func isSessionActive(sid string) bool {
+	sessionFile, err := getSessionFile(sid)
+	if err != nil {
+		return false
+	}
+	sessionDataFile, err := getSessionDataFile(sid)
+	if err != nil {
+		return false
+	}
+	for _, name := range []string{sessionFile, sessionDataFile} {
+		if st, e := os.Stat(name); e == nil && time.Since(st.ModTime()) < sessionActiveWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// clearSessions - remove all sessions older than olderThan, sessions
+// which cannot be loaded are reported and left alone, as well as those
+// which may be in use.
+func clearSessions(olderThan time.Duration) (cleared []sessionClearMessage) {
+	now := UTCNow()
+	for _, sid := range getSessionIDs() {
+		s, err := loadSessionV8Header(sid)
+		if err != nil {
+			errorIf(err.Trace(sid), "Unable to load session `"+sid+"`, skipping.")
+			continue
+		}
+		when := getSessionTime(s)
+		if now.Sub(when) < olderThan {
+			continue
+		}
+		if isSessionActive(sid) {
+			warningIf(errSessionActive(sid, sessionActiveWindow), "Skipping session `%s`.", sid)
+			continue
+		}
+		if err = s.Delete(); err != nil {
+			errorIf(err.Trace(sid), "Unable to remove session `"+sid+"`.")
+			continue
+		}
+		cleared = append(cleared, sessionClearMessage{
+			SessionID:   sid,
+			Time:        when,
+			CommandType: s.Header.CommandType,
+		})
+	}
+	cleared = append(cleared, clearOrphanSessionData(olderThan)...)
+	return append(cleared, clearCorruptSessions(olderThan)...)
+}
+
+// clearOrphanSessionData - remove the data files older than olderThan
+// of sessions which died before saving their header.
+func clearOrphanSessionData(olderThan time.Duration) (cleared []sessionClearMessage) {
+	sessionDir, err := getSessionDir()
+	fatalIf(err.Trace(), "Unable to access session folder.")
+
+	dataFiles, e := filepath.Glob(sessionDir + "/*.data")
+	fatalIf(probe.NewError(e), "Unable to access session folder `"+sessionDir+"`.")
+
+	now := UTCNow()
+	for _, dataFile := range dataFiles {
+		sid := strings.TrimSuffix(filepath.Base(dataFile), ".data")
+		if isSessionExists(sid) || isSessionActive(sid) {
+			continue
+		}
+		st, e := os.Stat(dataFile)
+		if e != nil || now.Sub(st.ModTime()) < olderThan {
+			continue
+		}
+		if e = os.Remove(dataFile); e != nil && !os.IsNotExist(e) {
+			errorIf(probe.NewError(e).Trace(dataFile), "Unable to remove session data `"+sid+"`.")
+			continue
+		}
+		cleared = append(cleared, sessionClearMessage{
+			SessionID: sid,
+			Time:      st.ModTime(),
+		})
+	}
+	return cleared
+}
+
+// clearCorruptSessions - remove the files older than olderThan of the
+// sessions moved aside as corrupt, see moveSessionAside.
+func clearCorruptSessions(olderThan time.Duration) (cleared []sessionClearMessage) {
+	sessionDir, err := getSessionDir()
+	fatalIf(err.Trace(), "Unable to access session folder.")
+
+	corruptFiles, e := filepath.Glob(sessionDir + "/*.json.corrupt")
+	fatalIf(probe.NewError(e), "Unable to access session folder `"+sessionDir+"`.")
+
+	now := UTCNow()
+	for _, corruptFile := range corruptFiles {
+		sid := strings.TrimSuffix(filepath.Base(corruptFile), ".json.corrupt")
+		st, e := os.Stat(corruptFile)
+		if e != nil || now.Sub(st.ModTime()) < olderThan {
+			continue
+		}
+		sessionDataFile, err := getSessionDataFile(sid)
+		if err != nil {
+			errorIf(err.Trace(sid), "Unable to remove corrupt session `"+sid+"`.")
+			continue
+		}
+		if e = os.Remove(sessionDataFile + ".corrupt"); e != nil && !os.IsNotExist(e) {
+			errorIf(probe.NewError(e).Trace(sessionDataFile), "Unable to remove corrupt session `"+sid+"`.")
+			continue
+		}
+		if e = os.Remove(corruptFile); e != nil && !os.IsNotExist(e) {
+			errorIf(probe.NewError(e).Trace(corruptFile), "Unable to remove corrupt session `"+sid+"`.")
+			continue
+		}
+		cleared = append(cleared, sessionClearMessage{
+			SessionID: sid,
+			Time:      st.ModTime(),
+		})
+	}
+	return cleared
+}
+
+// expireSessions - remove sessions past the configured expiry, called
+// before a new session is created.
+func expireSessions() {
+	expiry, err := getSessionExpiry()
+	if err != nil {
+		errorIf(err.Trace(), "Unable to parse MC_SESSION_EXPIRY, not removing expired sessions.")
+		return
+	}
+	if expiry == 0 {
+		return
+	}
+	clearSessions(expiry)
+}
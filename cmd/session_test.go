@@ -17,9 +17,24 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/minio/cli"
+	"github.com/minio/mc/pkg/probe"
 	. "gopkg.in/check.v1"
 )
 
@@ -58,3 +73,734 @@ func (s *TestSuite) TestSession(c *C) {
 	_, e = os.Stat(session.DataFP.Name())
 	c.Assert(e, NotNil)
 }
+
+func (s *TestSuite) TestSessionExists(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	sid := getHash("cp", []string{"session-exists"})
+	c.Assert(checkNewSessionID(sid), IsNil)
+
+	session := newSessionV8(sid)
+	c.Assert(session.Close(), IsNil)
+	defer session.Delete()
+
+	err := checkNewSessionID(sid)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(sessionExistsErr)
+	c.Assert(ok, Equals, true)
+
+	// Started twice at once, before the first one saved its header.
+	sid = getHash("cp", []string{"session-started-twice"})
+	dataFile, err := createSessionDataFile(sid)
+	c.Assert(err, IsNil)
+	defer os.Remove(dataFile.Name())
+	dataFile.Close()
+	c.Assert(checkNewSessionID(sid), IsNil)
+	_, err = createSessionDataFile(sid)
+	c.Assert(err, NotNil)
+
+	// Left by a session which died before saving its header.
+	past := time.Now().Add(-2 * sessionActiveWindow)
+	c.Assert(os.Chtimes(dataFile.Name(), past, past), IsNil)
+	dataFile, err = createSessionDataFile(sid)
+	c.Assert(err, IsNil)
+	dataFile.Close()
+}
+
+func (s *TestSuite) TestSessionDataConcurrent(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"data-concurrent"}))
+	defer session.Delete()
+
+	const lines = 20000
+	writer := session.NewDataWriter()
+	for i := 0; i < lines/2; i++ {
+		fmt.Fprintf(writer, "%d\n", i)
+	}
+	// Reading the copied URLs in between appends.
+	scanner := bufio.NewScanner(session.NewDataReader())
+	c.Assert(scanner.Scan(), Equals, true)
+	c.Assert(scanner.Text(), Equals, "0")
+	for i := lines / 2; i < lines/2+100; i++ {
+		fmt.Fprintf(writer, "%d\n", i)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := lines/2 + 100; i < lines; i++ {
+			fmt.Fprintf(writer, "%d\n", i)
+		}
+	}()
+
+	// Readers started while the data file grows see its lines in order
+	// and never move the offset of the writer.
+	readErrs := make(chan string, 4)
+	for r := 0; r < cap(readErrs); r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var read []string
+			scanner := bufio.NewScanner(session.NewDataReader())
+			for scanner.Scan() {
+				read = append(read, scanner.Text())
+			}
+			// The last line may still have been written.
+			for i := 0; i < len(read)-1; i++ {
+				if read[i] != strconv.Itoa(i) {
+					readErrs <- fmt.Sprintf("line %d is %q", i, read[i])
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(readErrs)
+	for e := range readErrs {
+		c.Error(e)
+	}
+
+	c.Assert(session.Save(), IsNil)
+	scanner = bufio.NewScanner(session.NewDataReader())
+	i := 0
+	for ; scanner.Scan(); i++ {
+		c.Assert(scanner.Text(), Equals, strconv.Itoa(i))
+	}
+	c.Assert(i, Equals, lines)
+}
+
+func (s *TestSuite) TestSessionCloseAndDie(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"close-and-die"}))
+	session.Header.CommandType = "cp"
+	c.Assert(session.CloseAndDie(), Equals, errSessionTerminated)
+	c.Assert(isSessionExists(session.SessionID), Equals, true)
+
+	// Without --continue only the data file is closed.
+	resumed, err := resumeSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(resumed.copyCloseAndDie(false), IsNil)
+	c.Assert(resumed.copyCloseAndDie(true), Equals, errSessionTerminated)
+	c.Assert(resumed.Delete(), IsNil)
+	c.Assert(isSessionExists(session.SessionID), Equals, false)
+}
+
+func (s *TestSuite) TestClearSessions(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	old := newSessionV8(getHash("cp", []string{"clear-old"}))
+	old.Header.When = UTCNow().Add(-60 * 24 * time.Hour)
+	c.Assert(old.Close(), IsNil)
+	oldFile, err := getSessionFile(old.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(os.Chtimes(oldFile, old.Header.When, old.Header.When), IsNil)
+	c.Assert(os.Chtimes(old.DataFP.Name(), old.Header.When, old.Header.When), IsNil)
+
+	recent := newSessionV8(getHash("cp", []string{"clear-recent"}))
+	c.Assert(recent.Close(), IsNil)
+	defer recent.Delete()
+
+	// A corrupt header is skipped without stopping the cleanup.
+	corruptFile, err := getSessionFile("corrupt")
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(corruptFile, []byte("{"), 0600), IsNil)
+	defer os.Remove(corruptFile)
+
+	// As well as the data of a session which never saved its header.
+	orphan, err := createSessionDataFile(getHash("cp", []string{"clear-orphan"}))
+	c.Assert(err, IsNil)
+	c.Assert(orphan.Close(), IsNil)
+	defer os.Remove(orphan.Name())
+	c.Assert(os.Chtimes(orphan.Name(), old.Header.When, old.Header.When), IsNil)
+
+	// And the files of sessions moved aside as corrupt.
+	asideFile, err := getSessionFile("clear-aside")
+	c.Assert(err, IsNil)
+	asideDataFile, err := getSessionDataFile("clear-aside")
+	c.Assert(err, IsNil)
+	for _, name := range []string{asideFile + ".corrupt", asideDataFile + ".corrupt"} {
+		c.Assert(ioutil.WriteFile(name, []byte("{"), 0600), IsNil)
+		defer os.Remove(name)
+		c.Assert(os.Chtimes(name, old.Header.When, old.Header.When), IsNil)
+	}
+
+	cleared := clearSessions(30 * 24 * time.Hour)
+	c.Assert(len(cleared), Equals, 3)
+	c.Assert(cleared[0].SessionID, Equals, old.SessionID)
+	c.Assert(cleared[1].SessionID, Equals, getHash("cp", []string{"clear-orphan"}))
+	c.Assert(cleared[2].SessionID, Equals, "clear-aside")
+	for _, name := range []string{asideFile + ".corrupt", asideDataFile + ".corrupt"} {
+		_, e := os.Stat(name)
+		c.Assert(os.IsNotExist(e), Equals, true)
+	}
+	_, e := os.Stat(orphan.Name())
+	c.Assert(os.IsNotExist(e), Equals, true)
+	c.Assert(isSessionExists(old.SessionID), Equals, false)
+	_, e = os.Stat(old.DataFP.Name())
+	c.Assert(os.IsNotExist(e), Equals, true)
+	c.Assert(isSessionExists(recent.SessionID), Equals, true)
+	c.Assert(isSessionExists("corrupt"), Equals, true)
+
+	// Sessions used of late may be running, even when clearing all.
+	c.Assert(isSessionActive(recent.SessionID), Equals, true)
+	c.Assert(len(clearSessions(0)), Equals, 0)
+	c.Assert(isSessionExists(recent.SessionID), Equals, true)
+}
+
+// Test that the uploads a session leaves incomplete are aborted when it
+// is removed, or when their source changed before they are resumed.
+func (s *TestSuite) TestSessionAbortUploads(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	var mutex sync.Mutex
+	var aborted []string
+	server := newTestS3Server(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			mutex.Lock()
+			aborted = append(aborted, r.URL.Path+"?"+r.URL.Query().Get("uploadId"))
+			mutex.Unlock()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	defer server.Close()
+
+	defer useEmptyMcConfig()()
+	defer setTestAlias("aborttarget", server.URL)()
+
+	session := newSessionV8(getHash("cp", []string{"abort-uploads"}))
+	modTime := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	session.Header.Uploads = map[string]sessionUpload{
+		server.URL + "/bucket/changed": {Size: 1, ModTime: modTime, Parts: uploadParts{UploadID: "upload1"}, Alias: "aborttarget"},
+		server.URL + "/bucket/left":    {Size: 1, ModTime: modTime, Parts: uploadParts{UploadID: "upload2"}, Alias: "aborttarget"},
+	}
+	c.Assert(session.Save(), IsNil)
+
+	cpURLs := URLs{
+		SourceContent: &clientContent{Size: 2, Time: modTime},
+		TargetAlias:   "aborttarget",
+		TargetContent: &clientContent{URL: *newClientURL(server.URL + "/bucket/changed")},
+	}
+	checkpoint, err := newSessionCheckpoint("1h")
+	c.Assert(err, IsNil)
+	ctx := session.withUploadParts(context.Background(), cpURLs, checkpoint)
+	c.Assert(ctx.Value(uploadPartsContextKey{}).(*uploadParts).UploadID, Equals, "")
+	c.Assert(aborted, DeepEquals, []string{"/bucket/changed?upload1"})
+
+	c.Assert(session.Delete(), IsNil)
+	c.Assert(aborted, DeepEquals, []string{"/bucket/changed?upload1", "/bucket/left?upload2"})
+}
+
+func (s *TestSuite) TestMigrateCorruptSession(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	corruptFile, err := getSessionFile("corrupt-migrate")
+	c.Assert(err, IsNil)
+	c.Assert(ioutil.WriteFile(corruptFile, []byte("{"), 0600), IsNil)
+	defer os.Remove(corruptFile + ".corrupt")
+
+	// Moved aside once, no longer listed for the next commands.
+	migrateSessionV5ToV6()
+	c.Assert(isSessionExists("corrupt-migrate"), Equals, false)
+	_, e := os.Stat(corruptFile + ".corrupt")
+	c.Assert(e, IsNil)
+}
+
+func (s *TestSuite) TestSessionProgress(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"progress"}))
+	defer session.Delete()
+
+	// Never started transferring.
+	bytesDone, objectsDone := session.progress()
+	c.Assert(bytesDone, Equals, int64(0))
+	c.Assert(objectsDone, Equals, int64(0))
+	c.Assert(sessionProgressPercent(0, 0, 0, 0), Equals, float64(0))
+
+	writer := session.NewDataWriter()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		data, e := json.Marshal(URLs{SourceContent: &clientContent{URL: *newClientURL(name), Size: 25}})
+		c.Assert(e, IsNil)
+		fmt.Fprintf(writer, "%s\n", data)
+	}
+	session.Header.TotalBytes, session.Header.TotalObjects = 100, 4
+	session.Header.LastCopied = "b"
+
+	bytesDone, objectsDone = session.progress()
+	c.Assert(bytesDone, Equals, int64(50))
+	c.Assert(objectsDone, Equals, int64(2))
+	c.Assert(sessionProgressPercent(bytesDone, 100, objectsDone, 4), Equals, float64(50))
+	// Progress by objects when all of them are empty.
+	c.Assert(sessionProgressPercent(0, 0, 1, 4), Equals, float64(25))
+}
+
+func (s *TestSuite) TestSessionCheckpoint(c *C) {
+	checkpoint, err := newSessionCheckpoint("3")
+	c.Assert(err, IsNil)
+	c.Assert(checkpoint.due(), Equals, false)
+	c.Assert(checkpoint.due(), Equals, false)
+	c.Assert(checkpoint.due(), Equals, true)
+	c.Assert(checkpoint.due(), Equals, false)
+
+	checkpoint, err = newSessionCheckpoint("1h")
+	c.Assert(err, IsNil)
+	c.Assert(checkpoint.due(), Equals, false)
+	// Parts are saved at the interval, not after each one.
+	c.Assert(checkpoint.partDue(), Equals, false)
+	checkpoint.lastSaved = time.Now().Add(-2 * time.Hour)
+	c.Assert(checkpoint.partDue(), Equals, true)
+	c.Assert(checkpoint.partDue(), Equals, false)
+	c.Assert((*sessionCheckpoint)(nil).partDue(), Equals, true)
+
+	for _, interval := range []string{"0", "-5", "-1s", "abc"} {
+		_, err = newSessionCheckpoint(interval)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *TestSuite) TestCopyWatermark(c *C) {
+	w := newCopyWatermark()
+	a, b, d := w.queue(), w.queue(), w.queue()
+
+	// Completed before the first one, not safe to resume after yet.
+	_, ok := w.complete(d, "d")
+	c.Assert(ok, Equals, false)
+	lastURL, ok := w.complete(a, "a")
+	c.Assert(ok, Equals, true)
+	c.Assert(lastURL, Equals, "a")
+	lastURL, ok = w.complete(b, "b")
+	c.Assert(ok, Equals, true)
+	c.Assert(lastURL, Equals, "d")
+}
+
+func (s *TestSuite) TestDrainCopyStatus(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+	savedQuiet := globalQuiet
+	globalQuiet = true
+	defer func() { globalQuiet = savedQuiet }()
+
+	session := newSessionV8(getHash("cp", []string{"drain-copy-status"}))
+	defer session.Delete()
+
+	w := newCopyWatermark()
+	statusCh := make(chan URLs, 3)
+	for _, name := range []string{"a", "b", "d"} {
+		cpURLs := URLs{SourceContent: &clientContent{URL: *newClientURL(name)}, seq: w.queue()}
+		if name == "b" {
+			cpURLs.Error = probe.NewError(errCopyNotStarted)
+		}
+		statusCh <- cpURLs
+	}
+	close(statusCh)
+
+	// Objects not started stop the session before them.
+	drainCopyStatus(session, w, statusCh)
+	c.Assert(session.Header.LastCopied, Equals, "a")
+}
+
+func (s *TestSuite) TestSessionDataMissing(c *C) {
+	err := createSessionDir()
+	c.Assert(err, IsNil)
+
+	args := []string{"mybucket", "myminio/mybucket"}
+	session := newSessionV8(getHash("cp", []string{"data-missing"}))
+	session.Header.CommandType = "cp"
+	session.Header.CommandArgs = args
+	session.Header.LastCopied = "mybucket/object"
+	session.Header.TotalObjects = 10
+	err = session.Close()
+	c.Assert(err, IsNil)
+
+	// Header is present, data file is not.
+	c.Assert(os.Remove(session.DataFP.Name()), IsNil)
+	c.Assert(isSessionExists(session.SessionID), Equals, true)
+
+	_, err = loadSessionV8(session.SessionID)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(SessionDataMissing)
+	c.Assert(ok, Equals, true)
+
+	restarted, err := resumeSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(restarted.Header.CommandArgs, DeepEquals, args)
+	c.Assert(restarted.HasData(), Equals, false)
+	c.Assert(restarted.Header.TotalObjects, Equals, int64(0))
+	_, e := os.Stat(restarted.DataFP.Name())
+	c.Assert(e, IsNil)
+
+	c.Assert(restarted.Delete(), IsNil)
+	c.Assert(isSessionExists(session.SessionID), Equals, false)
+}
+
+func (s *TestSuite) TestSessionDataCorrupt(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"data-corrupt"}))
+	session.Header.CommandType = "cp"
+	session.Header.LastCopied = "mybucket/object"
+	session.Header.TotalObjects = 10
+	c.Assert(session.Close(), IsNil)
+	defer session.Delete()
+
+	for data, reason := range map[string]string{
+		"":                               "it is empty",
+		"{\"source\":{}}\n{\"source\"\n": "line 2 is not valid JSON",
+	} {
+		c.Assert(ioutil.WriteFile(session.DataFP.Name(), []byte(data), 0600), IsNil)
+		_, err := resumeSessionV8(session.SessionID)
+		c.Assert(err, NotNil)
+		corrupt, ok := err.ToGoError().(SessionDataCorrupt)
+		c.Assert(ok, Equals, true)
+		c.Assert(corrupt.Reason, Equals, reason)
+	}
+
+	// Only resumes read the data file.
+	loaded, err := loadSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(loaded.DataFP.Close(), IsNil)
+
+	// Streamed sessions not listed to the end list their sources again.
+	session.Header.CommandBoolFlags["stream"] = true
+	session.Header.TotalObjects = 0
+	c.Assert(session.Save(), IsNil)
+	loaded, err = resumeSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(loaded.Close(), IsNil)
+	session.Header.CommandBoolFlags["stream"] = false
+	session.Header.TotalObjects = 10
+	c.Assert(session.Save(), IsNil)
+
+	c.Assert(ioutil.WriteFile(session.DataFP.Name(), []byte("{\"source\":{}}\n"), 0600), IsNil)
+	loaded, err = resumeSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(loaded.Close(), IsNil)
+
+	// Repairing keeps the last copied object for the listing again.
+	repaired, err := repairSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(repaired.Header.LastCopied, Equals, "mybucket/object")
+	c.Assert(repaired.Header.TotalObjects, Equals, int64(0))
+	st, e := repaired.DataFP.Stat()
+	c.Assert(e, IsNil)
+	c.Assert(st.Size(), Equals, int64(0))
+	c.Assert(repaired.DataFP.Close(), IsNil)
+}
+
+func (s *TestSuite) TestScanSessionResume(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	// Scan sessions never write their data file.
+	session := newSessionV8(getHash("scan", []string{"scan-resume"}))
+	session.Header.CommandType = "scan"
+	session.Header.LastCopied = "myminio/mybucket/object"
+	c.Assert(session.Close(), IsNil)
+	defer session.Delete()
+
+	resumed, err := resumeSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(resumed.Header.LastCopied, Equals, "myminio/mybucket/object")
+	c.Assert(resumed.Close(), IsNil)
+}
+
+func (s *TestSuite) TestSessionDataFormat(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"data-format"}))
+	session.Header.LastCopied = "a"
+	defer session.Delete()
+
+	readLines := func() (lines []string) {
+		scanner := bufio.NewScanner(session.NewDataReader())
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		return lines
+	}
+
+	// Written with the format header, read without it.
+	fmt.Fprintf(session.NewDataWriter(), "{\"a\":1}\n")
+	c.Assert(session.Close(), IsNil)
+	data, e := ioutil.ReadFile(session.DataFP.Name())
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, sessionDataHeader(sessionDataFormat)+"{\"a\":1}\n")
+	loaded, err := loadSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	session.DataFP = loaded.DataFP
+	c.Assert(readLines(), DeepEquals, []string{"{\"a\":1}"})
+
+	// Data files from before the header are read as they are, and
+	// migrated by prepending it.
+	c.Assert(ioutil.WriteFile(session.DataFP.Name(), []byte("{\"b\":2}\n"), 0600), IsNil)
+	c.Assert(readLines(), DeepEquals, []string{"{\"b\":2}"})
+	c.Assert(session.DataFP.Close(), IsNil)
+	migrateSessionDataV0ToV1()
+	data, e = ioutil.ReadFile(session.DataFP.Name())
+	c.Assert(e, IsNil)
+	c.Assert(string(data), Equals, sessionDataHeader(sessionDataFormatV1)+"{\"b\":2}\n")
+
+	// Formats of a newer mc are refused.
+	c.Assert(ioutil.WriteFile(session.DataFP.Name(), []byte(sessionDataHeader(sessionDataFormat+1)), 0600), IsNil)
+	_, err = loadSessionV8(session.SessionID)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(SessionDataTooNew)
+	c.Assert(ok, Equals, true)
+}
+
+func (s *TestSuite) TestSessionExportImport(c *C) {
+	c.Assert(createSessionDir(), IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"export-import"}))
+	session.Header.CommandType = "cp"
+	session.Header.CommandArgs = []string{"backups/", "s3/mybucket/backups/"}
+	session.Header.RootPath = "/home/user"
+	session.Header.CommandBoolFlags["recursive"] = true
+	session.Header.LastCopied = "backups/b"
+	fmt.Fprintf(session.NewDataWriter(), "{\"a\":1}\n{\"b\":2}\n")
+	c.Assert(session.Close(), IsNil)
+	sid := session.SessionID
+	data, e := ioutil.ReadFile(session.DataFP.Name())
+	c.Assert(e, IsNil)
+
+	var archive bytes.Buffer
+	c.Assert(exportSession(sid, &archive), IsNil)
+	exported := archive.Bytes()
+
+	// The session exists here already.
+	_, err := importSession(bytes.NewReader(exported))
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(sessionExistsErr)
+	c.Assert(ok, Equals, true)
+
+	c.Assert(session.Delete(), IsNil)
+	imported, err := importSession(bytes.NewReader(exported))
+	c.Assert(err, IsNil)
+	c.Assert(imported.SessionID, Equals, sid)
+	c.Assert(imported.Header.CommandArgs, DeepEquals, session.Header.CommandArgs)
+	c.Assert(imported.Header.RootPath, Equals, "/home/user")
+	c.Assert(imported.Header.CommandBoolFlags["recursive"], Equals, true)
+	c.Assert(imported.Header.LastCopied, Equals, "backups/b")
+
+	resumed, err := loadSessionV8(sid)
+	c.Assert(err, IsNil)
+	defer resumed.Delete()
+	importedData, e := ioutil.ReadFile(resumed.DataFP.Name())
+	c.Assert(e, IsNil)
+	c.Assert(importedData, DeepEquals, data)
+
+	// Archives with anything but the files of one session are refused.
+	var bad bytes.Buffer
+	tw := tar.NewWriter(&bad)
+	c.Assert(tw.WriteHeader(&tar.Header{Name: "../escape.json", Mode: 0600, Size: 2, Typeflag: tar.TypeReg}), IsNil)
+	_, e = tw.Write([]byte("{}"))
+	c.Assert(e, IsNil)
+	c.Assert(tw.Close(), IsNil)
+	_, err = importSession(&bad)
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestRecomputeCopyTotals(c *C) {
+	err := createSessionDir()
+	c.Assert(err, IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"recompute-totals"}))
+	session.Header.CommandType = "cp"
+	session.Header.CommandArgs = []string{"source", "target"}
+	// Totals off from the listing of the session.
+	session.Header.TotalBytes = 100
+	session.Header.TotalObjects = 10
+	defer session.Delete()
+
+	dataFP := session.NewDataWriter()
+	for name, size := range map[string]int64{"a": 1, "b": 2, "dir/c": 3} {
+		data, e := json.Marshal(URLs{
+			SourceContent: &clientContent{URL: *newClientURL("source/" + name), Size: size},
+			TargetContent: &clientContent{URL: *newClientURL("target/" + name)},
+		})
+		c.Assert(e, IsNil)
+		_, e = dataFP.Write(append(data, '\n'))
+		c.Assert(e, IsNil)
+	}
+
+	totalBytes, totalObjects, err := recomputeCopyTotals(session)
+	c.Assert(err, IsNil)
+	c.Assert(totalBytes, Equals, int64(6))
+	c.Assert(totalObjects, Equals, int64(3))
+	c.Assert(session.Close(), IsNil)
+
+	loaded, err := loadSessionV8(session.SessionID)
+	c.Assert(err, IsNil)
+	c.Assert(loaded.Header.TotalBytes, Equals, int64(6))
+	c.Assert(loaded.Header.TotalObjects, Equals, int64(3))
+	c.Assert(loaded.Close(), IsNil)
+}
+
+func (s *TestSuite) TestResumeMarkerMissing(c *C) {
+	listing := []string{"mybucket/a", "mybucket/b", "mybucket/c"}
+
+	marker := newResumeMarker("mybucket/b")
+	var done []bool
+	for _, URL := range listing {
+		done = append(done, marker.isDone(URL))
+	}
+	c.Assert(done, DeepEquals, []bool{true, true, false})
+	c.Assert(marker.missing(), Equals, false)
+
+	// The last copied object was removed from the source between runs.
+	marker = newResumeMarker("mybucket/deleted")
+	done = nil
+	for _, URL := range listing {
+		done = append(done, marker.isDone(URL))
+	}
+	c.Assert(done, DeepEquals, []bool{true, true, true})
+	c.Assert(marker.missing(), Equals, true)
+
+	// Nothing to look for in a new session.
+	marker = newResumeMarker("")
+	c.Assert(marker.isDone("mybucket/a"), Equals, false)
+	c.Assert(marker.missing(), Equals, false)
+}
+
+func (s *TestSuite) TestSessionTooNew(c *C) {
+	err := createSessionDir()
+	c.Assert(err, IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"too-new"}))
+	session.Header.CommandType = "cp"
+	session.Header.CommandArgs = []string{"mybucket", "myminio/mybucket"}
+	session.Header.LastCopied = "mybucket/object"
+	// As written by a newer mc.
+	session.Header.Version = "9"
+	c.Assert(session.Close(), IsNil)
+	defer session.Delete()
+
+	sessionFile, err := getSessionFile(session.SessionID)
+	c.Assert(err, IsNil)
+	header, e := ioutil.ReadFile(sessionFile)
+	c.Assert(e, IsNil)
+
+	_, err = loadSessionV8(session.SessionID)
+	c.Assert(err, NotNil)
+	_, ok := err.ToGoError().(SessionTooNew)
+	c.Assert(ok, Equals, true)
+
+	// Resuming must fail the same way and leave the session untouched.
+	_, err = resumeSessionV8(session.SessionID)
+	c.Assert(err, NotNil)
+	_, ok = err.ToGoError().(SessionTooNew)
+	c.Assert(ok, Equals, true)
+	unchanged, e := ioutil.ReadFile(sessionFile)
+	c.Assert(e, IsNil)
+	c.Assert(string(unchanged), Equals, string(header))
+	_, e = os.Stat(session.DataFP.Name())
+	c.Assert(e, IsNil)
+}
+
+func (s *TestSuite) TestStreamCopyURLs(c *C) {
+	defer useEmptyMcConfig()()
+
+	err := createSessionDir()
+	c.Assert(err, IsNil)
+
+	tmpDir, e := ioutil.TempDir("", "stream-copy-urls-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(tmpDir)
+	var args, expected []string
+	for _, source := range []string{"s1", "s2", "s3", "s4"} {
+		sourceDir := filepath.Join(tmpDir, source)
+		c.Assert(os.MkdirAll(sourceDir, 0755), IsNil)
+		for _, name := range []string{"a", "b", "c"} {
+			c.Assert(ioutil.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0644), IsNil)
+			expected = append(expected, filepath.Join(sourceDir, name))
+		}
+		args = append(args, sourceDir)
+	}
+	c.Assert(os.MkdirAll(filepath.Join(tmpDir, "target"), 0755), IsNil)
+	args = append(args, filepath.Join(tmpDir, "target"))
+
+	session := newSessionV8(getHash("cp", []string{"stream-copy-urls"}))
+	session.Header.CommandType = "cp"
+	session.Header.CommandArgs = args
+	session.Header.CommandBoolFlags["recursive"] = true
+	session.Header.CommandBoolFlags["stream"] = true
+	session.Header.CommandIntFlags["list-workers"] = 4
+	defer session.Delete()
+
+	// Every listing of a streamed session is in the same order, resumes
+	// skip the URLs listed before the last copied one.
+	for i := 0; i < 3; i++ {
+		cpURLsCh := make(chan URLs, len(expected))
+		c.Assert(doStreamCopyURLs(session, nil, nil, newAccounter(0), cpURLsCh), IsNil)
+		close(cpURLsCh)
+		var listed []string
+		for cpURLs := range cpURLsCh {
+			c.Assert(cpURLs.Error, IsNil)
+			listed = append(listed, cpURLs.SourceContent.URL.Path)
+		}
+		c.Assert(listed, DeepEquals, expected)
+		c.Assert(session.Header.TotalObjects, Equals, int64(len(expected)))
+		c.Assert(session.Header.TotalBytes, Equals, int64(len(expected)))
+	}
+
+	// The data file holds the URLs of the last listing, in order.
+	var saved []string
+	scanner := bufio.NewScanner(session.NewDataReader())
+	for scanner.Scan() {
+		var cpURLs URLs
+		c.Assert(json.Unmarshal(scanner.Bytes(), &cpURLs), IsNil)
+		saved = append(saved, cpURLs.SourceContent.URL.Path)
+	}
+	c.Assert(saved, DeepEquals, expected)
+
+	// Listing stops at the first source which doesn't fit the free
+	// space of the target, the session is listed again on resume.
+	session.Header.TotalBytes, session.Header.TotalObjects = 0, 0
+	cpURLsCh := make(chan URLs, len(expected))
+	e = doStreamCopyURLs(session, nil, &freeSpaceCheck{path: tmpDir, free: 5}, newAccounter(0), cpURLsCh)
+	c.Assert(e, NotNil)
+	close(cpURLsCh)
+	c.Assert(len(cpURLsCh), Equals, 5)
+	c.Assert(session.isRelisted(), Equals, true)
+}
+
+func (s *TestSuite) TestResumePreserveMtime(c *C) {
+	defer useEmptyMcConfig()()
+	savedQuiet := globalQuiet
+	globalQuiet = true
+	defer func() { globalQuiet = savedQuiet }()
+
+	err := createSessionDir()
+	c.Assert(err, IsNil)
+
+	tmpDir, e := ioutil.TempDir("", "resume-preserve-mtime-")
+	c.Assert(e, IsNil)
+	defer os.RemoveAll(tmpDir)
+	source := filepath.Join(tmpDir, "object")
+	c.Assert(ioutil.WriteFile(source, []byte("data"), 0644), IsNil)
+	mtime := time.Date(2015, 6, 7, 8, 9, 10, 0, time.UTC)
+	c.Assert(os.Chtimes(source, mtime, mtime), IsNil)
+	targetDir := filepath.Join(tmpDir, "target")
+	c.Assert(os.MkdirAll(targetDir, 0755), IsNil)
+
+	session := newSessionV8(getHash("cp", []string{"resume-preserve-mtime"}))
+	session.Header.CommandType = "cp"
+	session.Header.CommandArgs = []string{source, targetDir}
+	session.Header.CommandBoolFlags["preserve-mtime"] = true
+	defer session.Delete()
+
+	// Resumed without --preserve-mtime, the flag of the session applies.
+	set := flag.NewFlagSet("cp", flag.ContinueOnError)
+	for _, f := range cpCmd.Flags {
+		f.Apply(set)
+	}
+	c.Assert(set.Parse([]string{"--continue"}), IsNil)
+	ctx := cli.NewContext(nil, set, nil)
+	ctx.Command = cpCmd
+	c.Assert(doCopySession(ctx, session, nil, nil), IsNil)
+
+	st, e := os.Stat(filepath.Join(targetDir, "object"))
+	c.Assert(e, IsNil)
+	c.Assert(st.ModTime().Equal(mtime), Equals, true)
+}
@@ -72,22 +72,10 @@ func checkShareDownloadSyntax(ctx *cli.Context, encKeyDB map[string][]prefixSSEP
 		cli.ShowCommandHelpAndExit(ctx, "download", 1) // last argument is exit code.
 	}
 
-	// Parse expiry.
-	expiry := shareDefaultExpiry
+	// Parse and validate expiry.
 	expireArg := ctx.String("expire")
-	if expireArg != "" {
-		var e error
-		expiry, e = time.ParseDuration(expireArg)
-		fatalIf(probe.NewError(e), "Unable to parse expire=`"+expireArg+"`.")
-	}
-
-	// Validate expiry.
-	if expiry.Seconds() < 1 {
-		fatalIf(errDummy().Trace(expiry.String()), "Expiry cannot be lesser than 1 second.")
-	}
-	if expiry.Seconds() > 604800 {
-		fatalIf(errDummy().Trace(expiry.String()), "Expiry cannot be larger than 7 days.")
-	}
+	_, err := parseShareExpiry(expireArg)
+	fatalIf(err.Trace(expireArg), "Unable to parse expire=`"+expireArg+"`.")
 
 	// Validate if object exists only if the `--recursive` flag was NOT specified
 	isRecursive := ctx.Bool("recursive")
@@ -182,6 +170,7 @@ func doShareDownloadURL(targetURL string, isRecursive bool, expiry time.Duration
 			ObjectURL:   objectURL,
 			ShareURL:    shareURL,
 			TimeLeft:    expiry,
+			Expiry:      time.Now().Add(expiry),
 			ContentType: contentType,
 		})
 	}
@@ -207,12 +196,8 @@ func mainShareDownload(ctx *cli.Context) error {
 
 	// Set command flags from context.
 	isRecursive := ctx.Bool("recursive")
-	expiry := shareDefaultExpiry
-	if ctx.String("expire") != "" {
-		var e error
-		expiry, e = time.ParseDuration(ctx.String("expire"))
-		fatalIf(probe.NewError(e), "Unable to parse expire=`"+ctx.String("expire")+"`.")
-	}
+	expiry, err := parseShareExpiry(ctx.String("expire"))
+	fatalIf(err.Trace(ctx.String("expire")), "Unable to parse expire=`"+ctx.String("expire")+"`.")
 
 	for _, targetURL := range ctx.Args() {
 		err := doShareDownloadURL(targetURL, isRecursive, expiry)
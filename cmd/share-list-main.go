@@ -95,6 +95,7 @@ func doShareList(cmd string) *probe.Error {
 			ObjectURL:   share.URL,
 			ShareURL:    shareURL,
 			TimeLeft:    share.Expiry - time.Since(share.Date),
+			Expiry:      share.Date.Add(share.Expiry),
 			ContentType: share.ContentType,
 		})
 	}
@@ -26,7 +26,13 @@ import (
 )
 
 var (
-	shareFlags = []cli.Flag{}
+	shareFlags = []cli.Flag{
+		shareFlagExpire,
+		cli.BoolFlag{
+			Name:  "upload",
+			Usage: "share the URLs given without a command for an upload with a PUT request, e.g. curl -T FILE URL, instead of a download",
+		},
+	}
 )
 
 // Share documents via URL.
@@ -62,7 +68,37 @@ func migrateShare() {
 
 // mainShare - main handler for mc share command.
 func mainShare(ctx *cli.Context) error {
-	cli.ShowCommandHelp(ctx, ctx.Args().First())
+	// Sub-commands like "upload" and "download" have their own main,
+	// other arguments are objects shared for download or a PUT upload.
+	if !ctx.Args().Present() {
+		cli.ShowCommandHelp(ctx, ctx.Args().First())
+		return nil
+	}
+
+	expireArg := ctx.String("expire")
+	expiry, err := parseShareExpiry(expireArg)
+	fatalIf(err.Trace(expireArg), "Unable to parse expire=`"+expireArg+"`.")
+
+	// Initialize share config folder.
+	initShareConfig()
+
+	// Additional command speific theme customization.
+	shareSetColor()
+
+	for _, targetURL := range ctx.Args() {
+		if ctx.Bool("upload") {
+			err = doSharePutURL(targetURL, expiry)
+		} else {
+			err = doShareDownloadURL(targetURL, false, expiry)
+		}
+		if err != nil {
+			switch err.ToGoError().(type) {
+			case APINotImplemented:
+				fatalIf(err.Trace(), "Unable to share a non S3 url `"+targetURL+"`.")
+			default:
+				fatalIf(err.Trace(targetURL), "Unable to share target `"+targetURL+"`.")
+			}
+		}
+	}
 	return nil
-	// Sub-commands like "upload" and "download" have their own main.
 }
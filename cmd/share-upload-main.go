@@ -78,23 +78,9 @@ func checkShareUploadSyntax(ctx *cli.Context) {
 	isRecursive := ctx.Bool("recursive")
 	expireArg := ctx.String("expire")
 
-	// Parse expiry.
-	expiry := shareDefaultExpiry
-	if expireArg != "" {
-		var e error
-		expiry, e = time.ParseDuration(expireArg)
-		fatalIf(probe.NewError(e), "Unable to parse expire=`"+expireArg+"`.")
-	}
-
-	// Validate expiry.
-	if expiry.Seconds() < 1 {
-		fatalIf(errDummy().Trace(expiry.String()),
-			"Expiry cannot be lesser than 1 second.")
-	}
-	if expiry.Seconds() > 604800 {
-		fatalIf(errDummy().Trace(expiry.String()),
-			"Expiry cannot be larger than 7 days.")
-	}
+	// Parse and validate expiry.
+	_, err := parseShareExpiry(expireArg)
+	fatalIf(err.Trace(expireArg), "Unable to parse expire=`"+expireArg+"`.")
 
 	for _, targetURL := range ctx.Args() {
 		url := newClientURL(targetURL)
@@ -167,6 +153,7 @@ func doShareUploadURL(objectURL string, isRecursive bool, expiry time.Duration,
 		ObjectURL:   objectURL,
 		ShareURL:    curlCmd,
 		TimeLeft:    expiry,
+		Expiry:      time.Now().Add(expiry),
 		ContentType: contentType,
 	})
 
@@ -174,6 +161,34 @@ func doShareUploadURL(objectURL string, isRecursive bool, expiry time.Duration,
 	return saveSharedURL(objectURL, curlCmd, expiry, contentType)
 }
 
+// doSharePutURL shares the target for an upload with a single PUT
+// request to the URL printed.
+func doSharePutURL(objectURL string, expiry time.Duration) *probe.Error {
+	clnt, err := newClient(objectURL)
+	if err != nil {
+		return err.Trace(objectURL)
+	}
+
+	// Generate pre-signed URL.
+	shareURL, err := clnt.SharePut(expiry)
+	if err != nil {
+		return err.Trace(objectURL, "expiry="+expiry.String())
+	}
+
+	// Get the new expanded url.
+	objectURL = clnt.GetURL().String()
+
+	printMsg(shareMesssage{
+		ObjectURL: objectURL,
+		ShareURL:  shareURL,
+		TimeLeft:  expiry,
+		Expiry:    time.Now().Add(expiry),
+	})
+
+	// save shared URL to disk.
+	return saveSharedURL(objectURL, shareURL, expiry, "")
+}
+
 // main for share upload command.
 func mainShareUpload(ctx *cli.Context) error {
 
@@ -189,13 +204,9 @@ func mainShareUpload(ctx *cli.Context) error {
 	// Set command flags from context.
 	isRecursive := ctx.Bool("recursive")
 	expireArg := ctx.String("expire")
-	expiry := shareDefaultExpiry
 	contentType := ctx.String("content-type")
-	if expireArg != "" {
-		var e error
-		expiry, e = time.ParseDuration(expireArg)
-		fatalIf(probe.NewError(e), "Unable to parse expire=`"+expireArg+"`.")
-	}
+	expiry, err := parseShareExpiry(expireArg)
+	fatalIf(err.Trace(expireArg), "Unable to parse expire=`"+expireArg+"`.")
 
 	for _, targetURL := range ctx.Args() {
 		err := doShareUploadURL(targetURL, isRecursive, expiry, contentType)
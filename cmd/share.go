@@ -18,9 +18,11 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,6 +36,9 @@ import (
 const (
 	// Default expiry is 7 days (168h).
 	shareDefaultExpiry = time.Duration(604800) * time.Second
+
+	// Presigned URLs are valid for at most 7 days.
+	shareMaxExpiry = 7 * 24 * time.Hour
 )
 
 // Upload specific flags.
@@ -45,7 +50,7 @@ var (
 	shareFlagExpire = cli.StringFlag{
 		Name:  "expire, E",
 		Value: "168h",
-		Usage: "set expiry in NN[h|m|s]",
+		Usage: "set expiry in NN[d|h|m|s], at most 7d",
 	}
 )
 
@@ -55,13 +60,14 @@ type shareMesssage struct {
 	ObjectURL   string        `json:"url"`
 	ShareURL    string        `json:"share"`
 	TimeLeft    time.Duration `json:"timeLeft"`
+	Expiry      time.Time     `json:"expiry"`
 	ContentType string        `json:"contentType,omitempty"` // Only used by upload cmd.
 }
 
 // String - Themefied string message for console printing.
 func (s shareMesssage) String() string {
 	msg := console.Colorize("URL", fmt.Sprintf("URL: %s\n", s.ObjectURL))
-	msg += console.Colorize("Expire", fmt.Sprintf("Expire: %s\n", timeDurationToHumanizedDuration(s.TimeLeft)))
+	msg += console.Colorize("Expire", fmt.Sprintf("Expire: %s (%s)\n", timeDurationToHumanizedDuration(s.TimeLeft), s.Expiry.Format(printDate)))
 	if s.ContentType != "" {
 		msg += console.Colorize("Content-type", fmt.Sprintf("Content-Type: %s\n", s.ContentType))
 	}
@@ -92,6 +98,41 @@ func (s shareMesssage) JSON() string {
 	return string(shareMessageBytes)
 }
 
+// parseShareExpiry parses an expiry of NN days followed by a duration,
+// e.g. 7d, 1d12h or 90m, the default expiry when empty.
+func parseShareExpiry(value string) (time.Duration, *probe.Error) {
+	if value == "" {
+		return shareDefaultExpiry, nil
+	}
+	var expiry time.Duration
+	duration := value
+	if i := strings.Index(duration, "d"); i >= 0 {
+		days, e := strconv.Atoi(duration[:i])
+		if e != nil || days < 0 {
+			return 0, probe.NewError(fmt.Errorf("invalid number of days in `%s`", value))
+		}
+		if days > 7 {
+			return 0, probe.NewError(errors.New("expiry cannot be larger than 7 days"))
+		}
+		expiry = time.Duration(days) * 24 * time.Hour
+		duration = duration[i+1:]
+	}
+	if duration != "" {
+		d, e := time.ParseDuration(duration)
+		if e != nil {
+			return 0, probe.NewError(e)
+		}
+		expiry += d
+	}
+	if expiry < time.Second {
+		return 0, probe.NewError(errors.New("expiry cannot be lesser than 1 second"))
+	}
+	if expiry > shareMaxExpiry {
+		return 0, probe.NewError(errors.New("expiry cannot be larger than 7 days"))
+	}
+	return expiry, nil
+}
+
 // shareSetColor sets colors share sub-commands.
 func shareSetColor() {
 	// Additional command speific theme customization.
@@ -0,0 +1,52 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseShareExpiry(t *testing.T) {
+	testCases := []struct {
+		value   string
+		expiry  time.Duration
+		success bool
+	}{
+		{"", shareDefaultExpiry, true},
+		{"168h", 7 * 24 * time.Hour, true},
+		{"7d", 7 * 24 * time.Hour, true},
+		{"1d12h", 36 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"0d1s", time.Second, true},
+		{"7d1s", 0, false},
+		{"8d", 0, false},
+		{"500ms", 0, false},
+		{"d", 0, false},
+		{"-1d", 0, false},
+		{"1w", 0, false},
+	}
+	for i, testCase := range testCases {
+		expiry, err := parseShareExpiry(testCase.value)
+		if testCase.success != (err == nil) {
+			t.Fatalf("Test %d: expected success %t, found error %v", i+1, testCase.success, err)
+		}
+		if expiry != testCase.expiry {
+			t.Fatalf("Test %d: expected %s, found %s", i+1, testCase.expiry, expiry)
+		}
+	}
+}
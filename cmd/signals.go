@@ -19,13 +19,16 @@ package cmd
 import (
 	"os"
 	"os/signal"
+
+	"github.com/minio/minio/pkg/console"
 )
 
-// trapSignals traps the registered signals and cancel the global context.
+// trapSignals traps the registered signals and cancel the global context,
+// a second signal exits right away with globalErrorExitStatus whatever
+// the command is doing, without waiting for it to clean up.
 func trapSignals(sig ...os.Signal) {
 	// channel to receive signals.
 	sigCh := make(chan os.Signal, 1)
-	defer close(sigCh)
 
 	// `signal.Notify` registers the given channel to
 	// receive notifications of the specified signals.
@@ -34,9 +37,14 @@ func trapSignals(sig ...os.Signal) {
 	// Wait for the signal.
 	<-sigCh
 
-	// Once signal has been received stop signal Notify handler.
-	signal.Stop(sigCh)
-
-	// Cancel the global context
+	// Cancel the global context, sessions finish the objects being
+	// copied and are saved.
 	globalCancel()
+
+	// Other packages trap signals as well, so the default handling is
+	// not restored by stopping the notifications, exit when signalled
+	// again instead of waiting.
+	<-sigCh
+	console.Errorln("Interrupted again, exiting now.")
+	os.Exit(globalErrorExitStatus)
 }
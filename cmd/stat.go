@@ -22,7 +22,6 @@ import (
 	"strings"
 	"time"
 
-	humanize "github.com/dustin/go-humanize"
 	json "github.com/minio/mc/pkg/colorjson"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
@@ -30,14 +29,15 @@ import (
 
 // contentMessage container for content message structure.
 type statMessage struct {
-	Status   string            `json:"status"`
-	Key      string            `json:"name"`
-	Date     time.Time         `json:"lastModified"`
-	Size     int64             `json:"size"`
-	ETag     string            `json:"etag"`
-	Type     string            `json:"type"`
-	Expires  time.Time         `json:"expires"`
-	Metadata map[string]string `json:"metadata"`
+	Status       string            `json:"status"`
+	Key          string            `json:"name"`
+	Date         time.Time         `json:"lastModified"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag"`
+	Type         string            `json:"type"`
+	StorageClass string            `json:"storageClass,omitempty"`
+	Expires      time.Time         `json:"expires"`
+	Metadata     map[string]string `json:"metadata"`
 }
 
 // String colorized string message.
@@ -46,11 +46,14 @@ func printStat(stat statMessage) {
 	stat.Key = fmt.Sprintf("%-10s: %s", "Name", stat.Key)
 	console.Println(console.Colorize("Name", stat.Key))
 	console.Println(fmt.Sprintf("%-10s: %s ", "Date", stat.Date.Format(printDate)))
-	console.Println(fmt.Sprintf("%-10s: %-6s ", "Size", humanize.IBytes(uint64(stat.Size))))
+	console.Println(fmt.Sprintf("%-10s: %-6s ", "Size", humanizeSize(stat.Size)))
 	if stat.ETag != "" {
 		console.Println(fmt.Sprintf("%-10s: %s ", "ETag", stat.ETag))
 	}
 	console.Println(fmt.Sprintf("%-10s: %s ", "Type", stat.Type))
+	if stat.StorageClass != "" {
+		console.Println(fmt.Sprintf("%-10s: %s ", "Class", stat.StorageClass))
+	}
 	if !stat.Expires.IsZero() {
 		console.Println(fmt.Sprintf("%-10s: %s ", "Expires", stat.Expires.Format(printDate)))
 	}
@@ -118,6 +121,12 @@ func parseStat(c *clientContent) statMessage {
 	content.ETag = strings.TrimPrefix(c.ETag, "\"")
 	content.ETag = strings.TrimSuffix(content.ETag, "\"")
 	content.Expires = c.Expires
+	// Listings report the storage class, HEAD requests a header which
+	// is left out for the standard class.
+	content.StorageClass = c.StorageClass
+	if content.StorageClass == "" {
+		content.StorageClass = c.Metadata["X-Amz-Storage-Class"]
+	}
 	return content
 }
 
@@ -126,6 +135,32 @@ func getStandardizedURL(targetURL string) string {
 	return filepath.FromSlash(targetURL)
 }
 
+// statSingleURL returns the metadata of targetURL when it is an object,
+// read from a HEAD request, or a bucket with its creation date. It
+// returns nil for anything else, such as folders, left to the listing.
+func statSingleURL(clnt Client, targetURL string, encKeyDB map[string][]prefixSSEPair) *clientContent {
+	_, stat, err := url2Stat(targetURL, true, true, encKeyDB)
+	if err != nil {
+		return nil
+	}
+	if stat.Type.IsRegular() {
+		return stat
+	}
+	s3Clnt, ok := clnt.(*s3Client)
+	if !ok {
+		return nil
+	}
+	bucket, object := s3Clnt.url2BucketAndObject()
+	if object != "" {
+		return nil
+	}
+	// Buckets are stat'ed with a HEAD request which has no date.
+	if date, err := s3Clnt.bucketCreationDate(bucket); err == nil {
+		stat.Time = date
+	}
+	return stat
+}
+
 // statURL - simple or recursive listing
 func statURL(targetURL string, isIncomplete, isRecursive bool, encKeyDB map[string][]prefixSSEPair) ([]*clientContent, *probe.Error) {
 	var stats []*clientContent
@@ -142,6 +177,16 @@ func statURL(targetURL string, isIncomplete, isRecursive bool, encKeyDB map[stri
 	if !strings.HasSuffix(prefixPath, separator) {
 		prefixPath = prefixPath[:strings.LastIndex(prefixPath, separator)+1]
 	}
+
+	// A single object or bucket needs no listing, which policies may
+	// deny even where reading the object is allowed.
+	if !isRecursive && !strings.HasSuffix(targetURL, separator) {
+		if stat := statSingleURL(clnt, targetURL, encKeyDB); stat != nil {
+			stat.URL.Path = strings.TrimPrefix(filepath.ToSlash(stat.URL.Path), filepath.ToSlash(prefixPath))
+			return []*clientContent{stat}, nil
+		}
+	}
+
 	var cErr error
 	for content := range clnt.List(isRecursive, isIncomplete, false, DirNone) {
 		if content.Err != nil {
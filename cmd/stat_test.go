@@ -65,3 +65,21 @@ func TestParseStat(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStatStorageClass(t *testing.T) {
+	testCases := []struct {
+		content      clientContent
+		storageClass string
+	}{
+		// Listed objects.
+		{clientContent{StorageClass: "GLACIER", Metadata: map[string]string{}}, "GLACIER"},
+		// HEAD requests.
+		{clientContent{Metadata: map[string]string{"X-Amz-Storage-Class": "STANDARD_IA"}}, "STANDARD_IA"},
+		{clientContent{Metadata: map[string]string{"Content-Type": "text/plain"}}, ""},
+	}
+	for i, testCase := range testCases {
+		if statMsg := parseStat(&testCase.content); statMsg.StorageClass != testCase.storageClass {
+			t.Errorf("Test %d: expected storage class %q, got %q", i+1, testCase.storageClass, statMsg.StorageClass)
+		}
+	}
+}
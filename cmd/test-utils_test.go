@@ -0,0 +1,71 @@
+/*
+ * MinIO Client (C) 2020 MinIO, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/minio/mc/pkg/probe"
+)
+
+// Credentials signing the requests sent to test servers.
+const (
+	testAccessKey = "WLGDGYAQYIGI833EV05A"
+	testSecretKey = "BYvgJM101sHngl2uzjXS/OBF/aMxAN06JrJ3qJlF"
+)
+
+// newTestS3Server starts a test server of object storage. Bucket
+// location requests are answered with the default region, any other
+// request is served by handler.
+func newTestS3Server(handler http.HandlerFunc) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["location"]; ok {
+			w.Write([]byte("<LocationConstraint xmlns=\"http://s3.amazonaws.com/doc/2006-03-01/\"></LocationConstraint>"))
+			return
+		}
+		handler(w, r)
+	}))
+}
+
+// newTestS3Config returns the config of a client of hostURL on a test
+// server, signing its requests with the test credentials.
+func newTestS3Config(hostURL string) *Config {
+	conf := new(Config)
+	conf.HostURL = hostURL
+	conf.AccessKey = testAccessKey
+	conf.SecretKey = testSecretKey
+	conf.Signature = "S3v4"
+	return conf
+}
+
+// useEmptyMcConfig replaces the config of mc with an empty one, hosts
+// are only known from the environment. The returned function restores
+// the config.
+func useEmptyMcConfig() func() {
+	savedLoadMcConfig := loadMcConfig
+	loadMcConfig = func() (*configV9, *probe.Error) { return newConfigV9(), nil }
+	return func() { loadMcConfig = savedLoadMcConfig }
+}
+
+// setTestAlias sets alias to urlStr in the environment, the returned
+// function unsets it.
+func setTestAlias(alias, urlStr string) func() {
+	os.Setenv(mcEnvHostPrefix+alias, urlStr)
+	return func() { os.Unsetenv(mcEnvHostPrefix + alias) }
+}
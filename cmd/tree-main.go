@@ -19,11 +19,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/minio/cli"
+	json "github.com/minio/mc/pkg/colorjson"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
 )
@@ -52,12 +54,41 @@ func (t treeMessage) String() string {
 }
 
 // JSON'ified message for scripting.
-// Does No-op. JSON requests are redirected to `ls -r --json`
+// Does No-op. JSON requests are rendered through treeJSONMessage.
 func (t treeMessage) JSON() string {
 	fatalIf(probe.NewError(errors.New("JSON() should never be called here")), "Unable to list in tree format. Please report this issue at https://github.com/minio/mc/issues")
 	return ""
 }
 
+// treeNode is a single entry in the nested JSON tree.
+type treeNode struct {
+	Name     string     `json:"name"`
+	Type     string     `json:"type"`
+	Size     int64      `json:"size,omitempty"`
+	Children []treeNode `json:"children,omitempty"`
+}
+
+// treeJSONMessage nested tree of a target for scripting.
+type treeJSONMessage struct {
+	Status string   `json:"status"`
+	URL    string   `json:"url"`
+	Tree   treeNode `json:"tree"`
+}
+
+// Colorized message for console printing.
+// Does No-op. Console output is rendered by doTree.
+func (t treeJSONMessage) String() string {
+	return t.JSON()
+}
+
+// JSON'ified message for scripting.
+func (t treeJSONMessage) JSON() string {
+	t.Status = "success"
+	treeMessageBytes, e := json.MarshalIndent(t, "", " ")
+	fatalIf(probe.NewError(e), "Unable to marshal into JSON.")
+	return string(treeMessageBytes)
+}
+
 var treeFlags = []cli.Flag{
 	cli.BoolFlag{
 		Name:  "files, f",
@@ -68,6 +99,11 @@ var treeFlags = []cli.Flag{
 		Usage: "sets the depth threshold",
 		Value: -1,
 	},
+	cli.StringFlag{
+		Name:  "delimiter",
+		Usage: "single character delimiting the object hierarchy on object storage",
+		Value: "/",
+	},
 }
 
 // trees files and folders.
@@ -101,6 +137,12 @@ EXAMPLES:
 
    5. List all directories upto depth level '2' in tree format.
       {{.Prompt}} {{.HelpName}} --depth 2 myminio/mybucket/
+
+   6. Print all directories and objects in "mybucket" as a nested JSON tree.
+      {{.Prompt}} {{.HelpName}} --json --files myminio/mybucket/
+
+   7. List all directories in "mybucket" whose object names use '|' as hierarchy delimiter.
+      {{.Prompt}} {{.HelpName}} --delimiter '|' myminio/mybucket/
 `,
 }
 
@@ -129,7 +171,7 @@ func checkTreeSyntax(ctx *cli.Context) {
 func doTree(url string, level int, leaf bool, branchString string, depth int, includeFiles bool) error {
 
 	targetAlias, targetURL, _ := mustExpandAlias(url)
-	if !strings.HasSuffix(targetURL, "/") {
+	if !strings.HasSuffix(targetURL, "/") && !strings.HasSuffix(targetURL, globalListDelimiter) {
 		targetURL += "/"
 	}
 
@@ -138,7 +180,7 @@ func doTree(url string, level int, leaf bool, branchString string, depth int, in
 
 	prefixPath := clnt.GetURL().Path
 	separator := string(clnt.GetURL().Separator)
-	if !strings.HasSuffix(prefixPath, separator) {
+	if !strings.HasSuffix(prefixPath, separator) && !strings.HasSuffix(prefixPath, globalListDelimiter) {
 		prefixPath = filepath.Dir(prefixPath) + "/"
 	}
 
@@ -184,8 +226,9 @@ func doTree(url string, level int, leaf bool, branchString string, depth int, in
 		prefixPath = strings.TrimPrefix(prefixPath, "."+separator)
 
 		if prev.Type.IsDir() {
+			entry := strings.TrimSuffix(strings.TrimPrefix(contentURL, prefixPath), "/")
 			printMsg(treeMessage{
-				Entry:        strings.TrimSuffix(strings.TrimPrefix(contentURL, prefixPath), "/"),
+				Entry:        strings.TrimSuffix(entry, globalListDelimiter),
 				IsDir:        true,
 				BranchString: currbranchString,
 			})
@@ -244,6 +287,53 @@ func doTree(url string, level int, leaf bool, branchString string, depth int, in
 	return nil
 }
 
+// doTreeJSON - collects all entities inside a folder into nested tree nodes.
+func doTreeJSON(url string, level int, depth int, includeFiles bool) ([]treeNode, error) {
+	targetAlias, targetURL, _ := mustExpandAlias(url)
+	if !strings.HasSuffix(targetURL, "/") && !strings.HasSuffix(targetURL, globalListDelimiter) {
+		targetURL += "/"
+	}
+
+	clnt, err := newClientFromAlias(targetAlias, targetURL)
+	fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
+
+	var nodes []treeNode
+	for content := range clnt.List(false, false, false, DirNone) {
+		if content.Err != nil {
+			errorIf(content.Err.Trace(clnt.GetURL().String()), "Unable to tree.")
+			continue
+		}
+
+		if !includeFiles && !content.Type.IsDir() {
+			continue
+		}
+
+		contentURL := filepath.ToSlash(content.URL.Path)
+		name := path.Base(strings.TrimSuffix(strings.TrimSuffix(contentURL, "/"), globalListDelimiter))
+		name = name[strings.LastIndex(name, globalListDelimiter)+1:]
+		if !content.Type.IsDir() {
+			nodes = append(nodes, treeNode{Name: name, Type: "file", Size: content.Size})
+			continue
+		}
+
+		node := treeNode{Name: name, Type: "dir"}
+		if depth == -1 || level <= depth {
+			childURL := contentURL
+			if targetAlias != "" {
+				childURL = targetAlias + "/" + contentURL
+			}
+			children, e := doTreeJSON(childURL, level+1, depth, includeFiles)
+			if e != nil {
+				return nil, e
+			}
+			node.Children = children
+		}
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
 // mainTree - is a handler for mc tree command
 func mainTree(ctx *cli.Context) error {
 
@@ -269,15 +359,15 @@ func mainTree(ctx *cli.Context) error {
 				cErr = e
 			}
 		} else {
-			targetAlias, targetURL, _ := mustExpandAlias(targetURL)
-			if !strings.HasSuffix(targetURL, "/") {
-				targetURL += "/"
-			}
-			clnt, err := newClientFromAlias(targetAlias, targetURL)
-			fatalIf(err.Trace(targetURL), "Unable to initialize target `"+targetURL+"`.")
-			if e := doList(clnt, true, false); e != nil {
+			children, e := doTreeJSON(targetURL, 1, depth, includeFiles)
+			if e != nil {
 				cErr = e
+				continue
 			}
+			printMsg(treeJSONMessage{
+				URL:  targetURL,
+				Tree: treeNode{Name: targetURL, Type: "dir", Children: children},
+			})
 		}
 	}
 	return cErr
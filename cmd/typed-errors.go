@@ -20,7 +20,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	humanize "github.com/dustin/go-humanize"
 	"github.com/minio/mc/pkg/probe"
 )
 
@@ -89,6 +91,48 @@ var errInvalidSource = func(URL string) *probe.Error {
 	return probe.NewError(invalidSourceErr(errors.New(msg))).Untrace()
 }
 
+type sourceNotFoundErr error
+
+var errSourceNotFound = func(URL string) *probe.Error {
+	msg := "Source `" + URL + "` does not exist."
+	return probe.NewError(sourceNotFoundErr(errors.New(msg))).Untrace()
+}
+
+type invalidContentEncodingErr error
+
+var errInvalidContentEncoding = func(URL, contentEncoding string) *probe.Error {
+	msg := "Unable to decode `" + URL + "` stored with `Content-Encoding: " + contentEncoding + "`, use --raw to output the stored bytes."
+	return probe.NewError(invalidContentEncodingErr(errors.New(msg))).Untrace()
+}
+
+type targetIsSymlinkErr error
+
+var errTargetIsSymlink = func(URL string) *probe.Error {
+	msg := "Folder `" + URL + "` is a symbolic link, refusing to write through it."
+	return probe.NewError(targetIsSymlinkErr(errors.New(msg))).Untrace()
+}
+
+type keysTooLongErr error
+
+var errKeysTooLong = func(keys []string) *probe.Error {
+	msg := fmt.Sprintf("%d object name(s) exceed the length limit of the target: `%s`.", len(keys), strings.Join(keys, "`, `"))
+	return probe.NewError(keysTooLongErr(errors.New(msg))).Untrace()
+}
+
+type hookFailedErr error
+
+var errHookFailed = func(command string, e error) *probe.Error {
+	msg := "Hook `" + command + "` failed, " + e.Error() + "."
+	return probe.NewError(hookFailedErr(errors.New(msg))).Untrace()
+}
+
+type sameSourceTargetErr error
+
+var errSameSourceTarget = func(URL string) *probe.Error {
+	msg := "Source and target `" + URL + "` are the same, nothing to copy."
+	return probe.NewError(sameSourceTargetErr(errors.New(msg))).Untrace()
+}
+
 type invalidTargetErr error
 
 var errInvalidTarget = func(URL string) *probe.Error {
@@ -96,6 +140,21 @@ var errInvalidTarget = func(URL string) *probe.Error {
 	return probe.NewError(invalidTargetErr(errors.New(msg))).Untrace()
 }
 
+type moveNotConfirmedErr error
+
+var errMoveNotConfirmed = func(URL, reason string) *probe.Error {
+	msg := fmt.Sprintf("Target `%s` %s, keeping the source.", URL, reason)
+	return probe.NewError(moveNotConfirmedErr(errors.New(msg))).Untrace()
+}
+
+type targetProtectedErr error
+
+var errTargetProtected = func(URL string, age, window time.Duration) *probe.Error {
+	msg := fmt.Sprintf("Target `%s` was modified %s ago, within the protection window of %s.",
+		URL, age.Round(time.Second), window)
+	return probe.NewError(targetProtectedErr(errors.New(msg))).Untrace()
+}
+
 type targetNotFoundErr error
 
 var errTargetNotFound = func(URL string) *probe.Error {
@@ -125,3 +184,39 @@ var errConflictSSE = func(sseServer, sseKeys string) *probe.Error {
 	err := fmt.Errorf("SSE alias '%s' overlaps with SSE-C aliases '%s'", sseServer, sseKeys)
 	return probe.NewError(conflictSSEErr(err)).Untrace()
 }
+
+type insufficientSpaceErr error
+
+var errInsufficientSpace = func(path string, required, available uint64) *probe.Error {
+	msg := fmt.Sprintf("Not enough free space on `%s`, need at least %s but only %s is available. Use `--no-space-check` to skip this check.",
+		path, humanize.IBytes(required), humanize.IBytes(available))
+	return probe.NewError(insufficientSpaceErr(errors.New(msg))).Untrace()
+}
+
+type sessionExistsErr error
+
+var errSessionExists = func(sid string) *probe.Error {
+	msg := "Session `" + sid + "` already exists and will not be overwritten."
+	return probe.NewError(sessionExistsErr(errors.New(msg))).Untrace()
+}
+
+type sessionActiveErr error
+
+var errSessionActive = func(sid string, window time.Duration) *probe.Error {
+	msg := "Session `" + sid + "` was used in the last " + window.String() + " and may be running."
+	return probe.NewError(sessionActiveErr(errors.New(msg))).Untrace()
+}
+
+type copiedMismatchErr error
+
+var errCopiedMismatch = func(URL, reason string) *probe.Error {
+	msg := "Target `" + URL + "` recorded as copied " + reason + "."
+	return probe.NewError(copiedMismatchErr(errors.New(msg))).Untrace()
+}
+
+type checksumMismatchErr error
+
+var errChecksumMismatch = func(URL, algorithm string) *probe.Error {
+	msg := "Source `" + URL + "` changed while it was copied, its " + algorithm + " checksum no longer matches the one stored with the target, the target was removed."
+	return probe.NewError(checksumMismatchErr(errors.New(msg))).Untrace()
+}
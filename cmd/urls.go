@@ -17,6 +17,8 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/minio/mc/pkg/probe"
 )
 
@@ -30,6 +32,19 @@ type URLs struct {
 	TotalSize     int64
 	encKeyDB      map[string][]prefixSSEPair
 	Error         *probe.Error `json:"-"`
+
+	// Number of times the transfer was started, and the step of it
+	// which failed, for error reports.
+	attempts  int
+	operation string
+
+	// Number of times a transfer failing with a transient error is
+	// started again, after retryDelay doubled at every retry.
+	retries    int
+	retryDelay time.Duration
+
+	// Position in the listing of a session, see copyWatermark.
+	seq int64
 }
 
 // WithError sets the error and returns object
@@ -38,6 +53,14 @@ func (m URLs) WithError(err *probe.Error) URLs {
 	return m
 }
 
+// WithOperationError sets the error along with the operation which
+// failed, one of those of errorReportEntry.
+func (m URLs) WithOperationError(operation string, err *probe.Error) URLs {
+	m.Error = err
+	m.operation = operation
+	return m
+}
+
 // Equal tests if both urls are equal
 func (m URLs) Equal(n URLs) bool {
 	if m.SourceContent == nil && n.SourceContent == nil {
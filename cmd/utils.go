@@ -17,23 +17,31 @@
 package cmd
 
 import (
+	"bytes"
 	"crypto/tls"
 	"errors"
 	"io"
 	"math/rand"
+	"mime"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	humanize "github.com/dustin/go-humanize"
 	"github.com/minio/minio-go/v6"
 	"github.com/minio/minio-go/v6/pkg/encrypt"
 
 	"github.com/minio/mc/pkg/ioutils"
 	"github.com/minio/mc/pkg/probe"
 	"github.com/minio/minio/pkg/console"
+	"golang.org/x/net/http/httpguts"
 )
 
 func isErrIgnored(err *probe.Error) (ignored bool) {
@@ -47,12 +55,29 @@ func isErrIgnored(err *probe.Error) (ignored bool) {
 		ignored = true
 	case ObjectAlreadyExistsAsDirectory, BucketDoesNotExist, BucketInvalid:
 		ignored = true
+	case PreconditionFailed, KeyTooLong:
+		ignored = true
 	default:
 		ignored = false
 	}
 	return ignored
 }
 
+// isErrSourceMissing - returns true if err reports that a bucket,
+// folder or object does not exist.
+func isErrSourceMissing(err *probe.Error) bool {
+	switch err.ToGoError().(type) {
+	case PathNotFound, ObjectMissing, BucketDoesNotExist:
+		return true
+	}
+	// Listings report server errors as is.
+	switch minio.ToErrorResponse(err.ToGoError()).Code {
+	case "NoSuchBucket", "NoSuchKey":
+		return true
+	}
+	return false
+}
+
 const (
 	letterBytes   = "abcdefghijklmnopqrstuvwxyz01234569"
 	letterIdxBits = 6                    // 6 bits to represent a letter index
@@ -126,6 +151,86 @@ func splitStr(path, sep string, n int) []string {
 	return splits
 }
 
+// Number of attempts used by `--retry-on`, matches minio-go default.
+const defaultRetryAttempts = 10
+
+// parseRetryStatusCodes parses a comma separated list of HTTP status codes.
+func parseRetryStatusCodes(codes string) (map[int]struct{}, *probe.Error) {
+	statuses := make(map[int]struct{})
+	for _, code := range strings.Split(codes, ",") {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		status, e := strconv.Atoi(code)
+		if e != nil || status < 100 || status > 599 {
+			return nil, errInvalidArgument().Trace(code)
+		}
+		statuses[status] = struct{}{}
+	}
+	if len(statuses) == 0 {
+		return nil, errInvalidArgument().Trace(codes)
+	}
+	return statuses, nil
+}
+
+// parseBindAddr returns the local IP address to bind connections to,
+// given either as an address of this host or as a network interface.
+func parseBindAddr(value string) (net.IP, *probe.Error) {
+	if ip := net.ParseIP(value); ip != nil {
+		addrs, e := net.InterfaceAddrs()
+		if e != nil {
+			return nil, probe.NewError(e)
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return ip, nil
+			}
+		}
+		return nil, probe.NewError(BindAddrNotLocal{Addr: value})
+	}
+	iface, e := net.InterfaceByName(value)
+	if e != nil {
+		return nil, probe.NewError(BindAddrNotLocal{Addr: value})
+	}
+	addrs, e := iface.Addrs()
+	if e != nil {
+		return nil, probe.NewError(e)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, probe.NewError(BindAddrNotLocal{Addr: value})
+}
+
+// localTCPAddr returns the local address of a dialer bound to ip, the
+// interface is left nil for an unbound dialer.
+func localTCPAddr(ip net.IP) net.Addr {
+	if ip == nil {
+		return nil
+	}
+	return &net.TCPAddr{IP: ip}
+}
+
+// newHTTPClient returns a client for plain HTTP requests to hosts,
+// which honours the proxy, TLS and bind address settings of mc.
+func newHTTPClient(timeout time.Duration, insecure bool) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:       http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{LocalAddr: localTCPAddr(globalBindAddr)}).DialContext,
+			TLSClientConfig: &tls.Config{
+				RootCAs:            globalRootCAs,
+				MinVersion:         tls.VersionTLS12,
+				InsecureSkipVerify: insecure,
+			},
+		},
+	}
+}
+
 // newS3Config simply creates a new Config struct using the passed
 // parameters.
 func newS3Config(urlStr string, hostCfg *hostConfigV9) *Config {
@@ -138,14 +243,34 @@ func newS3Config(urlStr string, hostCfg *hostConfigV9) *Config {
 	s3Config.AppComments = []string{os.Args[0], runtime.GOOS, runtime.GOARCH}
 	s3Config.Debug = globalDebug
 	s3Config.Insecure = globalInsecure
+	s3Config.ConnectTimeout = globalConnectTimeout
+	s3Config.ResponseHeaderTimeout = globalResponseHeaderTimeout
+	s3Config.ReadTimeout = globalReadTimeout
+	s3Config.RequestTimeout = globalRequestTimeout
+	s3Config.MaxIdleConns = globalMaxIdleConns
+	s3Config.MaxIdleConnsPerHost = globalMaxIdleConnsPerHost
+	s3Config.RetryStatusCodes = globalRetryStatusCodes
+	s3Config.ConnLimiter = globalConnLimiter
+	s3Config.Delimiter = globalListDelimiter
+	s3Config.DualStack = globalDualStack
+	s3Config.RegionRedirect = globalRegionRedirect
+	s3Config.DefaultRegion = globalDefaultRegion
+	s3Config.BindAddr = globalBindAddr
 
 	s3Config.HostURL = urlStr
 	if hostCfg != nil {
 		s3Config.AccessKey = hostCfg.AccessKey
 		s3Config.SecretKey = hostCfg.SecretKey
 		s3Config.Signature = hostCfg.API
+		s3Config.CACertFile = hostCfg.CACert
+	}
+	if globalCACertFile != "" {
+		s3Config.CACertFile = globalCACertFile
 	}
 	s3Config.Lookup = getLookupType(hostCfg.Lookup)
+	// The lookup of the host config is only guessed when left to auto.
+	s3Config.PathStyle = globalPathStyle ||
+		(s3Config.Lookup == minio.BucketLookupAuto && isPathStyleHost(newClientURL(urlStr).Host))
 	return s3Config
 }
 
@@ -302,6 +427,54 @@ func getSSE(resource string, encKeys []prefixSSEPair) encrypt.ServerSide {
 	return nil
 }
 
+// parseTargetSSE returns the server managed encryption asked for with
+// --sse and --sse-kms-key-id for objects written under targetURL. It may
+// not overlap the prefixes encrypted with --encrypt or --encrypt-key.
+func parseTargetSSE(algorithm, kmsKeyID, targetURL string, encKeys []prefixSSEPair) (encrypt.ServerSide, *probe.Error) {
+	var sse encrypt.ServerSide
+	switch {
+	case algorithm == "":
+		if kmsKeyID != "" {
+			return nil, probe.NewError(errors.New("SSE KMS key id requires --sse aws:kms"))
+		}
+		return nil, nil
+	case strings.EqualFold(algorithm, "AES256"):
+		if kmsKeyID != "" {
+			return nil, probe.NewError(errors.New("SSE KMS key id can only be used with --sse aws:kms, not " + algorithm))
+		}
+		sse = encrypt.NewSSE()
+	case strings.EqualFold(algorithm, "aws:kms"):
+		var e error
+		if sse, e = encrypt.NewSSEKMS(kmsKeyID, nil); e != nil {
+			return nil, probe.NewError(e)
+		}
+	default:
+		return nil, probe.NewError(errors.New("SSE algorithm " + algorithm + " is not supported, use AES256 or aws:kms"))
+	}
+
+	if alias, expandedURL, _ := mustExpandAlias(targetURL); alias == "" && newClientURL(expandedURL).Type == fileSystem {
+		return nil, probe.NewError(errors.New("--sse is only supported for object storage targets"))
+	}
+	for _, k := range encKeys {
+		if strings.HasPrefix(targetURL, k.Prefix) || strings.HasPrefix(k.Prefix, targetURL) {
+			return nil, probe.NewError(errors.New("--sse target " + targetURL + " overlaps with the encryption prefix " + k.Prefix))
+		}
+	}
+	return sse, nil
+}
+
+// addTargetSSE encrypts objects written under targetURL with sse.
+func addTargetSSE(encKeyDB map[string][]prefixSSEPair, targetURL string, sse encrypt.ServerSide) {
+	if sse == nil {
+		return
+	}
+	alias, _ := url2Alias(targetURL)
+	encKeyDB[alias] = append(encKeyDB[alias], prefixSSEPair{
+		Prefix: targetURL,
+		SSE:    sse,
+	})
+}
+
 // Return true if target url is a part of a source url such as:
 // alias/bucket/ and alias/bucket/dir/, however
 func isURLContains(srcURL, tgtURL, sep string) bool {
@@ -349,3 +522,142 @@ func parseAttribute(attrs string) (map[string]string, error) {
 
 	return attribute, nil
 }
+
+// humanizeSize - returns size in IEC units without a space, e.g. 1.2GiB,
+// or "-" when the size is unknown.
+func humanizeSize(size int64) string {
+	if size < 0 {
+		return "-"
+	}
+	return strings.Join(strings.Fields(humanize.IBytes(uint64(size))), "")
+}
+
+// isValidContentDisposition - returns true if value is a disposition type
+// optionally followed by parameters, e.g. `attachment; filename="a.txt"`.
+func isValidContentDisposition(value string) bool {
+	if !httpguts.ValidHeaderFieldValue(value) {
+		return false
+	}
+	_, _, e := mime.ParseMediaType(value)
+	return e == nil
+}
+
+// isValidCacheControl - returns true if value is a comma separated list of
+// directives, each one either a token or a token=value pair.
+func isValidCacheControl(value string) bool {
+	if strings.TrimSpace(value) == "" || !httpguts.ValidHeaderFieldValue(value) {
+		return false
+	}
+	for _, directive := range strings.Split(value, ",") {
+		name := strings.TrimSpace(directive)
+		if i := strings.Index(name, "="); i >= 0 {
+			if strings.TrimSpace(name[i+1:]) == "" {
+				return false
+			}
+			name = strings.TrimSpace(name[:i])
+		}
+		if !httpguts.ValidHeaderFieldName(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidContentType - returns true if value is a type/subtype media type
+// optionally followed by parameters, e.g. `text/html; charset=utf-8`.
+func isValidContentType(value string) bool {
+	if !httpguts.ValidHeaderFieldValue(value) {
+		return false
+	}
+	mediaType, _, e := mime.ParseMediaType(value)
+	if e != nil {
+		return false
+	}
+	i := strings.Index(mediaType, "/")
+	return i > 0 && i < len(mediaType)-1
+}
+
+// isValidCharset - returns true if value is usable as a charset
+// parameter, e.g. `utf-8` or `iso-8859-1`.
+func isValidCharset(value string) bool {
+	return httpguts.ValidHeaderFieldName(value)
+}
+
+// isTextContentType - returns true for content types which carry a
+// charset parameter, i.e. text/* and the common structured text types.
+func isTextContentType(contentType string) bool {
+	mediaType, _, e := mime.ParseMediaType(contentType)
+	if e != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml":
+		return true
+	}
+	return false
+}
+
+// isCompressibleContentType - returns true for content types which are
+// worth compressing, leaving images, archives and the like alone.
+func isCompressibleContentType(contentType string) bool {
+	if isTextContentType(contentType) {
+		return true
+	}
+	mediaType, _, e := mime.ParseMediaType(contentType)
+	if e != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/csv", "application/x-ndjson", "application/yaml", "application/x-yaml", "application/sql":
+		return true
+	}
+	return false
+}
+
+// detectCharset - guess the charset of a text chunk from its byte order
+// mark, falling back to validating it as utf-8. Returns "" when unknown.
+func detectCharset(buf []byte, isPartial bool) string {
+	switch {
+	case bytes.HasPrefix(buf, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8"
+	case bytes.HasPrefix(buf, []byte{0xFE, 0xFF}):
+		return "utf-16be"
+	case bytes.HasPrefix(buf, []byte{0xFF, 0xFE}):
+		return "utf-16le"
+	}
+	if isPartial {
+		// Ignore a multi-byte rune cut at the end of the chunk.
+		for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(buf[i]) {
+				if !utf8.FullRune(buf[i:]) {
+					buf = buf[:i]
+				}
+				break
+			}
+		}
+	}
+	if utf8.Valid(buf) {
+		return "utf-8"
+	}
+	return ""
+}
+
+// setContentTypeCharset - returns contentType with its charset parameter
+// set to charset, binary content types are returned as is.
+func setContentTypeCharset(contentType, charset string) string {
+	if charset == "" || !isTextContentType(contentType) {
+		return contentType
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	params["charset"] = charset
+	if ctype := mime.FormatMediaType(mediaType, params); ctype != "" {
+		return ctype
+	}
+	return contentType
+}
@@ -17,6 +17,7 @@
 package cmd
 
 import (
+	"net"
 	"reflect"
 	"testing"
 
@@ -160,6 +161,61 @@ func TestParseEncryptionKeys(t *testing.T) {
 	}
 }
 
+func TestParseTargetSSE(t *testing.T) {
+	defer useEmptyMcConfig()()
+	defer setTestAlias("ssetest", "http://127.0.0.1:9000")()
+
+	sseKey, err := encrypt.NewSSEC([]byte("32byteslongsecretkeymustbegiven1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encKeys := []prefixSSEPair{{Prefix: "ssetest/secret/", SSE: sseKey}}
+
+	testCases := []struct {
+		algorithm string
+		kmsKeyID  string
+		targetURL string
+		sseType   encrypt.Type
+		success   bool
+	}{
+		{"", "", "ssetest/bucket/", "", true},
+		{"AES256", "", "ssetest/bucket/", encrypt.S3, true},
+		{"aes256", "", "ssetest/bucket/", encrypt.S3, true},
+		{"aws:kms", "", "ssetest/bucket/", encrypt.KMS, true},
+		{"aws:kms", "my-key", "ssetest/bucket/", encrypt.KMS, true},
+		// A KMS key id only goes with aws:kms.
+		{"", "my-key", "ssetest/bucket/", "", false},
+		{"AES256", "my-key", "ssetest/bucket/", "", false},
+		{"aws:kms:my-key", "", "ssetest/bucket/", "", false},
+		// Local targets are not encrypted by a server.
+		{"AES256", "", "/tmp/backups", "", false},
+		// Overlaps with the SSE-C prefix.
+		{"AES256", "", "ssetest/secret/docs/", "", false},
+		{"AES256", "", "ssetest/", "", false},
+	}
+	for i, testCase := range testCases {
+		sse, err := parseTargetSSE(testCase.algorithm, testCase.kmsKeyID, testCase.targetURL, encKeys)
+		if err != nil && testCase.success {
+			t.Fatalf("Test %d: Expected success, got %s", i+1, err)
+		}
+		if err == nil && !testCase.success {
+			t.Fatalf("Test %d: Expected error, got success", i+1)
+		}
+		if !testCase.success {
+			continue
+		}
+		if testCase.sseType == "" {
+			if sse != nil {
+				t.Errorf("Test %d: Expected no encryption, got %s", i+1, sse.Type())
+			}
+			continue
+		}
+		if sse == nil || sse.Type() != testCase.sseType {
+			t.Errorf("Test %d: Expected %s, got %v", i+1, testCase.sseType, sse)
+		}
+	}
+}
+
 func TestParseAttribute(t *testing.T) {
 	metaDataCases := []struct {
 		input  string
@@ -204,3 +260,194 @@ func TestParseAttribute(t *testing.T) {
 
 	}
 }
+
+func TestParseRetryStatusCodes(t *testing.T) {
+	testCases := []struct {
+		codes    string
+		statuses map[int]struct{}
+		success  bool
+	}{
+		{"500,502,503,504", map[int]struct{}{500: {}, 502: {}, 503: {}, 504: {}}, true},
+		{" 429 , 503,", map[int]struct{}{429: {}, 503: {}}, true},
+		{"", nil, false},
+		{"50x", nil, false},
+		{"600", nil, false},
+	}
+
+	for i, testCase := range testCases {
+		statuses, err := parseRetryStatusCodes(testCase.codes)
+		if testCase.success && err != nil {
+			t.Fatalf("Test %d: unexpected error %s", i+1, err)
+		}
+		if !testCase.success && err == nil {
+			t.Fatalf("Test %d: expected error for `%s`", i+1, testCase.codes)
+		}
+		if !reflect.DeepEqual(statuses, testCase.statuses) {
+			t.Fatalf("Test %d: expected %v, found %v", i+1, testCase.statuses, statuses)
+		}
+	}
+}
+
+func TestParseBindAddr(t *testing.T) {
+	// Name of the loopback interface differs across platforms.
+	loopback := ""
+	ifaces, e := net.Interfaces()
+	if e != nil {
+		t.Fatal(e)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			loopback = iface.Name
+			break
+		}
+	}
+
+	testCases := []struct {
+		value   string
+		success bool
+	}{
+		{"127.0.0.1", true},
+		{loopback, true},
+		{"192.0.2.1", false},
+		{"not-an-interface0", false},
+	}
+
+	for i, testCase := range testCases {
+		ip, err := parseBindAddr(testCase.value)
+		if testCase.success && (err != nil || ip == nil) {
+			t.Fatalf("Test %d: unexpected error for `%s`: %v", i+1, testCase.value, err)
+		}
+		if !testCase.success && err == nil {
+			t.Fatalf("Test %d: expected error for `%s`", i+1, testCase.value)
+		}
+	}
+	if localTCPAddr(nil) != nil {
+		t.Fatal("Unbound dialer must have a nil local address")
+	}
+}
+
+func TestNewS3ConfigPathStyle(t *testing.T) {
+	defer func(pathStyle bool) { globalPathStyle = pathStyle }(globalPathStyle)
+
+	testCases := []struct {
+		url         string
+		lookup      string
+		globalStyle bool
+		pathStyle   bool
+	}{
+		{"http://localhost:9000", "auto", false, true},
+		{"http://localhost:9000", "dns", false, false},
+		{"http://localhost:9000", "path", false, false},
+		{"https://play.min.io", "auto", false, false},
+		{"http://localhost:9000", "dns", true, true},
+	}
+	for i, testCase := range testCases {
+		globalPathStyle = testCase.globalStyle
+		s3Config := newS3Config(testCase.url, &hostConfigV9{Lookup: testCase.lookup})
+		if s3Config.PathStyle != testCase.pathStyle {
+			t.Errorf("Test %d: expected path style %t, got %t", i+1, testCase.pathStyle, s3Config.PathStyle)
+		}
+	}
+}
+
+func TestValidHeaderValues(t *testing.T) {
+	testCases := []struct {
+		value          string
+		isCacheControl bool
+		valid          bool
+	}{
+		{`attachment; filename="report.pdf"`, false, true},
+		{"inline", false, true},
+		{"attachment; filename=", false, false},
+		{"attach\nment", false, false},
+		{"public, max-age=86400", true, true},
+		{"no-cache", true, true},
+		{"max-age=", true, false},
+		{"no cache", true, false},
+		{" ", true, false},
+	}
+
+	for i, testCase := range testCases {
+		valid := isValidContentDisposition(testCase.value)
+		if testCase.isCacheControl {
+			valid = isValidCacheControl(testCase.value)
+		}
+		if valid != testCase.valid {
+			t.Fatalf("Test %d: expected %t for `%s`, found %t", i+1, testCase.valid, testCase.value, valid)
+		}
+	}
+}
+
+func TestValidContentType(t *testing.T) {
+	testCases := []struct {
+		value string
+		valid bool
+	}{
+		{"text/html", true},
+		{"text/html; charset=utf-8", true},
+		{"application/vnd.api+json", true},
+		{"text", false},
+		{"text/", false},
+		{"/html", false},
+		{"text/html; charset=", false},
+		{"text/\nhtml", false},
+	}
+
+	for i, testCase := range testCases {
+		if valid := isValidContentType(testCase.value); valid != testCase.valid {
+			t.Fatalf("Test %d: expected %t for `%s`, found %t", i+1, testCase.valid, testCase.value, valid)
+		}
+	}
+}
+
+func TestContentTypeCharset(t *testing.T) {
+	testCases := []struct {
+		contentType string
+		data        []byte
+		isPartial   bool
+		expected    string
+	}{
+		{"text/plain", []byte("hello world"), false, "text/plain; charset=utf-8"},
+		{"text/html", []byte{0xEF, 0xBB, 0xBF, '<', 'p', '>'}, false, "text/html; charset=utf-8"},
+		{"text/plain", []byte{0xFF, 0xFE, 'h', 0x00}, false, "text/plain; charset=utf-16le"},
+		{"text/plain", []byte{0xFE, 0xFF, 0x00, 'h'}, false, "text/plain; charset=utf-16be"},
+		{"application/json", []byte(`{"a":1}`), false, "application/json; charset=utf-8"},
+		// A multi-byte rune cut at the end of the sniffed chunk is still utf-8.
+		{"text/plain", []byte{'a', 0xC3}, true, "text/plain; charset=utf-8"},
+		{"text/plain", []byte{'a', 0xC3}, false, "text/plain"},
+		{"text/plain", []byte{0xE9, 't', 0xE9}, false, "text/plain"},
+		{"image/png", []byte("hello world"), false, "image/png"},
+		{"application/octet-stream", []byte("hello world"), false, "application/octet-stream"},
+	}
+
+	for i, testCase := range testCases {
+		contentType := setContentTypeCharset(testCase.contentType, detectCharset(testCase.data, testCase.isPartial))
+		if contentType != testCase.expected {
+			t.Fatalf("Test %d: expected `%s`, found `%s`", i+1, testCase.expected, contentType)
+		}
+	}
+}
+
+func TestCompressibleContentType(t *testing.T) {
+	testCases := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"text/csv", true},
+		{"text/plain; charset=utf-8", true},
+		{"application/json", true},
+		{"application/xml", true},
+		{"image/svg+xml", true},
+		{"application/x-ndjson", true},
+		{"image/jpeg", false},
+		{"application/zip", false},
+		{"application/gzip", false},
+		{"application/octet-stream", false},
+		{"", false},
+	}
+	for i, testCase := range testCases {
+		if isCompressibleContentType(testCase.contentType) != testCase.expected {
+			t.Fatalf("Test %d: expected %t for `%s`", i+1, testCase.expected, testCase.contentType)
+		}
+	}
+}